@@ -4,6 +4,7 @@ import (
 	"github.com/codecrafters-io/redis-starter-go/app/internal/config"
 	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/internal/server/master"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/server/sentinel"
 	"github.com/codecrafters-io/redis-starter-go/app/internal/server/slave"
 )
 
@@ -14,9 +15,12 @@ type Server interface {
 }
 
 func NewServer(cfg *config.ServerConfig) Server {
-	if cfg.Role == "slave" {
+	switch cfg.Role {
+	case "slave":
 		return slave.NewSlaveServer(cfg)
-	} else {
+	case "sentinel":
+		return sentinel.NewSentinelServer(cfg)
+	default:
 		return master.NewMasterServer(cfg)
 	}
 }
@@ -0,0 +1,206 @@
+// Package blocking 阻塞命令 (BLPOP 等) 的等待队列
+//
+// 没有可用数据的阻塞命令注册一个等待者后挂起连接; 写命令执行完成后 (持有写锁)
+// 服务器对被写入的键调用 SignalKeys + ServeReady, 按阻塞的先后顺序 (FIFO)
+// 在写入方的 goroutine 中为等待者弹出数据并传播, 复制流中写入与弹出的顺序与主节点一致.
+// 等待者被服务或超时后由自己的连接写回复; 连接断开 (ctx 取消) 的等待者不再被服务.
+package blocking
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ServeFunc 在写锁内尝试为等待者服务 key 上没有可用数据时返回 false
+// 成功时返回给客户端的回复 (可以是错误回复)
+type ServeFunc func(key string) (reply any, ok bool)
+
+// Waiter 一个被阻塞的客户端
+type Waiter struct {
+	r            *Registry
+	ctx          context.Context // 连接的生命周期 取消后不再服务
+	keys         []string
+	timeout      time.Duration // 0 表示一直等待
+	timeoutReply any
+	serve        ServeFunc
+
+	mu        sync.Mutex
+	reply     any
+	served    bool
+	cancelled bool
+	done      chan struct{}
+}
+
+// Registry 按键组织的等待队列
+type Registry struct {
+	mu       sync.Mutex
+	waiters  map[string][]*Waiter
+	ready    []string
+	readySet map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		waiters:  make(map[string][]*Waiter),
+		readySet: make(map[string]bool),
+	}
+}
+
+// Block 注册等待者 调用方需持有写锁 (与写入互斥, 注册前检查过的数据不会在注册前变化)
+// 超时或 ctx 取消 (客户端断开) 时 Wait 返回 timeoutReply
+func (r *Registry) Block(ctx context.Context, keys []string, timeout time.Duration, timeoutReply any, serve ServeFunc) *Waiter {
+	w := &Waiter{
+		r:            r,
+		ctx:          ctx,
+		keys:         keys,
+		timeout:      timeout,
+		timeoutReply: timeoutReply,
+		serve:        serve,
+		done:         make(chan struct{}),
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		// BLPOP a a 只排队一次
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		r.waiters[key] = append(r.waiters[key], w)
+	}
+	return w
+}
+
+// SignalKeys 写命令修改了 keys: 有等待者的键标记为就绪
+func (r *Registry) SignalKeys(keys ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range keys {
+		if len(r.waiters[key]) > 0 && !r.readySet[key] {
+			r.readySet[key] = true
+			r.ready = append(r.ready, key)
+		}
+	}
+}
+
+// ServeReady 依次处理就绪的键 调用方需持有写锁
+// 每个键按等待顺序服务; 无法服务的等待者不影响之后的等待者 (如不同消费者组的 XREADGROUP,
+// 数据用完后之后的列表等待者也会失败). 服务过程中写入的键 (如 BLMOVE 的目标) 会继续处理
+func (r *Registry) ServeReady() {
+	for {
+		r.mu.Lock()
+		if len(r.ready) == 0 {
+			r.mu.Unlock()
+			return
+		}
+		key := r.ready[0]
+		r.ready = r.ready[1:]
+		delete(r.readySet, key)
+		queue := append([]*Waiter(nil), r.waiters[key]...)
+		r.mu.Unlock()
+
+		for _, w := range queue {
+			w.tryServe(key)
+		}
+	}
+}
+
+// tryServe 数据不可用时返回 false, 已超时或连接已断开的等待者跳过 (不弹出数据)
+func (w *Waiter) tryServe(key string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.served || w.cancelled {
+		return true
+	}
+	if w.ctx.Err() != nil {
+		w.cancelled = true
+		w.r.remove(w)
+		return true
+	}
+	reply, ok := w.serve(key)
+	if !ok {
+		return false
+	}
+	w.reply = reply
+	w.served = true
+	w.r.remove(w)
+	close(w.done)
+	return true
+}
+
+// remove 从所有键的队列中删除等待者
+func (r *Registry) remove(w *Waiter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range w.keys {
+		queue := r.waiters[key]
+		for i, other := range queue {
+			if other == w {
+				queue = append(queue[:i:i], queue[i+1:]...)
+				break
+			}
+		}
+		if len(queue) == 0 {
+			delete(r.waiters, key)
+		} else {
+			r.waiters[key] = queue
+		}
+	}
+}
+
+// Wait 等待被服务、超时或 ctx 取消 返回给客户端的回复 调用方不能持有写锁
+func (w *Waiter) Wait() any {
+	var timeout <-chan time.Time
+	if w.timeout > 0 {
+		timer := time.NewTimer(w.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-w.done:
+	case <-timeout:
+	case <-w.ctx.Done():
+	}
+	return w.finish(w.timeoutReply)
+}
+
+// Cancel 不等待直接结束 (不能阻塞的上下文中执行阻塞命令) 返回超时回复
+func (w *Waiter) Cancel() any {
+	return w.finish(w.timeoutReply)
+}
+
+// finish 结束等待: 已被服务时返回服务的回复, 否则注销并返回 reply
+func (w *Waiter) finish(reply any) any {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.served {
+		return w.reply
+	}
+	if !w.cancelled {
+		w.cancelled = true
+		w.r.remove(w)
+	}
+	return reply
+}
+
+// UnblockAll 以 reply 唤醒所有等待者 (节点变为从节点时)
+func (r *Registry) UnblockAll(reply any) {
+	r.mu.Lock()
+	var all []*Waiter
+	for _, queue := range r.waiters {
+		all = append(all, queue...)
+	}
+	r.mu.Unlock()
+	for _, w := range all {
+		w.mu.Lock()
+		if !w.served && !w.cancelled {
+			w.reply = reply
+			w.served = true
+			w.r.remove(w)
+			close(w.done)
+		}
+		w.mu.Unlock()
+	}
+}
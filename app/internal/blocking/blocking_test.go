@@ -0,0 +1,74 @@
+package blocking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// 按阻塞顺序服务 数据用完后剩余等待者继续阻塞
+func TestServeFIFO(t *testing.T) {
+	r := NewRegistry()
+	items := []string{}
+	serve := func(key string) (any, bool) {
+		if len(items) == 0 {
+			return nil, false
+		}
+		v := items[0]
+		items = items[1:]
+		return v, true
+	}
+	w1 := r.Block(context.Background(), []string{"q"}, 0, nil, serve)
+	w2 := r.Block(context.Background(), []string{"q", "q"}, 0, nil, serve)
+	w3 := r.Block(context.Background(), []string{"other", "q"}, 20*time.Millisecond, "timeout", serve)
+
+	items = append(items, "a", "b")
+	r.SignalKeys("q", "unwatched")
+	r.ServeReady()
+	assert.Equal(t, "a", w1.Wait())
+	assert.Equal(t, "b", w2.Wait())
+	assert.Equal(t, "timeout", w3.Wait())
+	assert.Equal(t, 0, len(r.waiters))
+
+	// 超时后写入的数据不会被已超时的等待者取走
+	items = append(items, "c")
+	r.SignalKeys("q")
+	r.ServeReady()
+	assert.Equal(t, []string{"c"}, items)
+}
+
+func TestUnblockAll(t *testing.T) {
+	r := NewRegistry()
+	w := r.Block(context.Background(), []string{"q"}, 0, nil, func(string) (any, bool) { return nil, false })
+	r.UnblockAll("unblocked")
+	assert.Equal(t, "unblocked", w.Wait())
+	assert.Equal(t, 0, len(r.waiters))
+}
+
+// 连接断开 (ctx 取消) 的等待者不取走之后写入的数据
+func TestCancelledWaiterSkipped(t *testing.T) {
+	r := NewRegistry()
+	items := []string{}
+	serve := func(key string) (any, bool) {
+		if len(items) == 0 {
+			return nil, false
+		}
+		v := items[0]
+		items = items[1:]
+		return v, true
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	gone := r.Block(ctx, []string{"q"}, 0, "timeout", serve)
+	w := r.Block(context.Background(), []string{"q"}, 0, nil, serve)
+	cancel()
+
+	items = append(items, "a", "b")
+	r.SignalKeys("q")
+	r.ServeReady()
+	assert.Equal(t, "a", w.Wait())
+	assert.Equal(t, "timeout", gone.Wait())
+	assert.Equal(t, []string{"b"}, items)
+	assert.Equal(t, 0, len(r.waiters))
+}
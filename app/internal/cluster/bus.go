@@ -0,0 +1,208 @@
+package cluster
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"time"
+)
+
+// 集群总线消息类型
+const (
+	msgPing        = "ping"
+	msgPong        = "pong"
+	msgMeet        = "meet"
+	msgFail        = "fail"
+	msgAuthRequest = "failover_auth_request"
+	msgAuthAck     = "failover_auth_ack"
+)
+
+// 总线连接的读写超时
+const busIOTimeout = 5 * time.Second
+
+// message 集群总线消息 每条消息为一行 JSON
+// 消息头携带发送者的角色、纪元与槽位图, 接收方据此更新自己的集群视图
+type message struct {
+	Type         string   `json:"type"`
+	Sender       string   `json:"sender"`
+	Port         int      `json:"port"`
+	BusPort      int      `json:"cport"`
+	Flags        int      `json:"flags"`
+	MasterID     string   `json:"master,omitempty"`
+	CurrentEpoch uint64   `json:"current_epoch"`
+	ConfigEpoch  uint64   `json:"config_epoch"` // 发送者 (从节点则为其主节点) 的配置纪元
+	Slots        []byte   `json:"slots"`        // 发送者 (从节点则为其主节点) 负责的槽
+	Offset       int64    `json:"offset"`
+	Gossip       []gossip `json:"gossip,omitempty"`
+	FailID       string   `json:"fail,omitempty"` // FAIL 消息中被判定下线的节点
+}
+
+// gossip 消息中附带的其他节点信息
+type gossip struct {
+	ID       string `json:"id"`
+	IP       string `json:"ip"`
+	Port     int    `json:"port"`
+	BusPort  int    `json:"cport"`
+	Flags    int    `json:"flags"`
+	PingSent int64  `json:"ping_sent"`
+	PongRecv int64  `json:"pong_recv"`
+}
+
+// busLink 本节点到另一个节点的总线连接 发送 PING/MEET 等消息, 读取对方的 PONG/ACK
+type busLink struct {
+	conn  net.Conn
+	ctime time.Time
+	out   chan *message
+	done  chan struct{}
+}
+
+func newBusLink(conn net.Conn) *busLink {
+	return &busLink{conn: conn, ctime: time.Now(), out: make(chan *message, 64), done: make(chan struct{})}
+}
+
+// send 不阻塞 队列满时丢弃 (下一轮 PING 会携带最新状态)
+func (l *busLink) send(msg *message) {
+	select {
+	case l.out <- msg:
+	case <-l.done:
+	default:
+	}
+}
+
+func (l *busLink) writeLoop() {
+	enc := json.NewEncoder(l.conn)
+	for {
+		select {
+		case <-l.done:
+			return
+		case msg := <-l.out:
+			l.conn.SetWriteDeadline(time.Now().Add(busIOTimeout))
+			if err := enc.Encode(msg); err != nil {
+				l.close()
+				return
+			}
+		}
+	}
+}
+
+func (l *busLink) close() {
+	select {
+	case <-l.done:
+	default:
+		close(l.done)
+		l.conn.Close()
+	}
+}
+
+// StartBus 加载 nodes.conf 并在 port+10000 上启动集群总线
+func (s *State) StartBus(confPath string, nodeTimeout time.Duration, r Replicator) error {
+	s.mu.Lock()
+	s.confPath = confPath
+	s.nodeTimeout = nodeTimeout
+	s.replicator = r
+	if err := s.load(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.dirty = true
+	myself := s.myself
+	var master *Node
+	if myself.IsSlave() {
+		master = s.nodes[myself.MasterID]
+	}
+	s.mu.Unlock()
+
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(myself.BusPort))
+	if err != nil {
+		log.Printf("Failed to bind cluster bus port %d : %s", myself.BusPort, err)
+		return err
+	}
+	log.Printf("Cluster bus started on port %d, node id: %s", myself.BusPort, myself.ID)
+
+	// 重启后继续复制 nodes.conf 中记录的主节点
+	if master != nil {
+		go r.ReplicaOf(master.IP, strconv.Itoa(master.Port))
+	}
+	go s.acceptLoop(l)
+	go s.cron()
+	return nil
+}
+
+func (s *State) acceptLoop(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			log.Printf("Cluster bus accept error: %s", err)
+			continue
+		}
+		go s.serveInbound(conn)
+	}
+}
+
+// serveInbound 处理其他节点发起的连接 需要回复的消息 (PONG / AUTH_ACK) 在同一连接上返回
+func (s *State) serveInbound(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	enc := json.NewEncoder(conn)
+	for {
+		var msg message
+		if err := dec.Decode(&msg); err != nil {
+			if err != io.EOF {
+				log.Printf("Cluster bus read error from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		reply := s.process(&msg, conn, nil)
+		if reply != nil {
+			conn.SetWriteDeadline(time.Now().Add(busIOTimeout))
+			if err := enc.Encode(reply); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// dial 建立到节点的总线连接 连接断开后由 cron 重连
+func (s *State) dial(n *Node, addr string) {
+	conn, err := net.DialTimeout("tcp", addr, busIOTimeout)
+
+	s.mu.Lock()
+	n.dialing = false
+	if err != nil || s.nodes[n.ID] != n {
+		s.mu.Unlock()
+		if conn != nil {
+			conn.Close()
+		}
+		return
+	}
+	link := newBusLink(conn)
+	n.link = link
+	n.Connected = true
+	go link.writeLoop()
+	s.sendPing(n)
+	s.mu.Unlock()
+
+	defer func() {
+		link.close()
+		s.mu.Lock()
+		if n.link == link {
+			n.link = nil
+			n.Connected = false
+		}
+		s.mu.Unlock()
+	}()
+
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	for {
+		var msg message
+		if err := dec.Decode(&msg); err != nil {
+			return
+		}
+		if reply := s.process(&msg, conn, n); reply != nil {
+			link.send(reply)
+		}
+	}
+}
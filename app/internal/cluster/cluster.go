@@ -0,0 +1,564 @@
+package cluster
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State 本节点视角的集群状态: 已知节点、槽归属以及迁移中的槽
+type State struct {
+	mu        sync.RWMutex
+	myself    *Node
+	nodes     map[string]*Node // id ->
+	owners    [SlotCount]*Node
+	migrating [SlotCount]*Node // 本节点正在迁出的槽 -> 目标节点
+	importing [SlotCount]*Node // 本节点正在迁入的槽 -> 源节点
+
+	currentEpoch        uint64
+	lastVoteEpoch       uint64 // 最近一次为从节点故障转移投票的纪元
+	requireFullCoverage bool
+
+	// 集群总线 StartBus 之前为零值
+	nodeTimeout time.Duration
+	confPath    string
+	replicator  Replicator
+	dirty       bool                 // 节点表已变化 由 cron 写入 nodes.conf
+	blacklist   map[string]time.Time // CLUSTER FORGET 的节点 到期前不通过 gossip 重新加入
+	auth        failoverAuth         // 本节点 (从节点) 发起的故障转移选举
+}
+
+// Replicator 由服务端实现 集群根据节点表切换本节点的复制关系
+type Replicator interface {
+	ReplicaOf(host, port string) error
+	ReplicaOfNoOne() error
+	ReplOffset() int64
+}
+
+// NewState 创建只包含自己的集群 本节点初始为不负责任何槽的主节点
+func NewState(ip string, port int, requireFullCoverage bool) *State {
+	myself := &Node{
+		ID:      NewNodeID(),
+		IP:      ip,
+		Port:    port,
+		BusPort: port + BusPortOffset,
+		Flags:   FlagMyself | FlagMaster,
+	}
+	return &State{
+		myself:              myself,
+		nodes:               map[string]*Node{myself.ID: myself},
+		requireFullCoverage: requireFullCoverage,
+		blacklist:           make(map[string]time.Time),
+	}
+}
+
+// BusPortOffset 集群总线端口 = 服务端口 + 10000
+const BusPortOffset = 10000
+
+// NewNodeID 40位十六进制随机节点ID
+func NewNodeID() string {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func (s *State) MyID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.myself.ID
+}
+
+// ok 集群是否可以服务请求 调用方需持有 s.mu
+func (s *State) ok() bool {
+	for _, n := range s.owners {
+		if n == nil {
+			if s.requireFullCoverage {
+				return false
+			}
+			continue
+		}
+		if n.Flags&FlagFail != 0 && s.requireFullCoverage {
+			return false
+		}
+	}
+	return true
+}
+
+// Route 判断命令的键是否由本节点处理 返回 nil 表示在本地执行, 否则返回需要回复给客户端的错误
+// asking 为客户端先发送了 ASKING; exists 判断键是否存在于本地 (迁移中的槽使用)
+func (s *State) Route(keys []string, asking bool, exists func(key string) bool) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	slot := KeySlot(keys[0])
+	for _, key := range keys[1:] {
+		if KeySlot(key) != slot {
+			return ErrCrossSlot
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.ok() {
+		return ErrClusterDown
+	}
+	owner := s.owners[slot]
+	if owner == nil {
+		return ErrSlotNotServed
+	}
+
+	// 迁移中的槽: 本地缺失的键已经 (或即将) 位于目标节点
+	missing := 0
+	if s.migrating[slot] != nil || s.importing[slot] != nil {
+		for _, key := range keys {
+			if !exists(key) {
+				missing++
+			}
+		}
+	}
+	if owner == s.myself {
+		if target := s.migrating[slot]; target != nil && missing > 0 {
+			if missing < len(keys) {
+				return ErrTryAgain
+			}
+			return redirect("ASK", slot, target)
+		}
+		return nil
+	}
+	if s.importing[slot] != nil && asking {
+		if missing > 0 && len(keys) > 1 {
+			return ErrTryAgain
+		}
+		return nil
+	}
+	return redirect("MOVED", slot, owner)
+}
+
+func redirect(kind string, slot int, n *Node) error {
+	return fmt.Errorf("%s %d %s", kind, slot, n.Addr())
+}
+
+// AddSlots CLUSTER ADDSLOTS 将未分配的槽分配给自己
+func (s *State) AddSlots(slots []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, slot := range slots {
+		if s.owners[slot] != nil {
+			return fmt.Errorf("ERR Slot %d is already busy", slot)
+		}
+	}
+	for _, slot := range slots {
+		s.assign(slot, s.myself)
+		// 迁入完成
+		s.importing[slot] = nil
+	}
+	return nil
+}
+
+// DelSlots CLUSTER DELSLOTS 槽变为未分配
+func (s *State) DelSlots(slots []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, slot := range slots {
+		if s.owners[slot] == nil {
+			return fmt.Errorf("ERR Slot %d is already unassigned", slot)
+		}
+	}
+	for _, slot := range slots {
+		s.assign(slot, nil)
+		s.migrating[slot] = nil
+		s.importing[slot] = nil
+	}
+	return nil
+}
+
+// FlushSlots CLUSTER FLUSHSLOTS 删除自己负责的所有槽
+func (s *State) FlushSlots() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for slot, n := range s.owners {
+		if n == s.myself {
+			s.assign(slot, nil)
+		}
+	}
+}
+
+// assign 调用方需持有 s.mu 写锁
+func (s *State) assign(slot int, n *Node) {
+	if old := s.owners[slot]; old != nil {
+		old.Slots.Clear(slot)
+	}
+	s.owners[slot] = n
+	if n != nil {
+		n.Slots.Set(slot)
+	}
+	s.dirty = true
+}
+
+// IsMySlot 槽是否由本节点负责
+func (s *State) IsMySlot(slot int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.owners[slot] == s.myself
+}
+
+// Nodes CLUSTER NODES 输出 迁移中的槽以 [slot->-id] / [slot-<-id] 标注在本节点的行尾
+func (s *State) Nodes() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nodesDescription(false)
+}
+
+// nodesDescription 调用方需持有 s.mu skipHandshake 为 true 时不包含握手中的节点 (nodes.conf)
+func (s *State) nodesDescription(skipHandshake bool) string {
+	var b strings.Builder
+	for _, n := range sortedNodes(s.nodes) {
+		if skipHandshake && n.Flags&FlagHandshake != 0 {
+			continue
+		}
+		var extra []string
+		if n == s.myself {
+			for slot := 0; slot < SlotCount; slot++ {
+				if t := s.migrating[slot]; t != nil {
+					extra = append(extra, fmt.Sprintf("[%d->-%s]", slot, t.ID))
+				}
+				if src := s.importing[slot]; src != nil {
+					extra = append(extra, fmt.Sprintf("[%d-<-%s]", slot, src.ID))
+				}
+			}
+		}
+		b.WriteString(n.nodesLine(extra))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// replicasOf 调用方需持有 s.mu
+func (s *State) replicasOf(master *Node) []*Node {
+	var list []*Node
+	for _, n := range sortedNodes(s.nodes) {
+		if n.MasterID == master.ID && n.Flags&FlagSlave != 0 {
+			list = append(list, n)
+		}
+	}
+	return list
+}
+
+func nodeEndpoint(n *Node) []any {
+	return []any{n.IP, int64(n.Port), n.ID}
+}
+
+// SlotsReply CLUSTER SLOTS: 每个连续区间 [start, end, [ip, port, id], 副本...]
+func (s *State) SlotsReply() []any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []any
+	for _, n := range sortedNodes(s.nodes) {
+		if !n.IsMaster() {
+			continue
+		}
+		replicas := s.replicasOf(n)
+		for _, r := range n.Slots.Ranges() {
+			entry := []any{int64(r[0]), int64(r[1]), nodeEndpoint(n)}
+			for _, rep := range replicas {
+				entry = append(entry, nodeEndpoint(rep))
+			}
+			out = append(out, entry)
+		}
+	}
+	if out == nil {
+		out = []any{}
+	}
+	return out
+}
+
+// ShardsReply CLUSTER SHARDS: 每个主节点及其副本为一个分片
+func (s *State) ShardsReply() []any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []any{}
+	for _, n := range sortedNodes(s.nodes) {
+		if !n.IsMaster() {
+			continue
+		}
+		slots := []any{}
+		for _, r := range n.Slots.Ranges() {
+			slots = append(slots, int64(r[0]), int64(r[1]))
+		}
+		nodes := []any{shardNode(n, "master")}
+		for _, rep := range s.replicasOf(n) {
+			nodes = append(nodes, shardNode(rep, "replica"))
+		}
+		out = append(out, []any{"slots", slots, "nodes", nodes})
+	}
+	return out
+}
+
+func shardNode(n *Node, role string) []any {
+	health := "online"
+	if n.Flags&(FlagFail|FlagPFail) != 0 {
+		health = "fail"
+	}
+	return []any{
+		"id", n.ID,
+		"port", int64(n.Port),
+		"ip", n.IP,
+		"endpoint", n.IP,
+		"role", role,
+		"replication-offset", n.ReplOffset,
+		"health", health,
+	}
+}
+
+// Info CLUSTER INFO
+func (s *State) Info() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	assigned, pfail, fail := 0, 0, 0
+	for _, n := range s.owners {
+		if n == nil {
+			continue
+		}
+		assigned++
+		if n.Flags&FlagFail != 0 {
+			fail++
+		} else if n.Flags&FlagPFail != 0 {
+			pfail++
+		}
+	}
+	size := 0
+	for _, n := range s.nodes {
+		if n.IsMaster() && n.Slots.Count() > 0 {
+			size++
+		}
+	}
+	state := "ok"
+	if !s.ok() {
+		state = "fail"
+	}
+	lines := []string{
+		"cluster_enabled:1",
+		"cluster_state:" + state,
+		"cluster_slots_assigned:" + strconv.Itoa(assigned),
+		"cluster_slots_ok:" + strconv.Itoa(assigned-pfail-fail),
+		"cluster_slots_pfail:" + strconv.Itoa(pfail),
+		"cluster_slots_fail:" + strconv.Itoa(fail),
+		"cluster_known_nodes:" + strconv.Itoa(len(s.nodes)),
+		"cluster_size:" + strconv.Itoa(size),
+		"cluster_current_epoch:" + strconv.FormatUint(s.currentEpoch, 10),
+		"cluster_my_epoch:" + strconv.FormatUint(s.myEpoch(), 10),
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// myEpoch 本节点 (或其主节点) 的配置纪元 调用方需持有 s.mu
+func (s *State) myEpoch() uint64 {
+	if m, ok := s.nodes[s.myself.MasterID]; ok {
+		return m.ConfigEpoch
+	}
+	return s.myself.ConfigEpoch
+}
+
+// Meet CLUSTER MEET 与 ip:port 握手 对方随后通过 gossip 介绍其他节点
+func (s *State) Meet(ip string, port, busPort int) error {
+	if net.ParseIP(ip) == nil || port <= 0 || port > 65535 || busPort <= 0 || busPort > 65535 {
+		return fmt.Errorf("ERR Invalid node address specified: %s:%d", ip, port)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.startHandshake(ip, port, busPort, true)
+	return nil
+}
+
+// Forget CLUSTER FORGET 删除节点 一段时间内不通过 gossip 重新加入
+func (s *State) Forget(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.nodes[id]
+	if !ok {
+		return fmt.Errorf("ERR Unknown node %s", id)
+	}
+	if n == s.myself {
+		return errors.New("ERR I tried hard but I can't forget myself...")
+	}
+	if s.myself.IsSlave() && s.myself.MasterID == id {
+		return errors.New("ERR Can't forget my master!")
+	}
+	s.blacklist[id] = time.Now().Add(forgetTTL)
+	s.delNode(n)
+	return nil
+}
+
+// Replicate CLUSTER REPLICATE 成为节点 id 的从节点 empty 为本地数据集是否为空
+func (s *State) Replicate(id string, empty bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.nodes[id]
+	if !ok || n.Flags&FlagHandshake != 0 {
+		return fmt.Errorf("ERR Unknown node %s", id)
+	}
+	if n == s.myself {
+		return errors.New("ERR Can't replicate myself")
+	}
+	if !n.IsMaster() {
+		return errors.New("ERR I can only replicate a master, not a replica.")
+	}
+	if s.myself.IsMaster() && (s.myself.Slots.Count() > 0 || !empty) {
+		return errors.New("ERR To set a master the node must be empty and without assigned slots.")
+	}
+	log.Printf("Configured as replica of %s (%s)", n.ID, n.Addr())
+	s.setMaster(n)
+	return nil
+}
+
+// Replicas CLUSTER REPLICAS 主节点 id 的从节点 (CLUSTER NODES 格式)
+func (s *State) Replicas(id string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n, ok := s.nodes[id]
+	if !ok {
+		return nil, fmt.Errorf("ERR Unknown node %s", id)
+	}
+	if !n.IsMaster() {
+		return nil, errors.New("ERR The specified node is not a master")
+	}
+	lines := []string{}
+	for _, r := range s.replicasOf(n) {
+		lines = append(lines, r.nodesLine(nil))
+	}
+	return lines, nil
+}
+
+// CountFailureReports CLUSTER COUNT-FAILURE-REPORTS 其他主节点报告该节点下线的次数
+func (s *State) CountFailureReports(id string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n, ok := s.nodes[id]
+	if !ok {
+		return 0, fmt.Errorf("ERR Unknown node %s", id)
+	}
+	return len(n.failReports), nil
+}
+
+// SetSlotMigrating CLUSTER SETSLOT <slot> MIGRATING <id> 本节点负责的槽开始迁出到 id
+func (s *State) SetSlotMigrating(slot int, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.myself.IsSlave() {
+		return ErrSetSlotReplica
+	}
+	if s.owners[slot] != s.myself {
+		return fmt.Errorf("ERR I'm not the owner of hash slot %d", slot)
+	}
+	n, err := s.setSlotTarget(id)
+	if err != nil {
+		return err
+	}
+	s.migrating[slot] = n
+	s.dirty = true
+	return nil
+}
+
+// SetSlotImporting CLUSTER SETSLOT <slot> IMPORTING <id> 从 id 迁入槽
+func (s *State) SetSlotImporting(slot int, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.myself.IsSlave() {
+		return ErrSetSlotReplica
+	}
+	if s.owners[slot] == s.myself {
+		return fmt.Errorf("ERR I'm already the owner of hash slot %d", slot)
+	}
+	n, err := s.setSlotTarget(id)
+	if err != nil {
+		return err
+	}
+	s.importing[slot] = n
+	s.dirty = true
+	return nil
+}
+
+// setSlotTarget 迁移的另一方必须是已知的其他主节点 调用方需持有 s.mu
+func (s *State) setSlotTarget(id string) (*Node, error) {
+	n, ok := s.nodes[id]
+	if !ok || n.Flags&FlagHandshake != 0 {
+		return nil, fmt.Errorf("ERR I don't know about node %s", id)
+	}
+	if n == s.myself {
+		return nil, errors.New("ERR Target node is myself")
+	}
+	if !n.IsMaster() {
+		return nil, errors.New("ERR Target node is not a master")
+	}
+	return n, nil
+}
+
+// SetSlotStable CLUSTER SETSLOT <slot> STABLE 清除迁移状态
+func (s *State) SetSlotStable(slot int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.myself.IsSlave() {
+		return ErrSetSlotReplica
+	}
+	s.migrating[slot] = nil
+	s.importing[slot] = nil
+	s.dirty = true
+	return nil
+}
+
+// SetSlotNode CLUSTER SETSLOT <slot> NODE <id> 迁移完成后将槽分配给 id
+// keys 为本节点在该槽中的键数; 迁入方接管槽时递增配置纪元, 使新的归属在集群中胜出
+func (s *State) SetSlotNode(slot int, id string, keys int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.myself.IsSlave() {
+		return ErrSetSlotReplica
+	}
+	n, ok := s.nodes[id]
+	if !ok || n.Flags&FlagHandshake != 0 {
+		return fmt.Errorf("ERR Unknown node %s", id)
+	}
+	if !n.IsMaster() {
+		return errors.New("ERR Target node is not a master")
+	}
+	if s.owners[slot] == s.myself && n != s.myself && keys > 0 {
+		return fmt.Errorf("ERR Can't assign hashslot %d to a different node while I still hold keys for this hash slot.", slot)
+	}
+	if s.migrating[slot] != nil && n != s.myself {
+		s.migrating[slot] = nil
+	}
+	if s.importing[slot] != nil && n == s.myself {
+		s.importing[slot] = nil
+		s.bumpConfigEpoch()
+	}
+	s.assign(slot, n)
+	log.Printf("Slot %d assigned to %s", slot, n.ID)
+	s.broadcast(s.header(msgPong))
+	return nil
+}
+
+// bumpConfigEpoch 不经过投票直接获取新的配置纪元 (槽迁移完成)
+// 本节点的纪元已是集群中唯一最大时不需要递增 调用方需持有 s.mu
+func (s *State) bumpConfigEpoch() {
+	maxEpoch := uint64(0)
+	for _, n := range s.nodes {
+		if n != s.myself {
+			maxEpoch = max(maxEpoch, n.ConfigEpoch)
+		}
+	}
+	if s.myself.ConfigEpoch == 0 || s.myself.ConfigEpoch <= maxEpoch {
+		s.currentEpoch++
+		s.myself.ConfigEpoch = s.currentEpoch
+		s.dirty = true
+		log.Printf("New config epoch set to %d", s.myself.ConfigEpoch)
+	}
+}
@@ -0,0 +1,77 @@
+package cluster
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestKeySlot(t *testing.T) {
+	// 与 Redis CLUSTER KEYSLOT 结果一致
+	assert.Equal(t, 11058, KeySlot("somekey"))
+	assert.Equal(t, 2515, KeySlot("foo{hash_tag}"))
+	assert.Equal(t, 12739, KeySlot("123456789"))
+	assert.Equal(t, KeySlot("{user1000}.following"), KeySlot("{user1000}.followers"))
+	// 空 tag 对整个键计算
+	assert.Equal(t, KeySlot("{}foo"), int(crc16("{}foo"))&(SlotCount-1))
+}
+
+func TestRoute(t *testing.T) {
+	s := NewState("127.0.0.1", 7000, true)
+	other := &Node{ID: NewNodeID(), IP: "127.0.0.1", Port: 7001, Flags: FlagMaster}
+	s.nodes[other.ID] = other
+
+	none := func(string) bool { return false }
+	assert.Equal(t, ErrClusterDown, s.Route([]string{"foo"}, false, none))
+
+	fooSlot := KeySlot("foo")
+	for slot := 0; slot < SlotCount; slot++ {
+		if slot == fooSlot {
+			s.assign(slot, other)
+		} else {
+			s.assign(slot, s.myself)
+		}
+	}
+	assert.Equal(t, nil, s.Route([]string{"bar"}, false, none))
+	assert.Equal(t, "MOVED 12182 127.0.0.1:7001", s.Route([]string{"foo"}, false, none).Error())
+	assert.Equal(t, ErrCrossSlot, s.Route([]string{"bar", "foo"}, false, none))
+
+	// 迁出中的槽: 本地不存在的键 ASK 到目标节点
+	barSlot := KeySlot("bar")
+	s.migrating[barSlot] = other
+	assert.Equal(t, "ASK 5061 127.0.0.1:7001", s.Route([]string{"bar"}, false, none).Error())
+	exists := func(string) bool { return true }
+	assert.Equal(t, nil, s.Route([]string{"bar"}, false, exists))
+
+	// 迁入中的槽: 只有 ASKING 之后才在本地执行
+	s.importing[fooSlot] = other
+	assert.Equal(t, nil, s.Route([]string{"foo"}, true, none))
+	assert.Equal(t, "MOVED 12182 127.0.0.1:7001", s.Route([]string{"foo"}, false, none).Error())
+}
+
+func TestNodesConfRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes.conf")
+	s := NewState("127.0.0.1", 7000, true)
+	s.confPath = path
+	other := &Node{ID: NewNodeID(), IP: "127.0.0.1", Port: 7001, BusPort: 17001, Flags: FlagMaster, ConfigEpoch: 2}
+	replica := &Node{ID: NewNodeID(), IP: "127.0.0.1", Port: 7002, BusPort: 17002, Flags: FlagSlave, MasterID: other.ID}
+	s.nodes[other.ID] = other
+	s.nodes[replica.ID] = replica
+	s.AddSlots([]int{0, 1, 2, 100})
+	s.assign(200, other)
+	s.migrating[1] = other
+	s.currentEpoch, s.lastVoteEpoch = 3, 2
+	assert.Equal(t, nil, s.save())
+
+	loaded := NewState("127.0.0.1", 7000, true)
+	loaded.confPath = path
+	assert.Equal(t, nil, loaded.load())
+	assert.Equal(t, s.MyID(), loaded.MyID())
+	assert.Equal(t, s.Nodes(), loaded.Nodes())
+	assert.Equal(t, uint64(3), loaded.currentEpoch)
+	assert.Equal(t, uint64(2), loaded.lastVoteEpoch)
+	assert.Equal(t, other.ID, loaded.migrating[1].ID)
+	assert.Equal(t, true, loaded.IsMySlot(100))
+	assert.Equal(t, other.ID, loaded.nodes[replica.ID].MasterID)
+}
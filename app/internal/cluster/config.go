@@ -0,0 +1,182 @@
+package cluster
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// save 将节点表写入 nodes.conf 格式与 CLUSTER NODES 相同, 最后一行记录纪元
+// 先写临时文件再重命名, 避免崩溃时留下不完整的配置 调用方需持有 s.mu
+func (s *State) save() error {
+	s.dirty = false
+	if s.confPath == "" {
+		return nil
+	}
+	content := s.nodesDescription(true) +
+		fmt.Sprintf("vars currentEpoch %d lastVoteEpoch %d\n", s.currentEpoch, s.lastVoteEpoch)
+	tmp := s.confPath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.confPath)
+}
+
+// SaveConfig CLUSTER SAVECONFIG
+func (s *State) SaveConfig() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save()
+}
+
+// load 读取 nodes.conf 文件不存在时保留新生成的节点ID 调用方需持有 s.mu
+func (s *State) load() error {
+	f, err := os.Open(s.confPath)
+	if os.IsNotExist(err) {
+		log.Printf("No cluster config %s, starting as new node %s", s.confPath, s.myself.ID)
+		return os.MkdirAll(filepath.Dir(s.confPath), 0755)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var lines [][]string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "vars" {
+			for i := 1; i+1 < len(fields); i += 2 {
+				v, _ := strconv.ParseUint(fields[i+1], 10, 64)
+				switch fields[i] {
+				case "currentEpoch":
+					s.currentEpoch = v
+				case "lastVoteEpoch":
+					s.lastVoteEpoch = v
+				}
+			}
+			continue
+		}
+		if len(fields) < 8 {
+			return fmt.Errorf("invalid cluster config line: %s", sc.Text())
+		}
+		lines = append(lines, fields)
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	// 第一遍创建节点 第二遍解析引用其他节点的槽迁移状态
+	nodes := make(map[string]*Node, len(lines))
+	var myself *Node
+	for _, fields := range lines {
+		n, err := parseNodeLine(fields)
+		if err != nil {
+			return err
+		}
+		if n.Flags&FlagMyself != 0 {
+			// 地址以当前配置为准
+			n.IP, n.Port, n.BusPort = s.myself.IP, s.myself.Port, s.myself.BusPort
+			myself = n
+		}
+		nodes[n.ID] = n
+	}
+	if myself == nil {
+		return fmt.Errorf("cluster config %s has no myself node", s.confPath)
+	}
+
+	s.myself = myself
+	s.nodes = nodes
+	s.owners = [SlotCount]*Node{}
+	for _, fields := range lines {
+		n := nodes[fields[0]]
+		for _, spec := range fields[8:] {
+			if err := s.loadSlotSpec(n, spec); err != nil {
+				return err
+			}
+		}
+	}
+	log.Printf("Loaded cluster config %s: %d nodes, current epoch %d, my id %s", s.confPath, len(nodes), s.currentEpoch, myself.ID)
+	return nil
+}
+
+// parseNodeLine <id> <ip:port@cport> <flags> <master> <ping-sent> <pong-recv> <config-epoch> <link-state>
+func parseNodeLine(fields []string) (*Node, error) {
+	n := &Node{ID: fields[0]}
+	addr, cport, ok := strings.Cut(fields[1], "@")
+	idx := strings.LastIndex(addr, ":")
+	if !ok || idx < 0 {
+		return nil, fmt.Errorf("invalid node address %s", fields[1])
+	}
+	n.IP = addr[:idx]
+	var err error
+	if n.Port, err = strconv.Atoi(addr[idx+1:]); err != nil {
+		return nil, err
+	}
+	if n.BusPort, err = strconv.Atoi(cport); err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(fields[2], ",") {
+		for _, f := range flagNames {
+			if f.name == name {
+				n.Flags |= f.flag
+			}
+		}
+	}
+	// 重启后重新探测可达性
+	n.Flags &^= FlagPFail | FlagHandshake
+	if fields[3] != "-" {
+		n.MasterID = fields[3]
+	}
+	if n.ConfigEpoch, err = strconv.ParseUint(fields[6], 10, 64); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// loadSlotSpec 槽区间 "start[-end]" 或迁移状态 "[slot->-id]" / "[slot-<-id]"
+func (s *State) loadSlotSpec(n *Node, spec string) error {
+	if strings.HasPrefix(spec, "[") {
+		body := strings.Trim(spec, "[]")
+		if slotStr, id, ok := strings.Cut(body, "->-"); ok {
+			slot, err := strconv.Atoi(slotStr)
+			if err == nil && s.nodes[id] != nil {
+				s.migrating[slot] = s.nodes[id]
+			}
+			return err
+		}
+		if slotStr, id, ok := strings.Cut(body, "-<-"); ok {
+			slot, err := strconv.Atoi(slotStr)
+			if err == nil && s.nodes[id] != nil {
+				s.importing[slot] = s.nodes[id]
+			}
+			return err
+		}
+		return fmt.Errorf("invalid slot spec %s", spec)
+	}
+	startStr, endStr, isRange := strings.Cut(spec, "-")
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return err
+	}
+	end := start
+	if isRange {
+		if end, err = strconv.Atoi(endStr); err != nil {
+			return err
+		}
+	}
+	if start < 0 || end >= SlotCount || start > end {
+		return fmt.Errorf("invalid slot range %s", spec)
+	}
+	for slot := start; slot <= end; slot++ {
+		s.assign(slot, n)
+	}
+	return nil
+}
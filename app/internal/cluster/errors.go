@@ -0,0 +1,14 @@
+package cluster
+
+import "errors"
+
+// 客户端可见的集群错误
+var (
+	ErrCrossSlot     = errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+	ErrClusterDown   = errors.New("CLUSTERDOWN The cluster is down")
+	ErrSlotNotServed = errors.New("CLUSTERDOWN Hash slot not served")
+	ErrTryAgain      = errors.New("TRYAGAIN Multiple keys request during rehashing of slot")
+	ErrInvalidSlot   = errors.New("ERR Invalid or out of range slot")
+
+	ErrSetSlotReplica = errors.New("ERR Please use SETSLOT only with masters.")
+)
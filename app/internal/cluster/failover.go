@@ -0,0 +1,137 @@
+package cluster
+
+import (
+	"log"
+	"math/rand"
+	"time"
+)
+
+// failoverAuth 从节点在主节点 FAIL 后发起的选举
+type failoverAuth struct {
+	startTime time.Time // 延迟结束、可以发起选举的时间 零值表示未计划
+	epoch     uint64    // 发起请求时的 currentEpoch
+	sent      bool
+	acks      int
+	rank      int
+}
+
+// handleReplicaFailover 主节点 FAIL 后: 按复制偏移量排名延迟 -> 递增纪元请求授权 -> 获得多数主节点投票后晋升
+// 调用方需持有 s.mu
+func (s *State) handleReplicaFailover(now time.Time) {
+	my := s.myself
+	if !my.IsSlave() {
+		return
+	}
+	master := s.nodes[my.MasterID]
+	if master == nil || master.Flags&FlagFail == 0 || master.Slots.Count() == 0 {
+		s.auth = failoverAuth{}
+		return
+	}
+
+	timeout := max(2*s.nodeTimeout, 2*time.Second)
+	f := &s.auth
+	if !f.startTime.IsZero() && now.Sub(f.startTime) > 2*timeout {
+		// 上一轮选举超时 重新计划
+		f.startTime = time.Time{}
+	}
+	if f.startTime.IsZero() {
+		f.rank = s.replicaRank()
+		delay := 500*time.Millisecond + time.Duration(rand.Int63n(int64(500*time.Millisecond))) + time.Duration(f.rank)*time.Second
+		*f = failoverAuth{startTime: now.Add(delay), rank: f.rank}
+		log.Printf("Start of election delayed for %s (rank #%d, offset %d)", delay, f.rank, s.myOffset())
+		return
+	}
+	if now.Before(f.startTime) || now.Sub(f.startTime) > timeout {
+		return
+	}
+
+	if !f.sent {
+		s.currentEpoch++
+		f.epoch = s.currentEpoch
+		f.sent = true
+		s.dirty = true
+		log.Printf("Starting a failover election for epoch %d", f.epoch)
+		s.broadcast(s.header(msgAuthRequest))
+		return
+	}
+
+	if f.acks < s.quorum() {
+		return
+	}
+	log.Printf("Failover election won, promoting myself to master of epoch %d", f.epoch)
+	my.Flags = my.Flags&^FlagSlave | FlagMaster
+	my.MasterID = ""
+	for slot, owner := range s.owners {
+		if owner == master {
+			s.assign(slot, my)
+		}
+	}
+	if my.ConfigEpoch < f.epoch {
+		my.ConfigEpoch = f.epoch
+	}
+	s.auth = failoverAuth{}
+	s.dirty = true
+	if s.replicator != nil {
+		go s.replicator.ReplicaOfNoOne()
+	}
+	// 立即通知其他节点新的槽归属
+	s.broadcast(s.header(msgPong))
+}
+
+// replicaRank 同一主节点下复制偏移量大于本节点的从节点数 调用方需持有 s.mu
+func (s *State) replicaRank() int {
+	offset := s.myOffset()
+	rank := 0
+	for _, n := range s.nodes {
+		if n != s.myself && n.IsSlave() && n.MasterID == s.myself.MasterID && n.ReplOffset > offset {
+			rank++
+		}
+	}
+	return rank
+}
+
+func (s *State) myOffset() int64 {
+	if s.replicator == nil {
+		return 0
+	}
+	return s.replicator.ReplOffset()
+}
+
+// vote 主节点处理故障转移授权请求 每个纪元最多投一票 调用方需持有 s.mu
+func (s *State) vote(sender *Node, msg *message, now time.Time) *message {
+	my := s.myself
+	if !my.IsMaster() || my.Slots.Count() == 0 {
+		return nil
+	}
+	// process 已将 currentEpoch 更新为请求中的纪元
+	if msg.CurrentEpoch < s.currentEpoch || s.lastVoteEpoch == s.currentEpoch {
+		return nil
+	}
+	if !sender.IsSlave() {
+		return nil
+	}
+	master := s.nodes[sender.MasterID]
+	if master == nil || master.Flags&FlagFail == 0 {
+		log.Printf("Failover auth denied to %s: its master is up", sender.ID)
+		return nil
+	}
+	if now.Sub(master.votedTime) < 2*s.nodeTimeout {
+		log.Printf("Failover auth denied to %s: can't vote for this master before %s", sender.ID, master.votedTime.Add(2*s.nodeTimeout).Sub(now))
+		return nil
+	}
+	// 请求接管的槽不能已经被更新的配置占有
+	var claimed Slots
+	copy(claimed[:], msg.Slots)
+	for slot := 0; slot < SlotCount; slot++ {
+		if owner := s.owners[slot]; claimed.Has(slot) && owner != nil && owner.ConfigEpoch > msg.ConfigEpoch {
+			log.Printf("Failover auth denied to %s: slot %d epoch %d > reqEpoch %d", sender.ID, slot, owner.ConfigEpoch, msg.ConfigEpoch)
+			return nil
+		}
+	}
+
+	s.lastVoteEpoch = s.currentEpoch
+	master.votedTime = now
+	s.dirty = true
+	log.Printf("Failover auth granted to %s for epoch %d", sender.ID, s.currentEpoch)
+	return s.header(msgAuthAck)
+}
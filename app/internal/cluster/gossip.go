@@ -0,0 +1,463 @@
+package cluster
+
+import (
+	"log"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+)
+
+// CLUSTER FORGET 之后节点在黑名单中保留的时间
+const forgetTTL = 60 * time.Second
+
+// header 以本节点当前视图构造消息头 调用方需持有 s.mu
+// 从节点携带其主节点的配置纪元与槽位图
+func (s *State) header(typ string) *message {
+	my := s.myself
+	master := my
+	if m, ok := s.nodes[my.MasterID]; ok && my.IsSlave() {
+		master = m
+	}
+	slots := master.Slots
+	msg := &message{
+		Type:         typ,
+		Sender:       my.ID,
+		Port:         my.Port,
+		BusPort:      my.BusPort,
+		Flags:        my.Flags & (FlagMaster | FlagSlave),
+		MasterID:     my.MasterID,
+		CurrentEpoch: s.currentEpoch,
+		ConfigEpoch:  master.ConfigEpoch,
+		Slots:        slots[:],
+	}
+	if s.replicator != nil {
+		msg.Offset = s.replicator.ReplOffset()
+	}
+	return msg
+}
+
+// gossipSection 随机选取部分节点附在 PING/PONG 中 疑似下线的节点总是包含在内
+func (s *State) gossipSection(to *Node) []gossip {
+	var candidates, failing []*Node
+	for _, n := range s.nodes {
+		if n == s.myself || n == to || n.Flags&(FlagHandshake|FlagNoAddr) != 0 {
+			continue
+		}
+		if n.Flags&FlagPFail != 0 {
+			failing = append(failing, n)
+			continue
+		}
+		candidates = append(candidates, n)
+	}
+	wanted := max(3, len(s.nodes)/10)
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	if len(candidates) > wanted {
+		candidates = candidates[:wanted]
+	}
+	out := make([]gossip, 0, len(candidates)+len(failing))
+	for _, n := range append(candidates, failing...) {
+		out = append(out, gossip{
+			ID:       n.ID,
+			IP:       n.IP,
+			Port:     n.Port,
+			BusPort:  n.BusPort,
+			Flags:    n.Flags &^ FlagMyself,
+			PingSent: n.PingSent,
+			PongRecv: n.PongRecv,
+		})
+	}
+	return out
+}
+
+// sendPing 通过本节点发起的连接发送 PING (握手节点为 MEET) 调用方需持有 s.mu
+func (s *State) sendPing(n *Node) {
+	if n.link == nil {
+		return
+	}
+	typ := msgPing
+	if n.meet {
+		typ = msgMeet
+	}
+	msg := s.header(typ)
+	msg.Gossip = s.gossipSection(n)
+	n.link.send(msg)
+	if n.PingSent == 0 {
+		n.PingSent = time.Now().UnixMilli()
+	}
+}
+
+// broadcast 发送给所有已建立连接的节点 调用方需持有 s.mu
+func (s *State) broadcast(msg *message) {
+	for _, n := range s.nodes {
+		if n.link != nil && n.Flags&FlagHandshake == 0 {
+			n.link.send(msg)
+		}
+	}
+}
+
+// process 处理一条总线消息 返回需要在同一连接上回复的消息
+// linkNode 为本节点发起的连接对应的节点, 对方发起的连接为 nil
+func (s *State) process(msg *message, conn net.Conn, linkNode *Node) *message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+
+	sender := s.nodes[msg.Sender]
+	if sender != nil && sender.Flags&FlagHandshake != 0 {
+		sender = nil
+	}
+	if sender != nil && msg.CurrentEpoch > s.currentEpoch {
+		s.currentEpoch = msg.CurrentEpoch
+		s.dirty = true
+	}
+
+	// 握手完成: 用对方的真实ID替换握手时的临时ID
+	if msg.Type == msgPong && linkNode != nil && linkNode.Flags&FlagHandshake != 0 {
+		if sender != nil {
+			// 已经通过其他途径认识该节点
+			s.delNode(linkNode)
+			return nil
+		}
+		delete(s.nodes, linkNode.ID)
+		log.Printf("Cluster handshake with %s completed, node id: %s", linkNode.BusAddr(), msg.Sender)
+		linkNode.ID = msg.Sender
+		linkNode.Flags &^= FlagHandshake
+		linkNode.meet = false
+		s.nodes[linkNode.ID] = linkNode
+		s.dirty = true
+		sender = linkNode
+	}
+
+	if msg.Type == msgMeet && sender == nil {
+		// 被 CLUSTER MEET 的一方 通过对方连接的本地地址得知自己的IP
+		if host, _, err := net.SplitHostPort(conn.LocalAddr().String()); err == nil {
+			s.myself.IP = host
+		}
+		if host, _, err := net.SplitHostPort(conn.RemoteAddr().String()); err == nil {
+			s.startHandshake(host, msg.Port, msg.BusPort, false)
+		}
+	}
+
+	var reply *message
+	switch msg.Type {
+	case msgPing, msgMeet:
+		reply = s.header(msgPong)
+		reply.Gossip = s.gossipSection(sender)
+	}
+	if sender == nil {
+		return reply
+	}
+
+	if msg.Type == msgPong {
+		sender.PongRecv = now.UnixMilli()
+		if linkNode == sender {
+			sender.PingSent = 0
+		}
+		if sender.Flags&FlagPFail != 0 {
+			sender.Flags &^= FlagPFail
+			log.Printf("Cluster node %s is reachable again", sender.ID)
+		}
+		s.clearFailureIfNeeded(sender, now)
+	}
+
+	s.updateRole(sender, msg)
+	sender.ReplOffset = msg.Offset
+	if sender.IsMaster() {
+		if sender.ConfigEpoch != msg.ConfigEpoch {
+			sender.ConfigEpoch = msg.ConfigEpoch
+			s.dirty = true
+		}
+		var claimed Slots
+		copy(claimed[:], msg.Slots)
+		s.updateSlots(sender, &claimed, msg.ConfigEpoch)
+		s.handleEpochCollision(sender)
+	}
+
+	switch msg.Type {
+	case msgPing, msgPong, msgMeet:
+		s.processGossip(sender, msg.Gossip, now)
+	case msgFail:
+		if n := s.nodes[msg.FailID]; n != nil && n != s.myself && n.Flags&FlagFail == 0 {
+			log.Printf("FAIL message received from %s about %s", sender.ID, n.ID)
+			n.Flags = n.Flags&^FlagPFail | FlagFail
+			n.failTime = now
+			s.dirty = true
+		}
+	case msgAuthRequest:
+		return s.vote(sender, msg, now)
+	case msgAuthAck:
+		if sender.IsMaster() && sender.Slots.Count() > 0 && msg.CurrentEpoch >= s.auth.epoch {
+			s.auth.acks++
+			log.Printf("Failover auth granted to me by %s, votes: %d", sender.ID, s.auth.acks)
+		}
+	}
+	return reply
+}
+
+// updateRole 根据消息头更新发送者的主从角色 调用方需持有 s.mu
+func (s *State) updateRole(sender *Node, msg *message) {
+	if msg.Flags&FlagMaster != 0 && !sender.IsMaster() {
+		sender.Flags = sender.Flags&^FlagSlave | FlagMaster
+		sender.MasterID = ""
+		s.dirty = true
+		log.Printf("Cluster node %s is now a master", sender.ID)
+	}
+	if msg.Flags&FlagSlave != 0 && (!sender.IsSlave() || sender.MasterID != msg.MasterID) {
+		if sender.IsMaster() {
+			// 主节点降级为从节点 其负责的槽等待新主节点的声明
+			for slot, owner := range s.owners {
+				if owner == sender {
+					s.assign(slot, nil)
+				}
+			}
+		}
+		sender.Flags = sender.Flags&^FlagMaster | FlagSlave
+		sender.MasterID = msg.MasterID
+		s.dirty = true
+		log.Printf("Cluster node %s is now a replica of %s", sender.ID, msg.MasterID)
+	}
+}
+
+// updateSlots 配置纪元更大的声明覆盖本地的槽归属 调用方需持有 s.mu
+// 本节点 (或本节点的主节点) 失去全部槽时改为复制新的负责节点
+func (s *State) updateSlots(sender *Node, claimed *Slots, epoch uint64) {
+	myMaster := s.myself
+	if s.myself.IsSlave() {
+		myMaster = s.nodes[s.myself.MasterID]
+	}
+	lost := 0
+	for slot := 0; slot < SlotCount; slot++ {
+		if !claimed.Has(slot) {
+			continue
+		}
+		owner := s.owners[slot]
+		if owner == sender || s.importing[slot] != nil {
+			continue
+		}
+		if owner != nil && owner.ConfigEpoch >= epoch {
+			continue
+		}
+		if owner != nil && owner == myMaster {
+			lost++
+		}
+		s.assign(slot, sender)
+	}
+	if lost == 0 || myMaster == nil || myMaster.Slots.Count() > 0 {
+		return
+	}
+	log.Printf("Lost all my slots to %s (config epoch %d), reconfiguring as its replica", sender.ID, epoch)
+	s.setMaster(sender)
+}
+
+// setMaster 本节点成为 master 的从节点 调用方需持有 s.mu
+func (s *State) setMaster(master *Node) {
+	my := s.myself
+	my.Flags = my.Flags&^FlagMaster | FlagSlave
+	my.MasterID = master.ID
+	s.auth = failoverAuth{}
+	s.dirty = true
+	if s.replicator != nil {
+		go s.replicator.ReplicaOf(master.IP, strconv.Itoa(master.Port))
+	}
+}
+
+// handleEpochCollision 两个主节点的配置纪元相同时 ID 较小的一方递增纪元 调用方需持有 s.mu
+func (s *State) handleEpochCollision(sender *Node) {
+	my := s.myself
+	if !my.IsMaster() || sender.ConfigEpoch != my.ConfigEpoch || sender.ID <= my.ID {
+		return
+	}
+	s.currentEpoch++
+	my.ConfigEpoch = s.currentEpoch
+	s.dirty = true
+	log.Printf("Config epoch collision with %s, my config epoch is now %d", sender.ID, my.ConfigEpoch)
+}
+
+// processGossip 收集主节点的故障报告, 并与未知节点握手 调用方需持有 s.mu
+func (s *State) processGossip(sender *Node, entries []gossip, now time.Time) {
+	for _, g := range entries {
+		n := s.nodes[g.ID]
+		if n == s.myself {
+			continue
+		}
+		if n != nil {
+			if n.Flags&FlagHandshake != 0 || !sender.IsMaster() {
+				continue
+			}
+			if g.Flags&(FlagPFail|FlagFail) != 0 {
+				if n.failReports == nil {
+					n.failReports = make(map[string]time.Time)
+				}
+				n.failReports[sender.ID] = now
+				s.markFailIfNeeded(n, now)
+			} else {
+				delete(n.failReports, sender.ID)
+			}
+			continue
+		}
+		if g.Flags&FlagNoAddr != 0 {
+			continue
+		}
+		if until, ok := s.blacklist[g.ID]; ok && now.Before(until) {
+			continue
+		}
+		s.startHandshake(g.IP, g.Port, g.BusPort, false)
+	}
+}
+
+// quorum 判定 FAIL 或故障转移授权所需的主节点数 (负责槽的主节点过半)
+func (s *State) quorum() int {
+	size := 0
+	for _, n := range s.nodes {
+		if n.IsMaster() && n.Slots.Count() > 0 {
+			size++
+		}
+	}
+	return size/2 + 1
+}
+
+// markFailIfNeeded 本节点认为 PFAIL 且足够多的主节点报告时标记为 FAIL 并广播 调用方需持有 s.mu
+func (s *State) markFailIfNeeded(n *Node, now time.Time) {
+	if n.Flags&FlagPFail == 0 || n.Flags&FlagFail != 0 {
+		return
+	}
+	failures := len(n.failReports)
+	if s.myself.IsMaster() {
+		failures++
+	}
+	if failures < s.quorum() {
+		return
+	}
+	log.Printf("Marking node %s as failing (quorum reached)", n.ID)
+	n.Flags = n.Flags&^FlagPFail | FlagFail
+	n.failTime = now
+	s.dirty = true
+	msg := s.header(msgFail)
+	msg.FailID = n.ID
+	s.broadcast(msg)
+}
+
+// clearFailureIfNeeded 从节点或不负责槽的主节点恢复后立即清除 FAIL
+// 负责槽的主节点在一段时间内没有被故障转移时同样清除 调用方需持有 s.mu
+func (s *State) clearFailureIfNeeded(n *Node, now time.Time) {
+	if n.Flags&FlagFail == 0 {
+		return
+	}
+	if n.IsSlave() || n.Slots.Count() == 0 || now.Sub(n.failTime) > 2*s.nodeTimeout {
+		log.Printf("Clear FAIL state for node %s", n.ID)
+		n.Flags &^= FlagFail
+		s.dirty = true
+	}
+}
+
+// startHandshake 添加一个临时ID的握手节点 由 cron 建立连接 调用方需持有 s.mu
+func (s *State) startHandshake(ip string, port, busPort int, meet bool) {
+	for _, n := range s.nodes {
+		if n.Flags&FlagHandshake != 0 && n.IP == ip && n.Port == port && n.BusPort == busPort {
+			return
+		}
+	}
+	n := &Node{
+		ID:             NewNodeID(),
+		IP:             ip,
+		Port:           port,
+		BusPort:        busPort,
+		Flags:          FlagHandshake,
+		handshakeStart: time.Now(),
+		meet:           meet,
+	}
+	s.nodes[n.ID] = n
+	log.Printf("Start handshake with %s:%d", ip, busPort)
+}
+
+// delNode 从节点表中删除 调用方需持有 s.mu
+func (s *State) delNode(n *Node) {
+	for slot := 0; slot < SlotCount; slot++ {
+		if s.owners[slot] == n {
+			s.assign(slot, nil)
+		}
+		if s.migrating[slot] == n {
+			s.migrating[slot] = nil
+		}
+		if s.importing[slot] == n {
+			s.importing[slot] = nil
+		}
+	}
+	for _, other := range s.nodes {
+		delete(other.failReports, n.ID)
+	}
+	delete(s.nodes, n.ID)
+	if n.link != nil {
+		n.link.close()
+	}
+	s.dirty = true
+}
+
+// cron 每 100ms 维护连接、发送 PING、检测故障并持久化节点表
+func (s *State) cron() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.tick()
+	}
+}
+
+func (s *State) tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	nowMs := now.UnixMilli()
+	timeoutMs := s.nodeTimeout.Milliseconds()
+	handshakeTimeout := max(s.nodeTimeout, time.Second)
+
+	for _, n := range s.nodes {
+		if n == s.myself {
+			continue
+		}
+		if n.Flags&FlagHandshake != 0 && now.Sub(n.handshakeStart) > handshakeTimeout {
+			log.Printf("Handshake with %s timed out", n.BusAddr())
+			s.delNode(n)
+			continue
+		}
+		if n.link == nil {
+			if n.PingSent == 0 && n.Flags&FlagHandshake == 0 {
+				// 连接断开期间视为有一个未回复的 PING
+				n.PingSent = nowMs
+			}
+			if !n.dialing {
+				n.dialing = true
+				go s.dial(n, n.BusAddr())
+			}
+		} else if n.PingSent == 0 && nowMs-n.PongRecv >= 1000 {
+			s.sendPing(n)
+		} else if n.PingSent > 0 && nowMs-n.PingSent > timeoutMs/2 && now.Sub(n.link.ctime) > s.nodeTimeout/2 {
+			// 长时间没有 PONG 重建连接
+			n.link.close()
+		}
+
+		for id, t := range n.failReports {
+			if now.Sub(t) > 2*s.nodeTimeout {
+				delete(n.failReports, id)
+			}
+		}
+		if n.PingSent > 0 && nowMs-n.PingSent > timeoutMs && !n.failing() && n.Flags&FlagHandshake == 0 {
+			log.Printf("Cluster node %s might be failing (no PONG for %dms)", n.ID, nowMs-n.PingSent)
+			n.Flags |= FlagPFail
+		}
+		s.markFailIfNeeded(n, now)
+	}
+	for id, until := range s.blacklist {
+		if now.After(until) {
+			delete(s.blacklist, id)
+		}
+	}
+
+	s.handleReplicaFailover(now)
+
+	if s.dirty {
+		if err := s.save(); err != nil {
+			log.Printf("Save cluster config Error: %s", err)
+		}
+	}
+}
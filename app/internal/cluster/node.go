@@ -0,0 +1,177 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 节点标志 (CLUSTER NODES 第三列)
+const (
+	FlagMyself = 1 << iota
+	FlagMaster
+	FlagSlave
+	FlagPFail // 本节点认为其不可达
+	FlagFail  // 多数主节点认为其不可达
+	FlagHandshake
+	FlagNoAddr
+)
+
+var flagNames = []struct {
+	flag int
+	name string
+}{
+	{FlagMyself, "myself"},
+	{FlagMaster, "master"},
+	{FlagSlave, "slave"},
+	{FlagPFail, "fail?"},
+	{FlagFail, "fail"},
+	{FlagHandshake, "handshake"},
+	{FlagNoAddr, "noaddr"},
+}
+
+// Slots 槽位图
+type Slots [SlotCount / 8]byte
+
+func (s *Slots) Has(slot int) bool {
+	return s[slot/8]&(1<<(slot%8)) != 0
+}
+
+func (s *Slots) Set(slot int) {
+	s[slot/8] |= 1 << (slot % 8)
+}
+
+func (s *Slots) Clear(slot int) {
+	s[slot/8] &^= 1 << (slot % 8)
+}
+
+func (s *Slots) Count() int {
+	n := 0
+	for slot := 0; slot < SlotCount; slot++ {
+		if s.Has(slot) {
+			n++
+		}
+	}
+	return n
+}
+
+// Ranges 连续的槽区间 [start, end]
+func (s *Slots) Ranges() [][2]int {
+	var ranges [][2]int
+	start := -1
+	for slot := 0; slot <= SlotCount; slot++ {
+		if slot < SlotCount && s.Has(slot) {
+			if start < 0 {
+				start = slot
+			}
+			continue
+		}
+		if start >= 0 {
+			ranges = append(ranges, [2]int{start, slot - 1})
+			start = -1
+		}
+	}
+	return ranges
+}
+
+// Node 集群中的一个节点
+type Node struct {
+	ID          string
+	IP          string
+	Port        int
+	BusPort     int
+	Flags       int
+	MasterID    string // 从节点复制的主节点
+	ConfigEpoch uint64
+	Slots       Slots
+
+	PingSent  int64 // 毫秒时间戳 0 表示没有等待中的 PING
+	PongRecv  int64
+	Connected bool // 集群总线连接状态
+
+	ReplOffset     int64                // 最近一次消息中报告的复制偏移量 (从节点故障转移排名)
+	failReports    map[string]time.Time // 报告其 PFAIL/FAIL 的主节点 -> 报告时间
+	failTime       time.Time
+	votedTime      time.Time // 最近一次为其从节点的故障转移投票
+	handshakeStart time.Time
+	meet           bool     // 握手时发送 MEET 而不是 PING (CLUSTER MEET)
+	link           *busLink // 本节点发起的总线连接
+	dialing        bool
+}
+
+func (n *Node) IsSlave() bool {
+	return n.Flags&FlagSlave != 0
+}
+
+func (n *Node) failing() bool {
+	return n.Flags&(FlagPFail|FlagFail) != 0
+}
+
+func (n *Node) IsMaster() bool {
+	return n.Flags&FlagMaster != 0
+}
+
+func (n *Node) Addr() string {
+	return net.JoinHostPort(n.IP, strconv.Itoa(n.Port))
+}
+
+func (n *Node) BusAddr() string {
+	return net.JoinHostPort(n.IP, strconv.Itoa(n.BusPort))
+}
+
+func (n *Node) FlagString() string {
+	var names []string
+	for _, f := range flagNames {
+		if n.Flags&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+	if len(names) == 0 {
+		return "noflags"
+	}
+	return strings.Join(names, ",")
+}
+
+// nodesLine CLUSTER NODES / nodes.conf 中的一行
+// <id> <ip:port@cport> <flags> <master> <ping-sent> <pong-recv> <config-epoch> <link-state> <slot> ...
+func (n *Node) nodesLine(extra []string) string {
+	master := "-"
+	if n.MasterID != "" {
+		master = n.MasterID
+	}
+	link := "disconnected"
+	if n.Connected || n.Flags&FlagMyself != 0 {
+		link = "connected"
+	}
+	fields := []string{
+		n.ID,
+		fmt.Sprintf("%s:%d@%d", n.IP, n.Port, n.BusPort),
+		n.FlagString(),
+		master,
+		strconv.FormatInt(n.PingSent, 10),
+		strconv.FormatInt(n.PongRecv, 10),
+		strconv.FormatUint(n.ConfigEpoch, 10),
+		link,
+	}
+	for _, r := range n.Slots.Ranges() {
+		if r[0] == r[1] {
+			fields = append(fields, strconv.Itoa(r[0]))
+		} else {
+			fields = append(fields, fmt.Sprintf("%d-%d", r[0], r[1]))
+		}
+	}
+	fields = append(fields, extra...)
+	return strings.Join(fields, " ")
+}
+
+func sortedNodes(nodes map[string]*Node) []*Node {
+	list := make([]*Node, 0, len(nodes))
+	for _, n := range nodes {
+		list = append(list, n)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
@@ -0,0 +1,49 @@
+package cluster
+
+// SlotCount 哈希槽数量
+const SlotCount = 16384
+
+// crc16Table CRC16-CCITT (XMODEM) 多项式 0x1021, 与 Redis Cluster 一致
+var crc16Table = func() [256]uint16 {
+	var table [256]uint16
+	for i := range table {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+func crc16(data string) uint16 {
+	var crc uint16
+	for i := 0; i < len(data); i++ {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^data[i]]
+	}
+	return crc
+}
+
+// KeySlot 键所属的槽 键中含有非空的 {hash tag} 时只对 tag 计算,
+// 使相关的键 (如 {user1000}.following 与 {user1000}.followers) 落在同一个槽
+func KeySlot(key string) int {
+	for i := 0; i < len(key); i++ {
+		if key[i] != '{' {
+			continue
+		}
+		for j := i + 1; j < len(key); j++ {
+			if key[j] == '}' {
+				if j > i+1 {
+					key = key[i+1 : j]
+				}
+				return int(crc16(key)) & (SlotCount - 1)
+			}
+		}
+		break
+	}
+	return int(crc16(key)) & (SlotCount - 1)
+}
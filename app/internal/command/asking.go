@@ -0,0 +1,34 @@
+package command
+
+import (
+	"context"
+	"net"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+)
+
+// AskingSetter 记录连接的 ASKING 标志 仅对下一条命令有效
+type AskingSetter interface {
+	SetAsking(conn net.Conn)
+}
+
+// AskingCommand ASKING 客户端收到 -ASK 重定向后先发送 ASKING 再重试命令
+type AskingCommand struct {
+	setter AskingSetter
+}
+
+func NewAskingCommand(setter AskingSetter) *AskingCommand {
+	return &AskingCommand{setter: setter}
+}
+
+func (c *AskingCommand) Name() string {
+	return "ASKING"
+}
+
+func (c *AskingCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 1 {
+		return rw.WriteError("ERR wrong number of arguments for 'asking' command")
+	}
+	c.setter.SetAsking(rw.Conn())
+	return rw.WriteSimpleString("OK")
+}
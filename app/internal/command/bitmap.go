@@ -0,0 +1,588 @@
+package command
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+const errBitOffset = "ERR bit offset is not an integer or out of range"
+
+// BitmapCommands 字符串值上的位操作命令
+func BitmapCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&SetBitCommand{dataset: d},
+		&GetBitCommand{dataset: d},
+		&BitCountCommand{dataset: d},
+		&BitPosCommand{dataset: d},
+		&BitOpCommand{dataset: d},
+		&BitFieldCommand{dataset: d, name: "BITFIELD"},
+		&BitFieldCommand{dataset: d, name: "BITFIELD_RO", ro: true},
+	}
+}
+
+// parseBitOffset 位偏移 不能为负且不能超出字符串的最大长度
+// bitfield 的偏移可以写成 #N 表示第 N 个 width 位宽的整数
+func parseBitOffset(s string, hash bool, width int) (int64, bool) {
+	mul := int64(1)
+	if hash && strings.HasPrefix(s, "#") {
+		s, mul = s[1:], int64(width)
+	}
+	n, ok := parseInt(s)
+	if !ok || n < 0 || n > math.MaxInt64/mul {
+		return 0, false
+	}
+	n *= mul
+	return n, n>>3 < maxStringSize
+}
+
+// readString 只读命令读取字符串值 键不存在时为空串
+func (d *dataset) readString(key string) (string, bool, error) {
+	var s string
+	var exists bool
+	err := d.store.Update(func(tx *kvstore.Tx) error {
+		o, err := tx.String(key)
+		if o != nil {
+			s, exists = o.Str(), true
+		}
+		return err
+	})
+	return s, exists, err
+}
+
+// SetBitCommand SETBIT key offset value 返回原来的位 字符串按需以 0 补齐
+type SetBitCommand struct {
+	dataset
+}
+
+func (c *SetBitCommand) Name() string {
+	return "SETBIT"
+}
+
+func (c *SetBitCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *SetBitCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *SetBitCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs("setbit"))
+	}
+	offset, ok := parseBitOffset(args[2], false, 0)
+	if !ok {
+		return rw.WriteError(errBitOffset)
+	}
+	if args[3] != "0" && args[3] != "1" {
+		return rw.WriteError("ERR bit is not an integer or out of range")
+	}
+	var old int
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, err := tx.String(args[1])
+		if err != nil {
+			return err
+		}
+		var buf []byte
+		if o != nil {
+			buf = []byte(o.Str())
+		}
+		if need := int(offset>>3) + 1; need > len(buf) {
+			buf = append(buf, make([]byte, need-len(buf))...)
+		}
+		old = kvstore.SetBit(buf, offset, int(args[3][0]-'0'))
+		if o == nil {
+			tx.Add(args[1], kvstore.NewStringObject(string(buf)))
+		} else {
+			tx.Replace(args[1], kvstore.NewStringObject(string(buf)))
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed(args)
+	return rw.WriteInteger(int64(old))
+}
+
+// GetBitCommand GETBIT key offset 超出长度或键不存在时为 0
+type GetBitCommand struct {
+	dataset
+}
+
+func (c *GetBitCommand) Name() string {
+	return "GETBIT"
+}
+
+func (c *GetBitCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *GetBitCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("getbit"))
+	}
+	offset, ok := parseBitOffset(args[2], false, 0)
+	if !ok {
+		return rw.WriteError(errBitOffset)
+	}
+	s, _, err := c.readString(args[1])
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(int64(kvstore.GetBit(s, offset)))
+}
+
+// parseBitRange 解析 start end [BYTE|BIT] 并换算为位区间 [lo, hi] 区间为空时 lo > hi
+// 负数从末尾倒数 超出的部分截断到值的范围内
+func parseBitRange(args []string, n int64) (lo, hi int64, reply string) {
+	start, ok1 := parseInt(args[0])
+	end, ok2 := parseInt(args[1])
+	if !ok1 || !ok2 {
+		return 0, 0, errNotInteger
+	}
+	isBit := false
+	if len(args) == 3 {
+		switch strings.ToUpper(args[2]) {
+		case "BYTE":
+		case "BIT":
+			isBit = true
+		default:
+			return 0, 0, errSyntax
+		}
+	}
+	total := n
+	if isBit {
+		total = n * 8
+	}
+	if start < 0 {
+		start += total
+	}
+	if end < 0 {
+		end += total
+	}
+	start = min(max(start, 0), total)
+	end = min(max(end, 0), total-1)
+	if isBit {
+		return start, end, ""
+	}
+	return start * 8, end*8 + 7, ""
+}
+
+// BitCountCommand BITCOUNT key [start end [BYTE|BIT]]
+type BitCountCommand struct {
+	dataset
+}
+
+func (c *BitCountCommand) Name() string {
+	return "BITCOUNT"
+}
+
+func (c *BitCountCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *BitCountCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs("bitcount"))
+	}
+	if len(args) == 3 || len(args) > 5 {
+		return rw.WriteError(errSyntax)
+	}
+	s, _, err := c.readString(args[1])
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	lo, hi := int64(0), int64(len(s))*8-1
+	if len(args) > 2 {
+		var reply string
+		if lo, hi, reply = parseBitRange(args[2:], int64(len(s))); reply != "" {
+			return rw.WriteError(reply)
+		}
+	}
+	return rw.WriteInteger(kvstore.CountBits(s, lo, hi))
+}
+
+// BitPosCommand BITPOS key bit [start [end [BYTE|BIT]]]
+// 找 0 且没有给出 end 时 值右侧视为补 0
+type BitPosCommand struct {
+	dataset
+}
+
+func (c *BitPosCommand) Name() string {
+	return "BITPOS"
+}
+
+func (c *BitPosCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *BitPosCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 || len(args) > 6 {
+		return rw.WriteError(errWrongArgs("bitpos"))
+	}
+	bit, ok := parseInt(args[2])
+	if !ok {
+		return rw.WriteError(errNotInteger)
+	}
+	if bit != 0 && bit != 1 {
+		return rw.WriteError("ERR The bit argument must be 1 or 0.")
+	}
+	s, exists, err := c.readString(args[1])
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	n := int64(len(s))
+	lo, hi := int64(0), n*8-1
+	endGiven := len(args) > 4
+	if len(args) > 3 {
+		rangeArgs := args[3:]
+		if !endGiven {
+			rangeArgs = []string{args[3], "-1"}
+		}
+		var reply string
+		if lo, hi, reply = parseBitRange(rangeArgs, n); reply != "" {
+			return rw.WriteError(reply)
+		}
+	}
+	// 键不存在时 找 1 为 -1, 找 0 为 0
+	if !exists {
+		if bit == 1 {
+			return rw.WriteInteger(-1)
+		}
+		return rw.WriteInteger(0)
+	}
+	if lo > hi {
+		return rw.WriteInteger(-1)
+	}
+	pos := kvstore.FindBit(s, int(bit), lo, hi)
+	if pos == -1 && bit == 0 && !endGiven {
+		pos = hi + 1
+	}
+	return rw.WriteInteger(pos)
+}
+
+// BitOpCommand BITOP AND|OR|XOR|NOT|DIFF destkey key [key ...] 返回结果的长度
+// 结果为空时删除 destkey
+type BitOpCommand struct {
+	dataset
+}
+
+func (c *BitOpCommand) Name() string {
+	return "BITOP"
+}
+
+func (c *BitOpCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *BitOpCommand) KeySpec() KeySpec {
+	return KeySpec{First: 2, Last: -1, Step: 1}
+}
+
+var bitOps = map[string]kvstore.BitOp{
+	"AND":  kvstore.BitAnd,
+	"OR":   kvstore.BitOr,
+	"XOR":  kvstore.BitXor,
+	"NOT":  kvstore.BitNot,
+	"DIFF": kvstore.BitDiff,
+}
+
+func (c *BitOpCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 {
+		return rw.WriteError(errWrongArgs("bitop"))
+	}
+	name := strings.ToUpper(args[1])
+	op, ok := bitOps[name]
+	if !ok {
+		return rw.WriteError(errSyntax)
+	}
+	keys := args[3:]
+	if op == kvstore.BitNot && len(keys) != 1 {
+		return rw.WriteError("ERR BITOP NOT must be called with a single source key.")
+	}
+	if op == kvstore.BitDiff && len(keys) < 2 {
+		return rw.WriteError("ERR BITOP DIFF must be called with at least two source keys.")
+	}
+	var length int
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		srcs := make([]string, len(keys))
+		for i, key := range keys {
+			o, err := tx.String(key)
+			if err != nil {
+				return err
+			}
+			if o != nil {
+				srcs[i] = o.Str()
+			}
+		}
+		res := kvstore.BitOperation(op, srcs)
+		length = len(res)
+		if length == 0 {
+			tx.Delete(args[2])
+			return nil
+		}
+		tx.Put(args[2], kvstore.NewStringObject(string(res)), 0)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed(args)
+	return rw.WriteInteger(int64(length))
+}
+
+// 溢出策略
+const (
+	overflowWrap = iota
+	overflowSat
+	overflowFail
+)
+
+// bitfieldOp BITFIELD 的一个子命令
+type bitfieldOp struct {
+	op       string // GET / SET / INCRBY
+	signed   bool
+	width    int
+	offset   int64
+	value    int64
+	overflow int
+}
+
+// parseBitfieldType i1..i64 / u1..u63
+func parseBitfieldType(s string) (signed bool, width int, ok bool) {
+	if len(s) < 2 {
+		return false, 0, false
+	}
+	switch s[0] {
+	case 'i', 'I':
+		signed = true
+	case 'u', 'U':
+	default:
+		return false, 0, false
+	}
+	n, err := strconv.Atoi(s[1:])
+	if err != nil || n < 1 || (signed && n > 64) || (!signed && n > 63) {
+		return false, 0, false
+	}
+	return signed, n, true
+}
+
+func parseBitfieldOps(args []string, ro bool) ([]bitfieldOp, string) {
+	var ops []bitfieldOp
+	overflow := overflowWrap
+	for i := 0; i < len(args); i++ {
+		sub, rem := strings.ToUpper(args[i]), len(args)-i-1
+		switch {
+		case sub == "GET" && rem >= 2:
+		case (sub == "SET" || sub == "INCRBY") && rem >= 3:
+		case sub == "OVERFLOW" && rem >= 1:
+			switch strings.ToUpper(args[i+1]) {
+			case "WRAP":
+				overflow = overflowWrap
+			case "SAT":
+				overflow = overflowSat
+			case "FAIL":
+				overflow = overflowFail
+			default:
+				return nil, "ERR Invalid OVERFLOW type specified"
+			}
+			i++
+			continue
+		default:
+			return nil, errSyntax
+		}
+		if ro && sub != "GET" {
+			return nil, "ERR BITFIELD_RO only supports the GET subcommand"
+		}
+		signed, width, ok := parseBitfieldType(args[i+1])
+		if !ok {
+			return nil, "ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is."
+		}
+		offset, ok := parseBitOffset(args[i+2], true, width)
+		if !ok {
+			return nil, errBitOffset
+		}
+		op := bitfieldOp{op: sub, signed: signed, width: width, offset: offset, overflow: overflow}
+		if sub != "GET" {
+			if op.value, ok = parseInt(args[i+3]); !ok {
+				return nil, errNotInteger
+			}
+			i++
+		}
+		ops = append(ops, op)
+		i += 2
+	}
+	return ops, ""
+}
+
+// signedOverflow 检查 value+incr 是否超出 width 位有符号整数的范围
+// 溢出时按策略返回环绕或饱和后的值
+func signedOverflow(value, incr int64, width int, overflow int) (int64, bool) {
+	maxv := int64(math.MaxInt64)
+	if width < 64 {
+		maxv = 1<<(width-1) - 1
+	}
+	minv := -maxv - 1
+	maxIncr, minIncr := maxv-value, minv-value
+	limit := minv
+	switch {
+	case value > maxv || (width != 64 && incr > maxIncr) || (value >= 0 && incr > 0 && incr > maxIncr):
+		limit = maxv
+	case value < minv || (width != 64 && incr < minIncr) || (value < 0 && incr < 0 && incr < minIncr):
+	default:
+		return value + incr, false
+	}
+	if overflow != overflowWrap {
+		return limit, true
+	}
+	c := uint64(value) + uint64(incr)
+	if width < 64 {
+		mask := ^uint64(0) << width
+		if c&(1<<(width-1)) != 0 {
+			c |= mask
+		} else {
+			c &^= mask
+		}
+	}
+	return int64(c), true
+}
+
+// unsignedOverflow 检查 value+incr 是否超出 width 位无符号整数的范围
+func unsignedOverflow(value uint64, incr int64, width int, overflow int) (uint64, bool) {
+	maxv := uint64(1)<<width - 1
+	maxIncr, minIncr := int64(maxv-value), -int64(value)
+	var limit uint64
+	switch {
+	case value > maxv || (incr > 0 && incr > maxIncr):
+		limit = maxv
+	case incr < 0 && incr < minIncr:
+	default:
+		return value + uint64(incr), false
+	}
+	if overflow != overflowWrap {
+		return limit, true
+	}
+	return (value + uint64(incr)) &^ (^uint64(0) << width), true
+}
+
+// BitFieldCommand BITFIELD key [GET type offset] [SET type offset value] [INCRBY type offset increment] [OVERFLOW WRAP|SAT|FAIL] ...
+// BITFIELD_RO 只允许 GET
+// GET 返回当前值, SET 返回原来的值, INCRBY 返回新值; OVERFLOW FAIL 时溢出的操作不生效并返回 nil
+type BitFieldCommand struct {
+	dataset
+	name string
+	ro   bool
+}
+
+func (c *BitFieldCommand) Name() string {
+	return c.name
+}
+
+func (c *BitFieldCommand) Flags() Flag {
+	if c.ro {
+		return 0
+	}
+	return FlagWrite
+}
+
+func (c *BitFieldCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *BitFieldCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	ops, reply := parseBitfieldOps(args[2:], c.ro)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	// 写操作涉及的最大字节数 执行前先把值补齐到这个长度
+	need := 0
+	for _, op := range ops {
+		if op.op != "GET" {
+			need = max(need, int((op.offset+int64(op.width)-1)>>3)+1)
+		}
+	}
+	res := make([]any, 0, len(ops))
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, err := tx.String(args[1])
+		if err != nil {
+			return err
+		}
+		var buf []byte
+		if o != nil {
+			buf = []byte(o.Str())
+		}
+		if need > len(buf) {
+			buf = append(buf, make([]byte, need-len(buf))...)
+		}
+		for _, op := range ops {
+			res = append(res, op.apply(buf))
+		}
+		if need == 0 {
+			return nil
+		}
+		if o == nil {
+			tx.Add(args[1], kvstore.NewStringObject(string(buf)))
+		} else {
+			tx.Replace(args[1], kvstore.NewStringObject(string(buf)))
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if need > 0 {
+		c.changed(args)
+	}
+	return rw.WriteValue(res)
+}
+
+// apply 在 buf 上执行子命令并返回应答中的值
+func (op bitfieldOp) apply(buf []byte) any {
+	if op.signed {
+		old := kvstore.GetSignedBits(buf, op.offset, op.width)
+		if op.op == "GET" {
+			return old
+		}
+		incr, base := op.value, old
+		if op.op == "SET" {
+			incr, base = 0, op.value
+		}
+		v, overflowed := signedOverflow(base, incr, op.width, op.overflow)
+		if overflowed && op.overflow == overflowFail {
+			return nil
+		}
+		kvstore.SetBits(buf, op.offset, op.width, uint64(v))
+		if op.op == "SET" {
+			return old
+		}
+		return v
+	}
+	old := kvstore.GetBits(buf, op.offset, op.width)
+	if op.op == "GET" {
+		return int64(old)
+	}
+	incr, base := op.value, old
+	if op.op == "SET" {
+		incr, base = 0, uint64(op.value)
+	}
+	v, overflowed := unsignedOverflow(base, incr, op.width, op.overflow)
+	if overflowed && op.overflow == overflowFail {
+		return nil
+	}
+	kvstore.SetBits(buf, op.offset, op.width, v)
+	if op.op == "SET" {
+		return int64(old)
+	}
+	return int64(v)
+}
@@ -0,0 +1,258 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// BloomCommands BF.* 可扩展布隆过滤器 (与 RedisBloom 兼容)
+// BF.ADD/BF.MADD 在键不存在时以默认参数创建 (错误率 0.01, 容量 100, 扩展倍数 2)
+func BloomCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&BFReserveCommand{dataset: d},
+		&BFAddCommand{dataset: d, name: "BF.ADD"},
+		&BFAddCommand{dataset: d, name: "BF.MADD", multi: true},
+		&BFExistsCommand{dataset: d, name: "BF.EXISTS"},
+		&BFExistsCommand{dataset: d, name: "BF.MEXISTS", multi: true},
+		&BFInfoCommand{dataset: d},
+	}
+}
+
+const (
+	errBloomExists   = "ERR item exists"
+	errBloomNotFound = "ERR not found"
+)
+
+// parseUint 解析正整数参数
+func parseUint(s string) (uint64, bool) {
+	n, ok := parseInt(s)
+	return uint64(n), ok && n > 0
+}
+
+// BFReserveCommand BF.RESERVE key error_rate capacity [EXPANSION expansion] [NONSCALING]
+type BFReserveCommand struct {
+	dataset
+}
+
+func (c *BFReserveCommand) Name() string {
+	return "BF.RESERVE"
+}
+
+func (c *BFReserveCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *BFReserveCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *BFReserveCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 {
+		return rw.WriteError(errWrongArgs("bf.reserve"))
+	}
+	errorRate, ok := parseFloat(args[2])
+	if !ok || errorRate <= 0 || errorRate >= 1 {
+		return rw.WriteError("ERR (0 < error rate range < 1)")
+	}
+	capacity, ok := parseUint(args[3])
+	if !ok {
+		return rw.WriteError("ERR (capacity should be larger than 0)")
+	}
+	expansion := uint64(kvstore.BloomDefaultExpansion)
+	for i := 4; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "EXPANSION":
+			if i+1 >= len(args) {
+				return rw.WriteError(errSyntax)
+			}
+			if expansion, ok = parseUint(args[i+1]); !ok {
+				return rw.WriteError("ERR expansion should be greater or equal to 1")
+			}
+			i++
+		case "NONSCALING":
+			expansion = 0
+		default:
+			return rw.WriteError(errSyntax)
+		}
+	}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		if _, ok := tx.Lookup(args[1]); ok {
+			return errors.New(errBloomExists)
+		}
+		b, err := kvstore.NewBloom(errorRate, capacity, expansion)
+		if err != nil {
+			return err
+		}
+		tx.Add(args[1], kvstore.NewBloomObject(b))
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed(args)
+	return rw.WriteSimpleString("OK")
+}
+
+// BFAddCommand BF.ADD key item / BF.MADD key item [item ...]
+// 元素新加入时为 1, 可能已存在为 0; 过滤器已满时 BF.ADD 报错, BF.MADD 在对应位置回复错误
+type BFAddCommand struct {
+	dataset
+	name  string
+	multi bool
+}
+
+func (c *BFAddCommand) Name() string {
+	return c.name
+}
+
+func (c *BFAddCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *BFAddCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *BFAddCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if (!c.multi && len(args) != 3) || len(args) < 3 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	res := make([]any, 0, len(args)-2)
+	modified := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		b, err := tx.Bloom(args[1])
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			if b, err = kvstore.NewBloom(kvstore.BloomDefaultErrorRate, kvstore.BloomDefaultCapacity, kvstore.BloomDefaultExpansion); err != nil {
+				return err
+			}
+			tx.Add(args[1], kvstore.NewBloomObject(b))
+			modified = true
+		}
+		for _, item := range args[2:] {
+			added, err := b.Add(item)
+			if err != nil {
+				if !c.multi {
+					return err
+				}
+				res = append(res, protocol.ErrorReply(err.Error()))
+				continue
+			}
+			modified = modified || added
+			res = append(res, boolInt(added))
+		}
+		return nil
+	})
+	if modified {
+		c.changed(args)
+	}
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if !c.multi {
+		return rw.WriteValue(res[0])
+	}
+	return rw.WriteValue(res)
+}
+
+// BFExistsCommand BF.EXISTS key item / BF.MEXISTS key item [item ...] 键不存在时为 0
+type BFExistsCommand struct {
+	dataset
+	name  string
+	multi bool
+}
+
+func (c *BFExistsCommand) Name() string {
+	return c.name
+}
+
+func (c *BFExistsCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *BFExistsCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if (!c.multi && len(args) != 3) || len(args) < 3 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	res := make([]any, len(args)-2)
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		b, err := tx.Bloom(args[1])
+		if err != nil {
+			return err
+		}
+		for i, item := range args[2:] {
+			res[i] = boolInt(b != nil && b.Exists(item))
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if !c.multi {
+		return rw.WriteValue(res[0])
+	}
+	return rw.WriteValue(res)
+}
+
+// BFInfoCommand BF.INFO key [CAPACITY|SIZE|FILTERS|ITEMS|EXPANSION]
+// 不扩展的过滤器扩展倍数为 nil
+type BFInfoCommand struct {
+	dataset
+}
+
+func (c *BFInfoCommand) Name() string {
+	return "BF.INFO"
+}
+
+func (c *BFInfoCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *BFInfoCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 && len(args) != 3 {
+		return rw.WriteError(errWrongArgs("bf.info"))
+	}
+	var info []any
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		b, err := tx.Bloom(args[1])
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return errors.New(errBloomNotFound)
+		}
+		var expansion any
+		if b.Expansion > 0 {
+			expansion = int64(b.Expansion)
+		}
+		info = []any{
+			"Capacity", int64(b.Capacity()),
+			"Size", int64(b.Size()),
+			"Number of filters", int64(len(b.Layers)),
+			"Number of items inserted", int64(b.Count()),
+			"Expansion rate", expansion,
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if len(args) == 2 {
+		return rw.WriteValue(info)
+	}
+	fields := []string{"CAPACITY", "SIZE", "FILTERS", "ITEMS", "EXPANSION"}
+	for i, f := range fields {
+		if strings.EqualFold(args[2], f) {
+			return rw.WriteValue([]any{info[2*i+1]})
+		}
+	}
+	return rw.WriteError("ERR Invalid information value")
+}
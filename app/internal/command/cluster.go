@@ -0,0 +1,267 @@
+package command
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/cluster"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// ClusterCommand CLUSTER <subcommand> 仅在 cluster-enabled 时注册
+type ClusterCommand struct {
+	state *cluster.State
+	store *kvstore.Store
+}
+
+func NewClusterCommand(state *cluster.State, store *kvstore.Store) *ClusterCommand {
+	return &ClusterCommand{state: state, store: store}
+}
+
+func (c *ClusterCommand) Name() string {
+	return "CLUSTER"
+}
+
+func (c *ClusterCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError("ERR wrong number of arguments for 'cluster' command")
+	}
+	sub := strings.ToUpper(args[1])
+	params := args[2:]
+
+	switch sub {
+	case "INFO":
+		return rw.WriteBulkString(c.state.Info())
+	case "MYID":
+		return rw.WriteBulkString(c.state.MyID())
+	case "NODES":
+		return rw.WriteBulkString(c.state.Nodes())
+	case "SLOTS":
+		return rw.WriteValue(c.state.SlotsReply())
+	case "SHARDS":
+		return rw.WriteValue(c.state.ShardsReply())
+	case "KEYSLOT":
+		if len(params) != 1 {
+			return c.wrongArgs(rw, sub)
+		}
+		return rw.WriteInteger(int64(cluster.KeySlot(params[0])))
+	case "COUNTKEYSINSLOT":
+		if len(params) != 1 {
+			return c.wrongArgs(rw, sub)
+		}
+		slot, err := parseSlot(params[0])
+		if err != nil {
+			return rw.WriteError(err.Error())
+		}
+		return rw.WriteInteger(int64(len(c.keysInSlot(slot, -1))))
+	case "GETKEYSINSLOT":
+		if len(params) != 2 {
+			return c.wrongArgs(rw, sub)
+		}
+		slot, err := parseSlot(params[0])
+		if err != nil {
+			return rw.WriteError(err.Error())
+		}
+		count, err := strconv.Atoi(params[1])
+		if err != nil || count < 0 {
+			return rw.WriteError("ERR Invalid number of keys")
+		}
+		return rw.WriteArray(c.keysInSlot(slot, count))
+	case "ADDSLOTS", "DELSLOTS":
+		if len(params) == 0 {
+			return c.wrongArgs(rw, sub)
+		}
+		slots, err := parseSlots(params)
+		if err != nil {
+			return rw.WriteError(err.Error())
+		}
+		return c.changeSlots(rw, sub == "ADDSLOTS", slots)
+	case "ADDSLOTSRANGE", "DELSLOTSRANGE":
+		if len(params) == 0 || len(params)%2 != 0 {
+			return c.wrongArgs(rw, sub)
+		}
+		var slots []int
+		for i := 0; i < len(params); i += 2 {
+			start, err := parseSlot(params[i])
+			if err != nil {
+				return rw.WriteError(err.Error())
+			}
+			end, err := parseSlot(params[i+1])
+			if err != nil {
+				return rw.WriteError(err.Error())
+			}
+			if start > end {
+				return rw.WriteError("ERR start slot number " + params[i] + " is greater than end slot number " + params[i+1])
+			}
+			for slot := start; slot <= end; slot++ {
+				slots = append(slots, slot)
+			}
+		}
+		return c.changeSlots(rw, sub == "ADDSLOTSRANGE", slots)
+	case "FLUSHSLOTS":
+		if len(c.store.Keys()) > 0 {
+			return rw.WriteError("ERR DB must be empty to perform CLUSTER FLUSHSLOTS.")
+		}
+		c.state.FlushSlots()
+		return rw.WriteSimpleString("OK")
+	case "MEET":
+		// CLUSTER MEET ip port [cluster-bus-port]
+		if len(params) != 2 && len(params) != 3 {
+			return c.wrongArgs(rw, sub)
+		}
+		port, err := strconv.Atoi(params[1])
+		if err != nil {
+			return rw.WriteError("ERR Invalid base port specified: " + params[1])
+		}
+		busPort := port + cluster.BusPortOffset
+		if len(params) == 3 {
+			if busPort, err = strconv.Atoi(params[2]); err != nil {
+				return rw.WriteError("ERR Invalid bus port specified: " + params[2])
+			}
+		}
+		if err := c.state.Meet(params[0], port, busPort); err != nil {
+			return rw.WriteError(err.Error())
+		}
+		return rw.WriteSimpleString("OK")
+	case "FORGET":
+		if len(params) != 1 {
+			return c.wrongArgs(rw, sub)
+		}
+		if err := c.state.Forget(params[0]); err != nil {
+			return rw.WriteError(err.Error())
+		}
+		return rw.WriteSimpleString("OK")
+	case "REPLICATE":
+		if len(params) != 1 {
+			return c.wrongArgs(rw, sub)
+		}
+		if err := c.state.Replicate(params[0], len(c.store.Keys()) == 0); err != nil {
+			return rw.WriteError(err.Error())
+		}
+		return rw.WriteSimpleString("OK")
+	case "REPLICAS", "SLAVES":
+		if len(params) != 1 {
+			return c.wrongArgs(rw, sub)
+		}
+		lines, err := c.state.Replicas(params[0])
+		if err != nil {
+			return rw.WriteError(err.Error())
+		}
+		return rw.WriteArray(lines)
+	case "COUNT-FAILURE-REPORTS":
+		if len(params) != 1 {
+			return c.wrongArgs(rw, sub)
+		}
+		n, err := c.state.CountFailureReports(params[0])
+		if err != nil {
+			return rw.WriteError(err.Error())
+		}
+		return rw.WriteInteger(int64(n))
+	case "SETSLOT":
+		return c.setSlot(rw, params)
+	case "SAVECONFIG":
+		if err := c.state.SaveConfig(); err != nil {
+			return rw.WriteError("ERR error saving the cluster node config: " + err.Error())
+		}
+		return rw.WriteSimpleString("OK")
+	}
+	return rw.WriteError("ERR unknown subcommand '" + args[1] + "'. Try CLUSTER HELP.")
+}
+
+func (c *ClusterCommand) changeSlots(rw protocol.ResponseWriter, add bool, slots []int) error {
+	seen := make(map[int]bool, len(slots))
+	for _, slot := range slots {
+		if seen[slot] {
+			return rw.WriteError("ERR Slot " + strconv.Itoa(slot) + " specified multiple times")
+		}
+		seen[slot] = true
+	}
+	var err error
+	if add {
+		err = c.state.AddSlots(slots)
+	} else {
+		err = c.state.DelSlots(slots)
+	}
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteSimpleString("OK")
+}
+
+// setSlot CLUSTER SETSLOT <slot> IMPORTING <id> | MIGRATING <id> | NODE <id> | STABLE
+func (c *ClusterCommand) setSlot(rw protocol.ResponseWriter, params []string) error {
+	if len(params) < 2 {
+		return c.wrongArgs(rw, "SETSLOT")
+	}
+	slot, err := parseSlot(params[0])
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	action := strings.ToUpper(params[1])
+	if action == "STABLE" {
+		if len(params) != 2 {
+			return rw.WriteError("ERR syntax error")
+		}
+		err = c.state.SetSlotStable(slot)
+	} else {
+		if len(params) != 3 {
+			return rw.WriteError("ERR syntax error")
+		}
+		switch action {
+		case "MIGRATING":
+			err = c.state.SetSlotMigrating(slot, params[2])
+		case "IMPORTING":
+			err = c.state.SetSlotImporting(slot, params[2])
+		case "NODE":
+			err = c.state.SetSlotNode(slot, params[2], len(c.keysInSlot(slot, -1)))
+		default:
+			return rw.WriteError("ERR Invalid CLUSTER SETSLOT action or number of arguments. Try CLUSTER HELP")
+		}
+	}
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteSimpleString("OK")
+}
+
+// keysInSlot 本地属于 slot 的键 (按字典序), count 为负数时不限数量
+func (c *ClusterCommand) keysInSlot(slot, count int) []string {
+	var keys []string
+	for _, key := range c.store.Keys() {
+		if cluster.KeySlot(key) == slot {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if count >= 0 && len(keys) > count {
+		keys = keys[:count]
+	}
+	return keys
+}
+
+func (c *ClusterCommand) wrongArgs(rw protocol.ResponseWriter, sub string) error {
+	return rw.WriteError("ERR wrong number of arguments for 'cluster|" + strings.ToLower(sub) + "' command")
+}
+
+func parseSlot(s string) (int, error) {
+	slot, err := strconv.Atoi(s)
+	if err != nil || slot < 0 || slot >= cluster.SlotCount {
+		return 0, cluster.ErrInvalidSlot
+	}
+	return slot, nil
+}
+
+func parseSlots(params []string) ([]int, error) {
+	slots := make([]int, 0, len(params))
+	for _, p := range params {
+		slot, err := parseSlot(p)
+		if err != nil {
+			return nil, err
+		}
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
@@ -0,0 +1,293 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// CMSCommands CMS.* count-min sketch (与 RedisBloom 兼容) 使用前需要 CMS.INITBYDIM/INITBYPROB 创建
+func CMSCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&CMSInitCommand{dataset: d, name: "CMS.INITBYDIM"},
+		&CMSInitCommand{dataset: d, name: "CMS.INITBYPROB", byProb: true},
+		&CMSIncrByCommand{dataset: d},
+		&CMSQueryCommand{dataset: d},
+		&CMSMergeCommand{dataset: d},
+		&CMSInfoCommand{dataset: d},
+	}
+}
+
+const (
+	errCMSExists   = "CMS: key already exists"
+	errCMSNotFound = "CMS: key does not exist"
+	errCMSNumber   = "CMS: Cannot parse number"
+)
+
+// cmsSketch 读取已存在的 sketch
+func cmsSketch(tx *kvstore.Tx, key string) (*kvstore.CountMinSketch, error) {
+	s, err := tx.CMS(key)
+	if err == nil && s == nil {
+		err = errors.New(errCMSNotFound)
+	}
+	return s, err
+}
+
+// CMSInitCommand CMS.INITBYDIM key width depth / CMS.INITBYPROB key error probability
+// 按误差创建时宽度为 ceil(2/error), 深度为 ceil(log(probability)/log(0.5))
+type CMSInitCommand struct {
+	dataset
+	name   string
+	byProb bool
+}
+
+func (c *CMSInitCommand) Name() string {
+	return c.name
+}
+
+func (c *CMSInitCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *CMSInitCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *CMSInitCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	var width, depth uint64
+	if c.byProb {
+		errorRate, ok := parseFloat(args[2])
+		if !ok || errorRate <= 0 || errorRate >= 1 {
+			return rw.WriteError("CMS: invalid overestimation value")
+		}
+		prob, ok := parseFloat(args[3])
+		if !ok || prob <= 0 || prob >= 1 {
+			return rw.WriteError("CMS: invalid prob value")
+		}
+		width, depth = kvstore.CMSDimensions(errorRate, prob)
+	} else {
+		var ok1, ok2 bool
+		width, ok1 = parseUint(args[2])
+		depth, ok2 = parseUint(args[3])
+		if !ok1 || !ok2 {
+			return rw.WriteError(kvstore.ErrCMSSize.Error())
+		}
+	}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		if _, ok := tx.Lookup(args[1]); ok {
+			return errors.New(errCMSExists)
+		}
+		s, err := kvstore.NewCountMinSketch(width, depth)
+		if err != nil {
+			return err
+		}
+		tx.Add(args[1], kvstore.NewCMSObject(s))
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed(args)
+	return rw.WriteSimpleString("OK")
+}
+
+// CMSIncrByCommand CMS.INCRBY key item increment [item increment ...] 回复各元素的新计数
+type CMSIncrByCommand struct {
+	dataset
+}
+
+func (c *CMSIncrByCommand) Name() string {
+	return "CMS.INCRBY"
+}
+
+func (c *CMSIncrByCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *CMSIncrByCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *CMSIncrByCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 || len(args)%2 != 0 {
+		return rw.WriteError(errWrongArgs("cms.incrby"))
+	}
+	incrs := make([]uint32, 0, len(args)/2-1)
+	for i := 3; i < len(args); i += 2 {
+		n, err := strconv.ParseUint(args[i], 10, 32)
+		if err != nil {
+			return rw.WriteError(errCMSNumber)
+		}
+		incrs = append(incrs, uint32(n))
+	}
+	res := make([]any, 0, len(incrs))
+	modified := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := cmsSketch(tx, args[1])
+		if err != nil {
+			return err
+		}
+		for i, incr := range incrs {
+			n, err := s.IncrBy(args[2+2*i], incr)
+			if err != nil {
+				return err
+			}
+			modified = true
+			res = append(res, int64(n))
+		}
+		return nil
+	})
+	if modified {
+		c.changed(args)
+	}
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// CMSQueryCommand CMS.QUERY key item [item ...]
+type CMSQueryCommand struct {
+	dataset
+}
+
+func (c *CMSQueryCommand) Name() string {
+	return "CMS.QUERY"
+}
+
+func (c *CMSQueryCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *CMSQueryCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("cms.query"))
+	}
+	res := make([]any, len(args)-2)
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := cmsSketch(tx, args[1])
+		if err != nil {
+			return err
+		}
+		for i, item := range args[2:] {
+			res[i] = int64(s.Query(item))
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// CMSMergeCommand CMS.MERGE destination numKeys source [source ...] [WEIGHTS weight [weight ...]]
+// 目标必须已存在且与所有来源的宽度/深度相同, 计数器被加权和覆盖
+type CMSMergeCommand struct {
+	dataset
+}
+
+func (c *CMSMergeCommand) Name() string {
+	return "CMS.MERGE"
+}
+
+func (c *CMSMergeCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *CMSMergeCommand) FindKeys(args []string) []string {
+	if len(args) < 3 {
+		return nil
+	}
+	n, ok := parseInt(args[2])
+	if !ok || n < 1 || int(n) > len(args)-3 {
+		return args[1:2]
+	}
+	return append([]string{args[1]}, args[3:3+n]...)
+}
+
+func (c *CMSMergeCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 {
+		return rw.WriteError(errWrongArgs("cms.merge"))
+	}
+	n, ok := parseInt(args[2])
+	if !ok || n < 1 || int(n) > len(args)-3 {
+		return rw.WriteError("CMS: wrong number of keys")
+	}
+	keys := args[3 : 3+n]
+	weights := make([]int64, n)
+	for i := range weights {
+		weights[i] = 1
+	}
+	if rest := args[3+n:]; len(rest) > 0 {
+		if !strings.EqualFold(rest[0], "WEIGHTS") || int64(len(rest)-1) != n {
+			return rw.WriteError("CMS: wrong number of keys/weights")
+		}
+		for i, w := range rest[1:] {
+			if weights[i], ok = parseInt(w); !ok {
+				return rw.WriteError(errCMSNumber)
+			}
+		}
+	}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		dst, err := cmsSketch(tx, args[1])
+		if err != nil {
+			return err
+		}
+		srcs := make([]*kvstore.CountMinSketch, len(keys))
+		for i, key := range keys {
+			if srcs[i], err = cmsSketch(tx, key); err != nil {
+				return err
+			}
+			if srcs[i].Width != dst.Width || srcs[i].Depth != dst.Depth {
+				return errors.New("CMS: width/depth is not equal")
+			}
+		}
+		return dst.Merge(srcs, weights)
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed(args)
+	return rw.WriteSimpleString("OK")
+}
+
+// CMSInfoCommand CMS.INFO key
+type CMSInfoCommand struct {
+	dataset
+}
+
+func (c *CMSInfoCommand) Name() string {
+	return "CMS.INFO"
+}
+
+func (c *CMSInfoCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *CMSInfoCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs("cms.info"))
+	}
+	var info []any
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := cmsSketch(tx, args[1])
+		if err != nil {
+			return err
+		}
+		info = []any{"width", int64(s.Width), "depth", int64(s.Depth), "count", int64(s.Count)}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(info)
+}
@@ -2,7 +2,6 @@ package command
 
 import (
 	"context"
-	"reflect"
 	"strings"
 
 	"github.com/codecrafters-io/redis-starter-go/app/internal/config"
@@ -23,13 +22,27 @@ func (c *ConfigCommand) Name() string {
 
 // ArrayFmt
 func (c *ConfigCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
-	// CONFIG GET 命令
-	if strings.EqualFold(args[1], "GET") == true {
+	if len(args) < 3 {
+		return rw.WriteError("ERR wrong number of arguments for 'config' command")
+	}
+	switch strings.ToUpper(args[1]) {
+	case "GET":
+		// CONFIG GET 命令
 		getName := args[2]
-		// 反射获取结构体字段 即配置
-		val := reflect.ValueOf(c.Cfg).Elem().FieldByName(getName)
-		getvalue := val.String()
+		getvalue, ok := c.Cfg.Get(getName)
+		if !ok {
+			return rw.WriteArray([]string{})
+		}
 		return rw.WriteArray([]string{getName, getvalue})
+	case "SET":
+		// CONFIG SET name value
+		if len(args) != 4 {
+			return rw.WriteError("ERR wrong number of arguments for 'config|set' command")
+		}
+		if err := c.Cfg.Set(args[2], args[3]); err != nil {
+			return rw.WriteError("ERR " + err.Error())
+		}
+		return rw.WriteSimpleString("OK")
 	}
-	return nil
+	return rw.WriteError("ERR unknown subcommand '" + args[1] + "'")
 }
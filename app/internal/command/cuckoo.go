@@ -0,0 +1,388 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// CuckooCommands CF.* 布谷鸟过滤器 (与 RedisBloom 兼容) 支持删除与计数
+// CF.ADD/CF.ADDNX/CF.INSERT 在键不存在时创建 (容量 1024, 桶大小 2, 最多踢出 20 次, 扩展倍数 1)
+func CuckooCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&CFReserveCommand{dataset: d},
+		&CFAddCommand{dataset: d, name: "CF.ADD"},
+		&CFAddCommand{dataset: d, name: "CF.ADDNX", nx: true},
+		&CFInsertCommand{dataset: d, name: "CF.INSERT"},
+		&CFInsertCommand{dataset: d, name: "CF.INSERTNX", nx: true},
+		&CFExistsCommand{dataset: d, name: "CF.EXISTS"},
+		&CFExistsCommand{dataset: d, name: "CF.MEXISTS", multi: true},
+		&CFExistsCommand{dataset: d, name: "CF.COUNT", count: true},
+		&CFDelCommand{dataset: d},
+		&CFInfoCommand{dataset: d},
+	}
+}
+
+// cuckooOrCreate 键不存在时以 capacity 与默认参数创建
+func cuckooOrCreate(tx *kvstore.Tx, key string, capacity uint64) (*kvstore.Cuckoo, bool, error) {
+	c, err := tx.Cuckoo(key)
+	if err != nil || c != nil {
+		return c, false, err
+	}
+	c, err = kvstore.NewCuckoo(capacity, kvstore.CuckooDefaultBucketSize, kvstore.CuckooDefaultMaxIterations, kvstore.CuckooDefaultExpansion)
+	if err != nil {
+		return nil, false, err
+	}
+	tx.Add(key, kvstore.NewCuckooObject(c))
+	return c, true, nil
+}
+
+// cuckooAdd 插入一个元素 nx 时已存在的元素回复 0
+func cuckooAdd(c *kvstore.Cuckoo, item string, nx bool) (bool, error) {
+	if nx {
+		return c.AddNX(item)
+	}
+	if err := c.Add(item); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CFReserveCommand CF.RESERVE key capacity [BUCKETSIZE n] [MAXITERATIONS n] [EXPANSION n]
+// EXPANSION 0 表示满后不扩展
+type CFReserveCommand struct {
+	dataset
+}
+
+func (c *CFReserveCommand) Name() string {
+	return "CF.RESERVE"
+}
+
+func (c *CFReserveCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *CFReserveCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *CFReserveCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 || len(args)%2 != 1 {
+		return rw.WriteError(errWrongArgs("cf.reserve"))
+	}
+	capacity, ok := parseUint(args[2])
+	if !ok {
+		return rw.WriteError("ERR Bad capacity")
+	}
+	bucketSize := uint64(kvstore.CuckooDefaultBucketSize)
+	maxIterations := uint64(kvstore.CuckooDefaultMaxIterations)
+	expansion := uint64(kvstore.CuckooDefaultExpansion)
+	for i := 3; i < len(args); i += 2 {
+		n, ok := parseInt(args[i+1])
+		switch strings.ToUpper(args[i]) {
+		case "BUCKETSIZE":
+			if !ok || n < 1 || n > 255 {
+				return rw.WriteError("ERR Bad bucket size")
+			}
+			bucketSize = uint64(n)
+		case "MAXITERATIONS":
+			if !ok || n < 1 || n > 65535 {
+				return rw.WriteError("ERR Bad maxIterations")
+			}
+			maxIterations = uint64(n)
+		case "EXPANSION":
+			if !ok || n < 0 || n > 32768 {
+				return rw.WriteError("ERR Bad expansion")
+			}
+			expansion = uint64(n)
+		default:
+			return rw.WriteError(errSyntax)
+		}
+	}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		if _, ok := tx.Lookup(args[1]); ok {
+			return errors.New(errBloomExists)
+		}
+		cf, err := kvstore.NewCuckoo(capacity, bucketSize, maxIterations, expansion)
+		if err != nil {
+			return err
+		}
+		tx.Add(args[1], kvstore.NewCuckooObject(cf))
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed(args)
+	return rw.WriteSimpleString("OK")
+}
+
+// CFAddCommand CF.ADD key item (回复 1) / CF.ADDNX key item (已存在时回复 0)
+type CFAddCommand struct {
+	dataset
+	name string
+	nx   bool
+}
+
+func (c *CFAddCommand) Name() string {
+	return c.name
+}
+
+func (c *CFAddCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *CFAddCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *CFAddCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	added, created := false, false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		cf, ok, err := cuckooOrCreate(tx, args[1], kvstore.CuckooDefaultCapacity)
+		if err != nil {
+			return err
+		}
+		created = ok
+		added, err = cuckooAdd(cf, args[2], c.nx)
+		return err
+	})
+	if added || created {
+		c.changed(args)
+	}
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(boolInt(added))
+}
+
+// CFInsertCommand CF.INSERT[NX] key [CAPACITY n] [NOCREATE] ITEMS item [item ...]
+// 每个元素一个结果: 1 插入, 0 已存在 (NX), -1 过滤器已满
+type CFInsertCommand struct {
+	dataset
+	name string
+	nx   bool
+}
+
+func (c *CFInsertCommand) Name() string {
+	return c.name
+}
+
+func (c *CFInsertCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *CFInsertCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *CFInsertCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	capacity := uint64(kvstore.CuckooDefaultCapacity)
+	noCreate := false
+	i := 2
+	for ; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		if opt == "ITEMS" {
+			break
+		}
+		switch opt {
+		case "CAPACITY":
+			var ok bool
+			if i+1 >= len(args) {
+				return rw.WriteError(errSyntax)
+			}
+			if capacity, ok = parseUint(args[i+1]); !ok {
+				return rw.WriteError("ERR Bad capacity")
+			}
+			i++
+		case "NOCREATE":
+			noCreate = true
+		default:
+			return rw.WriteError(errSyntax)
+		}
+	}
+	items := args[min(i+1, len(args)):]
+	if len(items) == 0 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	res := make([]any, 0, len(items))
+	modified := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		if noCreate {
+			cf, err := tx.Cuckoo(args[1])
+			if err != nil {
+				return err
+			}
+			if cf == nil {
+				return errors.New(errBloomNotFound)
+			}
+		}
+		cf, created, err := cuckooOrCreate(tx, args[1], capacity)
+		if err != nil {
+			return err
+		}
+		modified = created
+		for _, item := range items {
+			added, err := cuckooAdd(cf, item, c.nx)
+			if err != nil {
+				res = append(res, int64(-1))
+				continue
+			}
+			modified = modified || added
+			res = append(res, boolInt(added))
+		}
+		return nil
+	})
+	if modified {
+		c.changed(args)
+	}
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// CFExistsCommand CF.EXISTS key item / CF.MEXISTS key item [item ...] / CF.COUNT key item
+// 键不存在时为 0
+type CFExistsCommand struct {
+	dataset
+	name  string
+	multi bool
+	count bool
+}
+
+func (c *CFExistsCommand) Name() string {
+	return c.name
+}
+
+func (c *CFExistsCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *CFExistsCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if (!c.multi && len(args) != 3) || len(args) < 3 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	res := make([]any, len(args)-2)
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		cf, err := tx.Cuckoo(args[1])
+		if err != nil {
+			return err
+		}
+		for i, item := range args[2:] {
+			switch {
+			case cf == nil:
+				res[i] = int64(0)
+			case c.count:
+				res[i] = int64(cf.Count(item))
+			default:
+				res[i] = boolInt(cf.Exists(item))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if !c.multi {
+		return rw.WriteValue(res[0])
+	}
+	return rw.WriteValue(res)
+}
+
+// CFDelCommand CF.DEL key item 删除一个指纹 不存在时回复 0
+type CFDelCommand struct {
+	dataset
+}
+
+func (c *CFDelCommand) Name() string {
+	return "CF.DEL"
+}
+
+func (c *CFDelCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *CFDelCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *CFDelCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("cf.del"))
+	}
+	deleted := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		cf, err := tx.Cuckoo(args[1])
+		if err != nil {
+			return err
+		}
+		if cf == nil {
+			return errors.New(errBloomNotFound)
+		}
+		deleted = cf.Delete(args[2])
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if deleted {
+		c.changed(args)
+	}
+	return rw.WriteInteger(boolInt(deleted))
+}
+
+// CFInfoCommand CF.INFO key
+type CFInfoCommand struct {
+	dataset
+}
+
+func (c *CFInfoCommand) Name() string {
+	return "CF.INFO"
+}
+
+func (c *CFInfoCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *CFInfoCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs("cf.info"))
+	}
+	var info []any
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		cf, err := tx.Cuckoo(args[1])
+		if err != nil {
+			return err
+		}
+		if cf == nil {
+			return errors.New(errBloomNotFound)
+		}
+		info = []any{
+			"Size", int64(cf.Size()),
+			"Number of buckets", int64(cf.NumBuckets()),
+			"Number of filters", int64(len(cf.Layers)),
+			"Number of items inserted", int64(cf.Items),
+			"Number of items deleted", int64(cf.Deletes),
+			"Bucket size", int64(cf.BucketSize),
+			"Expansion rate", int64(cf.Expansion),
+			"Max iterations", int64(cf.MaxIterations),
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(info)
+}
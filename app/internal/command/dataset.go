@@ -0,0 +1,72 @@
+package command
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/rdb"
+)
+
+// dataset 数据类型命令 (列表/哈希/集合等) 共用的依赖
+type dataset struct {
+	store  *kvstore.Store
+	fn     string
+	master replication.MasterServerInterface
+}
+
+// changed 写命令修改了数据集: 更新 RDB 并传播到副本
+// 没有修改数据的写命令 (如 LPOP 空列表) 不调用
+func (d *dataset) changed(args []string) {
+	_ = rdb.UpdateRDB(d.fn, d.store)
+	if d.master != nil {
+		if err := d.master.PropagateToReplicas(args); err != nil {
+			log.Printf("Failed to propagate %s command: %v", strings.ToUpper(args[0]), err)
+		}
+	}
+}
+
+// changedAll 一次修改以多条命令传播 (如 XCLAIM 每个被认领的条目一条) RDB 只更新一次
+func (d *dataset) changedAll(cmds [][]string) {
+	_ = rdb.UpdateRDB(d.fn, d.store)
+	if d.master == nil {
+		return
+	}
+	for _, args := range cmds {
+		if err := d.master.PropagateToReplicas(args); err != nil {
+			log.Printf("Failed to propagate %s command: %v", strings.ToUpper(args[0]), err)
+		}
+	}
+}
+
+const (
+	errNotInteger = "ERR value is not an integer or out of range"
+	errSyntax     = "ERR syntax error"
+)
+
+func errWrongArgs(name string) string {
+	return "ERR wrong number of arguments for '" + strings.ToLower(name) + "' command"
+}
+
+// parseInt 解析整数参数 失败时返回客户端错误
+func parseInt(s string) (int64, bool) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+// parseFloat 解析浮点数参数 不接受 NaN
+func parseFloat(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+// formatFloat 浮点数结果的字符串形式 (HINCRBYFLOAT 等) 使用最短的可还原表示
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"context"
+	"log"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/rdb"
+)
+
+// DelCommand DEL key [key ...] 返回删除的键数
+type DelCommand struct {
+	store  *kvstore.Store
+	fn     string
+	master replication.MasterServerInterface
+}
+
+func NewDelCommand(store *kvstore.Store, fn string, master replication.MasterServerInterface) *DelCommand {
+	return &DelCommand{store: store, fn: fn, master: master}
+}
+
+func (c *DelCommand) Name() string {
+	return "DEL"
+}
+
+func (c *DelCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *DelCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: -1, Step: 1}
+}
+
+func (c *DelCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError("ERR wrong number of arguments for 'del' command")
+	}
+	deleted := 0
+	for _, key := range args[1:] {
+		if c.store.Exists(key) {
+			c.store.Delete(key)
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		_ = rdb.UpdateRDB(c.fn, c.store)
+		if c.master != nil {
+			if err := c.master.PropagateToReplicas(args); err != nil {
+				log.Printf("Failed to propagate DEL command: %v", err)
+			}
+		}
+	}
+	return rw.WriteInteger(int64(deleted))
+}
@@ -0,0 +1,52 @@
+package command
+
+import (
+	"context"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/rdb"
+)
+
+// DumpCommand DUMP key 返回值的序列化载荷 键不存在时返回 nil
+type DumpCommand struct {
+	store *kvstore.Store
+}
+
+func NewDumpCommand(store *kvstore.Store) *DumpCommand {
+	return &DumpCommand{store: store}
+}
+
+func (c *DumpCommand) Name() string {
+	return "DUMP"
+}
+
+func (c *DumpCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *DumpCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError("ERR wrong number of arguments for 'dump' command")
+	}
+	// 容器类型的对象被原地修改 序列化需在锁内完成
+	var payload []byte
+	found := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, ok := tx.Lookup(args[1])
+		if !ok {
+			return nil
+		}
+		found = true
+		var err error
+		payload, err = rdb.Dump(o)
+		return err
+	})
+	if err != nil {
+		return rw.WriteError("ERR " + err.Error())
+	}
+	if !found {
+		return rw.WriteNull()
+	}
+	return rw.WriteBulkString(string(payload))
+}
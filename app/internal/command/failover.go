@@ -0,0 +1,73 @@
+package command
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+)
+
+// FailoverCommand FAILOVER [TO host port [FORCE]] [ABORT] [TIMEOUT ms]
+// 立即返回 OK, 切换在后台进行, 进度见 INFO replication 的 master_failover_state
+type FailoverCommand struct {
+	fc replication.FailoverCoordinator
+}
+
+func NewFailoverCommand(fc replication.FailoverCoordinator) *FailoverCommand {
+	return &FailoverCommand{fc: fc}
+}
+
+func (c *FailoverCommand) Name() string {
+	return "FAILOVER"
+}
+
+func (c *FailoverCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	var opts replication.FailoverOptions
+	abort := false
+	for i := 1; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "TO":
+			if i+2 >= len(args) {
+				return rw.WriteError("ERR syntax error")
+			}
+			if _, err := strconv.ParseUint(args[i+2], 10, 16); err != nil {
+				return rw.WriteError("ERR value is not an integer or out of range")
+			}
+			opts.Host, opts.Port = args[i+1], args[i+2]
+			i += 2
+		case "TIMEOUT":
+			if i+1 >= len(args) {
+				return rw.WriteError("ERR syntax error")
+			}
+			ms, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || ms <= 0 {
+				return rw.WriteError("ERR FAILOVER timeout must be greater than 0")
+			}
+			opts.Timeout = time.Duration(ms) * time.Millisecond
+			i++
+		case "FORCE":
+			opts.Force = true
+		case "ABORT":
+			abort = true
+		default:
+			return rw.WriteError("ERR syntax error")
+		}
+	}
+
+	if abort {
+		if opts.Host != "" || opts.Timeout > 0 || opts.Force {
+			return rw.WriteError("ERR FAILOVER ABORT can't be combined with other arguments")
+		}
+		if err := c.fc.AbortFailover(); err != nil {
+			return rw.WriteError(err.Error())
+		}
+		return rw.WriteSimpleString("OK")
+	}
+	if err := c.fc.Failover(opts); err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteSimpleString("OK")
+}
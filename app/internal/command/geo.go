@@ -0,0 +1,642 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// GeoCommands 地理位置命令 数据保存在有序集合中 分值为 52 位 geohash
+func GeoCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&GeoAddCommand{zadd: &ZAddCommand{dataset: d}},
+		&GeoPosCommand{dataset: d},
+		&GeoDistCommand{dataset: d},
+		&GeoHashCommand{dataset: d},
+		&GeoSearchCommand{dataset: d, name: "GEOSEARCH"},
+		&GeoSearchCommand{dataset: d, name: "GEOSEARCHSTORE", store: true},
+		&GeoRadiusCommand{dataset: d, name: "GEORADIUS"},
+		&GeoRadiusCommand{dataset: d, name: "GEORADIUS_RO", ro: true},
+		&GeoRadiusCommand{dataset: d, name: "GEORADIUSBYMEMBER", byMember: true},
+		&GeoRadiusCommand{dataset: d, name: "GEORADIUSBYMEMBER_RO", byMember: true, ro: true},
+	}
+}
+
+const errGeoUnit = "ERR unsupported unit provided. please use M, KM, FT, MI"
+
+// parseGeoUnit 单位换算为米的系数
+func parseGeoUnit(s string) (float64, bool) {
+	switch strings.ToLower(s) {
+	case "m":
+		return 1, true
+	case "km":
+		return 1000, true
+	case "ft":
+		return 0.3048, true
+	case "mi":
+		return 1609.34, true
+	}
+	return 0, false
+}
+
+// parseLonLat 解析并检查经纬度
+func parseLonLat(lonArg, latArg string) (lon, lat float64, reply string) {
+	lon, ok1 := parseFloat(lonArg)
+	lat, ok2 := parseFloat(latArg)
+	if !ok1 || !ok2 {
+		return 0, 0, errNotFloat
+	}
+	if !kvstore.GeoValid(lon, lat) {
+		return 0, 0, fmt.Sprintf("ERR invalid longitude,latitude pair %f,%f", lon, lat)
+	}
+	return lon, lat, ""
+}
+
+// formatGeoCoord 坐标的回复 17 位小数并去掉末尾的 0
+func formatGeoCoord(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 17, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func formatGeoDist(d float64) string {
+	return strconv.FormatFloat(d, 'f', 4, 64)
+}
+
+func geoPosition(score float64) []any {
+	lon, lat := kvstore.GeoPosition(score)
+	return []any{formatGeoCoord(lon), formatGeoCoord(lat)}
+}
+
+// GeoAddCommand GEOADD key [NX|XX] [CH] longitude latitude member [...]
+// 转换为 ZADD 执行 复制流中也以 ZADD 传播
+type GeoAddCommand struct {
+	zadd *ZAddCommand
+}
+
+func (c *GeoAddCommand) Name() string {
+	return "GEOADD"
+}
+
+func (c *GeoAddCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *GeoAddCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *GeoAddCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 5 {
+		return rw.WriteError(errWrongArgs("geoadd"))
+	}
+	zargs := []string{"ZADD", args[1]}
+	var nx, xx bool
+	i := 2
+opts:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "CH":
+		default:
+			break opts
+		}
+		zargs = append(zargs, args[i])
+	}
+	triples := args[i:]
+	if len(triples) == 0 || len(triples)%3 != 0 || (nx && xx) {
+		return rw.WriteError(errSyntax)
+	}
+	for j := 0; j < len(triples); j += 3 {
+		lon, lat, reply := parseLonLat(triples[j], triples[j+1])
+		if reply != "" {
+			return rw.WriteError(reply)
+		}
+		score := uint64(kvstore.GeoScore(lon, lat))
+		zargs = append(zargs, strconv.FormatUint(score, 10), triples[j+2])
+	}
+	return c.zadd.Execute(ctx, rw, zargs)
+}
+
+// GeoPosCommand GEOPOS key [member ...] 不存在的成员为 nil
+type GeoPosCommand struct {
+	dataset
+}
+
+func (c *GeoPosCommand) Name() string {
+	return "GEOPOS"
+}
+
+func (c *GeoPosCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *GeoPosCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs("geopos"))
+	}
+	res := make([]any, len(args)-2)
+	for i := range res {
+		res[i] = protocol.NullArray
+	}
+	err := c.readZSet(args[1], func(z *kvstore.ZSet) {
+		for i, m := range args[2:] {
+			if score, ok := z.Score(m); ok {
+				res[i] = geoPosition(score)
+			}
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// GeoDistCommand GEODIST key member1 member2 [M|KM|FT|MI] 任一成员不存在时为 nil
+type GeoDistCommand struct {
+	dataset
+}
+
+func (c *GeoDistCommand) Name() string {
+	return "GEODIST"
+}
+
+func (c *GeoDistCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *GeoDistCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 {
+		return rw.WriteError(errWrongArgs("geodist"))
+	}
+	if len(args) > 5 {
+		return rw.WriteError(errSyntax)
+	}
+	unit := 1.0
+	if len(args) == 5 {
+		var ok bool
+		if unit, ok = parseGeoUnit(args[4]); !ok {
+			return rw.WriteError(errGeoUnit)
+		}
+	}
+	var res any
+	err := c.readZSet(args[1], func(z *kvstore.ZSet) {
+		s1, ok1 := z.Score(args[2])
+		s2, ok2 := z.Score(args[3])
+		if !ok1 || !ok2 {
+			return
+		}
+		lon1, lat1 := kvstore.GeoPosition(s1)
+		lon2, lat2 := kvstore.GeoPosition(s2)
+		res = formatGeoDist(kvstore.GeoDistance(lon1, lat1, lon2, lat2) / unit)
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// GeoHashCommand GEOHASH key [member ...] 标准的 11 位 geohash 字符串
+type GeoHashCommand struct {
+	dataset
+}
+
+func (c *GeoHashCommand) Name() string {
+	return "GEOHASH"
+}
+
+func (c *GeoHashCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *GeoHashCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs("geohash"))
+	}
+	res := make([]any, len(args)-2)
+	err := c.readZSet(args[1], func(z *kvstore.ZSet) {
+		for i, m := range args[2:] {
+			if score, ok := z.Score(m); ok {
+				res[i] = kvstore.GeoHashString(score)
+			}
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// geoQuery GEOSEARCH 与 GEORADIUS 解析后的查询
+type geoQuery struct {
+	key        string
+	fromMember bool
+	member     string
+	shape      kvstore.GeoShape // 中心为 FROMMEMBER 时在查询时填入
+	unit       float64
+	sort       int // 0 不排序, 1 升序, -1 降序
+	count      int64
+	any        bool
+	withDist   bool
+	withHash   bool
+	withCoord  bool
+	storeKey   string
+	storeDist  bool
+}
+
+// geoPoint 命中的成员
+type geoPoint struct {
+	member   string
+	score    float64
+	dist     float64 // 以查询的单位表示
+	lon, lat float64
+}
+
+// geoSearch 在一次 Update 内查询 指定了 storeKey 时把结果写入目标键
+func (d *dataset) geoSearch(q *geoQuery) ([]geoPoint, bool, string, error) {
+	var points []geoPoint
+	stored := false
+	reply := ""
+	err := d.store.Update(func(tx *kvstore.Tx) error {
+		z, err := tx.ZSet(q.key)
+		if err != nil {
+			return err
+		}
+		if z != nil {
+			if q.fromMember {
+				score, ok := z.Score(q.member)
+				if !ok {
+					reply = "ERR could not decode requested zset member"
+					return nil
+				}
+				q.shape.Lon, q.shape.Lat = kvstore.GeoPosition(score)
+			}
+			points = q.collect(z)
+		}
+		if q.storeKey == "" {
+			return nil
+		}
+		if len(points) == 0 {
+			_, stored = tx.Lookup(q.storeKey)
+			tx.Delete(q.storeKey)
+			return nil
+		}
+		entries := make([]kvstore.ZEntry, len(points))
+		for i, p := range points {
+			entries[i] = kvstore.ZEntry{Member: p.member, Score: p.score}
+			if q.storeDist {
+				entries[i].Score = p.dist
+			}
+		}
+		tx.Put(q.storeKey, kvstore.NewZSetObjectFrom(entries), 0)
+		stored = true
+		return nil
+	})
+	return points, stored, reply, err
+}
+
+// collect 扫描中心及相邻 geohash 区域对应的分值区间 按实际距离过滤
+func (q *geoQuery) collect(z *kvstore.ZSet) []geoPoint {
+	limit := 0
+	if q.any {
+		limit = int(q.count)
+	}
+	var points []geoPoint
+scan:
+	for _, r := range q.shape.SearchRanges() {
+		for _, e := range z.RangeByScore(r, false, 0, -1) {
+			lon, lat := kvstore.GeoPosition(e.Score)
+			dist, ok := q.shape.Within(lon, lat)
+			if !ok {
+				continue
+			}
+			points = append(points, geoPoint{member: e.Member, score: e.Score, dist: dist / q.unit, lon: lon, lat: lat})
+			if limit > 0 && len(points) >= limit {
+				break scan
+			}
+		}
+	}
+	// 不带 ANY 的 COUNT 需要最近的 N 个 默认按升序
+	order := q.sort
+	if q.count > 0 && order == 0 && !q.any {
+		order = 1
+	}
+	if order != 0 {
+		sort.SliceStable(points, func(i, j int) bool {
+			if order > 0 {
+				return points[i].dist < points[j].dist
+			}
+			return points[i].dist > points[j].dist
+		})
+	}
+	if q.count > 0 && int64(len(points)) > q.count {
+		points = points[:q.count]
+	}
+	return points
+}
+
+// reply 只有成员名 或每个成员一个数组: 名称 [距离] [geohash] [坐标]
+func (q *geoQuery) reply(points []geoPoint) []any {
+	res := make([]any, len(points))
+	for i, p := range points {
+		if !q.withDist && !q.withHash && !q.withCoord {
+			res[i] = p.member
+			continue
+		}
+		item := []any{p.member}
+		if q.withDist {
+			item = append(item, formatGeoDist(p.dist))
+		}
+		if q.withHash {
+			item = append(item, int64(p.score))
+		}
+		if q.withCoord {
+			item = append(item, []any{formatGeoCoord(p.lon), formatGeoCoord(p.lat)})
+		}
+		res[i] = item
+	}
+	return res
+}
+
+// parseCount COUNT count
+func (q *geoQuery) parseCount(arg string) string {
+	n, ok := parseInt(arg)
+	if !ok {
+		return errNotInteger
+	}
+	if n <= 0 {
+		return "ERR COUNT must be > 0"
+	}
+	q.count = n
+	return ""
+}
+
+// parseRadius radius unit
+func (q *geoQuery) parseRadius(radius, unit string) string {
+	r, ok := parseFloat(radius)
+	if !ok {
+		return "ERR need numeric radius"
+	}
+	if r < 0 {
+		return "ERR radius cannot be negative"
+	}
+	if q.unit, ok = parseGeoUnit(unit); !ok {
+		return errGeoUnit
+	}
+	q.shape.Radius = r * q.unit
+	return ""
+}
+
+// GeoSearchCommand GEOSEARCH key FROMMEMBER member|FROMLONLAT lon lat BYRADIUS radius unit|BYBOX width height unit
+// [ASC|DESC] [COUNT count [ANY]] [WITHCOORD] [WITHDIST] [WITHHASH]
+// GEOSEARCHSTORE destination source ... [STOREDIST] 返回写入的成员数
+type GeoSearchCommand struct {
+	dataset
+	name  string
+	store bool
+}
+
+func (c *GeoSearchCommand) Name() string {
+	return c.name
+}
+
+func (c *GeoSearchCommand) Flags() Flag {
+	if c.store {
+		return FlagWrite
+	}
+	return 0
+}
+
+func (c *GeoSearchCommand) KeySpec() KeySpec {
+	if c.store {
+		return KeySpec{First: 1, Last: 2, Step: 1}
+	}
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *GeoSearchCommand) parse(args []string) (*geoQuery, string) {
+	q := &geoQuery{key: args[1]}
+	opts := args[2:]
+	if c.store {
+		q.storeKey, q.key, opts = args[1], args[2], args[3:]
+	}
+	var from, by int
+	for i := 0; i < len(opts); i++ {
+		rest := len(opts) - i - 1
+		switch arg := strings.ToUpper(opts[i]); {
+		case arg == "FROMMEMBER" && rest >= 1:
+			q.fromMember, q.member = true, opts[i+1]
+			from++
+			i++
+		case arg == "FROMLONLAT" && rest >= 2:
+			lon, lat, reply := parseLonLat(opts[i+1], opts[i+2])
+			if reply != "" {
+				return nil, reply
+			}
+			q.shape.Lon, q.shape.Lat = lon, lat
+			from++
+			i += 2
+		case arg == "BYRADIUS" && rest >= 2:
+			if reply := q.parseRadius(opts[i+1], opts[i+2]); reply != "" {
+				return nil, reply
+			}
+			by++
+			i += 2
+		case arg == "BYBOX" && rest >= 3:
+			w, ok := parseFloat(opts[i+1])
+			if !ok {
+				return nil, "ERR need numeric width"
+			}
+			h, ok := parseFloat(opts[i+2])
+			if !ok {
+				return nil, "ERR need numeric height"
+			}
+			if w < 0 || h < 0 {
+				return nil, "ERR height or width cannot be negative"
+			}
+			if q.unit, ok = parseGeoUnit(opts[i+3]); !ok {
+				return nil, errGeoUnit
+			}
+			q.shape.Box, q.shape.Width, q.shape.Height = true, w*q.unit, h*q.unit
+			by++
+			i += 3
+		case arg == "ASC":
+			q.sort = 1
+		case arg == "DESC":
+			q.sort = -1
+		case arg == "ANY":
+			q.any = true
+		case arg == "COUNT" && rest >= 1:
+			if reply := q.parseCount(opts[i+1]); reply != "" {
+				return nil, reply
+			}
+			i++
+		case arg == "WITHDIST" && !c.store:
+			q.withDist = true
+		case arg == "WITHHASH" && !c.store:
+			q.withHash = true
+		case arg == "WITHCOORD" && !c.store:
+			q.withCoord = true
+		case arg == "STOREDIST" && c.store:
+			q.storeDist = true
+		default:
+			return nil, errSyntax
+		}
+	}
+	if from != 1 {
+		return nil, "ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for " + args[0]
+	}
+	if by != 1 {
+		return nil, "ERR exactly one of BYRADIUS and BYBOX can be specified for " + args[0]
+	}
+	if q.any && q.count == 0 {
+		return nil, "ERR the ANY argument requires COUNT argument"
+	}
+	return q, ""
+}
+
+func (c *GeoSearchCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if (c.store && len(args) < 4) || len(args) < 3 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	q, reply := c.parse(args)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	return c.geoExecute(rw, q, args)
+}
+
+// geoExecute 执行查询并回复 写入目标键时传播原命令
+func (d *dataset) geoExecute(rw protocol.ResponseWriter, q *geoQuery, args []string) error {
+	points, stored, reply, err := d.geoSearch(q)
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	if q.storeKey == "" {
+		return rw.WriteValue(q.reply(points))
+	}
+	if stored {
+		d.changed(args)
+	}
+	return rw.WriteInteger(int64(len(points)))
+}
+
+// GeoRadiusCommand GEORADIUS key longitude latitude radius unit / GEORADIUSBYMEMBER key member radius unit
+// [WITHCOORD] [WITHDIST] [WITHHASH] [COUNT count [ANY]] [ASC|DESC] [STORE key] [STOREDIST key]
+// _RO 版本不支持 STORE
+type GeoRadiusCommand struct {
+	dataset
+	name     string
+	byMember bool
+	ro       bool
+}
+
+func (c *GeoRadiusCommand) Name() string {
+	return c.name
+}
+
+func (c *GeoRadiusCommand) Flags() Flag {
+	if c.ro {
+		return 0
+	}
+	return FlagWrite
+}
+
+// positional 选项之前的参数个数
+func (c *GeoRadiusCommand) positional() int {
+	if c.byMember {
+		return 5
+	}
+	return 6
+}
+
+// FindKeys 查询的键以及 STORE/STOREDIST 的目标键
+func (c *GeoRadiusCommand) FindKeys(args []string) []string {
+	if len(args) < 2 {
+		return nil
+	}
+	keys := []string{args[1]}
+	for i := c.positional(); i+1 < len(args); i++ {
+		if opt := strings.ToUpper(args[i]); opt == "STORE" || opt == "STOREDIST" {
+			keys = append(keys, args[i+1])
+			i++
+		}
+	}
+	return keys
+}
+
+func (c *GeoRadiusCommand) parse(args []string) (*geoQuery, string) {
+	q := &geoQuery{key: args[1]}
+	pos := c.positional()
+	if c.byMember {
+		q.fromMember, q.member = true, args[2]
+	} else {
+		lon, lat, reply := parseLonLat(args[2], args[3])
+		if reply != "" {
+			return nil, reply
+		}
+		q.shape.Lon, q.shape.Lat = lon, lat
+	}
+	if reply := q.parseRadius(args[pos-2], args[pos-1]); reply != "" {
+		return nil, reply
+	}
+	opts := args[pos:]
+	for i := 0; i < len(opts); i++ {
+		rest := len(opts) - i - 1
+		switch arg := strings.ToUpper(opts[i]); {
+		case arg == "WITHDIST":
+			q.withDist = true
+		case arg == "WITHHASH":
+			q.withHash = true
+		case arg == "WITHCOORD":
+			q.withCoord = true
+		case arg == "ANY":
+			q.any = true
+		case arg == "ASC":
+			q.sort = 1
+		case arg == "DESC":
+			q.sort = -1
+		case arg == "COUNT" && rest >= 1:
+			if reply := q.parseCount(opts[i+1]); reply != "" {
+				return nil, reply
+			}
+			i++
+		case (arg == "STORE" || arg == "STOREDIST") && rest >= 1 && !c.ro:
+			q.storeKey, q.storeDist = opts[i+1], arg == "STOREDIST"
+			i++
+		default:
+			return nil, errSyntax
+		}
+	}
+	if q.storeKey != "" && (q.withDist || q.withHash || q.withCoord) {
+		return nil, "ERR STORE option in " + c.name + " is not compatible with WITHDIST, WITHHASH and WITHCOORD options"
+	}
+	if q.any && q.count == 0 {
+		return nil, "ERR the ANY argument requires COUNT argument"
+	}
+	return q, ""
+}
+
+func (c *GeoRadiusCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < c.positional() {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	q, reply := c.parse(args)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	return c.geoExecute(rw, q, args)
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
 	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/rdb"
-	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
 )
 
 type GetCommand struct {
@@ -23,39 +22,42 @@ func (c *GetCommand) Name() string {
 	return "GET"
 }
 
+func (c *GetCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
 func (c *GetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError("ERR wrong number of arguments for 'get' command")
+	}
 	// 从rdb文件中查找
-	if len(c.store.Data) == 0 {
+	if c.store.Len() == 0 {
 		log.Printf("Get from rdb")
 		kv, err := rdb.GetRDBkeys(c.fn)
 		if err != nil {
+			// 文件不存在或无法解析 视为空数据集
 			log.Printf("LS HandleCmd `GET` GetRDBkeys func Wrong: %s", err)
-			return err
+			return rw.WriteNull()
 		}
-		found := false
 		// 在RDB文件的kv组合中查找key
 		for i := 0; i < len(kv); i += 2 {
 			// GET + 查找键值
 			if kv[i] == args[1] {
 				log.Printf("GET bulkStringFmt: %s", kv[i+1])
-				// conn.Write(rfmt.BulkStringFmt(kv[i+1]))
-				found = true
 				return rw.WriteBulkString(kv[i+1])
 			}
 		}
-		// 没有对应key
-		if !found {
-			return errors_r.ErrKeyNotFoundInRDB
-		}
-	} else {
-		// 从Map中查找
-		log.Printf("Get from Map, Received GET, getting %s", args[1])
-		OP, ok := c.store.Get(args[1])
-		if !ok {
-			log.Printf("ERROR GET: %s", args[1])
-			return errors_r.ErrKeyNotFoundInMap
-		}
-		return rw.WriteBulkString(OP)
+		// 没有对应key 回复 nil
+		return rw.WriteNull()
+	}
+	// 从Map中查找
+	log.Printf("Get from Map, Received GET, getting %s", args[1])
+	OP, ok, err := c.store.GetString(args[1])
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if !ok {
+		return rw.WriteNull()
 	}
-	return errors_r.ErrKeyNotFound
+	return rw.WriteBulkString(OP)
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"strings"
 
+	"github.com/codecrafters-io/redis-starter-go/app/internal/blocking"
 	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
 )
 
@@ -15,6 +16,75 @@ type Handler interface {
 	// Arity() int // 参数数量，-1 表示可变参数
 }
 
+// Flag 命令标志
+type Flag uint32
+
+const (
+	FlagWrite  Flag = 1 << iota // 修改数据集 只读从节点拒绝客户端执行
+	FlagAsking                  // 隐含 ASKING (RESTORE-ASKING) 集群模式下可写入迁入中的槽
+)
+
+// Flagged 可选接口 需要声明标志的命令实现
+type Flagged interface {
+	Flags() Flag
+}
+
+// FlagsOf 返回命令的标志 未实现 Flagged 的命令没有任何标志
+func FlagsOf(h Handler) Flag {
+	if f, ok := h.(Flagged); ok {
+		return f.Flags()
+	}
+	return 0
+}
+
+// KeySpec 命令参数中键的位置 (args[0] 为命令名)
+// Last 为负数时从末尾倒数: -1 表示最后一个参数
+type KeySpec struct {
+	First int
+	Last  int
+	Step  int
+}
+
+// Keyed 可选接口 操作键的命令声明键的位置 集群模式据此计算槽并重定向
+type Keyed interface {
+	KeySpec() KeySpec
+}
+
+// KeyFinder 可选接口 键的位置取决于参数的命令 (如带 numkeys 的命令) 自行提取
+type KeyFinder interface {
+	FindKeys(args []string) []string
+}
+
+// Blocker 可选接口 阻塞命令 (BLPOP 等)
+// Block 在写锁内执行: 有可用数据时直接回复并返回 nil; 否则注册等待者,
+// 调用方释放写锁后调用 Waiter.Wait 并写回复. 复制流与 Execute 中按非阻塞方式执行 (没有数据时立即超时)
+// ctx 随客户端连接断开而取消, 注册等待者时传入 Registry.Block
+type Blocker interface {
+	Block(ctx context.Context, rw protocol.ResponseWriter, args []string) (*blocking.Waiter, error)
+}
+
+// KeysOf 返回命令访问的键 不访问键的命令返回 nil
+func KeysOf(h Handler, args []string) []string {
+	if f, ok := h.(KeyFinder); ok {
+		return f.FindKeys(args)
+	}
+	k, ok := h.(Keyed)
+	if !ok {
+		return nil
+	}
+	spec := k.KeySpec()
+	last := spec.Last
+	if last < 0 {
+		last = len(args) + last
+	}
+	step := max(spec.Step, 1)
+	var keys []string
+	for i := spec.First; i <= last && i < len(args); i += step {
+		keys = append(keys, args[i])
+	}
+	return keys
+}
+
 // // Command 命令注册结构
 // type Command struct {
 // 	Name    string
@@ -0,0 +1,640 @@
+package command
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// HashCommands 哈希类型的全部命令 (含字段过期时间命令)
+func HashCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&HSetCommand{dataset: d, name: "HSET"},
+		&HSetCommand{dataset: d, name: "HMSET"},
+		&HSetNXCommand{dataset: d},
+		&HGetCommand{dataset: d},
+		&HMGetCommand{dataset: d},
+		&HDelCommand{dataset: d},
+		&HGetAllCommand{dataset: d, name: "HGETALL", fields: true, values: true},
+		&HGetAllCommand{dataset: d, name: "HKEYS", fields: true},
+		&HGetAllCommand{dataset: d, name: "HVALS", values: true},
+		&HLenCommand{dataset: d},
+		&HExistsCommand{dataset: d},
+		&HStrLenCommand{dataset: d},
+		&HIncrByCommand{dataset: d},
+		&HIncrByFloatCommand{dataset: d},
+		&HRandFieldCommand{dataset: d},
+		&HScanCommand{dataset: d},
+		&HExpireCommand{dataset: d, name: "HEXPIRE", unit: 1000},
+		&HExpireCommand{dataset: d, name: "HPEXPIRE", unit: 1},
+		&HExpireCommand{dataset: d, name: "HEXPIREAT", unit: 1000, abs: true},
+		&HExpireCommand{dataset: d, name: "HPEXPIREAT", unit: 1, abs: true},
+		&HTTLCommand{dataset: d, name: "HTTL", unit: 1000},
+		&HTTLCommand{dataset: d, name: "HPTTL", unit: 1},
+		&HTTLCommand{dataset: d, name: "HEXPIRETIME", unit: 1000, abs: true},
+		&HTTLCommand{dataset: d, name: "HPEXPIRETIME", unit: 1, abs: true},
+		&HPersistCommand{dataset: d},
+	}
+}
+
+// readHash 在一次 Update 内读取哈希 键不存在时 h 为 nil
+func (d *dataset) readHash(key string, fn func(h *kvstore.Hash)) error {
+	return d.store.Update(func(tx *kvstore.Tx) error {
+		h, err := tx.Hash(key)
+		if err == nil && h != nil {
+			fn(h)
+		}
+		return err
+	})
+}
+
+// HSetCommand HSET key field value [field value ...] 返回新增的字段数
+// HMSET 参数相同 返回 OK
+type HSetCommand struct {
+	dataset
+	name string
+}
+
+func (c *HSetCommand) Name() string {
+	return c.name
+}
+
+func (c *HSetCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *HSetCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HSetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 || len(args)%2 != 0 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	key := args[1]
+	added := 0
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		h, err := tx.HashOrCreate(key)
+		if err != nil {
+			return err
+		}
+		for i := 2; i < len(args); i += 2 {
+			if h.Set(args[i], args[i+1]) {
+				added++
+			}
+		}
+		tx.HashChanged(key, args[2:]...)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed(args)
+	if c.name == "HMSET" {
+		return rw.WriteSimpleString("OK")
+	}
+	return rw.WriteInteger(int64(added))
+}
+
+// HSetNXCommand HSETNX key field value 字段不存在时写入
+type HSetNXCommand struct {
+	dataset
+}
+
+func (c *HSetNXCommand) Name() string {
+	return "HSETNX"
+}
+
+func (c *HSetNXCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *HSetNXCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HSetNXCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs("hsetnx"))
+	}
+	key := args[1]
+	set := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		h, err := tx.HashOrCreate(key)
+		if err != nil {
+			return err
+		}
+		if _, exists := h.Get(args[2]); !exists {
+			set = h.Set(args[2], args[3])
+			tx.HashChanged(key, args[2:]...)
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if !set {
+		return rw.WriteInteger(0)
+	}
+	c.changed(args)
+	return rw.WriteInteger(1)
+}
+
+// HGetCommand HGET key field
+type HGetCommand struct {
+	dataset
+}
+
+func (c *HGetCommand) Name() string {
+	return "HGET"
+}
+
+func (c *HGetCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HGetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("hget"))
+	}
+	var reply any
+	err := c.readHash(args[1], func(h *kvstore.Hash) {
+		if v, ok := h.Get(args[2]); ok {
+			reply = v
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(reply)
+}
+
+// HMGetCommand HMGET key field [field ...] 不存在的字段为 nil
+type HMGetCommand struct {
+	dataset
+}
+
+func (c *HMGetCommand) Name() string {
+	return "HMGET"
+}
+
+func (c *HMGetCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HMGetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("hmget"))
+	}
+	res := make([]any, len(args)-2)
+	err := c.readHash(args[1], func(h *kvstore.Hash) {
+		for i, f := range args[2:] {
+			if v, ok := h.Get(f); ok {
+				res[i] = v
+			}
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// HDelCommand HDEL key field [field ...] 返回删除的字段数
+type HDelCommand struct {
+	dataset
+}
+
+func (c *HDelCommand) Name() string {
+	return "HDEL"
+}
+
+func (c *HDelCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *HDelCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HDelCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("hdel"))
+	}
+	key := args[1]
+	deleted := 0
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		h, err := tx.Hash(key)
+		if err != nil || h == nil {
+			return err
+		}
+		for _, f := range args[2:] {
+			if h.Delete(f) {
+				deleted++
+			}
+		}
+		tx.HashChanged(key)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if deleted > 0 {
+		c.changed(args)
+	}
+	return rw.WriteInteger(int64(deleted))
+}
+
+// HGetAllCommand HGETALL|HKEYS|HVALS key
+type HGetAllCommand struct {
+	dataset
+	name           string
+	fields, values bool
+}
+
+func (c *HGetAllCommand) Name() string {
+	return c.name
+}
+
+func (c *HGetAllCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HGetAllCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	res := []string{}
+	err := c.readHash(args[1], func(h *kvstore.Hash) {
+		for _, f := range h.Fields() {
+			if c.fields {
+				res = append(res, f)
+			}
+			if c.values {
+				v, _ := h.Get(f)
+				res = append(res, v)
+			}
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteArray(res)
+}
+
+// HLenCommand HLEN key
+type HLenCommand struct {
+	dataset
+}
+
+func (c *HLenCommand) Name() string {
+	return "HLEN"
+}
+
+func (c *HLenCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HLenCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs("hlen"))
+	}
+	n := 0
+	err := c.readHash(args[1], func(h *kvstore.Hash) {
+		n = h.Len()
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(int64(n))
+}
+
+// HExistsCommand HEXISTS key field
+type HExistsCommand struct {
+	dataset
+}
+
+func (c *HExistsCommand) Name() string {
+	return "HEXISTS"
+}
+
+func (c *HExistsCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HExistsCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("hexists"))
+	}
+	n := 0
+	err := c.readHash(args[1], func(h *kvstore.Hash) {
+		if _, ok := h.Get(args[2]); ok {
+			n = 1
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(int64(n))
+}
+
+// HStrLenCommand HSTRLEN key field
+type HStrLenCommand struct {
+	dataset
+}
+
+func (c *HStrLenCommand) Name() string {
+	return "HSTRLEN"
+}
+
+func (c *HStrLenCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HStrLenCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("hstrlen"))
+	}
+	n := 0
+	err := c.readHash(args[1], func(h *kvstore.Hash) {
+		v, _ := h.Get(args[2])
+		n = len(v)
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(int64(n))
+}
+
+// HIncrByCommand HINCRBY key field increment 字段保留原有的过期时间
+type HIncrByCommand struct {
+	dataset
+}
+
+func (c *HIncrByCommand) Name() string {
+	return "HINCRBY"
+}
+
+func (c *HIncrByCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *HIncrByCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HIncrByCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs("hincrby"))
+	}
+	incr, ok := parseInt(args[3])
+	if !ok {
+		return rw.WriteError(errNotInteger)
+	}
+	key := args[1]
+	var n int64
+	reply := ""
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		h, err := tx.HashOrCreate(key)
+		if err != nil {
+			return err
+		}
+		defer tx.HashChanged(key)
+		if v, exists := h.Get(args[2]); exists {
+			if n, ok = parseInt(v); !ok {
+				reply = "ERR hash value is not an integer"
+				return nil
+			}
+		}
+		if (incr > 0 && n > math.MaxInt64-incr) || (incr < 0 && n < math.MinInt64-incr) {
+			reply = "ERR increment or decrement would overflow"
+			return nil
+		}
+		n += incr
+		v := strconv.FormatInt(n, 10)
+		if _, exists := h.Get(args[2]); exists {
+			h.Update(args[2], v)
+		} else {
+			h.Set(args[2], v)
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	c.changed(args)
+	return rw.WriteInteger(n)
+}
+
+// HIncrByFloatCommand HINCRBYFLOAT key field increment
+type HIncrByFloatCommand struct {
+	dataset
+}
+
+func (c *HIncrByFloatCommand) Name() string {
+	return "HINCRBYFLOAT"
+}
+
+func (c *HIncrByFloatCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *HIncrByFloatCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HIncrByFloatCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs("hincrbyfloat"))
+	}
+	incr, ok := parseFloat(args[3])
+	if !ok {
+		return rw.WriteError("ERR value is not a valid float")
+	}
+	key := args[1]
+	result, reply := "", ""
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		h, err := tx.HashOrCreate(key)
+		if err != nil {
+			return err
+		}
+		defer tx.HashChanged(key)
+		f := 0.0
+		v, exists := h.Get(args[2])
+		if exists {
+			if f, ok = parseFloat(v); !ok {
+				reply = "ERR hash value is not a float"
+				return nil
+			}
+		}
+		f += incr
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			reply = "ERR increment would produce NaN or Infinity"
+			return nil
+		}
+		result = formatFloat(f)
+		if exists {
+			h.Update(args[2], result)
+		} else {
+			h.Set(args[2], result)
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	c.changed(args)
+	return rw.WriteBulkString(result)
+}
+
+// HRandFieldCommand HRANDFIELD key [count [WITHVALUES]]
+// count 为正数时返回不重复的字段, 负数时可以重复且恰好返回 |count| 个
+type HRandFieldCommand struct {
+	dataset
+}
+
+func (c *HRandFieldCommand) Name() string {
+	return "HRANDFIELD"
+}
+
+func (c *HRandFieldCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HRandFieldCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 || len(args) > 4 {
+		return rw.WriteError(errWrongArgs("hrandfield"))
+	}
+	var count int64
+	withCount, withValues := len(args) >= 3, false
+	if withCount {
+		n, ok := parseInt(args[2])
+		if !ok {
+			return rw.WriteError(errNotInteger)
+		}
+		count = n
+	}
+	if len(args) == 4 {
+		if !strings.EqualFold(args[3], "WITHVALUES") {
+			return rw.WriteError(errSyntax)
+		}
+		withValues = true
+	}
+	var picked, values []string
+	err := c.readHash(args[1], func(h *kvstore.Hash) {
+		fields := h.Fields()
+		if !withCount {
+			count = 1
+		}
+		picked = randomPick(fields, count)
+		for _, f := range picked {
+			v, _ := h.Get(f)
+			values = append(values, v)
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if !withCount {
+		if len(picked) == 0 {
+			return rw.WriteNull()
+		}
+		return rw.WriteBulkString(picked[0])
+	}
+	res := []string{}
+	for i, f := range picked {
+		res = append(res, f)
+		if withValues {
+			res = append(res, values[i])
+		}
+	}
+	return rw.WriteArray(res)
+}
+
+// randomPick HRANDFIELD/SRANDMEMBER/ZRANDMEMBER 的选取规则
+// count > 0 不重复 至多 len(items) 个; count < 0 可以重复 恰好 |count| 个
+func randomPick(items []string, count int64) []string {
+	if len(items) == 0 || count == 0 {
+		return nil
+	}
+	if count < 0 {
+		res := make([]string, 0, min(-count, 1<<16))
+		for i := int64(0); i < -count; i++ {
+			res = append(res, items[rand.Intn(len(items))])
+		}
+		return res
+	}
+	perm := rand.Perm(len(items))
+	n := min(int(min(count, int64(len(items)))), len(items))
+	res := make([]string, n)
+	for i := 0; i < n; i++ {
+		res[i] = items[perm[i]]
+	}
+	return res
+}
+
+// HScanCommand HSCAN key cursor [MATCH pattern] [COUNT count] [NOVALUES]
+type HScanCommand struct {
+	dataset
+}
+
+func (c *HScanCommand) Name() string {
+	return "HSCAN"
+}
+
+func (c *HScanCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HScanCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("hscan"))
+	}
+	sa, reply := parseScan(args, true)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	next := 0
+	res := []string{}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, ok := tx.Lookup(args[1])
+		if !ok {
+			return nil
+		}
+		if o.Type != kvstore.TypeHash {
+			return errors_r.ErrWrongType
+		}
+		h := o.Value.(*kvstore.Hash)
+		var page []string
+		page, next = sa.page(h.Fields(), o.Encoding != kvstore.EncHashtable)
+		for _, f := range page {
+			if !sa.matches(f) {
+				continue
+			}
+			res = append(res, f)
+			if !sa.noValues {
+				v, _ := h.Get(f)
+				res = append(res, v)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue([]any{strconv.Itoa(next), res})
+}
@@ -0,0 +1,251 @@
+package command
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// 字段过期时间命令的逐字段返回值
+const (
+	hfieldMissing   = -2 // 字段不存在
+	hfieldNoTTL     = -1 // 字段没有过期时间
+	hfieldSkipped   = 0  // NX/XX/GT/LT 条件不满足
+	hfieldSet       = 1  // 设置成功 / HPERSIST 清除成功
+	hfieldDeleted   = 2  // 过期时间已过 字段被删除
+	hexpireMaxMilli = math.MaxInt64 / 2
+)
+
+// parseFields 解析 FIELDS numfields field [field ...] (从 args[i] 开始, 必须到末尾)
+func parseFields(args []string, i int) ([]string, string) {
+	if i >= len(args) || !strings.EqualFold(args[i], "FIELDS") || i+1 >= len(args) {
+		return nil, "ERR Mandatory argument FIELDS is missing or not at the right position"
+	}
+	n, ok := parseInt(args[i+1])
+	if !ok || n <= 0 {
+		return nil, "ERR Parameter `numFields` should be greater than 0"
+	}
+	fields := args[i+2:]
+	if int64(len(fields)) != n {
+		return nil, "ERR The `numfields` parameter must match the number of arguments"
+	}
+	return fields, ""
+}
+
+// HExpireCommand HEXPIRE|HPEXPIRE|HEXPIREAT|HPEXPIREAT key time [NX|XX|GT|LT] FIELDS numfields field [field ...]
+// 复制流中统一以 HPEXPIREAT (绝对毫秒时间) 传播, 副本不受传播延迟影响
+type HExpireCommand struct {
+	dataset
+	name string
+	unit int64 // 时间参数的单位 (毫秒)
+	abs  bool  // 时间参数为 Unix 时间戳
+}
+
+func (c *HExpireCommand) Name() string {
+	return c.name
+}
+
+func (c *HExpireCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *HExpireCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HExpireCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 6 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	t, ok := parseInt(args[2])
+	if !ok {
+		return rw.WriteError(errNotInteger)
+	}
+	if t < 0 {
+		return rw.WriteError("ERR invalid expire time, must be >= 0")
+	}
+	if t > hexpireMaxMilli/c.unit {
+		return rw.WriteError("ERR invalid expire time in '" + strings.ToLower(c.name) + "' command")
+	}
+	now := time.Now().UnixMilli()
+	at := t * c.unit
+	if !c.abs {
+		at += now
+	}
+
+	i, cond := 3, ""
+	switch opt := strings.ToUpper(args[3]); opt {
+	case "NX", "XX", "GT", "LT":
+		cond = opt
+		i++
+	}
+	fields, reply := parseFields(args, i)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+
+	key := args[1]
+	res := make([]any, len(fields))
+	changed := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		h, err := tx.Hash(key)
+		if err != nil {
+			return err
+		}
+		for j, f := range fields {
+			res[j] = hfieldMissing
+			if h == nil {
+				continue
+			}
+			if _, exists := h.Get(f); !exists {
+				continue
+			}
+			cur, hasTTL := h.ExpireAt(f)
+			// 没有过期时间视为无限长
+			if (cond == "NX" && hasTTL) || (cond == "XX" && !hasTTL) ||
+				(cond == "GT" && (!hasTTL || at <= cur)) || (cond == "LT" && hasTTL && at >= cur) {
+				res[j] = hfieldSkipped
+				continue
+			}
+			changed = true
+			if at <= now {
+				h.Delete(f)
+				res[j] = hfieldDeleted
+				continue
+			}
+			h.SetExpireAt(f, at)
+			res[j] = hfieldSet
+		}
+		if h != nil {
+			tx.HashChanged(key)
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if changed {
+		propagated := []string{"HPEXPIREAT", key, strconv.FormatInt(at, 10)}
+		if cond != "" {
+			propagated = append(propagated, cond)
+		}
+		c.changed(append(propagated, args[i:]...))
+	}
+	return rw.WriteValue(res)
+}
+
+// HTTLCommand HTTL|HPTTL|HEXPIRETIME|HPEXPIRETIME key FIELDS numfields field [field ...]
+type HTTLCommand struct {
+	dataset
+	name string
+	unit int64
+	abs  bool // 返回绝对时间戳而不是剩余时间
+}
+
+func (c *HTTLCommand) Name() string {
+	return c.name
+}
+
+func (c *HTTLCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HTTLCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 5 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	fields, reply := parseFields(args, 2)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	res := make([]any, len(fields))
+	for j := range res {
+		res[j] = hfieldMissing
+	}
+	err := c.readHash(args[1], func(h *kvstore.Hash) {
+		now := time.Now().UnixMilli()
+		for j, f := range fields {
+			if _, exists := h.Get(f); !exists {
+				continue
+			}
+			at, ok := h.ExpireAt(f)
+			switch {
+			case !ok:
+				res[j] = hfieldNoTTL
+			case c.abs:
+				res[j] = at / c.unit
+			default:
+				// 剩余时间向上取整 (与 TTL 一致)
+				res[j] = (at - now + c.unit - 1) / c.unit
+			}
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// HPersistCommand HPERSIST key FIELDS numfields field [field ...]
+type HPersistCommand struct {
+	dataset
+}
+
+func (c *HPersistCommand) Name() string {
+	return "HPERSIST"
+}
+
+func (c *HPersistCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *HPersistCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *HPersistCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 5 {
+		return rw.WriteError(errWrongArgs("hpersist"))
+	}
+	fields, reply := parseFields(args, 2)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	key := args[1]
+	res := make([]any, len(fields))
+	for j := range res {
+		res[j] = hfieldMissing
+	}
+	changed := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		h, err := tx.Hash(key)
+		if err != nil || h == nil {
+			return err
+		}
+		for j, f := range fields {
+			if _, exists := h.Get(f); !exists {
+				continue
+			}
+			if !h.Persist(f) {
+				res[j] = hfieldNoTTL
+				continue
+			}
+			res[j] = hfieldSet
+			changed = true
+		}
+		tx.HashChanged(key)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if changed {
+		c.changed(args)
+	}
+	return rw.WriteValue(res)
+}
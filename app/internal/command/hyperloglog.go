@@ -0,0 +1,331 @@
+package command
+
+import (
+	"context"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// HyperLogLogCommands PFADD/PFCOUNT/PFMERGE 等 值为 Redis 格式的 HLL 字符串
+func HyperLogLogCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&PFAddCommand{dataset: d},
+		&PFCountCommand{dataset: d},
+		&PFMergeCommand{dataset: d},
+		&PFDebugCommand{dataset: d},
+		&PFSelfTestCommand{},
+	}
+}
+
+// hllObject 读取 HLL 键不存在时返回 nil 不是合法的 HLL 字符串时返回 WRONGTYPE
+func hllObject(tx *kvstore.Tx, key string) (*kvstore.Object, error) {
+	o, err := tx.String(key)
+	if err != nil || o == nil {
+		return nil, err
+	}
+	if !kvstore.IsHLL(o.Str()) {
+		return nil, kvstore.ErrHLLType
+	}
+	return o, nil
+}
+
+// PFAddCommand PFADD key [element ...] 有寄存器变化 (或创建了键) 时返回 1
+type PFAddCommand struct {
+	dataset
+}
+
+func (c *PFAddCommand) Name() string {
+	return "PFADD"
+}
+
+func (c *PFAddCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *PFAddCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *PFAddCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs("pfadd"))
+	}
+	updated := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, err := hllObject(tx, args[1])
+		if err != nil {
+			return err
+		}
+		s := ""
+		if o == nil {
+			s = string(kvstore.NewHLL())
+		} else {
+			s = o.Str()
+		}
+		buf, changed, err := kvstore.HLLAdd(s, args[2:])
+		if err != nil {
+			return err
+		}
+		switch {
+		case o == nil:
+			tx.Add(args[1], kvstore.NewStringObject(string(buf)))
+			updated = true
+		case changed:
+			tx.Replace(args[1], kvstore.NewStringObject(string(buf)))
+			updated = true
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if updated {
+		c.changed(args)
+	}
+	return rw.WriteInteger(boolInt(updated))
+}
+
+// PFCountCommand PFCOUNT key [key ...]
+// 单个键时使用并更新值中的基数缓存; 多个键时合并后估算 (不缓存)
+// 缓存只是派生数据 更新时不传播 (只读命令不持有写锁), 副本在自己被查询时计算
+type PFCountCommand struct {
+	dataset
+}
+
+func (c *PFCountCommand) Name() string {
+	return "PFCOUNT"
+}
+
+func (c *PFCountCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: -1, Step: 1}
+}
+
+func (c *PFCountCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs("pfcount"))
+	}
+	var n uint64
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		if len(args) == 2 {
+			o, err := hllObject(tx, args[1])
+			if err != nil || o == nil {
+				return err
+			}
+			s := o.Str()
+			var ok bool
+			if n, ok = kvstore.HLLCachedCount(s); ok {
+				return nil
+			}
+			regs, err := kvstore.HLLRegisterValues(s)
+			if err != nil {
+				return err
+			}
+			n = kvstore.HLLEstimate(regs)
+			buf := []byte(s)
+			kvstore.HLLSetCache(buf, n)
+			tx.Replace(args[1], kvstore.NewStringObject(string(buf)))
+			return nil
+		}
+		merged := make([]uint8, kvstore.HLLRegisters)
+		for _, key := range args[1:] {
+			o, err := hllObject(tx, key)
+			if err != nil {
+				return err
+			}
+			if o == nil {
+				continue
+			}
+			regs, err := kvstore.HLLRegisterValues(o.Str())
+			if err != nil {
+				return err
+			}
+			kvstore.HLLMerge(merged, regs)
+		}
+		n = kvstore.HLLEstimate(merged)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(int64(n))
+}
+
+// PFMergeCommand PFMERGE destkey [sourcekey ...] destkey 本身也参与合并
+// 输入中有 dense 时结果为 dense, 否则尽量保持 sparse
+type PFMergeCommand struct {
+	dataset
+}
+
+func (c *PFMergeCommand) Name() string {
+	return "PFMERGE"
+}
+
+func (c *PFMergeCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *PFMergeCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: -1, Step: 1}
+}
+
+func (c *PFMergeCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs("pfmerge"))
+	}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		merged := make([]uint8, kvstore.HLLRegisters)
+		sparse := true
+		for _, key := range args[1:] {
+			o, err := hllObject(tx, key)
+			if err != nil {
+				return err
+			}
+			if o == nil {
+				continue
+			}
+			s := o.Str()
+			sparse = sparse && kvstore.HLLIsSparse(s)
+			regs, err := kvstore.HLLRegisterValues(s)
+			if err != nil {
+				return err
+			}
+			kvstore.HLLMerge(merged, regs)
+		}
+		hdr := string(kvstore.NewHLL())
+		dst, _ := hllObject(tx, args[1])
+		if dst != nil {
+			hdr = dst.Str()
+		}
+		buf := kvstore.HLLEncode(hdr, merged, sparse)
+		kvstore.HLLInvalidateCache(buf)
+		if dst == nil {
+			tx.Add(args[1], kvstore.NewStringObject(string(buf)))
+		} else {
+			tx.Replace(args[1], kvstore.NewStringObject(string(buf)))
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed(args)
+	return rw.WriteSimpleString("OK")
+}
+
+// PFDebugCommand PFDEBUG GETREG|DECODE|ENCODING|TODENSE key
+// GETREG 与 TODENSE 会把值转换为 dense
+type PFDebugCommand struct {
+	dataset
+}
+
+func (c *PFDebugCommand) Name() string {
+	return "PFDEBUG"
+}
+
+func (c *PFDebugCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *PFDebugCommand) KeySpec() KeySpec {
+	return KeySpec{First: 2, Last: 2, Step: 1}
+}
+
+func (c *PFDebugCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("pfdebug"))
+	}
+	sub := strings.ToUpper(args[1])
+	var res any
+	converted := false
+	reply := ""
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, err := hllObject(tx, args[2])
+		if err != nil {
+			return err
+		}
+		if o == nil {
+			reply = "ERR The specified key does not exist"
+			return nil
+		}
+		s := o.Str()
+		toDense := func() error {
+			if !kvstore.HLLIsSparse(s) {
+				return nil
+			}
+			regs, err := kvstore.HLLRegisterValues(s)
+			if err != nil {
+				return err
+			}
+			s = string(kvstore.HLLEncode(s, regs, false))
+			tx.Replace(args[2], kvstore.NewStringObject(s))
+			converted = true
+			return nil
+		}
+		switch sub {
+		case "GETREG":
+			if err := toDense(); err != nil {
+				return err
+			}
+			regs, _ := kvstore.HLLRegisterValues(s)
+			vals := make([]any, len(regs))
+			for i, v := range regs {
+				vals[i] = int64(v)
+			}
+			res = vals
+		case "DECODE":
+			if !kvstore.HLLIsSparse(s) {
+				reply = "ERR HLL encoding is not sparse"
+				return nil
+			}
+			decoded, err := kvstore.HLLDecode(s)
+			if err != nil {
+				return err
+			}
+			res = decoded
+		case "ENCODING":
+			res = protocol.SimpleString("dense")
+			if kvstore.HLLIsSparse(s) {
+				res = protocol.SimpleString("sparse")
+			}
+		case "TODENSE":
+			if err := toDense(); err != nil {
+				return err
+			}
+			res = boolInt(converted)
+		default:
+			reply = "ERR Unknown PFDEBUG subcommand '" + args[1] + "'"
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	if converted {
+		c.changed(args)
+	}
+	return rw.WriteValue(res)
+}
+
+// PFSelfTestCommand PFSELFTEST 检查 HLL 实现
+type PFSelfTestCommand struct{}
+
+func (c *PFSelfTestCommand) Name() string {
+	return "PFSELFTEST"
+}
+
+func (c *PFSelfTestCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 1 {
+		return rw.WriteError(errWrongArgs("pfselftest"))
+	}
+	if err := kvstore.HLLSelfTest(); err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteSimpleString("OK")
+}
@@ -7,15 +7,16 @@ import (
 
 	"github.com/codecrafters-io/redis-starter-go/app/internal/config"
 	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
-	"github.com/codecrafters-io/redis-starter-go/app/internal/rtest"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
 )
 
 type InfoCommand struct {
-	Cfg *config.ServerConfig
+	Cfg  *config.ServerConfig
+	repl replication.InfoProvider
 }
 
-func NewInfoCommand(cfg *config.ServerConfig) *InfoCommand {
-	return &InfoCommand{Cfg: cfg}
+func NewInfoCommand(cfg *config.ServerConfig, repl replication.InfoProvider) *InfoCommand {
+	return &InfoCommand{Cfg: cfg, repl: repl}
 }
 
 func (c *InfoCommand) Name() string {
@@ -29,20 +30,38 @@ func (c *InfoCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, a
 		return rw.WriteNull()
 	}
 
-	section := strings.ToUpper(args[0])
+	// args[0] 为 INFO 本身 不带 section 时输出全部
+	section := "ALL"
+	if len(args) > 1 {
+		section = strings.ToUpper(args[1])
+	}
+
+	// 哨兵模式只有 sentinel 段
+	if sp, ok := c.repl.(replication.SentinelInfoProvider); ok {
+		switch section {
+		case "SENTINEL", "ALL", "DEFAULT", "EVERYTHING":
+			return rw.WriteBulkString(fmt.Sprintf("# Sentinel\r\n%s", sp.SentinelInfo()))
+		}
+		return rw.WriteBulkString("")
+	}
 
+	var sections []string
 	switch section {
-	case "REPLICATION":
-		if c.Cfg.Role == "slave" {
-			// Handle replica info
-			rw.WriteBulkString(fmt.Sprintf("role:slave\r\n"))
-			// Add more replica-specific info
-		} else {
-			rw.WriteBulkString(fmt.Sprintf("role:master\r\nmaster_repl_offset:%s\r\nmaster_replid:%s\r\n", rtest.Master_repl_offset, rtest.Master_replid))
-			// Add more master-specific info
+	case "REPLICATION", "ALL", "DEFAULT", "EVERYTHING":
+		sections = append(sections, fmt.Sprintf("# Replication\r\n%s", c.repl.ReplicationInfo()))
+	}
+	switch section {
+	case "CLUSTER", "ALL", "DEFAULT", "EVERYTHING":
+		enabled := 0
+		if c.Cfg.ClusterEnabled {
+			enabled = 1
 		}
-		// 其他 待扩展
+		sections = append(sections, fmt.Sprintf("# Cluster\r\ncluster_enabled:%d\r\n", enabled))
+	}
+	// 其他 待扩展
+	if len(sections) > 0 {
+		return rw.WriteBulkString(strings.Join(sections, "\r\n"))
 	}
 
-	return nil
+	return rw.WriteBulkString("")
 }
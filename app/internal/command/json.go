@@ -0,0 +1,385 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// JSONCommands JSON.* 文档命令 (与 RedisJSON 兼容)
+// 路径以 $ 开头时回复每个匹配的结果 (数组), 旧的点路径只回复第一个匹配
+func JSONCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&JSONSetCommand{dataset: d},
+		&JSONGetCommand{dataset: d},
+		&JSONDelCommand{dataset: d, name: "JSON.DEL"},
+		&JSONDelCommand{dataset: d, name: "JSON.FORGET"},
+		&JSONMGetCommand{dataset: d},
+		&JSONTypeCommand{dataset: d},
+		&JSONArrAppendCommand{dataset: d},
+		&JSONArrInsertCommand{dataset: d},
+		&JSONArrLenCommand{dataset: d},
+		&JSONArrPopCommand{dataset: d},
+		&JSONObjKeysCommand{dataset: d},
+		&JSONNumIncrByCommand{dataset: d},
+		&JSONStrAppendCommand{dataset: d},
+	}
+}
+
+const (
+	jsonRootLegacy = "."
+	errJSONNoKey   = "ERR could not perform this operation on a key that doesn't exist"
+	errJSONRoot    = "ERR new objects must be created at the root"
+)
+
+func errJSONNoPath(p *kvstore.JSONPath) error {
+	return fmt.Errorf("ERR Path '%s' does not exist", p)
+}
+
+// readJSON 在一次 Update 内读取文档 键不存在时 fn 的参数为 nil
+func (d *dataset) readJSON(key string, fn func(doc *kvstore.JSON) error) error {
+	return d.store.Update(func(tx *kvstore.Tx) error {
+		doc, err := tx.JSON(key)
+		if err != nil {
+			return err
+		}
+		return fn(doc)
+	})
+}
+
+// jsonPathArg 可选的路径参数 缺省为根 (旧路径)
+func jsonPathArg(args []string, i int) (*kvstore.JSONPath, error) {
+	if i >= len(args) {
+		return kvstore.ParseJSONPath(jsonRootLegacy)
+	}
+	return kvstore.ParseJSONPath(args[i])
+}
+
+// jsonMatches 按路径取值 JSONPath 为所有匹配组成的数组, 旧路径为第一个匹配 (没有匹配时报错)
+func jsonMatches(doc *kvstore.JSON, p *kvstore.JSONPath) (any, error) {
+	refs := p.Eval(doc)
+	if p.Legacy {
+		if len(refs) == 0 {
+			return nil, errJSONNoPath(p)
+		}
+		return refs[0].Value, nil
+	}
+	a := &kvstore.JSONArray{Elems: make([]any, len(refs))}
+	for i, r := range refs {
+		a.Elems[i] = r.Value
+	}
+	return a, nil
+}
+
+// JSONSetCommand JSON.SET key path value [NX|XX]
+// 路径没有匹配时, 若最后一步是键名且父路径匹配到对象, 则在对象中新增该键
+// 新键只能在根路径创建; 条件不满足或没有任何修改时回复 nil
+type JSONSetCommand struct {
+	dataset
+}
+
+func (c *JSONSetCommand) Name() string {
+	return "JSON.SET"
+}
+
+func (c *JSONSetCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *JSONSetCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *JSONSetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 && len(args) != 5 {
+		return rw.WriteError(errWrongArgs("json.set"))
+	}
+	nx, xx := false, false
+	if len(args) == 5 {
+		switch strings.ToUpper(args[4]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		default:
+			return rw.WriteError(errSyntax)
+		}
+	}
+	p, err := kvstore.ParseJSONPath(args[2])
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	v, err := kvstore.ParseJSON(args[3])
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	updated := false
+	err = c.store.Update(func(tx *kvstore.Tx) error {
+		doc, err := tx.JSON(args[1])
+		if err != nil {
+			return err
+		}
+		if doc == nil {
+			if !p.IsRoot() {
+				return errors.New(errJSONRoot)
+			}
+			if !xx {
+				tx.Add(args[1], kvstore.NewJSONObject(v))
+				updated = true
+			}
+			return nil
+		}
+		if refs := p.Eval(doc); len(refs) > 0 {
+			if nx {
+				return nil
+			}
+			// 每个匹配位置使用独立的副本
+			for _, r := range refs {
+				doc.Replace(r, kvstore.CloneJSON(v))
+			}
+			updated = true
+			return nil
+		}
+		parent, name, ok := p.SplitLast()
+		if xx || !ok {
+			return nil
+		}
+		for _, r := range parent.Eval(doc) {
+			if m, ok := r.Value.(*kvstore.JSONMap); ok {
+				m.Set(name, kvstore.CloneJSON(v))
+				updated = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if !updated {
+		return rw.WriteNull()
+	}
+	c.changed(args)
+	return rw.WriteSimpleString("OK")
+}
+
+// JSONGetCommand JSON.GET key [INDENT s] [NEWLINE s] [SPACE s] [path ...]
+// 多个路径时回复以路径为键的对象; 其中有 JSONPath 时所有值都是匹配组成的数组
+type JSONGetCommand struct {
+	dataset
+}
+
+func (c *JSONGetCommand) Name() string {
+	return "JSON.GET"
+}
+
+func (c *JSONGetCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *JSONGetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs("json.get"))
+	}
+	var indent, newline, space string
+	var paths []*kvstore.JSONPath
+	for i := 2; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		if (opt == "INDENT" || opt == "NEWLINE" || opt == "SPACE") && i+1 < len(args) {
+			switch opt {
+			case "INDENT":
+				indent = args[i+1]
+			case "NEWLINE":
+				newline = args[i+1]
+			default:
+				space = args[i+1]
+			}
+			i++
+			continue
+		}
+		p, err := kvstore.ParseJSONPath(args[i])
+		if err != nil {
+			return rw.WriteError(err.Error())
+		}
+		paths = append(paths, p)
+	}
+	if len(paths) == 0 {
+		p, _ := kvstore.ParseJSONPath(jsonRootLegacy)
+		paths = append(paths, p)
+	}
+	var reply any
+	err := c.readJSON(args[1], func(doc *kvstore.JSON) error {
+		if doc == nil {
+			return nil
+		}
+		if len(paths) == 1 {
+			v, err := jsonMatches(doc, paths[0])
+			if err != nil {
+				return err
+			}
+			reply = kvstore.JSONFormat(v, indent, newline, space)
+			return nil
+		}
+		legacy := true
+		for _, p := range paths {
+			legacy = legacy && p.Legacy
+		}
+		m := kvstore.NewJSONMap()
+		for _, p := range paths {
+			if !legacy && p.Legacy {
+				// 与 JSONPath 混用时旧路径也回复所有匹配
+				p, _ = kvstore.ParseJSONPath("$" + strings.TrimPrefix(p.String(), "."))
+			}
+			v, err := jsonMatches(doc, p)
+			if err != nil {
+				return err
+			}
+			m.Set(p.String(), v)
+		}
+		reply = kvstore.JSONFormat(m, indent, newline, space)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(reply)
+}
+
+// JSONDelCommand JSON.DEL key [path] 回复删除的值的个数 路径为根时删除键
+// JSON.FORGET 为别名
+type JSONDelCommand struct {
+	dataset
+	name string
+}
+
+func (c *JSONDelCommand) Name() string {
+	return c.name
+}
+
+func (c *JSONDelCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *JSONDelCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *JSONDelCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 && len(args) != 3 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	p, err := jsonPathArg(args, 2)
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	deleted := 0
+	err = c.store.Update(func(tx *kvstore.Tx) error {
+		doc, err := tx.JSON(args[1])
+		if err != nil || doc == nil {
+			return err
+		}
+		if p.IsRoot() {
+			tx.Delete(args[1])
+			deleted = 1
+			return nil
+		}
+		deleted = doc.Delete(p.Eval(doc))
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if deleted > 0 {
+		c.changed(args)
+	}
+	return rw.WriteInteger(int64(deleted))
+}
+
+// JSONMGetCommand JSON.MGET key [key ...] path 键不存在或不是 JSON 时为 nil
+type JSONMGetCommand struct {
+	dataset
+}
+
+func (c *JSONMGetCommand) Name() string {
+	return "JSON.MGET"
+}
+
+func (c *JSONMGetCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: -2, Step: 1}
+}
+
+func (c *JSONMGetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("json.mget"))
+	}
+	p, err := kvstore.ParseJSONPath(args[len(args)-1])
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	keys := args[1 : len(args)-1]
+	res := make([]any, len(keys))
+	c.store.Update(func(tx *kvstore.Tx) error {
+		for i, key := range keys {
+			doc, err := tx.JSON(key)
+			if err != nil || doc == nil {
+				continue
+			}
+			if v, err := jsonMatches(doc, p); err == nil {
+				res[i] = kvstore.MarshalJSON(v)
+			}
+		}
+		return nil
+	})
+	return rw.WriteValue(res)
+}
+
+// JSONTypeCommand JSON.TYPE key [path]
+type JSONTypeCommand struct {
+	dataset
+}
+
+func (c *JSONTypeCommand) Name() string {
+	return "JSON.TYPE"
+}
+
+func (c *JSONTypeCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *JSONTypeCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 && len(args) != 3 {
+		return rw.WriteError(errWrongArgs("json.type"))
+	}
+	p, err := jsonPathArg(args, 2)
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	var reply any
+	err = c.readJSON(args[1], func(doc *kvstore.JSON) error {
+		if doc == nil {
+			return nil
+		}
+		refs := p.Eval(doc)
+		if p.Legacy {
+			if len(refs) > 0 {
+				reply = protocol.SimpleString(kvstore.JSONTypeName(refs[0].Value))
+			}
+			return nil
+		}
+		types := make([]any, len(refs))
+		for i, r := range refs {
+			types[i] = protocol.SimpleString(kvstore.JSONTypeName(r.Value))
+		}
+		reply = types
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(reply)
+}
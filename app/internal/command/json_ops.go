@@ -0,0 +1,423 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// jsonTypeIs 值的类型是否为 want ("number" 包括整数)
+func jsonTypeIs(v any, want string) bool {
+	t := kvstore.JSONTypeName(v)
+	return t == want || (want == "number" && t == "integer")
+}
+
+// jsonApply 对路径匹配到的 want 类型的值执行 op
+// JSONPath 回复每个匹配的结果 (类型不符的为 nil); 旧路径只处理第一个匹配, 没有匹配或类型不符时报错
+func jsonApply(doc *kvstore.JSON, p *kvstore.JSONPath, want string, op func(r kvstore.JSONRef) (any, error)) (any, error) {
+	refs := p.Eval(doc)
+	if p.Legacy {
+		if len(refs) == 0 {
+			return nil, errJSONNoPath(p)
+		}
+		if !jsonTypeIs(refs[0].Value, want) {
+			return nil, fmt.Errorf("WRONGTYPE wrong type of path value - expected %s but found %s", want, kvstore.JSONTypeName(refs[0].Value))
+		}
+		return op(refs[0])
+	}
+	res := make([]any, len(refs))
+	for i, r := range refs {
+		if !jsonTypeIs(r.Value, want) {
+			continue
+		}
+		v, err := op(r)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = v
+	}
+	return res, nil
+}
+
+// jsonModify 写命令: 键必须存在
+func (d *dataset) jsonModify(key string, p *kvstore.JSONPath, want string, op func(doc *kvstore.JSON, r kvstore.JSONRef) (any, error)) (any, error) {
+	var reply any
+	err := d.store.Update(func(tx *kvstore.Tx) error {
+		doc, err := tx.JSON(key)
+		if err != nil {
+			return err
+		}
+		if doc == nil {
+			return errors.New(errJSONNoKey)
+		}
+		reply, err = jsonApply(doc, p, want, func(r kvstore.JSONRef) (any, error) {
+			return op(doc, r)
+		})
+		return err
+	})
+	return reply, err
+}
+
+// parseJSONValues 解析命令中的多个 JSON 值
+func parseJSONValues(args []string) ([]any, error) {
+	values := make([]any, len(args))
+	for i, s := range args {
+		v, err := kvstore.ParseJSON(s)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func cloneJSONValues(values []any) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = kvstore.CloneJSON(v)
+	}
+	return out
+}
+
+// JSONArrAppendCommand JSON.ARRAPPEND key path value [value ...] 回复数组的新长度
+type JSONArrAppendCommand struct {
+	dataset
+}
+
+func (c *JSONArrAppendCommand) Name() string {
+	return "JSON.ARRAPPEND"
+}
+
+func (c *JSONArrAppendCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *JSONArrAppendCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *JSONArrAppendCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 {
+		return rw.WriteError(errWrongArgs("json.arrappend"))
+	}
+	p, err := kvstore.ParseJSONPath(args[2])
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	values, err := parseJSONValues(args[3:])
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	modified := false
+	reply, err := c.jsonModify(args[1], p, "array", func(doc *kvstore.JSON, r kvstore.JSONRef) (any, error) {
+		a := r.Value.(*kvstore.JSONArray)
+		a.Elems = append(a.Elems, cloneJSONValues(values)...)
+		modified = true
+		return int64(len(a.Elems)), nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if modified {
+		c.changed(args)
+	}
+	return rw.WriteValue(reply)
+}
+
+// JSONArrInsertCommand JSON.ARRINSERT key path index value [value ...]
+// 在 index 之前插入 (负数从末尾计算), 超出范围时报错
+type JSONArrInsertCommand struct {
+	dataset
+}
+
+func (c *JSONArrInsertCommand) Name() string {
+	return "JSON.ARRINSERT"
+}
+
+func (c *JSONArrInsertCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *JSONArrInsertCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *JSONArrInsertCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 5 {
+		return rw.WriteError(errWrongArgs("json.arrinsert"))
+	}
+	p, err := kvstore.ParseJSONPath(args[2])
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	index, ok := parseInt(args[3])
+	if !ok {
+		return rw.WriteError(errNotInteger)
+	}
+	values, err := parseJSONValues(args[4:])
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	modified := false
+	reply, err := c.jsonModify(args[1], p, "array", func(doc *kvstore.JSON, r kvstore.JSONRef) (any, error) {
+		a := r.Value.(*kvstore.JSONArray)
+		n := int64(len(a.Elems))
+		i := index
+		if i < 0 {
+			i += n
+		}
+		if i < 0 || i > n {
+			return nil, errors.New("ERR index out of bounds")
+		}
+		elems := append(cloneJSONValues(values), a.Elems[i:]...)
+		a.Elems = append(a.Elems[:i], elems...)
+		modified = true
+		return int64(len(a.Elems)), nil
+	})
+	if modified {
+		c.changed(args)
+	}
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(reply)
+}
+
+// JSONArrLenCommand JSON.ARRLEN key [path] 键不存在时回复 nil
+type JSONArrLenCommand struct {
+	dataset
+}
+
+func (c *JSONArrLenCommand) Name() string {
+	return "JSON.ARRLEN"
+}
+
+func (c *JSONArrLenCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *JSONArrLenCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 && len(args) != 3 {
+		return rw.WriteError(errWrongArgs("json.arrlen"))
+	}
+	p, err := jsonPathArg(args, 2)
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	var reply any
+	err = c.readJSON(args[1], func(doc *kvstore.JSON) error {
+		if doc == nil {
+			return nil
+		}
+		reply, err = jsonApply(doc, p, "array", func(r kvstore.JSONRef) (any, error) {
+			return int64(len(r.Value.(*kvstore.JSONArray).Elems)), nil
+		})
+		return err
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(reply)
+}
+
+// JSONArrPopCommand JSON.ARRPOP key [path [index]] 移除并回复 index (默认 -1) 处的元素
+// 下标超出范围时取最近的一端, 空数组回复 nil
+type JSONArrPopCommand struct {
+	dataset
+}
+
+func (c *JSONArrPopCommand) Name() string {
+	return "JSON.ARRPOP"
+}
+
+func (c *JSONArrPopCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *JSONArrPopCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *JSONArrPopCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 || len(args) > 4 {
+		return rw.WriteError(errWrongArgs("json.arrpop"))
+	}
+	p, err := jsonPathArg(args, 2)
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	index := int64(-1)
+	if len(args) == 4 {
+		var ok bool
+		if index, ok = parseInt(args[3]); !ok {
+			return rw.WriteError(errNotInteger)
+		}
+	}
+	modified := false
+	reply, err := c.jsonModify(args[1], p, "array", func(doc *kvstore.JSON, r kvstore.JSONRef) (any, error) {
+		a := r.Value.(*kvstore.JSONArray)
+		n := int64(len(a.Elems))
+		if n == 0 {
+			return nil, nil
+		}
+		i := index
+		if i < 0 {
+			i += n
+		}
+		i = max(min(i, n-1), 0)
+		v := a.Elems[i]
+		a.Elems = append(a.Elems[:i], a.Elems[i+1:]...)
+		modified = true
+		return kvstore.MarshalJSON(v), nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if modified {
+		c.changed(args)
+	}
+	return rw.WriteValue(reply)
+}
+
+// JSONObjKeysCommand JSON.OBJKEYS key [path] 对象的键 (插入顺序)
+type JSONObjKeysCommand struct {
+	dataset
+}
+
+func (c *JSONObjKeysCommand) Name() string {
+	return "JSON.OBJKEYS"
+}
+
+func (c *JSONObjKeysCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *JSONObjKeysCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 && len(args) != 3 {
+		return rw.WriteError(errWrongArgs("json.objkeys"))
+	}
+	p, err := jsonPathArg(args, 2)
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	var reply any
+	err = c.readJSON(args[1], func(doc *kvstore.JSON) error {
+		if doc == nil {
+			return nil
+		}
+		reply, err = jsonApply(doc, p, "object", func(r kvstore.JSONRef) (any, error) {
+			keys := r.Value.(*kvstore.JSONMap).Keys()
+			res := make([]any, len(keys))
+			for i, k := range keys {
+				res[i] = k
+			}
+			return res, nil
+		})
+		return err
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(reply)
+}
+
+// JSONNumIncrByCommand JSON.NUMINCRBY key path value
+// 回复新值的 JSON: JSONPath 为数组 (不是数字的匹配为 null)
+type JSONNumIncrByCommand struct {
+	dataset
+}
+
+func (c *JSONNumIncrByCommand) Name() string {
+	return "JSON.NUMINCRBY"
+}
+
+func (c *JSONNumIncrByCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *JSONNumIncrByCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *JSONNumIncrByCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs("json.numincrby"))
+	}
+	p, err := kvstore.ParseJSONPath(args[2])
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	by, err := kvstore.ParseJSON(args[3])
+	if err != nil || !jsonTypeIs(by, "number") {
+		return rw.WriteError("ERR expected value at line 1 column 1")
+	}
+	modified := false
+	reply, err := c.jsonModify(args[1], p, "number", func(doc *kvstore.JSON, r kvstore.JSONRef) (any, error) {
+		v, ok := kvstore.JSONIncr(r.Value, by)
+		if !ok {
+			return nil, errors.New("ERR result is not a number")
+		}
+		doc.Replace(r, v)
+		modified = true
+		return v, nil
+	})
+	if modified {
+		c.changed(args)
+	}
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if res, ok := reply.([]any); ok {
+		reply = &kvstore.JSONArray{Elems: res}
+	}
+	return rw.WriteBulkString(kvstore.MarshalJSON(reply))
+}
+
+// JSONStrAppendCommand JSON.STRAPPEND key [path] value value 为 JSON 字符串 回复新长度
+type JSONStrAppendCommand struct {
+	dataset
+}
+
+func (c *JSONStrAppendCommand) Name() string {
+	return "JSON.STRAPPEND"
+}
+
+func (c *JSONStrAppendCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *JSONStrAppendCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *JSONStrAppendCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 && len(args) != 4 {
+		return rw.WriteError(errWrongArgs("json.strappend"))
+	}
+	p, err := jsonPathArg(args[:len(args)-1], 2)
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	v, err := kvstore.ParseJSON(args[len(args)-1])
+	suffix, ok := v.(string)
+	if err != nil || !ok {
+		return rw.WriteError("ERR expected string value, e.g. '\"foo\"'")
+	}
+	modified := false
+	reply, err := c.jsonModify(args[1], p, "string", func(doc *kvstore.JSON, r kvstore.JSONRef) (any, error) {
+		s := r.Value.(string) + suffix
+		doc.Replace(r, s)
+		modified = true
+		return int64(len(s)), nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if modified {
+		c.changed(args)
+	}
+	return rw.WriteValue(reply)
+}
@@ -0,0 +1,747 @@
+package command
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// ListCommands 列表类型的全部命令
+func ListCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&PushCommand{dataset: d, name: "LPUSH", left: true},
+		&PushCommand{dataset: d, name: "RPUSH"},
+		&PushCommand{dataset: d, name: "LPUSHX", left: true, exists: true},
+		&PushCommand{dataset: d, name: "RPUSHX", exists: true},
+		&PopCommand{dataset: d, name: "LPOP", left: true},
+		&PopCommand{dataset: d, name: "RPOP"},
+		&LLenCommand{dataset: d},
+		&LRangeCommand{dataset: d},
+		&LIndexCommand{dataset: d},
+		&LSetCommand{dataset: d},
+		&LInsertCommand{dataset: d},
+		&LRemCommand{dataset: d},
+		&LTrimCommand{dataset: d},
+		&LPosCommand{dataset: d},
+		&LMoveCommand{dataset: d, name: "LMOVE"},
+		&LMoveCommand{dataset: d, name: "RPOPLPUSH"},
+		&LMPopCommand{dataset: d},
+	}
+}
+
+// parseSide 解析 LEFT|RIGHT
+func parseSide(s string) (left bool, ok bool) {
+	switch strings.ToUpper(s) {
+	case "LEFT":
+		return true, true
+	case "RIGHT":
+		return false, true
+	}
+	return false, false
+}
+
+func listPop(l *kvstore.Quicklist, left bool) (string, bool) {
+	if left {
+		return l.PopFront()
+	}
+	return l.PopBack()
+}
+
+func listPush(l *kvstore.Quicklist, left bool, value string) {
+	if left {
+		l.PushFront(value)
+	} else {
+		l.PushBack(value)
+	}
+}
+
+// PushCommand LPUSH|RPUSH|LPUSHX|RPUSHX key element [element ...] 返回列表长度
+// X 版本仅在列表已存在时写入
+type PushCommand struct {
+	dataset
+	name   string
+	left   bool
+	exists bool
+}
+
+func (c *PushCommand) Name() string {
+	return c.name
+}
+
+func (c *PushCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *PushCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *PushCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	key := args[1]
+	n := 0
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		var l *kvstore.Quicklist
+		var err error
+		if c.exists {
+			l, err = tx.List(key)
+		} else {
+			l, err = tx.ListOrCreate(key)
+		}
+		if err != nil || l == nil {
+			return err
+		}
+		for _, v := range args[2:] {
+			listPush(l, c.left, v)
+		}
+		n = l.Len()
+		tx.ListChanged(key, args[2:]...)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if n > 0 {
+		c.changed(args)
+	}
+	return rw.WriteInteger(int64(n))
+}
+
+// PopCommand LPOP|RPOP key [count]
+// 不带 count 时返回单个元素, 带 count 时返回数组; 键不存在时返回 nil
+type PopCommand struct {
+	dataset
+	name string
+	left bool
+}
+
+func (c *PopCommand) Name() string {
+	return c.name
+}
+
+func (c *PopCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *PopCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *PopCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 && len(args) != 3 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	key := args[1]
+	count, withCount := int64(1), len(args) == 3
+	if withCount {
+		n, ok := parseInt(args[2])
+		if !ok || n < 0 {
+			return rw.WriteError("ERR value is out of range, must be positive")
+		}
+		count = n
+	}
+	var popped []string
+	found := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		l, err := tx.List(key)
+		if err != nil || l == nil {
+			return err
+		}
+		found = true
+		for i := int64(0); i < count; i++ {
+			v, ok := listPop(l, c.left)
+			if !ok {
+				break
+			}
+			popped = append(popped, v)
+		}
+		tx.ListChanged(key)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if len(popped) > 0 {
+		c.changed(args)
+	}
+	switch {
+	case !found && withCount:
+		return rw.WriteValue(protocol.NullArray)
+	case !found:
+		return rw.WriteNull()
+	case withCount:
+		return rw.WriteArray(popped)
+	}
+	return rw.WriteBulkString(popped[0])
+}
+
+// LLenCommand LLEN key 键不存在时为 0
+type LLenCommand struct {
+	dataset
+}
+
+func (c *LLenCommand) Name() string {
+	return "LLEN"
+}
+
+func (c *LLenCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *LLenCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs("llen"))
+	}
+	n := 0
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		l, err := tx.List(args[1])
+		if l != nil {
+			n = l.Len()
+		}
+		return err
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(int64(n))
+}
+
+// LRangeCommand LRANGE key start stop 支持负数下标
+type LRangeCommand struct {
+	dataset
+}
+
+func (c *LRangeCommand) Name() string {
+	return "LRANGE"
+}
+
+func (c *LRangeCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *LRangeCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs("lrange"))
+	}
+	start, ok1 := parseInt(args[2])
+	stop, ok2 := parseInt(args[3])
+	if !ok1 || !ok2 {
+		return rw.WriteError(errNotInteger)
+	}
+	res := []string{}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		l, err := tx.List(args[1])
+		if l != nil {
+			res = l.Range(int(start), int(stop))
+		}
+		return err
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteArray(res)
+}
+
+// LIndexCommand LINDEX key index 越界或键不存在时返回 nil
+type LIndexCommand struct {
+	dataset
+}
+
+func (c *LIndexCommand) Name() string {
+	return "LINDEX"
+}
+
+func (c *LIndexCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *LIndexCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("lindex"))
+	}
+	i, ok := parseInt(args[2])
+	if !ok {
+		return rw.WriteError(errNotInteger)
+	}
+	var reply any
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		l, err := tx.List(args[1])
+		if l != nil {
+			if v, ok := l.Index(int(i)); ok {
+				reply = v
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(reply)
+}
+
+// LSetCommand LSET key index element
+type LSetCommand struct {
+	dataset
+}
+
+func (c *LSetCommand) Name() string {
+	return "LSET"
+}
+
+func (c *LSetCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *LSetCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *LSetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs("lset"))
+	}
+	i, ok := parseInt(args[2])
+	if !ok {
+		return rw.WriteError(errNotInteger)
+	}
+	key := args[1]
+	reply := ""
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		l, err := tx.List(key)
+		switch {
+		case err != nil:
+			return err
+		case l == nil:
+			reply = "ERR no such key"
+		case !l.Set(int(i), args[3]):
+			reply = "ERR index out of range"
+		default:
+			tx.ListChanged(key, args[3])
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	c.changed(args)
+	return rw.WriteSimpleString("OK")
+}
+
+// LInsertCommand LINSERT key BEFORE|AFTER pivot element
+// 返回插入后的长度, 找不到 pivot 时返回 -1, 键不存在时返回 0
+type LInsertCommand struct {
+	dataset
+}
+
+func (c *LInsertCommand) Name() string {
+	return "LINSERT"
+}
+
+func (c *LInsertCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *LInsertCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *LInsertCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 5 {
+		return rw.WriteError(errWrongArgs("linsert"))
+	}
+	var before bool
+	switch strings.ToUpper(args[2]) {
+	case "BEFORE":
+		before = true
+	case "AFTER":
+	default:
+		return rw.WriteError(errSyntax)
+	}
+	key := args[1]
+	n := 0
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		l, err := tx.List(key)
+		if err != nil || l == nil {
+			return err
+		}
+		if !l.Insert(args[3], args[4], before) {
+			n = -1
+			return nil
+		}
+		n = l.Len()
+		tx.ListChanged(key, args[4])
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if n > 0 {
+		c.changed(args)
+	}
+	return rw.WriteInteger(int64(n))
+}
+
+// LRemCommand LREM key count element 返回删除的元素个数
+type LRemCommand struct {
+	dataset
+}
+
+func (c *LRemCommand) Name() string {
+	return "LREM"
+}
+
+func (c *LRemCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *LRemCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *LRemCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs("lrem"))
+	}
+	count, ok := parseInt(args[2])
+	if !ok {
+		return rw.WriteError(errNotInteger)
+	}
+	key := args[1]
+	removed := 0
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		l, err := tx.List(key)
+		if err != nil || l == nil {
+			return err
+		}
+		removed = l.Remove(args[3], int(count))
+		tx.ListChanged(key)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if removed > 0 {
+		c.changed(args)
+	}
+	return rw.WriteInteger(int64(removed))
+}
+
+// LTrimCommand LTRIM key start stop
+type LTrimCommand struct {
+	dataset
+}
+
+func (c *LTrimCommand) Name() string {
+	return "LTRIM"
+}
+
+func (c *LTrimCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *LTrimCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *LTrimCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs("ltrim"))
+	}
+	start, ok1 := parseInt(args[2])
+	stop, ok2 := parseInt(args[3])
+	if !ok1 || !ok2 {
+		return rw.WriteError(errNotInteger)
+	}
+	key := args[1]
+	trimmed := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		l, err := tx.List(key)
+		if err != nil || l == nil {
+			return err
+		}
+		before := l.Len()
+		l.Trim(int(start), int(stop))
+		trimmed = l.Len() != before
+		tx.ListChanged(key)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if trimmed {
+		c.changed(args)
+	}
+	return rw.WriteSimpleString("OK")
+}
+
+// LPosCommand LPOS key element [RANK rank] [COUNT num-matches] [MAXLEN len]
+// 不带 COUNT 时返回第一个匹配的下标, 带 COUNT 时返回下标数组 (COUNT 0 表示全部)
+type LPosCommand struct {
+	dataset
+}
+
+func (c *LPosCommand) Name() string {
+	return "LPOS"
+}
+
+func (c *LPosCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *LPosCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("lpos"))
+	}
+	rank, count, maxlen := int64(1), int64(-1), int64(0)
+	for i := 3; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return rw.WriteError(errSyntax)
+		}
+		n, ok := parseInt(args[i+1])
+		if !ok {
+			return rw.WriteError(errNotInteger)
+		}
+		switch strings.ToUpper(args[i]) {
+		case "RANK":
+			if n == 0 {
+				return rw.WriteError("ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")
+			}
+			rank = n
+		case "COUNT":
+			if n < 0 {
+				return rw.WriteError("ERR COUNT can't be negative")
+			}
+			count = n
+		case "MAXLEN":
+			if n < 0 {
+				return rw.WriteError("ERR MAXLEN can't be negative")
+			}
+			maxlen = n
+		default:
+			return rw.WriteError(errSyntax)
+		}
+	}
+	var matches []int64
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		l, err := tx.List(args[1])
+		if err != nil || l == nil {
+			return err
+		}
+		// 负数 RANK 从表尾开始 跳过前 |rank|-1 个匹配
+		skip, scanned := rank-1, int64(0)
+		if rank < 0 {
+			skip = -rank - 1
+		}
+		l.Each(rank < 0, func(i int, v string) bool {
+			if maxlen > 0 && scanned >= maxlen {
+				return false
+			}
+			scanned++
+			if v != args[2] {
+				return true
+			}
+			if skip > 0 {
+				skip--
+				return true
+			}
+			matches = append(matches, int64(i))
+			// count 为 -1 (未指定) 时只取一个
+			return count == 0 || int64(len(matches)) < max(count, 1)
+		})
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if count < 0 {
+		if len(matches) == 0 {
+			return rw.WriteNull()
+		}
+		return rw.WriteInteger(matches[0])
+	}
+	res := make([]any, len(matches))
+	for i, m := range matches {
+		res[i] = m
+	}
+	return rw.WriteValue(res)
+}
+
+// LMoveCommand LMOVE source destination LEFT|RIGHT LEFT|RIGHT
+// RPOPLPUSH source destination 等价于 LMOVE source destination RIGHT LEFT
+type LMoveCommand struct {
+	dataset
+	name string
+}
+
+func (c *LMoveCommand) Name() string {
+	return c.name
+}
+
+func (c *LMoveCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *LMoveCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 2, Step: 1}
+}
+
+func (c *LMoveCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	from, to := false, true
+	switch {
+	case c.name == "RPOPLPUSH" && len(args) == 3:
+	case c.name == "LMOVE" && len(args) == 5:
+		var ok1, ok2 bool
+		from, ok1 = parseSide(args[3])
+		to, ok2 = parseSide(args[4])
+		if !ok1 || !ok2 {
+			return rw.WriteError(errSyntax)
+		}
+	default:
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	v, moved, err := c.move(args[1], args[2], from, to)
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if !moved {
+		return rw.WriteNull()
+	}
+	c.changed(args)
+	return rw.WriteBulkString(v)
+}
+
+// move 从 src 弹出一个元素写入 dst src 为空时返回 false
+func (c *LMoveCommand) move(src, dst string, from, to bool) (string, bool, error) {
+	var v string
+	moved := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		l, err := tx.List(src)
+		if err != nil || l == nil {
+			return err
+		}
+		// 目标类型错误时不弹出
+		if _, err := tx.List(dst); err != nil {
+			return err
+		}
+		v, moved = listPop(l, from)
+		tx.ListChanged(src)
+		d, _ := tx.ListOrCreate(dst)
+		listPush(d, to, v)
+		tx.ListChanged(dst, v)
+		return nil
+	})
+	return v, moved, err
+}
+
+// LMPopCommand LMPOP numkeys key [key ...] LEFT|RIGHT [COUNT count]
+// 从第一个非空列表弹出 返回 [key, [element ...]]
+type LMPopCommand struct {
+	dataset
+}
+
+func (c *LMPopCommand) Name() string {
+	return "LMPOP"
+}
+
+func (c *LMPopCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *LMPopCommand) FindKeys(args []string) []string {
+	keys, _, _, _ := parseMPop(args, 1)
+	return keys
+}
+
+// parseMPop 解析 [LB]MPOP 从 args[first] 的 numkeys 开始的参数
+func parseMPop(args []string, first int) (keys []string, left bool, count int64, reply string) {
+	if len(args) < first+3 {
+		return nil, false, 0, errWrongArgs(args[0])
+	}
+	numkeys, ok := parseInt(args[first])
+	if !ok || numkeys <= 0 {
+		return nil, false, 0, "ERR numkeys should be greater than 0"
+	}
+	rest := args[first+1:]
+	if int64(len(rest)) <= numkeys {
+		return nil, false, 0, errSyntax
+	}
+	keys, rest = rest[:numkeys], rest[numkeys:]
+	left, ok = parseSide(rest[0])
+	if !ok {
+		return nil, false, 0, errSyntax
+	}
+	count = 1
+	switch {
+	case len(rest) == 1:
+	case len(rest) == 3 && strings.EqualFold(rest[1], "COUNT"):
+		count, ok = parseInt(rest[2])
+		if !ok || count <= 0 {
+			return nil, false, 0, "ERR count should be greater than 0"
+		}
+	default:
+		return nil, false, 0, errSyntax
+	}
+	return keys, left, count, ""
+}
+
+func (c *LMPopCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	keys, left, count, reply := parseMPop(args, 1)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	key, popped, err := mpop(c.store, keys, left, count)
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if popped == nil {
+		return rw.WriteValue(protocol.NullArray)
+	}
+	c.changed(mpopPropagated(key, left, len(popped)))
+	return rw.WriteValue([]any{key, popped})
+}
+
+// mpop 从第一个非空列表弹出至多 count 个元素 全部为空时 popped 为 nil
+func mpop(store *kvstore.Store, keys []string, left bool, count int64) (key string, popped []string, err error) {
+	err = store.Update(func(tx *kvstore.Tx) error {
+		for _, k := range keys {
+			l, err := tx.List(k)
+			if err != nil {
+				return err
+			}
+			if l == nil {
+				continue
+			}
+			for i := int64(0); i < count; i++ {
+				v, ok := listPop(l, left)
+				if !ok {
+					break
+				}
+				popped = append(popped, v)
+			}
+			key = k
+			tx.ListChanged(k)
+			return nil
+		}
+		return nil
+	})
+	return key, popped, err
+}
+
+// mpopPropagated 复制流中以 LPOP/RPOP key count 代替多键弹出 副本无需重新选择键
+func mpopPropagated(key string, left bool, n int) []string {
+	name := "RPOP"
+	if left {
+		name = "LPOP"
+	}
+	return []string{name, key, strconv.Itoa(n)}
+}
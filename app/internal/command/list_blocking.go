@@ -0,0 +1,222 @@
+package command
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/blocking"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// BlockingListCommands 阻塞列表命令 被服务时以对应的非阻塞命令传播
+func BlockingListCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface, blocked *blocking.Registry) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&BPopCommand{dataset: d, blocked: blocked, name: "BLPOP", left: true},
+		&BPopCommand{dataset: d, blocked: blocked, name: "BRPOP"},
+		&BLMoveCommand{LMoveCommand: LMoveCommand{dataset: d, name: "BLMOVE"}, blocked: blocked},
+		&BLMoveCommand{LMoveCommand: LMoveCommand{dataset: d, name: "BRPOPLPUSH"}, blocked: blocked},
+		&BLMPopCommand{dataset: d, blocked: blocked},
+	}
+}
+
+// parseTimeout 解析以秒为单位的超时参数 (可带小数) 0 表示一直等待
+func parseTimeout(s string) (time.Duration, string) {
+	sec, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(sec) || math.IsInf(sec, 0) {
+		return 0, "ERR timeout is not a float or out of range"
+	}
+	if sec < 0 {
+		return 0, "ERR timeout is negative"
+	}
+	return time.Duration(sec * float64(time.Second)), ""
+}
+
+// BPopCommand BLPOP|BRPOP key [key ...] timeout
+// 返回 [key, element], 超时返回 nil
+type BPopCommand struct {
+	dataset
+	blocked *blocking.Registry
+	name    string
+	left    bool
+}
+
+func (c *BPopCommand) Name() string {
+	return c.name
+}
+
+func (c *BPopCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *BPopCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: -2, Step: 1}
+}
+
+func (c *BPopCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	w, err := c.Block(ctx, rw, args)
+	if w != nil {
+		// 不能阻塞时视为立即超时
+		return rw.WriteValue(w.Cancel())
+	}
+	return err
+}
+
+func (c *BPopCommand) Block(ctx context.Context, rw protocol.ResponseWriter, args []string) (*blocking.Waiter, error) {
+	if len(args) < 3 {
+		return nil, rw.WriteError(errWrongArgs(c.name))
+	}
+	timeout, reply := parseTimeout(args[len(args)-1])
+	if reply != "" {
+		return nil, rw.WriteError(reply)
+	}
+	keys := args[1 : len(args)-1]
+	key, popped, err := mpop(c.store, keys, c.left, 1)
+	if err != nil {
+		return nil, rw.WriteError(err.Error())
+	}
+	if popped != nil {
+		c.changed(mpopPropagated(key, c.left, 1))
+		return nil, rw.WriteValue([]any{key, popped[0]})
+	}
+	return c.blocked.Block(ctx, keys, timeout, protocol.NullArray, c.serve), nil
+}
+
+func (c *BPopCommand) serve(key string) (any, bool) {
+	_, popped, err := mpop(c.store, []string{key}, c.left, 1)
+	if err != nil || popped == nil {
+		return nil, false
+	}
+	c.changed(mpopPropagated(key, c.left, 1))
+	return []any{key, popped[0]}, true
+}
+
+// BLMoveCommand BLMOVE source destination LEFT|RIGHT LEFT|RIGHT timeout
+// BRPOPLPUSH source destination timeout
+// 超时返回 nil 被服务时以 LMOVE 传播
+type BLMoveCommand struct {
+	LMoveCommand
+	blocked *blocking.Registry
+}
+
+func (c *BLMoveCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	w, err := c.Block(ctx, rw, args)
+	if w != nil {
+		return rw.WriteValue(w.Cancel())
+	}
+	return err
+}
+
+func (c *BLMoveCommand) Block(ctx context.Context, rw protocol.ResponseWriter, args []string) (*blocking.Waiter, error) {
+	from, to := false, true
+	switch {
+	case c.name == "BRPOPLPUSH" && len(args) == 4:
+	case c.name == "BLMOVE" && len(args) == 6:
+		var ok1, ok2 bool
+		from, ok1 = parseSide(args[3])
+		to, ok2 = parseSide(args[4])
+		if !ok1 || !ok2 {
+			return nil, rw.WriteError(errSyntax)
+		}
+	default:
+		return nil, rw.WriteError(errWrongArgs(c.name))
+	}
+	timeout, reply := parseTimeout(args[len(args)-1])
+	if reply != "" {
+		return nil, rw.WriteError(reply)
+	}
+	src, dst := args[1], args[2]
+	propagated := []string{"LMOVE", src, dst, sideName(from), sideName(to)}
+	v, moved, err := c.move(src, dst, from, to)
+	if err != nil {
+		return nil, rw.WriteError(err.Error())
+	}
+	if moved {
+		c.changed(propagated)
+		return nil, rw.WriteBulkString(v)
+	}
+	serve := func(key string) (any, bool) {
+		v, moved, err := c.move(src, dst, from, to)
+		if err != nil {
+			// 目标类型错误: 结束阻塞并返回错误
+			return protocol.ErrorReply(err.Error()), true
+		}
+		if !moved {
+			return nil, false
+		}
+		c.changed(propagated)
+		// 目标列表上可能有其他等待者
+		c.blocked.SignalKeys(dst)
+		return v, true
+	}
+	return c.blocked.Block(ctx, []string{src}, timeout, nil, serve), nil
+}
+
+func sideName(left bool) string {
+	if left {
+		return "LEFT"
+	}
+	return "RIGHT"
+}
+
+// BLMPopCommand BLMPOP timeout numkeys key [key ...] LEFT|RIGHT [COUNT count]
+type BLMPopCommand struct {
+	dataset
+	blocked *blocking.Registry
+}
+
+func (c *BLMPopCommand) Name() string {
+	return "BLMPOP"
+}
+
+func (c *BLMPopCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *BLMPopCommand) FindKeys(args []string) []string {
+	keys, _, _, _ := parseMPop(args, 2)
+	return keys
+}
+
+func (c *BLMPopCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	w, err := c.Block(ctx, rw, args)
+	if w != nil {
+		return rw.WriteValue(w.Cancel())
+	}
+	return err
+}
+
+func (c *BLMPopCommand) Block(ctx context.Context, rw protocol.ResponseWriter, args []string) (*blocking.Waiter, error) {
+	if len(args) < 2 {
+		return nil, rw.WriteError(errWrongArgs("blmpop"))
+	}
+	timeout, reply := parseTimeout(args[1])
+	if reply != "" {
+		return nil, rw.WriteError(reply)
+	}
+	keys, left, count, reply := parseMPop(args, 2)
+	if reply != "" {
+		return nil, rw.WriteError(reply)
+	}
+	key, popped, err := mpop(c.store, keys, left, count)
+	if err != nil {
+		return nil, rw.WriteError(err.Error())
+	}
+	if popped != nil {
+		c.changed(mpopPropagated(key, left, len(popped)))
+		return nil, rw.WriteValue([]any{key, popped})
+	}
+	serve := func(key string) (any, bool) {
+		_, popped, err := mpop(c.store, []string{key}, left, count)
+		if err != nil || popped == nil {
+			return nil, false
+		}
+		c.changed(mpopPropagated(key, left, len(popped)))
+		return []any{key, popped}, true
+	}
+	return c.blocked.Block(ctx, keys, timeout, protocol.NullArray, serve), nil
+}
@@ -0,0 +1,181 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/rdb"
+)
+
+// MigrateCommand MIGRATE host port key|"" destination-db timeout [COPY] [REPLACE] [KEYS key [key ...]]
+// 以 RESTORE-ASKING 将键写入目标节点, 成功后删除本地副本 (COPY 时保留)
+type MigrateCommand struct {
+	store  *kvstore.Store
+	fn     string
+	master replication.MasterServerInterface
+}
+
+func NewMigrateCommand(store *kvstore.Store, fn string, master replication.MasterServerInterface) *MigrateCommand {
+	return &MigrateCommand{store: store, fn: fn, master: master}
+}
+
+func (c *MigrateCommand) Name() string {
+	return "MIGRATE"
+}
+
+func (c *MigrateCommand) Flags() Flag {
+	return FlagWrite
+}
+
+// FindKeys key 为空字符串时迁移 KEYS 之后的所有键
+func (c *MigrateCommand) FindKeys(args []string) []string {
+	if len(args) < 6 {
+		return nil
+	}
+	if args[3] != "" {
+		return args[3:4]
+	}
+	for i := 6; i < len(args); i++ {
+		if strings.EqualFold(args[i], "KEYS") {
+			return args[i+1:]
+		}
+	}
+	return nil
+}
+
+func (c *MigrateCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 6 {
+		return rw.WriteError("ERR wrong number of arguments for 'migrate' command")
+	}
+	host, port := args[1], args[2]
+	db, err := strconv.Atoi(args[4])
+	if err != nil {
+		return rw.WriteError("ERR value is not an integer or out of range")
+	}
+	timeoutMs, err := strconv.ParseInt(args[5], 10, 64)
+	if err != nil {
+		return rw.WriteError("ERR value is not an integer or out of range")
+	}
+	if timeoutMs <= 0 {
+		timeoutMs = 1000
+	}
+	timeout := time.Duration(timeoutMs) * time.Millisecond
+
+	copyKeys, replace := false, false
+	keys := []string{args[3]}
+	for i := 6; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "COPY":
+			copyKeys = true
+		case "REPLACE":
+			replace = true
+		case "KEYS":
+			if args[3] != "" {
+				return rw.WriteError("ERR When using MIGRATE KEYS option, the key argument must be set to the empty string")
+			}
+			keys = args[i+1:]
+			i = len(args)
+		default:
+			return rw.WriteError("ERR syntax error")
+		}
+	}
+	// 只有 0 号数据库
+	if db != 0 {
+		return rw.WriteError("ERR DB index is out of range")
+	}
+
+	// 只迁移存在的键 容器类型的对象被原地修改, 序列化需在锁内完成
+	var present []string
+	var restores [][]string
+	err = c.store.Update(func(tx *kvstore.Tx) error {
+		for _, key := range keys {
+			o, ok := tx.Lookup(key)
+			if !ok {
+				continue
+			}
+			payload, err := rdb.Dump(o)
+			if err != nil {
+				return err
+			}
+			ttl := int64(0)
+			if at, ok := tx.Expiry(key); ok {
+				ttl = max(time.Until(at).Milliseconds(), 1)
+			}
+			restore := []string{"RESTORE-ASKING", key, strconv.FormatInt(ttl, 10), string(payload)}
+			if replace {
+				restore = append(restore, "REPLACE")
+			}
+			present = append(present, key)
+			restores = append(restores, restore)
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError("ERR " + err.Error())
+	}
+	if len(present) == 0 {
+		return rw.WriteSimpleString("NOKEY")
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), timeout)
+	if err != nil {
+		log.Printf("MIGRATE connect %s:%s Error: %s", host, port, err)
+		return rw.WriteError("IOERR error or timeout connecting to the client")
+	}
+	defer conn.Close()
+
+	// 流水线发送 RESTORE-ASKING 按顺序读取回复
+	var buf []byte
+	for _, restore := range restores {
+		buf = append(buf, protocol.ArrayFmt(restore)...)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(buf); err != nil {
+		return rw.WriteError("IOERR error or timeout writing to target instance")
+	}
+	rd := protocol.NewReader(conn)
+	var migrated []string
+	var replyErr error
+	for _, key := range present {
+		conn.SetDeadline(time.Now().Add(timeout))
+		if _, err := rd.ReadValue(); err != nil {
+			var reply protocol.ErrorReply
+			if !errors.As(err, &reply) {
+				log.Printf("MIGRATE read reply from %s:%s Error: %s", host, port, err)
+				replyErr = errors.New("IOERR error or timeout reading to target instance")
+				break
+			}
+			if replyErr == nil {
+				replyErr = errors.New("ERR Target instance replied with error: " + reply.Error())
+			}
+			continue
+		}
+		migrated = append(migrated, key)
+	}
+
+	// 目标节点已经写入的键从本地删除 复制流中传播为 DEL
+	if !copyKeys && len(migrated) > 0 {
+		for _, key := range migrated {
+			c.store.Delete(key)
+		}
+		_ = rdb.UpdateRDB(c.fn, c.store)
+		if c.master != nil {
+			if err := c.master.PropagateToReplicas(append([]string{"DEL"}, migrated...)); err != nil {
+				log.Printf("Failed to propagate MIGRATE as DEL: %v", err)
+			}
+		}
+	}
+	log.Printf("MIGRATE %d/%d keys to %s:%s", len(migrated), len(present), host, port)
+	if replyErr != nil {
+		return rw.WriteError(replyErr.Error())
+	}
+	return rw.WriteSimpleString("OK")
+}
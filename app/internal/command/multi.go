@@ -0,0 +1,50 @@
+package command
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+)
+
+// Transactor 管理连接上的事务 MULTI 之后的命令由服务器排队, EXEC 时依次执行
+type Transactor interface {
+	Multi(conn net.Conn) error
+	// Exec 执行排队的命令并写回复 (每条命令一个回复的数组)
+	Exec(rw protocol.ResponseWriter) error
+	Discard(conn net.Conn) error
+}
+
+// TxCommand MULTI | EXEC | DISCARD
+type TxCommand struct {
+	name string
+	tx   Transactor
+}
+
+func NewTxCommand(name string, tx Transactor) *TxCommand {
+	return &TxCommand{name: name, tx: tx}
+}
+
+func (c *TxCommand) Name() string {
+	return c.name
+}
+
+func (c *TxCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 1 {
+		return rw.WriteError("ERR wrong number of arguments for '" + strings.ToLower(c.name) + "' command")
+	}
+	var err error
+	switch c.name {
+	case "MULTI":
+		err = c.tx.Multi(rw.Conn())
+	case "EXEC":
+		return c.tx.Exec(rw)
+	case "DISCARD":
+		err = c.tx.Discard(rw.Conn())
+	}
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteSimpleString("OK")
+}
@@ -0,0 +1,83 @@
+package command
+
+import (
+	"context"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// ObjectCommand OBJECT ENCODING|IDLETIME|FREQ|REFCOUNT key 查看值对象的内部信息
+// 内省命令不更新键的访问时钟
+type ObjectCommand struct {
+	store *kvstore.Store
+}
+
+func NewObjectCommand(store *kvstore.Store) *ObjectCommand {
+	return &ObjectCommand{store: store}
+}
+
+func (c *ObjectCommand) Name() string {
+	return "OBJECT"
+}
+
+func (c *ObjectCommand) KeySpec() KeySpec {
+	return KeySpec{First: 2, Last: 2, Step: 1}
+}
+
+var objectHelp = []string{
+	"OBJECT <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+	"ENCODING <key>",
+	"    Return the kind of internal representation used in order to store the value",
+	"    associated with a <key>.",
+	"FREQ <key>",
+	"    Return the access frequency index of the <key>. The returned integer is",
+	"    proportional to the logarithm of the recent access frequency of the key.",
+	"IDLETIME <key>",
+	"    Return the idle time of the <key>, that is the approximated number of",
+	"    seconds elapsed since the last access to the key.",
+	"REFCOUNT <key>",
+	"    Return the number of references of the value associated with the specified",
+	"    <key>.",
+	"HELP",
+	"    Print this help.",
+}
+
+func (c *ObjectCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError("ERR wrong number of arguments for 'object' command")
+	}
+	sub := strings.ToUpper(args[1])
+	if sub == "HELP" && len(args) == 2 {
+		return rw.WriteArray(objectHelp)
+	}
+	if len(args) != 3 {
+		return rw.WriteError("ERR unknown subcommand or wrong number of arguments for '" + args[1] + "'. Try OBJECT HELP.")
+	}
+	o, ok := c.store.Peek(args[2])
+	switch sub {
+	case "ENCODING":
+		if !ok {
+			return rw.WriteNull()
+		}
+		return rw.WriteBulkString(o.Encoding.String())
+	case "IDLETIME":
+		if !ok {
+			return rw.WriteNull()
+		}
+		return rw.WriteInteger(int64(o.IdleTime().Seconds()))
+	case "FREQ":
+		if !ok {
+			return rw.WriteNull()
+		}
+		return rw.WriteInteger(int64(o.Freq()))
+	case "REFCOUNT":
+		if !ok {
+			return rw.WriteNull()
+		}
+		// 没有共享对象 引用计数恒为 1
+		return rw.WriteInteger(1)
+	}
+	return rw.WriteError("ERR unknown subcommand '" + args[1] + "'. Try OBJECT HELP.")
+}
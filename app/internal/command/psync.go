@@ -4,10 +4,11 @@ package command
 import (
 	"context"
 	"log"
+	"strconv"
+	"strings"
 
 	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
 	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
-	"github.com/codecrafters-io/redis-starter-go/app/internal/rtest"
 )
 
 type PsyncCommand struct {
@@ -22,19 +23,24 @@ func (c *PsyncCommand) Name() string {
 	return "PSYNC"
 }
 
+// PSYNC <replid> <offset> [FAILOVER]  首次同步为 PSYNC ? -1
 func (c *PsyncCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
-	// return rw.WriteSimpleString("FULLRESYNC" + " " + rtest.FirReplId + " " + rtest.FirReplOffset)
-	if len(args) < 2 {
+	if len(args) < 3 {
 		log.Printf("ERR wrong number of arguments for 'PSYNC' command")
-		return nil
+		return rw.WriteError("ERR wrong number of arguments for 'psync' command")
 	}
-	// 发送 FULLRESYNC 响应  格式: +FULLRESYNC <replid> <offset>
-	if err := rw.WriteSimpleString("FULLRESYNC" + " " + rtest.FirReplId + " " + rtest.FirReplOffset); err != nil {
-		return err
+	offset, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return rw.WriteError("ERR value is not an integer or out of range")
 	}
-	// 获取连接
-	conn := rw.Conn()
-	// 添加到副本列表 并发送空RDB文件
-	c.ms.AddReplica(conn)
-	return nil
+	// 主节点执行 FAILOVER 时携带 FAILOVER 请求本节点晋升
+	failover := false
+	if len(args) > 3 {
+		if !strings.EqualFold(args[3], "FAILOVER") {
+			return rw.WriteError("ERR syntax error")
+		}
+		failover = true
+	}
+	// 由服务端决定 +CONTINUE 或 +FULLRESYNC 并发送数据, 随后连接加入副本列表
+	return c.ms.Psync(rw.Conn(), args[1], offset, failover)
 }
@@ -3,18 +3,21 @@ package command
 import (
 	"context"
 	"log"
+	"strconv"
 	"strings"
 
 	"github.com/codecrafters-io/redis-starter-go/app/internal/config"
 	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
 )
 
 type ReplconfCommand struct {
 	Cfg *config.ServerConfig
+	ms  replication.MasterServerInterface
 }
 
-func NewReplconfCommand(cfg *config.ServerConfig) *ReplconfCommand {
-	return &ReplconfCommand{Cfg: cfg}
+func NewReplconfCommand(cfg *config.ServerConfig, ms replication.MasterServerInterface) *ReplconfCommand {
+	return &ReplconfCommand{Cfg: cfg, ms: ms}
 }
 
 func (c *ReplconfCommand) Name() string {
@@ -30,8 +33,11 @@ func (c *ReplconfCommand) Execute(ctx context.Context, rw protocol.ResponseWrite
 	//  args : [REPLCONF subcommand arg1 arg2 ...]
 	switch strings.ToUpper(args[1]) {
 	case "LISTENING-PORT":
-		// Store replica's listening port
-		c.Cfg.Port = args[2]
+		// 副本的监听端口 不能覆盖本节点的 Cfg.Port
+		if len(args) < 3 {
+			return rw.WriteError("ERR syntax error")
+		}
+		c.ms.SetReplicaPort(rw.Conn(), args[2])
 		return rw.WriteSimpleString("OK")
 	case "CAPA":
 		// Handle capabilities
@@ -39,6 +45,15 @@ func (c *ReplconfCommand) Execute(ctx context.Context, rw protocol.ResponseWrite
 	case "GETACK":
 		// Handle ACK from replica
 		return rw.WriteSimpleString("OK")
+	case "ACK":
+		// 副本上报偏移量 不回复 (否则会写入副本的复制流)
+		if len(args) < 3 {
+			return nil
+		}
+		if offset, err := strconv.ParseInt(args[2], 10, 64); err == nil {
+			c.ms.ReplicaAck(rw.Conn(), offset)
+		}
+		return nil
 	default:
 		log.Printf("[REPLCONF] unknown subcommand '%s'", args[1])
 		return rw.WriteNull()
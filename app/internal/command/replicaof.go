@@ -0,0 +1,50 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+)
+
+// ReplicaofCommand REPLICAOF host port | REPLICAOF NO ONE  (SLAVEOF 为旧名称)
+type ReplicaofCommand struct {
+	name string
+	rs   replication.RoleSwitcher
+}
+
+func NewReplicaofCommand(name string, rs replication.RoleSwitcher) *ReplicaofCommand {
+	return &ReplicaofCommand{name: name, rs: rs}
+}
+
+func (c *ReplicaofCommand) Name() string {
+	return c.name
+}
+
+func (c *ReplicaofCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(c.name)))
+	}
+
+	// REPLICAOF NO ONE 晋升为主节点
+	if strings.EqualFold(args[1], "NO") && strings.EqualFold(args[2], "ONE") {
+		if err := c.rs.ReplicaOfNoOne(); err != nil {
+			log.Printf("[%s] promote error: %s", c.name, err)
+			return rw.WriteError("ERR " + err.Error())
+		}
+		return rw.WriteSimpleString("OK")
+	}
+
+	if _, err := strconv.ParseUint(args[2], 10, 16); err != nil {
+		return rw.WriteError("ERR Invalid master port")
+	}
+	if err := c.rs.ReplicaOf(args[1], args[2]); err != nil {
+		log.Printf("[%s] replicaof %s:%s error: %s", c.name, args[1], args[2], err)
+		return rw.WriteError("ERR " + err.Error())
+	}
+	return rw.WriteSimpleString("OK")
+}
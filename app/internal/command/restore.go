@@ -0,0 +1,135 @@
+package command
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/rdb"
+)
+
+// RestoreCommand RESTORE key ttl serialized-value [REPLACE] [ABSTTL] [IDLETIME seconds] [FREQ frequency]
+// RESTORE-ASKING 为 MIGRATE 发往目标节点的版本 隐含 ASKING, 迁入中的槽可直接写入
+type RestoreCommand struct {
+	name   string
+	store  *kvstore.Store
+	fn     string
+	master replication.MasterServerInterface
+}
+
+func NewRestoreCommand(name string, store *kvstore.Store, fn string, master replication.MasterServerInterface) *RestoreCommand {
+	return &RestoreCommand{name: name, store: store, fn: fn, master: master}
+}
+
+func (c *RestoreCommand) Name() string {
+	return c.name
+}
+
+func (c *RestoreCommand) Flags() Flag {
+	if c.name == "RESTORE-ASKING" {
+		return FlagWrite | FlagAsking
+	}
+	return FlagWrite
+}
+
+func (c *RestoreCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *RestoreCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 {
+		return rw.WriteError("ERR wrong number of arguments for '" + strings.ToLower(c.name) + "' command")
+	}
+	key := args[1]
+	replace, absTTL := false, false
+	idle, freq := int64(-1), int64(-1)
+	for i := 4; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "REPLACE":
+			replace = true
+		case "ABSTTL":
+			absTTL = true
+		case "IDLETIME":
+			if i+1 >= len(args) || freq >= 0 {
+				return rw.WriteError("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return rw.WriteError("ERR value is not an integer or out of range")
+			}
+			if n < 0 {
+				return rw.WriteError("ERR Invalid IDLETIME value, must be >= 0")
+			}
+			idle = n
+			i++
+		case "FREQ":
+			if i+1 >= len(args) || idle >= 0 {
+				return rw.WriteError("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return rw.WriteError("ERR value is not an integer or out of range")
+			}
+			if n < 0 || n > 255 {
+				return rw.WriteError("ERR Invalid FREQ value, must be >= 0 and <= 255")
+			}
+			freq = n
+			i++
+		default:
+			return rw.WriteError("ERR syntax error")
+		}
+	}
+
+	ttl, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return rw.WriteError("ERR value is not an integer or out of range")
+	}
+	if ttl < 0 {
+		return rw.WriteError("ERR Invalid TTL value, must be >= 0")
+	}
+	if c.store.Exists(key) && !replace {
+		return rw.WriteError("BUSYKEY Target key name already exists.")
+	}
+	o, err := rdb.Restore([]byte(args[3]))
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if o == nil {
+		// 哈希字段已全部过期 不创建键
+		return rw.WriteSimpleString("OK")
+	}
+	if idle >= 0 {
+		o.SetIdleTime(time.Duration(idle) * time.Second)
+	}
+	if freq >= 0 {
+		o.SetFreq(uint8(freq))
+	}
+
+	var expire time.Duration
+	if ttl > 0 {
+		expire = time.Duration(ttl) * time.Millisecond
+		if absTTL {
+			expire = time.Until(time.UnixMilli(ttl))
+		}
+	}
+	if expire < 0 {
+		// 已经过期 不创建键
+		c.store.Delete(key)
+	} else {
+		c.store.SetObject(key, o, expire)
+	}
+	_ = rdb.UpdateRDB(c.fn, c.store)
+	log.Printf("[%s] restored %s (ttl %s)", c.name, key, expire)
+
+	if c.master != nil {
+		if err := c.master.PropagateToReplicas(args); err != nil {
+			log.Printf("Failed to propagate %s command: %v", c.name, err)
+		}
+	}
+	return rw.WriteSimpleString("OK")
+}
@@ -0,0 +1,70 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/glob"
+)
+
+// scanArgs HSCAN/SSCAN/ZSCAN 的公共参数: key cursor [MATCH pattern] [COUNT count] [NOVALUES]
+type scanArgs struct {
+	cursor   int
+	match    string
+	count    int
+	noValues bool
+}
+
+// parseScan 解析 args[2:] (args[1] 为键) allowNoValues 仅 HSCAN 支持 NOVALUES
+func parseScan(args []string, allowNoValues bool) (scanArgs, string) {
+	sa := scanArgs{count: 10}
+	cursor, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		return sa, "ERR invalid cursor"
+	}
+	sa.cursor = int(min(cursor, uint64(^uint(0)>>1)))
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); {
+		case opt == "MATCH" && i+1 < len(args):
+			sa.match = args[i+1]
+			i++
+		case opt == "COUNT" && i+1 < len(args):
+			n, ok := parseInt(args[i+1])
+			if !ok {
+				return sa, errNotInteger
+			}
+			if n < 1 {
+				return sa, errSyntax
+			}
+			sa.count = int(n)
+			i++
+		case opt == "NOVALUES" && allowNoValues:
+			sa.noValues = true
+		default:
+			return sa, errSyntax
+		}
+	}
+	return sa, ""
+}
+
+// page 从有序的元素中取出一页 返回本页的元素与下一次的游标 (0 表示遍历结束)
+// all 为 true 时 (紧凑编码的小集合) 忽略 COUNT 一次返回全部
+func (sa scanArgs) page(items []string, all bool) ([]string, int) {
+	if all {
+		return items, 0
+	}
+	if sa.cursor >= len(items) {
+		return nil, 0
+	}
+	end := min(sa.cursor+sa.count, len(items))
+	next := end
+	if end == len(items) {
+		next = 0
+	}
+	return items[sa.cursor:end], next
+}
+
+// matches 是否满足 MATCH
+func (sa scanArgs) matches(s string) bool {
+	return sa.match == "" || glob.Match(sa.match, s)
+}
@@ -0,0 +1,127 @@
+package command
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+)
+
+// SentinelCommand 哨兵模式下的 SENTINEL <subcommand>
+type SentinelCommand struct {
+	sm replication.SentinelMonitor
+}
+
+func NewSentinelCommand(sm replication.SentinelMonitor) *SentinelCommand {
+	return &SentinelCommand{sm: sm}
+}
+
+func (c *SentinelCommand) Name() string {
+	return "SENTINEL"
+}
+
+func (c *SentinelCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError("ERR wrong number of arguments for 'sentinel' command")
+	}
+	sub := strings.ToUpper(args[1])
+	params := args[2:]
+
+	switch sub {
+	case "MYID":
+		return rw.WriteBulkString(c.sm.MyID())
+	case "MASTERS":
+		return rw.WriteValue(nested(c.sm.Masters()))
+	case "GET-MASTER-ADDR-BY-NAME":
+		if len(params) != 1 {
+			return c.wrongArgs(rw, sub)
+		}
+		host, port, ok := c.sm.MasterAddr(params[0])
+		if !ok {
+			return rw.WriteValue(protocol.NullArray)
+		}
+		return rw.WriteArray([]string{host, port})
+	case "MASTER", "REPLICAS", "SLAVES", "SENTINELS", "CKQUORUM", "FAILOVER":
+		if len(params) != 1 {
+			return c.wrongArgs(rw, sub)
+		}
+		return c.byName(rw, sub, params[0])
+	case "IS-MASTER-DOWN-BY-ADDR":
+		if len(params) != 4 {
+			return c.wrongArgs(rw, sub)
+		}
+		epoch, err := strconv.ParseInt(params[2], 10, 64)
+		if err != nil {
+			return rw.WriteError("ERR value is not an integer or out of range")
+		}
+		down, leader, leaderEpoch := c.sm.IsMasterDownByAddr(params[0], params[1], epoch, params[3])
+		downInt := 0
+		if down {
+			downInt = 1
+		}
+		return rw.WriteValue([]any{downInt, leader, leaderEpoch})
+	case "HELLO":
+		// 哨兵之间的内部命令
+		if len(params) != 8 {
+			return c.wrongArgs(rw, sub)
+		}
+		epoch, err1 := strconv.ParseInt(params[3], 10, 64)
+		configEpoch, err2 := strconv.ParseInt(params[7], 10, 64)
+		if err1 != nil || err2 != nil {
+			return rw.WriteError("ERR value is not an integer or out of range")
+		}
+		c.sm.Hello(replication.SentinelHello{
+			IP: params[0], Port: params[1], RunID: params[2], CurrentEpoch: epoch,
+			MasterName: params[4], MasterIP: params[5], MasterPort: params[6], ConfigEpoch: configEpoch,
+		})
+		return rw.WriteSimpleString(c.sm.MyID())
+	}
+	return rw.WriteError("ERR Unknown sentinel subcommand '" + args[1] + "'")
+}
+
+// byName 针对单个主节点的子命令
+func (c *SentinelCommand) byName(rw protocol.ResponseWriter, sub, name string) error {
+	var (
+		reply any
+		err   error
+	)
+	switch sub {
+	case "MASTER":
+		var fields []string
+		fields, err = c.sm.Master(name)
+		reply = fields
+	case "REPLICAS", "SLAVES":
+		var list [][]string
+		list, err = c.sm.Replicas(name)
+		reply = nested(list)
+	case "SENTINELS":
+		var list [][]string
+		list, err = c.sm.Sentinels(name)
+		reply = nested(list)
+	case "CKQUORUM":
+		var msg string
+		msg, err = c.sm.CkQuorum(name)
+		reply = protocol.SimpleString(msg)
+	case "FAILOVER":
+		err = c.sm.Failover(name)
+		reply = protocol.SimpleString("OK")
+	}
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(reply)
+}
+
+func (c *SentinelCommand) wrongArgs(rw protocol.ResponseWriter, sub string) error {
+	return rw.WriteError("ERR wrong number of arguments for 'sentinel|" + strings.ToLower(sub) + "' command")
+}
+
+func nested(list [][]string) []any {
+	out := make([]any, len(list))
+	for i, item := range list {
+		out[i] = item
+	}
+	return out
+}
@@ -29,11 +29,19 @@ func (c *SetCommand) Name() string {
 	return "SET"
 }
 
+func (c *SetCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *SetCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
 //	func (c *SetCommand) Arity() int {
 //		return -3 // 至少需要3个参数: SET key value [EX seconds|PX milliseconds]
 //	}
 func (c *SetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
-	if len(args) < 2 {
+	if len(args) < 3 {
 		return errors_r.ErrWrongNumberOfArguments
 	}
 
@@ -62,9 +70,6 @@ func (c *SetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, ar
 		}
 	}
 
-	// 写入OK
-	if c.master != nil {
-		return rw.WriteSimpleString("OK")
-	}
-	return nil
+	// 写入OK (主从链路上的回复由链路丢弃)
+	return rw.WriteSimpleString("OK")
 }
@@ -0,0 +1,681 @@
+package command
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// 集合运算的种类
+const (
+	setInter = iota
+	setUnion
+	setDiff
+)
+
+// SetCommands 集合类型的全部命令
+func SetCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&SAddCommand{dataset: d},
+		&SRemCommand{dataset: d},
+		&SIsMemberCommand{dataset: d},
+		&SMIsMemberCommand{dataset: d},
+		&SMembersCommand{dataset: d},
+		&SCardCommand{dataset: d},
+		&SPopCommand{dataset: d},
+		&SRandMemberCommand{dataset: d},
+		&SMoveCommand{dataset: d},
+		&SetOpCommand{dataset: d, name: "SINTER", op: setInter},
+		&SetOpCommand{dataset: d, name: "SUNION", op: setUnion},
+		&SetOpCommand{dataset: d, name: "SDIFF", op: setDiff},
+		&SetOpCommand{dataset: d, name: "SINTERSTORE", op: setInter, dest: true},
+		&SetOpCommand{dataset: d, name: "SUNIONSTORE", op: setUnion, dest: true},
+		&SetOpCommand{dataset: d, name: "SDIFFSTORE", op: setDiff, dest: true},
+		&SInterCardCommand{dataset: d},
+		&SScanCommand{dataset: d},
+	}
+}
+
+// readSet 在一次 Update 内读取集合 键不存在时不调用 fn
+func (d *dataset) readSet(key string, fn func(set *kvstore.Set)) error {
+	return d.store.Update(func(tx *kvstore.Tx) error {
+		set, err := tx.Set(key)
+		if err == nil && set != nil {
+			fn(set)
+		}
+		return err
+	})
+}
+
+// sortedMembers 按字典序排序的成员 (SSCAN 游标与 SRANDMEMBER 依赖稳定的顺序)
+func sortedMembers(set *kvstore.Set) []string {
+	members := set.Members()
+	sort.Strings(members)
+	return members
+}
+
+// SAddCommand SADD key member [member ...] 返回新增的成员数
+type SAddCommand struct {
+	dataset
+}
+
+func (c *SAddCommand) Name() string {
+	return "SADD"
+}
+
+func (c *SAddCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *SAddCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *SAddCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("sadd"))
+	}
+	key := args[1]
+	added := 0
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		set, err := tx.SetOrCreate(key)
+		if err != nil {
+			return err
+		}
+		for _, m := range args[2:] {
+			if set.Add(m) {
+				added++
+			}
+		}
+		tx.SetChanged(key, args[2:]...)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if added > 0 {
+		c.changed(args)
+	}
+	return rw.WriteInteger(int64(added))
+}
+
+// SRemCommand SREM key member [member ...] 返回删除的成员数
+type SRemCommand struct {
+	dataset
+}
+
+func (c *SRemCommand) Name() string {
+	return "SREM"
+}
+
+func (c *SRemCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *SRemCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *SRemCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("srem"))
+	}
+	key := args[1]
+	removed := 0
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		set, err := tx.Set(key)
+		if err != nil || set == nil {
+			return err
+		}
+		for _, m := range args[2:] {
+			if set.Remove(m) {
+				removed++
+			}
+		}
+		tx.SetChanged(key)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if removed > 0 {
+		c.changed(args)
+	}
+	return rw.WriteInteger(int64(removed))
+}
+
+// SIsMemberCommand SISMEMBER key member
+type SIsMemberCommand struct {
+	dataset
+}
+
+func (c *SIsMemberCommand) Name() string {
+	return "SISMEMBER"
+}
+
+func (c *SIsMemberCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *SIsMemberCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("sismember"))
+	}
+	n := 0
+	err := c.readSet(args[1], func(set *kvstore.Set) {
+		if set.Has(args[2]) {
+			n = 1
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(int64(n))
+}
+
+// SMIsMemberCommand SMISMEMBER key member [member ...] 逐个返回 0/1
+type SMIsMemberCommand struct {
+	dataset
+}
+
+func (c *SMIsMemberCommand) Name() string {
+	return "SMISMEMBER"
+}
+
+func (c *SMIsMemberCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *SMIsMemberCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("smismember"))
+	}
+	res := make([]any, len(args)-2)
+	for i := range res {
+		res[i] = 0
+	}
+	err := c.readSet(args[1], func(set *kvstore.Set) {
+		for i, m := range args[2:] {
+			if set.Has(m) {
+				res[i] = 1
+			}
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// SMembersCommand SMEMBERS key
+type SMembersCommand struct {
+	dataset
+}
+
+func (c *SMembersCommand) Name() string {
+	return "SMEMBERS"
+}
+
+func (c *SMembersCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *SMembersCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs("smembers"))
+	}
+	res := []string{}
+	err := c.readSet(args[1], func(set *kvstore.Set) {
+		res = set.Members()
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteArray(res)
+}
+
+// SCardCommand SCARD key
+type SCardCommand struct {
+	dataset
+}
+
+func (c *SCardCommand) Name() string {
+	return "SCARD"
+}
+
+func (c *SCardCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *SCardCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs("scard"))
+	}
+	n := 0
+	err := c.readSet(args[1], func(set *kvstore.Set) {
+		n = set.Len()
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(int64(n))
+}
+
+// SPopCommand SPOP key [count]
+// 弹出的成员是随机的 复制流中以 SREM key member... 传播, 副本删除相同的成员
+type SPopCommand struct {
+	dataset
+}
+
+func (c *SPopCommand) Name() string {
+	return "SPOP"
+}
+
+func (c *SPopCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *SPopCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *SPopCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return rw.WriteError(errWrongArgs("spop"))
+	}
+	count := int64(1)
+	if len(args) == 3 {
+		n, ok := parseInt(args[2])
+		if !ok || n < 0 {
+			return rw.WriteError("ERR value is out of range, must be positive")
+		}
+		count = n
+	}
+	key := args[1]
+	popped := []string{}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		set, err := tx.Set(key)
+		if err != nil || set == nil {
+			return err
+		}
+		for int64(len(popped)) < count {
+			m, ok := set.Pop()
+			if !ok {
+				break
+			}
+			popped = append(popped, m)
+		}
+		tx.SetChanged(key)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if len(popped) > 0 {
+		c.changed(append([]string{"SREM", key}, popped...))
+	}
+	if len(args) == 3 {
+		return rw.WriteArray(popped)
+	}
+	if len(popped) == 0 {
+		return rw.WriteNull()
+	}
+	return rw.WriteBulkString(popped[0])
+}
+
+// SRandMemberCommand SRANDMEMBER key [count] count 的含义与 HRANDFIELD 相同
+type SRandMemberCommand struct {
+	dataset
+}
+
+func (c *SRandMemberCommand) Name() string {
+	return "SRANDMEMBER"
+}
+
+func (c *SRandMemberCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *SRandMemberCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return rw.WriteError(errWrongArgs("srandmember"))
+	}
+	count := int64(1)
+	if len(args) == 3 {
+		n, ok := parseInt(args[2])
+		if !ok {
+			return rw.WriteError(errNotInteger)
+		}
+		count = n
+	}
+	var picked []string
+	err := c.readSet(args[1], func(set *kvstore.Set) {
+		picked = randomPick(sortedMembers(set), count)
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if len(args) == 3 {
+		if picked == nil {
+			picked = []string{}
+		}
+		return rw.WriteArray(picked)
+	}
+	if len(picked) == 0 {
+		return rw.WriteNull()
+	}
+	return rw.WriteBulkString(picked[0])
+}
+
+// SMoveCommand SMOVE source destination member
+type SMoveCommand struct {
+	dataset
+}
+
+func (c *SMoveCommand) Name() string {
+	return "SMOVE"
+}
+
+func (c *SMoveCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *SMoveCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 2, Step: 1}
+}
+
+func (c *SMoveCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs("smove"))
+	}
+	src, dst, member := args[1], args[2], args[3]
+	moved, changed := false, false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		from, err := tx.Set(src)
+		if err != nil || from == nil {
+			return err
+		}
+		// 目标类型不符时不能先删除源成员
+		if _, err := tx.Set(dst); err != nil {
+			return err
+		}
+		if !from.Has(member) {
+			return nil
+		}
+		moved = true
+		if src == dst {
+			return nil
+		}
+		to, _ := tx.SetOrCreate(dst)
+		from.Remove(member)
+		to.Add(member)
+		tx.SetChanged(src)
+		tx.SetChanged(dst, member)
+		changed = true
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if changed {
+		c.changed(args)
+	}
+	if moved {
+		return rw.WriteInteger(1)
+	}
+	return rw.WriteInteger(0)
+}
+
+// SetOpCommand SINTER|SUNION|SDIFF key [key ...]
+// SINTERSTORE|SUNIONSTORE|SDIFFSTORE destination key [key ...] 覆盖目标键 返回结果的成员数
+type SetOpCommand struct {
+	dataset
+	name string
+	op   int
+	dest bool // 结果写入 args[1]
+}
+
+func (c *SetOpCommand) Name() string {
+	return c.name
+}
+
+func (c *SetOpCommand) Flags() Flag {
+	if c.dest {
+		return FlagWrite
+	}
+	return 0
+}
+
+func (c *SetOpCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: -1, Step: 1}
+}
+
+func (c *SetOpCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	first := 1
+	if c.dest {
+		first = 2
+	}
+	if len(args) < first+1 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	var res []string
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		sets, err := lookupSets(tx, args[first:])
+		if err != nil {
+			return err
+		}
+		res = setOp(c.op, sets)
+		if !c.dest {
+			return nil
+		}
+		if len(res) == 0 {
+			tx.Delete(args[1])
+			return nil
+		}
+		tx.Put(args[1], kvstore.NewSetObjectFrom(res), 0)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if !c.dest {
+		return rw.WriteArray(res)
+	}
+	c.changed(args)
+	return rw.WriteInteger(int64(len(res)))
+}
+
+// lookupSets 读取多个集合 不存在的键为 nil (视为空集合) 任一类型不符时返回 WRONGTYPE
+func lookupSets(tx *kvstore.Tx, keys []string) ([]*kvstore.Set, error) {
+	sets := make([]*kvstore.Set, len(keys))
+	for i, key := range keys {
+		set, err := tx.Set(key)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	return sets, nil
+}
+
+// setOp 计算集合运算 SDIFF 以第一个集合为基准
+func setOp(op int, sets []*kvstore.Set) []string {
+	res := []string{}
+	switch op {
+	case setUnion:
+		seen := make(map[string]struct{})
+		for _, set := range sets {
+			if set == nil {
+				continue
+			}
+			for _, m := range set.Members() {
+				if _, ok := seen[m]; !ok {
+					seen[m] = struct{}{}
+					res = append(res, m)
+				}
+			}
+		}
+	case setInter:
+		res = intersect(sets, 0)
+	case setDiff:
+		if sets[0] == nil {
+			return res
+		}
+	next:
+		for _, m := range sets[0].Members() {
+			for _, set := range sets[1:] {
+				if set != nil && set.Has(m) {
+					continue next
+				}
+			}
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// intersect 求交集 limit > 0 时得到 limit 个成员后停止 (SINTERCARD LIMIT)
+func intersect(sets []*kvstore.Set, limit int) []string {
+	res := []string{}
+	smallest := -1
+	for i, set := range sets {
+		if set == nil {
+			return res
+		}
+		if smallest < 0 || set.Len() < sets[smallest].Len() {
+			smallest = i
+		}
+	}
+	// 从最小的集合出发逐个检查
+next:
+	for _, m := range sets[smallest].Members() {
+		for i, set := range sets {
+			if i != smallest && !set.Has(m) {
+				continue next
+			}
+		}
+		res = append(res, m)
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+	}
+	return res
+}
+
+// SInterCardCommand SINTERCARD numkeys key [key ...] [LIMIT limit] 返回交集的成员数
+type SInterCardCommand struct {
+	dataset
+}
+
+func (c *SInterCardCommand) Name() string {
+	return "SINTERCARD"
+}
+
+func (c *SInterCardCommand) FindKeys(args []string) []string {
+	keys, _, _ := parseInterCard(args)
+	return keys
+}
+
+// parseInterCard 解析 numkeys key [key ...] [LIMIT limit]
+func parseInterCard(args []string) (keys []string, limit int64, reply string) {
+	if len(args) < 3 {
+		return nil, 0, errWrongArgs(args[0])
+	}
+	numkeys, ok := parseInt(args[1])
+	if !ok || numkeys <= 0 {
+		return nil, 0, "ERR numkeys should be greater than 0"
+	}
+	if numkeys > int64(len(args)-2) {
+		return nil, 0, "ERR Number of keys can't be greater than number of args"
+	}
+	keys = args[2 : 2+numkeys]
+	rest := args[2+numkeys:]
+	for i := 0; i < len(rest); i++ {
+		if !strings.EqualFold(rest[i], "LIMIT") || i+1 >= len(rest) {
+			return nil, 0, errSyntax
+		}
+		n, ok := parseInt(rest[i+1])
+		if !ok {
+			return nil, 0, errNotInteger
+		}
+		if n < 0 {
+			return nil, 0, "ERR LIMIT can't be negative"
+		}
+		limit = n
+		i++
+	}
+	return keys, limit, ""
+}
+
+func (c *SInterCardCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	keys, limit, reply := parseInterCard(args)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	n := 0
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		sets, err := lookupSets(tx, keys)
+		if err != nil {
+			return err
+		}
+		n = len(intersect(sets, int(limit)))
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(int64(n))
+}
+
+// SScanCommand SSCAN key cursor [MATCH pattern] [COUNT count]
+type SScanCommand struct {
+	dataset
+}
+
+func (c *SScanCommand) Name() string {
+	return "SSCAN"
+}
+
+func (c *SScanCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *SScanCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("sscan"))
+	}
+	sa, reply := parseScan(args, false)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	next := 0
+	res := []string{}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, ok := tx.Lookup(args[1])
+		if !ok {
+			return nil
+		}
+		if o.Type != kvstore.TypeSet {
+			return errors_r.ErrWrongType
+		}
+		var page []string
+		page, next = sa.page(sortedMembers(o.Value.(*kvstore.Set)), o.Encoding != kvstore.EncHashtable)
+		for _, m := range page {
+			if sa.matches(m) {
+				res = append(res, m)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue([]any{strconv.Itoa(next), res})
+}
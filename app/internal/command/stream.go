@@ -0,0 +1,670 @@
+package command
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/blocking"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+const (
+	errInvalidStreamID = "ERR Invalid stream ID specified as stream command argument"
+	// streamTrimDefaultLimit 近似裁剪 (~) 默认每次最多删除的条目数 (100 * stream-node-max-entries)
+	streamTrimDefaultLimit = 10000
+)
+
+// StreamCommands 流类型的全部命令 (含阻塞的 XREAD/XREADGROUP 与消费者组)
+func StreamCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface, blocked *blocking.Registry) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&XAddCommand{dataset: d},
+		&XRangeCommand{dataset: d, name: "XRANGE"},
+		&XRangeCommand{dataset: d, name: "XREVRANGE", rev: true},
+		&XLenCommand{dataset: d},
+		&XDelCommand{dataset: d},
+		&XTrimCommand{dataset: d},
+		&XReadCommand{dataset: d, blocked: blocked},
+		&XGroupCommand{dataset: d},
+		&XReadGroupCommand{dataset: d, blocked: blocked},
+		&XAckCommand{dataset: d},
+		&XPendingCommand{dataset: d},
+		&XClaimCommand{dataset: d},
+		&XAutoClaimCommand{dataset: d},
+		&XInfoCommand{dataset: d},
+	}
+}
+
+// parseStreamID 解析 <ms>-<seq> 省略序号时为 missingSeq
+func parseStreamID(s string, missingSeq uint64) (kvstore.StreamID, bool) {
+	ms, seq, found := strings.Cut(s, "-")
+	id := kvstore.StreamID{Seq: missingSeq}
+	var err error
+	if id.Ms, err = strconv.ParseUint(ms, 10, 64); err != nil {
+		return id, false
+	}
+	if found {
+		if id.Seq, err = strconv.ParseUint(seq, 10, 64); err != nil {
+			return id, false
+		}
+	}
+	return id, true
+}
+
+// parseRangeID 解析区间的起点/终点: - 与 + 为最小/最大 ID, ( 前缀表示不包含
+// 起点省略的序号为 0, 终点为最大值
+func parseRangeID(s string, end bool) (kvstore.StreamID, string) {
+	switch s {
+	case "-":
+		return kvstore.StreamID{}, ""
+	case "+":
+		return kvstore.MaxStreamID, ""
+	}
+	exclusive := strings.HasPrefix(s, "(")
+	s = strings.TrimPrefix(s, "(")
+	missingSeq := uint64(0)
+	if end {
+		missingSeq = math.MaxUint64
+	}
+	id, ok := parseStreamID(s, missingSeq)
+	if !ok {
+		return id, errInvalidStreamID
+	}
+	if !exclusive {
+		return id, ""
+	}
+	if end {
+		if id, ok = id.Prev(); !ok {
+			return id, "ERR invalid end ID for the interval"
+		}
+	} else if id, ok = id.Next(); !ok {
+		return id, "ERR invalid start ID for the interval"
+	}
+	return id, ""
+}
+
+// streamEntry 条目的回复 [id, [field, value, ...]] 已删除的条目字段为 nil 数组
+func streamEntry(e kvstore.StreamEntry) []any {
+	if e.Fields == nil {
+		return []any{e.ID.String(), protocol.NullArray}
+	}
+	return []any{e.ID.String(), e.Fields}
+}
+
+func streamEntries(entries []kvstore.StreamEntry) []any {
+	res := make([]any, len(entries))
+	for i, e := range entries {
+		res[i] = streamEntry(e)
+	}
+	return res
+}
+
+// streamTrim XADD/XTRIM 的裁剪参数 MAXLEN|MINID [=|~] threshold [LIMIT count]
+type streamTrim struct {
+	maxLen bool
+	len    uint64
+	minID  kvstore.StreamID
+	approx bool
+	limit  int64
+}
+
+// parseStreamTrim 从 args[i] (MAXLEN|MINID) 开始解析 返回下一个参数的位置
+func parseStreamTrim(args []string, i int) (streamTrim, int, string) {
+	t := streamTrim{maxLen: strings.ToUpper(args[i]) == "MAXLEN"}
+	i++
+	if i < len(args) && (args[i] == "=" || args[i] == "~") {
+		t.approx = args[i] == "~"
+		i++
+	}
+	if i >= len(args) {
+		return t, i, errSyntax
+	}
+	if t.maxLen {
+		n, ok := parseInt(args[i])
+		if !ok {
+			return t, i, errNotInteger
+		}
+		if n < 0 {
+			return t, i, "ERR The MAXLEN argument must be >= 0."
+		}
+		t.len = uint64(n)
+	} else {
+		id, ok := parseStreamID(args[i], 0)
+		if !ok {
+			return t, i, errInvalidStreamID
+		}
+		t.minID = id
+	}
+	i++
+	if t.approx {
+		t.limit = streamTrimDefaultLimit
+	}
+	if i+1 < len(args) && strings.ToUpper(args[i]) == "LIMIT" {
+		n, ok := parseInt(args[i+1])
+		if !ok {
+			return t, i, errNotInteger
+		}
+		if n < 0 {
+			return t, i, "ERR The LIMIT argument must be >= 0."
+		}
+		if !t.approx {
+			return t, i, "ERR syntax error, LIMIT cannot be used without the special ~ option"
+		}
+		t.limit = n
+		i += 2
+	}
+	return t, i, ""
+}
+
+func (t streamTrim) apply(s *kvstore.Stream) int64 {
+	if t.maxLen {
+		return s.TrimMaxLen(t.len, t.approx, t.limit)
+	}
+	return s.TrimMinID(t.minID, t.approx, t.limit)
+}
+
+// XAddCommand XADD key [NOMKSTREAM] [MAXLEN|MINID [=|~] threshold [LIMIT count]] *|ms-*|id field value [field value ...]
+// 返回新条目的 ID; 以确定的 ID 与裁剪后的精确长度传播
+type XAddCommand struct {
+	dataset
+}
+
+func (c *XAddCommand) Name() string {
+	return "XADD"
+}
+
+func (c *XAddCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *XAddCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *XAddCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 5 {
+		return rw.WriteError(errWrongArgs("xadd"))
+	}
+	noMkStream := false
+	var trim *streamTrim
+	i := 2
+opts:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NOMKSTREAM":
+			noMkStream = true
+		case "MAXLEN", "MINID":
+			t, next, reply := parseStreamTrim(args, i)
+			if reply != "" {
+				return rw.WriteError(reply)
+			}
+			trim = &t
+			i = next - 1
+		default:
+			break opts
+		}
+	}
+	if i >= len(args) {
+		return rw.WriteError(errSyntax)
+	}
+	idArg, fields := args[i], args[i+1:]
+	if len(fields) == 0 || len(fields)%2 != 0 {
+		return rw.WriteError(errWrongArgs("xadd"))
+	}
+	// auto: * 时序号与时间戳都自动生成, ms-* 时只生成序号
+	auto, autoSeq := idArg == "*", false
+	var id kvstore.StreamID
+	if !auto {
+		var ok bool
+		if ms, found := strings.CutSuffix(idArg, "-*"); found {
+			id, ok = parseStreamID(ms, 0)
+			ok = ok && !strings.Contains(ms, "-")
+			autoSeq = true
+		} else {
+			id, ok = parseStreamID(idArg, 0)
+		}
+		if !ok {
+			return rw.WriteError(errInvalidStreamID)
+		}
+		if !autoSeq && id.IsZero() {
+			return rw.WriteError("ERR The ID specified in XADD must be greater than 0-0")
+		}
+	}
+
+	key := args[1]
+	added := false
+	reply := ""
+	var length uint64
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := tx.Stream(key)
+		if err != nil || (s == nil && noMkStream) {
+			return err
+		}
+		created := s == nil
+		if created {
+			s = kvstore.NewStream()
+		}
+		last := s.LastID
+		switch {
+		case auto:
+			now := uint64(time.Now().UnixMilli())
+			if now > last.Ms {
+				id = kvstore.StreamID{Ms: now}
+			} else if next, ok := last.Next(); ok {
+				id = next
+			} else {
+				reply = "ERR The stream has exhausted the last possible ID, unable to add more items"
+				return nil
+			}
+		case autoSeq && id.Ms == last.Ms && last.Seq < math.MaxUint64:
+			id.Seq = last.Seq + 1
+		case autoSeq && id.Ms > last.Ms:
+		case autoSeq, !last.Less(id):
+			reply = "ERR The ID specified in XADD is equal or smaller than the target stream top item"
+			return nil
+		}
+		if created {
+			tx.Add(key, kvstore.NewObject(kvstore.TypeStream, kvstore.EncStream, s))
+		}
+		s.Append(id, append([]string(nil), fields...))
+		if trim != nil {
+			trim.apply(s)
+		}
+		length = s.Len()
+		added = true
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	if !added {
+		return rw.WriteNull()
+	}
+	propagated := []string{"XADD", key}
+	if trim != nil {
+		propagated = append(propagated, "MAXLEN", strconv.FormatUint(length, 10))
+	}
+	c.changed(append(append(propagated, id.String()), fields...))
+	return rw.WriteBulkString(id.String())
+}
+
+// XRangeCommand XRANGE key start end [COUNT count] / XREVRANGE key end start [COUNT count]
+type XRangeCommand struct {
+	dataset
+	name string
+	rev  bool
+}
+
+func (c *XRangeCommand) Name() string {
+	return c.name
+}
+
+func (c *XRangeCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *XRangeCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 && len(args) != 6 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	startArg, endArg := args[2], args[3]
+	if c.rev {
+		startArg, endArg = endArg, startArg
+	}
+	start, reply := parseRangeID(startArg, false)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	end, reply := parseRangeID(endArg, true)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	count := int64(-1)
+	if len(args) == 6 {
+		if strings.ToUpper(args[4]) != "COUNT" {
+			return rw.WriteError(errSyntax)
+		}
+		n, ok := parseInt(args[5])
+		if !ok {
+			return rw.WriteError(errNotInteger)
+		}
+		count = max(n, 0)
+	}
+	res := []any{}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := tx.Stream(args[1])
+		if err != nil || s == nil || count == 0 {
+			return err
+		}
+		res = streamEntries(s.Range(start, end, c.rev, int(count)))
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// XLenCommand XLEN key
+type XLenCommand struct {
+	dataset
+}
+
+func (c *XLenCommand) Name() string {
+	return "XLEN"
+}
+
+func (c *XLenCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *XLenCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs("xlen"))
+	}
+	var n uint64
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := tx.Stream(args[1])
+		if s != nil {
+			n = s.Len()
+		}
+		return err
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(int64(n))
+}
+
+// XDelCommand XDEL key id [id ...] 返回删除的条目数
+type XDelCommand struct {
+	dataset
+}
+
+func (c *XDelCommand) Name() string {
+	return "XDEL"
+}
+
+func (c *XDelCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *XDelCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *XDelCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("xdel"))
+	}
+	ids, ok := parseStreamIDs(args[2:])
+	if !ok {
+		return rw.WriteError(errInvalidStreamID)
+	}
+	var deleted int64
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := tx.Stream(args[1])
+		if err != nil || s == nil {
+			return err
+		}
+		for _, id := range ids {
+			if s.Delete(id) {
+				deleted++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if deleted > 0 {
+		c.changed(args)
+	}
+	return rw.WriteInteger(deleted)
+}
+
+// parseStreamIDs 解析一组完整 ID (XDEL/XACK/XCLAIM)
+func parseStreamIDs(args []string) ([]kvstore.StreamID, bool) {
+	ids := make([]kvstore.StreamID, len(args))
+	for i, a := range args {
+		id, ok := parseStreamID(a, 0)
+		if !ok {
+			return nil, false
+		}
+		ids[i] = id
+	}
+	return ids, true
+}
+
+// XTrimCommand XTRIM key MAXLEN|MINID [=|~] threshold [LIMIT count] 返回删除的条目数
+// 以 MAXLEN <剩余长度> 传播
+type XTrimCommand struct {
+	dataset
+}
+
+func (c *XTrimCommand) Name() string {
+	return "XTRIM"
+}
+
+func (c *XTrimCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *XTrimCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *XTrimCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 {
+		return rw.WriteError(errWrongArgs("xtrim"))
+	}
+	if s := strings.ToUpper(args[2]); s != "MAXLEN" && s != "MINID" {
+		return rw.WriteError(errSyntax)
+	}
+	trim, next, reply := parseStreamTrim(args, 2)
+	if reply == "" && next != len(args) {
+		reply = errSyntax
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	var removed int64
+	var length uint64
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := tx.Stream(args[1])
+		if err != nil || s == nil {
+			return err
+		}
+		removed = trim.apply(s)
+		length = s.Len()
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if removed > 0 {
+		c.changed([]string{"XTRIM", args[1], "MAXLEN", strconv.FormatUint(length, 10)})
+	}
+	return rw.WriteInteger(removed)
+}
+
+// streamRead XREAD/XREADGROUP 共用的参数 [COUNT count] [BLOCK ms] [NOACK] STREAMS key [key ...] id [id ...]
+type streamRead struct {
+	count   int
+	block   bool
+	timeout time.Duration
+	noAck   bool
+	keys    []string
+	ids     []string
+}
+
+// parseStreamRead 从 args[i] 开始解析 group 为 XREADGROUP (接受 NOACK)
+func parseStreamRead(args []string, i int, group bool) (streamRead, string) {
+	var r streamRead
+	for ; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		switch {
+		case opt == "STREAMS":
+			rest := args[i+1:]
+			if len(rest) == 0 || len(rest)%2 != 0 {
+				name := "xread"
+				if group {
+					name = "xreadgroup"
+				}
+				return r, "ERR Unbalanced '" + name + "' list of streams: for each stream key an ID or '$' must be specified."
+			}
+			r.keys, r.ids = rest[:len(rest)/2], rest[len(rest)/2:]
+			return r, ""
+		case opt == "COUNT" && i+1 < len(args):
+			n, ok := parseInt(args[i+1])
+			if !ok {
+				return r, errNotInteger
+			}
+			r.count = int(max(n, 0))
+			i++
+		case opt == "BLOCK" && i+1 < len(args):
+			ms, ok := parseInt(args[i+1])
+			if !ok {
+				return r, "ERR timeout is not an integer or out of range"
+			}
+			if ms < 0 {
+				return r, "ERR timeout is negative"
+			}
+			r.block, r.timeout = true, time.Duration(ms)*time.Millisecond
+			i++
+		case opt == "NOACK" && group:
+			r.noAck = true
+		default:
+			return r, errSyntax
+		}
+	}
+	return r, errSyntax
+}
+
+// streamKeys STREAMS 之后的键 (前一半参数)
+func streamKeys(args []string) []string {
+	for i, a := range args {
+		if strings.ToUpper(a) == "STREAMS" {
+			rest := args[i+1:]
+			return rest[:len(rest)/2]
+		}
+	}
+	return nil
+}
+
+// XReadCommand XREAD [COUNT count] [BLOCK ms] STREAMS key [key ...] id [id ...]
+// 返回每个有新条目的流 [key, entries]; 都没有时阻塞或返回 nil 数组
+// $ 表示只读取之后添加的条目, + 表示最后一个条目
+type XReadCommand struct {
+	dataset
+	blocked *blocking.Registry
+}
+
+func (c *XReadCommand) Name() string {
+	return "XREAD"
+}
+
+func (c *XReadCommand) FindKeys(args []string) []string {
+	return streamKeys(args)
+}
+
+func (c *XReadCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	w, err := c.Block(ctx, rw, args)
+	if w != nil {
+		return rw.WriteValue(w.Cancel())
+	}
+	return err
+}
+
+func (c *XReadCommand) Block(ctx context.Context, rw protocol.ResponseWriter, args []string) (*blocking.Waiter, error) {
+	if len(args) < 4 {
+		return nil, rw.WriteError(errWrongArgs("xread"))
+	}
+	r, reply := parseStreamRead(args, 1, false)
+	if reply != "" {
+		return nil, rw.WriteError(reply)
+	}
+	// 解析 $ 与 + 需要读取流 与读取在同一次 Update 内完成
+	after := make(map[string]kvstore.StreamID, len(r.keys))
+	res := []any{}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		for i, key := range r.keys {
+			s, err := tx.Stream(key)
+			if err != nil {
+				return err
+			}
+			var id kvstore.StreamID
+			switch r.ids[i] {
+			case "$":
+				if s != nil {
+					id = s.LastID
+				}
+			case "+":
+				if s != nil {
+					id = s.LastID
+					if last, ok := s.Last(); ok {
+						id, _ = last.ID.Prev()
+					}
+				}
+			default:
+				var ok bool
+				if id, ok = parseStreamID(r.ids[i], 0); !ok {
+					reply = errInvalidStreamID
+					return nil
+				}
+			}
+			after[key] = id
+			if entries := readAfter(s, id, r.count); len(entries) > 0 {
+				res = append(res, []any{key, streamEntries(entries)})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return nil, rw.WriteError(reply)
+	}
+	if len(res) > 0 {
+		return nil, rw.WriteValue(res)
+	}
+	if !r.block {
+		return nil, rw.WriteValue(protocol.NullArray)
+	}
+	// 被唤醒时只返回有新条目的流 不消耗数据, 同一个键上的其他等待者都会被服务
+	return c.blocked.Block(ctx, r.keys, r.timeout, protocol.NullArray, func(key string) (any, bool) {
+		var entries []kvstore.StreamEntry
+		_ = c.store.Update(func(tx *kvstore.Tx) error {
+			s, err := tx.Stream(key)
+			if err == nil {
+				entries = readAfter(s, after[key], r.count)
+			}
+			return nil
+		})
+		if len(entries) == 0 {
+			return nil, false
+		}
+		return []any{[]any{key, streamEntries(entries)}}, true
+	}), nil
+}
+
+// readAfter ID 大于 after 的条目 流不存在时为空
+func readAfter(s *kvstore.Stream, after kvstore.StreamID, count int) []kvstore.StreamEntry {
+	if s == nil {
+		return nil
+	}
+	from, ok := after.Next()
+	if !ok {
+		return nil
+	}
+	return s.Range(from, kvstore.MaxStreamID, false, count)
+}
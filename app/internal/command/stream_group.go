@@ -0,0 +1,967 @@
+package command
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/blocking"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+const errXGroupKeyRequired = "ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically."
+
+func errNoGroup(key, group string) string {
+	return "NOGROUP No such consumer group '" + group + "' for key name '" + key + "'"
+}
+
+// lookupGroup 读取流与消费者组 键或组不存在时 g 为 nil
+func lookupGroup(tx *kvstore.Tx, key, group string) (*kvstore.Stream, *kvstore.ConsumerGroup, error) {
+	s, err := tx.Stream(key)
+	if err != nil || s == nil {
+		return s, nil, err
+	}
+	return s, s.Group(group), nil
+}
+
+// entriesReadArg 消费者组 entries-read 的参数形式 (未知为 -1)
+func entriesReadArg(g *kvstore.ConsumerGroup) string {
+	return strconv.FormatInt(g.EntriesRead, 10)
+}
+
+var xgroupHelp = []string{
+	"XGROUP <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+	"CREATE <key> <groupname> <id|$> [option]",
+	"    Create a new consumer group. Options are:",
+	"    * MKSTREAM",
+	"      Create the empty stream if it does not exist.",
+	"    * ENTRIESREAD entries_read",
+	"      Set the group's entries_read counter (internal use).",
+	"CREATECONSUMER <key> <groupname> <consumer>",
+	"    Create a new consumer in the specified group.",
+	"DELCONSUMER <key> <groupname> <consumer>",
+	"    Remove the specified consumer.",
+	"DESTROY <key> <groupname>",
+	"    Remove the specified group.",
+	"SETID <key> <groupname> <id|$> [ENTRIESREAD entries_read]",
+	"    Set the current group ID and entries_read counter.",
+	"HELP",
+	"    Print this help.",
+}
+
+// XGroupCommand XGROUP CREATE|SETID|DESTROY|CREATECONSUMER|DELCONSUMER
+// CREATE/SETID 的 $ 以确定的 ID 与 ENTRIESREAD 传播
+type XGroupCommand struct {
+	dataset
+}
+
+func (c *XGroupCommand) Name() string {
+	return "XGROUP"
+}
+
+func (c *XGroupCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *XGroupCommand) FindKeys(args []string) []string {
+	if len(args) < 3 {
+		return nil
+	}
+	return args[2:3]
+}
+
+func (c *XGroupCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs("xgroup"))
+	}
+	sub := strings.ToUpper(args[1])
+	arity := map[string]int{"CREATE": -5, "SETID": -5, "DESTROY": 4, "CREATECONSUMER": 5, "DELCONSUMER": 5, "HELP": 2}
+	n, ok := arity[sub]
+	if !ok {
+		return rw.WriteError("ERR unknown subcommand '" + args[1] + "'. Try XGROUP HELP.")
+	}
+	if (n > 0 && len(args) != n) || (n < 0 && len(args) < -n) {
+		return rw.WriteError(errWrongArgs("xgroup|" + strings.ToLower(sub)))
+	}
+	if sub == "HELP" {
+		return rw.WriteArray(xgroupHelp)
+	}
+
+	key, group := args[2], args[3]
+	// CREATE/SETID: <id|$> [MKSTREAM] [ENTRIESREAD n]
+	var id kvstore.StreamID
+	mkStream := false
+	entriesRead := int64(math.MinInt64)
+	if sub == "CREATE" || sub == "SETID" {
+		if args[4] != "$" {
+			if id, ok = parseStreamID(args[4], 0); !ok {
+				return rw.WriteError(errInvalidStreamID)
+			}
+		}
+		for i := 5; i < len(args); i++ {
+			switch opt := strings.ToUpper(args[i]); {
+			case opt == "MKSTREAM" && sub == "CREATE":
+				mkStream = true
+			case opt == "ENTRIESREAD" && i+1 < len(args):
+				n, ok := parseInt(args[i+1])
+				if !ok {
+					return rw.WriteError(errNotInteger)
+				}
+				if n < -1 {
+					return rw.WriteError("ERR value for ENTRIESREAD must be positive or -1")
+				}
+				entriesRead = n
+				i++
+			default:
+				return rw.WriteError(errSyntax)
+			}
+		}
+	}
+
+	var result any
+	var propagated []string
+	reply := ""
+	now := time.Now().UnixMilli()
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, g, err := lookupGroup(tx, key, group)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			if sub != "CREATE" || !mkStream {
+				reply = errXGroupKeyRequired
+				return nil
+			}
+			if s, err = tx.StreamOrCreate(key); err != nil {
+				return err
+			}
+		}
+		if sub != "CREATE" && sub != "DESTROY" && g == nil {
+			reply = errNoGroup(key, group)
+			return nil
+		}
+		switch sub {
+		case "CREATE", "SETID":
+			if args[4] == "$" {
+				id = s.LastID
+			}
+			if entriesRead == math.MinInt64 {
+				entriesRead = s.EntriesReadAt(id)
+				if args[4] == "$" {
+					entriesRead = int64(s.EntriesAdded)
+				}
+			}
+			if sub == "CREATE" {
+				if g = s.CreateGroup(group, id, entriesRead); g == nil {
+					reply = "BUSYGROUP Consumer Group name already exists"
+					return nil
+				}
+				propagated = []string{"XGROUP", "CREATE", key, group, id.String(), "MKSTREAM", "ENTRIESREAD", entriesReadArg(g)}
+			} else {
+				g.LastID, g.EntriesRead = id, entriesRead
+				propagated = []string{"XGROUP", "SETID", key, group, id.String(), "ENTRIESREAD", entriesReadArg(g)}
+			}
+			result = protocol.SimpleString("OK")
+		case "DESTROY":
+			destroyed := s.DestroyGroup(group)
+			if destroyed {
+				propagated = args
+			}
+			result = boolInt(destroyed)
+		case "CREATECONSUMER":
+			_, created := g.Consumer(args[4], true, now)
+			if created {
+				propagated = args
+			}
+			result = boolInt(created)
+		case "DELCONSUMER":
+			pending, deleted := g.DeleteConsumer(args[4])
+			if deleted {
+				propagated = args
+			}
+			result = int64(pending)
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	if propagated != nil {
+		c.changed(propagated)
+	}
+	return rw.WriteValue(result)
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// XReadGroupCommand XREADGROUP GROUP group consumer [COUNT count] [BLOCK ms] [NOACK] STREAMS key [key ...] id [id ...]
+// > 读取组内尚未投递的条目 (加入 PEL), 其他 ID 重新读取消费者自己的待确认条目
+// 去掉 BLOCK 后传播; 阻塞后被服务时以读取到的条目数作为 COUNT 传播
+type XReadGroupCommand struct {
+	dataset
+	blocked *blocking.Registry
+}
+
+func (c *XReadGroupCommand) Name() string {
+	return "XREADGROUP"
+}
+
+func (c *XReadGroupCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *XReadGroupCommand) FindKeys(args []string) []string {
+	return streamKeys(args)
+}
+
+func (c *XReadGroupCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	w, err := c.Block(ctx, rw, args)
+	if w != nil {
+		return rw.WriteValue(w.Cancel())
+	}
+	return err
+}
+
+func (c *XReadGroupCommand) Block(ctx context.Context, rw protocol.ResponseWriter, args []string) (*blocking.Waiter, error) {
+	if len(args) < 7 {
+		return nil, rw.WriteError(errWrongArgs("xreadgroup"))
+	}
+	if strings.ToUpper(args[1]) != "GROUP" {
+		return nil, rw.WriteError("ERR Missing GROUP option for XREADGROUP")
+	}
+	group, consumer := args[2], args[3]
+	r, reply := parseStreamRead(args, 4, true)
+	if reply != "" {
+		return nil, rw.WriteError(reply)
+	}
+	// ids[i] 为 nil 表示 >
+	ids := make([]*kvstore.StreamID, len(r.keys))
+	for i, a := range r.ids {
+		switch a {
+		case ">":
+			continue
+		case "$":
+			return nil, rw.WriteError("ERR The $ ID is meaningful only for XREAD command")
+		}
+		id, ok := parseStreamID(a, 0)
+		if !ok {
+			return nil, rw.WriteError(errInvalidStreamID)
+		}
+		ids[i] = &id
+	}
+
+	now := time.Now().UnixMilli()
+	res := []any{}
+	changed := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		groups := make([]*kvstore.ConsumerGroup, len(r.keys))
+		streams := make([]*kvstore.Stream, len(r.keys))
+		for i, key := range r.keys {
+			s, g, err := lookupGroup(tx, key, group)
+			if err != nil {
+				return err
+			}
+			if g == nil {
+				reply = "NOGROUP No such key '" + key + "' or consumer group '" + group + "' in XREADGROUP with GROUP option"
+				return nil
+			}
+			streams[i], groups[i] = s, g
+		}
+		for i, key := range r.keys {
+			s, g := streams[i], groups[i]
+			cons, created := g.Consumer(consumer, true, now)
+			cons.SeenTime = now
+			changed = changed || created
+			if ids[i] == nil {
+				if entries := g.ReadNew(s, cons, r.count, r.noAck, now); len(entries) > 0 {
+					res = append(res, []any{key, streamEntries(entries)})
+					changed = true
+				}
+				continue
+			}
+			entries := g.ReadPending(s, cons, *ids[i], r.count, now)
+			res = append(res, []any{key, streamEntries(entries)})
+			changed = changed || len(entries) > 0
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return nil, rw.WriteError(reply)
+	}
+	if changed {
+		c.changed(withoutBlock(args))
+	}
+	if len(res) > 0 {
+		return nil, rw.WriteValue(res)
+	}
+	if !r.block {
+		return nil, rw.WriteValue(protocol.NullArray)
+	}
+	return c.blocked.Block(ctx, r.keys, r.timeout, protocol.NullArray, func(key string) (any, bool) {
+		var entries []kvstore.StreamEntry
+		reply := ""
+		now := time.Now().UnixMilli()
+		_ = c.store.Update(func(tx *kvstore.Tx) error {
+			s, g, err := lookupGroup(tx, key, group)
+			if err != nil || g == nil {
+				reply = "NOGROUP the consumer group this client was blocked on no longer exists"
+				return nil
+			}
+			cons, _ := g.Consumer(consumer, true, now)
+			cons.SeenTime = now
+			entries = g.ReadNew(s, cons, r.count, r.noAck, now)
+			return nil
+		})
+		if reply != "" {
+			return protocol.ErrorReply(reply), true
+		}
+		if len(entries) == 0 {
+			return nil, false
+		}
+		propagated := []string{"XREADGROUP", "GROUP", group, consumer, "COUNT", strconv.Itoa(len(entries))}
+		if r.noAck {
+			propagated = append(propagated, "NOACK")
+		}
+		c.changed(append(propagated, "STREAMS", key, ">"))
+		return []any{[]any{key, streamEntries(entries)}}, true
+	}), nil
+}
+
+// withoutBlock 去掉 STREAMS 之前的 BLOCK ms 选项
+func withoutBlock(args []string) []string {
+	res := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "BLOCK":
+			i++
+			continue
+		case "STREAMS":
+			return append(res, args[i:]...)
+		}
+		res = append(res, args[i])
+	}
+	return res
+}
+
+// XAckCommand XACK key group id [id ...] 返回确认的条目数
+type XAckCommand struct {
+	dataset
+}
+
+func (c *XAckCommand) Name() string {
+	return "XACK"
+}
+
+func (c *XAckCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *XAckCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *XAckCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 {
+		return rw.WriteError(errWrongArgs("xack"))
+	}
+	ids, ok := parseStreamIDs(args[3:])
+	if !ok {
+		return rw.WriteError(errInvalidStreamID)
+	}
+	var acked int64
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		_, g, err := lookupGroup(tx, args[1], args[2])
+		if err != nil || g == nil {
+			return err
+		}
+		for _, id := range ids {
+			if g.Ack(id) {
+				acked++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if acked > 0 {
+		c.changed(args)
+	}
+	return rw.WriteInteger(acked)
+}
+
+// XPendingCommand XPENDING key group [[IDLE min-idle-time] start end count [consumer]]
+// 摘要形式返回 [数量, 最小 ID, 最大 ID, [[消费者, 数量] ...]]
+// 扩展形式返回 [[id, 消费者, 空闲毫秒, 投递次数] ...]
+type XPendingCommand struct {
+	dataset
+}
+
+func (c *XPendingCommand) Name() string {
+	return "XPENDING"
+}
+
+func (c *XPendingCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *XPendingCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("xpending"))
+	}
+	key, group := args[1], args[2]
+	extended := len(args) > 3
+	var minIdle int64
+	var start, end kvstore.StreamID
+	var count int64
+	consumer := ""
+	if extended {
+		i := 3
+		if strings.ToUpper(args[i]) == "IDLE" && i+1 < len(args) {
+			n, ok := parseInt(args[i+1])
+			if !ok {
+				return rw.WriteError(errNotInteger)
+			}
+			minIdle = n
+			i += 2
+		}
+		if len(args)-i != 3 && len(args)-i != 4 {
+			return rw.WriteError(errSyntax)
+		}
+		var reply string
+		if start, reply = parseRangeID(args[i], false); reply == "" {
+			end, reply = parseRangeID(args[i+1], true)
+		}
+		if reply != "" {
+			return rw.WriteError(reply)
+		}
+		n, ok := parseInt(args[i+2])
+		if !ok {
+			return rw.WriteError(errNotInteger)
+		}
+		count = max(n, 0)
+		if len(args)-i == 4 {
+			consumer = args[i+3]
+		}
+	}
+
+	now := time.Now().UnixMilli()
+	var res any
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		_, g, err := lookupGroup(tx, key, group)
+		if err != nil || g == nil {
+			return err
+		}
+		if !extended {
+			res = pendingSummary(g)
+			return nil
+		}
+		pel := g.PEL
+		if consumer != "" {
+			cons, _ := g.Consumer(consumer, false, now)
+			if cons == nil {
+				res = []any{}
+				return nil
+			}
+			pel = cons.PEL
+		}
+		entries := []any{}
+		pel.Ascend(start.Key(), func(_ []byte, pe *kvstore.PendingEntry) bool {
+			if int64(len(entries)) >= count || end.Less(pe.ID) {
+				return false
+			}
+			if idle := now - pe.DeliveryTime; idle >= minIdle {
+				entries = append(entries, []any{pe.ID.String(), pe.Consumer.Name, idle, int64(pe.DeliveryCount)})
+			}
+			return true
+		})
+		res = entries
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if res == nil {
+		return rw.WriteError("NOGROUP No such key '" + key + "' or consumer group '" + group + "'")
+	}
+	return rw.WriteValue(res)
+}
+
+func pendingSummary(g *kvstore.ConsumerGroup) []any {
+	if g.PEL.Len() == 0 {
+		return []any{int64(0), nil, nil, protocol.NullArray}
+	}
+	first, _, _ := g.PEL.First()
+	last, _, _ := g.PEL.Last()
+	consumers := []any{}
+	for _, cons := range g.Consumers() {
+		if n := cons.PEL.Len(); n > 0 {
+			consumers = append(consumers, []any{cons.Name, strconv.Itoa(n)})
+		}
+	}
+	return []any{int64(g.PEL.Len()), kvstore.StreamIDFromKey(first).String(), kvstore.StreamIDFromKey(last).String(), consumers}
+}
+
+// streamClaim 认领一个条目后的回复与传播
+// 以 XCLAIM ... 0 id TIME t RETRYCOUNT n FORCE JUSTID 传播, 副本上得到相同的 PEL
+type streamClaim struct {
+	key, group, consumer string
+	justID               bool
+	res                  []any
+	propagated           [][]string
+}
+
+func (sc *streamClaim) claim(g *kvstore.ConsumerGroup, e kvstore.StreamEntry, force bool, now, deliveryTime, retryCount int64) {
+	cons, _ := g.Consumer(sc.consumer, true, now)
+	cons.SeenTime = now
+	pe := g.Claim(e.ID, cons, now, force, sc.justID)
+	pe.DeliveryTime = deliveryTime
+	if retryCount >= 0 {
+		pe.DeliveryCount = uint64(retryCount)
+	}
+	if sc.justID {
+		sc.res = append(sc.res, e.ID.String())
+	} else {
+		sc.res = append(sc.res, streamEntry(e))
+	}
+	sc.propagated = append(sc.propagated, []string{
+		"XCLAIM", sc.key, sc.group, sc.consumer, "0", e.ID.String(),
+		"TIME", strconv.FormatInt(pe.DeliveryTime, 10),
+		"RETRYCOUNT", strconv.FormatUint(pe.DeliveryCount, 10),
+		"FORCE", "JUSTID", "LASTID", g.LastID.String(),
+	})
+}
+
+// deleted 待确认的条目已从流中删除: 从 PEL 删除并以 XACK 传播
+func (sc *streamClaim) deleted(g *kvstore.ConsumerGroup, id kvstore.StreamID) {
+	g.Ack(id)
+	sc.propagated = append(sc.propagated, []string{"XACK", sc.key, sc.group, id.String()})
+}
+
+// XClaimCommand XCLAIM key group consumer min-idle-time id [id ...]
+// [IDLE ms] [TIME unix-time-milliseconds] [RETRYCOUNT count] [FORCE] [JUSTID] [LASTID lastid]
+// 返回认领的条目 (JUSTID 时为 ID)
+type XClaimCommand struct {
+	dataset
+}
+
+func (c *XClaimCommand) Name() string {
+	return "XCLAIM"
+}
+
+func (c *XClaimCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *XClaimCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *XClaimCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 6 {
+		return rw.WriteError(errWrongArgs("xclaim"))
+	}
+	minIdle, ok := parseInt(args[4])
+	if !ok {
+		return rw.WriteError("ERR Invalid min-idle-time argument for XCLAIM")
+	}
+	i := 5
+	var ids []kvstore.StreamID
+	for ; i < len(args); i++ {
+		id, ok := parseStreamID(args[i], 0)
+		if !ok {
+			break
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return rw.WriteError(errInvalidStreamID)
+	}
+	now := time.Now().UnixMilli()
+	deliveryTime, retryCount := now, int64(-1)
+	force, justID := false, false
+	var lastID *kvstore.StreamID
+	for ; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		switch {
+		case opt == "FORCE":
+			force = true
+		case opt == "JUSTID":
+			justID = true
+		case (opt == "IDLE" || opt == "TIME" || opt == "RETRYCOUNT") && i+1 < len(args):
+			n, ok := parseInt(args[i+1])
+			if !ok {
+				return rw.WriteError(errNotInteger)
+			}
+			switch opt {
+			case "IDLE":
+				deliveryTime = now - n
+			case "TIME":
+				deliveryTime = n
+			default:
+				retryCount = max(n, 0)
+			}
+			i++
+		case opt == "LASTID" && i+1 < len(args):
+			id, ok := parseStreamID(args[i+1], 0)
+			if !ok {
+				return rw.WriteError(errInvalidStreamID)
+			}
+			lastID = &id
+			i++
+		default:
+			return rw.WriteError("ERR Unrecognized XCLAIM option '" + args[i] + "'")
+		}
+	}
+	if deliveryTime < 0 || deliveryTime > now {
+		deliveryTime = now
+	}
+
+	key, group := args[1], args[2]
+	sc := &streamClaim{key: key, group: group, consumer: args[3], justID: justID, res: []any{}}
+	found := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, g, err := lookupGroup(tx, key, group)
+		if err != nil || g == nil {
+			return err
+		}
+		found = true
+		if lastID != nil && g.LastID.Less(*lastID) {
+			g.LastID = *lastID
+			sc.propagated = append(sc.propagated, []string{"XGROUP", "SETID", key, group, g.LastID.String(), "ENTRIESREAD", entriesReadArg(g)})
+		}
+		for _, id := range ids {
+			pe, pending := g.Pending(id)
+			e, exists := s.Get(id)
+			switch {
+			case pending && !exists:
+				sc.deleted(g, id)
+				continue
+			case !pending && (!force || !exists):
+				continue
+			case pending && now-pe.DeliveryTime < minIdle:
+				continue
+			}
+			sc.claim(g, e, force, now, deliveryTime, retryCount)
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if !found {
+		return rw.WriteError("NOGROUP No such key '" + key + "' or consumer group '" + group + "'")
+	}
+	if len(sc.propagated) > 0 {
+		c.changedAll(sc.propagated)
+	}
+	return rw.WriteValue(sc.res)
+}
+
+// XAutoClaimCommand XAUTOCLAIM key group consumer min-idle-time start [COUNT count] [JUSTID]
+// 从 start 开始扫描组的 PEL (最多 count*10 个), 认领空闲足够久的条目
+// 返回 [下一次扫描的起点 (0-0 表示结束), 认领的条目, 已从流中删除的 ID]
+type XAutoClaimCommand struct {
+	dataset
+}
+
+func (c *XAutoClaimCommand) Name() string {
+	return "XAUTOCLAIM"
+}
+
+func (c *XAutoClaimCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *XAutoClaimCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *XAutoClaimCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 6 {
+		return rw.WriteError(errWrongArgs("xautoclaim"))
+	}
+	minIdle, ok := parseInt(args[4])
+	if !ok {
+		return rw.WriteError("ERR Invalid min-idle-time argument for XAUTOCLAIM")
+	}
+	start, reply := parseRangeID(args[5], false)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	count, justID := int64(100), false
+	for i := 6; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); {
+		case opt == "JUSTID":
+			justID = true
+		case opt == "COUNT" && i+1 < len(args):
+			n, ok := parseInt(args[i+1])
+			if !ok {
+				return rw.WriteError(errNotInteger)
+			}
+			if n < 1 || n > math.MaxInt64/10 {
+				return rw.WriteError("ERR COUNT must be > 0")
+			}
+			count = n
+			i++
+		default:
+			return rw.WriteError(errSyntax)
+		}
+	}
+
+	key, group := args[1], args[2]
+	now := time.Now().UnixMilli()
+	sc := &streamClaim{key: key, group: group, consumer: args[3], justID: justID, res: []any{}}
+	deleted := []string{}
+	cursor := kvstore.StreamID{}
+	found := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, g, err := lookupGroup(tx, key, group)
+		if err != nil || g == nil {
+			return err
+		}
+		found = true
+		// 先收集再认领 遍历 PEL 时不能修改; 多取一个作为下一次的起点
+		attempts := count * 10
+		var candidates []*kvstore.PendingEntry
+		g.PEL.Ascend(start.Key(), func(_ []byte, pe *kvstore.PendingEntry) bool {
+			candidates = append(candidates, pe)
+			return int64(len(candidates)) <= attempts
+		})
+		i := 0
+		for ; i < len(candidates) && int64(i) < attempts && count > 0; i++ {
+			pe := candidates[i]
+			e, exists := s.Get(pe.ID)
+			if !exists {
+				sc.deleted(g, pe.ID)
+				deleted = append(deleted, pe.ID.String())
+				continue
+			}
+			if now-pe.DeliveryTime < minIdle {
+				continue
+			}
+			sc.claim(g, e, false, now, now, -1)
+			count--
+		}
+		if i < len(candidates) {
+			cursor = candidates[i].ID
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if !found {
+		return rw.WriteError("NOGROUP No such key '" + key + "' or consumer group '" + group + "'")
+	}
+	if len(sc.propagated) > 0 {
+		c.changedAll(sc.propagated)
+	}
+	return rw.WriteValue([]any{cursor.String(), sc.res, deleted})
+}
+
+var xinfoHelp = []string{
+	"XINFO <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+	"CONSUMERS <key> <groupname>",
+	"    Show consumers of <groupname>.",
+	"GROUPS <key>",
+	"    Show the stream consumer groups.",
+	"STREAM <key> [FULL [COUNT <count>]",
+	"    Show information about the stream.",
+	"HELP",
+	"    Print this help.",
+}
+
+// XInfoCommand XINFO STREAM key [FULL [COUNT count]] / GROUPS key / CONSUMERS key group
+type XInfoCommand struct {
+	dataset
+}
+
+func (c *XInfoCommand) Name() string {
+	return "XINFO"
+}
+
+func (c *XInfoCommand) FindKeys(args []string) []string {
+	if len(args) < 3 {
+		return nil
+	}
+	return args[2:3]
+}
+
+func (c *XInfoCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs("xinfo"))
+	}
+	sub := strings.ToUpper(args[1])
+	switch {
+	case sub == "HELP" && len(args) == 2:
+		return rw.WriteArray(xinfoHelp)
+	case sub == "STREAM" && len(args) >= 3,
+		sub == "GROUPS" && len(args) == 3,
+		sub == "CONSUMERS" && len(args) == 4:
+	case sub == "STREAM" || sub == "GROUPS" || sub == "CONSUMERS" || sub == "HELP":
+		return rw.WriteError(errWrongArgs("xinfo|" + strings.ToLower(sub)))
+	default:
+		return rw.WriteError("ERR unknown subcommand '" + args[1] + "'. Try XINFO HELP.")
+	}
+	full, count := false, int64(10)
+	if sub == "STREAM" && len(args) > 3 {
+		switch {
+		case len(args) == 4 && strings.ToUpper(args[3]) == "FULL":
+			full = true
+		case len(args) == 6 && strings.ToUpper(args[3]) == "FULL" && strings.ToUpper(args[4]) == "COUNT":
+			n, ok := parseInt(args[5])
+			if !ok {
+				return rw.WriteError(errNotInteger)
+			}
+			full, count = true, max(n, 0)
+		default:
+			return rw.WriteError(errSyntax)
+		}
+	}
+
+	key := args[2]
+	now := time.Now().UnixMilli()
+	var res any
+	reply := ""
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := tx.Stream(key)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			reply = "ERR no such key"
+			return nil
+		}
+		switch sub {
+		case "STREAM":
+			res = streamInfo(s, full, int(count))
+		case "GROUPS":
+			groups := []any{}
+			for _, g := range s.Groups() {
+				groups = append(groups, []any{
+					"name", g.Name,
+					"consumers", int64(len(g.Consumers())),
+					"pending", int64(g.PEL.Len()),
+					"last-delivered-id", g.LastID.String(),
+					"entries-read", optionalInt(g.EntriesRead),
+					"lag", optionalInt(s.Lag(g)),
+				})
+			}
+			res = groups
+		case "CONSUMERS":
+			g := s.Group(args[3])
+			if g == nil {
+				reply = errNoGroup(key, args[3])
+				return nil
+			}
+			consumers := []any{}
+			for _, cons := range g.Consumers() {
+				inactive := int64(-1)
+				if cons.ActiveTime >= 0 {
+					inactive = now - cons.ActiveTime
+				}
+				consumers = append(consumers, []any{
+					"name", cons.Name,
+					"pending", int64(cons.PEL.Len()),
+					"idle", now - cons.SeenTime,
+					"inactive", inactive,
+				})
+			}
+			res = consumers
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	return rw.WriteValue(res)
+}
+
+// optionalInt 未知 (-1) 时为 nil
+func optionalInt(n int64) any {
+	if n < 0 {
+		return nil
+	}
+	return n
+}
+
+// streamInfo XINFO STREAM 的回复 full 时包含条目与消费者组的 PEL (各最多 count 个, 0 不限)
+func streamInfo(s *kvstore.Stream, full bool, count int) []any {
+	keys, nodes := s.Nodes()
+	res := []any{
+		"length", int64(s.Len()),
+		"radix-tree-keys", int64(keys),
+		"radix-tree-nodes", int64(nodes),
+		"last-generated-id", s.LastID.String(),
+		"max-deleted-entry-id", s.MaxDeletedID.String(),
+		"entries-added", int64(s.EntriesAdded),
+		"recorded-first-entry-id", s.FirstID().String(),
+	}
+	if !full {
+		var first, last any
+		if e, ok := s.First(); ok {
+			first = streamEntry(e)
+		}
+		if e, ok := s.Last(); ok {
+			last = streamEntry(e)
+		}
+		return append(res, "groups", int64(len(s.Groups())), "first-entry", first, "last-entry", last)
+	}
+
+	// limited PEL 遍历最多 count 个
+	limited := func(pel *kvstore.Rax[*kvstore.PendingEntry], fn func(pe *kvstore.PendingEntry) any) []any {
+		items := []any{}
+		pel.Ascend(nil, func(_ []byte, pe *kvstore.PendingEntry) bool {
+			items = append(items, fn(pe))
+			return count == 0 || len(items) < count
+		})
+		return items
+	}
+	groups := []any{}
+	for _, g := range s.Groups() {
+		consumers := []any{}
+		for _, cons := range g.Consumers() {
+			consumers = append(consumers, []any{
+				"name", cons.Name,
+				"seen-time", cons.SeenTime,
+				"active-time", cons.ActiveTime,
+				"pel-count", int64(cons.PEL.Len()),
+				"pending", limited(cons.PEL, func(pe *kvstore.PendingEntry) any {
+					return []any{pe.ID.String(), pe.DeliveryTime, int64(pe.DeliveryCount)}
+				}),
+			})
+		}
+		groups = append(groups, []any{
+			"name", g.Name,
+			"last-delivered-id", g.LastID.String(),
+			"entries-read", optionalInt(g.EntriesRead),
+			"lag", optionalInt(s.Lag(g)),
+			"pel-count", int64(g.PEL.Len()),
+			"pending", limited(g.PEL, func(pe *kvstore.PendingEntry) any {
+				return []any{pe.ID.String(), pe.Consumer.Name, pe.DeliveryTime, int64(pe.DeliveryCount)}
+			}),
+			"consumers", consumers,
+		})
+	}
+	return append(res,
+		"entries", streamEntries(s.Range(kvstore.StreamID{}, kvstore.MaxStreamID, false, count)),
+		"groups", groups)
+}
@@ -0,0 +1,720 @@
+package command
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// maxStringSize 字符串的最大长度 (proto-max-bulk-len)
+const maxStringSize = 512 * 1024 * 1024
+
+// StringCommands GET/SET 之外的字符串命令
+func StringCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&IncrCommand{dataset: d, name: "INCR", sign: 1},
+		&IncrCommand{dataset: d, name: "DECR", sign: -1},
+		&IncrCommand{dataset: d, name: "INCRBY", sign: 1, by: true},
+		&IncrCommand{dataset: d, name: "DECRBY", sign: -1, by: true},
+		&IncrByFloatCommand{dataset: d},
+		&AppendCommand{dataset: d},
+		&StrlenCommand{dataset: d},
+		&GetRangeCommand{dataset: d, name: "GETRANGE"},
+		&GetRangeCommand{dataset: d, name: "SUBSTR"},
+		&SetRangeCommand{dataset: d},
+		&GetDelCommand{dataset: d},
+		&GetExCommand{dataset: d},
+		&GetSetCommand{dataset: d},
+		&SetNXCommand{dataset: d},
+		&SetExCommand{dataset: d, name: "SETEX", unit: time.Second},
+		&SetExCommand{dataset: d, name: "PSETEX", unit: time.Millisecond},
+		&MGetCommand{dataset: d},
+		&MSetCommand{dataset: d, name: "MSET"},
+		&MSetCommand{dataset: d, name: "MSETNX", nx: true},
+		&LCSCommand{dataset: d},
+	}
+}
+
+// IncrCommand INCR|DECR key / INCRBY|DECRBY key delta 返回新值
+// 值以 int 编码保存, 原有的过期时间不变
+type IncrCommand struct {
+	dataset
+	name string
+	sign int64
+	by   bool
+}
+
+func (c *IncrCommand) Name() string {
+	return c.name
+}
+
+func (c *IncrCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *IncrCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *IncrCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if (c.by && len(args) != 3) || (!c.by && len(args) != 2) {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	delta := int64(1)
+	if c.by {
+		n, ok := parseInt(args[2])
+		if !ok {
+			return rw.WriteError(errNotInteger)
+		}
+		if c.sign < 0 && n == math.MinInt64 {
+			return rw.WriteError("ERR decrement would overflow")
+		}
+		delta = n
+	}
+	delta *= c.sign
+
+	var result int64
+	reply := ""
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, err := tx.String(args[1])
+		if err != nil {
+			return err
+		}
+		if o == nil {
+			result = delta
+			tx.Add(args[1], kvstore.NewIntObject(delta))
+			return nil
+		}
+		cur, ok := o.Int()
+		if !ok {
+			reply = errNotInteger
+			return nil
+		}
+		if (delta > 0 && cur > math.MaxInt64-delta) || (delta < 0 && cur < math.MinInt64-delta) {
+			reply = "ERR increment or decrement would overflow"
+			return nil
+		}
+		result = cur + delta
+		tx.Replace(args[1], kvstore.NewIntObject(result))
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	c.changed(args)
+	return rw.WriteInteger(result)
+}
+
+// IncrByFloatCommand INCRBYFLOAT key increment 返回新值 (字符串)
+type IncrByFloatCommand struct {
+	dataset
+}
+
+func (c *IncrByFloatCommand) Name() string {
+	return "INCRBYFLOAT"
+}
+
+func (c *IncrByFloatCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *IncrByFloatCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *IncrByFloatCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("incrbyfloat"))
+	}
+	incr, ok := parseFloat(args[2])
+	if !ok {
+		return rw.WriteError(errNotFloat)
+	}
+	result := ""
+	reply := ""
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, err := tx.String(args[1])
+		if err != nil {
+			return err
+		}
+		cur := 0.0
+		if o != nil {
+			if cur, ok = parseFloat(o.Str()); !ok {
+				reply = errNotFloat
+				return nil
+			}
+		}
+		f := cur + incr
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			reply = "ERR increment would produce NaN or Infinity"
+			return nil
+		}
+		result = formatFloat(f)
+		if o == nil {
+			tx.Add(args[1], kvstore.NewStringObject(result))
+		} else {
+			tx.Replace(args[1], kvstore.NewStringObject(result))
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	c.changed(args)
+	return rw.WriteBulkString(result)
+}
+
+// AppendCommand APPEND key value 返回追加后的长度 键不存在时创建
+type AppendCommand struct {
+	dataset
+}
+
+func (c *AppendCommand) Name() string {
+	return "APPEND"
+}
+
+func (c *AppendCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *AppendCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *AppendCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("append"))
+	}
+	var length int
+	reply := ""
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, err := tx.String(args[1])
+		if err != nil {
+			return err
+		}
+		if o == nil {
+			length = len(args[2])
+			tx.Add(args[1], kvstore.NewStringObject(args[2]))
+			return nil
+		}
+		cur := o.Str()
+		if len(cur)+len(args[2]) > maxStringSize {
+			reply = "ERR string exceeds maximum allowed size (proto-max-bulk-len)"
+			return nil
+		}
+		tx.Replace(args[1], kvstore.NewStringObject(cur+args[2]))
+		length = len(cur) + len(args[2])
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	c.changed(args)
+	return rw.WriteInteger(int64(length))
+}
+
+// StrlenCommand STRLEN key 键不存在时为 0
+type StrlenCommand struct {
+	dataset
+}
+
+func (c *StrlenCommand) Name() string {
+	return "STRLEN"
+}
+
+func (c *StrlenCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *StrlenCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs("strlen"))
+	}
+	var n int
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, err := tx.String(args[1])
+		if o != nil {
+			n = len(o.Str())
+		}
+		return err
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(int64(n))
+}
+
+// GetRangeCommand GETRANGE|SUBSTR key start end 两端都包含 负数从末尾倒数
+type GetRangeCommand struct {
+	dataset
+	name string
+}
+
+func (c *GetRangeCommand) Name() string {
+	return c.name
+}
+
+func (c *GetRangeCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *GetRangeCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	start, ok1 := parseInt(args[2])
+	end, ok2 := parseInt(args[3])
+	if !ok1 || !ok2 {
+		return rw.WriteError(errNotInteger)
+	}
+	s := ""
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, err := tx.String(args[1])
+		if o != nil {
+			s = o.Str()
+		}
+		return err
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	n := int64(len(s))
+	// 两端都为负数且 start > end 时为空 (与 Redis 一致)
+	if start < 0 && end < 0 && start > end {
+		return rw.WriteBulkString("")
+	}
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if end < 0 {
+		end = max(n+end, 0)
+	}
+	end = min(end, n-1)
+	if n == 0 || start > end {
+		return rw.WriteBulkString("")
+	}
+	return rw.WriteBulkString(s[start : end+1])
+}
+
+// SetRangeCommand SETRANGE key offset value 从 offset 开始覆盖 不足的部分以 \x00 填充
+// 返回修改后的长度
+type SetRangeCommand struct {
+	dataset
+}
+
+func (c *SetRangeCommand) Name() string {
+	return "SETRANGE"
+}
+
+func (c *SetRangeCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *SetRangeCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *SetRangeCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs("setrange"))
+	}
+	offset, ok := parseInt(args[2])
+	if !ok {
+		return rw.WriteError(errNotInteger)
+	}
+	if offset < 0 {
+		return rw.WriteError("ERR offset is out of range")
+	}
+	value := args[3]
+	if offset+int64(len(value)) > maxStringSize {
+		return rw.WriteError("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+	}
+	var length int
+	modified := false
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, err := tx.String(args[1])
+		if err != nil {
+			return err
+		}
+		cur := ""
+		if o != nil {
+			cur = o.Str()
+		}
+		length = len(cur)
+		// 空值不修改 (键不存在时也不创建)
+		if value == "" {
+			return nil
+		}
+		buf := []byte(cur)
+		if end := int(offset) + len(value); end > len(buf) {
+			buf = append(buf, make([]byte, end-len(buf))...)
+		}
+		copy(buf[offset:], value)
+		length, modified = len(buf), true
+		if o == nil {
+			tx.Add(args[1], kvstore.NewStringObject(string(buf)))
+		} else {
+			tx.Replace(args[1], kvstore.NewStringObject(string(buf)))
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if modified {
+		c.changed(args)
+	}
+	return rw.WriteInteger(int64(length))
+}
+
+// GetDelCommand GETDEL key 返回值并删除键
+type GetDelCommand struct {
+	dataset
+}
+
+func (c *GetDelCommand) Name() string {
+	return "GETDEL"
+}
+
+func (c *GetDelCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *GetDelCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *GetDelCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs("getdel"))
+	}
+	var value any
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, err := tx.String(args[1])
+		if err != nil || o == nil {
+			return err
+		}
+		value = o.Str()
+		tx.Delete(args[1])
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if value != nil {
+		c.changed([]string{"DEL", args[1]})
+	}
+	return rw.WriteValue(value)
+}
+
+// parseExpire 解析 EX|PX|EXAT|PXAT 的参数 返回绝对过期时间
+func parseExpire(opt, arg, name string) (time.Time, string) {
+	n, ok := parseInt(arg)
+	if !ok {
+		return time.Time{}, errNotInteger
+	}
+	invalid := "ERR invalid expire time in '" + strings.ToLower(name) + "' command"
+	if n <= 0 {
+		return time.Time{}, invalid
+	}
+	switch opt {
+	case "EX", "EXAT":
+		if n > math.MaxInt64/1000 {
+			return time.Time{}, invalid
+		}
+		n *= 1000
+	}
+	if opt == "EX" || opt == "PX" {
+		now := time.Now().UnixMilli()
+		if n > math.MaxInt64-now {
+			return time.Time{}, invalid
+		}
+		n += now
+	}
+	return time.UnixMilli(n), ""
+}
+
+// GetExCommand GETEX key [EX seconds|PX ms|EXAT unix-time|PXAT unix-time-ms|PERSIST]
+// 返回值并修改过期时间; 以 PXAT 绝对时间传播, 过期时间已过时删除键并以 DEL 传播
+type GetExCommand struct {
+	dataset
+}
+
+func (c *GetExCommand) Name() string {
+	return "GETEX"
+}
+
+func (c *GetExCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *GetExCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *GetExCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs("getex"))
+	}
+	var at time.Time
+	persist := false
+	switch opt := ""; len(args) {
+	case 2:
+	case 3:
+		if strings.ToUpper(args[2]) != "PERSIST" {
+			return rw.WriteError(errSyntax)
+		}
+		persist = true
+	case 4:
+		opt = strings.ToUpper(args[2])
+		if opt != "EX" && opt != "PX" && opt != "EXAT" && opt != "PXAT" {
+			return rw.WriteError(errSyntax)
+		}
+		var reply string
+		if at, reply = parseExpire(opt, args[3], "getex"); reply != "" {
+			return rw.WriteError(reply)
+		}
+	default:
+		return rw.WriteError(errSyntax)
+	}
+
+	key := args[1]
+	var value any
+	var propagated []string
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, err := tx.String(key)
+		if err != nil || o == nil {
+			return err
+		}
+		value = o.Str()
+		switch {
+		case persist:
+			if tx.Persist(key) {
+				propagated = []string{"GETEX", key, "PERSIST"}
+			}
+		case !at.IsZero() && !at.After(time.Now()):
+			tx.Delete(key)
+			propagated = []string{"DEL", key}
+		case !at.IsZero():
+			tx.ExpireAt(key, at)
+			propagated = []string{"GETEX", key, "PXAT", strconv.FormatInt(at.UnixMilli(), 10)}
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if propagated != nil {
+		c.changed(propagated)
+	}
+	return rw.WriteValue(value)
+}
+
+// GetSetCommand GETSET key value 设置新值并返回旧值 清除过期时间
+type GetSetCommand struct {
+	dataset
+}
+
+func (c *GetSetCommand) Name() string {
+	return "GETSET"
+}
+
+func (c *GetSetCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *GetSetCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *GetSetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("getset"))
+	}
+	var old any
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, err := tx.String(args[1])
+		if err != nil {
+			return err
+		}
+		if o != nil {
+			old = o.Str()
+		}
+		tx.Put(args[1], kvstore.NewStringObject(args[2]), 0)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed([]string{"SET", args[1], args[2]})
+	return rw.WriteValue(old)
+}
+
+// SetNXCommand SETNX key value 键不存在时设置 返回 1/0
+type SetNXCommand struct {
+	dataset
+}
+
+func (c *SetNXCommand) Name() string {
+	return "SETNX"
+}
+
+func (c *SetNXCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *SetNXCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *SetNXCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("setnx"))
+	}
+	set := false
+	_ = c.store.Update(func(tx *kvstore.Tx) error {
+		if _, ok := tx.Lookup(args[1]); !ok {
+			tx.Add(args[1], kvstore.NewStringObject(args[2]))
+			set = true
+		}
+		return nil
+	})
+	if set {
+		c.changed(args)
+		return rw.WriteInteger(1)
+	}
+	return rw.WriteInteger(0)
+}
+
+// SetExCommand SETEX key seconds value / PSETEX key milliseconds value
+// 以 SET key value PX <毫秒> 传播
+type SetExCommand struct {
+	dataset
+	name string
+	unit time.Duration
+}
+
+func (c *SetExCommand) Name() string {
+	return c.name
+}
+
+func (c *SetExCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *SetExCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *SetExCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	n, ok := parseInt(args[2])
+	if !ok {
+		return rw.WriteError(errNotInteger)
+	}
+	if n <= 0 || n > math.MaxInt64/int64(c.unit) {
+		return rw.WriteError("ERR invalid expire time in '" + strings.ToLower(c.name) + "' command")
+	}
+	ttl := time.Duration(n) * c.unit
+	_ = c.store.Update(func(tx *kvstore.Tx) error {
+		tx.Put(args[1], kvstore.NewStringObject(args[3]), ttl)
+		return nil
+	})
+	c.changed([]string{"SET", args[1], args[3], "PX", strconv.FormatInt(ttl.Milliseconds(), 10)})
+	return rw.WriteSimpleString("OK")
+}
+
+// MGetCommand MGET key [key ...] 不存在或不是字符串的键为 nil
+type MGetCommand struct {
+	dataset
+}
+
+func (c *MGetCommand) Name() string {
+	return "MGET"
+}
+
+func (c *MGetCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: -1, Step: 1}
+}
+
+func (c *MGetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs("mget"))
+	}
+	res := make([]any, len(args)-1)
+	_ = c.store.Update(func(tx *kvstore.Tx) error {
+		for i, key := range args[1:] {
+			if o, ok := tx.Lookup(key); ok && o.Type == kvstore.TypeString {
+				res[i] = o.Str()
+			}
+		}
+		return nil
+	})
+	return rw.WriteValue(res)
+}
+
+// MSetCommand MSET|MSETNX key value [key value ...] 在一次 Update 内写入全部键
+// MSETNX 只在所有键都不存在时写入 返回 1/0
+type MSetCommand struct {
+	dataset
+	name string
+	nx   bool
+}
+
+func (c *MSetCommand) Name() string {
+	return c.name
+}
+
+func (c *MSetCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *MSetCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: -1, Step: 2}
+}
+
+func (c *MSetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 || len(args)%2 != 1 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	set := true
+	_ = c.store.Update(func(tx *kvstore.Tx) error {
+		if c.nx {
+			for i := 1; i < len(args); i += 2 {
+				if _, ok := tx.Lookup(args[i]); ok {
+					set = false
+					return nil
+				}
+			}
+		}
+		for i := 1; i < len(args); i += 2 {
+			tx.Put(args[i], kvstore.NewStringObject(args[i+1]), 0)
+		}
+		return nil
+	})
+	if set {
+		c.changed(args)
+	}
+	if !c.nx {
+		return rw.WriteSimpleString("OK")
+	}
+	return rw.WriteInteger(boolInt(set))
+}
@@ -0,0 +1,164 @@
+package command
+
+import (
+	"context"
+	"math"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// LCSCommand LCS key1 key2 [LEN] [IDX] [MINMATCHLEN len] [WITHMATCHLEN]
+// 不存在的键视为空串 默认返回公共子序列本身
+type LCSCommand struct {
+	dataset
+}
+
+func (c *LCSCommand) Name() string {
+	return "LCS"
+}
+
+func (c *LCSCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 2, Step: 1}
+}
+
+func (c *LCSCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("lcs"))
+	}
+	var getLen, getIdx, withLen bool
+	var minLen int64
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "LEN":
+			getLen = true
+		case "IDX":
+			getIdx = true
+		case "WITHMATCHLEN":
+			withLen = true
+		case "MINMATCHLEN":
+			if i+1 >= len(args) {
+				return rw.WriteError(errSyntax)
+			}
+			n, ok := parseInt(args[i+1])
+			if !ok {
+				return rw.WriteError(errNotInteger)
+			}
+			minLen = max(n, 0)
+			i++
+		default:
+			return rw.WriteError(errSyntax)
+		}
+	}
+	if getLen && getIdx {
+		return rw.WriteError("ERR If you want both the length and indexes, please just use IDX.")
+	}
+
+	var a, b string
+	wrongType := false
+	_ = c.store.Update(func(tx *kvstore.Tx) error {
+		for i, key := range args[1:3] {
+			o, ok := tx.Lookup(key)
+			if !ok {
+				continue
+			}
+			if o.Type != kvstore.TypeString {
+				wrongType = true
+				return nil
+			}
+			if i == 0 {
+				a = o.Str()
+			} else {
+				b = o.Str()
+			}
+		}
+		return nil
+	})
+	if wrongType {
+		return rw.WriteError("ERR The specified keys must contain string values")
+	}
+	if uint64(len(a)+1)*uint64(len(b)+1) >= math.MaxUint32 {
+		return rw.WriteError("ERR String too long for LCS")
+	}
+
+	lcs, matches, n := computeLCS(a, b, getIdx, !getLen && !getIdx, minLen, withLen)
+	switch {
+	case getLen:
+		return rw.WriteInteger(int64(n))
+	case getIdx:
+		return rw.WriteValue([]any{"matches", matches, "len", int64(n)})
+	}
+	return rw.WriteBulkString(lcs)
+}
+
+// computeLCS 动态规划求最长公共子序列 再从表尾回溯
+// 回溯时把连续匹配的区间合并输出 顺序为从后往前 (与 Redis 一致)
+func computeLCS(a, b string, idx, str bool, minLen int64, withLen bool) (string, []any, uint32) {
+	w := len(b) + 1
+	dp := make([]uint32, (len(a)+1)*w)
+	at := func(i, j int) uint32 { return dp[i*w+j] }
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				dp[i*w+j] = at(i-1, j-1) + 1
+			} else {
+				dp[i*w+j] = max(at(i-1, j), at(i, j-1))
+			}
+		}
+	}
+	n := at(len(a), len(b))
+	if !idx && !str {
+		return "", nil, n
+	}
+
+	result := make([]byte, n)
+	matches := []any{}
+	k := int(n)
+	// aStart == len(a) 表示当前没有正在累积的区间
+	aStart, aEnd, bStart, bEnd := len(a), 0, 0, 0
+	for i, j := len(a), len(b); i > 0 && j > 0; {
+		emit := false
+		if a[i-1] == b[j-1] {
+			result[k-1] = a[i-1]
+			if aStart == len(a) {
+				aStart, aEnd, bStart, bEnd = i-1, i-1, j-1, j-1
+			} else if aStart == i && bStart == j {
+				aStart--
+				bStart--
+			} else {
+				emit = true
+			}
+			if aStart == 0 || bStart == 0 {
+				emit = true
+			}
+			k--
+			i--
+			j--
+		} else {
+			if at(i-1, j) > at(i, j-1) {
+				i--
+			} else {
+				j--
+			}
+			if aStart != len(a) {
+				emit = true
+			}
+		}
+		if emit {
+			l := int64(aEnd - aStart + 1)
+			if idx && (minLen == 0 || l >= minLen) {
+				m := []any{
+					[]any{int64(aStart), int64(aEnd)},
+					[]any{int64(bStart), int64(bEnd)},
+				}
+				if withLen {
+					m = append(m, l)
+				}
+				matches = append(matches, m)
+			}
+			aStart = len(a)
+		}
+	}
+	return string(result), matches, n
+}
@@ -0,0 +1,868 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// TimeSeriesCommands TS.* 时间序列 (与 RedisTimeSeries 兼容)
+// 时间戳为毫秒, * 表示服务器当前时间, 传播时替换为实际写入的时间戳
+func TimeSeriesCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&TSCreateCommand{dataset: d},
+		&TSAddCommand{dataset: d},
+		&TSMAddCommand{dataset: d},
+		&TSDelCommand{dataset: d},
+		&TSGetCommand{dataset: d},
+		&TSRangeCommand{dataset: d, name: "TS.RANGE"},
+		&TSRangeCommand{dataset: d, name: "TS.REVRANGE", rev: true},
+		&TSMRangeCommand{dataset: d, name: "TS.MRANGE"},
+		&TSMRangeCommand{dataset: d, name: "TS.MREVRANGE", rev: true},
+		&TSMGetCommand{dataset: d},
+		&TSQueryIndexCommand{dataset: d},
+		&TSCreateRuleCommand{dataset: d},
+		&TSDeleteRuleCommand{dataset: d},
+		&TSInfoCommand{dataset: d},
+	}
+}
+
+const (
+	errTSExists    = "ERR TSDB: key already exists"
+	errTSNotFound  = "ERR TSDB: the key does not exist"
+	errTSTimestamp = "ERR TSDB: invalid timestamp, must be a nonnegative integer"
+	errTSValue     = "ERR TSDB: invalid value"
+	errTSFilter    = "ERR TSDB: failed parsing labels"
+	errTSNoMatcher = "ERR TSDB: please provide at least one matcher"
+)
+
+func tsSeries(tx *kvstore.Tx, key string) (*kvstore.TimeSeries, error) {
+	s, err := tx.TimeSeries(key)
+	if err == nil && s == nil {
+		err = errors.New(errTSNotFound)
+	}
+	return s, err
+}
+
+// parseTSTimestamp 非负整数或 * (当前时间)
+func parseTSTimestamp(s string) (int64, bool) {
+	if s == "*" {
+		return time.Now().UnixMilli(), true
+	}
+	n, ok := parseInt(s)
+	return n, ok && n >= 0
+}
+
+// parseTSBound 查询区间的端点 - 与 + 表示最早与最晚
+func parseTSBound(s string) (int64, bool) {
+	switch s {
+	case "-":
+		return 0, true
+	case "+":
+		return math.MaxInt64, true
+	}
+	return parseTSTimestamp(s)
+}
+
+// tsOptions TS.CREATE/TS.ADD 的创建参数
+type tsOptions struct {
+	retention int64
+	chunkSize uint64
+	duplicate kvstore.TSDuplicatePolicy
+	onDup     *kvstore.TSDuplicatePolicy
+	labels    []kvstore.TSLabel
+}
+
+// parseTSOptions [RETENTION ms] [ENCODING COMPRESSED] [CHUNK_SIZE size] [DUPLICATE_POLICY policy]
+// [ON_DUPLICATE policy (仅 TS.ADD)] [LABELS label value ...]
+func parseTSOptions(args []string, add bool) (tsOptions, string) {
+	opts := tsOptions{chunkSize: kvstore.TSDefaultChunkSize}
+	for i := 0; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		if opt == "LABELS" {
+			rest := args[i+1:]
+			if len(rest)%2 != 0 {
+				return opts, errTSFilter
+			}
+			for j := 0; j < len(rest); j += 2 {
+				opts.labels = append(opts.labels, kvstore.TSLabel{Name: rest[j], Value: rest[j+1]})
+			}
+			break
+		}
+		if i+1 >= len(args) {
+			return opts, errSyntax
+		}
+		val := args[i+1]
+		i++
+		switch opt {
+		case "RETENTION":
+			n, ok := parseInt(val)
+			if !ok || n < 0 {
+				return opts, "ERR TSDB: invalid RETENTION value"
+			}
+			opts.retention = n
+		case "ENCODING":
+			if !strings.EqualFold(val, "COMPRESSED") {
+				return opts, "ERR TSDB: only COMPRESSED encoding is supported"
+			}
+		case "CHUNK_SIZE":
+			n, ok := parseInt(val)
+			if !ok || n < 48 || n > 1048576 || n%8 != 0 {
+				return opts, "ERR TSDB: CHUNK_SIZE value must be a multiple of 8 in the range [48 .. 1048576]"
+			}
+			opts.chunkSize = uint64(n)
+		case "DUPLICATE_POLICY", "ON_DUPLICATE":
+			p, ok := kvstore.ParseTSDuplicatePolicy(val)
+			if !ok {
+				return opts, "ERR TSDB: Unknown DUPLICATE_POLICY"
+			}
+			if opt == "DUPLICATE_POLICY" {
+				opts.duplicate = p
+			} else if add {
+				opts.onDup = &p
+			} else {
+				return opts, errSyntax
+			}
+		default:
+			return opts, errSyntax
+		}
+	}
+	return opts, ""
+}
+
+func (o tsOptions) series() *kvstore.TimeSeries {
+	s := kvstore.NewTimeSeries()
+	s.Retention = o.retention
+	s.ChunkSize = o.chunkSize
+	s.Duplicate = o.duplicate
+	s.Labels = o.labels
+	return s
+}
+
+func tsSampleReply(s kvstore.TSSample) []any {
+	return []any{s.Timestamp, protocol.SimpleString(formatFloat(s.Value))}
+}
+
+func tsLabelsReply(labels []kvstore.TSLabel) []any {
+	res := make([]any, len(labels))
+	for i, l := range labels {
+		res[i] = []any{l.Name, l.Value}
+	}
+	return res
+}
+
+// TSCreateCommand TS.CREATE key [选项]
+type TSCreateCommand struct {
+	dataset
+}
+
+func (c *TSCreateCommand) Name() string {
+	return "TS.CREATE"
+}
+
+func (c *TSCreateCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *TSCreateCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *TSCreateCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs("ts.create"))
+	}
+	opts, reply := parseTSOptions(args[2:], false)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		if _, ok := tx.Lookup(args[1]); ok {
+			return errors.New(errTSExists)
+		}
+		tx.Add(args[1], kvstore.NewTimeSeriesObject(opts.series()))
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed(args)
+	return rw.WriteSimpleString("OK")
+}
+
+// TSAddCommand TS.ADD key timestamp value [选项] 键不存在时按选项创建, 回复写入的时间戳
+// ON_DUPLICATE 覆盖序列本身的重复策略
+type TSAddCommand struct {
+	dataset
+}
+
+func (c *TSAddCommand) Name() string {
+	return "TS.ADD"
+}
+
+func (c *TSAddCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *TSAddCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *TSAddCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 {
+		return rw.WriteError(errWrongArgs("ts.add"))
+	}
+	t, ok := parseTSTimestamp(args[2])
+	if !ok {
+		return rw.WriteError(errTSTimestamp)
+	}
+	v, ok := parseFloat(args[3])
+	if !ok {
+		return rw.WriteError(errTSValue)
+	}
+	opts, reply := parseTSOptions(args[4:], true)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := tx.TimeSeries(args[1])
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			s = opts.series()
+			tx.Add(args[1], kvstore.NewTimeSeriesObject(s))
+		}
+		policy := s.Duplicate
+		if opts.onDup != nil {
+			policy = *opts.onDup
+		}
+		return tx.TSAdd(s, t, v, policy)
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	propagated := slices.Clone(args)
+	propagated[2] = strconv.FormatInt(t, 10)
+	c.changed(propagated)
+	return rw.WriteInteger(t)
+}
+
+// TSMAddCommand TS.MADD key timestamp value [key timestamp value ...]
+// 键必须已存在; 每个样本一个结果 (时间戳或错误)
+type TSMAddCommand struct {
+	dataset
+}
+
+func (c *TSMAddCommand) Name() string {
+	return "TS.MADD"
+}
+
+func (c *TSMAddCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *TSMAddCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: -1, Step: 3}
+}
+
+func (c *TSMAddCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 || (len(args)-1)%3 != 0 {
+		return rw.WriteError(errWrongArgs("ts.madd"))
+	}
+	propagated := slices.Clone(args)
+	samples := make([]kvstore.TSSample, 0, len(args)/3)
+	for i := 1; i < len(args); i += 3 {
+		t, ok := parseTSTimestamp(args[i+1])
+		if !ok {
+			return rw.WriteError(errTSTimestamp)
+		}
+		v, ok := parseFloat(args[i+2])
+		if !ok {
+			return rw.WriteError(errTSValue)
+		}
+		propagated[i+1] = strconv.FormatInt(t, 10)
+		samples = append(samples, kvstore.TSSample{Timestamp: t, Value: v})
+	}
+	res := make([]any, len(samples))
+	modified := false
+	c.store.Update(func(tx *kvstore.Tx) error {
+		for i, smp := range samples {
+			s, err := tsSeries(tx, args[1+3*i])
+			if err == nil {
+				err = tx.TSAdd(s, smp.Timestamp, smp.Value, s.Duplicate)
+			}
+			if err != nil {
+				res[i] = protocol.ErrorReply(err.Error())
+				continue
+			}
+			modified = true
+			res[i] = smp.Timestamp
+		}
+		return nil
+	})
+	if modified {
+		c.changed(propagated)
+	}
+	return rw.WriteValue(res)
+}
+
+// TSDelCommand TS.DEL key from to 删除区间内的样本 (不更新压缩规则的目标)
+type TSDelCommand struct {
+	dataset
+}
+
+func (c *TSDelCommand) Name() string {
+	return "TS.DEL"
+}
+
+func (c *TSDelCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *TSDelCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *TSDelCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs("ts.del"))
+	}
+	from, ok1 := parseTSBound(args[2])
+	to, ok2 := parseTSBound(args[3])
+	if !ok1 || !ok2 {
+		return rw.WriteError(errTSTimestamp)
+	}
+	var deleted uint64
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := tsSeries(tx, args[1])
+		if err != nil {
+			return err
+		}
+		deleted = s.Delete(from, to)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if deleted > 0 {
+		c.changed(args)
+	}
+	return rw.WriteInteger(int64(deleted))
+}
+
+// TSGetCommand TS.GET key 最后一个样本 没有样本时为空数组
+type TSGetCommand struct {
+	dataset
+}
+
+func (c *TSGetCommand) Name() string {
+	return "TS.GET"
+}
+
+func (c *TSGetCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *TSGetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs("ts.get"))
+	}
+	res := []any{}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := tsSeries(tx, args[1])
+		if err != nil {
+			return err
+		}
+		if last, ok := s.Last(); ok {
+			res = tsSampleReply(last)
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// tsRangeQuery TS.RANGE/TS.MRANGE 的查询参数
+type tsRangeQuery struct {
+	from, to    int64
+	filterTS    []int64
+	filterValue []float64 // [min, max]
+	count       int64     // -1 表示不限
+	agg         *kvstore.TSRule
+	withLabels  bool
+	matchers    []kvstore.TSMatcher
+}
+
+// parseTSRange from to [FILTER_BY_TS ts ...] [FILTER_BY_VALUE min max] [COUNT count]
+// [ALIGN align] [AGGREGATION aggregator bucketDuration]; multi 时还有 [WITHLABELS] FILTER filter ...
+func parseTSRange(args []string, multi bool) (*tsRangeQuery, string) {
+	if len(args) < 2 {
+		return nil, errSyntax
+	}
+	q := &tsRangeQuery{count: -1}
+	var ok1, ok2 bool
+	q.from, ok1 = parseTSBound(args[0])
+	q.to, ok2 = parseTSBound(args[1])
+	if !ok1 || !ok2 {
+		return nil, errTSTimestamp
+	}
+	align := ""
+	for i := 2; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); {
+		case opt == "FILTER_BY_TS":
+			for i+1 < len(args) {
+				t, ok := parseInt(args[i+1])
+				if !ok {
+					break
+				}
+				q.filterTS = append(q.filterTS, t)
+				i++
+			}
+			if len(q.filterTS) == 0 {
+				return nil, "ERR TSDB: FILTER_BY_TS one or more arguments are missing"
+			}
+		case opt == "FILTER_BY_VALUE" && i+2 < len(args):
+			lo, ok1 := parseFloat(args[i+1])
+			hi, ok2 := parseFloat(args[i+2])
+			if !ok1 || !ok2 {
+				return nil, "ERR TSDB: wrong value for FILTER_BY_VALUE"
+			}
+			q.filterValue = []float64{lo, hi}
+			i += 2
+		case opt == "COUNT" && i+1 < len(args):
+			n, ok := parseInt(args[i+1])
+			if !ok || n <= 0 {
+				return nil, "ERR TSDB: Invalid COUNT value"
+			}
+			q.count = n
+			i++
+		case opt == "ALIGN" && i+1 < len(args):
+			align = args[i+1]
+			i++
+		case opt == "AGGREGATION" && i+2 < len(args):
+			agg, ok := kvstore.ParseTSAggregator(args[i+1])
+			if !ok {
+				return nil, "ERR TSDB: Unknown aggregation type"
+			}
+			bucket, ok := parseInt(args[i+2])
+			if !ok || bucket <= 0 {
+				return nil, "ERR TSDB: bucketDuration must be greater than zero"
+			}
+			q.agg = &kvstore.TSRule{Agg: agg, Bucket: bucket}
+			i += 2
+		case multi && opt == "WITHLABELS":
+			q.withLabels = true
+		case multi && opt == "FILTER":
+			var reply string
+			if q.matchers, reply = parseTSFilters(args[i+1:]); reply != "" {
+				return nil, reply
+			}
+			i = len(args)
+		default:
+			return nil, errSyntax
+		}
+	}
+	if multi && q.matchers == nil {
+		return nil, errTSNoMatcher
+	}
+	if align != "" {
+		if q.agg == nil {
+			return nil, "ERR TSDB: ALIGN parameter can only be used with AGGREGATION"
+		}
+		switch strings.ToLower(align) {
+		case "-", "start":
+			q.agg.Align = q.from
+		case "+", "end":
+			q.agg.Align = q.to
+		default:
+			n, ok := parseInt(align)
+			if !ok {
+				return nil, "ERR TSDB: unknown ALIGN parameter"
+			}
+			q.agg.Align = n
+		}
+	}
+	return q, ""
+}
+
+// parseTSFilters FILTER 之后的过滤条件 label=value | label!=value | label=(v1,v2) | label!=(v1,v2)
+// 至少要有一个 label=value 或 label=(v1,v2); 不支持 GROUPBY/REDUCE
+func parseTSFilters(args []string) ([]kvstore.TSMatcher, string) {
+	matchers := []kvstore.TSMatcher{}
+	for _, f := range args {
+		if strings.EqualFold(f, "GROUPBY") || strings.EqualFold(f, "REDUCE") {
+			return nil, "ERR TSDB: GROUPBY/REDUCE is not supported"
+		}
+		m, ok := kvstore.ParseTSMatcher(f)
+		if !ok {
+			return nil, errTSFilter + " '" + f + "': expected label=value, label!=value, label=(v1,v2) or label!=(v1,v2)"
+		}
+		matchers = append(matchers, m)
+	}
+	if !slices.ContainsFunc(matchers, kvstore.TSMatcher.Positive) {
+		return nil, errTSNoMatcher
+	}
+	return matchers, ""
+}
+
+// samples 区间内的样本 先按时间戳/值过滤, 再聚合, 最后按方向截取 COUNT 个
+func (q *tsRangeQuery) samples(s *kvstore.TimeSeries, rev bool) []any {
+	samples := s.Range(q.from, q.to)
+	samples = slices.DeleteFunc(samples, func(smp kvstore.TSSample) bool {
+		return (q.filterTS != nil && !slices.Contains(q.filterTS, smp.Timestamp)) ||
+			(q.filterValue != nil && (smp.Value < q.filterValue[0] || smp.Value > q.filterValue[1]))
+	})
+	if q.agg != nil {
+		samples = kvstore.TSAggregate(samples, q.agg.Agg, q.agg.Bucket, q.agg.Align)
+	}
+	if rev {
+		slices.Reverse(samples)
+	}
+	if q.count >= 0 && int64(len(samples)) > q.count {
+		samples = samples[:q.count]
+	}
+	res := make([]any, len(samples))
+	for i, smp := range samples {
+		res[i] = tsSampleReply(smp)
+	}
+	return res
+}
+
+func (q *tsRangeQuery) match(s *kvstore.TimeSeries) bool {
+	for _, m := range q.matchers {
+		if !m.Match(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// TSRangeCommand TS.RANGE key from to [选项] / TS.REVRANGE (按时间戳从新到旧)
+type TSRangeCommand struct {
+	dataset
+	name string
+	rev  bool
+}
+
+func (c *TSRangeCommand) Name() string {
+	return c.name
+}
+
+func (c *TSRangeCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *TSRangeCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	q, reply := parseTSRange(args[2:], false)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	var res []any
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := tsSeries(tx, args[1])
+		if err != nil {
+			return err
+		}
+		res = q.samples(s, c.rev)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// tsMatching 标签满足条件的所有序列 按键名排序
+func tsMatching(store *kvstore.Store, q *tsRangeQuery, fn func(key string, s *kvstore.TimeSeries)) {
+	keys := store.Keys()
+	slices.Sort(keys)
+	store.Update(func(tx *kvstore.Tx) error {
+		for _, key := range keys {
+			if s, err := tx.TimeSeries(key); err == nil && s != nil && q.match(s) {
+				fn(key, s)
+			}
+		}
+		return nil
+	})
+}
+
+// TSMRangeCommand TS.MRANGE from to [选项] [WITHLABELS] FILTER filter ... / TS.MREVRANGE
+// 每个序列回复 [键, 标签 (WITHLABELS 时), 样本]
+type TSMRangeCommand struct {
+	dataset
+	name string
+	rev  bool
+}
+
+func (c *TSMRangeCommand) Name() string {
+	return c.name
+}
+
+func (c *TSMRangeCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 5 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	q, reply := parseTSRange(args[1:], true)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	res := []any{}
+	tsMatching(c.store, q, func(key string, s *kvstore.TimeSeries) {
+		labels := []any{}
+		if q.withLabels {
+			labels = tsLabelsReply(s.Labels)
+		}
+		res = append(res, []any{key, labels, q.samples(s, c.rev)})
+	})
+	return rw.WriteValue(res)
+}
+
+// TSMGetCommand TS.MGET [WITHLABELS] FILTER filter ...
+// 每个序列回复 [键, 标签 (WITHLABELS 时), 最新样本 (没有样本时为空数组)]
+type TSMGetCommand struct {
+	dataset
+}
+
+func (c *TSMGetCommand) Name() string {
+	return "TS.MGET"
+}
+
+func (c *TSMGetCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("ts.mget"))
+	}
+	q := &tsRangeQuery{}
+	for i := 1; i < len(args) && q.matchers == nil; i++ {
+		switch strings.ToUpper(args[i]) {
+		case "WITHLABELS":
+			q.withLabels = true
+		case "FILTER":
+			var reply string
+			if q.matchers, reply = parseTSFilters(args[i+1:]); reply != "" {
+				return rw.WriteError(reply)
+			}
+		default:
+			return rw.WriteError(errSyntax)
+		}
+	}
+	if q.matchers == nil {
+		return rw.WriteError(errTSNoMatcher)
+	}
+	res := []any{}
+	tsMatching(c.store, q, func(key string, s *kvstore.TimeSeries) {
+		labels, sample := []any{}, []any{}
+		if q.withLabels {
+			labels = tsLabelsReply(s.Labels)
+		}
+		if last, ok := s.Last(); ok {
+			sample = tsSampleReply(last)
+		}
+		res = append(res, []any{key, labels, sample})
+	})
+	return rw.WriteValue(res)
+}
+
+// TSQueryIndexCommand TS.QUERYINDEX filter [filter ...] 标签满足条件的键
+type TSQueryIndexCommand struct {
+	dataset
+}
+
+func (c *TSQueryIndexCommand) Name() string {
+	return "TS.QUERYINDEX"
+}
+
+func (c *TSQueryIndexCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 {
+		return rw.WriteError(errWrongArgs("ts.queryindex"))
+	}
+	q, reply := parseTSRange(append([]string{"-", "+", "FILTER"}, args[1:]...), true)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	res := []any{}
+	tsMatching(c.store, q, func(key string, s *kvstore.TimeSeries) {
+		res = append(res, key)
+	})
+	return rw.WriteValue(res)
+}
+
+// TSCreateRuleCommand TS.CREATERULE sourceKey destKey AGGREGATION aggregator bucketDuration [alignTimestamp]
+// 目标只能有一个源, 且自身不能有规则 (避免环); 已有的样本不会回填, 也不参与之后的聚合
+type TSCreateRuleCommand struct {
+	dataset
+}
+
+func (c *TSCreateRuleCommand) Name() string {
+	return "TS.CREATERULE"
+}
+
+func (c *TSCreateRuleCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *TSCreateRuleCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 2, Step: 1}
+}
+
+func (c *TSCreateRuleCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if (len(args) != 6 && len(args) != 7) || !strings.EqualFold(args[3], "AGGREGATION") {
+		return rw.WriteError(errWrongArgs("ts.createrule"))
+	}
+	rule := kvstore.TSRule{DestKey: args[2]}
+	var ok bool
+	if rule.Agg, ok = kvstore.ParseTSAggregator(args[4]); !ok {
+		return rw.WriteError("ERR TSDB: Unknown aggregation type")
+	}
+	if rule.Bucket, ok = parseInt(args[5]); !ok || rule.Bucket <= 0 {
+		return rw.WriteError("ERR TSDB: bucketDuration must be greater than zero")
+	}
+	if len(args) == 7 {
+		if rule.Align, ok = parseInt(args[6]); !ok {
+			return rw.WriteError("ERR TSDB: invalid alignTimestamp")
+		}
+	}
+	if args[1] == args[2] {
+		return rw.WriteError("ERR TSDB: the source key and destination key should be different")
+	}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		src, err := tsSeries(tx, args[1])
+		if err != nil {
+			return err
+		}
+		dst, err := tsSeries(tx, args[2])
+		if err != nil {
+			return err
+		}
+		// 源键被删除后目标上留下的 SrcKey 不再有效
+		if dst.SrcKey != "" {
+			if old, _ := tx.TimeSeries(dst.SrcKey); old != nil && slices.ContainsFunc(old.Rules, func(r kvstore.TSRule) bool { return r.DestKey == args[2] }) {
+				return errors.New("ERR TSDB: the destination key already has a src rule")
+			}
+		}
+		if len(dst.Rules) > 0 {
+			return errors.New("ERR TSDB: the destination key already has a dst rule")
+		}
+		// 已有的样本不参与聚合 第一个桶从之后写入的样本开始
+		if last, ok := src.Last(); ok {
+			rule.Since = last.Timestamp + 1
+		}
+		src.Rules = append(src.Rules, rule)
+		dst.SrcKey = args[1]
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed(args)
+	return rw.WriteSimpleString("OK")
+}
+
+// TSDeleteRuleCommand TS.DELETERULE sourceKey destKey
+type TSDeleteRuleCommand struct {
+	dataset
+}
+
+func (c *TSDeleteRuleCommand) Name() string {
+	return "TS.DELETERULE"
+}
+
+func (c *TSDeleteRuleCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *TSDeleteRuleCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 2, Step: 1}
+}
+
+func (c *TSDeleteRuleCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("ts.deleterule"))
+	}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		src, err := tsSeries(tx, args[1])
+		if err != nil {
+			return err
+		}
+		if !src.RemoveRule(args[2]) {
+			return errors.New("ERR TSDB: compaction rule does not exist")
+		}
+		if dst, _ := tx.TimeSeries(args[2]); dst != nil && dst.SrcKey == args[1] {
+			dst.SrcKey = ""
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed(args)
+	return rw.WriteSimpleString("OK")
+}
+
+// TSInfoCommand TS.INFO key
+type TSInfoCommand struct {
+	dataset
+}
+
+func (c *TSInfoCommand) Name() string {
+	return "TS.INFO"
+}
+
+func (c *TSInfoCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *TSInfoCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs("ts.info"))
+	}
+	var info []any
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		s, err := tsSeries(tx, args[1])
+		if err != nil {
+			return err
+		}
+		last, _ := s.Last()
+		var src any
+		if s.SrcKey != "" {
+			src = s.SrcKey
+		}
+		rules := make([]any, len(s.Rules))
+		for i, r := range s.Rules {
+			rules[i] = []any{r.DestKey, r.Bucket, strings.ToUpper(r.Agg.String()), r.Align}
+		}
+		info = []any{
+			"totalSamples", int64(s.Total),
+			"memoryUsage", int64(s.MemoryUsage()),
+			"firstTimestamp", s.First(),
+			"lastTimestamp", last.Timestamp,
+			"retentionTime", s.Retention,
+			"chunkCount", int64(len(s.Chunks)),
+			"chunkSize", int64(s.ChunkSize),
+			"chunkType", "compressed",
+			"duplicatePolicy", s.Duplicate.String(),
+			"labels", tsLabelsReply(s.Labels),
+			"sourceKey", src,
+			"rules", rules,
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(info)
+}
@@ -0,0 +1,270 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// TopKCommands TOPK.* 高频元素跟踪 (与 RedisBloom 兼容) 使用前需要 TOPK.RESERVE 创建
+func TopKCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&TopKReserveCommand{dataset: d},
+		&TopKAddCommand{dataset: d, name: "TOPK.ADD"},
+		&TopKAddCommand{dataset: d, name: "TOPK.INCRBY", incr: true},
+		&TopKQueryCommand{dataset: d, name: "TOPK.QUERY"},
+		&TopKQueryCommand{dataset: d, name: "TOPK.COUNT", count: true},
+		&TopKListCommand{dataset: d},
+		&TopKInfoCommand{dataset: d},
+	}
+}
+
+const (
+	errTopKExists   = "TopK: key already exists"
+	errTopKNotFound = "TopK: key does not exist"
+)
+
+func topkSketch(tx *kvstore.Tx, key string) (*kvstore.TopK, error) {
+	t, err := tx.TopK(key)
+	if err == nil && t == nil {
+		err = errors.New(errTopKNotFound)
+	}
+	return t, err
+}
+
+// TopKReserveCommand TOPK.RESERVE key topk [width depth decay] 默认 8, 7, 0.9
+type TopKReserveCommand struct {
+	dataset
+}
+
+func (c *TopKReserveCommand) Name() string {
+	return "TOPK.RESERVE"
+}
+
+func (c *TopKReserveCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *TopKReserveCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *TopKReserveCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 && len(args) != 6 {
+		return rw.WriteError(errWrongArgs("topk.reserve"))
+	}
+	k, ok := parseUint(args[2])
+	if !ok {
+		return rw.WriteError("TopK: invalid k")
+	}
+	width, depth, decay := uint64(kvstore.TopKDefaultWidth), uint64(kvstore.TopKDefaultDepth), kvstore.TopKDefaultDecay
+	if len(args) == 6 {
+		var ok1, ok2, ok3 bool
+		width, ok1 = parseUint(args[3])
+		depth, ok2 = parseUint(args[4])
+		decay, ok3 = parseFloat(args[5])
+		if !ok1 || !ok2 {
+			return rw.WriteError("TopK: invalid width or depth")
+		}
+		if !ok3 || decay <= 0 || decay > 1 {
+			return rw.WriteError("TopK: invalid decay value. must be '<= 1' & '> 0'")
+		}
+	}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		if _, ok := tx.Lookup(args[1]); ok {
+			return errors.New(errTopKExists)
+		}
+		t, err := kvstore.NewTopK(k, width, depth, decay)
+		if err != nil {
+			return err
+		}
+		tx.Add(args[1], kvstore.NewTopKObject(t))
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed(args)
+	return rw.WriteSimpleString("OK")
+}
+
+// TopKAddCommand TOPK.ADD key item [item ...] / TOPK.INCRBY key item increment [item increment ...]
+// 每个元素回复被挤出前 K 的元素 (没有时为 nil)
+type TopKAddCommand struct {
+	dataset
+	name string
+	incr bool
+}
+
+func (c *TopKAddCommand) Name() string {
+	return c.name
+}
+
+func (c *TopKAddCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *TopKAddCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *TopKAddCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 || (c.incr && len(args)%2 != 0) {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	var items []string
+	var incrs []uint32
+	if c.incr {
+		for i := 2; i < len(args); i += 2 {
+			n, ok := parseInt(args[i+1])
+			if !ok || n < 1 || n > 100000 {
+				return rw.WriteError("TopK: increment must be an integer greater or equal to 1 and less than or equal to 100000")
+			}
+			items = append(items, args[i])
+			incrs = append(incrs, uint32(n))
+		}
+	} else {
+		items = args[2:]
+	}
+	res := make([]any, len(items))
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		t, err := topkSketch(tx, args[1])
+		if err != nil {
+			return err
+		}
+		for i, item := range items {
+			incr := uint32(1)
+			if c.incr {
+				incr = incrs[i]
+			}
+			if expelled, ok := t.Add(item, incr); ok {
+				res[i] = expelled
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	c.changed(args)
+	return rw.WriteValue(res)
+}
+
+// TopKQueryCommand TOPK.QUERY key item [item ...] (是否在前 K 个中) / TOPK.COUNT key item [item ...] (估计计数)
+type TopKQueryCommand struct {
+	dataset
+	name  string
+	count bool
+}
+
+func (c *TopKQueryCommand) Name() string {
+	return c.name
+}
+
+func (c *TopKQueryCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *TopKQueryCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	res := make([]any, len(args)-2)
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		t, err := topkSketch(tx, args[1])
+		if err != nil {
+			return err
+		}
+		for i, item := range args[2:] {
+			if c.count {
+				res[i] = int64(t.Count(item))
+			} else {
+				res[i] = boolInt(t.Query(item))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// TopKListCommand TOPK.LIST key [WITHCOUNT] 按计数从大到小
+type TopKListCommand struct {
+	dataset
+}
+
+func (c *TopKListCommand) Name() string {
+	return "TOPK.LIST"
+}
+
+func (c *TopKListCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *TopKListCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 && len(args) != 3 {
+		return rw.WriteError(errWrongArgs("topk.list"))
+	}
+	withCount := len(args) == 3
+	if withCount && !strings.EqualFold(args[2], "WITHCOUNT") {
+		return rw.WriteError(errSyntax)
+	}
+	var res []any
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		t, err := topkSketch(tx, args[1])
+		if err != nil {
+			return err
+		}
+		res = []any{}
+		for _, it := range t.List() {
+			res = append(res, it.Item)
+			if withCount {
+				res = append(res, int64(it.Count))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// TopKInfoCommand TOPK.INFO key
+type TopKInfoCommand struct {
+	dataset
+}
+
+func (c *TopKInfoCommand) Name() string {
+	return "TOPK.INFO"
+}
+
+func (c *TopKInfoCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *TopKInfoCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs("topk.info"))
+	}
+	var info []any
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		t, err := topkSketch(tx, args[1])
+		if err != nil {
+			return err
+		}
+		info = []any{"k", int64(t.K), "width", int64(t.Width), "depth", int64(t.Depth), "decay", formatFloat(t.Decay)}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(info)
+}
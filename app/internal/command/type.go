@@ -0,0 +1,32 @@
+package command
+
+import (
+	"context"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// TypeCommand TYPE key 返回值的类型 键不存在时为 none
+type TypeCommand struct {
+	store *kvstore.Store
+}
+
+func NewTypeCommand(store *kvstore.Store) *TypeCommand {
+	return &TypeCommand{store: store}
+}
+
+func (c *TypeCommand) Name() string {
+	return "TYPE"
+}
+
+func (c *TypeCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *TypeCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError("ERR wrong number of arguments for 'type' command")
+	}
+	return rw.WriteSimpleString(c.store.Type(args[1]))
+}
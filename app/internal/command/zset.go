@@ -0,0 +1,684 @@
+package command
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/blocking"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+const errNotFloat = "ERR value is not a valid float"
+
+// ZSetCommands 有序集合类型的全部命令 (含 BZPOPMIN/BZPOPMAX)
+func ZSetCommands(store *kvstore.Store, fn string, master replication.MasterServerInterface, blocked *blocking.Registry) []Handler {
+	d := dataset{store: store, fn: fn, master: master}
+	return []Handler{
+		&ZAddCommand{dataset: d},
+		&ZIncrByCommand{dataset: d},
+		&ZScoreCommand{dataset: d},
+		&ZMScoreCommand{dataset: d},
+		&ZCardCommand{dataset: d},
+		&ZRankCommand{dataset: d, name: "ZRANK"},
+		&ZRankCommand{dataset: d, name: "ZREVRANK", rev: true},
+		&ZRemCommand{dataset: d},
+		&ZPopCommand{dataset: d, name: "ZPOPMIN"},
+		&ZPopCommand{dataset: d, name: "ZPOPMAX", max: true},
+		&BZPopCommand{dataset: d, blocked: blocked, name: "BZPOPMIN"},
+		&BZPopCommand{dataset: d, blocked: blocked, name: "BZPOPMAX", max: true},
+		&ZRandMemberCommand{dataset: d},
+		&ZScanCommand{dataset: d},
+		&ZRangeCommand{dataset: d, name: "ZRANGE", by: zbyRank, generic: true},
+		&ZRangeCommand{dataset: d, name: "ZRANGESTORE", by: zbyRank, generic: true, dest: true},
+		&ZRangeCommand{dataset: d, name: "ZREVRANGE", by: zbyRank, rev: true},
+		&ZRangeCommand{dataset: d, name: "ZRANGEBYSCORE", by: zbyScore},
+		&ZRangeCommand{dataset: d, name: "ZREVRANGEBYSCORE", by: zbyScore, rev: true},
+		&ZRangeCommand{dataset: d, name: "ZRANGEBYLEX", by: zbyLex},
+		&ZRangeCommand{dataset: d, name: "ZREVRANGEBYLEX", by: zbyLex, rev: true},
+		&ZRemRangeCommand{dataset: d, name: "ZREMRANGEBYRANK", by: zbyRank},
+		&ZRemRangeCommand{dataset: d, name: "ZREMRANGEBYSCORE", by: zbyScore},
+		&ZRemRangeCommand{dataset: d, name: "ZREMRANGEBYLEX", by: zbyLex},
+		&ZCountCommand{dataset: d, name: "ZCOUNT", by: zbyScore},
+		&ZCountCommand{dataset: d, name: "ZLEXCOUNT", by: zbyLex},
+		&ZSetOpCommand{dataset: d, name: "ZUNION", op: setUnion},
+		&ZSetOpCommand{dataset: d, name: "ZINTER", op: setInter},
+		&ZSetOpCommand{dataset: d, name: "ZDIFF", op: setDiff},
+		&ZSetOpCommand{dataset: d, name: "ZUNIONSTORE", op: setUnion, dest: true},
+		&ZSetOpCommand{dataset: d, name: "ZINTERSTORE", op: setInter, dest: true},
+		&ZSetOpCommand{dataset: d, name: "ZDIFFSTORE", op: setDiff, dest: true},
+	}
+}
+
+// formatScore 分值的字符串形式 无穷大为 inf/-inf
+func formatScore(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return formatFloat(f)
+}
+
+// zentries 元素的回复 withScores 时为 member, score 交替的平铺数组
+func zentries(entries []kvstore.ZEntry, withScores bool) []string {
+	res := make([]string, 0, len(entries)*2)
+	for _, e := range entries {
+		res = append(res, e.Member)
+		if withScores {
+			res = append(res, formatScore(e.Score))
+		}
+	}
+	return res
+}
+
+// readZSet 在一次 Update 内读取有序集合 键不存在时不调用 fn
+func (d *dataset) readZSet(key string, fn func(z *kvstore.ZSet)) error {
+	return d.store.Update(func(tx *kvstore.Tx) error {
+		z, err := tx.ZSet(key)
+		if err == nil && z != nil {
+			fn(z)
+		}
+		return err
+	})
+}
+
+// ZAddCommand ZADD key [NX|XX] [GT|LT] [CH] [INCR] score member [score member ...]
+// 默认返回新增的成员数, CH 时加上分值被修改的成员数; INCR 时返回新分值 (条件不满足时为 nil)
+type ZAddCommand struct {
+	dataset
+}
+
+func (c *ZAddCommand) Name() string {
+	return "ZADD"
+}
+
+func (c *ZAddCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *ZAddCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *ZAddCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 4 {
+		return rw.WriteError(errWrongArgs("zadd"))
+	}
+	var nx, xx, gt, lt, ch, incr bool
+	i := 2
+opts:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GT":
+			gt = true
+		case "LT":
+			lt = true
+		case "CH":
+			ch = true
+		case "INCR":
+			incr = true
+		default:
+			break opts
+		}
+	}
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return rw.WriteError(errSyntax)
+	}
+	if nx && xx {
+		return rw.WriteError("ERR XX and NX options at the same time are not compatible")
+	}
+	if (gt && lt) || (nx && (gt || lt)) {
+		return rw.WriteError("ERR GT, LT, and/or NX options at the same time are not compatible")
+	}
+	if incr && len(pairs) > 2 {
+		return rw.WriteError("ERR INCR option supports a single increment-element pair")
+	}
+	scores := make([]float64, len(pairs)/2)
+	for j := range scores {
+		f, ok := parseFloat(pairs[2*j])
+		if !ok {
+			return rw.WriteError(errNotFloat)
+		}
+		scores[j] = f
+	}
+
+	key := args[1]
+	added, updated := 0, 0
+	var result any
+	reply := ""
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		z, err := tx.ZSetOrCreate(key)
+		if err != nil {
+			return err
+		}
+		members := make([]string, 0, len(scores))
+		for j, score := range scores {
+			m := pairs[2*j+1]
+			cur, exists := z.Score(m)
+			if !exists {
+				if xx {
+					continue
+				}
+				z.Set(m, score)
+				members = append(members, m)
+				added++
+				result = formatScore(score)
+				continue
+			}
+			if nx {
+				continue
+			}
+			if incr {
+				score += cur
+				if math.IsNaN(score) {
+					reply = "ERR resulting score is not a number (NaN)"
+					break
+				}
+			}
+			if (gt && score <= cur) || (lt && score >= cur) {
+				continue
+			}
+			if score != cur {
+				z.Set(m, score)
+				updated++
+			}
+			result = formatScore(score)
+		}
+		tx.ZSetChanged(key, members...)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	if added+updated > 0 {
+		c.changed(args)
+	}
+	switch {
+	case incr:
+		return rw.WriteValue(result)
+	case ch:
+		return rw.WriteInteger(int64(added + updated))
+	}
+	return rw.WriteInteger(int64(added))
+}
+
+// ZIncrByCommand ZINCRBY key increment member 返回新分值
+type ZIncrByCommand struct {
+	dataset
+}
+
+func (c *ZIncrByCommand) Name() string {
+	return "ZINCRBY"
+}
+
+func (c *ZIncrByCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *ZIncrByCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *ZIncrByCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs("zincrby"))
+	}
+	incr, ok := parseFloat(args[2])
+	if !ok {
+		return rw.WriteError(errNotFloat)
+	}
+	key, member := args[1], args[3]
+	var score float64
+	reply := ""
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		z, err := tx.ZSetOrCreate(key)
+		if err != nil {
+			return err
+		}
+		defer tx.ZSetChanged(key, member)
+		cur, _ := z.Score(member)
+		score = cur + incr
+		if math.IsNaN(score) {
+			reply = "ERR resulting score is not a number (NaN)"
+			return nil
+		}
+		z.Set(member, score)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	c.changed(args)
+	return rw.WriteBulkString(formatScore(score))
+}
+
+// ZScoreCommand ZSCORE key member
+type ZScoreCommand struct {
+	dataset
+}
+
+func (c *ZScoreCommand) Name() string {
+	return "ZSCORE"
+}
+
+func (c *ZScoreCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *ZScoreCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 3 {
+		return rw.WriteError(errWrongArgs("zscore"))
+	}
+	var reply any
+	err := c.readZSet(args[1], func(z *kvstore.ZSet) {
+		if s, ok := z.Score(args[2]); ok {
+			reply = formatScore(s)
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(reply)
+}
+
+// ZMScoreCommand ZMSCORE key member [member ...] 不存在的成员为 nil
+type ZMScoreCommand struct {
+	dataset
+}
+
+func (c *ZMScoreCommand) Name() string {
+	return "ZMSCORE"
+}
+
+func (c *ZMScoreCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *ZMScoreCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("zmscore"))
+	}
+	res := make([]any, len(args)-2)
+	err := c.readZSet(args[1], func(z *kvstore.ZSet) {
+		for i, m := range args[2:] {
+			if s, ok := z.Score(m); ok {
+				res[i] = formatScore(s)
+			}
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(res)
+}
+
+// ZCardCommand ZCARD key
+type ZCardCommand struct {
+	dataset
+}
+
+func (c *ZCardCommand) Name() string {
+	return "ZCARD"
+}
+
+func (c *ZCardCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *ZCardCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 2 {
+		return rw.WriteError(errWrongArgs("zcard"))
+	}
+	n := 0
+	err := c.readZSet(args[1], func(z *kvstore.ZSet) {
+		n = z.Len()
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(int64(n))
+}
+
+// ZRankCommand ZRANK|ZREVRANK key member [WITHSCORE]
+// 成员不存在时返回 nil, WITHSCORE 时返回 [rank, score]
+type ZRankCommand struct {
+	dataset
+	name string
+	rev  bool
+}
+
+func (c *ZRankCommand) Name() string {
+	return c.name
+}
+
+func (c *ZRankCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *ZRankCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 || len(args) > 4 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	withScore := len(args) == 4
+	if withScore && !strings.EqualFold(args[3], "WITHSCORE") {
+		return rw.WriteError(errSyntax)
+	}
+	var reply any
+	if withScore {
+		reply = protocol.NullArray
+	}
+	err := c.readZSet(args[1], func(z *kvstore.ZSet) {
+		rank, ok := z.Rank(args[2], c.rev)
+		if !ok {
+			return
+		}
+		if !withScore {
+			reply = rank
+			return
+		}
+		score, _ := z.Score(args[2])
+		reply = []any{rank, formatScore(score)}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue(reply)
+}
+
+// ZRemCommand ZREM key member [member ...] 返回删除的成员数
+type ZRemCommand struct {
+	dataset
+}
+
+func (c *ZRemCommand) Name() string {
+	return "ZREM"
+}
+
+func (c *ZRemCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *ZRemCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *ZRemCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("zrem"))
+	}
+	key := args[1]
+	removed := 0
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		z, err := tx.ZSet(key)
+		if err != nil || z == nil {
+			return err
+		}
+		for _, m := range args[2:] {
+			if z.Remove(m) {
+				removed++
+			}
+		}
+		tx.ZSetChanged(key)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if removed > 0 {
+		c.changed(args)
+	}
+	return rw.WriteInteger(int64(removed))
+}
+
+// ZPopCommand ZPOPMIN|ZPOPMAX key [count] 返回 member, score 交替的平铺数组
+type ZPopCommand struct {
+	dataset
+	name string
+	max  bool
+}
+
+func (c *ZPopCommand) Name() string {
+	return c.name
+}
+
+func (c *ZPopCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *ZPopCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *ZPopCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	count := int64(1)
+	if len(args) == 3 {
+		n, ok := parseInt(args[2])
+		if !ok || n < 0 {
+			return rw.WriteError("ERR value is out of range, must be positive")
+		}
+		count = n
+	}
+	_, popped, err := zpop(c.store, args[1:2], c.max, count)
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if len(popped) > 0 {
+		c.changed(zpopPropagated(c.name, args[1], len(popped)))
+	}
+	return rw.WriteArray(zentries(popped, true))
+}
+
+// zpop 从第一个非空的有序集合弹出至多 count 个最小 (max 时最大) 的元素
+func zpop(store *kvstore.Store, keys []string, max bool, count int64) (key string, popped []kvstore.ZEntry, err error) {
+	err = store.Update(func(tx *kvstore.Tx) error {
+		for _, k := range keys {
+			z, err := tx.ZSet(k)
+			if err != nil {
+				return err
+			}
+			if z == nil {
+				continue
+			}
+			key = k
+			if count == 0 {
+				return nil
+			}
+			popped = z.Range(0, int(min(count, int64(z.Len())))-1, max)
+			for _, e := range popped {
+				z.Remove(e.Member)
+			}
+			tx.ZSetChanged(k)
+			return nil
+		}
+		return nil
+	})
+	return key, popped, err
+}
+
+// zpopPropagated 复制流中以 ZPOPMIN|ZPOPMAX key count 传播
+func zpopPropagated(name, key string, n int) []string {
+	name = strings.TrimPrefix(strings.ToUpper(name), "B")
+	return []string{name, key, strconv.Itoa(n)}
+}
+
+// BZPopCommand BZPOPMIN|BZPOPMAX key [key ...] timeout
+// 返回 [key, member, score], 超时返回 nil
+type BZPopCommand struct {
+	dataset
+	blocked *blocking.Registry
+	name    string
+	max     bool
+}
+
+func (c *BZPopCommand) Name() string {
+	return c.name
+}
+
+func (c *BZPopCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *BZPopCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: -2, Step: 1}
+}
+
+func (c *BZPopCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	w, err := c.Block(ctx, rw, args)
+	if w != nil {
+		return rw.WriteValue(w.Cancel())
+	}
+	return err
+}
+
+func (c *BZPopCommand) Block(ctx context.Context, rw protocol.ResponseWriter, args []string) (*blocking.Waiter, error) {
+	if len(args) < 3 {
+		return nil, rw.WriteError(errWrongArgs(c.name))
+	}
+	timeout, reply := parseTimeout(args[len(args)-1])
+	if reply != "" {
+		return nil, rw.WriteError(reply)
+	}
+	keys := args[1 : len(args)-1]
+	v, ok, err := c.pop(keys)
+	if err != nil {
+		return nil, rw.WriteError(err.Error())
+	}
+	if ok {
+		return nil, rw.WriteValue(v)
+	}
+	return c.blocked.Block(ctx, keys, timeout, protocol.NullArray, func(key string) (any, bool) {
+		v, ok, err := c.pop([]string{key})
+		if err != nil {
+			return nil, false
+		}
+		return v, ok
+	}), nil
+}
+
+func (c *BZPopCommand) pop(keys []string) (any, bool, error) {
+	key, popped, err := zpop(c.store, keys, c.max, 1)
+	if err != nil || len(popped) == 0 {
+		return nil, false, err
+	}
+	c.changed(zpopPropagated(c.name, key, 1))
+	return []any{key, popped[0].Member, formatScore(popped[0].Score)}, true, nil
+}
+
+// ZRandMemberCommand ZRANDMEMBER key [count [WITHSCORES]] count 的含义与 HRANDFIELD 相同
+type ZRandMemberCommand struct {
+	dataset
+}
+
+func (c *ZRandMemberCommand) Name() string {
+	return "ZRANDMEMBER"
+}
+
+func (c *ZRandMemberCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *ZRandMemberCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 2 || len(args) > 4 {
+		return rw.WriteError(errWrongArgs("zrandmember"))
+	}
+	count := int64(1)
+	withCount, withScores := len(args) >= 3, false
+	if withCount {
+		n, ok := parseInt(args[2])
+		if !ok {
+			return rw.WriteError(errNotInteger)
+		}
+		count = n
+	}
+	if len(args) == 4 {
+		if !strings.EqualFold(args[3], "WITHSCORES") {
+			return rw.WriteError(errSyntax)
+		}
+		withScores = true
+	}
+	var picked []kvstore.ZEntry
+	err := c.readZSet(args[1], func(z *kvstore.ZSet) {
+		for _, m := range randomPick(z.Members(), count) {
+			s, _ := z.Score(m)
+			picked = append(picked, kvstore.ZEntry{Member: m, Score: s})
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if !withCount {
+		if len(picked) == 0 {
+			return rw.WriteNull()
+		}
+		return rw.WriteBulkString(picked[0].Member)
+	}
+	return rw.WriteArray(zentries(picked, withScores))
+}
+
+// ZScanCommand ZSCAN key cursor [MATCH pattern] [COUNT count]
+type ZScanCommand struct {
+	dataset
+}
+
+func (c *ZScanCommand) Name() string {
+	return "ZSCAN"
+}
+
+func (c *ZScanCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *ZScanCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) < 3 {
+		return rw.WriteError(errWrongArgs("zscan"))
+	}
+	sa, reply := parseScan(args, false)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	next := 0
+	res := []string{}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		o, ok := tx.Lookup(args[1])
+		if !ok {
+			return nil
+		}
+		if o.Type != kvstore.TypeZSet {
+			return errors_r.ErrWrongType
+		}
+		z := o.Value.(*kvstore.ZSet)
+		var page []string
+		page, next = sa.page(z.Members(), o.Encoding != kvstore.EncSkiplist)
+		for _, m := range page {
+			if sa.matches(m) {
+				s, _ := z.Score(m)
+				res = append(res, m, formatScore(s))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteValue([]any{strconv.Itoa(next), res})
+}
@@ -0,0 +1,234 @@
+package command
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// ZSetOpCommand ZUNION|ZINTER|ZDIFF numkeys key [key ...] [WEIGHTS weight ...] [AGGREGATE SUM|MIN|MAX] [WITHSCORES]
+// ZUNIONSTORE|ZINTERSTORE|ZDIFFSTORE destination numkeys key [key ...] ... 覆盖目标键 返回结果的成员数
+// 输入可以是普通集合 (分值视为 1); ZDIFF 不支持 WEIGHTS/AGGREGATE
+type ZSetOpCommand struct {
+	dataset
+	name string
+	op   int
+	dest bool
+}
+
+// zsetOp 解析后的参数
+type zsetOp struct {
+	keys       []string
+	weights    []float64
+	aggregate  string
+	withScores bool
+}
+
+func (c *ZSetOpCommand) Name() string {
+	return c.name
+}
+
+func (c *ZSetOpCommand) Flags() Flag {
+	if c.dest {
+		return FlagWrite
+	}
+	return 0
+}
+
+func (c *ZSetOpCommand) FindKeys(args []string) []string {
+	p, _ := c.parse(args)
+	if c.dest && len(args) > 1 {
+		return append([]string{args[1]}, p.keys...)
+	}
+	return p.keys
+}
+
+func (c *ZSetOpCommand) parse(args []string) (zsetOp, string) {
+	p := zsetOp{aggregate: "SUM"}
+	first := 1
+	if c.dest {
+		first = 2
+	}
+	if len(args) < first+2 {
+		return p, errWrongArgs(c.name)
+	}
+	numkeys, ok := parseInt(args[first])
+	if !ok {
+		return p, errNotInteger
+	}
+	if numkeys < 1 {
+		return p, "ERR at least 1 input key is needed for '" + strings.ToLower(c.name) + "' command"
+	}
+	rest := args[first+1:]
+	if numkeys > int64(len(rest)) {
+		return p, errSyntax
+	}
+	p.keys, rest = rest[:numkeys], rest[numkeys:]
+	for i := 0; i < len(rest); i++ {
+		switch opt := strings.ToUpper(rest[i]); {
+		case opt == "WEIGHTS" && c.op != setDiff && i+len(p.keys) < len(rest):
+			p.weights = make([]float64, len(p.keys))
+			for j := range p.weights {
+				w, ok := parseFloat(rest[i+1+j])
+				if !ok {
+					return p, "ERR weight value is not a float"
+				}
+				p.weights[j] = w
+			}
+			i += len(p.keys)
+		case opt == "AGGREGATE" && c.op != setDiff && i+1 < len(rest):
+			p.aggregate = strings.ToUpper(rest[i+1])
+			if p.aggregate != "SUM" && p.aggregate != "MIN" && p.aggregate != "MAX" {
+				return p, errSyntax
+			}
+			i++
+		case opt == "WITHSCORES" && !c.dest:
+			p.withScores = true
+		default:
+			return p, errSyntax
+		}
+	}
+	return p, ""
+}
+
+func (c *ZSetOpCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	p, reply := c.parse(args)
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	var res []kvstore.ZEntry
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		inputs := make([]map[string]float64, len(p.keys))
+		for i, key := range p.keys {
+			in, err := zsetInput(tx, key)
+			if err != nil {
+				return err
+			}
+			inputs[i] = in
+		}
+		res = p.compute(c.op, inputs)
+		if !c.dest {
+			return nil
+		}
+		if len(res) == 0 {
+			tx.Delete(args[1])
+			return nil
+		}
+		tx.Put(args[1], kvstore.NewZSetObjectFrom(res), 0)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if c.dest {
+		c.changed(args)
+		return rw.WriteInteger(int64(len(res)))
+	}
+	return rw.WriteArray(zentries(res, p.withScores))
+}
+
+// zsetInput 读取一个输入键的成员与分值 普通集合的分值为 1 不存在的键为空
+func zsetInput(tx *kvstore.Tx, key string) (map[string]float64, error) {
+	o, ok := tx.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	in := make(map[string]float64)
+	switch o.Type {
+	case kvstore.TypeZSet:
+		for _, e := range o.Value.(*kvstore.ZSet).Entries() {
+			in[e.Member] = e.Score
+		}
+	case kvstore.TypeSet:
+		for _, m := range o.Value.(*kvstore.Set).Members() {
+			in[m] = 1
+		}
+	default:
+		return nil, errors_r.ErrWrongType
+	}
+	return in, nil
+}
+
+// weighted 第 i 个输入的加权分值 (inf * 0 视为 0)
+func (p zsetOp) weighted(i int, score float64) float64 {
+	if p.weights == nil {
+		return score
+	}
+	v := score * p.weights[i]
+	if math.IsNaN(v) {
+		return 0
+	}
+	return v
+}
+
+func (p zsetOp) combine(a, b float64) float64 {
+	switch p.aggregate {
+	case "MIN":
+		return min(a, b)
+	case "MAX":
+		return max(a, b)
+	}
+	// inf + -inf 视为 0
+	if v := a + b; !math.IsNaN(v) {
+		return v
+	}
+	return 0
+}
+
+// compute 计算集合运算 结果按 (分值, 成员) 升序
+func (p zsetOp) compute(op int, inputs []map[string]float64) []kvstore.ZEntry {
+	scores := make(map[string]float64)
+	switch op {
+	case setUnion:
+		for i, in := range inputs {
+			for m, s := range in {
+				s = p.weighted(i, s)
+				if cur, ok := scores[m]; ok {
+					s = p.combine(cur, s)
+				}
+				scores[m] = s
+			}
+		}
+	case setInter:
+	next:
+		for m, s := range inputs[0] {
+			s = p.weighted(0, s)
+			for i, in := range inputs[1:] {
+				other, ok := in[m]
+				if !ok {
+					continue next
+				}
+				s = p.combine(s, p.weighted(i+1, other))
+			}
+			scores[m] = s
+		}
+	case setDiff:
+		for m, s := range inputs[0] {
+			found := false
+			for _, in := range inputs[1:] {
+				if _, found = in[m]; found {
+					break
+				}
+			}
+			if !found {
+				scores[m] = s
+			}
+		}
+	}
+	res := make([]kvstore.ZEntry, 0, len(scores))
+	for m, s := range scores {
+		res = append(res, kvstore.ZEntry{Member: m, Score: s})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score < res[j].Score
+		}
+		return res[i].Member < res[j].Member
+	})
+	return res
+}
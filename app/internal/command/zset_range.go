@@ -0,0 +1,302 @@
+package command
+
+import (
+	"context"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// 有序集合区间的种类
+const (
+	zbyRank = iota
+	zbyScore
+	zbyLex
+)
+
+// parseScoreBound 解析分值区间端点 "1.5" "(1.5" "-inf" "+inf"
+func parseScoreBound(s string) (float64, bool, bool) {
+	ex := strings.HasPrefix(s, "(")
+	if ex {
+		s = s[1:]
+	}
+	f, ok := parseFloat(s)
+	return f, ex, ok
+}
+
+func parseScoreRange(min, max string) (kvstore.ScoreRange, string) {
+	var r kvstore.ScoreRange
+	var ok1, ok2 bool
+	r.Min, r.MinEx, ok1 = parseScoreBound(min)
+	r.Max, r.MaxEx, ok2 = parseScoreBound(max)
+	if !ok1 || !ok2 {
+		return r, "ERR min or max is not a float"
+	}
+	return r, ""
+}
+
+// parseLexBound 解析字典序区间端点 "[a" "(a" "-" "+"
+func parseLexBound(s string) (kvstore.LexBound, bool) {
+	switch {
+	case s == "-":
+		return kvstore.LexBound{Inf: -1}, true
+	case s == "+":
+		return kvstore.LexBound{Inf: 1}, true
+	case strings.HasPrefix(s, "["):
+		return kvstore.LexBound{Value: s[1:]}, true
+	case strings.HasPrefix(s, "("):
+		return kvstore.LexBound{Value: s[1:], Exclusive: true}, true
+	}
+	return kvstore.LexBound{}, false
+}
+
+func parseLexRange(min, max string) (kvstore.LexRange, string) {
+	var r kvstore.LexRange
+	var ok1, ok2 bool
+	r.Min, ok1 = parseLexBound(min)
+	r.Max, ok2 = parseLexBound(max)
+	if !ok1 || !ok2 {
+		return r, "ERR min or max not valid string range item"
+	}
+	return r, ""
+}
+
+// zrange 区间查询的参数 start/stop 为原始参数 (BYSCORE/BYLEX 且 rev 时已交换为 min, max)
+type zrange struct {
+	by          int
+	rev         bool
+	start, stop string
+	offset      int64
+	limit       int64 // < 0 不限
+}
+
+// parse 校验区间参数
+func (q *zrange) parse() (start, stop int64, sr kvstore.ScoreRange, lr kvstore.LexRange, reply string) {
+	switch q.by {
+	case zbyRank:
+		var ok1, ok2 bool
+		start, ok1 = parseInt(q.start)
+		stop, ok2 = parseInt(q.stop)
+		if !ok1 || !ok2 {
+			reply = errNotInteger
+		}
+	case zbyScore:
+		sr, reply = parseScoreRange(q.start, q.stop)
+	case zbyLex:
+		lr, reply = parseLexRange(q.start, q.stop)
+	}
+	return
+}
+
+// run 在有序集合上执行区间查询
+func (q *zrange) run(z *kvstore.ZSet, start, stop int64, sr kvstore.ScoreRange, lr kvstore.LexRange) []kvstore.ZEntry {
+	if q.offset < 0 {
+		return []kvstore.ZEntry{}
+	}
+	switch q.by {
+	case zbyScore:
+		return z.RangeByScore(sr, q.rev, int(q.offset), int(q.limit))
+	case zbyLex:
+		return z.RangeByLex(lr, q.rev, int(q.offset), int(q.limit))
+	}
+	n := int64(z.Len())
+	start, stop = max(start, -n-1), min(stop, n)
+	return z.Range(int(start), int(stop), q.rev)
+}
+
+// ZRangeCommand ZRANGE key start stop [BYSCORE|BYLEX] [REV] [LIMIT offset count] [WITHSCORES]
+// ZRANGESTORE dst src start stop [BYSCORE|BYLEX] [REV] [LIMIT offset count]
+// 以及旧的 ZREVRANGE/ZRANGEBYSCORE/ZREVRANGEBYSCORE/ZRANGEBYLEX/ZREVRANGEBYLEX
+type ZRangeCommand struct {
+	dataset
+	name    string
+	by      int
+	rev     bool
+	generic bool // 接受 BYSCORE/BYLEX/REV (ZRANGE/ZRANGESTORE)
+	dest    bool // 结果写入 args[1]
+}
+
+func (c *ZRangeCommand) Name() string {
+	return c.name
+}
+
+func (c *ZRangeCommand) Flags() Flag {
+	if c.dest {
+		return FlagWrite
+	}
+	return 0
+}
+
+func (c *ZRangeCommand) KeySpec() KeySpec {
+	if c.dest {
+		return KeySpec{First: 1, Last: 2, Step: 1}
+	}
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *ZRangeCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	src := 1
+	if c.dest {
+		src = 2
+	}
+	if len(args) < src+3 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	q := zrange{by: c.by, rev: c.rev, start: args[src+1], stop: args[src+2], limit: -1}
+	withScores, hasLimit := false, false
+	for i := src + 3; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); {
+		case opt == "WITHSCORES" && !c.dest:
+			withScores = true
+		case opt == "LIMIT" && i+2 < len(args):
+			var ok1, ok2 bool
+			q.offset, ok1 = parseInt(args[i+1])
+			q.limit, ok2 = parseInt(args[i+2])
+			if !ok1 || !ok2 {
+				return rw.WriteError(errNotInteger)
+			}
+			hasLimit = true
+			i += 2
+		case opt == "BYSCORE" && c.generic:
+			q.by = zbyScore
+		case opt == "BYLEX" && c.generic:
+			q.by = zbyLex
+		case opt == "REV" && c.generic:
+			q.rev = true
+		default:
+			return rw.WriteError(errSyntax)
+		}
+	}
+	if hasLimit && q.by == zbyRank {
+		return rw.WriteError("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+	if withScores && q.by == zbyLex {
+		return rw.WriteError("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+	// 按分值/字典序倒序时参数为 max min
+	if q.rev && q.by != zbyRank {
+		q.start, q.stop = q.stop, q.start
+	}
+	start, stop, sr, lr, reply := q.parse()
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+
+	entries := []kvstore.ZEntry{}
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		z, err := tx.ZSet(args[src])
+		if err != nil {
+			return err
+		}
+		if z != nil {
+			entries = q.run(z, start, stop, sr, lr)
+		}
+		if !c.dest {
+			return nil
+		}
+		if len(entries) == 0 {
+			tx.Delete(args[1])
+			return nil
+		}
+		tx.Put(args[1], kvstore.NewZSetObjectFrom(entries), 0)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if c.dest {
+		c.changed(args)
+		return rw.WriteInteger(int64(len(entries)))
+	}
+	return rw.WriteArray(zentries(entries, withScores))
+}
+
+// ZRemRangeCommand ZREMRANGEBYRANK|ZREMRANGEBYSCORE|ZREMRANGEBYLEX key min max 返回删除的成员数
+type ZRemRangeCommand struct {
+	dataset
+	name string
+	by   int
+}
+
+func (c *ZRemRangeCommand) Name() string {
+	return c.name
+}
+
+func (c *ZRemRangeCommand) Flags() Flag {
+	return FlagWrite
+}
+
+func (c *ZRemRangeCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *ZRemRangeCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	q := zrange{by: c.by, start: args[2], stop: args[3], limit: -1}
+	start, stop, sr, lr, reply := q.parse()
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	key := args[1]
+	removed := 0
+	err := c.store.Update(func(tx *kvstore.Tx) error {
+		z, err := tx.ZSet(key)
+		if err != nil || z == nil {
+			return err
+		}
+		for _, e := range q.run(z, start, stop, sr, lr) {
+			z.Remove(e.Member)
+			removed++
+		}
+		tx.ZSetChanged(key)
+		return nil
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	if removed > 0 {
+		c.changed(args)
+	}
+	return rw.WriteInteger(int64(removed))
+}
+
+// ZCountCommand ZCOUNT|ZLEXCOUNT key min max
+type ZCountCommand struct {
+	dataset
+	name string
+	by   int
+}
+
+func (c *ZCountCommand) Name() string {
+	return c.name
+}
+
+func (c *ZCountCommand) KeySpec() KeySpec {
+	return KeySpec{First: 1, Last: 1, Step: 1}
+}
+
+func (c *ZCountCommand) Execute(ctx context.Context, rw protocol.ResponseWriter, args []string) error {
+	if len(args) != 4 {
+		return rw.WriteError(errWrongArgs(c.name))
+	}
+	q := zrange{by: c.by, start: args[2], stop: args[3]}
+	_, _, sr, lr, reply := q.parse()
+	if reply != "" {
+		return rw.WriteError(reply)
+	}
+	n := 0
+	err := c.readZSet(args[1], func(z *kvstore.ZSet) {
+		if c.by == zbyLex {
+			n = z.LexCount(lr)
+		} else {
+			n = z.Count(sr)
+		}
+	})
+	if err != nil {
+		return rw.WriteError(err.Error())
+	}
+	return rw.WriteInteger(int64(n))
+}
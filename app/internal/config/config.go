@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/pflag"
@@ -17,6 +19,39 @@ type ServerConfig struct {
 	Port      string        `mapstructure:"port"`
 	Role      string        `mapstructure:"role"`
 	ReplicaOf ReplicaConfig `mapstructure:"replicaof"`
+
+	ReplicaReadOnly bool `mapstructure:"replica-read-only"` // 从节点拒绝客户端写命令
+
+	MinReplicasToWrite int `mapstructure:"min-replicas-to-write"` // 健康副本少于该值时拒绝写入, 0 表示关闭
+	MinReplicasMaxLag  int `mapstructure:"min-replicas-max-lag"`  // 健康副本的最大 ACK 延迟(秒)
+
+	ClientOutputBufferLimit string `mapstructure:"client-output-buffer-limit"` // 如 "replica 256mb 64mb 60"
+
+	ReplicaPriority int `mapstructure:"replica-priority"` // 哨兵故障转移时选择副本的优先级 越小越优先, 0 表示永不晋升
+
+	Sentinel SentinelConfig `mapstructure:"sentinel"` // role 为 sentinel 时生效
+
+	ClusterEnabled             bool   `mapstructure:"cluster-enabled"`               // 集群模式 键按哈希槽分布在多个节点
+	ClusterRequireFullCoverage bool   `mapstructure:"cluster-require-full-coverage"` // 存在未分配的槽时拒绝所有键命令
+	ClusterConfigFile          string `mapstructure:"cluster-config-file"`           // 节点表持久化文件 位于 dir 下
+	ClusterNodeTimeout         int    `mapstructure:"cluster-node-timeout"`          // 节点不可达多久(毫秒)后判定 PFAIL
+}
+
+// SentinelConfig 哨兵模式配置
+type SentinelConfig struct {
+	Monitors       []MonitorConfig `mapstructure:"monitors"`
+	KnownSentinels []string        `mapstructure:"known_sentinels"` // 其他哨兵地址 host:port
+}
+
+// MonitorConfig 一个被监控的主节点 (sentinel monitor <name> <host> <port> <quorum>)
+type MonitorConfig struct {
+	Name              string `mapstructure:"name"`
+	Host              string `mapstructure:"host"`
+	Port              string `mapstructure:"port"`
+	Quorum            int    `mapstructure:"quorum"`                  // 判定客观下线所需的哨兵数
+	DownAfterMs       int    `mapstructure:"down_after_milliseconds"` // 无有效回复多久后判定主观下线
+	FailoverTimeoutMs int    `mapstructure:"failover_timeout"`
+	ParallelSyncs     int    `mapstructure:"parallel_syncs"` // 故障转移后同时重新同步的副本数
 }
 
 type ReplicaConfig struct {
@@ -36,6 +71,15 @@ func LoadConfig() (*ServerConfig, error) {
 	viper.SetDefault("role", "master")
 	viper.SetDefault("replicaof.master_host", "")
 	viper.SetDefault("replicaof.master_port", "")
+	viper.SetDefault("replica-read-only", true)
+	viper.SetDefault("min-replicas-to-write", 0)
+	viper.SetDefault("min-replicas-max-lag", 10)
+	viper.SetDefault("client-output-buffer-limit", "replica 256mb 64mb 60")
+	viper.SetDefault("replica-priority", 100)
+	viper.SetDefault("cluster-enabled", false)
+	viper.SetDefault("cluster-require-full-coverage", true)
+	viper.SetDefault("cluster-config-file", "nodes.conf")
+	viper.SetDefault("cluster-node-timeout", 15000)
 
 	// 配置文件查找路径
 	viper.AddConfigPath(".")                // main.go 目录
@@ -72,8 +116,21 @@ func LoadConfig() (*ServerConfig, error) {
 	pflag.String("dir", "", "持久化数据存储目录")
 	pflag.String("dbfilename", "", "数据库文件名")
 	pflag.StringP("port", "p", "", "绑定端口号")
-	pflag.String("role", "", "角色：master/slave")
+	pflag.String("role", "", "角色：master/slave/sentinel")
 	pflag.String("replicaof", "", "配置为该地址的副本: '<MASTER_HOST> <MASTER_PORT>'")
+	pflag.Bool("replica-read-only", true, "从节点是否拒绝客户端写命令")
+	pflag.Int("min-replicas-to-write", 0, "健康副本少于该值时拒绝写入")
+	pflag.Int("min-replicas-max-lag", 10, "健康副本的最大 ACK 延迟(秒)")
+	pflag.String("client-output-buffer-limit", "replica 256mb 64mb 60", "输出缓冲区限制: '<class> <hard> <soft> <soft-seconds>'")
+	pflag.Int("replica-priority", 100, "哨兵选择晋升副本的优先级 越小越优先, 0 表示永不晋升")
+	pflag.String("sentinel-monitor", "", "哨兵监控的主节点: '<name> <host> <port> <quorum>'")
+	pflag.StringSlice("sentinel-known-sentinels", nil, "其他哨兵地址 host:port")
+	pflag.Int("sentinel-down-after-milliseconds", 30000, "主观下线判定时间(毫秒)")
+	pflag.Int("sentinel-failover-timeout", 180000, "故障转移超时(毫秒)")
+	pflag.Bool("cluster-enabled", false, "启用集群模式")
+	pflag.Bool("cluster-require-full-coverage", true, "存在未分配的槽时集群不可用")
+	pflag.String("cluster-config-file", "nodes.conf", "集群节点表文件名")
+	pflag.Int("cluster-node-timeout", 15000, "节点不可达判定时间(毫秒)")
 	// 解析参数
 	pflag.Parse()
 
@@ -112,8 +169,67 @@ func LoadConfig() (*ServerConfig, error) {
 			MasterHost: viper.GetString("replicaof.master_host"),
 			MasterPort: viper.GetString("replicaof.master_port"),
 		},
+		ReplicaReadOnly:    viper.GetBool("replica-read-only"),
+		MinReplicasToWrite: viper.GetInt("min-replicas-to-write"),
+		MinReplicasMaxLag:  viper.GetInt("min-replicas-max-lag"),
+
+		ClientOutputBufferLimit: viper.GetString("client-output-buffer-limit"),
+		ReplicaPriority:         viper.GetInt("replica-priority"),
+
+		ClusterEnabled:             viper.GetBool("cluster-enabled"),
+		ClusterRequireFullCoverage: viper.GetBool("cluster-require-full-coverage"),
+		ClusterConfigFile:          viper.GetString("cluster-config-file"),
+		ClusterNodeTimeout:         viper.GetInt("cluster-node-timeout"),
+	}
+
+	if err := loadSentinelConfig(cfg); err != nil {
+		return nil, err
 	}
 
 	cfg.Fn = filepath.Join(cfg.Dir, cfg.Dbfilename)
 	return cfg, nil
 }
+
+// loadSentinelConfig 合并配置文件中的 sentinel.monitors 与 --sentinel-monitor 参数 并填充默认值
+func loadSentinelConfig(cfg *ServerConfig) error {
+	if err := viper.UnmarshalKey("sentinel", &cfg.Sentinel); err != nil {
+		return err
+	}
+	if known := viper.GetStringSlice("sentinel-known-sentinels"); len(known) > 0 {
+		cfg.Sentinel.KnownSentinels = known
+	}
+	if monitor := viper.GetString("sentinel-monitor"); monitor != "" {
+		parts := strings.Fields(monitor)
+		if len(parts) != 4 {
+			return fmt.Errorf("--sentinel-monitor 格式: <name> <host> <port> <quorum>")
+		}
+		quorum, err := strconv.Atoi(parts[3])
+		if err != nil || quorum <= 0 {
+			return fmt.Errorf("invalid sentinel quorum: %s", parts[3])
+		}
+		cfg.Sentinel.Monitors = append(cfg.Sentinel.Monitors, MonitorConfig{
+			Name:              parts[0],
+			Host:              parts[1],
+			Port:              parts[2],
+			Quorum:            quorum,
+			DownAfterMs:       viper.GetInt("sentinel-down-after-milliseconds"),
+			FailoverTimeoutMs: viper.GetInt("sentinel-failover-timeout"),
+		})
+	}
+	for i := range cfg.Sentinel.Monitors {
+		m := &cfg.Sentinel.Monitors[i]
+		if m.Quorum <= 0 {
+			m.Quorum = 1
+		}
+		if m.DownAfterMs <= 0 {
+			m.DownAfterMs = 30000
+		}
+		if m.FailoverTimeoutMs <= 0 {
+			m.FailoverTimeoutMs = 180000
+		}
+		if m.ParallelSyncs <= 0 {
+			m.ParallelSyncs = 1
+		}
+	}
+	return nil
+}
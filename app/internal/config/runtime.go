@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// field 按 mapstructure 标签(如 "replica-read-only")或字段名(如 "Dir")查找配置项, 不区分大小写
+func (c *ServerConfig) field(name string) (reflect.Value, bool) {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type.Kind() == reflect.Struct {
+			continue
+		}
+		if strings.EqualFold(f.Tag.Get("mapstructure"), name) || strings.EqualFold(f.Name, name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// Get CONFIG GET 使用 布尔值按 Redis 习惯输出 yes/no
+func (c *ServerConfig) Get(name string) (string, bool) {
+	val, ok := c.field(name)
+	if !ok {
+		return "", false
+	}
+	switch val.Kind() {
+	case reflect.Bool:
+		if val.Bool() {
+			return "yes", true
+		}
+		return "no", true
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(val.Int(), 10), true
+	default:
+		return val.String(), true
+	}
+}
+
+// Set CONFIG SET 使用
+func (c *ServerConfig) Set(name, value string) error {
+	val, ok := c.field(name)
+	if !ok {
+		return fmt.Errorf("Unknown option or number of arguments for CONFIG SET - '%s'", name)
+	}
+	switch val.Kind() {
+	case reflect.Bool:
+		switch strings.ToLower(value) {
+		case "yes":
+			val.SetBool(true)
+		case "no":
+			val.SetBool(false)
+		default:
+			return fmt.Errorf("argument must be 'yes' or 'no'")
+		}
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("argument couldn't be parsed into an integer")
+		}
+		val.SetInt(n)
+	case reflect.String:
+		val.SetString(value)
+	default:
+		return fmt.Errorf("Unsupported CONFIG parameter: %s", name)
+	}
+	return nil
+}
+
+// ReplicaOutputBufferLimit 解析 client-output-buffer-limit 中 replica(slave) 类的限制
+// 格式同 Redis: "<class> <hard> <soft> <soft-seconds> ..." 未配置 replica 类时返回 0 (不限制)
+func (c *ServerConfig) ReplicaOutputBufferLimit() (hard, soft int64, softSeconds int, err error) {
+	fields := strings.Fields(c.ClientOutputBufferLimit)
+	if len(fields)%4 != 0 {
+		return 0, 0, 0, fmt.Errorf("wrong number of arguments in client-output-buffer-limit")
+	}
+	for i := 0; i < len(fields); i += 4 {
+		class := strings.ToLower(fields[i])
+		if class != "replica" && class != "slave" {
+			continue
+		}
+		if hard, err = ParseMemory(fields[i+1]); err != nil {
+			return 0, 0, 0, err
+		}
+		if soft, err = ParseMemory(fields[i+2]); err != nil {
+			return 0, 0, 0, err
+		}
+		if softSeconds, err = strconv.Atoi(fields[i+3]); err != nil {
+			return 0, 0, 0, err
+		}
+		return hard, soft, softSeconds, nil
+	}
+	return 0, 0, 0, nil
+}
+
+// ParseMemory 解析内存大小 支持 b/k/kb/m/mb/g/gb 单位 (k=1000, kb=1024)
+func ParseMemory(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mul    int64
+	}{
+		{"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10},
+		{"g", 1000 * 1000 * 1000}, {"m", 1000 * 1000}, {"k", 1000}, {"b", 1},
+	}
+	lower := strings.ToLower(s)
+	for _, u := range units {
+		if strings.HasSuffix(lower, u.suffix) {
+			n, err := strconv.ParseInt(strings.TrimSuffix(lower, u.suffix), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid memory value '%s'", s)
+			}
+			return n * u.mul, nil
+		}
+	}
+	n, err := strconv.ParseInt(lower, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory value '%s'", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestReplicaOutputBufferLimit(t *testing.T) {
+	c := &ServerConfig{ClientOutputBufferLimit: "normal 0 0 0 replica 256mb 64mb 60 pubsub 32mb 8mb 60"}
+	hard, soft, seconds, err := c.ReplicaOutputBufferLimit()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(256<<20), hard)
+	assert.Equal(t, int64(64<<20), soft)
+	assert.Equal(t, 60, seconds)
+
+	// slave 为旧名称 k 为 1000
+	c.ClientOutputBufferLimit = "slave 2k 1kb 5"
+	hard, soft, seconds, err = c.ReplicaOutputBufferLimit()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(2000), hard)
+	assert.Equal(t, int64(1024), soft)
+	assert.Equal(t, 5, seconds)
+
+	// 未配置 replica 类时不限制
+	for _, s := range []string{"", "normal 0 0 0"} {
+		c.ClientOutputBufferLimit = s
+		hard, soft, seconds, err = c.ReplicaOutputBufferLimit()
+		assert.Equal(t, nil, err)
+		assert.Equal(t, int64(0), hard+soft+int64(seconds))
+	}
+
+	for _, s := range []string{"replica 1mb 0", "replica 1xb 0 0", "replica 1mb 1mb abc"} {
+		c.ClientOutputBufferLimit = s
+		_, _, _, err = c.ReplicaOutputBufferLimit()
+		assert.NotEqual(t, nil, err)
+	}
+}
@@ -12,6 +12,12 @@ type ResponseWriter interface {
 	WriteBulkString(str string) error
 	// 写入数组响应
 	WriteArray(str []string) error
+	// 写入错误响应 如 "ERR syntax error"
+	WriteError(str string) error
+	// 写入整数响应
+	WriteInteger(n int64) error
+	// 写入任意值 (嵌套数组等) 编码规则见 ValueFmt
+	WriteValue(v any) error
 	// 写入空批量字符串 (-1)
 	WriteNull() error
 	// 刷新缓冲区
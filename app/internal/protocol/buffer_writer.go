@@ -0,0 +1,70 @@
+package protocol
+
+import (
+	"bytes"
+	"net"
+)
+
+// BufferResponseWriter 将回复编码后保存在内存中 用于 EXEC 收集事务中每条命令的回复
+type BufferResponseWriter struct {
+	conn net.Conn
+	buf  bytes.Buffer
+}
+
+// NewBufferResponseWriter 创建一个保留连接、回复写入内存的 ResponseWriter
+func NewBufferResponseWriter(conn net.Conn) *BufferResponseWriter {
+	return &BufferResponseWriter{conn: conn}
+}
+
+func (w *BufferResponseWriter) Conn() net.Conn {
+	return w.conn
+}
+
+func (w *BufferResponseWriter) WriteSimpleString(str string) error {
+	w.buf.Write(SimpleStringFmt(str))
+	return nil
+}
+
+func (w *BufferResponseWriter) WriteBulkString(str string) error {
+	w.buf.Write(BulkStringFmt(str))
+	return nil
+}
+
+func (w *BufferResponseWriter) WriteArray(str []string) error {
+	w.buf.Write(ArrayFmt(str))
+	return nil
+}
+
+func (w *BufferResponseWriter) WriteError(str string) error {
+	w.buf.Write(ErrorFmt(str))
+	return nil
+}
+
+func (w *BufferResponseWriter) WriteInteger(n int64) error {
+	w.buf.Write(IntegerFmt(n))
+	return nil
+}
+
+func (w *BufferResponseWriter) WriteValue(v any) error {
+	w.buf.Write(ValueFmt(v))
+	return nil
+}
+
+func (w *BufferResponseWriter) WriteNull() error {
+	w.buf.Write(NullFmt())
+	return nil
+}
+
+func (w *BufferResponseWriter) Flush() error { return nil }
+
+// Take 返回已写入的回复并清空缓冲区
+func (w *BufferResponseWriter) Take() Raw {
+	reply := Raw(bytes.Clone(w.buf.Bytes()))
+	w.buf.Reset()
+	return reply
+}
+
+// Len 缓冲区中尚未取走的字节数
+func (w *BufferResponseWriter) Len() int {
+	return w.buf.Len()
+}
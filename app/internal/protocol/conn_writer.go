@@ -40,6 +40,21 @@ func (w *connResponseWriter) WriteArray(str []string) error {
 	return err
 }
 
+func (w *connResponseWriter) WriteError(str string) error {
+	_, err := w.conn.Write(ErrorFmt(str))
+	return err
+}
+
+func (w *connResponseWriter) WriteInteger(n int64) error {
+	_, err := w.conn.Write(IntegerFmt(n))
+	return err
+}
+
+func (w *connResponseWriter) WriteValue(v any) error {
+	_, err := w.conn.Write(ValueFmt(v))
+	return err
+}
+
 func (w *connResponseWriter) WriteNull() error {
 	_, err := w.conn.Write(NullFmt())
 	return err
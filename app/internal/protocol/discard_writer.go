@@ -0,0 +1,26 @@
+package protocol
+
+import "net"
+
+// discardResponseWriter 丢弃所有回复 用于处理主节点复制流 (主从链路上从节点不回复普通命令)
+type discardResponseWriter struct {
+	conn net.Conn
+}
+
+// NewDiscardResponseWriter 创建一个只保留连接、丢弃所有写入的 ResponseWriter
+func NewDiscardResponseWriter(conn net.Conn) ResponseWriter {
+	return &discardResponseWriter{conn: conn}
+}
+
+func (w *discardResponseWriter) Conn() net.Conn {
+	return w.conn
+}
+
+func (w *discardResponseWriter) WriteSimpleString(str string) error { return nil }
+func (w *discardResponseWriter) WriteBulkString(str string) error   { return nil }
+func (w *discardResponseWriter) WriteArray(str []string) error      { return nil }
+func (w *discardResponseWriter) WriteError(str string) error        { return nil }
+func (w *discardResponseWriter) WriteInteger(n int64) error         { return nil }
+func (w *discardResponseWriter) WriteValue(v any) error             { return nil }
+func (w *discardResponseWriter) WriteNull() error                   { return nil }
+func (w *discardResponseWriter) Flush() error                       { return nil }
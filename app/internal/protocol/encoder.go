@@ -41,7 +41,9 @@ func ArrayFmt(str []string) []byte {
 	// ByteOutput := []byte(Output)
 	// return ByteOutput
 	var builder strings.Builder
-	builder.Grow(len(str)*len(str[0]) + 16)
+	if len(str) > 0 {
+		builder.Grow(len(str)*len(str[0]) + 16)
+	}
 	builder.WriteString("*")
 	builder.WriteString(strconv.Itoa(len(str)))
 	builder.WriteString("\r\n")
@@ -55,6 +57,21 @@ func ArrayFmt(str []string) []byte {
 	return []byte(builder.String())
 }
 
+// RESP 错误编码
+func ErrorFmt(str string) []byte {
+	var builder strings.Builder
+	builder.Grow(len(str) + 16)
+	builder.WriteString("-")
+	builder.WriteString(str)
+	builder.WriteString("\r\n")
+	return []byte(builder.String())
+}
+
+// RESP 整数编码
+func IntegerFmt(n int64) []byte {
+	return []byte(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
 func NullFmt() []byte {
 	return []byte("$-1\r\n")
 }
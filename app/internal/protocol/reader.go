@@ -0,0 +1,200 @@
+// protocol/reader.go
+package protocol
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// Reader 基于 bufio 的 RESP 流式解析器
+// 与 ParseRequest 不同，Reader 按协议边界读取，支持同一次 Read 中到达的多条命令（管道 / 复制流）
+type Reader struct {
+	rd     *bufio.Reader
+	last   int    // 上一条消息占用的字节数 复制偏移量按此累加
+	record bool   // 是否保留上一条消息的原始字节
+	raw    []byte // 上一条消息的原始字节 (record 为 true 时有效)
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{rd: bufio.NewReader(r)}
+}
+
+// Record 开启后保留每条消息的原始字节 用于原样转发复制流
+func (r *Reader) Record(on bool) {
+	r.record = on
+}
+
+// Raw 返回上一条消息的原始字节 需先开启 Record; 仅在下一次读取前有效
+func (r *Reader) Raw() []byte {
+	return r.raw
+}
+
+// LastSize 返回上一次 ReadRequest / ReadLine 消费的原始字节数
+func (r *Reader) LastSize() int {
+	return r.last
+}
+
+// ReadRequest 读取一条完整命令 支持 RESP 数组与内联命令(+PING / PING)
+func (r *Reader) ReadRequest() (string, []string, error) {
+	r.reset()
+	line, err := r.readLine()
+	// 跳过空行 (心跳换行符)
+	for err == nil && (line == "" || line == "*0") {
+		line, err = r.readLine()
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	switch line[0] {
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count < 0 {
+			return "", nil, errors_r.ErrInvalidRequest
+		}
+		args := make([]string, count)
+		for i := 0; i < count; i++ {
+			bulk, err := r.readLine()
+			if err != nil {
+				return "", nil, err
+			}
+			if len(bulk) == 0 || bulk[0] != '$' {
+				return "", nil, errors_r.ErrInvalidRequest
+			}
+			n, err := strconv.Atoi(bulk[1:])
+			if err != nil || n < 0 {
+				return "", nil, errors_r.ErrInvalidRequest
+			}
+			buf := make([]byte, n+2)
+			if _, err := io.ReadFull(r.rd, buf); err != nil {
+				return "", nil, err
+			}
+			r.consume(buf)
+			args[i] = string(buf[:n])
+		}
+		return args[0], args, nil
+	case '+':
+		// usually "+PING"
+		cmd := strings.TrimSpace(line[1:])
+		return cmd, []string{cmd}, nil
+	default:
+		// 内联命令 如 nc 直接输入 "PING"
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			return "", nil, errors_r.ErrInvalidRequest
+		}
+		return args[0], args, nil
+	}
+}
+
+// ReadLine 读取一行回复(+OK / -ERR / :1) 去掉结尾的 \r\n
+func (r *Reader) ReadLine() (string, error) {
+	r.reset()
+	return r.readLine()
+}
+
+// ReadBulkPayload 读取 "$<len>\r\n<payload>" 格式的载荷 (RDB 传输不带结尾 \r\n)
+func (r *Reader) ReadBulkPayload() ([]byte, error) {
+	r.reset()
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '$' {
+		return nil, errors_r.ErrInvalidMessage
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return nil, errors_r.ErrInvalidMessage
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r.rd, payload); err != nil {
+		return nil, err
+	}
+	r.consume(payload)
+	return payload, nil
+}
+
+// ReadValue 读取一个完整的 RESP 回复 (客户端侧使用)
+// 返回 string(简单/批量字符串) int64 []any nil; 错误回复以 ErrorReply 作为 error 返回
+func (r *Reader) ReadValue() (any, error) {
+	r.reset()
+	return r.readValue()
+}
+
+func (r *Reader) readValue() (any, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, errors_r.ErrInvalidMessage
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, ErrorReply(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, errors_r.ErrInvalidMessage
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r.rd, buf); err != nil {
+			return nil, err
+		}
+		r.consume(buf)
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, errors_r.ErrInvalidMessage
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			item, err := r.readValue()
+			if e, ok := err.(ErrorReply); ok {
+				// 数组中的错误回复 (如 EXEC 的结果) 作为元素保留
+				item = e
+			} else if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, errors_r.ErrInvalidMessage
+}
+
+func (r *Reader) readLine() (string, error) {
+	line, err := r.rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	r.consume([]byte(line))
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (r *Reader) reset() {
+	r.last = 0
+	r.raw = r.raw[:0]
+}
+
+func (r *Reader) consume(p []byte) {
+	r.last += len(p)
+	if r.record {
+		r.raw = append(r.raw, p...)
+	}
+}
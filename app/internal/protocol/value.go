@@ -0,0 +1,71 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SimpleString 以 +str 形式编码
+type SimpleString string
+
+// ErrorReply 以 -str 形式编码; 也是 ReadValue 读到错误回复时返回的 error
+type ErrorReply string
+
+func (e ErrorReply) Error() string {
+	return string(e)
+}
+
+// Raw 已编码的 RESP 数据 原样写出 (EXEC 汇总事务中每条命令的回复)
+type Raw []byte
+
+type nullArray struct{}
+
+// NullArray 空数组 *-1 (如阻塞命令超时)
+var NullArray = nullArray{}
+
+// ValueFmt 按类型编码任意值:
+// string/[]byte -> 批量字符串, int/int64 -> 整数, nil -> $-1, []string/[]any -> 数组(可嵌套),
+// SimpleString -> +, ErrorReply -> -, NullArray -> *-1, float64 -> 批量字符串, Raw -> 原样写出
+func ValueFmt(v any) []byte {
+	var builder strings.Builder
+	writeValue(&builder, v)
+	return []byte(builder.String())
+}
+
+func writeValue(b *strings.Builder, v any) {
+	switch val := v.(type) {
+	case nil:
+		b.WriteString("$-1\r\n")
+	case nullArray:
+		b.WriteString("*-1\r\n")
+	case SimpleString:
+		b.WriteString("+" + string(val) + "\r\n")
+	case ErrorReply:
+		b.WriteString("-" + string(val) + "\r\n")
+	case string:
+		b.WriteString("$" + strconv.Itoa(len(val)) + "\r\n" + val + "\r\n")
+	case Raw:
+		b.Write(val)
+	case []byte:
+		b.WriteString("$" + strconv.Itoa(len(val)) + "\r\n" + string(val) + "\r\n")
+	case int:
+		b.WriteString(":" + strconv.Itoa(val) + "\r\n")
+	case int64:
+		b.WriteString(":" + strconv.FormatInt(val, 10) + "\r\n")
+	case float64:
+		writeValue(b, strconv.FormatFloat(val, 'f', -1, 64))
+	case []string:
+		b.WriteString("*" + strconv.Itoa(len(val)) + "\r\n")
+		for _, s := range val {
+			writeValue(b, s)
+		}
+	case []any:
+		b.WriteString("*" + strconv.Itoa(len(val)) + "\r\n")
+		for _, item := range val {
+			writeValue(b, item)
+		}
+	default:
+		writeValue(b, fmt.Sprint(val))
+	}
+}
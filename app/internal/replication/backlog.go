@@ -0,0 +1,76 @@
+package replication
+
+// Backlog 复制积压缓冲区 (环形)
+// 保存最近写入的复制流 断线重连的从节点可从中继续同步 (PSYNC 部分重同步)
+type Backlog struct {
+	buf     []byte
+	idx     int   // 下一次写入的位置
+	histlen int   // 已保存的有效字节数
+	offset  int64 // 缓冲区中第一个字节对应的复制偏移量
+}
+
+func NewBacklog(size int) *Backlog {
+	return &Backlog{buf: make([]byte, size), offset: 1}
+}
+
+func (b *Backlog) Size() int {
+	return len(b.buf)
+}
+
+func (b *Backlog) HistLen() int {
+	return b.histlen
+}
+
+func (b *Backlog) FirstByteOffset() int64 {
+	return b.offset
+}
+
+// Reset 清空缓冲区 下一个写入的字节对应偏移量 start
+func (b *Backlog) Reset(start int64) {
+	b.idx = 0
+	b.histlen = 0
+	b.offset = start
+}
+
+// Write 追加复制流 超出容量时覆盖最旧的数据
+func (b *Backlog) Write(p []byte) {
+	size := len(b.buf)
+	if size == 0 {
+		return
+	}
+	for len(p) > 0 {
+		n := copy(b.buf[b.idx:], p)
+		p = p[n:]
+		b.idx = (b.idx + n) % size
+		b.histlen += n
+	}
+	if b.histlen > size {
+		b.offset += int64(b.histlen - size)
+		b.histlen = size
+	}
+}
+
+// From 返回从偏移量 off 开始直到末尾的数据 off 不在缓冲区内时返回 false
+func (b *Backlog) From(off int64) ([]byte, bool) {
+	end := b.offset + int64(b.histlen)
+	if off < b.offset || off > end {
+		return nil, false
+	}
+	skip := int(off - b.offset)
+	n := b.histlen - skip
+	out := make([]byte, 0, n)
+	if n == 0 {
+		return out, true
+	}
+	size := len(b.buf)
+	// 最旧数据的位置
+	start := (b.idx - b.histlen + size) % size
+	pos := (start + skip) % size
+	for n > 0 {
+		chunk := min(n, size-pos)
+		out = append(out, b.buf[pos:pos+chunk]...)
+		n -= chunk
+		pos = (pos + chunk) % size
+	}
+	return out, true
+}
@@ -0,0 +1,275 @@
+package replication
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+)
+
+// 链路状态
+const (
+	LinkConnecting = "connecting"
+	LinkSync       = "sync"
+	LinkConnected  = "connected"
+)
+
+// Applier 由服务端实现 链路收到的数据通过它作用到本地数据集
+type Applier interface {
+	// 执行来自主节点的命令 不受只读限制
+	// raw 为命令的原始字节, 执行后原样写入积压缓冲区并转发给下游副本 (链式复制)
+	ProcessMasterCommand(rw protocol.ResponseWriter, cmd string, args []string, raw []byte) error
+	LoadRDB(payload []byte) error
+	// 复制历史发生变化时断开下游副本 让其重新 PSYNC
+	DisconnectReplicas()
+}
+
+// Link 从节点到主节点的复制链路
+// 负责握手、PSYNC、接收 RDB 以及持续应用复制流, 断线后自动重连并尝试部分重同步
+type Link struct {
+	Host       string
+	Port       string
+	listenPort string
+	state      *State
+	node       Applier
+	failover   chan<- error // 非nil时首次 PSYNC 携带 FAILOVER, 结果通过它返回 (FAILOVER 命令)
+
+	mu     sync.Mutex
+	conn   net.Conn
+	status string
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func NewLink(host, port, listenPort string, state *State, node Applier) *Link {
+	return &Link{
+		Host:       host,
+		Port:       port,
+		listenPort: listenPort,
+		state:      state,
+		node:       node,
+		status:     LinkConnecting,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// SetFailover 首次握手以 PSYNC <replid> <offset> FAILOVER 请求目标晋升为主节点 需在 Start 之前调用
+func (l *Link) SetFailover(result chan<- error) {
+	l.failover = result
+}
+
+// reportFailover 返回 PSYNC FAILOVER 的结果 只报告一次, 之后的重连使用普通 PSYNC
+func (l *Link) reportFailover(err error) {
+	if l.failover != nil {
+		l.failover <- err
+		l.failover = nil
+	}
+}
+
+func (l *Link) Start() {
+	go l.run()
+}
+
+// Stop 断开链路并等待后台协程退出
+func (l *Link) Stop() {
+	close(l.stop)
+	l.mu.Lock()
+	if l.conn != nil {
+		l.conn.Close()
+	}
+	l.mu.Unlock()
+	<-l.done
+}
+
+func (l *Link) Status() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.status
+}
+
+func (l *Link) setStatus(status string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.status = status
+}
+
+func (l *Link) run() {
+	defer close(l.done)
+	for {
+		if err := l.connectAndSync(); err != nil {
+			log.Printf("Master link %s:%s error: %s", l.Host, l.Port, err)
+			l.reportFailover(err)
+		}
+		l.setStatus(LinkConnecting)
+		select {
+		case <-l.stop:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (l *Link) connectAndSync() error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(l.Host, l.Port), 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	l.mu.Lock()
+	select {
+	case <-l.stop:
+		l.mu.Unlock()
+		return nil
+	default:
+	}
+	l.conn = conn
+	l.status = LinkSync
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.conn = nil
+		l.mu.Unlock()
+	}()
+
+	rd := protocol.NewReader(conn)
+	if err := l.handshake(conn, rd); err != nil {
+		return err
+	}
+	l.setStatus(LinkConnected)
+	log.Printf("握手完成，开始处理主节点 %s:%s 复制流", l.Host, l.Port)
+	return l.stream(conn, rd)
+}
+
+// 启动同步 PING -> REPLCONF -> PSYNC -> (RDB)
+func (l *Link) handshake(conn net.Conn, rd *protocol.Reader) error {
+	steps := [][]string{
+		{"PING"},
+		{"REPLCONF", "listening-port", l.listenPort},
+		{"REPLCONF", "capa", "psync2"},
+	}
+	for _, step := range steps {
+		if _, err := conn.Write(protocol.ArrayFmt(step)); err != nil {
+			return err
+		}
+		resp, err := rd.ReadLine()
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(resp, "-") {
+			return fmt.Errorf("主节点拒绝 %s: %s", step[0], resp)
+		}
+	}
+
+	// 有复制历史时携带 replid 与 offset+1 请求部分重同步
+	replID, offset := l.state.IDs()
+	psync := []string{"PSYNC", "?", "-1"}
+	if offset > 0 {
+		psync = []string{"PSYNC", replID, strconv.FormatInt(offset+1, 10)}
+	}
+	if l.failover != nil {
+		psync = append(psync, "FAILOVER")
+	}
+	if _, err := conn.Write(protocol.ArrayFmt(psync)); err != nil {
+		return err
+	}
+	resp, err := rd.ReadLine()
+	if err != nil {
+		return err
+	}
+	parts := strings.Fields(strings.TrimPrefix(resp, "+"))
+	if len(parts) == 0 || strings.HasPrefix(resp, "-") {
+		return fmt.Errorf("PSYNC 失败: %s", resp)
+	}
+
+	switch strings.ToUpper(parts[0]) {
+	case "FULLRESYNC":
+		if len(parts) < 3 {
+			return fmt.Errorf("不完整的FULLRESYNC响应: %s", resp)
+		}
+		masterOffset, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return err
+		}
+		payload, err := rd.ReadBulkPayload()
+		if err != nil {
+			return err
+		}
+		if err := l.node.LoadRDB(payload); err != nil {
+			return err
+		}
+		l.state.FullResync(parts[1], masterOffset)
+		// 数据集已被替换 下游副本必须重新全量同步
+		l.node.DisconnectReplicas()
+		log.Printf("全量同步完成 replid:%s offset:%d", parts[1], masterOffset)
+	case "CONTINUE":
+		newID := ""
+		if len(parts) > 1 {
+			newID = parts[1]
+		}
+		if l.state.Continue(newID) {
+			// 通知下游副本复制ID变化 它们重连后可凭 replid2 部分重同步
+			l.node.DisconnectReplicas()
+		}
+		log.Printf("部分重同步 replid:%s offset:%d", newID, offset)
+	default:
+		return fmt.Errorf("未知的 PSYNC 响应: %s", resp)
+	}
+	l.reportFailover(nil)
+	return nil
+}
+
+// 持续应用复制流 偏移量按原始字节累加
+// 主节点链路上的命令不回复 (GETACK 除外); 原始字节原样转发给下游副本
+func (l *Link) stream(conn net.Conn, rd *protocol.Reader) error {
+	rw := protocol.NewDiscardResponseWriter(conn)
+	rd.Record(true)
+
+	// 每秒上报一次 ACK 主节点据此计算延迟 (min-replicas-max-lag)
+	var writeMu sync.Mutex
+	sendAck := func() error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		ack := []string{"REPLCONF", "ACK", strconv.FormatInt(l.state.Offset(), 10)}
+		_, err := conn.Write(protocol.ArrayFmt(ack))
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := sendAck(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		cmd, args, err := rd.ReadRequest()
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(cmd, "REPLCONF") && len(args) > 1 && strings.EqualFold(args[1], "GETACK") {
+			// ACK 上报的是处理 GETACK 之前的偏移量
+			if err := sendAck(); err != nil {
+				return err
+			}
+		}
+		if err := l.node.ProcessMasterCommand(rw, cmd, args, rd.Raw()); err != nil {
+			log.Printf("Apply replicated command error: %v, cmd : %s", err, cmd)
+		}
+	}
+}
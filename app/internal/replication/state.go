@@ -0,0 +1,128 @@
+package replication
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// 默认积压缓冲区大小 1MB (与 Redis repl-backlog-size 默认值一致)
+const DefaultBacklogSize = 1 << 20
+
+// 未设置 replid2 时的占位值
+var emptyReplID = strings.Repeat("0", 40)
+
+// State 节点的复制状态 主从角色切换时保留
+// replid/offset 标识当前复制历史; replid2/secondOffset 记录上一段历史 (晋升为主节点前的主节点)
+// 使得其他从节点可以在新主节点上部分重同步
+type State struct {
+	mu           sync.Mutex
+	replID       string
+	replID2      string
+	offset       int64 // master_repl_offset: 已产生(主)或已处理(从)的复制流字节数
+	secondOffset int64 // replid2 可接受的最大 PSYNC 偏移量, -1 表示无效
+	backlog      *Backlog
+}
+
+func NewState(backlogSize int) *State {
+	return &State{
+		replID:       NewReplID(),
+		replID2:      emptyReplID,
+		secondOffset: -1,
+		backlog:      NewBacklog(backlogSize),
+	}
+}
+
+// NewReplID 生成40位十六进制随机复制ID
+func NewReplID() string {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// IDs 返回当前复制ID与偏移量
+func (s *State) IDs() (string, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replID, s.offset
+}
+
+func (s *State) Offset() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offset
+}
+
+// Feed 写入复制流 并推进偏移量
+func (s *State) Feed(p []byte) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backlog.Write(p)
+	s.offset += int64(len(p))
+	return s.offset
+}
+
+// ShiftReplID 从节点晋升为主节点时调用
+// 旧的复制ID作为 replid2 保留, 原来同一主节点下的其他从节点仍可部分重同步
+func (s *State) ShiftReplID() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replID2 = s.replID
+	s.secondOffset = s.offset + 1
+	s.replID = NewReplID()
+}
+
+// FullResync 全量同步完成后采用主节点的复制ID和偏移量
+func (s *State) FullResync(replID string, offset int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replID = replID
+	s.replID2 = emptyReplID
+	s.secondOffset = -1
+	s.offset = offset
+	s.backlog.Reset(offset + 1)
+}
+
+// Continue 部分重同步成功 主节点可能已经更换了复制ID (例如它自己刚被晋升)
+// 返回复制ID是否发生变化
+func (s *State) Continue(replID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if replID == "" || replID == s.replID {
+		return false
+	}
+	s.replID2 = s.replID
+	s.secondOffset = s.offset + 1
+	s.replID = replID
+	return true
+}
+
+// PartialFrom 判断从节点的 PSYNC <replid> <offset> 能否部分重同步, 可以则返回需要补发的数据
+func (s *State) PartialFrom(replID string, offset int64) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if replID != s.replID && (replID != s.replID2 || offset > s.secondOffset) {
+		return nil, false
+	}
+	return s.backlog.From(offset)
+}
+
+// Info INFO replication 中与复制历史相关的字段
+func (s *State) Info() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return []string{
+		"master_replid:" + s.replID,
+		"master_replid2:" + s.replID2,
+		fmt.Sprintf("master_repl_offset:%d", s.offset),
+		fmt.Sprintf("second_repl_offset:%d", s.secondOffset),
+		"repl_backlog_active:1",
+		fmt.Sprintf("repl_backlog_size:%d", s.backlog.Size()),
+		fmt.Sprintf("repl_backlog_first_byte_offset:%d", s.backlog.FirstByteOffset()),
+		fmt.Sprintf("repl_backlog_histlen:%d", s.backlog.HistLen()),
+	}
+}
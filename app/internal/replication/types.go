@@ -1,7 +1,9 @@
 package replication
 
 import (
+	"errors"
 	"net"
+	"time"
 )
 
 type MasterServerInterface interface {
@@ -10,4 +12,75 @@ type MasterServerInterface interface {
 	PropagateToReplicas(args []string) error
 	SendRDBFile(conn net.Conn) error
 	GetPoolLen() int
+	// 处理 PSYNC <replid> <offset> [FAILOVER]: 部分重同步或全量同步
+	// failover 为 true 时本节点 (请求方的副本) 先晋升为主节点
+	Psync(conn net.Conn, replID string, offset int64, failover bool) error
+	// REPLCONF listening-port <port>
+	SetReplicaPort(conn net.Conn, port string)
+	// REPLCONF ACK <offset>
+	ReplicaAck(conn net.Conn, offset int64)
+}
+
+// RoleSwitcher 运行时切换主从角色 (REPLICAOF / SLAVEOF)
+type RoleSwitcher interface {
+	ReplicaOf(host, port string) error
+	ReplicaOfNoOne() error
+}
+
+// FailoverOptions FAILOVER [TO host port] [TIMEOUT ms] [FORCE]
+type FailoverOptions struct {
+	Host    string // 为空时选择第一个追上复制偏移量的副本
+	Port    string
+	Timeout time.Duration // 0 表示一直等待
+	Force   bool          // 超时后即使目标未追上也继续切换
+}
+
+// FailoverCoordinator 主节点发起的计划内主从切换
+type FailoverCoordinator interface {
+	Failover(opts FailoverOptions) error
+	AbortFailover() error
+}
+
+// InfoProvider 提供 INFO replication 段的内容
+type InfoProvider interface {
+	ReplicationInfo() string
+}
+
+// SentinelInfoProvider 哨兵模式提供 INFO sentinel 段
+type SentinelInfoProvider interface {
+	SentinelInfo() string
+}
+
+var (
+	ErrNoSuchMaster       = errors.New("ERR No such master with that name")
+	ErrFailoverInProgress = errors.New("INPROG Failover already in progress")
+	ErrNoGoodSlave        = errors.New("NOGOODSLAVE No suitable replica to promote")
+)
+
+// SentinelHello 哨兵之间周期性交换的 hello 消息
+// SENTINEL HELLO <ip> <port> <runid> <current-epoch> <master-name> <master-ip> <master-port> <config-epoch>
+type SentinelHello struct {
+	IP           string
+	Port         string
+	RunID        string
+	CurrentEpoch int64
+	MasterName   string
+	MasterIP     string
+	MasterPort   string
+	ConfigEpoch  int64
+}
+
+// SentinelMonitor SENTINEL 命令的实现
+type SentinelMonitor interface {
+	MyID() string
+	MasterAddr(name string) (host, port string, ok bool)
+	Masters() [][]string
+	Master(name string) ([]string, error)
+	Replicas(name string) ([][]string, error)
+	Sentinels(name string) ([][]string, error)
+	// 返回是否主观下线 runID 不为 "*" 时同时请求在 epoch 中为其投票, 返回本哨兵的投票
+	IsMasterDownByAddr(host, port string, epoch int64, runID string) (down bool, leader string, leaderEpoch int64)
+	Hello(h SentinelHello)
+	CkQuorum(name string) (string, error)
+	Failover(name string) error
 }
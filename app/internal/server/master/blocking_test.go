@@ -0,0 +1,50 @@
+package master_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/go-playground/assert/v2"
+)
+
+// 阻塞期间断开的客户端不再被服务 之后写入的数据保留在键中
+func TestBlockedClientDisconnect(t *testing.T) {
+	startMaster(t, "6406", false)
+	c := dialResp(t, "6406")
+
+	for _, block := range [][]string{
+		{"BLPOP", "jobs", "0"},
+		{"BLMOVE", "jobs", "dst", "LEFT", "RIGHT", "0"},
+		{"BZPOPMIN", "z", "0"},
+		{"XREAD", "BLOCK", "0", "STREAMS", "s", "$"},
+	} {
+		b := dialResp(t, "6406")
+		_, err := b.conn.Write(protocol.ArrayFmt(block))
+		assert.Equal(t, nil, err)
+		time.Sleep(100 * time.Millisecond)
+		b.conn.Close()
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	c.do(t, "RPUSH", "jobs", "job1")
+	c.do(t, "ZADD", "z", "1", "m")
+	c.do(t, "XADD", "s", "1-1", "f", "v")
+	v, _ := c.do(t, "LRANGE", "jobs", "0", "-1")
+	assert.Equal(t, []any{"job1"}, v)
+	v, _ = c.do(t, "TYPE", "dst")
+	assert.Equal(t, "none", v)
+	v, _ = c.do(t, "ZCARD", "z")
+	assert.Equal(t, int64(1), v)
+
+	// 仍在等待的客户端照常被服务
+	b := dialResp(t, "6406")
+	ch := make(chan any, 1)
+	go func() {
+		v, _ := b.do(t, "BLPOP", "q", "0")
+		ch <- v
+	}()
+	time.Sleep(100 * time.Millisecond)
+	c.do(t, "RPUSH", "q", "x")
+	assert.Equal(t, []any{"q", "x"}, <-ch)
+}
@@ -0,0 +1,41 @@
+package master
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/cluster"
+)
+
+var (
+	errClusterReplicaOf = errors.New("REPLICAOF not allowed in cluster mode.")
+	errClusterFailover  = errors.New("ERR FAILOVER not allowed in cluster mode.")
+)
+
+// clusterReplicator 集群根据节点表切换复制关系 (CLUSTER REPLICATE / 故障转移)
+// 绕过 REPLICAOF 在集群模式下的限制
+type clusterReplicator struct {
+	m *MasterServer
+}
+
+var _ cluster.Replicator = clusterReplicator{}
+
+func (r clusterReplicator) ReplicaOf(host, port string) error {
+	return r.m.replicaOf(host, port, nil)
+}
+
+func (r clusterReplicator) ReplicaOfNoOne() error {
+	return r.m.promote()
+}
+
+func (r clusterReplicator) ReplOffset() int64 {
+	return r.m.Repl.Offset()
+}
+
+// startClusterBus 加载 nodes.conf 并启动集群总线
+func (m *MasterServer) startClusterBus() error {
+	path := filepath.Join(m.Cfg.Dir, m.Cfg.ClusterConfigFile)
+	timeout := time.Duration(m.Cfg.ClusterNodeTimeout) * time.Millisecond
+	return m.Cluster.StartBus(path, timeout, clusterReplicator{m})
+}
@@ -0,0 +1,246 @@
+package master
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+)
+
+// master_failover_state
+const (
+	failoverNone       = "no-failover"
+	failoverWaitSync   = "waiting-for-sync"     // 已暂停写入 等待目标副本追上复制偏移量
+	failoverInProgress = "failover-in-progress" // 已降级为目标的副本 等待 PSYNC FAILOVER 的结果
+)
+
+// PSYNC FAILOVER 握手的最长等待时间
+const failoverPsyncTimeout = 10 * time.Second
+
+var (
+	errFailoverReplica    = errors.New("ERR FAILOVER is not valid when server is a replica.")
+	errFailoverNoReplicas = errors.New("ERR FAILOVER requires connected replicas.")
+	errFailoverInProgress = errors.New("ERR FAILOVER already in progress.")
+	errFailoverNotRunning = errors.New("ERR No failover in progress.")
+	errFailoverForce      = errors.New("ERR FAILOVER with force option requires both a timeout and target HOST and IP.")
+	errFailoverTarget     = errors.New("ERR FAILOVER target HOST and PORT is not a replica.")
+	errFailoverRoleChange = errors.New("REPLICAOF not allowed while failing over.")
+	errPsyncFailoverRole  = errors.New("ERR PSYNC FAILOVER can't be sent to a master.")
+	errPsyncFailoverID    = errors.New("ERR PSYNC FAILOVER replid must match my replid.")
+)
+
+// failoverJob 一次 FAILOVER 的进度 state 由 m.Mu 保护
+type failoverJob struct {
+	opts     replication.FailoverOptions
+	deadline time.Time // 零值表示不超时
+	state    string
+
+	abortOnce sync.Once
+	abort     chan struct{} // FAILOVER ABORT
+	resume    chan struct{} // 关闭时恢复写入
+}
+
+func (m *MasterServer) failingOver() bool {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+	return m.failover != nil
+}
+
+// failoverState 调用方需持有 m.Mu
+func (m *MasterServer) failoverState() string {
+	if m.failover == nil {
+		return failoverNone
+	}
+	return m.failover.state
+}
+
+// lockWrites 获取 writeMu FAILOVER 暂停写入期间等待其结束
+func (m *MasterServer) lockWrites() {
+	for {
+		m.writeMu.Lock()
+		m.Mu.RLock()
+		f := m.failover
+		m.Mu.RUnlock()
+		if f == nil {
+			return
+		}
+		m.writeMu.Unlock()
+		<-f.resume
+	}
+}
+
+// Failover FAILOVER [TO host port] [TIMEOUT ms] [FORCE] 检查参数后在后台执行
+func (m *MasterServer) Failover(opts replication.FailoverOptions) error {
+	if m.Cluster != nil {
+		return errClusterFailover
+	}
+	if opts.Force && (opts.Host == "" || opts.Timeout <= 0) {
+		return errFailoverForce
+	}
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+	if m.link != nil {
+		return errFailoverReplica
+	}
+	if m.failover != nil {
+		return errFailoverInProgress
+	}
+	if len(m.Replicas) == 0 {
+		return errFailoverNoReplicas
+	}
+	if opts.Host != "" && m.findReplicaByAddr(opts.Host, opts.Port) == nil {
+		return errFailoverTarget
+	}
+
+	f := &failoverJob{
+		opts:   opts,
+		state:  failoverWaitSync,
+		abort:  make(chan struct{}),
+		resume: make(chan struct{}),
+	}
+	if opts.Timeout > 0 {
+		f.deadline = time.Now().Add(opts.Timeout)
+	}
+	// 设置后新的写命令在 lockWrites 中等待
+	m.failover = f
+	go m.runFailover(f)
+	log.Printf("FAILOVER requested, target: %s:%s, timeout: %s, force: %v", opts.Host, opts.Port, opts.Timeout, opts.Force)
+	return nil
+}
+
+// AbortFailover FAILOVER ABORT
+func (m *MasterServer) AbortFailover() error {
+	m.Mu.RLock()
+	f := m.failover
+	m.Mu.RUnlock()
+	if f == nil {
+		return errFailoverNotRunning
+	}
+	f.abortOnce.Do(func() { close(f.abort) })
+	return nil
+}
+
+func (m *MasterServer) findReplicaByAddr(host, port string) *replicaInfo {
+	for _, r := range m.Replicas {
+		if r.ip == host && r.port == port {
+			return r
+		}
+	}
+	return nil
+}
+
+// runFailover 等待目标副本追上 -> 降级为其副本并发送 PSYNC FAILOVER -> 恢复写入
+func (m *MasterServer) runFailover(f *failoverJob) {
+	defer func() {
+		m.Mu.Lock()
+		m.failover = nil
+		m.Mu.Unlock()
+		close(f.resume)
+	}()
+
+	// 等待进行中的写命令结束 记录此刻的偏移量并让副本立即上报 ACK
+	m.writeMu.Lock()
+	m.Mu.RLock()
+	target := m.Repl.Offset()
+	m.feed(protocol.ArrayFmt([]string{"REPLCONF", "GETACK", "*"}))
+	m.Mu.RUnlock()
+	m.writeMu.Unlock()
+
+	host, port, ok := m.waitFailoverSync(f, target)
+	if !ok {
+		return
+	}
+
+	m.Mu.Lock()
+	f.state = failoverInProgress
+	m.Mu.Unlock()
+	log.Printf("FAILOVER: demoting to replica of %s:%s", host, port)
+
+	result := make(chan error, 1)
+	m.replicaOf(host, port, result)
+	var err error
+	select {
+	case err = <-result:
+	case <-f.abort:
+		err = errors.New("aborted")
+	case <-time.After(failoverPsyncTimeout):
+		err = errors.New("timeout waiting for PSYNC FAILOVER reply")
+	}
+	if err != nil {
+		log.Printf("FAILOVER to %s:%s failed: %s, reverting to master", host, port, err)
+		m.revertFailover()
+		return
+	}
+	log.Printf("FAILOVER to %s:%s completed", host, port)
+}
+
+// waitFailoverSync 等待目标副本 (或未指定时任意副本) 的 ACK 偏移量追上 target
+func (m *MasterServer) waitFailoverSync(f *failoverJob, target int64) (string, string, bool) {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.abort:
+			log.Printf("FAILOVER aborted")
+			return "", "", false
+		case <-ticker.C:
+		}
+
+		m.Mu.RLock()
+		var caughtUp *replicaInfo
+		for _, r := range m.Replicas {
+			if f.opts.Host != "" && (r.ip != f.opts.Host || r.port != f.opts.Port) {
+				continue
+			}
+			if r.ackOffset.Load() >= target {
+				caughtUp = r
+				break
+			}
+		}
+		m.Mu.RUnlock()
+		if caughtUp != nil {
+			return caughtUp.ip, caughtUp.port, true
+		}
+
+		if !f.deadline.IsZero() && time.Now().After(f.deadline) {
+			if f.opts.Force {
+				log.Printf("FAILOVER timeout, forcing failover to %s:%s", f.opts.Host, f.opts.Port)
+				return f.opts.Host, f.opts.Port, true
+			}
+			log.Printf("FAILOVER timeout waiting for replica offset %d, aborting", target)
+			return "", "", false
+		}
+	}
+}
+
+// revertFailover PSYNC FAILOVER 失败 恢复为主节点 (复制历史未变化, 无需更换复制ID)
+func (m *MasterServer) revertFailover() {
+	m.Mu.Lock()
+	link := m.link
+	m.link = nil
+	m.Cfg.Role = "master"
+	m.Cfg.ReplicaOf = config.ReplicaConfig{}
+	m.Mu.Unlock()
+	if link != nil {
+		link.Stop()
+	}
+}
+
+// acceptFailover 收到当前主节点的 PSYNC FAILOVER: 校验复制ID后晋升为主节点
+func (m *MasterServer) acceptFailover(replID string) error {
+	m.Mu.RLock()
+	isReplica := m.link != nil
+	m.Mu.RUnlock()
+	if !isReplica {
+		return errPsyncFailoverRole
+	}
+	if replID != m.replID() {
+		return errPsyncFailoverID
+	}
+	log.Printf("PSYNC FAILOVER received, promoting to master")
+	return m.promote()
+}
@@ -1,15 +1,18 @@
 package master
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"log"
 	"net"
-	"os"
-	"slices"
+	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/codecrafters-io/redis-starter-go/app/internal/blocking"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/cluster"
 	"github.com/codecrafters-io/redis-starter-go/app/internal/command"
 	"github.com/codecrafters-io/redis-starter-go/app/internal/config"
 	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
@@ -22,25 +25,48 @@ import (
 
 // 确保MasterServer实现replication.MasterServerInterface接口（编译时检查）
 var _ replication.MasterServerInterface = (*MasterServer)(nil)
+var _ replication.RoleSwitcher = (*MasterServer)(nil)
+var _ replication.FailoverCoordinator = (*MasterServer)(nil)
 
 type MasterServer struct {
 	*server.BaseServer // cfg & store & registry
 	Replicas           []*replicaInfo
+	Repl               *replication.State        // 复制ID/偏移量/积压缓冲区 角色切换时保留
+	link               *replication.Link         // 非nil时当前节点为从节点
+	pendingPorts       map[net.Conn]string       // 完成 PSYNC 前上报的副本监听端口
+	failover           *failoverJob              // 进行中的 FAILOVER 命令
+	Cluster            *cluster.State            // 集群模式下的槽归属 未启用时为nil
+	asking             map[net.Conn]bool         // 发送了 ASKING 的连接 仅对下一条命令有效
+	txs                map[net.Conn]*transaction // MULTI 之后排队中的事务
+	Blocked            *blocking.Registry        // 阻塞在 BLPOP 等命令上的客户端
 
-	Mu sync.RWMutex
-}
+	Mu      sync.RWMutex
+	writeMu sync.Mutex // 串行化写命令与复制流 加锁顺序: writeMu -> Mu
 
-type replicaInfo struct {
-	conn net.Conn
-	addr string
-	mu   sync.Mutex // 保护conn的并发访问
+	// 以下由 writeMu 保护
+	execRunning    bool      // EXEC 执行中 传播的命令以 MULTI ... EXEC 包裹
+	execPropagated bool      // 本次 EXEC 已写入 MULTI
+	masterTx       *masterTx // 复制流中尚未收到 EXEC 的事务
 }
 
 func NewMasterServer(cfg *config.ServerConfig) *MasterServer {
 	store := kvstore.NewStore()
+	// 非字符串类型只能从内存读取 启动时加载整个 RDB
+	if err := filemanager.LoadFile(cfg.Fn, store); err != nil {
+		log.Printf("Load RDB %s Error: %s", cfg.Fn, err)
+	}
 	ms := &MasterServer{
-		BaseServer: server.NewBaseServer(cfg, store),
-		Replicas:   make([]*replicaInfo, 0), // 初始化为空
+		BaseServer:   server.NewBaseServer(cfg, store),
+		Replicas:     make([]*replicaInfo, 0), // 初始化为空
+		Repl:         replication.NewState(replication.DefaultBacklogSize),
+		pendingPorts: make(map[net.Conn]string),
+		asking:       make(map[net.Conn]bool),
+		txs:          make(map[net.Conn]*transaction),
+		Blocked:      blocking.NewRegistry(),
+	}
+	if cfg.ClusterEnabled {
+		port, _ := strconv.Atoi(cfg.Port)
+		ms.Cluster = cluster.NewState("127.0.0.1", port, cfg.ClusterRequireFullCoverage)
 	}
 	ms.RegisterCmd()
 	return ms
@@ -54,9 +80,74 @@ func (m *MasterServer) RegisterCmd() {
 	m.Registry.Register(command.NewGetCommand(m.Store, m.Cfg.Fn))
 	m.Registry.Register(command.NewConfigCommand(m.Cfg))
 	m.Registry.Register(command.NewKeysCommand(m.Store, m.Cfg.Fn))
-	m.Registry.Register(command.NewInfoCommand(m.Cfg))
-	m.Registry.Register(command.NewReplconfCommand(m.Cfg))
+	m.Registry.Register(command.NewDelCommand(m.Store, m.Cfg.Fn, m))
+	m.Registry.Register(command.NewDumpCommand(m.Store))
+	m.Registry.Register(command.NewTypeCommand(m.Store))
+	m.Registry.Register(command.NewObjectCommand(m.Store))
+	for _, h := range command.StringCommands(m.Store, m.Cfg.Fn, m) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.ListCommands(m.Store, m.Cfg.Fn, m) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.HashCommands(m.Store, m.Cfg.Fn, m) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.SetCommands(m.Store, m.Cfg.Fn, m) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.ZSetCommands(m.Store, m.Cfg.Fn, m, m.Blocked) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.BlockingListCommands(m.Store, m.Cfg.Fn, m, m.Blocked) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.BitmapCommands(m.Store, m.Cfg.Fn, m) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.HyperLogLogCommands(m.Store, m.Cfg.Fn, m) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.GeoCommands(m.Store, m.Cfg.Fn, m) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.JSONCommands(m.Store, m.Cfg.Fn, m) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.BloomCommands(m.Store, m.Cfg.Fn, m) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.CuckooCommands(m.Store, m.Cfg.Fn, m) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.CMSCommands(m.Store, m.Cfg.Fn, m) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.TopKCommands(m.Store, m.Cfg.Fn, m) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.TimeSeriesCommands(m.Store, m.Cfg.Fn, m) {
+		m.Registry.Register(h)
+	}
+	for _, h := range command.StreamCommands(m.Store, m.Cfg.Fn, m, m.Blocked) {
+		m.Registry.Register(h)
+	}
+	m.Registry.Register(command.NewRestoreCommand("RESTORE", m.Store, m.Cfg.Fn, m))
+	m.Registry.Register(command.NewRestoreCommand("RESTORE-ASKING", m.Store, m.Cfg.Fn, m))
+	m.Registry.Register(command.NewMigrateCommand(m.Store, m.Cfg.Fn, m))
+	m.Registry.Register(command.NewInfoCommand(m.Cfg, m))
+	m.Registry.Register(command.NewReplconfCommand(m.Cfg, m))
 	m.Registry.Register(command.NewPsyncCommand(m))
+	m.Registry.Register(command.NewReplicaofCommand("REPLICAOF", m))
+	m.Registry.Register(command.NewReplicaofCommand("SLAVEOF", m))
+	m.Registry.Register(command.NewFailoverCommand(m))
+	m.Registry.Register(command.NewTxCommand("MULTI", m))
+	m.Registry.Register(command.NewTxCommand("EXEC", m))
+	m.Registry.Register(command.NewTxCommand("DISCARD", m))
+	if m.Cluster != nil {
+		m.Registry.Register(command.NewClusterCommand(m.Cluster, m.Store))
+		m.Registry.Register(command.NewAskingCommand(m))
+	}
 }
 
 func (m *MasterServer) Start() error {
@@ -67,7 +158,12 @@ func (m *MasterServer) Start() error {
 	}
 	defer l.Close()
 
-	log.Printf("Master server started on port %s", m.Cfg.Port)
+	if m.Cluster != nil {
+		if err := m.startClusterBus(); err != nil {
+			return err
+		}
+	}
+	log.Printf("Server started on port %s (role: %s)", m.Cfg.Port, m.Role())
 
 	for {
 		conn, err := l.Accept()
@@ -82,156 +178,396 @@ func (m *MasterServer) Start() error {
 func (m *MasterServer) HandleConnection(conn net.Conn) {
 	defer func() {
 		m.RemoveReplica(conn)
+		m.Mu.Lock()
+		delete(m.asking, conn)
+		delete(m.txs, conn)
+		m.Mu.Unlock()
 		conn.Close()
 	}()
 	// 创建响应写入器
 	rw := protocol.NewConnResponseWriter(conn)
-	for {
-		// 解析命令
-		cmd, args, err := protocol.ParseRequest(conn)
-		if err != nil {
-			if err != io.EOF {
-				log.Printf("Protocol error: %v", err)
-				rw.WriteNull()
+	// 读取在单独的 goroutine 中进行: 命令阻塞期间客户端断开也能及时发现,
+	// ctx 随之取消, 阻塞在键上的等待者不再被服务 (数据不会写给已关闭的连接)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	requests := make(chan request)
+	var readErr error
+	go func() {
+		defer close(requests)
+		defer cancel()
+		rd := protocol.NewReader(conn)
+		for {
+			// 解析命令
+			cmd, args, err := rd.ReadRequest()
+			if err != nil {
+				readErr = err
+				return
+			}
+			select {
+			case requests <- request{cmd: cmd, args: args}:
+			case <-ctx.Done():
+				return
 			}
-			return
 		}
+	}()
+	for req := range requests {
 		// 处理命令
-		if err := m.ProcessCommand(rw, cmd, args); err != nil {
-			log.Printf("Command error: %v, cmd : %s", err, cmd)
+		if err := m.dispatch(ctx, rw, req.cmd, req.args, false); err != nil {
+			log.Printf("Command error: %v, cmd : %s", err, req.cmd)
 			return
 		}
 	}
+	if readErr != nil && readErr != io.EOF {
+		log.Printf("Protocol error: %v", readErr)
+		rw.WriteNull()
+	}
+}
+
+// request 从连接读取的一条命令
+type request struct {
+	cmd  string
+	args []string
 }
 
+// ProcessCommand 处理普通客户端的命令
 func (m *MasterServer) ProcessCommand(rw protocol.ResponseWriter, cmd string, args []string) error {
+	return m.dispatch(context.Background(), rw, cmd, args, false)
+}
+
+// ProcessMasterCommand 处理主从链路上来自主节点的命令
+// 执行后将原始字节写入积压缓冲区并转发给下游副本, 下游副本因此与本节点共享主节点的复制ID与偏移量
+func (m *MasterServer) ProcessMasterCommand(rw protocol.ResponseWriter, cmd string, args []string, raw []byte) error {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	// raw 在下一次读取时会被复用
+	stream, err := m.applyMaster(rw, cmd, args, bytes.Clone(raw))
+	if stream != nil {
+		m.Mu.RLock()
+		m.feed(stream)
+		m.Mu.RUnlock()
+	}
+	return err
+}
+
+// dispatch ctx 为客户端连接的生命周期 连接断开后取消
+func (m *MasterServer) dispatch(ctx context.Context, rw protocol.ResponseWriter, cmd string, args []string, fromMaster bool) error {
 	// 查找命令处理器
 	handler, ok := m.Registry.GetHandler(cmd)
+	// MULTI 之后除 MULTI/EXEC/DISCARD 以外的命令排队
+	if !fromMaster {
+		if _, isTx := handler.(*command.TxCommand); !isTx {
+			if tx := m.txOf(rw.Conn()); tx != nil {
+				return m.queue(rw, tx, handler, cmd, args)
+			}
+		}
+	}
 	if !ok {
 		log.Printf("ERR unknown command '%s'", cmd)
 		return errors_r.ErrInvalidRequest
 	}
 
+	// 集群模式: 键不属于本节点负责的槽时重定向 (MOVED/ASK) 复制流不受限制
+	if m.Cluster != nil && !fromMaster {
+		asking := m.takeAsking(rw.Conn(), handler) || command.FlagsOf(handler)&command.FlagAsking != 0
+		if err := m.Cluster.Route(command.KeysOf(handler, args), asking, m.keyExists); err != nil {
+			return rw.WriteError(err.Error())
+		}
+	}
+
+	waiter, err := m.execute(ctx, rw, handler, args, fromMaster)
+	if err != nil || waiter == nil {
+		return err
+	}
+	// 阻塞命令: 已释放写锁 等待其他客户端写入或超时
+	return rw.WriteValue(waiter.Wait())
+}
+
+// execute 执行命令 阻塞命令没有可用数据时返回等待者
+func (m *MasterServer) execute(ctx context.Context, rw protocol.ResponseWriter, handler command.Handler, args []string, fromMaster bool) (*blocking.Waiter, error) {
+	write := !fromMaster && command.FlagsOf(handler)&command.FlagWrite != 0
+	// 只读的阻塞命令 (XREAD) 也需要写锁: 检查数据与注册等待者之间不能有写入
+	blocker, isBlocker := handler.(command.Blocker)
+	block := isBlocker && !fromMaster
+	// 写命令串行执行: 执行与传播作为整体, 复制流与本地数据集的修改顺序一致
+	// FAILOVER 暂停写入期间在此等待, 切换完成后本节点可能已是只读从节点
+	if write || block {
+		m.lockWrites()
+		defer m.writeMu.Unlock()
+	}
+
+	// 只读从节点拒绝客户端的写命令 主节点的复制流不受限制
+	if write && m.isReadOnlyReplica() {
+		return nil, rw.WriteError("READONLY You can't write against a read only replica.")
+	}
+	// min-replicas-to-write: 健康副本不足时拒绝写入 限制网络分区时的数据丢失
+	if write && !m.enoughGoodReplicas() {
+		return nil, rw.WriteError("NOREPLICAS Not enough good replicas to write.")
+	}
+
 	// 执行命令
-	ctx := context.Background()
-	return handler.Execute(ctx, rw, args)
+	var waiter *blocking.Waiter
+	var err error
+	if block {
+		waiter, err = blocker.Block(ctx, rw, args)
+	} else {
+		err = handler.Execute(ctx, rw, args)
+	}
+	// 写命令执行完成后 (仍持有写锁) 服务阻塞在被写入键上的客户端
+	if command.FlagsOf(handler)&command.FlagWrite != 0 {
+		m.Blocked.SignalKeys(command.KeysOf(handler, args)...)
+		m.Blocked.ServeReady()
+	}
+	return waiter, err
+}
+
+// SetAsking ASKING 命令
+func (m *MasterServer) SetAsking(conn net.Conn) {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+	m.asking[conn] = true
 }
 
-func (m *MasterServer) AddReplica(conn net.Conn) {
+// takeAsking 返回并清除连接的 ASKING 标志 ASKING 命令本身不消耗标志
+func (m *MasterServer) takeAsking(conn net.Conn, handler command.Handler) bool {
+	if conn == nil || handler.Name() == "ASKING" {
+		return false
+	}
 	m.Mu.Lock()
 	defer m.Mu.Unlock()
-	info := &replicaInfo{conn: conn, addr: conn.RemoteAddr().String()}
-	m.Replicas = append(m.Replicas, info)
+	asking := m.asking[conn]
+	delete(m.asking, conn)
+	return asking
+}
 
-	log.Printf("New replica connected: %s (Total: %d)", info.addr, len(m.Replicas))
+func (m *MasterServer) keyExists(key string) bool {
+	return m.Store.Exists(key)
+}
 
-	go m.syncToReplica(info)
+func (m *MasterServer) isReadOnlyReplica() bool {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+	return m.link != nil && m.Cfg.ReplicaReadOnly
 }
 
-// 删除副本   unused
-func (m *MasterServer) RemoveReplica(conn net.Conn) {
+// Role 当前角色 master/slave
+func (m *MasterServer) Role() string {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+	return m.Cfg.Role
+}
+
+// ReplicaOf 成为 host:port 的从节点 数据集与复制历史保留, 以便尝试部分重同步
+func (m *MasterServer) ReplicaOf(host, port string) error {
+	if m.Cluster != nil {
+		return errClusterReplicaOf
+	}
+	if m.failingOver() {
+		return errFailoverRoleChange
+	}
+	return m.replicaOf(host, port, nil)
+}
+
+// replicaOf failoverResult 非nil时以 PSYNC FAILOVER 请求新主节点晋升 (FAILOVER 命令)
+func (m *MasterServer) replicaOf(host, port string, failoverResult chan<- error) error {
 	m.Mu.Lock()
-	defer m.Mu.Unlock()
-	if m.Replicas == nil {
-		return
+	if m.link != nil && m.link.Host == host && m.link.Port == port {
+		m.Mu.Unlock()
+		return nil
 	}
-	for i, r := range m.Replicas {
-		if r.conn == conn {
-			// 从切片中移除
-			m.Replicas = slices.Delete(m.Replicas, i, i+1)
-			log.Printf("Replica disconnected: %s (Remaining: %d)", r.addr, len(m.Replicas))
-			return
-		}
+	old := m.link
+	// 更换主节点 断开下游副本让其重新 PSYNC (链路建立后可部分重同步)
+	m.disconnectReplicas()
+	m.Cfg.Role = "slave"
+	m.Cfg.ReplicaOf = config.ReplicaConfig{MasterHost: host, MasterPort: port}
+	m.link = replication.NewLink(host, port, m.Cfg.Port, m.Repl, m)
+	if failoverResult != nil {
+		m.link.SetFailover(failoverResult)
+	}
+	link := m.link
+	m.Mu.Unlock()
+
+	// 从节点不能服务阻塞命令 (写命令) 唤醒所有等待者
+	m.Blocked.UnblockAll(protocol.ErrorReply("UNBLOCKED force unblock from blocking operation, instance state changed (master -> replica?)"))
+	if old != nil {
+		old.Stop()
 	}
+	link.Start()
+	log.Printf("Now replicating from %s:%s", host, port)
+	return nil
 }
 
-func (m *MasterServer) syncToReplica(info *replicaInfo) {
-	info.mu.Lock()
-	defer info.mu.Unlock()
-	// 实现数据同步逻辑
-	if err := m.SendRDBFile(info.conn); err != nil {
-		log.Printf("Sync to replica Error: %s", err)
-		return
+// ReplicaOfNoOne 晋升为主节点 旧复制ID保留为 replid2
+func (m *MasterServer) ReplicaOfNoOne() error {
+	if m.Cluster != nil {
+		return errClusterReplicaOf
+	}
+	if m.failingOver() {
+		return errFailoverRoleChange
 	}
-	log.Printf("Sync to replica Success: %s", info.addr)
+	return m.promote()
 }
 
-func (m *MasterServer) SendRDBFile(conn net.Conn) error {
+// promote 停止复制链路并晋升 不检查 REPLICAOF 的限制 (PSYNC FAILOVER / 集群故障转移)
+func (m *MasterServer) promote() error {
+	m.Mu.RLock()
+	old := m.link
+	m.Mu.RUnlock()
+	if old == nil {
+		return nil
+	}
+
+	// 先停止链路 确保偏移量不再变化
+	old.Stop()
+
 	m.Mu.Lock()
-	defer m.Mu.Unlock()
-	return IoCopyEmpty(m.Cfg.Fn, conn)
+	if m.link != old {
+		// 期间又执行了 REPLICAOF host port
+		m.Mu.Unlock()
+		return nil
+	}
+	m.link = nil
+	m.Repl.ShiftReplID()
+	m.Cfg.Role = "master"
+	m.Cfg.ReplicaOf = config.ReplicaConfig{}
+	m.Mu.Unlock()
+	log.Printf("Promoted to master, replid: %s", m.replID())
+	return nil
 }
 
-func IoCopyEmpty(fn string, conn net.Conn) error {
-	// REWrite by minimal RDB empty file instead ofnerated from SaveToRDB func. ge
-	// minimalRDB :=
-	err := filemanager.SaveToRDB(fn, nil)
-	if err != nil {
-		log.Printf("Save Empty file Error: %s", err)
+func (m *MasterServer) replID() string {
+	id, _ := m.Repl.IDs()
+	return id
+}
+
+// LoadRDB 全量同步: 清空数据集并加载主节点发送的 RDB
+func (m *MasterServer) LoadRDB(payload []byte) error {
+	m.Store.Flush()
+	if err := filemanager.LoadRDB(bytes.NewReader(payload), m.Store); err != nil {
+		log.Printf("Load RDB from master Error: %s", err)
 		return err
 	}
-	file, err := os.Open(fn)
-	if err != nil {
-		log.Printf("Open File Error: %s", err)
+	return filemanager.UpdateRDB(m.Cfg.Fn, m.Store)
+}
+
+func (m *MasterServer) ReplicationInfo() string {
+	m.Mu.RLock()
+	lines := []string{"role:" + m.Cfg.Role}
+	if m.link != nil {
+		status := "down"
+		if m.link.Status() == replication.LinkConnected {
+			status = "up"
+		}
+		lines = append(lines,
+			"master_host:"+m.link.Host,
+			"master_port:"+m.link.Port,
+			"master_link_status:"+status,
+			fmt.Sprintf("slave_repl_offset:%d", m.Repl.Offset()),
+			fmt.Sprintf("slave_priority:%d", m.Cfg.ReplicaPriority),
+		)
+	}
+	lines = append(lines, fmt.Sprintf("connected_slaves:%d", len(m.Replicas)))
+	if m.link == nil && m.Cfg.MinReplicasToWrite > 0 && m.Cfg.MinReplicasMaxLag > 0 {
+		lines = append(lines, fmt.Sprintf("min_slaves_good_slaves:%d", m.goodReplicas()))
+	}
+	lines = append(lines, m.replicasInfo()...)
+	lines = append(lines, "master_failover_state:"+m.failoverState())
+	m.Mu.RUnlock()
+	lines = append(lines, m.Repl.Info()...)
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// Psync 在 writeMu 与 m.Mu 保护下生成快照并加入副本列表
+// 同步数据先于之后传播的命令进入该副本的输出队列, 由写协程异步发送
+func (m *MasterServer) Psync(conn net.Conn, replID string, offset int64, failover bool) error {
+	if failover {
+		// PSYNC FAILOVER: 主节点已暂停写入并等待本节点追上, 晋升后按 replid2 部分重同步
+		if err := m.acceptFailover(replID); err != nil {
+			_, werr := conn.Write(protocol.ErrorFmt(err.Error()))
+			return werr
+		}
+	}
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+	// 从节点同样可以服务 PSYNC (链式复制), 但必须已与自己的主节点同步
+	if m.link != nil && m.link.Status() != replication.LinkConnected {
+		_, err := conn.Write(protocol.ErrorFmt("NOMASTERLINK Can't SYNC while not connected with my master"))
 		return err
 	}
-	defer file.Close()
-	fileInfo, err := file.Stat()
-	fileSize := fileInfo.Size()
-	s := fmt.Sprintf("$%d\r\n", fileSize)
-	conn.Write([]byte(s))
-	_, err = io.Copy(conn, file) // avoid MemCopy
+
+	info := newReplicaInfo(conn)
+	// 部分重同步: +CONTINUE <replid> 后补发积压缓冲区中缺失的数据
+	if backlog, ok := m.Repl.PartialFrom(replID, offset); ok {
+		id, _ := m.Repl.IDs()
+		info.enqueue(protocol.SimpleStringFmt("CONTINUE "+id), false, outputLimit{})
+		info.enqueue(backlog, false, outputLimit{})
+		m.addReplica(info)
+		log.Printf("Partial resync with %s from offset %d (%d bytes)", conn.RemoteAddr(), offset, len(backlog))
+		return nil
+	}
+
+	// 全量同步: 发送 FULLRESYNC <replid> <offset> 与当前数据集的 RDB
+	id, masterOffset := m.Repl.IDs()
+	payload, err := m.rdbPayload()
 	if err != nil {
-		log.Printf("ioCopy Error: %s", err)
+		log.Printf("Sync to replica Error: %s", err)
 		return err
 	}
+	info.enqueue(protocol.SimpleStringFmt(fmt.Sprintf("FULLRESYNC %s %d", id, masterOffset)), false, outputLimit{})
+	info.enqueue(payload, false, outputLimit{})
+	m.addReplica(info)
+	log.Printf("Full resync with %s at offset %d (%d bytes)", info.addr, masterOffset, len(payload))
 	return nil
 }
 
+// SendRDBFile 发送当前数据集快照 格式: $<len>\r\n<rdb> (无结尾\r\n)
+func (m *MasterServer) SendRDBFile(conn net.Conn) error {
+	payload, err := m.rdbPayload()
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(payload)
+	return err
+}
+
+func (m *MasterServer) rdbPayload() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := filemanager.WriteRDB(&buf, m.Store); err != nil {
+		log.Printf("Encode RDB Error: %s", err)
+		return nil, err
+	}
+	return append([]byte(fmt.Sprintf("$%d\r\n", buf.Len())), buf.Bytes()...), nil
+}
+
+// PropagateToReplicas 不等待副本写出 调用方(写命令)持有 writeMu, 因此命令按执行顺序进入复制流
 func (m *MasterServer) PropagateToReplicas(args []string) error {
-	var wg sync.WaitGroup
 	m.Mu.RLock()
 	defer m.Mu.RUnlock()
-	if len(m.Replicas) != 0 {
-		log.Printf("Propagating command to %d replicas", len(m.Replicas))
-		for idx, c := range m.Replicas {
-			if c == nil {
-				log.Printf("Warning: nil replica found at index %d", idx)
-				continue
-			}
-			// 可能会有竞态  循环与goroutine异步 可能在goroutine启动时已经进行了几次循环，所以闭包中取值显式赋值
-			res := args
-			wg.Add(1)
-			go func(c *replicaInfo) {
-				defer wg.Done()
-				if c == nil || c.conn == nil {
-					log.Println("Warning: replica or replica.conn is nil")
-					return
-				}
-				if err := c.Write(res); err != nil {
-					log.Printf("Propogated Error %s :%s", res[0], err)
-					return
-				}
-				log.Printf("Propogated Success %s, Target Addr:%s", res[0], c.addr)
-			}(c)
-		}
-	} else {
-		log.Printf("replConnPool is nil")
+	// 从节点的复制流由主从链路原样写入 (ProcessMasterCommand)
+	if m.link != nil {
+		return nil
+	}
+	// EXEC 中第一条传播的命令之前写入 MULTI
+	if m.execRunning && !m.execPropagated {
+		m.execPropagated = true
+		m.feed(protocol.ArrayFmt([]string{"MULTI"}))
 	}
-	wg.Wait()
+	m.feed(protocol.ArrayFmt(args))
 	return nil
 }
 
-func (r *replicaInfo) Write(args []string) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if r.conn == nil {
-		return fmt.Errorf("connection is closed")
+// feed 写入积压缓冲区并放入每个副本的输出队列 调用方需持有 writeMu 与 m.Mu 读锁
+func (m *MasterServer) feed(raw []byte) {
+	m.Repl.Feed(raw)
+	if len(m.Replicas) == 0 {
+		return
+	}
+	limit := m.replicaOutputLimit()
+	for _, r := range m.Replicas {
+		if !r.enqueue(raw, true, limit) {
+			// 超过 client-output-buffer-limit 断开 副本重连后尝试部分重同步
+			r.close()
+		}
 	}
-	_, err := r.conn.Write(protocol.ArrayFmt(args))
-	return err
-}
-
-func (m *MasterServer) GetPoolLen() int {
-	return len(m.Replicas)
 }
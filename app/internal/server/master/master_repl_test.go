@@ -2,6 +2,7 @@ package master_test
 
 import (
 	"net"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -15,14 +16,18 @@ import (
 // server/master/master_integration_test.go
 func TestMasterSlaveReplication(t *testing.T) {
 	// 启动主服务器
-	masterCfg := &config.ServerConfig{Dir: "/home/raymond/ryan_Redis/app/internal/server/master/testMaster", Dbfilename: "dump.rdb", Port: "6380", Role: "master"}
+	masterDir := t.TempDir()
+	masterCfg := &config.ServerConfig{Dir: masterDir, Dbfilename: "dump.rdb", Fn: filepath.Join(masterDir, "dump.rdb"), Port: "6380", Role: "master"}
 	master := master.NewMasterServer(masterCfg)
 	go master.Start()
+	time.Sleep(100 * time.Millisecond)
 
 	// 启动从服务器
-	slaveCfg := &config.ServerConfig{Dir: "/home/raymond/ryan_Redis/app/internal/server/master/test_slave", Dbfilename: "dump.rdb", Port: "6381", Role: "slave", ReplicaOf: config.ReplicaConfig{MasterHost: "localhost", MasterPort: "6380"}}
+	slaveDir := t.TempDir()
+	slaveCfg := &config.ServerConfig{Dir: slaveDir, Dbfilename: "dump.rdb", Fn: filepath.Join(slaveDir, "dump.rdb"), Port: "6381", Role: "slave", ReplicaOf: config.ReplicaConfig{MasterHost: "localhost", MasterPort: "6380"}}
 	slave := slave.NewSlaveServer(slaveCfg)
 	go slave.Start()
+	time.Sleep(200 * time.Millisecond)
 
 	// 给主服务器发送命令
 	conn, err := net.Dial("tcp", "localhost:6380")
@@ -45,3 +50,178 @@ func TestMasterSlaveReplication(t *testing.T) {
 	n, _ = slaveConn.Read(buf)
 	assert.Equal(t, "$3\r\nbar\r\n", string(buf[:n]))
 }
+
+// REPLICAOF NO ONE 晋升后 数据保留且旧主节点可以部分重同步
+func TestReplicaOfPromotion(t *testing.T) {
+	masterDir := t.TempDir()
+	masterCfg := &config.ServerConfig{Dir: masterDir, Dbfilename: "dump.rdb", Fn: filepath.Join(masterDir, "dump.rdb"), Port: "6382", Role: "master"}
+	m := master.NewMasterServer(masterCfg)
+	go m.Start()
+	time.Sleep(100 * time.Millisecond)
+
+	slaveDir := t.TempDir()
+	slaveCfg := &config.ServerConfig{Dir: slaveDir, Dbfilename: "dump.rdb", Fn: filepath.Join(slaveDir, "dump.rdb"), Port: "6383", Role: "slave", ReplicaOf: config.ReplicaConfig{MasterHost: "localhost", MasterPort: "6382"}}
+	s := slave.NewSlaveServer(slaveCfg)
+	go s.Start()
+	time.Sleep(200 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "localhost:6382")
+	require.NoError(t, err)
+	defer conn.Close()
+	buf := make([]byte, 1024)
+	conn.Write([]byte("*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"))
+	n, _ := conn.Read(buf)
+	assert.Equal(t, "+OK\r\n", string(buf[:n]))
+	time.Sleep(100 * time.Millisecond)
+
+	// 晋升从节点
+	slaveConn, err := net.Dial("tcp", "localhost:6383")
+	require.NoError(t, err)
+	defer slaveConn.Close()
+	slaveConn.Write([]byte("*3\r\n$9\r\nREPLICAOF\r\n$2\r\nNO\r\n$3\r\nONE\r\n"))
+	n, _ = slaveConn.Read(buf)
+	assert.Equal(t, "+OK\r\n", string(buf[:n]))
+	assert.Equal(t, "master", s.Role())
+
+	// 旧主节点降级为新主节点的从节点
+	conn.Write([]byte("*3\r\n$9\r\nREPLICAOF\r\n$9\r\nlocalhost\r\n$4\r\n6383\r\n"))
+	n, _ = conn.Read(buf)
+	assert.Equal(t, "+OK\r\n", string(buf[:n]))
+	time.Sleep(300 * time.Millisecond)
+
+	slaveConn.Write([]byte("*3\r\n$3\r\nSET\r\n$3\r\nbaz\r\n$3\r\nqux\r\n"))
+	n, _ = slaveConn.Read(buf)
+	assert.Equal(t, "+OK\r\n", string(buf[:n]))
+	time.Sleep(100 * time.Millisecond)
+
+	v, ok := m.Store.Get("baz")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "qux", v)
+	v, _ = s.Store.Get("foo")
+	assert.Equal(t, "bar", v)
+	// 部分重同步: 新主节点的 replid2 为旧主节点的 replid
+	newID, _ := s.Repl.IDs()
+	gotID, _ := m.Repl.IDs()
+	assert.Equal(t, newID, gotID)
+}
+
+// 只读从节点拒绝客户端写入 主节点复制流正常应用
+func TestReadOnlyReplica(t *testing.T) {
+	masterDir := t.TempDir()
+	masterCfg := &config.ServerConfig{Dir: masterDir, Dbfilename: "dump.rdb", Fn: filepath.Join(masterDir, "dump.rdb"), Port: "6384", Role: "master"}
+	m := master.NewMasterServer(masterCfg)
+	go m.Start()
+	time.Sleep(100 * time.Millisecond)
+
+	slaveDir := t.TempDir()
+	slaveCfg := &config.ServerConfig{Dir: slaveDir, Dbfilename: "dump.rdb", Fn: filepath.Join(slaveDir, "dump.rdb"), Port: "6385", Role: "slave", ReplicaOf: config.ReplicaConfig{MasterHost: "localhost", MasterPort: "6384"}, ReplicaReadOnly: true}
+	s := slave.NewSlaveServer(slaveCfg)
+	go s.Start()
+	time.Sleep(200 * time.Millisecond)
+
+	slaveConn, err := net.Dial("tcp", "localhost:6385")
+	require.NoError(t, err)
+	defer slaveConn.Close()
+	buf := make([]byte, 1024)
+	slaveConn.Write([]byte("*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"))
+	n, _ := slaveConn.Read(buf)
+	assert.Equal(t, "-READONLY You can't write against a read only replica.\r\n", string(buf[:n]))
+
+	conn, err := net.Dial("tcp", "localhost:6384")
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.Write([]byte("*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbaz\r\n"))
+	n, _ = conn.Read(buf)
+	assert.Equal(t, "+OK\r\n", string(buf[:n]))
+	time.Sleep(100 * time.Millisecond)
+	v, _ := s.Store.Get("foo")
+	assert.Equal(t, "baz", v)
+
+	// replica-read-only no 时允许写入
+	slaveConn.Write([]byte("*4\r\n$6\r\nCONFIG\r\n$3\r\nSET\r\n$17\r\nreplica-read-only\r\n$2\r\nno\r\n"))
+	n, _ = slaveConn.Read(buf)
+	assert.Equal(t, "+OK\r\n", string(buf[:n]))
+	slaveConn.Write([]byte("*3\r\n$3\r\nSET\r\n$5\r\nlocal\r\n$1\r\n1\r\n"))
+	n, _ = slaveConn.Read(buf)
+	assert.Equal(t, "+OK\r\n", string(buf[:n]))
+}
+
+// 链式复制: 从节点的下游副本共享主节点的复制ID与偏移量
+func TestChainedReplication(t *testing.T) {
+	newCfg := func(port, masterPort string) *config.ServerConfig {
+		dir := t.TempDir()
+		cfg := &config.ServerConfig{Dir: dir, Dbfilename: "dump.rdb", Fn: filepath.Join(dir, "dump.rdb"), Port: port, Role: "master", ReplicaReadOnly: true}
+		if masterPort != "" {
+			cfg.Role = "slave"
+			cfg.ReplicaOf = config.ReplicaConfig{MasterHost: "localhost", MasterPort: masterPort}
+		}
+		return cfg
+	}
+	m := master.NewMasterServer(newCfg("6386", ""))
+	go m.Start()
+	time.Sleep(100 * time.Millisecond)
+	s1 := slave.NewSlaveServer(newCfg("6387", "6386"))
+	go s1.Start()
+	time.Sleep(200 * time.Millisecond)
+	s2 := slave.NewSlaveServer(newCfg("6388", "6387"))
+	go s2.Start()
+	time.Sleep(300 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "localhost:6386")
+	require.NoError(t, err)
+	defer conn.Close()
+	buf := make([]byte, 1024)
+	conn.Write([]byte("*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"))
+	n, _ := conn.Read(buf)
+	assert.Equal(t, "+OK\r\n", string(buf[:n]))
+	time.Sleep(200 * time.Millisecond)
+
+	v, _ := s2.Store.Get("foo")
+	assert.Equal(t, "bar", v)
+	masterID, masterOffset := m.Repl.IDs()
+	subID, subOffset := s2.Repl.IDs()
+	assert.Equal(t, masterID, subID)
+	assert.Equal(t, masterOffset, subOffset)
+}
+
+func TestFailoverCommand(t *testing.T) {
+	masterDir := t.TempDir()
+	masterCfg := &config.ServerConfig{Dir: masterDir, Dbfilename: "dump.rdb", Fn: filepath.Join(masterDir, "dump.rdb"), Port: "6389", Role: "master", ReplicaReadOnly: true}
+	m := master.NewMasterServer(masterCfg)
+	go m.Start()
+	time.Sleep(100 * time.Millisecond)
+
+	slaveDir := t.TempDir()
+	slaveCfg := &config.ServerConfig{Dir: slaveDir, Dbfilename: "dump.rdb", Fn: filepath.Join(slaveDir, "dump.rdb"), Port: "6390", Role: "slave", ReplicaOf: config.ReplicaConfig{MasterHost: "127.0.0.1", MasterPort: "6389"}}
+	s := slave.NewSlaveServer(slaveCfg)
+	go s.Start()
+	time.Sleep(200 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "localhost:6389")
+	require.NoError(t, err)
+	defer conn.Close()
+	buf := make([]byte, 1024)
+	conn.Write([]byte("*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"))
+	n, _ := conn.Read(buf)
+	assert.Equal(t, "+OK\r\n", string(buf[:n]))
+
+	conn.Write([]byte("*4\r\n$8\r\nFAILOVER\r\n$2\r\nTO\r\n$9\r\n127.0.0.1\r\n$4\r\n6390\r\n"))
+	n, _ = conn.Read(buf)
+	assert.Equal(t, "+OK\r\n", string(buf[:n]))
+
+	// 切换期间的写命令等待 完成后旧主节点已是只读从节点
+	conn.Write([]byte("*3\r\n$3\r\nSET\r\n$3\r\nbaz\r\n$3\r\nqux\r\n"))
+	n, _ = conn.Read(buf)
+	assert.Equal(t, "-READONLY You can't write against a read only replica.\r\n", string(buf[:n]))
+
+	assert.Equal(t, "slave", m.Role())
+	assert.Equal(t, "master", s.Role())
+	require.Contains(t, m.ReplicationInfo(), "master_failover_state:no-failover")
+	v, _ := s.Store.Get("foo")
+	assert.Equal(t, "bar", v)
+	// 无数据丢失: 双方复制历史一致
+	newID, newOffset := s.Repl.IDs()
+	gotID, gotOffset := m.Repl.IDs()
+	assert.Equal(t, newID, gotID)
+	assert.Equal(t, newOffset, gotOffset)
+}
@@ -0,0 +1,263 @@
+package master_test
+
+import (
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/cluster"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/server/master"
+	"github.com/go-playground/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// respClient 按 RESP 收发命令 错误回复以 protocol.ErrorReply 返回
+type respClient struct {
+	conn net.Conn
+	rd   *protocol.Reader
+}
+
+func dialResp(t *testing.T, port string) *respClient {
+	conn, err := net.Dial("tcp", "localhost:"+port)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &respClient{conn: conn, rd: protocol.NewReader(conn)}
+}
+
+func (c *respClient) do(t *testing.T, args ...string) (any, error) {
+	_, err := c.conn.Write(protocol.ArrayFmt(args))
+	require.NoError(t, err)
+	c.conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	v, err := c.rd.ReadValue()
+	if _, ok := err.(protocol.ErrorReply); err != nil && !ok {
+		t.Fatalf("%v: %v", args, err)
+	}
+	return v, err
+}
+
+func startMaster(t *testing.T, port string, clusterEnabled bool) *master.MasterServer {
+	dir := t.TempDir()
+	cfg := &config.ServerConfig{Dir: dir, Dbfilename: "dump.rdb", Fn: filepath.Join(dir, "dump.rdb"), Port: port, Role: "master",
+		ClusterEnabled: clusterEnabled, ClusterConfigFile: "nodes.conf", ClusterNodeTimeout: 5000}
+	m := master.NewMasterServer(cfg)
+	go m.Start()
+	time.Sleep(100 * time.Millisecond)
+	return m
+}
+
+func TestDumpRestore(t *testing.T) {
+	m := startMaster(t, "6393", false)
+	c := dialResp(t, "6393")
+
+	c.do(t, "RPUSH", "list", "a", "b", "c")
+	payload, err := c.do(t, "DUMP", "list")
+	require.NoError(t, err)
+	v, err := c.do(t, "DUMP", "nosuchkey")
+	assert.Equal(t, nil, v)
+	assert.Equal(t, nil, err)
+
+	v, _ = c.do(t, "RESTORE", "copy", "0", payload.(string))
+	assert.Equal(t, "OK", v)
+	v, _ = c.do(t, "LRANGE", "copy", "0", "-1")
+	assert.Equal(t, []any{"a", "b", "c"}, v)
+	_, ok := m.Store.ExpireAt("copy")
+	assert.Equal(t, false, ok)
+
+	// 已存在的键需要 REPLACE
+	_, err = c.do(t, "RESTORE", "copy", "0", payload.(string))
+	assert.Equal(t, protocol.ErrorReply("BUSYKEY Target key name already exists."), err)
+	c.do(t, "SET", "str", "x")
+	v, _ = c.do(t, "RESTORE", "str", "5000", payload.(string), "REPLACE")
+	assert.Equal(t, "OK", v)
+	v, _ = c.do(t, "TYPE", "str")
+	assert.Equal(t, "list", v)
+	at, ok := m.Store.ExpireAt("str")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, true, time.Until(at) > 4*time.Second && time.Until(at) <= 5*time.Second)
+
+	// ABSTTL: ttl 为 Unix 毫秒时间戳 已经过去的时间不创建键
+	deadline := time.Now().Add(time.Minute).UnixMilli()
+	v, _ = c.do(t, "RESTORE", "abs", strconv.FormatInt(deadline, 10), payload.(string), "ABSTTL")
+	assert.Equal(t, "OK", v)
+	at, _ = m.Store.ExpireAt("abs")
+	assert.Equal(t, deadline, at.UnixMilli())
+	v, _ = c.do(t, "RESTORE", "past", "1000", payload.(string), "ABSTTL")
+	assert.Equal(t, "OK", v)
+	v, _ = c.do(t, "TYPE", "past")
+	assert.Equal(t, "none", v)
+
+	v, _ = c.do(t, "RESTORE", "idle", "0", payload.(string), "IDLETIME", "100")
+	assert.Equal(t, "OK", v)
+	v, _ = c.do(t, "OBJECT", "IDLETIME", "idle")
+	assert.Equal(t, int64(100), v)
+
+	// 篡改 CRC 或版本号的载荷被拒绝
+	bad := []byte(payload.(string))
+	bad[len(bad)-1] ^= 0xff
+	_, err = c.do(t, "RESTORE", "bad", "0", string(bad))
+	assert.Equal(t, protocol.ErrorReply("ERR DUMP payload version or checksum are wrong"), err)
+	bad = []byte(payload.(string))
+	bad[len(bad)-10] = 0xff
+	_, err = c.do(t, "RESTORE", "bad", "0", string(bad))
+	assert.Equal(t, protocol.ErrorReply("ERR DUMP payload version or checksum are wrong"), err)
+	v, _ = c.do(t, "TYPE", "bad")
+	assert.Equal(t, "none", v)
+
+	_, err = c.do(t, "RESTORE", "bad", "-1", payload.(string))
+	assert.Equal(t, protocol.ErrorReply("ERR Invalid TTL value, must be >= 0"), err)
+}
+
+func TestMigrate(t *testing.T) {
+	src := startMaster(t, "6394", false)
+	dst := startMaster(t, "6395", false)
+	c := dialResp(t, "6394")
+	d := dialResp(t, "6395")
+
+	c.do(t, "SET", "a", "1", "PX", "60000")
+	c.do(t, "SADD", "b", "x", "y")
+	v, _ := c.do(t, "MIGRATE", "127.0.0.1", "6395", "a", "0", "1000")
+	assert.Equal(t, "OK", v)
+	assert.Equal(t, false, src.Store.Exists("a"))
+	v, _ = d.do(t, "GET", "a")
+	assert.Equal(t, "1", v)
+	// 过期时间随键迁移
+	at, ok := dst.Store.ExpireAt("a")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, true, time.Until(at) > 50*time.Second)
+
+	v, _ = c.do(t, "MIGRATE", "127.0.0.1", "6395", "a", "0", "1000")
+	assert.Equal(t, "NOKEY", v)
+
+	// COPY 保留本地键; 目标已存在时需要 REPLACE
+	v, _ = c.do(t, "MIGRATE", "127.0.0.1", "6395", "b", "0", "1000", "COPY")
+	assert.Equal(t, "OK", v)
+	assert.Equal(t, true, src.Store.Exists("b"))
+	_, err := c.do(t, "MIGRATE", "127.0.0.1", "6395", "b", "0", "1000")
+	assert.Equal(t, true, strings.HasPrefix(err.Error(), "ERR Target instance replied with error: BUSYKEY"))
+	assert.Equal(t, true, src.Store.Exists("b"))
+	c.do(t, "SADD", "b", "z")
+	v, _ = c.do(t, "MIGRATE", "127.0.0.1", "6395", "b", "0", "1000", "REPLACE")
+	assert.Equal(t, "OK", v)
+	v, _ = d.do(t, "SCARD", "b")
+	assert.Equal(t, int64(3), v)
+
+	// KEYS 一次迁移多个键 不存在的键被跳过
+	c.do(t, "SET", "k1", "v1")
+	c.do(t, "SET", "k2", "v2")
+	_, err = c.do(t, "MIGRATE", "127.0.0.1", "6395", "k1", "0", "1000", "KEYS", "k2")
+	assert.Equal(t, protocol.ErrorReply("ERR When using MIGRATE KEYS option, the key argument must be set to the empty string"), err)
+	v, _ = c.do(t, "MIGRATE", "127.0.0.1", "6395", "", "0", "1000", "KEYS", "k1", "missing", "k2")
+	assert.Equal(t, "OK", v)
+	v, _ = d.do(t, "MGET", "k1", "k2")
+	assert.Equal(t, []any{"v1", "v2"}, v)
+	assert.Equal(t, 0, len(src.Store.Keys()))
+
+	_, err = c.do(t, "MIGRATE", "127.0.0.1", "6395", "a", "1", "1000")
+	assert.Equal(t, protocol.ErrorReply("ERR DB index is out of range"), err)
+}
+
+// 槽迁移过程: 源节点 MIGRATING, 目标节点 IMPORTING
+// 源节点上已迁走的键回复 ASK; 目标节点只在 ASKING 之后的一条命令中接受该槽的键
+func TestClusterMigrateAsk(t *testing.T) {
+	startMaster(t, "6396", true)
+	startMaster(t, "6397", true)
+	a := dialResp(t, "6396")
+	b := dialResp(t, "6397")
+
+	v, _ := a.do(t, "CLUSTER", "ADDSLOTSRANGE", "0", strconv.Itoa(cluster.SlotCount-1))
+	assert.Equal(t, "OK", v)
+	v, _ = a.do(t, "CLUSTER", "MEET", "127.0.0.1", "6397")
+	assert.Equal(t, "OK", v)
+	idA, _ := a.do(t, "CLUSTER", "MYID")
+	idB, _ := b.do(t, "CLUSTER", "MYID")
+
+	// 等待握手完成 双方都认识对方
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		na, _ := a.do(t, "CLUSTER", "NODES")
+		nb, _ := b.do(t, "CLUSTER", "NODES")
+		if strings.Contains(na.(string), idB.(string)) && strings.Contains(nb.(string), idA.(string)) &&
+			!strings.Contains(na.(string)+nb.(string), "handshake") {
+			break
+		}
+		require.True(t, time.Now().Before(deadline), "cluster handshake timed out")
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	slot := strconv.Itoa(cluster.KeySlot("{user}"))
+	a.do(t, "SET", "{user}:1", "alice")
+	a.do(t, "SET", "{user}:2", "bob")
+	_, err := b.do(t, "GET", "{user}:1")
+	assert.Equal(t, protocol.ErrorReply("MOVED "+slot+" 127.0.0.1:6396"), err)
+
+	v, _ = b.do(t, "CLUSTER", "SETSLOT", slot, "IMPORTING", idA.(string))
+	assert.Equal(t, "OK", v)
+	v, _ = a.do(t, "CLUSTER", "SETSLOT", slot, "MIGRATING", idB.(string))
+	assert.Equal(t, "OK", v)
+
+	v, _ = a.do(t, "MIGRATE", "127.0.0.1", "6397", "{user}:1", "0", "1000")
+	assert.Equal(t, "OK", v)
+
+	// 仍在源节点的键照常处理 已迁走的键 ASK 到目标节点
+	v, _ = a.do(t, "GET", "{user}:2")
+	assert.Equal(t, "bob", v)
+	_, err = a.do(t, "GET", "{user}:1")
+	assert.Equal(t, protocol.ErrorReply("ASK "+slot+" 127.0.0.1:6397"), err)
+
+	// 目标节点: 没有 ASKING 时 MOVED 回源节点; ASKING 只对下一条命令有效
+	_, err = b.do(t, "GET", "{user}:1")
+	assert.Equal(t, protocol.ErrorReply("MOVED "+slot+" 127.0.0.1:6396"), err)
+	v, _ = b.do(t, "ASKING")
+	assert.Equal(t, "OK", v)
+	v, _ = b.do(t, "GET", "{user}:1")
+	assert.Equal(t, "alice", v)
+	_, err = b.do(t, "GET", "{user}:1")
+	assert.Equal(t, protocol.ErrorReply("MOVED "+slot+" 127.0.0.1:6396"), err)
+
+	// 迁移完成后槽归目标节点
+	v, _ = a.do(t, "MIGRATE", "127.0.0.1", "6397", "{user}:2", "0", "1000")
+	assert.Equal(t, "OK", v)
+	b.do(t, "CLUSTER", "SETSLOT", slot, "NODE", idB.(string))
+	a.do(t, "CLUSTER", "SETSLOT", slot, "NODE", idB.(string))
+	_, err = a.do(t, "GET", "{user}:1")
+	assert.Equal(t, protocol.ErrorReply("MOVED "+slot+" 127.0.0.1:6397"), err)
+	v, _ = b.do(t, "GET", "{user}:2")
+	assert.Equal(t, "bob", v)
+}
+
+// DUMP/MIGRATE 与原地修改列表的写命令并发执行 (配合 -race 检查)
+func TestDumpConcurrentWithWrites(t *testing.T) {
+	startMaster(t, "6407", false)
+	startMaster(t, "6408", false)
+	w := dialResp(t, "6407")
+	c := dialResp(t, "6407")
+	dst := dialResp(t, "6408")
+
+	const rounds = 200
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < rounds; i++ {
+			w.do(t, "RPUSH", "l", strconv.Itoa(i))
+			w.do(t, "HSET", "h", strconv.Itoa(i), "v")
+		}
+	}()
+	for i := 0; i < rounds; i++ {
+		c.do(t, "DUMP", "l")
+		c.do(t, "DUMP", "h")
+		if i%20 == 0 {
+			c.do(t, "MIGRATE", "localhost", "6408", "", "0", "1000", "COPY", "REPLACE", "KEYS", "l", "h")
+		}
+	}
+	<-done
+
+	// 载荷完整 可以恢复出写入后的值
+	v, _ := c.do(t, "DUMP", "l")
+	dst.do(t, "RESTORE", "copy", "0", v.(string))
+	v, _ = dst.do(t, "LLEN", "copy")
+	assert.Equal(t, int64(rounds), v)
+}
@@ -0,0 +1,195 @@
+package master
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/command"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+)
+
+var (
+	errNestedMulti    = errors.New("ERR MULTI calls can not be nested")
+	errDiscardNoMulti = errors.New("ERR DISCARD without MULTI")
+)
+
+const errExecAbort = "EXECABORT Transaction discarded because of previous errors."
+
+// transaction MULTI 之后排队的命令 只由所属连接的 goroutine 访问
+type transaction struct {
+	queued []queuedCommand
+	dirty  bool // 排队时出错 (未知命令、重定向等) EXEC 放弃整个事务
+}
+
+type queuedCommand struct {
+	handler command.Handler
+	args    []string
+}
+
+var _ command.Transactor = (*MasterServer)(nil)
+
+// Multi 开始事务
+func (m *MasterServer) Multi(conn net.Conn) error {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+	if _, ok := m.txs[conn]; ok {
+		return errNestedMulti
+	}
+	m.txs[conn] = &transaction{}
+	return nil
+}
+
+// Discard 放弃排队的命令
+func (m *MasterServer) Discard(conn net.Conn) error {
+	if m.takeTx(conn) == nil {
+		return errDiscardNoMulti
+	}
+	return nil
+}
+
+// txOf 连接上进行中的事务 没有时返回 nil
+func (m *MasterServer) txOf(conn net.Conn) *transaction {
+	if conn == nil {
+		return nil
+	}
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+	return m.txs[conn]
+}
+
+// takeTx 返回并结束连接上的事务
+func (m *MasterServer) takeTx(conn net.Conn) *transaction {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+	tx := m.txs[conn]
+	delete(m.txs, conn)
+	return tx
+}
+
+// queue 事务中的命令入队并回复 QUEUED 能在排队时发现的错误直接回复并标记事务
+func (m *MasterServer) queue(rw protocol.ResponseWriter, tx *transaction, handler command.Handler, cmd string, args []string) error {
+	if handler == nil {
+		tx.dirty = true
+		var b strings.Builder
+		for _, arg := range args[1:] {
+			b.WriteString("'" + arg + "' ")
+		}
+		return rw.WriteError("ERR unknown command '" + cmd + "', with args beginning with: " + b.String())
+	}
+	if m.Cluster != nil {
+		asking := m.takeAsking(rw.Conn(), handler) || command.FlagsOf(handler)&command.FlagAsking != 0
+		if err := m.Cluster.Route(command.KeysOf(handler, args), asking, m.keyExists); err != nil {
+			tx.dirty = true
+			return rw.WriteError(err.Error())
+		}
+	}
+	if command.FlagsOf(handler)&command.FlagWrite != 0 && m.isReadOnlyReplica() {
+		tx.dirty = true
+		return rw.WriteError("READONLY You can't write against a read only replica.")
+	}
+	tx.queued = append(tx.queued, queuedCommand{handler: handler, args: args})
+	return rw.WriteSimpleString("QUEUED")
+}
+
+// Exec 在写锁内依次执行排队的命令, 期间不会穿插其他客户端的写命令与复制流
+// 阻塞命令按非阻塞方式执行; 被写入键上的等待者在整个事务完成后才被服务,
+// 事务中之后的命令 (如 LPUSH 之后的 LPOP) 先看到事务自己写入的数据
+func (m *MasterServer) Exec(rw protocol.ResponseWriter) error {
+	tx := m.takeTx(rw.Conn())
+	if tx == nil {
+		return rw.WriteError("ERR EXEC without MULTI")
+	}
+	if tx.dirty {
+		return rw.WriteError(errExecAbort)
+	}
+
+	write := false
+	for _, q := range tx.queued {
+		write = write || command.FlagsOf(q.handler)&command.FlagWrite != 0
+	}
+	m.lockWrites()
+	defer m.writeMu.Unlock()
+	if write && m.isReadOnlyReplica() {
+		return rw.WriteError("EXECABORT Transaction discarded because of: READONLY You can't write against a read only replica.")
+	}
+	if write && !m.enoughGoodReplicas() {
+		return rw.WriteError("EXECABORT Transaction discarded because of: NOREPLICAS Not enough good replicas to write.")
+	}
+
+	ctx := context.Background()
+	buf := protocol.NewBufferResponseWriter(rw.Conn())
+	replies := make([]any, 0, len(tx.queued))
+	var written []string
+	m.execRunning = true
+	for _, q := range tx.queued {
+		if err := q.handler.Execute(ctx, buf, q.args); err != nil && buf.Len() == 0 {
+			buf.WriteError("ERR " + err.Error())
+		}
+		replies = append(replies, buf.Take())
+		if command.FlagsOf(q.handler)&command.FlagWrite != 0 {
+			written = append(written, command.KeysOf(q.handler, q.args)...)
+		}
+	}
+	m.execRunning = false
+	// 传播过命令时以 EXEC 结束 副本整体应用事务; 之后服务等待者的传播在 EXEC 之后
+	if m.execPropagated {
+		m.execPropagated = false
+		m.Mu.RLock()
+		m.feed(protocol.ArrayFmt([]string{"EXEC"}))
+		m.Mu.RUnlock()
+	}
+	m.Blocked.SignalKeys(written...)
+	m.Blocked.ServeReady()
+	return rw.WriteValue(replies)
+}
+
+// masterTx 复制流中的事务 只由持有 writeMu 的复制链路访问
+type masterTx struct {
+	conn   net.Conn // 所属的主从链路 链路重建后丢弃
+	queued []queuedCommand
+	stream []byte // MULTI 起的原始字节 EXEC 后一起写入积压缓冲区
+}
+
+// applyMaster 执行复制流中的一条命令 返回要写入积压缓冲区的字节 (nil 表示暂不写入)
+// MULTI 之后的命令排队, 收到 EXEC 时一起执行并写入; 在此之前偏移量停在 MULTI 之前,
+// 链路中断时丢弃排队的命令, 重连后从 MULTI 开始部分重同步, 不会只应用事务的一部分
+func (m *MasterServer) applyMaster(rw protocol.ResponseWriter, cmd string, args []string, stream []byte) ([]byte, error) {
+	if tx := m.masterTx; tx != nil && tx.conn != rw.Conn() {
+		log.Printf("Discard unfinished replicated transaction (%d commands)", len(tx.queued))
+		m.masterTx = nil
+	}
+	tx := m.masterTx
+	switch {
+	case strings.EqualFold(cmd, "MULTI"):
+		m.masterTx = &masterTx{conn: rw.Conn(), stream: stream}
+		return nil, nil
+	case tx == nil:
+		return stream, m.dispatch(context.Background(), rw, cmd, args, true)
+	case !strings.EqualFold(cmd, "EXEC"):
+		if handler, ok := m.Registry.GetHandler(cmd); ok {
+			tx.queued = append(tx.queued, queuedCommand{handler: handler, args: args})
+		} else {
+			log.Printf("ERR unknown command '%s' in replicated transaction", cmd)
+		}
+		tx.stream = append(tx.stream, stream...)
+		return nil, nil
+	}
+
+	m.masterTx = nil
+	ctx := context.Background()
+	var written []string
+	for _, q := range tx.queued {
+		if err := q.handler.Execute(ctx, rw, q.args); err != nil {
+			log.Printf("Apply replicated command error: %v, cmd : %s", err, q.args[0])
+		}
+		if command.FlagsOf(q.handler)&command.FlagWrite != 0 {
+			written = append(written, command.KeysOf(q.handler, q.args)...)
+		}
+	}
+	m.Blocked.SignalKeys(written...)
+	m.Blocked.ServeReady()
+	return append(tx.stream, stream...), nil
+}
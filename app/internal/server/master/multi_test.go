@@ -0,0 +1,218 @@
+package master_test
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/rdb"
+	"github.com/go-playground/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiExec(t *testing.T) {
+	startMaster(t, "6400", false)
+	c := dialResp(t, "6400")
+
+	v, _ := c.do(t, "MULTI")
+	assert.Equal(t, "OK", v)
+	_, err := c.do(t, "MULTI")
+	assert.Equal(t, protocol.ErrorReply("ERR MULTI calls can not be nested"), err)
+	for _, args := range [][]string{{"SET", "a", "1"}, {"INCR", "a"}, {"GET", "a"}, {"RPUSH", "l", "x"}, {"INCR", "l"}, {"GET", "missing"}} {
+		v, _ = c.do(t, args...)
+		assert.Equal(t, "QUEUED", v)
+	}
+	// 执行期间的错误只影响出错的命令
+	v, _ = c.do(t, "EXEC")
+	assert.Equal(t, []any{"OK", int64(2), "2", int64(1),
+		protocol.ErrorReply("WRONGTYPE Operation against a key holding the wrong kind of value"), nil}, v)
+
+	_, err = c.do(t, "EXEC")
+	assert.Equal(t, protocol.ErrorReply("ERR EXEC without MULTI"), err)
+	_, err = c.do(t, "DISCARD")
+	assert.Equal(t, protocol.ErrorReply("ERR DISCARD without MULTI"), err)
+
+	c.do(t, "MULTI")
+	c.do(t, "SET", "a", "discarded")
+	v, _ = c.do(t, "DISCARD")
+	assert.Equal(t, "OK", v)
+	v, _ = c.do(t, "GET", "a")
+	assert.Equal(t, "2", v)
+
+	// 排队时的错误使整个事务放弃 连接保持可用
+	c.do(t, "MULTI")
+	c.do(t, "SET", "a", "3")
+	_, err = c.do(t, "NOSUCHCMD", "x")
+	assert.Equal(t, protocol.ErrorReply("ERR unknown command 'NOSUCHCMD', with args beginning with: 'x' "), err)
+	_, err = c.do(t, "EXEC")
+	assert.Equal(t, protocol.ErrorReply("EXECABORT Transaction discarded because of previous errors."), err)
+	v, _ = c.do(t, "GET", "a")
+	assert.Equal(t, "2", v)
+
+	// 阻塞命令在事务中不阻塞
+	c.do(t, "MULTI")
+	c.do(t, "BLPOP", "empty", "0")
+	v, _ = c.do(t, "EXEC")
+	assert.Equal(t, []any{nil}, v)
+}
+
+// 阻塞在键上的客户端在 EXEC 完成后才被服务
+func TestMultiExecWakesBlocked(t *testing.T) {
+	startMaster(t, "6401", false)
+	c := dialResp(t, "6401")
+	b := dialResp(t, "6401")
+
+	blpop := func() chan any {
+		ch := make(chan any, 1)
+		go func() {
+			v, _ := b.do(t, "BLPOP", "q", "2")
+			ch <- v
+		}()
+		time.Sleep(100 * time.Millisecond)
+		return ch
+	}
+
+	// 事务自己弹出了推入的元素 等待者继续阻塞
+	ch := blpop()
+	c.do(t, "MULTI")
+	c.do(t, "RPUSH", "q", "x")
+	c.do(t, "LPOP", "q")
+	v, _ := c.do(t, "EXEC")
+	assert.Equal(t, []any{int64(1), "x"}, v)
+	select {
+	case v := <-ch:
+		t.Fatalf("blocked client served with %v", v)
+	case <-time.After(200 * time.Millisecond):
+	}
+	c.do(t, "RPUSH", "q", "y")
+	assert.Equal(t, []any{"q", "y"}, <-ch)
+
+	// 事务结束后按最终状态服务等待者
+	ch = blpop()
+	c.do(t, "MULTI")
+	c.do(t, "RPUSH", "q", "a", "b", "c")
+	c.do(t, "LPOP", "q")
+	v, _ = c.do(t, "EXEC")
+	assert.Equal(t, []any{int64(3), "a"}, v)
+	assert.Equal(t, []any{"q", "b"}, <-ch)
+	v, _ = c.do(t, "LRANGE", "q", "0", "-1")
+	assert.Equal(t, []any{"c"}, v)
+}
+
+// EXEC 中传播的命令以 MULTI ... EXEC 包裹 只读事务不传播
+func TestMultiExecPropagation(t *testing.T) {
+	startMaster(t, "6409", false)
+	c := dialResp(t, "6409")
+	b := dialResp(t, "6409")
+	r := dialResp(t, "6409")
+
+	r.conn.Write(protocol.ArrayFmt([]string{"PSYNC", "?", "-1"}))
+	line, err := r.rd.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, true, strings.HasPrefix(line, "+FULLRESYNC"))
+	_, err = r.rd.ReadBulkPayload()
+	require.NoError(t, err)
+	next := func() []string {
+		for {
+			r.conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+			_, args, err := r.rd.ReadRequest()
+			require.NoError(t, err)
+			if args[0] != "PING" {
+				return args
+			}
+		}
+	}
+
+	for _, args := range [][]string{{"MULTI"}, {"SET", "a", "1"}, {"GET", "a"}, {"INCR", "a"}, {"EXEC"}} {
+		c.do(t, args...)
+	}
+	assert.Equal(t, []string{"MULTI"}, next())
+	assert.Equal(t, "SET", next()[0])
+	assert.Equal(t, "INCR", next()[0])
+	assert.Equal(t, []string{"EXEC"}, next())
+
+	c.do(t, "MULTI")
+	c.do(t, "GET", "a")
+	c.do(t, "EXEC")
+	c.do(t, "SET", "b", "2")
+	assert.Equal(t, "SET", next()[0])
+
+	// 被事务唤醒的等待者在 EXEC 之后传播
+	ch := make(chan any, 1)
+	go func() {
+		v, _ := b.do(t, "BLPOP", "q", "0")
+		ch <- v
+	}()
+	time.Sleep(100 * time.Millisecond)
+	c.do(t, "MULTI")
+	c.do(t, "RPUSH", "q", "x", "y")
+	c.do(t, "EXEC")
+	assert.Equal(t, []any{"q", "x"}, <-ch)
+	assert.Equal(t, []string{"MULTI"}, next())
+	assert.Equal(t, "RPUSH", next()[0])
+	assert.Equal(t, []string{"EXEC"}, next())
+	assert.Equal(t, true, next()[0] != "EXEC")
+}
+
+// acceptReplica 模拟主节点 完成复制链路的握手并返回 PSYNC 的参数
+func acceptReplica(t *testing.T, ln net.Listener) (net.Conn, []string) {
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	rd := protocol.NewReader(conn)
+	for {
+		_, args, err := rd.ReadRequest()
+		require.NoError(t, err)
+		if args[0] == "PSYNC" {
+			return conn, args
+		}
+		conn.Write([]byte("+OK\r\n"))
+	}
+}
+
+// 复制流中的事务在收到 EXEC 后才应用; 链路在事务中断开时偏移量停在 MULTI 之前
+func TestReplicaAppliesTransactionAtomically(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:6411")
+	require.NoError(t, err)
+	defer ln.Close()
+	startMaster(t, "6410", false)
+	c := dialResp(t, "6410")
+	v, _ := c.do(t, "REPLICAOF", "localhost", "6411")
+	assert.Equal(t, "OK", v)
+
+	const replID = "0123456789012345678901234567890123456789"
+	conn, psync := acceptReplica(t, ln)
+	assert.Equal(t, []string{"PSYNC", "?", "-1"}, psync)
+	var payload bytes.Buffer
+	require.NoError(t, rdb.WriteRDB(&payload, nil))
+	conn.Write([]byte("+FULLRESYNC " + replID + " 100\r\n$" + strconv.Itoa(payload.Len()) + "\r\n"))
+	conn.Write(payload.Bytes())
+	conn.Write(protocol.ArrayFmt([]string{"SET", "n", "1"}))
+	conn.Write(protocol.ArrayFmt([]string{"MULTI"}))
+	conn.Write(protocol.ArrayFmt([]string{"SET", "k", "v"}))
+	time.Sleep(200 * time.Millisecond)
+	v, _ = c.do(t, "GET", "n")
+	assert.Equal(t, "1", v)
+	v, _ = c.do(t, "GET", "k")
+	assert.Equal(t, nil, v)
+
+	// 重连后从 MULTI 开始部分重同步
+	conn.Close()
+	conn, psync = acceptReplica(t, ln)
+	offset := 100 + len(protocol.ArrayFmt([]string{"SET", "n", "1"}))
+	assert.Equal(t, []string{"PSYNC", replID, strconv.Itoa(offset + 1)}, psync)
+	conn.Write([]byte("+CONTINUE\r\n"))
+	conn.Write(protocol.ArrayFmt([]string{"MULTI"}))
+	conn.Write(protocol.ArrayFmt([]string{"SET", "k", "v"}))
+	conn.Write(protocol.ArrayFmt([]string{"INCR", "n"}))
+	conn.Write(protocol.ArrayFmt([]string{"EXEC"}))
+	time.Sleep(200 * time.Millisecond)
+	v, _ = c.do(t, "GET", "k")
+	assert.Equal(t, "v", v)
+	v, _ = c.do(t, "GET", "n")
+	assert.Equal(t, "2", v)
+}
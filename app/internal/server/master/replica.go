@@ -0,0 +1,266 @@
+package master
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// replicaInfo 一个下游副本
+// 传播时只把数据放入副本自己的输出队列, 由独立的写协程按顺序发送, 慢副本不会阻塞写命令
+type replicaInfo struct {
+	conn net.Conn
+	addr string
+
+	ip        string
+	port      string       // REPLCONF listening-port 上报的端口
+	ackOffset atomic.Int64 // REPLCONF ACK 上报的偏移量
+	ackTime   atomic.Int64 // 最近一次 ACK 的时间(UnixNano) 用于计算延迟
+
+	// 输出队列 保护以下字段
+	qmu       sync.Mutex
+	cond      *sync.Cond
+	queue     [][]byte
+	pending   int64     // 尚未写出的复制流字节数 (不含 RDB 快照)
+	softSince time.Time // 开始超过软限制的时间
+	closed    bool
+}
+
+// outputLimit client-output-buffer-limit replica <hard> <soft> <soft-seconds>
+type outputLimit struct {
+	hard, soft  int64
+	softSeconds int
+}
+
+func newReplicaInfo(conn net.Conn) *replicaInfo {
+	r := &replicaInfo{conn: conn, addr: conn.RemoteAddr().String()}
+	r.cond = sync.NewCond(&r.qmu)
+	r.ackTime.Store(time.Now().UnixNano())
+	r.ip, r.port, _ = net.SplitHostPort(r.addr)
+	return r
+}
+
+// lag 距离最近一次 ACK 的秒数
+func (r *replicaInfo) lag(now time.Time) int64 {
+	return int64(now.Sub(time.Unix(0, r.ackTime.Load())) / time.Second)
+}
+
+// enqueue 放入输出队列 countable 为 false 的数据(RDB 快照/同步头)不计入输出缓冲区限制
+// 超过硬限制或持续超过软限制时返回 false
+func (r *replicaInfo) enqueue(p []byte, countable bool, limit outputLimit) bool {
+	r.qmu.Lock()
+	defer r.qmu.Unlock()
+	if r.closed {
+		return true
+	}
+	r.queue = append(r.queue, p)
+	if countable {
+		r.pending += int64(len(p))
+	}
+	r.cond.Signal()
+
+	if limit.hard > 0 && r.pending > limit.hard {
+		log.Printf("Replica %s output buffer %d exceeds hard limit %d", r.addr, r.pending, limit.hard)
+		return false
+	}
+	if limit.soft > 0 && r.pending > limit.soft {
+		if r.softSince.IsZero() {
+			r.softSince = time.Now()
+		} else if time.Since(r.softSince) > time.Duration(limit.softSeconds)*time.Second {
+			log.Printf("Replica %s output buffer %d exceeds soft limit %d for %ds", r.addr, r.pending, limit.soft, limit.softSeconds)
+			return false
+		}
+	} else {
+		r.softSince = time.Time{}
+	}
+	return true
+}
+
+// writeLoop 副本专属的写协程 按入队顺序发送
+func (r *replicaInfo) writeLoop() {
+	for {
+		r.qmu.Lock()
+		for len(r.queue) == 0 && !r.closed {
+			r.cond.Wait()
+		}
+		if r.closed {
+			r.qmu.Unlock()
+			return
+		}
+		chunks := r.queue
+		r.queue = nil
+		r.qmu.Unlock()
+
+		var n int64
+		for _, c := range chunks {
+			n += int64(len(c))
+		}
+		bufs := net.Buffers(chunks)
+		if _, err := bufs.WriteTo(r.conn); err != nil {
+			log.Printf("Write to replica %s Error: %s", r.addr, err)
+			r.close()
+			return
+		}
+
+		r.qmu.Lock()
+		r.pending = max(r.pending-n, 0)
+		if r.pending == 0 {
+			r.softSince = time.Time{}
+		}
+		r.qmu.Unlock()
+	}
+}
+
+// close 停止写协程并断开连接 连接的读循环随后调用 RemoveReplica
+func (r *replicaInfo) close() {
+	r.qmu.Lock()
+	if r.closed {
+		r.qmu.Unlock()
+		return
+	}
+	r.closed = true
+	r.queue = nil
+	r.cond.Broadcast()
+	r.qmu.Unlock()
+	r.conn.Close()
+}
+
+func (r *replicaInfo) outputBuffer() int64 {
+	r.qmu.Lock()
+	defer r.qmu.Unlock()
+	return r.pending
+}
+
+func (m *MasterServer) AddReplica(conn net.Conn) {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+	m.addReplica(newReplicaInfo(conn))
+}
+
+// addReplica 调用方需持有 m.Mu 写锁
+func (m *MasterServer) addReplica(info *replicaInfo) {
+	if port, ok := m.pendingPorts[info.conn]; ok {
+		info.port = port
+		delete(m.pendingPorts, info.conn)
+	}
+	m.Replicas = append(m.Replicas, info)
+	go info.writeLoop()
+
+	log.Printf("New replica connected: %s (Total: %d)", info.addr, len(m.Replicas))
+}
+
+// DisconnectReplicas 断开所有下游副本
+func (m *MasterServer) DisconnectReplicas() {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+	m.disconnectReplicas()
+}
+
+func (m *MasterServer) disconnectReplicas() {
+	for _, r := range m.Replicas {
+		r.close()
+	}
+	if len(m.Replicas) > 0 {
+		log.Printf("Disconnected %d replicas", len(m.Replicas))
+	}
+	m.Replicas = m.Replicas[:0]
+}
+
+// 删除副本
+func (m *MasterServer) RemoveReplica(conn net.Conn) {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+	delete(m.pendingPorts, conn)
+	if m.Replicas == nil {
+		return
+	}
+	for i, r := range m.Replicas {
+		if r.conn == conn {
+			r.close()
+			// 从切片中移除
+			m.Replicas = slices.Delete(m.Replicas, i, i+1)
+			log.Printf("Replica disconnected: %s (Remaining: %d)", r.addr, len(m.Replicas))
+			return
+		}
+	}
+}
+
+// SetReplicaPort 记录 REPLCONF listening-port 此时连接尚未加入副本列表
+func (m *MasterServer) SetReplicaPort(conn net.Conn, port string) {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+	if r := m.findReplica(conn); r != nil {
+		r.port = port
+		return
+	}
+	m.pendingPorts[conn] = port
+}
+
+// ReplicaAck 处理副本的 REPLCONF ACK <offset>
+func (m *MasterServer) ReplicaAck(conn net.Conn, offset int64) {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+	if r := m.findReplica(conn); r != nil {
+		r.ackOffset.Store(offset)
+		r.ackTime.Store(time.Now().UnixNano())
+	}
+}
+
+func (m *MasterServer) findReplica(conn net.Conn) *replicaInfo {
+	for _, r := range m.Replicas {
+		if r.conn == conn {
+			return r
+		}
+	}
+	return nil
+}
+
+// goodReplicas 延迟不超过 min-replicas-max-lag 的副本数 调用方需持有 m.Mu
+func (m *MasterServer) goodReplicas() int {
+	now := time.Now()
+	good := 0
+	for _, r := range m.Replicas {
+		if r.lag(now) <= int64(m.Cfg.MinReplicasMaxLag) {
+			good++
+		}
+	}
+	return good
+}
+
+// enoughGoodReplicas min-replicas-to-write 检查 从节点不受限制
+func (m *MasterServer) enoughGoodReplicas() bool {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+	if m.link != nil || m.Cfg.MinReplicasToWrite <= 0 || m.Cfg.MinReplicasMaxLag <= 0 {
+		return true
+	}
+	return m.goodReplicas() >= m.Cfg.MinReplicasToWrite
+}
+
+// replicasInfo INFO replication 中的 slaveN 行 调用方需持有 m.Mu
+func (m *MasterServer) replicasInfo() []string {
+	now := time.Now()
+	lines := make([]string, 0, len(m.Replicas))
+	for i, r := range m.Replicas {
+		lines = append(lines, fmt.Sprintf("slave%d:ip=%s,port=%s,state=online,offset=%d,lag=%d", i, r.ip, r.port, r.ackOffset.Load(), r.lag(now)))
+	}
+	return lines
+}
+
+// replicaOutputLimit 解析 client-output-buffer-limit 中 replica 类的限制
+func (m *MasterServer) replicaOutputLimit() outputLimit {
+	hard, soft, seconds, err := m.Cfg.ReplicaOutputBufferLimit()
+	if err != nil {
+		log.Printf("Invalid client-output-buffer-limit: %s", err)
+		return outputLimit{}
+	}
+	return outputLimit{hard: hard, soft: soft, softSeconds: seconds}
+}
+
+func (m *MasterServer) GetPoolLen() int {
+	return len(m.Replicas)
+}
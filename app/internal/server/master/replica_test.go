@@ -0,0 +1,153 @@
+package master
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/config"
+	"github.com/go-playground/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMaster(t *testing.T, port string) *MasterServer {
+	dir := t.TempDir()
+	return NewMasterServer(&config.ServerConfig{Dir: dir, Dbfilename: "dump.rdb", Fn: filepath.Join(dir, "dump.rdb"), Port: port, Role: "master"})
+}
+
+// 副本的 ACK 超过 min-replicas-max-lag 后不再计入健康副本 新的 ACK 到达后恢复
+func TestGoodReplicas(t *testing.T) {
+	m := newTestMaster(t, "0")
+	m.Cfg.MinReplicasToWrite = 1
+	m.Cfg.MinReplicasMaxLag = 2
+	assert.Equal(t, false, m.enoughGoodReplicas())
+
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	m.AddReplica(conn)
+	assert.Equal(t, true, m.enoughGoodReplicas())
+
+	r := m.findReplica(conn)
+	r.ackTime.Store(time.Now().Add(-3 * time.Second).UnixNano())
+	assert.Equal(t, 0, m.goodReplicas())
+	assert.Equal(t, false, m.enoughGoodReplicas())
+
+	m.ReplicaAck(conn, 100)
+	assert.Equal(t, 1, m.goodReplicas())
+	assert.Equal(t, true, m.enoughGoodReplicas())
+	assert.Equal(t, int64(100), r.ackOffset.Load())
+
+	// min-replicas-to-write 为 0 时不检查
+	r.ackTime.Store(time.Now().Add(-3 * time.Second).UnixNano())
+	m.Cfg.MinReplicasToWrite = 0
+	assert.Equal(t, true, m.enoughGoodReplicas())
+	m.RemoveReplica(conn)
+}
+
+// 没有副本时写命令回复 NOREPLICAS 读命令不受影响; 副本连接并上报 ACK 后恢复写入
+func TestMinReplicasToWrite(t *testing.T) {
+	m := newTestMaster(t, "6391")
+	m.Cfg.MinReplicasToWrite = 1
+	m.Cfg.MinReplicasMaxLag = 10
+	go m.Start()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "localhost:6391")
+	require.NoError(t, err)
+	defer conn.Close()
+	buf := make([]byte, 1024)
+	conn.Write([]byte("*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"))
+	n, _ := conn.Read(buf)
+	assert.Equal(t, "-NOREPLICAS Not enough good replicas to write.\r\n", string(buf[:n]))
+	conn.Write([]byte("*2\r\n$4\r\nTYPE\r\n$3\r\nfoo\r\n"))
+	n, _ = conn.Read(buf)
+	assert.Equal(t, "+none\r\n", string(buf[:n]))
+
+	// 外部测试包中的 slave.NewSlaveServer 会引起循环导入 这里直接以 REPLICAOF 降级另一个实例
+	s := newTestMaster(t, "6392")
+	go s.Start()
+	require.NoError(t, s.ReplicaOf("localhost", "6391"))
+	time.Sleep(1500 * time.Millisecond)
+
+	m.Mu.RLock()
+	acked := len(m.Replicas) == 1 && m.Replicas[0].lag(time.Now()) < 2
+	m.Mu.RUnlock()
+	assert.Equal(t, true, acked)
+
+	conn.Write([]byte("*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"))
+	n, _ = conn.Read(buf)
+	assert.Equal(t, "+OK\r\n", string(buf[:n]))
+	time.Sleep(100 * time.Millisecond)
+	v, _ := s.Store.Get("foo")
+	assert.Equal(t, "bar", v)
+}
+
+// 输出缓冲区超过硬限制时立即断开 RDB 快照不计入
+func TestReplicaOutputHardLimit(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	r := newReplicaInfo(conn)
+	limit := outputLimit{hard: 100}
+	assert.Equal(t, true, r.enqueue(make([]byte, 1000), false, limit))
+	assert.Equal(t, true, r.enqueue(make([]byte, 60), true, limit))
+	assert.Equal(t, int64(60), r.outputBuffer())
+	assert.Equal(t, false, r.enqueue(make([]byte, 60), true, limit))
+}
+
+// 超过软限制持续 soft-seconds 秒后断开 写出后回到软限制以下时重新计时
+func TestReplicaOutputSoftLimit(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	r := newReplicaInfo(conn)
+	limit := outputLimit{soft: 50, softSeconds: 1}
+	assert.Equal(t, true, r.enqueue(make([]byte, 60), true, limit))
+	assert.Equal(t, true, r.enqueue(make([]byte, 10), true, limit))
+	r.qmu.Lock()
+	assert.Equal(t, false, r.softSince.IsZero())
+	r.softSince = time.Now().Add(-2 * time.Second)
+	r.qmu.Unlock()
+	assert.Equal(t, false, r.enqueue(make([]byte, 10), true, limit))
+
+	// 副本读走数据后计时清零
+	conn2, peer2 := net.Pipe()
+	defer peer2.Close()
+	r = newReplicaInfo(conn2)
+	assert.Equal(t, true, r.enqueue(make([]byte, 60), true, limit))
+	go r.writeLoop()
+	buf := make([]byte, 60)
+	_, err := io.ReadFull(peer2, buf)
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	r.qmu.Lock()
+	assert.Equal(t, int64(0), r.pending)
+	assert.Equal(t, true, r.softSince.IsZero())
+	r.qmu.Unlock()
+	r.close()
+}
+
+// feed 断开超过 client-output-buffer-limit 的副本 其他副本不受影响
+func TestFeedDisconnectsSlowReplica(t *testing.T) {
+	m := newTestMaster(t, "0")
+	m.Cfg.ClientOutputBufferLimit = "normal 0 0 0 replica 100 0 0"
+	assert.Equal(t, outputLimit{hard: 100}, m.replicaOutputLimit())
+
+	slow, slowPeer := net.Pipe()
+	defer slowPeer.Close()
+	m.AddReplica(slow)
+	payload := make([]byte, 150)
+	m.Mu.RLock()
+	m.feed(payload)
+	m.Mu.RUnlock()
+
+	// 写协程可能已写出部分数据 之后应读到 EOF
+	_, err := io.Copy(io.Discard, slowPeer)
+	require.NoError(t, err)
+	m.Mu.RLock()
+	r := m.Replicas[0]
+	m.Mu.RUnlock()
+	r.qmu.Lock()
+	assert.Equal(t, true, r.closed)
+	r.qmu.Unlock()
+}
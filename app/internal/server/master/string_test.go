@@ -0,0 +1,128 @@
+package master_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/go-playground/assert/v2"
+)
+
+// 修改字符串的命令与 GET 等读命令并发执行 (配合 -race 检查)
+// 修改以新对象替换旧值, 过期时间保留
+func TestStringWritesConcurrentWithReads(t *testing.T) {
+	m := startMaster(t, "6402", false)
+	w := dialResp(t, "6402")
+	c := dialResp(t, "6402")
+
+	w.do(t, "SET", "n", "0", "PX", "100000")
+	w.do(t, "SET", "s", "", "PX", "100000")
+	const rounds = 200
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			w.do(t, "INCR", "n")
+			w.do(t, "INCRBYFLOAT", "f", "0.5")
+			w.do(t, "APPEND", "s", "x")
+			w.do(t, "SETRANGE", "r", strconv.Itoa(i), "y")
+			w.do(t, "SETBIT", "b", strconv.Itoa(i), "1")
+			w.do(t, "PFADD", "h", strconv.Itoa(i))
+		}
+	}()
+	for i := 0; i < rounds; i++ {
+		for _, key := range []string{"n", "f", "s", "r", "b", "h"} {
+			c.do(t, "GET", key)
+			c.do(t, "STRLEN", key)
+			c.do(t, "DUMP", key)
+			c.do(t, "OBJECT", "ENCODING", key)
+		}
+	}
+	wg.Wait()
+
+	v, _ := c.do(t, "GET", "n")
+	assert.Equal(t, strconv.Itoa(rounds), v)
+	v, _ = c.do(t, "GET", "f")
+	assert.Equal(t, strconv.Itoa(rounds/2), v)
+	v, _ = c.do(t, "STRLEN", "s")
+	assert.Equal(t, int64(rounds), v)
+	v, _ = c.do(t, "BITCOUNT", "b")
+	assert.Equal(t, int64(rounds), v)
+	for _, key := range []string{"n", "s"} {
+		at, ok := m.Store.ExpireAt(key)
+		assert.Equal(t, true, ok)
+		assert.Equal(t, true, time.Until(at) > 90*time.Second)
+	}
+}
+
+func TestMultiKeyStrings(t *testing.T) {
+	startMaster(t, "6403", false)
+	c := dialResp(t, "6403")
+
+	v, _ := c.do(t, "MSET", "a", "1", "b", "2", "a", "3")
+	assert.Equal(t, "OK", v)
+	c.do(t, "RPUSH", "l", "x")
+	// 不存在或不是字符串的键返回 nil
+	v, _ = c.do(t, "MGET", "a", "b", "missing", "l")
+	assert.Equal(t, []any{"3", "2", nil, nil}, v)
+	_, err := c.do(t, "MSET", "a", "1", "b")
+	assert.Equal(t, protocol.ErrorReply("ERR wrong number of arguments for 'mset' command"), err)
+
+	// MSETNX: 任一键已存在时一个也不设置
+	v, _ = c.do(t, "MSETNX", "x", "1", "b", "9")
+	assert.Equal(t, int64(0), v)
+	v, _ = c.do(t, "MGET", "x", "b")
+	assert.Equal(t, []any{nil, "2"}, v)
+	v, _ = c.do(t, "MSETNX", "x", "1", "y", "2")
+	assert.Equal(t, int64(1), v)
+	v, _ = c.do(t, "MGET", "x", "y")
+	assert.Equal(t, []any{"1", "2"}, v)
+	// 已存在的非字符串键同样阻止写入
+	v, _ = c.do(t, "MSETNX", "l", "1", "z", "2")
+	assert.Equal(t, int64(0), v)
+	v, _ = c.do(t, "TYPE", "z")
+	assert.Equal(t, "none", v)
+}
+
+// 与 Redis 文档中 LCS 的示例输出一致
+func TestLCS(t *testing.T) {
+	startMaster(t, "6404", false)
+	c := dialResp(t, "6404")
+	c.do(t, "MSET", "key1", "ohmytext", "key2", "mynewtext")
+
+	v, _ := c.do(t, "LCS", "key1", "key2")
+	assert.Equal(t, "mytext", v)
+	v, _ = c.do(t, "LCS", "key1", "key2", "LEN")
+	assert.Equal(t, int64(6), v)
+
+	v, _ = c.do(t, "LCS", "key1", "key2", "IDX")
+	assert.Equal(t, []any{"matches", []any{
+		[]any{[]any{int64(4), int64(7)}, []any{int64(5), int64(8)}},
+		[]any{[]any{int64(2), int64(3)}, []any{int64(0), int64(1)}},
+	}, "len", int64(6)}, v)
+
+	v, _ = c.do(t, "LCS", "key1", "key2", "IDX", "MINMATCHLEN", "4")
+	assert.Equal(t, []any{"matches", []any{
+		[]any{[]any{int64(4), int64(7)}, []any{int64(5), int64(8)}},
+	}, "len", int64(6)}, v)
+
+	v, _ = c.do(t, "LCS", "key1", "key2", "IDX", "MINMATCHLEN", "4", "WITHMATCHLEN")
+	assert.Equal(t, []any{"matches", []any{
+		[]any{[]any{int64(4), int64(7)}, []any{int64(5), int64(8)}, int64(4)},
+	}, "len", int64(6)}, v)
+
+	v, _ = c.do(t, "LCS", "key1", "key2", "IDX", "WITHMATCHLEN")
+	assert.Equal(t, []any{"matches", []any{
+		[]any{[]any{int64(4), int64(7)}, []any{int64(5), int64(8)}, int64(4)},
+		[]any{[]any{int64(2), int64(3)}, []any{int64(0), int64(1)}, int64(2)},
+	}, "len", int64(6)}, v)
+
+	// 不存在的键视为空字符串
+	v, _ = c.do(t, "LCS", "key1", "missing")
+	assert.Equal(t, "", v)
+	_, err := c.do(t, "LCS", "key1", "key2", "LEN", "IDX")
+	assert.Equal(t, protocol.ErrorReply("ERR If you want both the length and indexes, please just use IDX."), err)
+}
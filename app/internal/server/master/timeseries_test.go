@@ -0,0 +1,83 @@
+package master_test
+
+import (
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/go-playground/assert/v2"
+)
+
+// FILTER 的 label=(v1,v2) / label!=(v1,v2) 列表形式与 TS.MGET
+func TestTSFilterLists(t *testing.T) {
+	startMaster(t, "6405", false)
+	c := dialResp(t, "6405")
+
+	c.do(t, "TS.CREATE", "a", "LABELS", "type", "temp", "area", "east")
+	c.do(t, "TS.CREATE", "b", "LABELS", "type", "temp", "area", "west")
+	c.do(t, "TS.CREATE", "c", "LABELS", "type", "temp", "area", "north")
+	c.do(t, "TS.ADD", "a", "1", "10")
+	c.do(t, "TS.ADD", "a", "2", "11")
+	c.do(t, "TS.ADD", "b", "1", "20")
+
+	for filter, want := range map[string][]any{
+		"area=(east,west)":     {"a", "b"},
+		"area=(east, west)":    {"a", "b"},
+		`area=("east","west")`: {"a", "b"},
+		"area!=(east,west)":    {"c"},
+	} {
+		v, _ := c.do(t, "TS.QUERYINDEX", "type=temp", filter)
+		assert.Equal(t, want, v)
+	}
+
+	v, _ := c.do(t, "TS.MRANGE", "-", "+", "FILTER", "area=(east,west)")
+	assert.Equal(t, []any{
+		[]any{"a", []any{}, []any{[]any{int64(1), "10"}, []any{int64(2), "11"}}},
+		[]any{"b", []any{}, []any{[]any{int64(1), "20"}}},
+	}, v)
+
+	// 没有样本的序列回复空数组
+	v, _ = c.do(t, "TS.MGET", "FILTER", "type=temp", "area!=(west)")
+	assert.Equal(t, []any{
+		[]any{"a", []any{}, []any{int64(2), "11"}},
+		[]any{"c", []any{}, []any{}},
+	}, v)
+	v, _ = c.do(t, "TS.MGET", "WITHLABELS", "FILTER", "area=(west)")
+	assert.Equal(t, []any{
+		[]any{"b", []any{[]any{"type", "temp"}, []any{"area", "west"}}, []any{int64(1), "20"}},
+	}, v)
+
+	_, err := c.do(t, "TS.MGET", "FILTER", "area=(east,west")
+	assert.Equal(t, protocol.ErrorReply("ERR TSDB: failed parsing labels 'area=(east,west': expected label=value, label!=value, label=(v1,v2) or label!=(v1,v2)"), err)
+	_, err = c.do(t, "TS.MRANGE", "-", "+", "FILTER", "area!=(east,west)")
+	assert.Equal(t, protocol.ErrorReply("ERR TSDB: please provide at least one matcher"), err)
+	_, err = c.do(t, "TS.MRANGE", "-", "+", "FILTER", "type=temp", "GROUPBY", "area", "REDUCE", "max")
+	assert.Equal(t, protocol.ErrorReply("ERR TSDB: GROUPBY/REDUCE is not supported"), err)
+	_, err = c.do(t, "TS.MGET", "COUNT", "1", "FILTER", "type=temp")
+	assert.Equal(t, protocol.ErrorReply("ERR syntax error"), err)
+}
+
+// 压缩规则只聚合创建之后写入的样本 (DUMP/RESTORE 后保持)
+func TestTSCreateRuleSkipsExistingSamples(t *testing.T) {
+	startMaster(t, "6412", false)
+	c := dialResp(t, "6412")
+
+	c.do(t, "TS.CREATE", "src")
+	c.do(t, "TS.CREATE", "dst")
+	c.do(t, "TS.ADD", "src", "1", "100")
+	c.do(t, "TS.ADD", "src", "3", "50")
+	v, _ := c.do(t, "TS.CREATERULE", "src", "dst", "AGGREGATION", "max", "10")
+	assert.Equal(t, "OK", v)
+	c.do(t, "TS.ADD", "src", "5", "7")
+	c.do(t, "TS.ADD", "src", "8", "9")
+	c.do(t, "TS.ADD", "src", "12", "1")
+	v, _ = c.do(t, "TS.RANGE", "dst", "-", "+")
+	assert.Equal(t, []any{[]any{int64(0), "9"}}, v)
+
+	// 重新聚合旧桶时同样跳过创建规则之前的样本
+	payload, _ := c.do(t, "DUMP", "src")
+	c.do(t, "RESTORE", "src", "0", payload.(string), "REPLACE")
+	c.do(t, "TS.ADD", "src", "2", "200")
+	c.do(t, "TS.ADD", "src", "25", "4")
+	v, _ = c.do(t, "TS.RANGE", "dst", "-", "+")
+	assert.Equal(t, []any{[]any{int64(0), "9"}, []any{int64(10), "1"}}, v)
+}
@@ -0,0 +1,123 @@
+package master_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/go-playground/assert/v2"
+)
+
+var errWrongType = protocol.ErrorReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// 每种类型的键: TYPE 与 OBJECT ENCODING 的输出, 以及其他类型的命令回复 WRONGTYPE
+func TestTypesAndWrongType(t *testing.T) {
+	startMaster(t, "6398", false)
+	c := dialResp(t, "6398")
+
+	// 不存在的键
+	v, err := c.do(t, "GET", "missing")
+	assert.Equal(t, nil, v)
+	assert.Equal(t, nil, err)
+	v, _ = c.do(t, "TYPE", "missing")
+	assert.Equal(t, "none", v)
+	_, err = c.do(t, "OBJECT", "ENCODING", "missing")
+	assert.Equal(t, nil, err)
+
+	c.do(t, "SET", "s", "hello")
+	c.do(t, "RPUSH", "l", "a")
+	c.do(t, "SADD", "st", "1")
+	c.do(t, "ZADD", "z", "1", "a")
+	c.do(t, "HSET", "h", "f", "v")
+	c.do(t, "XADD", "x", "1-1", "f", "v")
+	for key, typ := range map[string]string{"s": "string", "l": "list", "st": "set", "z": "zset", "h": "hash", "x": "stream"} {
+		v, _ = c.do(t, "TYPE", key)
+		assert.Equal(t, typ, v)
+	}
+
+	// 每个类型的命令作用在其他类型的键上
+	cmds := map[string][]string{
+		"s":  {"GET", "APPEND", "INCR", "STRLEN"},
+		"l":  {"LLEN", "LPUSH", "LRANGE"},
+		"st": {"SCARD", "SADD", "SMEMBERS"},
+		"z":  {"ZCARD", "ZADD", "ZSCORE"},
+		"h":  {"HLEN", "HSET", "HGET"},
+		"x":  {"XLEN", "XADD"},
+	}
+	args := map[string][]string{
+		"GET": nil, "APPEND": {"x"}, "INCR": nil, "STRLEN": nil,
+		"LLEN": nil, "LPUSH": {"x"}, "LRANGE": {"0", "-1"},
+		"SCARD": nil, "SADD": {"x"}, "SMEMBERS": nil,
+		"ZCARD": nil, "ZADD": {"1", "x"}, "ZSCORE": {"x"},
+		"HLEN": nil, "HSET": {"f", "x"}, "HGET": {"f"},
+		"XLEN": nil, "XADD": {"*", "f", "x"},
+	}
+	for owner, list := range cmds {
+		for key := range cmds {
+			if key == owner {
+				continue
+			}
+			for _, cmd := range list {
+				_, err = c.do(t, append([]string{cmd, key}, args[cmd]...)...)
+				assert.Equal(t, errWrongType, err)
+			}
+		}
+	}
+	// 值没有被修改
+	v, _ = c.do(t, "GET", "s")
+	assert.Equal(t, "hello", v)
+	v, _ = c.do(t, "LRANGE", "l", "0", "-1")
+	assert.Equal(t, []any{"a"}, v)
+}
+
+func TestObjectEncoding(t *testing.T) {
+	startMaster(t, "6399", false)
+	c := dialResp(t, "6399")
+	long := strings.Repeat("x", 100)
+	encoding := func(key string) any {
+		v, _ := c.do(t, "OBJECT", "ENCODING", key)
+		return v
+	}
+
+	c.do(t, "SET", "n", "12345")
+	assert.Equal(t, "int", encoding("n"))
+	c.do(t, "SET", "e", "hello")
+	assert.Equal(t, "embstr", encoding("e"))
+	c.do(t, "SET", "r", long)
+	assert.Equal(t, "raw", encoding("r"))
+	c.do(t, "INCR", "n")
+	assert.Equal(t, "int", encoding("n"))
+
+	c.do(t, "RPUSH", "l", "a", "b")
+	assert.Equal(t, "listpack", encoding("l"))
+	c.do(t, "RPUSH", "l", long)
+	assert.Equal(t, "quicklist", encoding("l"))
+
+	c.do(t, "SADD", "st", "1", "2")
+	assert.Equal(t, "intset", encoding("st"))
+	c.do(t, "SADD", "st", "a")
+	assert.Equal(t, "listpack", encoding("st"))
+	c.do(t, "SADD", "st", long)
+	assert.Equal(t, "hashtable", encoding("st"))
+
+	c.do(t, "ZADD", "z", "1", "a")
+	assert.Equal(t, "listpack", encoding("z"))
+	c.do(t, "ZADD", "z", "2", long)
+	assert.Equal(t, "skiplist", encoding("z"))
+
+	c.do(t, "HSET", "h", "f", "v")
+	assert.Equal(t, "listpack", encoding("h"))
+	c.do(t, "HEXPIRE", "h", "100", "FIELDS", "1", "f")
+	assert.Equal(t, "listpackex", encoding("h"))
+	c.do(t, "HSET", "h", "g", long)
+	assert.Equal(t, "hashtable", encoding("h"))
+
+	c.do(t, "XADD", "x", "1-1", "f", "v")
+	assert.Equal(t, "stream", encoding("x"))
+
+	// OBJECT 的其他子命令
+	v, _ := c.do(t, "OBJECT", "REFCOUNT", "l")
+	assert.Equal(t, int64(1), v)
+	v, _ = c.do(t, "OBJECT", "IDLETIME", "l")
+	assert.Equal(t, int64(0), v)
+}
@@ -0,0 +1,295 @@
+package sentinel
+
+import (
+	"log"
+	"math/rand"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+)
+
+// failoverState 故障转移状态机
+type failoverState int
+
+const (
+	failoverNone             failoverState = iota
+	failoverWaitStart                      // 等待赢得领头哨兵选举
+	failoverSelectSlave                    // 选择晋升的副本
+	failoverSendSlaveofNoOne               // 向选中的副本发送 REPLICAOF NO ONE
+	failoverWaitPromotion                  // 等待其 INFO 报告 role:master
+	failoverReconfSlaves                   // 让其他副本复制新的主节点
+)
+
+func (st failoverState) String() string {
+	switch st {
+	case failoverWaitStart:
+		return "wait_start"
+	case failoverSelectSlave:
+		return "select_slave"
+	case failoverSendSlaveofNoOne:
+		return "send_slaveof_noone"
+	case failoverWaitPromotion:
+		return "wait_promotion"
+	case failoverReconfSlaves:
+		return "reconf_slaves"
+	}
+	return "none"
+}
+
+// failover 一个主节点的故障转移进度
+type failover struct {
+	state       failoverState
+	epoch       int64     // 本次故障转移的纪元
+	startTime   time.Time // 发起时间 (含随机延迟), 也用于限制重试频率
+	stateChange time.Time
+	promoted    *instance
+	forced      bool // SENTINEL FAILOVER 发起, 无需其他哨兵同意
+}
+
+func (m *masterState) setState(st failoverState, now time.Time) {
+	m.state = st
+	m.stateChange = now
+}
+
+// failoverStep 推进故障转移状态机 调用方持有 s.mu
+func (s *SentinelServer) failoverStep(m *masterState, now time.Time) {
+	switch m.state {
+	case failoverNone:
+		s.startFailoverIfNeeded(m, now)
+	case failoverWaitStart:
+		if now.Before(m.startTime) {
+			return
+		}
+		leader := s.getLeader(m, now)
+		if leader != s.runID {
+			if now.Sub(m.startTime) > min(electionTimeout, m.failoverTimeout()) {
+				s.abortFailover(m, "not-elected", now)
+			}
+			return
+		}
+		log.Printf("+elected-leader master %s %s #epoch %d", m.name, m.inst.addr(), m.epoch)
+		m.setState(failoverSelectSlave, now)
+	case failoverSelectSlave:
+		r := s.selectReplica(m, now)
+		if r == nil {
+			s.abortFailover(m, "no-good-slave", now)
+			return
+		}
+		log.Printf("+selected-slave slave %s @ %s", r.addr(), m.name)
+		m.promoted = r
+		m.setState(failoverSendSlaveofNoOne, now)
+	case failoverSendSlaveofNoOne:
+		if m.promoted.sdown {
+			if now.Sub(m.stateChange) > m.failoverTimeout() {
+				s.abortFailover(m, "slave-timeout", now)
+			}
+			return
+		}
+		log.Printf("+failover-state-send-slaveof-noone slave %s @ %s", m.promoted.addr(), m.name)
+		promoted := m.promoted
+		go func() {
+			if _, err := promoted.link.Call("REPLICAOF", "NO", "ONE"); err != nil {
+				log.Printf("REPLICAOF NO ONE to %s error: %s", promoted.addr(), err)
+			}
+		}()
+		m.setState(failoverWaitPromotion, now)
+	case failoverWaitPromotion:
+		// 晋升由 handleInfo 确认
+		if now.Sub(m.stateChange) > m.failoverTimeout() {
+			s.abortFailover(m, "slave-timeout", now)
+		}
+	case failoverReconfSlaves:
+		s.reconfReplicas(m, now)
+	}
+}
+
+// startFailoverIfNeeded 主节点客观下线且距上次尝试超过 2*failover-timeout 时发起故障转移
+func (s *SentinelServer) startFailoverIfNeeded(m *masterState, now time.Time) {
+	if !m.odown || now.Sub(m.startTime) < 2*m.failoverTimeout() {
+		return
+	}
+	s.currentEpoch++
+	m.epoch = s.currentEpoch
+	m.forced = false
+	m.startTime = now.Add(time.Duration(rand.Int63n(int64(maxDesync))))
+	m.setState(failoverWaitStart, now)
+	log.Printf("+new-epoch %d", s.currentEpoch)
+	log.Printf("+try-failover master %s %s", m.name, m.inst.addr())
+}
+
+// Failover SENTINEL FAILOVER <name>: 不经其他哨兵同意强制故障转移
+func (s *SentinelServer) Failover(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.masters[name]
+	if !ok {
+		return replication.ErrNoSuchMaster
+	}
+	if m.state != failoverNone {
+		return replication.ErrFailoverInProgress
+	}
+	now := time.Now()
+	if s.selectReplica(m, now) == nil {
+		return replication.ErrNoGoodSlave
+	}
+	s.currentEpoch++
+	m.epoch = s.currentEpoch
+	m.leader, m.leaderEpoch = s.runID, s.currentEpoch
+	m.forced = true
+	m.startTime = now
+	m.setState(failoverSelectSlave, now)
+	log.Printf("+new-epoch %d", s.currentEpoch)
+	log.Printf("+try-failover master %s %s (forced)", m.name, m.inst.addr())
+	return nil
+}
+
+// getLeader 统计本纪元的投票 得票数达到 max(quorum, 多数派) 的哨兵成为领头哨兵
+// 自己尚未投票时投给当前得票最多的哨兵, 没有则投给自己
+func (s *SentinelServer) getLeader(m *masterState, now time.Time) string {
+	counts := make(map[string]int)
+	for _, p := range m.sentinels {
+		if p.leader != "" && p.leaderEpoch == m.epoch && now.Sub(p.lastReply) <= replyValidPeriod {
+			counts[p.leader]++
+		}
+	}
+	winner := mostVoted(counts)
+	candidate := winner
+	if candidate == "" {
+		candidate = s.runID
+	}
+	if myVote, epoch := s.voteLeader(m, m.epoch, candidate, now); epoch == m.epoch {
+		counts[myVote]++
+		winner = mostVoted(counts)
+	}
+
+	voters := len(m.sentinels) + 1
+	if winner == "" || counts[winner] < max(m.cfg.Quorum, voters/2+1) {
+		return ""
+	}
+	return winner
+}
+
+// mostVoted 得票最多者 平票时取 runid 较大者保证各哨兵结果一致
+func mostVoted(counts map[string]int) string {
+	winner, best := "", 0
+	for id, n := range counts {
+		if n > best || (n == best && id > winner) {
+			winner, best = id, n
+		}
+	}
+	return winner
+}
+
+// selectReplica 选择晋升的副本: 排除下线、INFO 过期与 priority 为 0 的副本,
+// 按 replica-priority 升序、复制偏移量降序、地址升序排序
+func (s *SentinelServer) selectReplica(m *masterState, now time.Time) *instance {
+	// 主节点下线时 INFO 每秒刷新, 否则每 infoPeriod 刷新
+	validity := 3 * infoPeriod
+	if m.inst.sdown {
+		validity = 5 * infoPeriodFast
+	}
+	var candidates []*instance
+	for _, r := range m.replicas {
+		if r.sdown || r.info.role != "slave" || r.info.priority == 0 {
+			continue
+		}
+		if r.lastInfo.IsZero() || now.Sub(r.lastInfo) > validity {
+			continue
+		}
+		candidates = append(candidates, r)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.info.priority != b.info.priority {
+			return a.info.priority < b.info.priority
+		}
+		if a.info.offset != b.info.offset {
+			return a.info.offset > b.info.offset
+		}
+		return a.addr() < b.addr()
+	})
+	return candidates[0]
+}
+
+// reconfReplicas 让其他副本复制新主节点 同时进行的数量不超过 parallel-syncs
+// 全部完成 (或超时) 后切换主节点地址
+func (s *SentinelServer) reconfReplicas(m *masterState, now time.Time) {
+	inProgress := 0
+	for _, r := range m.replicas {
+		if r != m.promoted && !r.reconfSent.IsZero() && !r.reconfDone {
+			if now.Sub(r.reconfSent) > m.failoverTimeout() {
+				log.Printf("-slave-reconf-sent-timeout slave %s @ %s", r.addr(), m.name)
+				r.reconfDone = true
+				continue
+			}
+			inProgress++
+		}
+	}
+
+	pending := false
+	for _, addr := range sortedKeys(m.replicas) {
+		r := m.replicas[addr]
+		if r == m.promoted || r.reconfDone || r.sdown {
+			continue
+		}
+		pending = true
+		if r.reconfSent.IsZero() && inProgress < m.cfg.ParallelSyncs {
+			r.reconfSent = now
+			r.lastReconf = now
+			inProgress++
+			log.Printf("+slave-reconf-sent slave %s @ %s", r.addr(), m.name)
+			s.sendReplicaOf(r, m.promoted.host, m.promoted.port)
+		}
+	}
+
+	if !pending || now.Sub(m.stateChange) > m.failoverTimeout() {
+		s.switchMaster(m, m.promoted.host, m.promoted.port, now)
+	}
+}
+
+// abortFailover 放弃本次故障转移 2*failover-timeout 后才会再次尝试
+func (s *SentinelServer) abortFailover(m *masterState, reason string, now time.Time) {
+	log.Printf("-failover-abort-%s master %s %s", reason, m.name, m.inst.addr())
+	m.resetFailover()
+	m.stateChange = now
+}
+
+func (m *masterState) resetFailover() {
+	m.state = failoverNone
+	m.promoted = nil
+	m.forced = false
+	for _, r := range m.replicas {
+		r.reconfSent = time.Time{}
+		r.reconfDone = false
+	}
+}
+
+// switchMaster 主节点地址变为 host:port 原主节点作为副本保留, 恢复上线后会被重新配置为新主节点的副本
+func (s *SentinelServer) switchMaster(m *masterState, host, port string, now time.Time) {
+	old := m.inst
+	newAddr := net.JoinHostPort(host, port)
+	log.Printf("+switch-master %s %s %s %s %s", m.name, old.host, old.port, host, port)
+
+	next, ok := m.replicas[newAddr]
+	if !ok {
+		next = newInstance(host, port)
+	}
+	delete(m.replicas, newAddr)
+	if old.addr() != newAddr {
+		m.replicas[old.addr()] = old
+	}
+	m.inst = next
+	// 新主节点重新计算下线状态
+	next.pong(now)
+	next.sdown = false
+	m.odown = false
+	m.resetFailover()
+	for _, p := range m.sentinels {
+		p.masterDown = false
+	}
+}
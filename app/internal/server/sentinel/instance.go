@@ -0,0 +1,225 @@
+package sentinel
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+)
+
+// 与被监控实例/其他哨兵通信的超时
+const callTimeout = time.Second
+
+// client 到被监控实例或其他哨兵的命令连接 出错后断开, 下次调用时重连
+type client struct {
+	addr string
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *protocol.Reader
+}
+
+func newClient(addr string) *client {
+	return &client{addr: addr}
+}
+
+func (c *client) connect() error {
+	if c.conn != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", c.addr, callTimeout)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.rd = protocol.NewReader(conn)
+	return nil
+}
+
+// LocalHost 本端在该连接上的地址 (哨兵 hello 中宣告自己的IP)
+func (c *client) LocalHost() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.connect(); err != nil {
+		return "", err
+	}
+	host, _, err := net.SplitHostPort(c.conn.LocalAddr().String())
+	return host, err
+}
+
+// Call 发送命令并读取回复 错误回复以 protocol.ErrorReply 返回, 连接保持
+func (c *client) Call(args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	c.conn.SetDeadline(time.Now().Add(callTimeout))
+	if _, err := c.conn.Write(protocol.ArrayFmt(args)); err != nil {
+		c.closeLocked()
+		return nil, err
+	}
+	reply, err := c.rd.ReadValue()
+	if _, ok := err.(protocol.ErrorReply); err != nil && !ok {
+		c.closeLocked()
+	}
+	return reply, err
+}
+
+func (c *client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeLocked()
+}
+
+func (c *client) closeLocked() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// instanceInfo 从 INFO replication 解析出的状态
+type instanceInfo struct {
+	role       string
+	masterHost string
+	masterPort string
+	linkUp     bool
+	offset     int64
+	priority   int
+	replicas   []string // 主节点上报的副本地址 host:port
+}
+
+// parseInfo 解析 INFO 输出 未知字段忽略
+func parseInfo(text string) instanceInfo {
+	info := instanceInfo{priority: 100}
+	for _, line := range strings.Split(text, "\r\n") {
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch {
+		case key == "role":
+			info.role = val
+		case key == "master_host":
+			info.masterHost = val
+		case key == "master_port":
+			info.masterPort = val
+		case key == "master_link_status":
+			info.linkUp = val == "up"
+		case key == "slave_repl_offset":
+			info.offset, _ = strconv.ParseInt(val, 10, 64)
+		case key == "slave_priority":
+			info.priority, _ = strconv.Atoi(val)
+		case strings.HasPrefix(key, "slave") && strings.Contains(val, "ip="):
+			// slaveN:ip=...,port=...,state=online,offset=...,lag=...
+			var ip, port string
+			for _, kv := range strings.Split(val, ",") {
+				k, v, _ := strings.Cut(kv, "=")
+				switch k {
+				case "ip":
+					ip = v
+				case "port":
+					port = v
+				}
+			}
+			if ip != "" && port != "" {
+				info.replicas = append(info.replicas, net.JoinHostPort(ip, port))
+			}
+		}
+	}
+	return info
+}
+
+// instance 被监控的主节点或副本
+type instance struct {
+	host, port string
+	link       *client
+
+	lastPingSent time.Time
+	actPingTime  time.Time // 最早一个尚未收到有效回复的 PING 的发送时间 收到回复后清零 (Redis 的 act_ping_time)
+	lastPong     time.Time // 最近一次有效的 PING 回复
+	pingPending  bool
+
+	lastInfoSent time.Time
+	lastInfo     time.Time
+	infoPending  bool
+	info         instanceInfo
+
+	sdown      bool
+	lastReconf time.Time // 最近一次发送 REPLICAOF 的时间
+
+	// 故障转移的重新配置阶段
+	reconfSent time.Time
+	reconfDone bool
+}
+
+// newInstance 从创建时开始计算未回复时间 始终连不上的实例同样会被判定下线
+func newInstance(host, port string) *instance {
+	now := time.Now()
+	return &instance{
+		host:        host,
+		port:        port,
+		link:        newClient(net.JoinHostPort(host, port)),
+		actPingTime: now,
+		lastPong:    now,
+	}
+}
+
+// pong 收到有效的 PING 回复
+func (i *instance) pong(now time.Time) {
+	i.lastPong = now
+	i.actPingTime = time.Time{}
+}
+
+// pingDelay 最早一个未回复的 PING 已等待的时间 没有未回复的 PING 时为 0
+// 按发送时间而不是上次回复计算: PING 周期接近 down-after-milliseconds 时, 正常的实例不会被误判下线
+func (i *instance) pingDelay(now time.Time) time.Duration {
+	if i.actPingTime.IsZero() {
+		return 0
+	}
+	return now.Sub(i.actPingTime)
+}
+
+func (i *instance) addr() string {
+	return net.JoinHostPort(i.host, i.port)
+}
+
+// pointsTo 副本上报的主节点是否为 target
+func (i *instance) pointsTo(target *instance) bool {
+	return i.info.role == "slave" && i.info.masterHost == target.host && i.info.masterPort == target.port
+}
+
+// peer 监控同一主节点的其他哨兵
+type peer struct {
+	host, port string
+	runID      string
+	link       *client
+
+	lastHelloSent time.Time
+	lastHello     time.Time // 最近一次收到对方的 hello
+	helloPending  bool
+
+	lastAsk     time.Time
+	lastReply   time.Time
+	askPending  bool
+	masterDown  bool   // 对方认为主节点主观下线
+	leader      string // 对方投票选出的领头哨兵
+	leaderEpoch int64
+}
+
+func newPeer(host, port string) *peer {
+	return &peer{host: host, port: port, link: newClient(net.JoinHostPort(host, port))}
+}
+
+func (p *peer) addr() string {
+	return net.JoinHostPort(p.host, p.port)
+}
+
+func (p *peer) String() string {
+	return fmt.Sprintf("%s %s", p.addr(), p.runID)
+}
@@ -0,0 +1,703 @@
+package sentinel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/command"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+var _ replication.SentinelMonitor = (*SentinelServer)(nil)
+
+// 周期 (与 Redis Sentinel 一致)
+const (
+	cronPeriod       = 100 * time.Millisecond
+	pingPeriod       = time.Second
+	infoPeriod       = 10 * time.Second
+	infoPeriodFast   = time.Second // 主节点下线或故障转移期间
+	helloPeriod      = 2 * time.Second
+	askPeriod        = time.Second
+	electionTimeout  = 10 * time.Second
+	maxDesync        = time.Second // 随机延迟 避免多个哨兵同时发起选举
+	reconfMinPeriod  = 4 * time.Second
+	replyValidPeriod = 5 * askPeriod // 其他哨兵的下线判断/投票的有效期
+)
+
+// masterState 一个被监控的主节点及其副本和其他哨兵
+type masterState struct {
+	name      string
+	cfg       config.MonitorConfig
+	inst      *instance
+	replicas  map[string]*instance // addr ->
+	sentinels map[string]*peer     // addr ->
+
+	odown       bool
+	configEpoch int64 // 当前主节点地址对应的配置纪元 hello 中纪元更大的配置胜出
+
+	// 本哨兵在 leaderEpoch 中的投票
+	leader      string
+	leaderEpoch int64
+
+	failover
+}
+
+// SentinelServer 哨兵模式 不保存数据, 只负责监控与故障转移
+type SentinelServer struct {
+	Cfg      *config.ServerConfig
+	Registry *command.Registry
+	runID    string
+
+	mu           sync.Mutex
+	currentEpoch int64
+	masters      map[string]*masterState
+}
+
+func NewSentinelServer(cfg *config.ServerConfig) *SentinelServer {
+	s := &SentinelServer{
+		Cfg:      cfg,
+		Registry: command.NewRegistry(),
+		runID:    replication.NewReplID(),
+		masters:  make(map[string]*masterState),
+	}
+	for _, mc := range cfg.Sentinel.Monitors {
+		m := &masterState{
+			name:      mc.Name,
+			cfg:       mc,
+			inst:      newInstance(mc.Host, mc.Port),
+			replicas:  make(map[string]*instance),
+			sentinels: make(map[string]*peer),
+		}
+		for _, addr := range cfg.Sentinel.KnownSentinels {
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				log.Printf("Invalid known sentinel %q: %s", addr, err)
+				continue
+			}
+			m.sentinels[addr] = newPeer(host, port)
+		}
+		s.masters[mc.Name] = m
+	}
+	s.RegisterCmd()
+	return s
+}
+
+func (s *SentinelServer) RegisterCmd() {
+	s.Registry.Register(command.NewPingCommand())
+	s.Registry.Register(command.NewInfoCommand(s.Cfg, s))
+	s.Registry.Register(command.NewSentinelCommand(s))
+}
+
+func (s *SentinelServer) Start() error {
+	l, err := net.Listen("tcp", ":"+s.Cfg.Port)
+	if err != nil {
+		log.Printf("Failed to bind to port %s : %s", s.Cfg.Port, err)
+		return err
+	}
+	defer l.Close()
+
+	log.Printf("Sentinel started on port %s, id: %s, monitoring %d masters", s.Cfg.Port, s.runID, len(s.masters))
+	go s.cron()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			log.Printf("Error accepting connection: %s", err)
+			continue
+		}
+		go s.HandleConnection(conn)
+	}
+}
+
+func (s *SentinelServer) HandleConnection(conn net.Conn) {
+	defer conn.Close()
+	rw := protocol.NewConnResponseWriter(conn)
+	rd := protocol.NewReader(conn)
+	for {
+		cmd, args, err := rd.ReadRequest()
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("Protocol error: %v", err)
+				rw.WriteNull()
+			}
+			return
+		}
+		if err := s.ProcessCommand(rw, cmd, args); err != nil {
+			log.Printf("Command error: %v, cmd : %s", err, cmd)
+			return
+		}
+	}
+}
+
+func (s *SentinelServer) ProcessCommand(rw protocol.ResponseWriter, cmd string, args []string) error {
+	handler, ok := s.Registry.GetHandler(cmd)
+	if !ok {
+		log.Printf("ERR unknown command '%s'", cmd)
+		return errors_r.ErrInvalidRequest
+	}
+	return handler.Execute(context.Background(), rw, args)
+}
+
+// cron 定时任务: 探测实例、判定下线、询问其他哨兵、推进故障转移
+// 网络调用都在后台协程中进行, 回复到达后在 s.mu 保护下更新状态
+func (s *SentinelServer) cron() {
+	ticker := time.NewTicker(cronPeriod)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.tick(time.Now())
+	}
+}
+
+// tick 一次定时任务
+func (s *SentinelServer) tick(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.masters {
+		s.monitor(m, m.inst, now)
+		for _, r := range m.replicas {
+			s.monitor(m, r, now)
+		}
+		s.checkDown(m, now)
+		for _, p := range m.sentinels {
+			s.sendHello(m, p, now)
+			s.askMasterState(m, p, now)
+		}
+		s.failoverStep(m, now)
+	}
+}
+
+// infoPeriod 主节点下线或故障转移期间加快 INFO 频率
+func (m *masterState) infoPeriod() time.Duration {
+	if m.inst.sdown || m.state != failoverNone {
+		return infoPeriodFast
+	}
+	return infoPeriod
+}
+
+// currentAddr 对外宣告的主节点 晋升确认后 (configEpoch 已更新) 即为新主节点
+func (m *masterState) currentAddr() *instance {
+	if m.state == failoverReconfSlaves && m.promoted != nil {
+		return m.promoted
+	}
+	return m.inst
+}
+
+func (m *masterState) downAfter() time.Duration {
+	return time.Duration(m.cfg.DownAfterMs) * time.Millisecond
+}
+
+func (m *masterState) failoverTimeout() time.Duration {
+	return time.Duration(m.cfg.FailoverTimeoutMs) * time.Millisecond
+}
+
+// monitor 按周期向实例发送 PING 与 INFO
+func (s *SentinelServer) monitor(m *masterState, inst *instance, now time.Time) {
+	if !inst.pingPending && now.Sub(inst.lastPingSent) >= min(pingPeriod, m.downAfter()) {
+		inst.pingPending = true
+		inst.lastPingSent = now
+		if inst.actPingTime.IsZero() {
+			inst.actPingTime = now
+		}
+		go func() {
+			reply, err := inst.link.Call("PING")
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			inst.pingPending = false
+			// LOADING / MASTERDOWN 同样视为有效回复
+			if e, ok := err.(protocol.ErrorReply); ok {
+				if strings.HasPrefix(string(e), "LOADING") || strings.HasPrefix(string(e), "MASTERDOWN") {
+					inst.pong(time.Now())
+				}
+				return
+			}
+			if err == nil && reply == "PONG" {
+				inst.pong(time.Now())
+			}
+		}()
+	}
+
+	if !inst.infoPending && now.Sub(inst.lastInfoSent) >= m.infoPeriod() {
+		inst.infoPending = true
+		inst.lastInfoSent = now
+		go func() {
+			reply, err := inst.link.Call("INFO", "replication")
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			inst.infoPending = false
+			text, ok := reply.(string)
+			if err != nil || !ok {
+				return
+			}
+			s.handleInfo(m, inst, parseInfo(text), time.Now())
+		}()
+	}
+}
+
+// handleInfo 根据 INFO 发现副本、确认晋升、跟踪重新配置进度、纠正角色错误的实例
+func (s *SentinelServer) handleInfo(m *masterState, inst *instance, info instanceInfo, now time.Time) {
+	inst.info = info
+	inst.lastInfo = now
+
+	if inst == m.inst {
+		if info.role != "master" {
+			return
+		}
+		for _, addr := range info.replicas {
+			if _, ok := m.replicas[addr]; ok || addr == m.inst.addr() {
+				continue
+			}
+			host, port, _ := net.SplitHostPort(addr)
+			m.replicas[addr] = newInstance(host, port)
+			log.Printf("+slave slave %s @ %s %s", addr, m.name, m.inst.addr())
+		}
+		return
+	}
+
+	switch {
+	case m.state == failoverWaitPromotion && inst == m.promoted:
+		if info.role == "master" {
+			m.configEpoch = m.epoch
+			m.setState(failoverReconfSlaves, now)
+			log.Printf("+promoted-slave slave %s @ %s", inst.addr(), m.name)
+		}
+	case m.state == failoverReconfSlaves && inst != m.promoted:
+		if !inst.reconfSent.IsZero() && inst.pointsTo(m.promoted) && info.linkUp {
+			inst.reconfDone = true
+			log.Printf("+slave-reconf-done slave %s @ %s", inst.addr(), m.name)
+		}
+	case m.state == failoverNone && !m.inst.sdown && m.inst.info.role == "master" && !inst.pointsTo(m.inst):
+		// 主节点正常时, 自称主节点或指向其他主节点的副本 (如恢复上线的旧主节点) 需要重新配置
+		if now.Sub(inst.lastReconf) < reconfMinPeriod {
+			return
+		}
+		inst.lastReconf = now
+		log.Printf("+convert-to-slave slave %s @ %s %s", inst.addr(), m.name, m.inst.addr())
+		s.sendReplicaOf(inst, m.inst.host, m.inst.port)
+	}
+}
+
+// sendReplicaOf 异步发送 REPLICAOF host port
+func (s *SentinelServer) sendReplicaOf(inst *instance, host, port string) {
+	go func() {
+		if _, err := inst.link.Call("REPLICAOF", host, port); err != nil {
+			log.Printf("REPLICAOF %s %s to %s error: %s", host, port, inst.addr(), err)
+		}
+	}()
+}
+
+// checkDown 主观下线: 最早一个未回复的 PING 已发出超过 down-after-milliseconds
+// 客观下线: 包括自己在内至少 quorum 个哨兵认为主节点主观下线
+func (s *SentinelServer) checkDown(m *masterState, now time.Time) {
+	all := []*instance{m.inst}
+	for _, r := range m.replicas {
+		all = append(all, r)
+	}
+	for _, inst := range all {
+		down := inst.pingDelay(now) > m.downAfter()
+		if down != inst.sdown {
+			inst.sdown = down
+			kind := "slave"
+			if inst == m.inst {
+				kind = "master"
+			}
+			if down {
+				log.Printf("+sdown %s %s @ %s", kind, inst.addr(), m.name)
+			} else {
+				log.Printf("-sdown %s %s @ %s", kind, inst.addr(), m.name)
+			}
+		}
+	}
+
+	odown := false
+	if m.inst.sdown {
+		votes := 1
+		for _, p := range m.sentinels {
+			if p.masterDown && now.Sub(p.lastReply) <= replyValidPeriod {
+				votes++
+			}
+		}
+		odown = votes >= m.cfg.Quorum
+	}
+	if odown != m.odown {
+		m.odown = odown
+		if odown {
+			log.Printf("+odown master %s %s #quorum %d", m.name, m.inst.addr(), m.cfg.Quorum)
+		} else {
+			log.Printf("-odown master %s %s", m.name, m.inst.addr())
+		}
+	}
+}
+
+// askMasterState 主节点主观下线时询问其他哨兵 故障转移期间同时请求对方投票
+func (s *SentinelServer) askMasterState(m *masterState, p *peer, now time.Time) {
+	if !m.inst.sdown || p.askPending || now.Sub(p.lastAsk) < askPeriod {
+		return
+	}
+	runID := "*"
+	if m.state != failoverNone {
+		if now.Before(m.startTime) {
+			return
+		}
+		runID = s.runID
+	}
+	p.askPending = true
+	p.lastAsk = now
+	args := []string{"SENTINEL", "is-master-down-by-addr", m.inst.host, m.inst.port, strconv.FormatInt(s.currentEpoch, 10), runID}
+	go func() {
+		reply, err := p.link.Call(args...)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		p.askPending = false
+		items, ok := reply.([]any)
+		if err != nil || !ok || len(items) != 3 {
+			return
+		}
+		down, _ := items[0].(int64)
+		leader, _ := items[1].(string)
+		leaderEpoch, _ := items[2].(int64)
+		p.lastReply = time.Now()
+		p.masterDown = down == 1
+		if leader != "*" {
+			p.leader = leader
+			p.leaderEpoch = leaderEpoch
+		}
+	}()
+}
+
+// sendHello 周期性向其他哨兵宣告自己以及当前的主节点配置
+func (s *SentinelServer) sendHello(m *masterState, p *peer, now time.Time) {
+	if p.helloPending || now.Sub(p.lastHelloSent) < helloPeriod {
+		return
+	}
+	p.helloPending = true
+	p.lastHelloSent = now
+	addr := m.currentAddr()
+	tail := []string{s.Cfg.Port, s.runID, strconv.FormatInt(s.currentEpoch, 10),
+		m.name, addr.host, addr.port, strconv.FormatInt(m.configEpoch, 10)}
+	go func() {
+		var reply any
+		ip, err := p.link.LocalHost()
+		if err == nil {
+			reply, err = p.link.Call(append([]string{"SENTINEL", "HELLO", ip}, tail...)...)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		p.helloPending = false
+		runID, ok := reply.(string)
+		if err != nil || !ok {
+			return
+		}
+		if runID == s.runID {
+			// known_sentinels 中包含了自己
+			delete(m.sentinels, p.addr())
+			p.link.Close()
+			return
+		}
+		for addr, other := range m.sentinels {
+			if other != p && other.runID == runID {
+				// 同一个哨兵以不同地址出现 保留先发现的
+				delete(m.sentinels, addr)
+				other.link.Close()
+			}
+		}
+		p.runID = runID
+	}()
+}
+
+// Hello 收到其他哨兵的 hello: 记录该哨兵, 同步纪元, 采用配置纪元更大的主节点地址
+func (s *SentinelServer) Hello(h replication.SentinelHello) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h.RunID == s.runID {
+		return
+	}
+	if h.CurrentEpoch > s.currentEpoch {
+		s.currentEpoch = h.CurrentEpoch
+		log.Printf("+new-epoch %d", s.currentEpoch)
+	}
+	m, ok := s.masters[h.MasterName]
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	var found *peer
+	for _, p := range m.sentinels {
+		if p.runID == h.RunID || (p.host == h.IP && p.port == h.Port) {
+			found = p
+			break
+		}
+	}
+	if found == nil {
+		found = newPeer(h.IP, h.Port)
+		m.sentinels[found.addr()] = found
+		log.Printf("+sentinel sentinel %s %s @ %s", found.addr(), h.RunID, m.name)
+	}
+	found.runID = h.RunID
+	found.lastHello = now
+
+	if h.ConfigEpoch > m.configEpoch {
+		m.configEpoch = h.ConfigEpoch
+		if h.MasterIP != m.inst.host || h.MasterPort != m.inst.port {
+			if m.state != failoverNone {
+				s.abortFailover(m, "config-update", now)
+			}
+			s.switchMaster(m, h.MasterIP, h.MasterPort, now)
+		}
+	}
+}
+
+// voteLeader 在 reqEpoch 中为 reqRunID 投票 每个纪元只投一次, 先到先得
+func (s *SentinelServer) voteLeader(m *masterState, reqEpoch int64, reqRunID string, now time.Time) (string, int64) {
+	if reqEpoch > s.currentEpoch {
+		s.currentEpoch = reqEpoch
+		log.Printf("+new-epoch %d", s.currentEpoch)
+	}
+	if m.leaderEpoch < reqEpoch && s.currentEpoch <= reqEpoch {
+		m.leader = reqRunID
+		m.leaderEpoch = s.currentEpoch
+		log.Printf("+vote-for-leader %s %d", reqRunID, m.leaderEpoch)
+		// 投给了别人 推迟自己发起故障转移
+		if reqRunID != s.runID {
+			m.startTime = now.Add(time.Duration(rand.Int63n(int64(maxDesync))))
+		}
+	}
+	return m.leader, m.leaderEpoch
+}
+
+// IsMasterDownByAddr SENTINEL is-master-down-by-addr <ip> <port> <current-epoch> <runid>
+func (s *SentinelServer) IsMasterDownByAddr(host, port string, epoch int64, runID string) (bool, string, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.masters {
+		if m.inst.host != host || m.inst.port != port {
+			continue
+		}
+		leader, leaderEpoch := "*", int64(0)
+		if runID != "*" {
+			leader, leaderEpoch = s.voteLeader(m, epoch, runID, time.Now())
+		}
+		return m.inst.sdown, leader, leaderEpoch
+	}
+	return false, "*", 0
+}
+
+func (s *SentinelServer) MyID() string {
+	return s.runID
+}
+
+// MasterAddr SENTINEL get-master-addr-by-name
+func (s *SentinelServer) MasterAddr(name string) (string, string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.masters[name]
+	if !ok {
+		return "", "", false
+	}
+	addr := m.currentAddr()
+	return addr.host, addr.port, true
+}
+
+func (s *SentinelServer) Masters() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.masters))
+	for name := range s.masters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([][]string, 0, len(names))
+	for _, name := range names {
+		out = append(out, s.masterFields(s.masters[name], time.Now()))
+	}
+	return out
+}
+
+func (s *SentinelServer) Master(name string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.masters[name]
+	if !ok {
+		return nil, replication.ErrNoSuchMaster
+	}
+	return s.masterFields(m, time.Now()), nil
+}
+
+func (s *SentinelServer) Replicas(name string) ([][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.masters[name]
+	if !ok {
+		return nil, replication.ErrNoSuchMaster
+	}
+	now := time.Now()
+	out := make([][]string, 0, len(m.replicas))
+	for _, addr := range sortedKeys(m.replicas) {
+		r := m.replicas[addr]
+		flags := "slave"
+		if r.sdown {
+			flags += ",s_down"
+		}
+		if r == m.promoted {
+			flags += ",promoted"
+		}
+		linkStatus := "err"
+		if r.info.linkUp {
+			linkStatus = "ok"
+		}
+		out = append(out, []string{
+			"name", addr,
+			"ip", r.host,
+			"port", r.port,
+			"flags", flags,
+			"last-ping-sent", strconv.FormatInt(r.pingDelay(now).Milliseconds(), 10),
+			"last-ok-ping-reply", strconv.FormatInt(now.Sub(r.lastPong).Milliseconds(), 10),
+			"info-refresh", strconv.FormatInt(now.Sub(r.lastInfo).Milliseconds(), 10),
+			"role-reported", r.info.role,
+			"master-link-status", linkStatus,
+			"master-host", r.info.masterHost,
+			"master-port", r.info.masterPort,
+			"slave-priority", strconv.Itoa(r.info.priority),
+			"slave-repl-offset", strconv.FormatInt(r.info.offset, 10),
+		})
+	}
+	return out, nil
+}
+
+func (s *SentinelServer) Sentinels(name string) ([][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.masters[name]
+	if !ok {
+		return nil, replication.ErrNoSuchMaster
+	}
+	now := time.Now()
+	out := make([][]string, 0, len(m.sentinels))
+	for _, addr := range sortedKeys(m.sentinels) {
+		p := m.sentinels[addr]
+		lastHello := "-1"
+		if !p.lastHello.IsZero() {
+			lastHello = strconv.FormatInt(now.Sub(p.lastHello).Milliseconds(), 10)
+		}
+		leader := p.leader
+		if leader == "" {
+			leader = "*"
+		}
+		out = append(out, []string{
+			"name", addr,
+			"ip", p.host,
+			"port", p.port,
+			"runid", p.runID,
+			"flags", "sentinel",
+			"last-hello-message", lastHello,
+			"voted-leader", leader,
+			"voted-leader-epoch", strconv.FormatInt(p.leaderEpoch, 10),
+		})
+	}
+	return out, nil
+}
+
+// CkQuorum 检查当前可用的哨兵能否达到 quorum 并授权故障转移 (多数派)
+func (s *SentinelServer) CkQuorum(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.masters[name]
+	if !ok {
+		return "", replication.ErrNoSuchMaster
+	}
+	now := time.Now()
+	usable := 1
+	for _, p := range m.sentinels {
+		if !p.lastHello.IsZero() && now.Sub(p.lastHello) <= 5*helloPeriod {
+			usable++
+		}
+	}
+	voters := len(m.sentinels) + 1
+	if usable < m.cfg.Quorum {
+		return "", fmt.Errorf("NOQUORUM %d usable Sentinels. Not enough available Sentinels to reach the specified quorum for this master", usable)
+	}
+	if usable < voters/2+1 {
+		return "", fmt.Errorf("NOQUORUM %d usable Sentinels. Not enough available Sentinels to reach the majority and authorize a failover", usable)
+	}
+	return fmt.Sprintf("OK %d usable Sentinels. Quorum and failover authorization can be reached", usable), nil
+}
+
+func (s *SentinelServer) masterFields(m *masterState, now time.Time) []string {
+	flags := "master"
+	if m.inst.sdown {
+		flags += ",s_down"
+	}
+	if m.odown {
+		flags += ",o_down"
+	}
+	if m.state != failoverNone {
+		flags += ",failover_in_progress"
+	}
+	return []string{
+		"name", m.name,
+		"ip", m.inst.host,
+		"port", m.inst.port,
+		"flags", flags,
+		"last-ping-sent", strconv.FormatInt(m.inst.pingDelay(now).Milliseconds(), 10),
+		"last-ok-ping-reply", strconv.FormatInt(now.Sub(m.inst.lastPong).Milliseconds(), 10),
+		"info-refresh", strconv.FormatInt(now.Sub(m.inst.lastInfo).Milliseconds(), 10),
+		"role-reported", m.inst.info.role,
+		"config-epoch", strconv.FormatInt(m.configEpoch, 10),
+		"num-slaves", strconv.Itoa(len(m.replicas)),
+		"num-other-sentinels", strconv.Itoa(len(m.sentinels)),
+		"quorum", strconv.Itoa(m.cfg.Quorum),
+		"down-after-milliseconds", strconv.Itoa(m.cfg.DownAfterMs),
+		"failover-timeout", strconv.Itoa(m.cfg.FailoverTimeoutMs),
+		"parallel-syncs", strconv.Itoa(m.cfg.ParallelSyncs),
+		"failover-state", m.state.String(),
+	}
+}
+
+// SentinelInfo INFO sentinel 段
+func (s *SentinelServer) SentinelInfo() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines := []string{
+		fmt.Sprintf("sentinel_masters:%d", len(s.masters)),
+		"sentinel_id:" + s.runID,
+		fmt.Sprintf("sentinel_current_epoch:%d", s.currentEpoch),
+	}
+	for i, name := range sortedKeys(s.masters) {
+		m := s.masters[name]
+		status := "ok"
+		if m.odown {
+			status = "odown"
+		} else if m.inst.sdown {
+			status = "sdown"
+		}
+		lines = append(lines, fmt.Sprintf("master%d:name=%s,status=%s,address=%s,slaves=%d,sentinels=%d",
+			i, name, status, m.inst.addr(), len(m.replicas), len(m.sentinels)+1))
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// ReplicationInfo 哨兵不参与复制
+func (s *SentinelServer) ReplicationInfo() string {
+	return "role:sentinel\r\n"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,207 @@
+package sentinel
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/app/internal/protocol"
+	"github.com/go-playground/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSentinel(host, port string, downAfterMs, quorum int) (*SentinelServer, *masterState) {
+	s := NewSentinelServer(&config.ServerConfig{Sentinel: config.SentinelConfig{Monitors: []config.MonitorConfig{{
+		Name: "mymaster", Host: host, Port: port, Quorum: quorum,
+		DownAfterMs: downAfterMs, FailoverTimeoutMs: 60000, ParallelSyncs: 1,
+	}}}})
+	return s, s.masters["mymaster"]
+}
+
+// fakeMaster 回复 PING 与 INFO 的主节点 paused 时读取命令但不回复 (模拟卡住)
+type fakeMaster struct {
+	l      net.Listener
+	paused atomic.Bool
+}
+
+func startFakeMaster(t *testing.T) *fakeMaster {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeMaster{l: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return f
+}
+
+func (f *fakeMaster) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := protocol.NewReader(conn)
+	for {
+		cmd, _, err := rd.ReadRequest()
+		if err != nil {
+			return
+		}
+		if f.paused.Load() {
+			continue
+		}
+		switch strings.ToUpper(cmd) {
+		case "PING":
+			conn.Write([]byte("+PONG\r\n"))
+		case "INFO":
+			info := "# Replication\r\nrole:master\r\nconnected_slaves:0\r\n"
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(info), info)
+		default:
+			conn.Write([]byte("-ERR unknown command\r\n"))
+		}
+	}
+}
+
+func (f *fakeMaster) hostPort() (string, string) {
+	host, port, _ := net.SplitHostPort(f.l.Addr().String())
+	return host, port
+}
+
+// 未回复的时间从最早一个未回复的 PING 的发送时间算起
+func TestCheckDownTiming(t *testing.T) {
+	s, m := newTestSentinel("127.0.0.1", "1", 1000, 2)
+	now := time.Now()
+	inst := m.inst
+
+	// 上次回复已超过 down-after, 但之后发出的 PING 刚发送: 不是主观下线
+	inst.pong(now.Add(-1100 * time.Millisecond))
+	inst.actPingTime = now.Add(-100 * time.Millisecond)
+	s.checkDown(m, now)
+	assert.Equal(t, false, inst.sdown)
+
+	inst.actPingTime = now.Add(-1001 * time.Millisecond)
+	s.checkDown(m, now)
+	assert.Equal(t, true, inst.sdown)
+	// 只有自己认为下线 未达到 quorum
+	assert.Equal(t, false, m.odown)
+
+	p := newPeer("127.0.0.1", "2")
+	m.sentinels[p.addr()] = p
+	p.masterDown, p.lastReply = true, now
+	s.checkDown(m, now)
+	assert.Equal(t, true, m.odown)
+
+	// 其他哨兵的回复过期后不再计入
+	p.lastReply = now.Add(-replyValidPeriod - time.Millisecond)
+	s.checkDown(m, now)
+	assert.Equal(t, true, inst.sdown)
+	assert.Equal(t, false, m.odown)
+
+	p.lastReply = now
+	inst.pong(now)
+	s.checkDown(m, now)
+	assert.Equal(t, false, inst.sdown)
+	assert.Equal(t, false, m.odown)
+
+	// 从未回复的实例从创建时开始计时
+	r := newInstance("127.0.0.1", "3")
+	m.replicas[r.addr()] = r
+	s.checkDown(m, r.actPingTime.Add(time.Second))
+	assert.Equal(t, false, r.sdown)
+	s.checkDown(m, r.actPingTime.Add(1001*time.Millisecond))
+	assert.Equal(t, true, r.sdown)
+}
+
+// down-after-milliseconds 不大于 PING 周期时 正常的主节点不应反复进入主观下线;
+// 停止回复后在 down-after 加一个 PING 周期内判定下线, 恢复后取消
+func TestHealthyMasterStaysUp(t *testing.T) {
+	f := startFakeMaster(t)
+	host, port := f.hostPort()
+	s, m := newTestSentinel(host, port, 300, 2)
+
+	flapped := false
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		s.tick(time.Now())
+		s.mu.Lock()
+		flapped = flapped || m.inst.sdown
+		s.mu.Unlock()
+		time.Sleep(cronPeriod / 10)
+	}
+	assert.Equal(t, false, flapped)
+
+	f.paused.Store(true)
+	down := false
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline) && !down; {
+		s.tick(time.Now())
+		s.mu.Lock()
+		down = m.inst.sdown
+		s.mu.Unlock()
+		time.Sleep(cronPeriod / 10)
+	}
+	assert.Equal(t, true, down)
+
+	// 卡住的调用在 callTimeout 后断开 重连后收到回复
+	f.paused.Store(false)
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline) && down; {
+		s.tick(time.Now())
+		s.mu.Lock()
+		down = m.inst.sdown
+		s.mu.Unlock()
+		time.Sleep(cronPeriod / 10)
+	}
+	assert.Equal(t, false, down)
+}
+
+func TestSelectReplica(t *testing.T) {
+	s, m := newTestSentinel("127.0.0.1", "1", 1000, 2)
+	now := time.Now()
+	add := func(port string, priority int, offset int64) *instance {
+		r := newInstance("127.0.0.1", port)
+		r.info = instanceInfo{role: "slave", priority: priority, offset: offset}
+		r.lastInfo = now
+		m.replicas[r.addr()] = r
+		return r
+	}
+	assert.Equal(t, (*instance)(nil), s.selectReplica(m, now))
+
+	a := add("10", 100, 500)
+	b := add("11", 100, 900)
+	c := add("12", 50, 100)
+	zero := add("13", 0, 10000)
+
+	// priority 越小越优先 priority 为 0 的副本永不晋升
+	assert.Equal(t, c, s.selectReplica(m, now))
+	// 相同 priority 时偏移量大者优先
+	c.sdown = true
+	assert.Equal(t, b, s.selectReplica(m, now))
+	// INFO 过期的副本不参与
+	b.lastInfo = now.Add(-3*infoPeriod - time.Second)
+	assert.Equal(t, a, s.selectReplica(m, now))
+	// 偏移量也相同时按地址排序
+	d := add("09", 100, 500)
+	assert.Equal(t, d, s.selectReplica(m, now))
+
+	// 主节点下线时 INFO 每秒刷新, 有效期缩短
+	m.inst.sdown = true
+	a.lastInfo = now.Add(-6 * time.Second)
+	d.lastInfo = now.Add(-6 * time.Second)
+	assert.Equal(t, (*instance)(nil), s.selectReplica(m, now))
+	assert.Equal(t, 0, zero.info.priority)
+}
+
+func TestParseInfo(t *testing.T) {
+	info := parseInfo("# Replication\r\nrole:slave\r\nmaster_host:127.0.0.1\r\nmaster_port:6379\r\n" +
+		"master_link_status:up\r\nslave_repl_offset:1234\r\nslave_priority:10\r\n")
+	assert.Equal(t, instanceInfo{role: "slave", masterHost: "127.0.0.1", masterPort: "6379", linkUp: true, offset: 1234, priority: 10}, info)
+
+	info = parseInfo("role:master\r\nconnected_slaves:2\r\nslave0:ip=127.0.0.1,port=7001,state=online,offset=10,lag=0\r\n" +
+		"slave1:ip=127.0.0.1,port=7002,state=online,offset=10,lag=1\r\n")
+	assert.Equal(t, []string{"127.0.0.1:7001", "127.0.0.1:7002"}, info.replicas)
+	assert.Equal(t, 100, info.priority)
+}
@@ -0,0 +1,171 @@
+package kvstore
+
+import "math/bits"
+
+// 位图直接保存在字符串值中 位 0 是第 0 字节的最高位
+
+// BitOp 位运算的类型
+type BitOp int
+
+const (
+	BitAnd BitOp = iota
+	BitOr
+	BitXor
+	BitNot
+	BitDiff // 第一个源中有 其余源中都没有的位
+)
+
+// word 以小端序读取 s[i:i+8] (只用于统计与跳过, 字节序不影响结果)
+func word(s string, i int) uint64 {
+	return uint64(s[i]) | uint64(s[i+1])<<8 | uint64(s[i+2])<<16 | uint64(s[i+3])<<24 |
+		uint64(s[i+4])<<32 | uint64(s[i+5])<<40 | uint64(s[i+6])<<48 | uint64(s[i+7])<<56
+}
+
+// PopCount 统计置 1 的位数 每次处理 8 字节
+func PopCount(s string) int {
+	n, i := 0, 0
+	for ; i+32 <= len(s); i += 32 {
+		n += bits.OnesCount64(word(s, i)) + bits.OnesCount64(word(s, i+8)) +
+			bits.OnesCount64(word(s, i+16)) + bits.OnesCount64(word(s, i+24))
+	}
+	for ; i+8 <= len(s); i += 8 {
+		n += bits.OnesCount64(word(s, i))
+	}
+	for ; i < len(s); i++ {
+		n += bits.OnesCount8(s[i])
+	}
+	return n
+}
+
+// CountBits 统计位区间 [lo, hi] 中置 1 的位数 hi 不能超出 s
+func CountBits(s string, lo, hi int64) int64 {
+	if lo > hi {
+		return 0
+	}
+	first, last := lo>>3, hi>>3
+	n := PopCount(s[first : last+1])
+	// 去掉首字节中 lo 之前与尾字节中 hi 之后的位
+	n -= bits.OnesCount8(s[first] &^ (0xFF >> (lo & 7)))
+	n -= bits.OnesCount8(s[last] & (0xFF >> (hi&7 + 1)))
+	return int64(n)
+}
+
+// GetBit 读取 offset 处的位 超出长度时为 0
+func GetBit(s string, offset int64) int {
+	if offset>>3 >= int64(len(s)) {
+		return 0
+	}
+	return int(s[offset>>3]>>(7-offset&7)) & 1
+}
+
+// FindBit 返回位区间 [lo, hi] 中第一个等于 bit 的位置 没有时返回 -1
+// 整字节 (以及 8 字节一组) 地跳过全 0 / 全 1 的部分
+func FindBit(s string, bit int, lo, hi int64) int64 {
+	skip, skipWord := byte(0), uint64(0)
+	if bit == 0 {
+		skip, skipWord = 0xFF, ^uint64(0)
+	}
+	end := int((hi + 1) >> 3) // 最后一个完整字节之后
+	for i := lo; i <= hi; {
+		if i&7 == 0 {
+			j := int(i >> 3)
+			for j+8 <= end && word(s, j) == skipWord {
+				j += 8
+			}
+			for j < end && s[j] == skip {
+				j++
+			}
+			if int64(j)<<3 != i {
+				i = int64(j) << 3
+				continue
+			}
+		}
+		if GetBit(s, i) == bit {
+			return i
+		}
+		i++
+	}
+	return -1
+}
+
+// SetBit 修改 offset 处的位并返回原来的值 buf 必须足够长
+func SetBit(buf []byte, offset int64, bit int) int {
+	b, shift := offset>>3, 7-offset&7
+	old := int(buf[b]>>shift) & 1
+	buf[b] = buf[b]&^(1<<shift) | byte(bit)<<shift
+	return old
+}
+
+// GetBits 读取从 offset 开始 width 位的无符号整数 (高位在前) 超出 buf 的部分为 0
+func GetBits(buf []byte, offset int64, width int) uint64 {
+	var v uint64
+	for i := int64(0); i < int64(width); i++ {
+		b := (offset + i) >> 3
+		bit := uint64(0)
+		if b < int64(len(buf)) {
+			bit = uint64(buf[b]>>(7-(offset+i)&7)) & 1
+		}
+		v = v<<1 | bit
+	}
+	return v
+}
+
+// SetBits 把 v 的低 width 位写入从 offset 开始的位置 (高位在前) buf 必须足够长
+func SetBits(buf []byte, offset int64, width int, v uint64) {
+	for i := int64(0); i < int64(width); i++ {
+		bit := int(v>>(int64(width)-1-i)) & 1
+		SetBit(buf, offset+i, bit)
+	}
+}
+
+// GetSignedBits 与 GetBits 相同 但按补码解释最高位
+func GetSignedBits(buf []byte, offset int64, width int) int64 {
+	v := GetBits(buf, offset, width)
+	if width < 64 && v&(1<<(width-1)) != 0 {
+		v |= ^uint64(0) << width
+	}
+	return int64(v)
+}
+
+// BitOperation 对 srcs 做位运算 较短的源在末尾补 0, 结果长度为最长的源
+// BitNot 只使用第一个源
+func BitOperation(op BitOp, srcs []string) []byte {
+	if op == BitNot {
+		res := []byte(srcs[0])
+		for i := range res {
+			res[i] = ^res[i]
+		}
+		return res
+	}
+	n := 0
+	for _, s := range srcs {
+		n = max(n, len(s))
+	}
+	res := make([]byte, n)
+	copy(res, srcs[0])
+	if op == BitDiff {
+		// res & ^(其余源的并集)
+		union := BitOperation(BitOr, srcs[1:])
+		for i, b := range union {
+			res[i] &^= b
+		}
+		return res
+	}
+	for _, s := range srcs[1:] {
+		for i := range res {
+			var b byte
+			if i < len(s) {
+				b = s[i]
+			}
+			switch op {
+			case BitAnd:
+				res[i] &= b
+			case BitOr:
+				res[i] |= b
+			case BitXor:
+				res[i] ^= b
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,76 @@
+package kvstore
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestBitmapCount(t *testing.T) {
+	// 与逐位统计的结果对比 覆盖 8/32 字节分组与首尾不完整字节
+	buf := make([]byte, 203)
+	rand.Read(buf)
+	s := string(buf)
+	naive := func(lo, hi int64) int64 {
+		var n int64
+		for i := lo; i <= hi; i++ {
+			n += int64(GetBit(s, i))
+		}
+		return n
+	}
+	assert.Equal(t, int(naive(0, int64(len(s))*8-1)), PopCount(s))
+	for _, r := range [][2]int64{{0, 7}, {3, 3}, {5, 12}, {7, 1000}, {9, 1623}, {100, 99}} {
+		assert.Equal(t, naive(r[0], r[1]), CountBits(s, r[0], r[1]))
+	}
+
+	// "foobar" 的示例来自 BITCOUNT 文档
+	assert.Equal(t, 26, PopCount("foobar"))
+	assert.Equal(t, int64(6), CountBits("foobar", 8, 15))
+	assert.Equal(t, int64(17), CountBits("foobar", 5, 30))
+}
+
+func TestBitmapFind(t *testing.T) {
+	s := "\xff\xf0\x00"
+	assert.Equal(t, int64(12), FindBit(s, 0, 0, 23))
+	assert.Equal(t, int64(0), FindBit(s, 1, 0, 23))
+	assert.Equal(t, int64(8), FindBit(s, 1, 8, 23))
+	assert.Equal(t, int64(-1), FindBit(s, 1, 12, 23))
+	assert.Equal(t, int64(-1), FindBit(s, 0, 0, 11))
+
+	// 跨过大段全 0 / 全 1 的字节
+	s = strings.Repeat("\x00", 100) + "\x01"
+	assert.Equal(t, int64(807), FindBit(s, 1, 0, 807))
+	assert.Equal(t, int64(-1), FindBit(s, 1, 0, 806))
+	s = strings.Repeat("\xff", 37) + "\xfe"
+	assert.Equal(t, int64(303), FindBit(s, 0, 3, 303))
+}
+
+func TestBitmapFields(t *testing.T) {
+	buf := make([]byte, 3)
+	assert.Equal(t, 0, SetBit(buf, 7, 1))
+	assert.Equal(t, 1, SetBit(buf, 7, 1))
+	assert.Equal(t, []byte{0x01, 0, 0}, buf)
+
+	// 跨字节写入 高位在前
+	SetBits(buf, 4, 12, 0xABC)
+	assert.Equal(t, []byte{0x0A, 0xBC, 0}, buf)
+	assert.Equal(t, uint64(0xABC), GetBits(buf, 4, 12))
+	assert.Equal(t, int64(-1348), GetSignedBits(buf, 4, 12))
+	// 超出长度的部分按 0 读取
+	assert.Equal(t, uint64(0xC0), GetBits(buf, 12, 8))
+	assert.Equal(t, uint64(0), GetBits(buf, 100, 64))
+
+	SetBits(buf, 0, 24, 1<<24-1)
+	assert.Equal(t, int64(-1), GetSignedBits(buf, 0, 24))
+}
+
+func TestBitOperation(t *testing.T) {
+	a, b, c := "\xf0\x0f", "\x3c", "\x31\x01\x01"
+	assert.Equal(t, []byte{0x30, 0x00, 0x00}, BitOperation(BitAnd, []string{a, b, c}))
+	assert.Equal(t, []byte{0xfd, 0x0f, 0x01}, BitOperation(BitOr, []string{a, b, c}))
+	assert.Equal(t, []byte{0xfd, 0x0e, 0x01}, BitOperation(BitXor, []string{a, b, c}))
+	assert.Equal(t, []byte{0x0f, 0xf0}, BitOperation(BitNot, []string{a}))
+	assert.Equal(t, []byte{0xc0, 0x0e, 0x00}, BitOperation(BitDiff, []string{a, b, c}))
+}
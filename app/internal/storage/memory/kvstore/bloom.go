@@ -0,0 +1,168 @@
+package kvstore
+
+import (
+	"errors"
+	"math"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// Bloom 可扩展布隆过滤器 (参数与 RedisBloom 相同)
+// 最后一层满后新增一层: 容量为上一层的 Expansion 倍, 误判率为上一层的一半, 总误判率不超过 2*ErrorRate
+// Expansion 为 0 表示不扩展 (NONSCALING), 满后拒绝插入
+type Bloom struct {
+	ErrorRate float64
+	Expansion uint64
+	Layers    []*BloomLayer
+}
+
+// BloomLayer 一层过滤器 位数组的长度为 64 的倍数
+type BloomLayer struct {
+	Capacity uint64
+	Count    uint64
+	Error    float64
+	Hashes   uint64
+	Bits     []byte
+}
+
+const (
+	BloomDefaultErrorRate = 0.01
+	BloomDefaultCapacity  = 100
+	BloomDefaultExpansion = 2
+
+	bloomMaxBytes = 512 << 20 // 单层位数组的上限 与字符串的最大长度相同
+)
+
+var (
+	ErrBloomFull     = errors.New("ERR non scaling filter is full")
+	ErrBloomTooLarge = errors.New("ERR filter size is too large")
+)
+
+// NewBloom 创建只有一层的过滤器
+func NewBloom(errorRate float64, capacity, expansion uint64) (*Bloom, error) {
+	l, err := newBloomLayer(capacity, errorRate)
+	if err != nil {
+		return nil, err
+	}
+	return &Bloom{ErrorRate: errorRate, Expansion: expansion, Layers: []*BloomLayer{l}}, nil
+}
+
+// newBloomLayer 每个元素的位数 bpe = -ln(e)/ln2², 哈希函数个数为 ceil(ln2*bpe)
+func newBloomLayer(capacity uint64, errorRate float64) (*BloomLayer, error) {
+	bpe := -math.Log(errorRate) / (math.Ln2 * math.Ln2)
+	bits := math.Ceil(float64(capacity) * bpe)
+	bits = math.Ceil(bits/64) * 64
+	if bits/8 > bloomMaxBytes {
+		return nil, ErrBloomTooLarge
+	}
+	return &BloomLayer{
+		Capacity: capacity,
+		Error:    errorRate,
+		Hashes:   uint64(math.Ceil(math.Ln2 * bpe)),
+		Bits:     make([]byte, int(bits/8)),
+	}, nil
+}
+
+// bloomHash 双重哈希的两个基础值 第 i 个位置为 h1 + i*h2
+func bloomHash(item string) (h1, h2 uint64) {
+	h1 = murmurHash64A(item, 0xc6a4a7935bd1e995)
+	return h1, murmurHash64A(item, h1)
+}
+
+func (l *BloomLayer) test(h1, h2 uint64) bool {
+	n := uint64(len(l.Bits)) * 8
+	for i := uint64(0); i < l.Hashes; i++ {
+		pos := (h1 + i*h2) % n
+		if l.Bits[pos/8]&(1<<(pos%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *BloomLayer) set(h1, h2 uint64) {
+	n := uint64(len(l.Bits)) * 8
+	for i := uint64(0); i < l.Hashes; i++ {
+		pos := (h1 + i*h2) % n
+		l.Bits[pos/8] |= 1 << (pos % 8)
+	}
+	l.Count++
+}
+
+// Exists 元素可能存在时返回 true
+func (b *Bloom) Exists(item string) bool {
+	h1, h2 := bloomHash(item)
+	for _, l := range b.Layers {
+		if l.test(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add 元素 (可能) 已存在时返回 false
+func (b *Bloom) Add(item string) (bool, error) {
+	h1, h2 := bloomHash(item)
+	for _, l := range b.Layers {
+		if l.test(h1, h2) {
+			return false, nil
+		}
+	}
+	last := b.Layers[len(b.Layers)-1]
+	if last.Count >= last.Capacity {
+		if b.Expansion == 0 {
+			return false, ErrBloomFull
+		}
+		l, err := newBloomLayer(last.Capacity*b.Expansion, last.Error/2)
+		if err != nil {
+			return false, err
+		}
+		b.Layers = append(b.Layers, l)
+		last = l
+	}
+	last.set(h1, h2)
+	return true, nil
+}
+
+// Capacity 所有层的容量之和
+func (b *Bloom) Capacity() uint64 {
+	var n uint64
+	for _, l := range b.Layers {
+		n += l.Capacity
+	}
+	return n
+}
+
+// Count 插入的元素个数
+func (b *Bloom) Count() uint64 {
+	var n uint64
+	for _, l := range b.Layers {
+		n += l.Count
+	}
+	return n
+}
+
+// Size 占用的字节数
+func (b *Bloom) Size() uint64 {
+	var n uint64
+	for _, l := range b.Layers {
+		n += uint64(len(l.Bits))
+	}
+	return n
+}
+
+func NewBloomObject(b *Bloom) *Object {
+	return NewObject(TypeBloom, EncRaw, b)
+}
+
+// Bloom 读取布隆过滤器 键不存在时返回 nil, 类型不符时返回 WRONGTYPE
+func (tx *Tx) Bloom(key string) (*Bloom, error) {
+	o, ok := tx.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if o.Type != TypeBloom {
+		return nil, errors_r.ErrWrongType
+	}
+	return o.Value.(*Bloom), nil
+}
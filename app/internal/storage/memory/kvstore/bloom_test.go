@@ -0,0 +1,49 @@
+package kvstore
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestBloom(t *testing.T) {
+	b, err := NewBloom(0.01, 100, 2)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, uint64(7), b.Layers[0].Hashes)
+	assert.Equal(t, 0, len(b.Layers[0].Bits)%8)
+
+	// 超出容量后新增一层 已插入的元素始终存在
+	for i := 0; i < 1000; i++ {
+		_, err := b.Add(strconv.Itoa(i))
+		assert.Equal(t, nil, err)
+	}
+	for i := 0; i < 1000; i++ {
+		assert.Equal(t, true, b.Exists(strconv.Itoa(i)))
+	}
+	assert.Equal(t, true, len(b.Layers) > 1)
+	assert.Equal(t, 100*uint64(1<<len(b.Layers)-1), b.Capacity())
+	assert.Equal(t, 0.005, b.Layers[1].Error)
+	added, _ := b.Add("0")
+	assert.Equal(t, false, added)
+
+	falsePositives := 0
+	for i := 1000; i < 11000; i++ {
+		if b.Exists(strconv.Itoa(i)) {
+			falsePositives++
+		}
+	}
+	assert.Equal(t, true, falsePositives < 200)
+
+	// 不扩展的过滤器满后报错
+	b, _ = NewBloom(0.01, 10, 0)
+	var full error
+	for i := 0; i < 100 && full == nil; i++ {
+		_, full = b.Add(strconv.Itoa(i))
+	}
+	assert.Equal(t, ErrBloomFull, full)
+	assert.Equal(t, uint64(10), b.Count())
+
+	_, err = NewBloom(1e-9, 1<<40, 2)
+	assert.Equal(t, ErrBloomTooLarge, err)
+}
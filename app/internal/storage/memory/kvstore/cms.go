@@ -0,0 +1,101 @@
+package kvstore
+
+import (
+	"errors"
+	"math"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// CountMinSketch Depth 行 Width 列的计数器 元素在每行中按不同种子的哈希选一列
+// 计数为各行的最小值 (只会偏大)
+type CountMinSketch struct {
+	Width, Depth uint64
+	Count        uint64 // 所有增量之和
+	Counters     []uint32
+}
+
+const cmsMaxCounters = 1 << 28
+
+var (
+	ErrCMSOverflow = errors.New("CMS: INCRBY overflow")
+	ErrCMSSize     = errors.New("CMS: invalid width/depth")
+)
+
+func NewCountMinSketch(width, depth uint64) (*CountMinSketch, error) {
+	if width == 0 || depth == 0 || width > cmsMaxCounters/depth {
+		return nil, ErrCMSSize
+	}
+	return &CountMinSketch{Width: width, Depth: depth, Counters: make([]uint32, width*depth)}, nil
+}
+
+// CMSDimensions 误差 error (相对总数) 与概率 probability 对应的宽度与深度
+func CMSDimensions(errorRate, probability float64) (width, depth uint64) {
+	width = uint64(math.Ceil(2 / errorRate))
+	depth = uint64(math.Ceil(math.Log10(probability) / math.Log10(0.5)))
+	return width, depth
+}
+
+func (s *CountMinSketch) index(item string, row uint64) uint64 {
+	return row*s.Width + murmurHash64A(item, row)%s.Width
+}
+
+// Query 元素的估计计数
+func (s *CountMinSketch) Query(item string) uint32 {
+	n := uint32(math.MaxUint32)
+	for row := uint64(0); row < s.Depth; row++ {
+		n = min(n, s.Counters[s.index(item, row)])
+	}
+	return n
+}
+
+// IncrBy 增加计数 返回新的估计值 任一计数器溢出时不做修改
+func (s *CountMinSketch) IncrBy(item string, incr uint32) (uint32, error) {
+	for row := uint64(0); row < s.Depth; row++ {
+		if s.Counters[s.index(item, row)] > math.MaxUint32-incr {
+			return 0, ErrCMSOverflow
+		}
+	}
+	for row := uint64(0); row < s.Depth; row++ {
+		s.Counters[s.index(item, row)] += incr
+	}
+	s.Count += uint64(incr)
+	return s.Query(item), nil
+}
+
+// Merge 以加权和覆盖 s 的计数器 (s 也可以是来源之一), 来源的维度必须与 s 相同
+func (s *CountMinSketch) Merge(srcs []*CountMinSketch, weights []int64) error {
+	counters := make([]uint32, len(s.Counters))
+	var count int64
+	for i := range counters {
+		var sum int64
+		for j, src := range srcs {
+			sum += int64(src.Counters[i]) * weights[j]
+		}
+		if sum < 0 || sum > math.MaxUint32 {
+			return ErrCMSOverflow
+		}
+		counters[i] = uint32(sum)
+	}
+	for j, src := range srcs {
+		count += int64(src.Count) * weights[j]
+	}
+	s.Counters, s.Count = counters, uint64(max(count, 0))
+	return nil
+}
+
+func NewCMSObject(s *CountMinSketch) *Object {
+	return NewObject(TypeCMS, EncRaw, s)
+}
+
+// CMS 读取 count-min sketch 键不存在时返回 nil, 类型不符时返回 WRONGTYPE
+func (tx *Tx) CMS(key string) (*CountMinSketch, error) {
+	o, ok := tx.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if o.Type != TypeCMS {
+		return nil, errors_r.ErrWrongType
+	}
+	return o.Value.(*CountMinSketch), nil
+}
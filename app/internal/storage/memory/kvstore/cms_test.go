@@ -0,0 +1,39 @@
+package kvstore
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestCountMinSketch(t *testing.T) {
+	w, d := CMSDimensions(0.001, 0.01)
+	assert.Equal(t, uint64(2000), w)
+	assert.Equal(t, uint64(7), d)
+
+	s, err := NewCountMinSketch(2000, 5)
+	assert.Equal(t, nil, err)
+	n, _ := s.IncrBy("a", 3)
+	assert.Equal(t, uint32(3), n)
+	n, _ = s.IncrBy("a", 2)
+	assert.Equal(t, uint32(5), n)
+	s.IncrBy("b", 1)
+	assert.Equal(t, uint32(5), s.Query("a"))
+	assert.Equal(t, uint32(0), s.Query("c"))
+	assert.Equal(t, uint64(6), s.Count)
+
+	_, err = s.IncrBy("a", math.MaxUint32)
+	assert.Equal(t, ErrCMSOverflow, err)
+	assert.Equal(t, uint32(5), s.Query("a"))
+
+	// 合并时 s 自身也可以是来源
+	o, _ := NewCountMinSketch(2000, 5)
+	o.IncrBy("a", 1)
+	assert.Equal(t, nil, s.Merge([]*CountMinSketch{s, o}, []int64{1, 3}))
+	assert.Equal(t, uint32(8), s.Query("a"))
+	assert.Equal(t, uint64(9), s.Count)
+
+	_, err = NewCountMinSketch(0, 5)
+	assert.Equal(t, ErrCMSSize, err)
+}
@@ -0,0 +1,235 @@
+package kvstore
+
+import (
+	"errors"
+	"math/bits"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// Cuckoo 布谷鸟过滤器 支持删除
+// 指纹为 8 位 (非 0), 每个元素有两个候选桶: i1 = h mod n, i2 = (i1 ^ fp*0x5bd1e995) mod n (n 为 2 的幂, 两者互为备选)
+// 两个桶都满时把已有指纹踢到它的备选桶, 最多 MaxIterations 次; 仍失败时撤销踢出,
+// 新增一层 (桶数为上一层的 Expansion 倍) 插入, Expansion 为 0 时报错
+// 踢出的位置按轮次确定 不使用随机数, 副本执行相同的命令得到相同的结果
+type Cuckoo struct {
+	BucketSize    uint64
+	MaxIterations uint64
+	Expansion     uint64
+	Items         uint64
+	Deletes       uint64
+	Layers        []*CuckooLayer
+}
+
+// CuckooLayer 一层过滤器 Data 为 NumBuckets*BucketSize 个指纹, 0 表示空位
+type CuckooLayer struct {
+	NumBuckets uint64
+	Data       []byte
+}
+
+const (
+	CuckooDefaultCapacity      = 1024
+	CuckooDefaultBucketSize    = 2
+	CuckooDefaultMaxIterations = 20
+	CuckooDefaultExpansion     = 1
+)
+
+var ErrCuckooFull = errors.New("ERR Filter is full")
+
+// NewCuckoo 桶数为 capacity/bucketSize 向上取 2 的幂
+func NewCuckoo(capacity, bucketSize, maxIterations, expansion uint64) (*Cuckoo, error) {
+	l, err := newCuckooLayer(nextPow2((capacity+bucketSize-1)/bucketSize), bucketSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Cuckoo{
+		BucketSize:    bucketSize,
+		MaxIterations: maxIterations,
+		Expansion:     nextPow2(expansion),
+		Layers:        []*CuckooLayer{l},
+	}, nil
+}
+
+func newCuckooLayer(numBuckets, bucketSize uint64) (*CuckooLayer, error) {
+	if numBuckets*bucketSize > bloomMaxBytes || numBuckets > bloomMaxBytes {
+		return nil, ErrBloomTooLarge
+	}
+	return &CuckooLayer{NumBuckets: numBuckets, Data: make([]byte, numBuckets*bucketSize)}, nil
+}
+
+func nextPow2(n uint64) uint64 {
+	if n <= 1 {
+		return n
+	}
+	return 1 << bits.Len64(n-1)
+}
+
+func cuckooHash(item string) (h uint64, fp byte) {
+	h = murmurHash64A(item, 0)
+	return h, byte(h%255 + 1)
+}
+
+func (l *CuckooLayer) alt(i uint64, fp byte) uint64 {
+	return (i ^ uint64(fp)*0x5bd1e995) & (l.NumBuckets - 1)
+}
+
+func (l *CuckooLayer) indexes(h uint64, fp byte) (uint64, uint64) {
+	i1 := h & (l.NumBuckets - 1)
+	return i1, l.alt(i1, fp)
+}
+
+func (l *CuckooLayer) bucket(i, size uint64) []byte {
+	return l.Data[i*size : (i+1)*size]
+}
+
+// place 放入桶中的空位
+func (l *CuckooLayer) place(i, size uint64, fp byte) bool {
+	b := l.bucket(i, size)
+	for j := range b {
+		if b[j] == 0 {
+			b[j] = fp
+			return true
+		}
+	}
+	return false
+}
+
+// kick 从 i 开始逐个踢出指纹 失败时按相反的顺序换回, 过滤器保持原样
+func (l *CuckooLayer) kick(i, size, maxIterations uint64, fp byte) bool {
+	type move struct{ bucket, slot uint64 }
+	path := make([]move, 0, maxIterations)
+	for n := uint64(0); n < maxIterations; n++ {
+		slot := n % size
+		b := l.bucket(i, size)
+		fp, b[slot] = b[slot], fp
+		path = append(path, move{i, slot})
+		i = l.alt(i, fp)
+		if l.place(i, size, fp) {
+			return true
+		}
+	}
+	for k := len(path) - 1; k >= 0; k-- {
+		b := l.bucket(path[k].bucket, size)
+		fp, b[path[k].slot] = b[path[k].slot], fp
+	}
+	return false
+}
+
+// Add 插入元素 (允许重复)
+func (c *Cuckoo) Add(item string) error {
+	h, fp := cuckooHash(item)
+	for j := len(c.Layers) - 1; j >= 0; j-- {
+		l := c.Layers[j]
+		i1, i2 := l.indexes(h, fp)
+		if l.place(i1, c.BucketSize, fp) || l.place(i2, c.BucketSize, fp) {
+			c.Items++
+			return nil
+		}
+	}
+	last := c.Layers[len(c.Layers)-1]
+	i1, _ := last.indexes(h, fp)
+	if !last.kick(i1, c.BucketSize, c.MaxIterations, fp) {
+		if c.Expansion == 0 {
+			return ErrCuckooFull
+		}
+		l, err := newCuckooLayer(last.NumBuckets*c.Expansion, c.BucketSize)
+		if err != nil {
+			return err
+		}
+		c.Layers = append(c.Layers, l)
+		i1, _ = l.indexes(h, fp)
+		l.place(i1, c.BucketSize, fp)
+	}
+	c.Items++
+	return nil
+}
+
+// AddNX 元素 (可能) 已存在时不插入 返回 false
+func (c *Cuckoo) AddNX(item string) (bool, error) {
+	if c.Exists(item) {
+		return false, nil
+	}
+	if err := c.Add(item); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Count 元素指纹在候选桶中出现的次数 (可能偏大)
+func (c *Cuckoo) Count(item string) uint64 {
+	h, fp := cuckooHash(item)
+	var n uint64
+	for _, l := range c.Layers {
+		i1, i2 := l.indexes(h, fp)
+		for _, i := range []uint64{i1, i2} {
+			for _, v := range l.bucket(i, c.BucketSize) {
+				if v == fp {
+					n++
+				}
+			}
+			if i1 == i2 {
+				break
+			}
+		}
+	}
+	return n
+}
+
+func (c *Cuckoo) Exists(item string) bool {
+	return c.Count(item) > 0
+}
+
+// Delete 删除一个指纹 从最新的一层开始查找
+func (c *Cuckoo) Delete(item string) bool {
+	h, fp := cuckooHash(item)
+	for j := len(c.Layers) - 1; j >= 0; j-- {
+		l := c.Layers[j]
+		i1, i2 := l.indexes(h, fp)
+		for _, i := range []uint64{i1, i2} {
+			b := l.bucket(i, c.BucketSize)
+			for k := range b {
+				if b[k] == fp {
+					b[k] = 0
+					c.Items--
+					c.Deletes++
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// NumBuckets 所有层的桶数之和
+func (c *Cuckoo) NumBuckets() uint64 {
+	var n uint64
+	for _, l := range c.Layers {
+		n += l.NumBuckets
+	}
+	return n
+}
+
+// Size 占用的字节数
+func (c *Cuckoo) Size() uint64 {
+	var n uint64
+	for _, l := range c.Layers {
+		n += uint64(len(l.Data))
+	}
+	return n
+}
+
+func NewCuckooObject(c *Cuckoo) *Object {
+	return NewObject(TypeCuckoo, EncRaw, c)
+}
+
+// Cuckoo 读取布谷鸟过滤器 键不存在时返回 nil, 类型不符时返回 WRONGTYPE
+func (tx *Tx) Cuckoo(key string) (*Cuckoo, error) {
+	o, ok := tx.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if o.Type != TypeCuckoo {
+		return nil, errors_r.ErrWrongType
+	}
+	return o.Value.(*Cuckoo), nil
+}
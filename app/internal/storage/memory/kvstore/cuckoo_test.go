@@ -0,0 +1,46 @@
+package kvstore
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestCuckoo(t *testing.T) {
+	c, err := NewCuckoo(1000, 2, 20, 1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, uint64(512), c.NumBuckets())
+
+	for i := 0; i < 3000; i++ {
+		assert.Equal(t, nil, c.Add(strconv.Itoa(i)))
+	}
+	assert.Equal(t, true, len(c.Layers) > 1)
+	assert.Equal(t, uint64(3000), c.Items)
+	for i := 0; i < 3000; i++ {
+		assert.Equal(t, true, c.Exists(strconv.Itoa(i)))
+	}
+
+	// 重复插入与删除
+	assert.Equal(t, nil, c.Add("0"))
+	assert.Equal(t, true, c.Count("0") >= 2)
+	assert.Equal(t, true, c.Delete("0"))
+	assert.Equal(t, true, c.Delete("0"))
+	assert.Equal(t, uint64(2999), c.Items)
+	assert.Equal(t, uint64(2), c.Deletes)
+	added, _ := c.AddNX("1")
+	assert.Equal(t, false, added)
+
+	// 不扩展的过滤器满后报错, 失败的插入不改变已有的指纹
+	c, _ = NewCuckoo(8, 2, 5, 0)
+	var full error
+	n := 0
+	for ; n < 100 && full == nil; n++ {
+		full = c.Add(strconv.Itoa(n))
+	}
+	assert.Equal(t, ErrCuckooFull, full)
+	for i := 0; i < n-1; i++ {
+		assert.Equal(t, true, c.Exists(strconv.Itoa(i)))
+	}
+	assert.Equal(t, uint64(n-1), c.Items)
+}
@@ -0,0 +1,293 @@
+package kvstore
+
+import "math"
+
+// 地理位置以 52 位 geohash 作为有序集合的分值 (经纬度各 26 位交错, 纬度在偶数位)
+// 编码与区域搜索的算法与 Redis 相同, 分值可以互通
+const (
+	GeoLonMin = -180.0
+	GeoLonMax = 180.0
+	GeoLatMin = -85.05112878
+	GeoLatMax = 85.05112878
+
+	geoStepMax       = 26
+	earthRadius      = 6372797.560856 // 米
+	mercatorMax      = 20037726.37
+	geoStandardLatLo = -90.0
+	geoStandardLatHi = 90.0
+)
+
+const geoAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// geoHash 某一精度 (step 位/坐标) 的 geohash
+type geoHash struct {
+	bits uint64
+	step uint
+}
+
+// geoArea geohash 覆盖的经纬度范围
+type geoArea struct {
+	lonMin, lonMax, latMin, latMax float64
+}
+
+// interleave 把 x 放在偶数位, y 放在奇数位
+func interleave(x, y uint32) uint64 {
+	spread := func(v uint32) uint64 {
+		u := uint64(v)
+		u = (u | u<<16) & 0x0000FFFF0000FFFF
+		u = (u | u<<8) & 0x00FF00FF00FF00FF
+		u = (u | u<<4) & 0x0F0F0F0F0F0F0F0F
+		u = (u | u<<2) & 0x3333333333333333
+		u = (u | u<<1) & 0x5555555555555555
+		return u
+	}
+	return spread(x) | spread(y)<<1
+}
+
+// deinterleave interleave 的逆运算
+func deinterleave(bits uint64) (x, y uint32) {
+	squash := func(u uint64) uint32 {
+		u &= 0x5555555555555555
+		u = (u | u>>1) & 0x3333333333333333
+		u = (u | u>>2) & 0x0F0F0F0F0F0F0F0F
+		u = (u | u>>4) & 0x00FF00FF00FF00FF
+		u = (u | u>>8) & 0x0000FFFF0000FFFF
+		u = (u | u>>16) & 0x00000000FFFFFFFF
+		return uint32(u)
+	}
+	return squash(bits), squash(bits >> 1)
+}
+
+func geoEncode(lon, lat, latMin, latMax float64, step uint) geoHash {
+	latOffset := (lat - latMin) / (latMax - latMin) * float64(uint64(1)<<step)
+	lonOffset := (lon - GeoLonMin) / (GeoLonMax - GeoLonMin) * float64(uint64(1)<<step)
+	return geoHash{bits: interleave(uint32(latOffset), uint32(lonOffset)), step: step}
+}
+
+func geoDecode(h geoHash) geoArea {
+	ilat, ilon := deinterleave(h.bits)
+	scale := float64(uint64(1) << h.step)
+	latScale, lonScale := GeoLatMax-GeoLatMin, GeoLonMax-GeoLonMin
+	return geoArea{
+		latMin: GeoLatMin + float64(ilat)/scale*latScale,
+		latMax: GeoLatMin + float64(ilat+1)/scale*latScale,
+		lonMin: GeoLonMin + float64(ilon)/scale*lonScale,
+		lonMax: GeoLonMin + float64(ilon+1)/scale*lonScale,
+	}
+}
+
+// GeoValid 坐标是否在可编码的范围内
+func GeoValid(lon, lat float64) bool {
+	return lon >= GeoLonMin && lon <= GeoLonMax && lat >= GeoLatMin && lat <= GeoLatMax
+}
+
+// GeoScore 坐标对应的有序集合分值
+func GeoScore(lon, lat float64) float64 {
+	return float64(geoEncode(lon, lat, GeoLatMin, GeoLatMax, geoStepMax).bits)
+}
+
+// GeoPosition 分值对应的坐标 (geohash 区域的中心)
+func GeoPosition(score float64) (lon, lat float64) {
+	a := geoDecode(geoHash{bits: uint64(score), step: geoStepMax})
+	lon = max(min((a.lonMin+a.lonMax)/2, GeoLonMax), GeoLonMin)
+	lat = max(min((a.latMin+a.latMax)/2, GeoLatMax), GeoLatMin)
+	return lon, lat
+}
+
+// GeoHashString 标准的 11 位 geohash 字符串 (纬度范围为 -90..90, 需重新编码)
+func GeoHashString(score float64) string {
+	lon, lat := GeoPosition(score)
+	h := geoEncode(lon, lat, geoStandardLatLo, geoStandardLatHi, geoStepMax)
+	buf := make([]byte, 11)
+	for i := range buf {
+		idx := 0
+		// 只有 52 位 最后一个字符按 0 补齐
+		if i < 10 {
+			idx = int(h.bits>>(52-(i+1)*5)) & 0x1f
+		}
+		buf[i] = geoAlphabet[idx]
+	}
+	return string(buf)
+}
+
+func degRad(d float64) float64 {
+	return d * math.Pi / 180
+}
+
+func radDeg(r float64) float64 {
+	return r / (math.Pi / 180)
+}
+
+// GeoDistance 两点间的球面距离 (米, haversine)
+func GeoDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	lat1r, lat2r := degRad(lat1), degRad(lat2)
+	v := math.Sin((degRad(lon2) - degRad(lon1)) / 2)
+	// 经度相同时只需要计算纬度方向的距离
+	if v == 0 {
+		return geoLatDistance(lat1, lat2)
+	}
+	u := math.Sin((lat2r - lat1r) / 2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(u*u+math.Cos(lat1r)*math.Cos(lat2r)*v*v))
+}
+
+func geoLatDistance(lat1, lat2 float64) float64 {
+	return earthRadius * math.Abs(degRad(lat2)-degRad(lat1))
+}
+
+// GeoShape 搜索范围: 圆 (Radius) 或矩形 (Width x Height) 单位均为米
+type GeoShape struct {
+	Lon, Lat      float64
+	Box           bool
+	Radius        float64
+	Width, Height float64
+}
+
+// Within 点是否在范围内 返回到中心的距离 (米)
+func (s GeoShape) Within(lon, lat float64) (float64, bool) {
+	if !s.Box {
+		d := GeoDistance(s.Lon, s.Lat, lon, lat)
+		return d, d <= s.Radius
+	}
+	// 纬度方向的距离计算更快 先检查
+	if geoLatDistance(lat, s.Lat) > s.Height/2 {
+		return 0, false
+	}
+	if GeoDistance(lon, lat, s.Lon, lat) > s.Width/2 {
+		return 0, false
+	}
+	return GeoDistance(s.Lon, s.Lat, lon, lat), true
+}
+
+// boundingBox 包含搜索范围的经纬度矩形
+func (s GeoShape) boundingBox() (lonMin, latMin, lonMax, latMax float64) {
+	height, width := s.Radius, s.Radius
+	if s.Box {
+		height, width = s.Height/2, s.Width/2
+	}
+	latDelta := radDeg(height / earthRadius)
+	lonDeltaTop := radDeg(width / earthRadius / math.Cos(degRad(s.Lat+latDelta)))
+	lonDeltaBottom := radDeg(width / earthRadius / math.Cos(degRad(s.Lat-latDelta)))
+	// 南北半球方向相反 取不同的点作为经度的边界
+	lonDelta := lonDeltaTop
+	if s.Lat < 0 {
+		lonDelta = lonDeltaBottom
+	}
+	return s.Lon - lonDelta, s.Lat - latDelta, s.Lon + lonDelta, s.Lat + latDelta
+}
+
+// geoStepsByRadius 让一个 geohash 区域大致覆盖半径的精度
+func geoStepsByRadius(meters, lat float64) uint {
+	if meters == 0 {
+		return geoStepMax
+	}
+	step := 1
+	for meters < mercatorMax {
+		meters *= 2
+		step++
+	}
+	step -= 2
+	// 靠近两极时经线变密 需要更大的区域
+	if lat > 66 || lat < -66 {
+		step--
+		if lat > 80 || lat < -80 {
+			step--
+		}
+	}
+	return uint(max(min(step, geoStepMax), 1))
+}
+
+// move 在经度 (dx) 或纬度 (dy) 方向移动一个区域
+func (h geoHash) move(dx, dy int) geoHash {
+	x := h.bits & 0xaaaaaaaaaaaaaaaa // 经度 (奇数位)
+	y := h.bits & 0x5555555555555555 // 纬度 (偶数位)
+	shift := 64 - h.step*2
+	if dx != 0 {
+		zz := uint64(0x5555555555555555) >> shift
+		if dx > 0 {
+			x += zz + 1
+		} else {
+			x = (x | zz) - (zz + 1)
+		}
+		x &= 0xaaaaaaaaaaaaaaaa >> shift
+	}
+	if dy != 0 {
+		zz := uint64(0xaaaaaaaaaaaaaaaa) >> shift
+		if dy > 0 {
+			y += zz + 1
+		} else {
+			y = (y | zz) - (zz + 1)
+		}
+		y &= 0x5555555555555555 >> shift
+	}
+	return geoHash{bits: x | y, step: h.step}
+}
+
+// SearchRanges 需要扫描的分值区间: 中心所在的 geohash 区域及其 8 个相邻区域
+// 与搜索范围不相交的相邻区域被排除, 相同的区域只扫描一次
+func (s GeoShape) SearchRanges() []ScoreRange {
+	radius := s.Radius
+	if s.Box {
+		radius = math.Sqrt(s.Width/2*s.Width/2 + s.Height/2*s.Height/2)
+	}
+	lonMin, latMin, lonMax, latMax := s.boundingBox()
+	step := geoStepsByRadius(radius, s.Lat)
+
+	var center geoHash
+	var area geoArea
+	var neighbors [8]geoHash
+	compute := func() {
+		center = geoEncode(s.Lon, s.Lat, GeoLatMin, GeoLatMax, step)
+		area = geoDecode(center)
+		// 北 南 东 西 东北 西北 东南 西南
+		dirs := [8][2]int{{0, 1}, {0, -1}, {1, 0}, {-1, 0}, {1, 1}, {-1, 1}, {1, -1}, {-1, -1}}
+		for i, d := range dirs {
+			neighbors[i] = center.move(d[0], d[1])
+		}
+	}
+	compute()
+	// 估算的精度在边缘处可能不够 相邻区域覆盖不到搜索范围时降低一级精度
+	north, south := geoDecode(neighbors[0]), geoDecode(neighbors[1])
+	east, west := geoDecode(neighbors[2]), geoDecode(neighbors[3])
+	if step > 1 && (north.latMax < latMax || south.latMin > latMin || east.lonMax < lonMax || west.lonMin > lonMin) {
+		step--
+		compute()
+	}
+
+	skip := [8]bool{}
+	if step >= 2 {
+		if area.latMin < latMin {
+			skip[1], skip[7], skip[6] = true, true, true
+		}
+		if area.latMax > latMax {
+			skip[0], skip[4], skip[5] = true, true, true
+		}
+		if area.lonMin < lonMin {
+			skip[3], skip[7], skip[5] = true, true, true
+		}
+		if area.lonMax > lonMax {
+			skip[2], skip[6], skip[4] = true, true, true
+		}
+	}
+
+	hashes := []geoHash{center}
+	for i, h := range neighbors {
+		if !skip[i] {
+			hashes = append(hashes, h)
+		}
+	}
+	seen := make(map[geoHash]bool)
+	var ranges []ScoreRange
+	for _, h := range hashes {
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		shift := 52 - h.step*2
+		ranges = append(ranges, ScoreRange{
+			Min:   float64(h.bits << shift),
+			Max:   float64((h.bits + 1) << shift),
+			MaxEx: true,
+		})
+	}
+	return ranges
+}
@@ -0,0 +1,65 @@
+package kvstore
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestGeohash(t *testing.T) {
+	// 数值来自 Redis 文档的 Sicily 示例
+	palermo := GeoScore(13.361389, 38.115556)
+	catania := GeoScore(15.087269, 37.502669)
+	assert.Equal(t, float64(3479099956230698), palermo)
+	assert.Equal(t, float64(3479447370796909), catania)
+
+	lon, lat := GeoPosition(palermo)
+	assert.Equal(t, "13.36138933897018433", fmt.Sprintf("%.17f", lon))
+	assert.Equal(t, "38.11555639549629859", fmt.Sprintf("%.17f", lat))
+
+	assert.Equal(t, "sqc8b49rny0", GeoHashString(palermo))
+	assert.Equal(t, "sqdtr74hyu0", GeoHashString(catania))
+
+	plon, plat := GeoPosition(palermo)
+	clon, clat := GeoPosition(catania)
+	assert.Equal(t, "166274.1516", fmt.Sprintf("%.4f", GeoDistance(plon, plat, clon, clat)))
+
+	i, j := deinterleave(interleave(0x2ABCDEF, 0x1234567))
+	assert.Equal(t, uint32(0x2ABCDEF), i)
+	assert.Equal(t, uint32(0x1234567), j)
+}
+
+func TestGeoSearchRanges(t *testing.T) {
+	// 扫描的区间要覆盖范围内的所有点
+	points := [][2]float64{{13.361389, 38.115556}, {15.087269, 37.502669}, {12.758489, 38.788135}, {17.241510, 38.788135}}
+	shapes := []GeoShape{
+		{Lon: 15, Lat: 37, Radius: 200000},
+		{Lon: 15, Lat: 37, Box: true, Width: 400000, Height: 400000},
+		{Lon: 13.36, Lat: 38.11, Radius: 10},
+		{Lon: 0, Lat: 84, Radius: 5000000},
+	}
+	for _, s := range shapes {
+		ranges := s.SearchRanges()
+		for _, p := range points {
+			score := GeoScore(p[0], p[1])
+			lon, lat := GeoPosition(score)
+			if _, ok := s.Within(lon, lat); !ok {
+				continue
+			}
+			covered := false
+			for _, r := range ranges {
+				covered = covered || (r.gteMin(score) && r.lteMax(score))
+			}
+			assert.Equal(t, true, covered)
+		}
+	}
+
+	d, ok := shapes[0].Within(GeoPosition(3479447370796909))
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "56.4413", fmt.Sprintf("%.4f", d/1000))
+	_, ok = shapes[1].Within(17.241510, 38.788135)
+	assert.Equal(t, true, ok)
+	_, ok = shapes[0].Within(17.241510, 38.788135)
+	assert.Equal(t, false, ok)
+}
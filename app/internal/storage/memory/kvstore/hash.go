@@ -0,0 +1,209 @@
+package kvstore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// listpack 编码的上限 (hash-max-listpack-entries / hash-max-listpack-value)
+const (
+	hashMaxListpackEntries = 128
+	hashMaxListpackValue   = 64
+)
+
+// Hash 哈希值 字段可以单独设置过期时间 (HEXPIRE)
+type Hash struct {
+	fields  map[string]string
+	expires map[string]int64 // 字段的过期时间 Unix 毫秒
+}
+
+func NewHash() *Hash {
+	return &Hash{fields: make(map[string]string)}
+}
+
+func (h *Hash) Len() int {
+	return len(h.fields)
+}
+
+func (h *Hash) Get(field string) (string, bool) {
+	v, ok := h.fields[field]
+	return v, ok
+}
+
+// Set 写入字段 并清除字段的过期时间 (HSET 语义) 返回是否为新字段
+func (h *Hash) Set(field, value string) bool {
+	_, exists := h.fields[field]
+	h.fields[field] = value
+	delete(h.expires, field)
+	return !exists
+}
+
+// Update 修改已有字段的值 保留过期时间 (HINCRBY 语义)
+func (h *Hash) Update(field, value string) {
+	h.fields[field] = value
+}
+
+func (h *Hash) Delete(field string) bool {
+	if _, ok := h.fields[field]; !ok {
+		return false
+	}
+	delete(h.fields, field)
+	delete(h.expires, field)
+	return true
+}
+
+// Fields 按字段名排序返回 (HSCAN 游标依赖稳定的顺序)
+func (h *Hash) Fields() []string {
+	fields := make([]string, 0, len(h.fields))
+	for f := range h.fields {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// ExpireAt 字段的过期时间 (Unix 毫秒) 没有过期时间时返回 false
+func (h *Hash) ExpireAt(field string) (int64, bool) {
+	ms, ok := h.expires[field]
+	return ms, ok
+}
+
+func (h *Hash) SetExpireAt(field string, ms int64) {
+	if h.expires == nil {
+		h.expires = make(map[string]int64)
+	}
+	h.expires[field] = ms
+}
+
+// Persist 清除字段的过期时间 字段原本没有过期时间时返回 false
+func (h *Hash) Persist(field string) bool {
+	if _, ok := h.expires[field]; !ok {
+		return false
+	}
+	delete(h.expires, field)
+	return true
+}
+
+// HasExpires 是否有设置了过期时间的字段
+func (h *Hash) HasExpires() bool {
+	return len(h.expires) > 0
+}
+
+// expireFields 删除已过期的字段 返回删除的个数
+func (h *Hash) expireFields(now int64) int {
+	n := 0
+	for f, ms := range h.expires {
+		if ms <= now {
+			delete(h.fields, f)
+			delete(h.expires, f)
+			n++
+		}
+	}
+	return n
+}
+
+func NewHashObject() *Object {
+	return NewObject(TypeHash, EncListpack, NewHash())
+}
+
+// Hash 读取哈希 键不存在时返回 nil, 类型不符时返回 WRONGTYPE
+func (tx *Tx) Hash(key string) (*Hash, error) {
+	o, ok := tx.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if o.Type != TypeHash {
+		return nil, errors_r.ErrWrongType
+	}
+	return o.Value.(*Hash), nil
+}
+
+// HashOrCreate 读取哈希 键不存在时创建
+func (tx *Tx) HashOrCreate(key string) (*Hash, error) {
+	h, err := tx.Hash(key)
+	if err != nil || h != nil {
+		return h, err
+	}
+	o := NewHashObject()
+	tx.Add(key, o)
+	return o.Value.(*Hash), nil
+}
+
+// HashChanged 哈希修改后调用: 空哈希删除键, 否则按大小更新编码
+// added 为本次写入的字段与值
+func (tx *Tx) HashChanged(key string, added ...string) {
+	o, ok := tx.s.Data[key]
+	if !ok || o.Type != TypeHash {
+		return
+	}
+	h := o.Value.(*Hash)
+	if h.Len() == 0 {
+		tx.Delete(key)
+		return
+	}
+	tx.s.track(key, o)
+	o.Encoding = hashEncoding(h, o.Encoding, added)
+}
+
+// hashEncoding 小哈希为 listpack (有字段过期时间时为 listpackex), 超出上限后转换为 hashtable
+func hashEncoding(h *Hash, enc Encoding, added []string) Encoding {
+	if enc == EncHashtable {
+		return enc
+	}
+	if h.Len() > hashMaxListpackEntries {
+		return EncHashtable
+	}
+	for _, v := range added {
+		if len(v) > hashMaxListpackValue {
+			return EncHashtable
+		}
+	}
+	if h.HasExpires() {
+		return EncListpackEx
+	}
+	return EncListpack
+}
+
+// NewHashObjectFrom 由字段构造哈希对象 (RDB 加载) expires 可以为 nil
+func NewHashObjectFrom(fields map[string]string, expires map[string]int64) *Object {
+	h := &Hash{fields: fields, expires: expires}
+	added := make([]string, 0, 2*len(fields))
+	for f, v := range fields {
+		added = append(added, f, v)
+	}
+	return NewObject(TypeHash, hashEncoding(h, EncListpack, added), h)
+}
+
+// track 记录有字段过期时间的哈希 供主动过期扫描 调用方需持有 s.Mu 写锁
+func (s *Store) track(key string, o *Object) {
+	if o.Type != TypeHash || !o.Value.(*Hash).HasExpires() {
+		return
+	}
+	if s.hashExpires == nil {
+		s.hashExpires = make(map[string]struct{})
+	}
+	s.hashExpires[key] = struct{}{}
+}
+
+// expireHashFields 主动过期: 删除哈希中已过期的字段 调用方需持有 s.Mu 写锁
+func (s *Store) expireHashFields(now time.Time) {
+	ms := now.UnixMilli()
+	for key := range s.hashExpires {
+		o, ok := s.Data[key]
+		if !ok || o.Type != TypeHash || !o.Value.(*Hash).HasExpires() {
+			delete(s.hashExpires, key)
+			continue
+		}
+		h := o.Value.(*Hash)
+		h.expireFields(ms)
+		if h.Len() == 0 {
+			delete(s.Data, key)
+			delete(s.Expires, key)
+		}
+		if !h.HasExpires() {
+			delete(s.hashExpires, key)
+		}
+	}
+}
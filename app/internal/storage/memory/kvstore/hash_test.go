@@ -0,0 +1,46 @@
+package kvstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestHashFieldExpire(t *testing.T) {
+	s := &Store{Data: make(map[string]*Object), Expires: make(map[string]time.Time)}
+	past := time.Now().Add(-time.Second).UnixMilli()
+	future := time.Now().Add(time.Hour).UnixMilli()
+
+	// 惰性过期: 访问时删除过期字段 全部过期后键不存在
+	s.Update(func(tx *Tx) error {
+		h, _ := tx.HashOrCreate("lazy")
+		h.Set("a", "1")
+		h.Set("b", "2")
+		h.SetExpireAt("a", past)
+		tx.HashChanged("lazy", "a", "1", "b", "2")
+		return nil
+	})
+	o, ok := s.Peek("lazy")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 1, o.Value.(*Hash).Len())
+
+	// 主动过期: 由清理周期删除
+	s.Update(func(tx *Tx) error {
+		h, _ := tx.HashOrCreate("active")
+		h.Set("a", "1")
+		h.Set("b", "2")
+		h.SetExpireAt("a", past)
+		h.SetExpireAt("b", future)
+		tx.HashChanged("active")
+		return nil
+	})
+	assert.Equal(t, EncListpackEx, s.Data["active"].Encoding)
+	s.cleanupExpired()
+	assert.Equal(t, 1, s.Data["active"].Value.(*Hash).Len())
+
+	s.Data["active"].Value.(*Hash).SetExpireAt("b", past)
+	s.cleanupExpired()
+	assert.Equal(t, false, s.Exists("active"))
+	assert.Equal(t, 0, len(s.hashExpires))
+}
@@ -0,0 +1,436 @@
+package kvstore
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// HyperLogLog 以字符串保存 格式与 Redis 相同 (DUMP/RESTORE 可以互通):
+//
+//	"HYLL" | 编码 (0 dense, 1 sparse) | 3 字节保留 | 8 字节小端基数缓存 (最高位为 1 表示缓存失效) | 寄存器
+//
+// 寄存器共 2^14 个:
+// dense 每个 6 位紧密排列 (低位在前); sparse 为游程编码:
+// ZERO 00xxxxxx 表示 1..64 个 0, XZERO 01xxxxxx yyyyyyyy 表示 1..16384 个 0, VAL 1vvvvvxx 表示 1..4 个值为 1..32 的寄存器
+const (
+	hllP         = 14
+	hllQ         = 64 - hllP
+	HLLRegisters = 1 << hllP
+	hllBits      = 6
+	hllRegMax    = 1<<hllBits - 1
+	hllHdrSize   = 16
+	HLLDenseSize = hllHdrSize + (HLLRegisters*hllBits+7)/8
+
+	hllDense  = 0
+	hllSparse = 1
+
+	hllSparseValMax = 32
+	hllSparseMaxLen = 4
+	hllZeroMaxLen   = 64
+	hllXZeroMaxLen  = 16384
+
+	// hllSparseMaxBytes sparse 表示的最大长度 (hll-sparse-max-bytes) 超出后转换为 dense
+	hllSparseMaxBytes = 3000
+
+	hllAlphaInf = 0.721347520444481703680 // 1 / (2 ln 2)
+)
+
+var (
+	ErrHLLType    = errors.New("WRONGTYPE Key is not a valid HyperLogLog string value.")
+	ErrHLLCorrupt = errors.New("INVALIDOBJ Corrupted HLL object detected")
+)
+
+// murmurHash64A 与 Redis 相同的 MurmurHash64A (小端读取)
+func murmurHash64A(key string, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+	h := seed ^ uint64(len(key))*m
+	n := len(key) &^ 7
+	for i := 0; i < n; i += 8 {
+		k := word(key, i)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+	}
+	if rest := key[n:]; len(rest) > 0 {
+		for i := len(rest) - 1; i >= 0; i-- {
+			h ^= uint64(rest[i]) << (8 * i)
+		}
+		h *= m
+	}
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
+
+// hllPatLen 元素对应的寄存器下标与 "000..1" 模式的长度 (1..51)
+func hllPatLen(ele string) (int, uint8) {
+	hash := murmurHash64A(ele, 0xadc83b19)
+	index := int(hash & (HLLRegisters - 1))
+	hash >>= hllP
+	hash |= 1 << hllQ
+	count := uint8(1)
+	for bit := uint64(1); hash&bit == 0; bit <<= 1 {
+		count++
+	}
+	return index, count
+}
+
+// NewHLL 空的 sparse HyperLogLog
+func NewHLL() []byte {
+	buf := make([]byte, hllHdrSize, hllHdrSize+2)
+	copy(buf, "HYLL")
+	buf[4] = hllSparse
+	return appendZeroRun(buf, HLLRegisters)
+}
+
+// IsHLL 检查头部: magic, 编码与 dense 的长度 (sparse 的内容在解码时检查)
+func IsHLL(s string) bool {
+	if len(s) < hllHdrSize || s[:4] != "HYLL" {
+		return false
+	}
+	switch s[4] {
+	case hllDense:
+		return len(s) == HLLDenseSize
+	case hllSparse:
+		return true
+	}
+	return false
+}
+
+// HLLIsSparse 是否为 sparse 编码
+func HLLIsSparse(s string) bool {
+	return s[4] == hllSparse
+}
+
+// HLLCachedCount 读取缓存的基数
+func HLLCachedCount(s string) (uint64, bool) {
+	if s[15]&0x80 != 0 {
+		return 0, false
+	}
+	var n uint64
+	for i := 7; i >= 0; i-- {
+		n = n<<8 | uint64(s[8+i])
+	}
+	return n, true
+}
+
+// HLLSetCache 保存基数缓存
+func HLLSetCache(buf []byte, n uint64) {
+	for i := 0; i < 8; i++ {
+		buf[8+i] = byte(n >> (8 * i))
+	}
+}
+
+// HLLInvalidateCache 标记基数缓存失效
+func HLLInvalidateCache(buf []byte) {
+	buf[15] |= 0x80
+}
+
+// denseGet/denseSet 读写 dense 表示中的第 i 个寄存器 (可能跨两个字节)
+func denseGet(regs []byte, i int) uint8 {
+	b, fb := i*hllBits/8, uint(i*hllBits&7)
+	v := uint(regs[b]) >> fb
+	if b+1 < len(regs) {
+		v |= uint(regs[b+1]) << (8 - fb)
+	}
+	return uint8(v & hllRegMax)
+}
+
+func denseSet(regs []byte, i int, val uint8) {
+	b, fb := i*hllBits/8, uint(i*hllBits&7)
+	regs[b] &^= byte(hllRegMax << fb)
+	regs[b] |= byte(uint(val) << fb)
+	if b+1 < len(regs) {
+		regs[b+1] &^= byte(hllRegMax >> (8 - fb))
+		regs[b+1] |= byte(uint(val) >> (8 - fb))
+	}
+}
+
+// HLLRegisterValues 解码出全部寄存器的值
+func HLLRegisterValues(s string) ([]uint8, error) {
+	regs := make([]uint8, HLLRegisters)
+	if !HLLIsSparse(s) {
+		dense := []byte(s[hllHdrSize:])
+		for i := range regs {
+			regs[i] = denseGet(dense, i)
+		}
+		return regs, nil
+	}
+	idx := 0
+	err := sparseRuns(s[hllHdrSize:], func(val uint8, run int) {
+		for i := 0; i < run; i++ {
+			regs[idx+i] = val
+		}
+		idx += run
+	})
+	return regs, err
+}
+
+// sparseRuns 依次回调 sparse 表示中的每个游程 寄存器总数不等于 2^14 时视为损坏
+func sparseRuns(p string, fn func(val uint8, run int)) error {
+	total := 0
+	for i := 0; i < len(p); i++ {
+		b := p[i]
+		var val uint8
+		var run int
+		switch {
+		case b&0xC0 == 0x00:
+			run = int(b&0x3F) + 1
+		case b&0xC0 == 0x40:
+			if i+1 >= len(p) {
+				return ErrHLLCorrupt
+			}
+			run = (int(b&0x3F)<<8 | int(p[i+1])) + 1
+			i++
+		default:
+			val, run = (b>>2)&0x1F+1, int(b&0x3)+1
+		}
+		if total+run > HLLRegisters {
+			return ErrHLLCorrupt
+		}
+		fn(val, run)
+		total += run
+	}
+	if total != HLLRegisters {
+		return ErrHLLCorrupt
+	}
+	return nil
+}
+
+func appendZeroRun(buf []byte, run int) []byte {
+	for run > 0 {
+		if run > hllZeroMaxLen {
+			n := min(run, hllXZeroMaxLen)
+			buf = append(buf, 0x40|byte((n-1)>>8), byte(n-1))
+			run -= n
+		} else {
+			buf = append(buf, byte(run-1))
+			run = 0
+		}
+	}
+	return buf
+}
+
+// encodeSparse 以 sparse 编码寄存器 有寄存器超过 32 或长度超出上限时返回 false
+func encodeSparse(hdr string, regs []uint8) ([]byte, bool) {
+	buf := append(make([]byte, 0, hllHdrSize+64), hdr[:hllHdrSize]...)
+	for i := 0; i < len(regs); {
+		v, j := regs[i], i
+		for j < len(regs) && regs[j] == v {
+			j++
+		}
+		if v > hllSparseValMax {
+			return nil, false
+		}
+		if v == 0 {
+			buf = appendZeroRun(buf, j-i)
+		} else {
+			for run := j - i; run > 0; run -= hllSparseMaxLen {
+				n := min(run, hllSparseMaxLen)
+				buf = append(buf, 0x80|(v-1)<<2|byte(n-1))
+			}
+		}
+		if len(buf) > hllHdrSize+hllSparseMaxBytes {
+			return nil, false
+		}
+		i = j
+	}
+	return buf, true
+}
+
+// encodeDense 以 dense 编码寄存器 头部的缓存从 hdr 复制
+func encodeDense(hdr string, regs []uint8) []byte {
+	buf := make([]byte, HLLDenseSize)
+	copy(buf, hdr[:hllHdrSize])
+	buf[4] = hllDense
+	for i, v := range regs {
+		denseSet(buf[hllHdrSize:], i, v)
+	}
+	return buf
+}
+
+// HLLEncode 把寄存器写回: sparse 放得下时保持 sparse, 否则为 dense
+func HLLEncode(hdr string, regs []uint8, sparse bool) []byte {
+	if sparse {
+		if buf, ok := encodeSparse(hdr, regs); ok {
+			return buf
+		}
+	}
+	return encodeDense(hdr, regs)
+}
+
+// HLLAdd 添加元素 返回新的值以及是否有寄存器变化 (变化时缓存失效)
+func HLLAdd(s string, eles []string) ([]byte, bool, error) {
+	if !HLLIsSparse(s) {
+		buf := []byte(s)
+		changed := false
+		for _, ele := range eles {
+			i, count := hllPatLen(ele)
+			if denseGet(buf[hllHdrSize:], i) < count {
+				denseSet(buf[hllHdrSize:], i, count)
+				changed = true
+			}
+		}
+		if changed {
+			HLLInvalidateCache(buf)
+		}
+		return buf, changed, nil
+	}
+	regs, err := HLLRegisterValues(s)
+	if err != nil {
+		return nil, false, err
+	}
+	changed := false
+	for _, ele := range eles {
+		i, count := hllPatLen(ele)
+		if regs[i] < count {
+			regs[i] = count
+			changed = true
+		}
+	}
+	if !changed {
+		return []byte(s), false, nil
+	}
+	buf := HLLEncode(s, regs, true)
+	HLLInvalidateCache(buf)
+	return buf, true, nil
+}
+
+// HLLMerge 逐个寄存器取最大值
+func HLLMerge(dst, src []uint8) {
+	for i, v := range src {
+		dst[i] = max(dst[i], v)
+	}
+}
+
+// HLLEstimate 根据寄存器估算基数 (Ertl 的改进估计, 与 Redis 相同)
+func HLLEstimate(regs []uint8) uint64 {
+	var histo [64]int
+	for _, v := range regs {
+		histo[v]++
+	}
+	m := float64(HLLRegisters)
+	z := m * hllTau((m-float64(histo[hllQ+1]))/m)
+	for j := hllQ; j >= 1; j-- {
+		z += float64(histo[j])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(histo[0])/m)
+	return uint64(math.Round(hllAlphaInf * m * m / z))
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if prev == z {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if prev == z {
+			return z / 3
+		}
+	}
+}
+
+// HLLDecode PFDEBUG DECODE 的输出 如 "Z:16300 v:3,1 z:60"
+func HLLDecode(s string) (string, error) {
+	var parts []string
+	p := s[hllHdrSize:]
+	for i := 0; i < len(p); i++ {
+		b := p[i]
+		switch {
+		case b&0xC0 == 0x00:
+			parts = append(parts, fmt.Sprintf("z:%d", int(b&0x3F)+1))
+		case b&0xC0 == 0x40:
+			if i+1 >= len(p) {
+				return "", ErrHLLCorrupt
+			}
+			parts = append(parts, fmt.Sprintf("Z:%d", (int(b&0x3F)<<8|int(p[i+1]))+1))
+			i++
+		default:
+			parts = append(parts, fmt.Sprintf("v:%d,%d", (b>>2)&0x1F+1, int(b&0x3)+1))
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// HLLSelfTest PFSELFTEST: 检查 dense 寄存器的读写, 以及 sparse 与 dense 的估算一致且误差在范围内
+func HLLSelfTest() error {
+	rng := rand.New(rand.NewSource(1))
+	dense := make([]byte, HLLDenseSize-hllHdrSize)
+	want := make([]uint8, HLLRegisters)
+	for round := 0; round < 100; round++ {
+		for i := range want {
+			want[i] = uint8(rng.Intn(hllRegMax + 1))
+			denseSet(dense, i, want[i])
+		}
+		for i, v := range want {
+			if got := denseGet(dense, i); got != v {
+				return fmt.Errorf("TESTFAILED Register error at %d: %d instead of %d", i, got, v)
+			}
+		}
+	}
+
+	sparse := string(NewHLL())
+	full := string(encodeDense(sparse, make([]uint8, HLLRegisters)))
+	relErr := 1.04 / math.Sqrt(HLLRegisters)
+	checkpoint := 1
+	for j := 1; j <= 100000; j++ {
+		ele := strconv.Itoa(rng.Int())
+		buf, _, err := HLLAdd(sparse, []string{ele})
+		if err != nil {
+			return err
+		}
+		sparse = string(buf)
+		buf, _, _ = HLLAdd(full, []string{ele})
+		full = string(buf)
+		if j != checkpoint {
+			continue
+		}
+		checkpoint *= 10
+		regs, err := HLLRegisterValues(sparse)
+		if err != nil {
+			return err
+		}
+		denseRegs, _ := HLLRegisterValues(full)
+		n, denseN := HLLEstimate(regs), HLLEstimate(denseRegs)
+		if n != denseN {
+			return fmt.Errorf("TESTFAILED dense/sparse disagree: %d != %d", denseN, n)
+		}
+		maxErr := relErr * 6 * float64(j)
+		if j == 10 {
+			maxErr = 1
+		}
+		if math.Abs(float64(n)-float64(j)) > maxErr {
+			return fmt.Errorf("TESTFAILED Too big error. card:%d abserr:%f", j, math.Abs(float64(n)-float64(j)))
+		}
+	}
+	return nil
+}
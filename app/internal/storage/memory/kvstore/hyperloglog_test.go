@@ -0,0 +1,55 @@
+package kvstore
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	// 空值: 头部 + 一个覆盖全部寄存器的 XZERO
+	s := string(NewHLL())
+	assert.Equal(t, "HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x7f\xff", s)
+	assert.Equal(t, true, IsHLL(s))
+	decoded, _ := HLLDecode(s)
+	assert.Equal(t, "Z:16384", decoded)
+
+	buf, changed, err := HLLAdd(s, []string{"a", "b", "c", "a"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, changed)
+	_, valid := HLLCachedCount(string(buf))
+	assert.Equal(t, false, valid)
+	_, changed, _ = HLLAdd(string(buf), []string{"b"})
+	assert.Equal(t, false, changed)
+	regs, _ := HLLRegisterValues(string(buf))
+	assert.Equal(t, uint64(3), HLLEstimate(regs))
+
+	// 元素增多后 sparse 超出上限转换为 dense, 两种表示的寄存器一致
+	s = string(buf)
+	for i := 0; i < 5000; i++ {
+		buf, _, _ = HLLAdd(s, []string{strconv.Itoa(i)})
+		s = string(buf)
+	}
+	assert.Equal(t, false, HLLIsSparse(s))
+	assert.Equal(t, HLLDenseSize, len(s))
+	regs, _ = HLLRegisterValues(s)
+	_, ok := encodeSparse(s, regs)
+	assert.Equal(t, false, ok)
+	n := HLLEstimate(regs)
+	assert.Equal(t, true, n > 4900 && n < 5100)
+
+	HLLSetCache(buf, 12345)
+	cached, valid := HLLCachedCount(string(buf))
+	assert.Equal(t, true, valid)
+	assert.Equal(t, uint64(12345), cached)
+
+	// 寄存器总数不对的 sparse 值视为损坏
+	_, err = HLLRegisterValues("HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x7f\xfe")
+	assert.Equal(t, ErrHLLCorrupt, err)
+	assert.Equal(t, false, IsHLL("HYLL\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"))
+}
+
+func TestHLLSelfTest(t *testing.T) {
+	assert.Equal(t, nil, HLLSelfTest())
+}
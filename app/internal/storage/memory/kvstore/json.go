@@ -0,0 +1,337 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// JSON 文档类型 (与 RedisJSON 兼容, TYPE 为 ReJSON-RL)
+// 文档解析为树: 对象为 *JSONMap (保持键的插入顺序), 数组为 *JSONArray,
+// 整数为 int64, 其他数字为 float64, 字符串为 string, 布尔为 bool, null 为 nil
+type JSON struct {
+	Root any
+}
+
+// JSONMap JSON 对象
+type JSONMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func NewJSONMap() *JSONMap {
+	return &JSONMap{values: make(map[string]any)}
+}
+
+func (m *JSONMap) Get(key string) (any, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+// Set 已有的键保持原来的位置
+func (m *JSONMap) Set(key string, v any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = v
+}
+
+func (m *JSONMap) Delete(key string) bool {
+	if _, ok := m.values[key]; !ok {
+		return false
+	}
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Keys 按插入顺序
+func (m *JSONMap) Keys() []string {
+	return m.keys
+}
+
+func (m *JSONMap) Len() int {
+	return len(m.keys)
+}
+
+// JSONArray JSON 数组
+type JSONArray struct {
+	Elems []any
+}
+
+var (
+	ErrJSONParse = errors.New("ERR expected value")
+	ErrJSONPath  = errors.New("ERR invalid JSONPath")
+)
+
+func NewJSONObject(root any) *Object {
+	return NewObject(TypeJSON, EncRaw, &JSON{Root: root})
+}
+
+// JSON 读取 JSON 文档 键不存在时返回 nil, 类型不符时返回 WRONGTYPE
+func (tx *Tx) JSON(key string) (*JSON, error) {
+	o, ok := tx.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if o.Type != TypeJSON {
+		return nil, errors_r.ErrWrongType
+	}
+	return o.Value.(*JSON), nil
+}
+
+// ParseJSON 解析 JSON 文本 文本中只能有一个值
+func ParseJSON(s string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	v, err := parseJSONValue(dec)
+	if err != nil {
+		return nil, ErrJSONParse
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, ErrJSONParse
+	}
+	return v, nil
+}
+
+func parseJSONValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			m := NewJSONMap()
+			for dec.More() {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				v, err := parseJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				m.Set(k.(string), v)
+			}
+			_, err = dec.Token()
+			return m, err
+		case '[':
+			a := &JSONArray{}
+			for dec.More() {
+				v, err := parseJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				a.Elems = append(a.Elems, v)
+			}
+			_, err = dec.Token()
+			return a, err
+		}
+		return nil, ErrJSONParse
+	case json.Number:
+		// 没有小数点与指数的数字为整数 超出 int64 范围时按浮点数保存
+		if !strings.ContainsAny(string(t), ".eE") {
+			if n, err := strconv.ParseInt(string(t), 10, 64); err == nil {
+				return n, nil
+			}
+		}
+		return strconv.ParseFloat(string(t), 64)
+	}
+	return tok, nil
+}
+
+// CloneJSON 深拷贝
+func CloneJSON(v any) any {
+	switch t := v.(type) {
+	case *JSONMap:
+		m := NewJSONMap()
+		for _, k := range t.keys {
+			m.Set(k, CloneJSON(t.values[k]))
+		}
+		return m
+	case *JSONArray:
+		a := &JSONArray{Elems: make([]any, len(t.Elems))}
+		for i, e := range t.Elems {
+			a.Elems[i] = CloneJSON(e)
+		}
+		return a
+	}
+	return v
+}
+
+// JSONTypeName JSON.TYPE 的输出
+func JSONTypeName(v any) string {
+	switch v.(type) {
+	case *JSONMap:
+		return "object"
+	case *JSONArray:
+		return "array"
+	case string:
+		return "string"
+	case int64:
+		return "integer"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+// JSONFormat 序列化值 indent/newline/space 为 JSON.GET 的格式选项 (均为空时输出紧凑格式)
+func JSONFormat(v any, indent, newline, space string) string {
+	var sb strings.Builder
+	f := jsonFormatter{sb: &sb, indent: indent, newline: newline, space: space}
+	f.write(v, 0)
+	return sb.String()
+}
+
+// MarshalJSON 紧凑格式 用于 RDB 与命令的回复
+func MarshalJSON(v any) string {
+	return JSONFormat(v, "", "", "")
+}
+
+type jsonFormatter struct {
+	sb                     *strings.Builder
+	indent, newline, space string
+}
+
+func (f *jsonFormatter) line(level int) {
+	f.sb.WriteString(f.newline)
+	for i := 0; i < level; i++ {
+		f.sb.WriteString(f.indent)
+	}
+}
+
+func (f *jsonFormatter) write(v any, level int) {
+	switch t := v.(type) {
+	case *JSONMap:
+		if t.Len() == 0 {
+			f.sb.WriteString("{}")
+			return
+		}
+		f.sb.WriteByte('{')
+		for i, k := range t.keys {
+			if i > 0 {
+				f.sb.WriteByte(',')
+			}
+			f.line(level + 1)
+			writeJSONString(f.sb, k)
+			f.sb.WriteByte(':')
+			f.sb.WriteString(f.space)
+			f.write(t.values[k], level+1)
+		}
+		f.line(level)
+		f.sb.WriteByte('}')
+	case *JSONArray:
+		if len(t.Elems) == 0 {
+			f.sb.WriteString("[]")
+			return
+		}
+		f.sb.WriteByte('[')
+		for i, e := range t.Elems {
+			if i > 0 {
+				f.sb.WriteByte(',')
+			}
+			f.line(level + 1)
+			f.write(e, level+1)
+		}
+		f.line(level)
+		f.sb.WriteByte(']')
+	case string:
+		writeJSONString(f.sb, t)
+	case int64:
+		f.sb.WriteString(strconv.FormatInt(t, 10))
+	case float64:
+		f.sb.WriteString(formatJSONFloat(t))
+	case bool:
+		f.sb.WriteString(strconv.FormatBool(t))
+	default:
+		f.sb.WriteString("null")
+	}
+}
+
+// formatJSONFloat 与 RedisJSON 相同: 整数值的浮点数保留 ".0", 很大或很小的数使用指数形式 (1e20, 1.5e-7)
+func formatJSONFloat(x float64) string {
+	if a := math.Abs(x); a != 0 && (a < 1e-5 || a >= 1e16) {
+		s := strconv.FormatFloat(x, 'e', -1, 64)
+		mant, exp, _ := strings.Cut(s, "e")
+		sign := ""
+		if exp[0] == '-' {
+			sign = "-"
+		}
+		return mant + "e" + sign + strings.TrimLeft(exp[1:], "0")
+	}
+	s := strconv.FormatFloat(x, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+func writeJSONString(sb *strings.Builder, s string) {
+	const hex = "0123456789abcdef"
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		default:
+			if c < 0x20 {
+				sb.WriteString(`\u00`)
+				sb.WriteByte(hex[c>>4])
+				sb.WriteByte(hex[c&0xF])
+				continue
+			}
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+}
+
+// JSONIncr NUMINCRBY 的加法 两个整数的和不溢出时结果仍为整数
+func JSONIncr(v, by any) (any, bool) {
+	if a, ok := v.(int64); ok {
+		if b, ok := by.(int64); ok {
+			if s := a + b; (s > a) == (b > 0) {
+				return s, true
+			}
+		}
+	}
+	s := jsonFloat(v) + jsonFloat(by)
+	if math.IsInf(s, 0) || math.IsNaN(s) {
+		return nil, false
+	}
+	return s, true
+}
+
+func jsonFloat(v any) float64 {
+	if n, ok := v.(int64); ok {
+		return float64(n)
+	}
+	return v.(float64)
+}
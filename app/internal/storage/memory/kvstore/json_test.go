@@ -0,0 +1,112 @@
+package kvstore
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestJSONParseFormat(t *testing.T) {
+	// 键保持插入顺序 整数与浮点数分开保存
+	s := `{"b":1,"a":[true,null,"x\n\"y\""],"c":{},"d":[],"e":2.5,"f":3.0,"g":1e20}`
+	v, err := ParseJSON(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `{"b":1,"a":[true,null,"x\n\"y\""],"c":{},"d":[],"e":2.5,"f":3.0,"g":1e20}`, MarshalJSON(v))
+	assert.Equal(t, []string{"b", "a", "c", "d", "e", "f", "g"}, v.(*JSONMap).Keys())
+
+	assert.Equal(t, "{\n\t\"a\": [\n\t\t1,\n\t\t2\n\t]\n}", JSONFormat(mustParseJSON(t, `{"a":[1,2]}`), "\t", "\n", " "))
+
+	for _, bad := range []string{``, `{`, `{"a" 1}`, `[1,]`, `1 2`, `nul`} {
+		_, err := ParseJSON(bad)
+		assert.Equal(t, ErrJSONParse, err)
+	}
+	assert.Equal(t, "integer", JSONTypeName(mustParseJSON(t, "9223372036854775807")))
+	assert.Equal(t, "number", JSONTypeName(mustParseJSON(t, "9223372036854775808")))
+}
+
+func mustParseJSON(t *testing.T, s string) any {
+	v, err := ParseJSON(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func evalJSON(t *testing.T, doc *JSON, path string) string {
+	p, err := ParseJSONPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &JSONArray{}
+	for _, r := range p.Eval(doc) {
+		a.Elems = append(a.Elems, r.Value)
+	}
+	return MarshalJSON(a)
+}
+
+func TestJSONPath(t *testing.T) {
+	doc := &JSON{Root: mustParseJSON(t, `{"a":1,"b":{"a":[1,2,3,4],"c":"x"},"d":[{"a":5},{"e":6}],"k.y":7}`)}
+	cases := map[string]string{
+		"$":            `[{"a":1,"b":{"a":[1,2,3,4],"c":"x"},"d":[{"a":5},{"e":6}],"k.y":7}]`,
+		"$.a":          `[1]`,
+		"$..a":         `[1,[1,2,3,4],5]`,
+		"$..a[*]":      `[1,2,3,4]`,
+		"$.b.a[-1]":    `[4]`,
+		"$.b.a[0,2,9]": `[1,3]`,
+		"$.b.a[1:]":    `[2,3,4]`,
+		"$.b.a[:-1:2]": `[1,3]`,
+		"$.b.*":        `[[1,2,3,4],"x"]`,
+		"$['k.y']":     `[7]`,
+		`$["a","b"].c`: `["x"]`,
+		"$.d[*].a":     `[5]`,
+		"$..[0]":       `[1,{"a":5}]`,
+		"$.missing":    `[]`,
+		".b.c":         `["x"]`,
+		"b.a[1]":       `[2]`,
+		".":            `[{"a":1,"b":{"a":[1,2,3,4],"c":"x"},"d":[{"a":5},{"e":6}],"k.y":7}]`,
+	}
+	for path, want := range cases {
+		assert.Equal(t, want, evalJSON(t, doc, path))
+	}
+
+	p, _ := ParseJSONPath(".b.c")
+	assert.Equal(t, true, p.Legacy)
+	p, _ = ParseJSONPath("$.b")
+	assert.Equal(t, false, p.Legacy)
+	for _, bad := range []string{"$.", "$..", "$[", "$[a]", "$[1:2:0]", "$x"} {
+		_, err := ParseJSONPath(bad)
+		assert.Equal(t, ErrJSONPath, err)
+	}
+}
+
+func TestJSONModify(t *testing.T) {
+	doc := &JSON{Root: mustParseJSON(t, `{"a":[1,2,3,4],"b":{"a":1}}`)}
+	p, _ := ParseJSONPath("$.a[1,3]")
+	assert.Equal(t, 2, doc.Delete(p.Eval(doc)))
+	assert.Equal(t, `{"a":[1,3],"b":{"a":1}}`, MarshalJSON(doc.Root))
+
+	p, _ = ParseJSONPath("$..a")
+	assert.Equal(t, 2, doc.Delete(p.Eval(doc)))
+	assert.Equal(t, `{"b":{}}`, MarshalJSON(doc.Root))
+
+	p, _ = ParseJSONPath("$.b.c")
+	parent, name, ok := p.SplitLast()
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "c", name)
+	for _, r := range parent.Eval(doc) {
+		r.Value.(*JSONMap).Set(name, int64(1))
+	}
+	for _, r := range p.Eval(doc) {
+		doc.Replace(r, CloneJSON(doc.Root))
+	}
+	assert.Equal(t, `{"b":{"c":{"b":{"c":1}}}}`, MarshalJSON(doc.Root))
+
+	v, ok := JSONIncr(int64(1), int64(2))
+	assert.Equal(t, int64(3), v)
+	v, _ = JSONIncr(int64(1), 0.5)
+	assert.Equal(t, 1.5, v)
+	v, _ = JSONIncr(int64(9223372036854775807), int64(1))
+	assert.Equal(t, "number", JSONTypeName(v))
+	_, ok = JSONIncr(1.7e308, 1.7e308)
+	assert.Equal(t, false, ok)
+}
@@ -0,0 +1,352 @@
+package kvstore
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPath 解析后的路径
+// 以 $ 开头的为 JSONPath: .key ['key'] [n] [a,b] [start:end:step] * 以及递归下降 ..
+// 其他为旧的点路径 (., .a.b, a[0]): 命令只使用第一个匹配, 没有匹配时报错
+type JSONPath struct {
+	Legacy bool
+	text   string
+	steps  []jsonStep
+}
+
+// jsonStep 路径中的一步 recursive 时先展开为当前值及其所有后代
+type jsonStep struct {
+	recursive bool
+	wildcard  bool
+	names     []string
+	indexes   []int
+	slice     *jsonSlice
+}
+
+type jsonSlice struct {
+	start, end       int
+	hasStart, hasEnd bool
+	step             int
+}
+
+// JSONRef 路径匹配到的值及其在父容器中的位置 (根节点的 parent 为 nil)
+type JSONRef struct {
+	Value  any
+	parent any
+	key    string
+	index  int
+}
+
+func (p *JSONPath) String() string {
+	return p.text
+}
+
+// ParseJSONPath 解析路径 旧路径转换为等价的 JSONPath
+func ParseJSONPath(s string) (*JSONPath, error) {
+	p := &JSONPath{text: s}
+	rest := s
+	switch {
+	case strings.HasPrefix(s, "$"):
+		rest = s[1:]
+	case s == ".":
+		p.Legacy, rest = true, ""
+	case strings.HasPrefix(s, ".") || strings.HasPrefix(s, "["):
+		p.Legacy = true
+	default:
+		p.Legacy, rest = true, "."+s
+	}
+	for rest != "" {
+		var st jsonStep
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			st.recursive = true
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				var err error
+				if rest, err = st.parseBracket(rest); err != nil {
+					return nil, err
+				}
+				break
+			}
+			rest = st.parseName(rest)
+		case rest[0] == '.':
+			rest = st.parseName(rest[1:])
+		case rest[0] == '[':
+			var err error
+			if rest, err = st.parseBracket(rest); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, ErrJSONPath
+		}
+		if !st.wildcard && st.names == nil && st.indexes == nil && st.slice == nil {
+			return nil, ErrJSONPath
+		}
+		p.steps = append(p.steps, st)
+	}
+	return p, nil
+}
+
+// parseName .key 或 .* 名字到下一个 . 或 [ 为止
+func (st *jsonStep) parseName(s string) string {
+	end := strings.IndexAny(s, ".[")
+	if end < 0 {
+		end = len(s)
+	}
+	switch name := s[:end]; name {
+	case "":
+	case "*":
+		st.wildcard = true
+	default:
+		st.names = []string{name}
+	}
+	return s[end:]
+}
+
+// parseBracket [*] ['a','b'] [0,-1] [start:end:step]
+func (st *jsonStep) parseBracket(s string) (string, error) {
+	end, quote := -1, byte(0)
+	for i := 1; i < len(s) && end < 0; i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			end = i
+		}
+	}
+	if end < 0 {
+		return "", ErrJSONPath
+	}
+	body := strings.TrimSpace(s[1:end])
+	rest := s[end+1:]
+	switch {
+	case body == "*":
+		st.wildcard = true
+	case body == "":
+		return "", ErrJSONPath
+	case body[0] == '\'' || body[0] == '"':
+		for body != "" {
+			q := body[0]
+			if q != '\'' && q != '"' {
+				return "", ErrJSONPath
+			}
+			var sb strings.Builder
+			i := 1
+			for ; i < len(body) && body[i] != q; i++ {
+				if body[i] == '\\' && i+1 < len(body) {
+					i++
+				}
+				sb.WriteByte(body[i])
+			}
+			if i >= len(body) {
+				return "", ErrJSONPath
+			}
+			st.names = append(st.names, sb.String())
+			body = strings.TrimSpace(body[i+1:])
+			if body != "" {
+				if body[0] != ',' {
+					return "", ErrJSONPath
+				}
+				body = strings.TrimSpace(body[1:])
+				if body == "" {
+					return "", ErrJSONPath
+				}
+			}
+		}
+	case strings.Contains(body, ":"):
+		parts := strings.Split(body, ":")
+		if len(parts) > 3 {
+			return "", ErrJSONPath
+		}
+		sl := &jsonSlice{step: 1}
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return "", ErrJSONPath
+			}
+			switch i {
+			case 0:
+				sl.start, sl.hasStart = n, true
+			case 1:
+				sl.end, sl.hasEnd = n, true
+			default:
+				sl.step = n
+			}
+		}
+		if sl.step <= 0 {
+			return "", ErrJSONPath
+		}
+		st.slice = sl
+	default:
+		for _, part := range strings.Split(body, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return "", ErrJSONPath
+			}
+			st.indexes = append(st.indexes, n)
+		}
+	}
+	return rest, nil
+}
+
+// Eval 路径在文档中的所有匹配 (按文档顺序)
+func (p *JSONPath) Eval(doc *JSON) []JSONRef {
+	refs := []JSONRef{{Value: doc.Root}}
+	for _, st := range p.steps {
+		var next []JSONRef
+		for _, r := range refs {
+			if !st.recursive {
+				next = st.apply(r, next)
+				continue
+			}
+			for _, d := range descendants(r, nil) {
+				next = st.apply(d, next)
+			}
+		}
+		refs = next
+	}
+	return refs
+}
+
+// descendants 当前值及其所有后代 (先序)
+func descendants(r JSONRef, out []JSONRef) []JSONRef {
+	out = append(out, r)
+	switch v := r.Value.(type) {
+	case *JSONMap:
+		for _, k := range v.keys {
+			out = descendants(JSONRef{Value: v.values[k], parent: v, key: k}, out)
+		}
+	case *JSONArray:
+		for i, e := range v.Elems {
+			out = descendants(JSONRef{Value: e, parent: v, index: i}, out)
+		}
+	}
+	return out
+}
+
+func (st *jsonStep) apply(r JSONRef, out []JSONRef) []JSONRef {
+	switch v := r.Value.(type) {
+	case *JSONMap:
+		if st.wildcard {
+			for _, k := range v.keys {
+				out = append(out, JSONRef{Value: v.values[k], parent: v, key: k})
+			}
+		}
+		for _, k := range st.names {
+			if e, ok := v.values[k]; ok {
+				out = append(out, JSONRef{Value: e, parent: v, key: k})
+			}
+		}
+	case *JSONArray:
+		n := len(v.Elems)
+		at := func(i int) {
+			out = append(out, JSONRef{Value: v.Elems[i], parent: v, index: i})
+		}
+		switch {
+		case st.wildcard:
+			for i := range v.Elems {
+				at(i)
+			}
+		case st.slice != nil:
+			start, end := 0, n
+			if st.slice.hasStart {
+				start = normalizeJSONIndex(st.slice.start, n)
+			}
+			if st.slice.hasEnd {
+				end = normalizeJSONIndex(st.slice.end, n)
+			}
+			for i := start; i < end; i += st.slice.step {
+				at(i)
+			}
+		default:
+			for _, i := range st.indexes {
+				if i < 0 {
+					i += n
+				}
+				if i >= 0 && i < n {
+					at(i)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// normalizeJSONIndex 切片的边界: 负数从末尾计算 结果限制在 [0, n]
+func normalizeJSONIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	return max(min(i, n), 0)
+}
+
+// SplitLast 最后一步为单个键名时返回其余部分与键名 (JSON.SET 在已有对象中新增键)
+func (p *JSONPath) SplitLast() (*JSONPath, string, bool) {
+	if len(p.steps) == 0 {
+		return nil, "", false
+	}
+	last := p.steps[len(p.steps)-1]
+	if last.recursive || last.wildcard || len(last.names) != 1 {
+		return nil, "", false
+	}
+	return &JSONPath{Legacy: p.Legacy, text: p.text, steps: p.steps[:len(p.steps)-1]}, last.names[0], true
+}
+
+// IsRoot 路径是否只指向根节点
+func (p *JSONPath) IsRoot() bool {
+	return len(p.steps) == 0
+}
+
+// Replace 替换匹配到的值 根节点时替换整个文档
+func (doc *JSON) Replace(r JSONRef, v any) {
+	switch parent := r.parent.(type) {
+	case *JSONMap:
+		parent.Set(r.key, v)
+	case *JSONArray:
+		parent.Elems[r.index] = v
+	default:
+		doc.Root = v
+	}
+}
+
+// Delete 删除匹配到的值 (根节点除外) 返回删除的个数
+// 同一数组中的元素从后往前删除 避免下标失效
+func (doc *JSON) Delete(refs []JSONRef) int {
+	refs = append([]JSONRef(nil), refs...)
+	sort.SliceStable(refs, func(i, j int) bool {
+		return refs[i].index > refs[j].index
+	})
+	type pos struct {
+		parent any
+		index  int
+	}
+	seen := make(map[pos]bool)
+	deleted := 0
+	for _, r := range refs {
+		switch parent := r.parent.(type) {
+		case *JSONMap:
+			if parent.Delete(r.key) {
+				deleted++
+			}
+		case *JSONArray:
+			if seen[pos{parent, r.index}] {
+				continue
+			}
+			seen[pos{parent, r.index}] = true
+			parent.Elems = append(parent.Elems[:r.index], parent.Elems[r.index+1:]...)
+			deleted++
+		}
+	}
+	return deleted
+}
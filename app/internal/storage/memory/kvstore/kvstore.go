@@ -4,17 +4,21 @@ package kvstore
 import (
 	"sync"
 	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
 )
 
 type Store struct {
 	Mu      sync.RWMutex
-	Data    map[string]string
+	Data    map[string]*Object
 	Expires map[string]time.Time
+
+	hashExpires map[string]struct{} // 有字段过期时间的哈希键 (可能包含已删除的键)
 }
 
 func NewStore() *Store {
 	s := &Store{
-		Data:    make(map[string]string),
+		Data:    make(map[string]*Object),
 		Expires: make(map[string]time.Time),
 	}
 	// 封装清理过期键的goroutine
@@ -22,36 +26,99 @@ func NewStore() *Store {
 	return s
 }
 
+// Set 写入字符串 并清除原有的过期时间
 func (s *Store) Set(key, value string) {
+	s.SetWithExpire(key, value, 0)
+}
+
+// Get 读取字符串值 键不存在或不是字符串时返回 false
+func (s *Store) Get(key string) (string, bool) {
+	value, ok, err := s.GetString(key)
+	return value, ok && err == nil
+}
+
+// GetString 读取字符串值 键的类型不是字符串时返回 WRONGTYPE 错误
+func (s *Store) GetString(key string) (string, bool, error) {
+	o, ok := s.Lookup(key)
+	if !ok {
+		return "", false, nil
+	}
+	if o.Type != TypeString {
+		return "", false, errors_r.ErrWrongType
+	}
+	return o.Str(), true, nil
+}
+
+func (s *Store) SetWithExpire(key, value string, ttl time.Duration) {
+	s.SetObject(key, NewStringObject(value), ttl)
+}
+
+// Lookup 查找键 同时更新访问时钟 (LRU/LFU)
+func (s *Store) Lookup(key string) (*Object, bool) {
 	s.Mu.Lock()
 	defer s.Mu.Unlock()
-	s.Data[key] = value
+	o, ok := s.lookup(key)
+	if ok {
+		o.touch()
+	}
+	return o, ok
 }
 
-func (s *Store) Get(key string) (string, bool) {
+// Peek 查找键 不更新访问时钟 (OBJECT 等内省命令)
+func (s *Store) Peek(key string) (*Object, bool) {
 	s.Mu.Lock()
 	defer s.Mu.Unlock()
+	return s.lookup(key)
+}
 
-	// Get时检查是否过期  （ 惰性删除 ）
+// lookup 调用方需持有 s.Mu 写锁
+func (s *Store) lookup(key string) (*Object, bool) {
+	// 检查是否过期  （ 惰性删除 ）
 	if expire, ok := s.Expires[key]; ok && time.Now().After(expire) {
 		delete(s.Data, key)
 		delete(s.Expires, key)
-		return "", false
+		return nil, false
 	}
-
-	val, ok := s.Data[key]
-	return val, ok
+	o, ok := s.Data[key]
+	// 哈希字段的惰性过期 字段全部过期时键也不存在
+	if ok && o.Type == TypeHash {
+		if h := o.Value.(*Hash); h.HasExpires() && h.expireFields(time.Now().UnixMilli()) > 0 && h.Len() == 0 {
+			delete(s.Data, key)
+			delete(s.Expires, key)
+			return nil, false
+		}
+	}
+	return o, ok
 }
 
-func (s *Store) SetWithExpire(key, value string, ttl time.Duration) {
+// SetObject 写入任意类型的值 ttl 为 0 时不过期
+func (s *Store) SetObject(key string, o *Object, ttl time.Duration) {
 	s.Mu.Lock()
 	defer s.Mu.Unlock()
-	s.Data[key] = value
+	s.Data[key] = o
 	if ttl > 0 {
 		s.Expires[key] = time.Now().Add(ttl)
 	} else {
 		delete(s.Expires, key)
 	}
+	s.track(key, o)
+}
+
+// Exists 键是否存在 (任意类型)
+func (s *Store) Exists(key string) bool {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	_, ok := s.lookup(key)
+	return ok
+}
+
+// Type TYPE 命令 键不存在时为 "none"
+func (s *Store) Type(key string) string {
+	o, ok := s.Peek(key)
+	if !ok {
+		return "none"
+	}
+	return o.Type.String()
 }
 
 func (s *Store) Delete(key string) {
@@ -61,6 +128,27 @@ func (s *Store) Delete(key string) {
 	delete(s.Expires, key)
 }
 
+// DeleteIfExpired 键已过期时删除 (PX 定时器到期)
+func (s *Store) DeleteIfExpired(key string) bool {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	if expire, ok := s.Expires[key]; ok && !time.Now().Before(expire) {
+		delete(s.Data, key)
+		delete(s.Expires, key)
+		return true
+	}
+	return false
+}
+
+// Flush 清空所有键 (全量同步加载 RDB 前调用)
+func (s *Store) Flush() {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	s.Data = make(map[string]*Object)
+	s.Expires = make(map[string]time.Time)
+	s.hashExpires = nil
+}
+
 func (s *Store) Keys() []string {
 	s.Mu.RLock()
 	defer s.Mu.RUnlock()
@@ -71,6 +159,12 @@ func (s *Store) Keys() []string {
 	return keys
 }
 
+func (s *Store) Len() int {
+	s.Mu.RLock()
+	defer s.Mu.RUnlock()
+	return len(s.Data)
+}
+
 // 后台清理过期键的goroutine
 func (s *Store) StartCleanup(interval time.Duration) {
 	go func() {
@@ -95,4 +189,14 @@ func (s *Store) cleanupExpired() {
 			delete(s.Expires, key)
 		}
 	}
+	// 哈希字段的过期时间
+	s.expireHashFields(now)
+}
+
+// ExpireAt 键的过期时间 没有设置过期时间时返回 false
+func (s *Store) ExpireAt(key string) (time.Time, bool) {
+	s.Mu.RLock()
+	defer s.Mu.RUnlock()
+	t, ok := s.Expires[key]
+	return t, ok
 }
@@ -0,0 +1,79 @@
+package kvstore
+
+import "github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+
+// listpack 编码的上限 超出后转换为 quicklist (不再转换回来)
+const (
+	listMaxListpackEntries = 128
+	listMaxListpackValue   = 64
+)
+
+func NewListObject() *Object {
+	return NewObject(TypeList, EncListpack, NewQuicklist())
+}
+
+// List 读取列表 键不存在时返回 nil, 类型不符时返回 WRONGTYPE
+func (tx *Tx) List(key string) (*Quicklist, error) {
+	o, ok := tx.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if o.Type != TypeList {
+		return nil, errors_r.ErrWrongType
+	}
+	return o.Value.(*Quicklist), nil
+}
+
+// ListOrCreate 读取列表 键不存在时创建空列表 (元素写入后由 ListChanged 维护编码)
+func (tx *Tx) ListOrCreate(key string) (*Quicklist, error) {
+	l, err := tx.List(key)
+	if err != nil || l != nil {
+		return l, err
+	}
+	o := NewListObject()
+	tx.Add(key, o)
+	return o.Value.(*Quicklist), nil
+}
+
+// ListChanged 列表修改后调用: 空列表删除键, 否则按大小更新编码
+// added 为本次写入的元素
+func (tx *Tx) ListChanged(key string, added ...string) {
+	o, ok := tx.s.Data[key]
+	if !ok || o.Type != TypeList {
+		return
+	}
+	l := o.Value.(*Quicklist)
+	if l.Len() == 0 {
+		tx.Delete(key)
+		return
+	}
+	if o.Encoding != EncListpack {
+		return
+	}
+	if l.Len() > listMaxListpackEntries {
+		o.Encoding = EncQuicklist
+		return
+	}
+	for _, v := range added {
+		if len(v) > listMaxListpackValue {
+			o.Encoding = EncQuicklist
+			return
+		}
+	}
+}
+
+// NewListObjectFrom 由元素构造列表对象 (RDB 加载) 编码按内容选择
+func NewListObjectFrom(values []string) *Object {
+	o := NewListObject()
+	l := o.Value.(*Quicklist)
+	for _, v := range values {
+		l.PushBack(v)
+		if len(v) > listMaxListpackValue {
+			o.Encoding = EncQuicklist
+		}
+	}
+	if l.Len() > listMaxListpackEntries {
+		o.Encoding = EncQuicklist
+	}
+	return o
+}
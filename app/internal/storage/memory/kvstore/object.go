@@ -0,0 +1,208 @@
+package kvstore
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// ObjType 值类型 (TYPE 命令的输出)
+type ObjType uint8
+
+const (
+	TypeString ObjType = iota
+	TypeList
+	TypeSet
+	TypeZSet
+	TypeHash
+	TypeStream
+	TypeJSON
+	TypeBloom
+	TypeCuckoo
+	TypeCMS
+	TypeTopK
+	TypeTimeSeries
+)
+
+func (t ObjType) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeList:
+		return "list"
+	case TypeSet:
+		return "set"
+	case TypeZSet:
+		return "zset"
+	case TypeHash:
+		return "hash"
+	case TypeStream:
+		return "stream"
+	case TypeJSON:
+		return "ReJSON-RL"
+	case TypeBloom:
+		return "MBbloom--"
+	case TypeCuckoo:
+		return "MBbloomCF"
+	case TypeCMS:
+		return "CMSk-TYPE"
+	case TypeTopK:
+		return "TopK-TYPE"
+	case TypeTimeSeries:
+		return "TSDB-TYPE"
+	}
+	return "unknown"
+}
+
+// Encoding 值的内部编码 (OBJECT ENCODING 的输出)
+type Encoding uint8
+
+const (
+	EncRaw Encoding = iota
+	EncInt
+	EncEmbstr
+	EncListpack
+	EncQuicklist
+	EncHashtable
+	EncIntset
+	EncSkiplist
+	EncStream
+	EncListpackEx // 带字段过期时间的小哈希
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case EncRaw:
+		return "raw"
+	case EncInt:
+		return "int"
+	case EncEmbstr:
+		return "embstr"
+	case EncListpack:
+		return "listpack"
+	case EncQuicklist:
+		return "quicklist"
+	case EncHashtable:
+		return "hashtable"
+	case EncIntset:
+		return "intset"
+	case EncSkiplist:
+		return "skiplist"
+	case EncStream:
+		return "stream"
+	case EncListpackEx:
+		return "listpackex"
+	}
+	return "unknown"
+}
+
+// embstr 编码的最大长度 (与 Redis OBJ_ENCODING_EMBSTR_SIZE_LIMIT 一致)
+const embstrSizeLimit = 44
+
+// Object 存储中的一个值
+// Value 的具体类型由 Type 决定: TypeString 为 string, 其他类型由各自的命令定义
+// 字符串对象创建后不再修改: GET 等在释放 Store.Mu 之后读取值, 写命令以新对象替换 (Tx.Replace)
+type Object struct {
+	Type     ObjType
+	Encoding Encoding
+	Value    any
+
+	lru  int64 // 最近一次访问时间 (Unix 毫秒) OBJECT IDLETIME
+	freq uint8 // 对数访问计数器 OBJECT FREQ
+}
+
+// LFU 计数器的初始值与增长因子 (lfu-log-factor)
+const (
+	lfuInitVal   = 5
+	lfuLogFactor = 10
+)
+
+// NewObject 创建对象 访问时钟从当前时间开始
+func NewObject(typ ObjType, enc Encoding, value any) *Object {
+	return &Object{Type: typ, Encoding: enc, Value: value, lru: time.Now().UnixMilli(), freq: lfuInitVal}
+}
+
+// NewStringObject 按内容选择编码: 可表示为 int64 的整数为 int (值保存为 int64), 短字符串为 embstr, 其余为 raw
+func NewStringObject(value string) *Object {
+	o := NewObject(TypeString, EncRaw, nil)
+	o.setStr(value)
+	return o
+}
+
+// NewIntObject 整数编码的字符串对象 (INCR 等计数器)
+func NewIntObject(n int64) *Object {
+	return NewObject(TypeString, EncInt, n)
+}
+
+// Str 字符串对象的值
+func (o *Object) Str() string {
+	switch v := o.Value.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+	return ""
+}
+
+// setStr 设置字符串对象的值并选择编码
+func (o *Object) setStr(value string) {
+	if n, ok := parseStrictInt(value); ok {
+		o.Encoding, o.Value = EncInt, n
+		return
+	}
+	o.Encoding, o.Value = EncRaw, value
+	if len(value) <= embstrSizeLimit {
+		o.Encoding = EncEmbstr
+	}
+}
+
+// Int 字符串对象的整数值 不是整数时返回 false
+func (o *Object) Int() (int64, bool) {
+	if n, ok := o.Value.(int64); ok {
+		return n, true
+	}
+	return parseStrictInt(o.Str())
+}
+
+// parseStrictInt 只接受整数的规范形式 (没有前导零, 正号与空白)
+func parseStrictInt(s string) (int64, bool) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil && strconv.FormatInt(n, 10) == s
+}
+
+// touch 记录一次访问 调用方需持有 Store.Mu 写锁
+func (o *Object) touch() {
+	o.lru = time.Now().UnixMilli()
+	// 计数器越大 增长概率越低
+	if o.freq == math.MaxUint8 {
+		return
+	}
+	base := float64(o.freq) - lfuInitVal
+	if base < 0 {
+		base = 0
+	}
+	if rand.Float64() < 1/(base*lfuLogFactor+1) {
+		o.freq++
+	}
+}
+
+// IdleTime 距离最近一次访问的时间
+func (o *Object) IdleTime() time.Duration {
+	return time.Since(time.UnixMilli(o.lru))
+}
+
+func (o *Object) Freq() uint8 {
+	return o.freq
+}
+
+// SetIdleTime RESTORE IDLETIME
+func (o *Object) SetIdleTime(d time.Duration) {
+	o.lru = time.Now().Add(-d).UnixMilli()
+}
+
+// SetFreq RESTORE FREQ
+func (o *Object) SetFreq(freq uint8) {
+	o.freq = freq
+}
@@ -0,0 +1,41 @@
+package kvstore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// 规范形式的整数以 int64 保存 其余按长度选择 embstr/raw
+func TestStringObjectEncoding(t *testing.T) {
+	cases := []struct {
+		value string
+		enc   Encoding
+	}{
+		{"123", EncInt},
+		{"-9223372036854775808", EncInt},
+		{"9223372036854775808", EncEmbstr},
+		{"007", EncEmbstr},
+		{"+1", EncEmbstr},
+		{" 1", EncEmbstr},
+		{strings.Repeat("x", 45), EncRaw},
+	}
+	for _, c := range cases {
+		o := NewStringObject(c.value)
+		assert.Equal(t, c.enc, o.Encoding)
+		assert.Equal(t, c.value, o.Str())
+	}
+
+	o := NewStringObject("10")
+	n, ok := o.Int()
+	assert.Equal(t, true, ok)
+	assert.Equal(t, int64(10), n)
+	o = NewStringObject("10.5")
+	_, ok = o.Int()
+	assert.Equal(t, false, ok)
+	assert.Equal(t, EncEmbstr, o.Encoding)
+	o = NewIntObject(-3)
+	assert.Equal(t, "-3", o.Str())
+	assert.Equal(t, EncInt, o.Encoding)
+}
@@ -0,0 +1,355 @@
+package kvstore
+
+// quicklistFill 每个节点最多保存的元素个数
+// 两端 push 只移动所在节点的元素, 大列表不会整体复制
+const quicklistFill = 128
+
+// Quicklist 列表值 由固定容量的节点组成的双向链表
+type Quicklist struct {
+	head, tail *qlNode
+	count      int
+	nodes      int
+}
+
+type qlNode struct {
+	prev, next *qlNode
+	entries    []string
+}
+
+func NewQuicklist() *Quicklist {
+	return &Quicklist{}
+}
+
+func (l *Quicklist) Len() int {
+	return l.count
+}
+
+func (l *Quicklist) PushFront(value string) {
+	if l.head == nil || len(l.head.entries) >= quicklistFill {
+		l.linkBefore(l.head, &qlNode{entries: make([]string, 0, 8)})
+	}
+	n := l.head
+	n.entries = append(n.entries, "")
+	copy(n.entries[1:], n.entries)
+	n.entries[0] = value
+	l.count++
+}
+
+func (l *Quicklist) PushBack(value string) {
+	if l.tail == nil || len(l.tail.entries) >= quicklistFill {
+		l.linkAfter(l.tail, &qlNode{entries: make([]string, 0, 8)})
+	}
+	l.tail.entries = append(l.tail.entries, value)
+	l.count++
+}
+
+func (l *Quicklist) PopFront() (string, bool) {
+	if l.count == 0 {
+		return "", false
+	}
+	n := l.head
+	v := n.entries[0]
+	n.entries = n.entries[1:]
+	l.count--
+	if len(n.entries) == 0 {
+		l.unlink(n)
+	}
+	return v, true
+}
+
+func (l *Quicklist) PopBack() (string, bool) {
+	if l.count == 0 {
+		return "", false
+	}
+	n := l.tail
+	v := n.entries[len(n.entries)-1]
+	n.entries = n.entries[:len(n.entries)-1]
+	l.count--
+	if len(n.entries) == 0 {
+		l.unlink(n)
+	}
+	return v, true
+}
+
+// index 将负数下标转换为正数 越界时返回 false
+func (l *Quicklist) index(i int) (int, bool) {
+	if i < 0 {
+		i += l.count
+	}
+	return i, i >= 0 && i < l.count
+}
+
+// locate 返回第 i 个元素所在的节点与节点内偏移 从较近的一端开始查找
+func (l *Quicklist) locate(i int) (*qlNode, int) {
+	if i < l.count/2 {
+		for n := l.head; n != nil; n = n.next {
+			if i < len(n.entries) {
+				return n, i
+			}
+			i -= len(n.entries)
+		}
+		return nil, 0
+	}
+	i = l.count - 1 - i
+	for n := l.tail; n != nil; n = n.prev {
+		if i < len(n.entries) {
+			return n, len(n.entries) - 1 - i
+		}
+		i -= len(n.entries)
+	}
+	return nil, 0
+}
+
+// Index LINDEX 支持负数下标
+func (l *Quicklist) Index(i int) (string, bool) {
+	i, ok := l.index(i)
+	if !ok {
+		return "", false
+	}
+	n, off := l.locate(i)
+	return n.entries[off], true
+}
+
+// Set LSET 越界时返回 false
+func (l *Quicklist) Set(i int, value string) bool {
+	i, ok := l.index(i)
+	if !ok {
+		return false
+	}
+	n, off := l.locate(i)
+	n.entries[off] = value
+	return true
+}
+
+// Range 返回 [start, stop] 内的元素 下标语义同 LRANGE
+func (l *Quicklist) Range(start, stop int) []string {
+	start, stop, ok := l.clamp(start, stop)
+	if !ok {
+		return []string{}
+	}
+	res := make([]string, 0, stop-start+1)
+	n, off := l.locate(start)
+	for ; n != nil && len(res) < cap(res); n, off = n.next, 0 {
+		end := min(len(n.entries), off+cap(res)-len(res))
+		res = append(res, n.entries[off:end]...)
+	}
+	return res
+}
+
+// clamp 规范化 [start, stop] 区间 区间为空时返回 false
+func (l *Quicklist) clamp(start, stop int) (int, int, bool) {
+	if start < 0 {
+		start += l.count
+	}
+	if stop < 0 {
+		stop += l.count
+	}
+	start = max(start, 0)
+	stop = min(stop, l.count-1)
+	return start, stop, start <= stop && start < l.count
+}
+
+// Values 按顺序返回全部元素
+func (l *Quicklist) Values() []string {
+	return l.Range(0, -1)
+}
+
+// Each 从表头 (reverse 时从表尾) 遍历 fn 返回 false 时停止
+// i 为元素在列表中的下标
+func (l *Quicklist) Each(reverse bool, fn func(i int, value string) bool) {
+	if !reverse {
+		i := 0
+		for n := l.head; n != nil; n = n.next {
+			for _, v := range n.entries {
+				if !fn(i, v) {
+					return
+				}
+				i++
+			}
+		}
+		return
+	}
+	i := l.count - 1
+	for n := l.tail; n != nil; n = n.prev {
+		for j := len(n.entries) - 1; j >= 0; j-- {
+			if !fn(i, n.entries[j]) {
+				return
+			}
+			i--
+		}
+	}
+}
+
+// Insert LINSERT 在第一个等于 pivot 的元素前/后插入 找不到 pivot 时返回 false
+func (l *Quicklist) Insert(pivot, value string, before bool) bool {
+	for n := l.head; n != nil; n = n.next {
+		for off, v := range n.entries {
+			if v != pivot {
+				continue
+			}
+			if !before {
+				off++
+			}
+			n.entries = append(n.entries, "")
+			copy(n.entries[off+1:], n.entries[off:])
+			n.entries[off] = value
+			l.count++
+			if len(n.entries) > quicklistFill {
+				l.split(n)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Remove LREM count > 0 从表头删除, count < 0 从表尾删除, count = 0 删除全部
+func (l *Quicklist) Remove(value string, count int) int {
+	limit := count
+	if limit < 0 {
+		limit = -limit
+	}
+	removed := 0
+	keep := func(entries []string, reverse bool) []string {
+		if reverse {
+			// 从尾部向前筛选 原地向右压缩 保持原有顺序
+			k := len(entries)
+			for j := len(entries) - 1; j >= 0; j-- {
+				if entries[j] == value && (limit == 0 || removed < limit) {
+					removed++
+					continue
+				}
+				k--
+				entries[k] = entries[j]
+			}
+			return entries[k:]
+		}
+		out := entries[:0]
+		for _, v := range entries {
+			if v == value && (limit == 0 || removed < limit) {
+				removed++
+				continue
+			}
+			out = append(out, v)
+		}
+		return out
+	}
+	if count >= 0 {
+		for n := l.head; n != nil; {
+			next := n.next
+			n.entries = keep(n.entries, false)
+			if len(n.entries) == 0 {
+				l.unlink(n)
+			}
+			n = next
+		}
+	} else {
+		for n := l.tail; n != nil; {
+			prev := n.prev
+			n.entries = keep(n.entries, true)
+			if len(n.entries) == 0 {
+				l.unlink(n)
+			}
+			n = prev
+		}
+	}
+	l.count -= removed
+	return removed
+}
+
+// Trim LTRIM 只保留 [start, stop] 内的元素
+func (l *Quicklist) Trim(start, stop int) {
+	start, stop, ok := l.clamp(start, stop)
+	if !ok {
+		*l = Quicklist{}
+		return
+	}
+	l.dropFront(start)
+	l.dropBack(l.count - (stop - start + 1))
+}
+
+// dropFront 删除表头的 k 个元素 整个节点直接摘除
+func (l *Quicklist) dropFront(k int) {
+	for k > 0 && l.head != nil {
+		n := l.head
+		if len(n.entries) <= k {
+			k -= len(n.entries)
+			l.count -= len(n.entries)
+			l.unlink(n)
+			continue
+		}
+		n.entries = append([]string(nil), n.entries[k:]...)
+		l.count -= k
+		return
+	}
+}
+
+func (l *Quicklist) dropBack(k int) {
+	for k > 0 && l.tail != nil {
+		n := l.tail
+		if len(n.entries) <= k {
+			k -= len(n.entries)
+			l.count -= len(n.entries)
+			l.unlink(n)
+			continue
+		}
+		n.entries = n.entries[:len(n.entries)-k]
+		l.count -= k
+		return
+	}
+}
+
+// split 将超出容量的节点一分为二
+func (l *Quicklist) split(n *qlNode) {
+	half := len(n.entries) / 2
+	right := &qlNode{entries: append([]string(nil), n.entries[half:]...)}
+	n.entries = n.entries[:half:half]
+	l.linkAfter(n, right)
+}
+
+// linkBefore 在 at 之前插入节点 at 为 nil 时插入到空链表
+func (l *Quicklist) linkBefore(at, n *qlNode) {
+	l.nodes++
+	if at == nil {
+		l.head, l.tail = n, n
+		return
+	}
+	n.next, n.prev = at, at.prev
+	if at.prev != nil {
+		at.prev.next = n
+	} else {
+		l.head = n
+	}
+	at.prev = n
+}
+
+// linkAfter 在 at 之后插入节点 at 为 nil 时插入到空链表
+func (l *Quicklist) linkAfter(at, n *qlNode) {
+	l.nodes++
+	if at == nil {
+		l.head, l.tail = n, n
+		return
+	}
+	n.prev, n.next = at, at.next
+	if at.next != nil {
+		at.next.prev = n
+	} else {
+		l.tail = n
+	}
+	at.next = n
+}
+
+func (l *Quicklist) unlink(n *qlNode) {
+	l.nodes--
+	if n.prev != nil {
+		n.prev.next = n.next
+	} else {
+		l.head = n.next
+	}
+	if n.next != nil {
+		n.next.prev = n.prev
+	} else {
+		l.tail = n.prev
+	}
+	n.prev, n.next = nil, nil
+}
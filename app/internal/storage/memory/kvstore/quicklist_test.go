@@ -0,0 +1,57 @@
+package kvstore
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// 跨越多个节点的操作与切片实现的结果一致
+func TestQuicklist(t *testing.T) {
+	l := NewQuicklist()
+	var want []string
+	for i := 0; i < 500; i++ {
+		v := strconv.Itoa(i)
+		if i%3 == 0 {
+			l.PushFront(v)
+			want = append([]string{v}, want...)
+		} else {
+			l.PushBack(v)
+			want = append(want, v)
+		}
+	}
+	assert.Equal(t, want, l.Values())
+	assert.Equal(t, want[200:301], l.Range(200, 300))
+	assert.Equal(t, want[490:], l.Range(-10, 1000))
+
+	v, ok := l.Index(-1)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, want[499], v)
+	_, ok = l.Index(500)
+	assert.Equal(t, false, ok)
+
+	// 在节点中间插入 超出容量时拆分节点
+	for i := 0; i < quicklistFill; i++ {
+		assert.Equal(t, true, l.Insert("250", "x", false))
+	}
+	assert.Equal(t, 500+quicklistFill, l.Len())
+	assert.Equal(t, quicklistFill, l.Remove("x", 0))
+	assert.Equal(t, want, l.Values())
+
+	l.Trim(100, -101)
+	assert.Equal(t, want[100:400], l.Values())
+	l.Trim(5, 1)
+	assert.Equal(t, 0, l.Len())
+	_, ok = l.PopBack()
+	assert.Equal(t, false, ok)
+}
+
+func TestQuicklistRemoveFromTail(t *testing.T) {
+	l := NewQuicklist()
+	for _, v := range []string{"a", "b", "a", "c", "a"} {
+		l.PushBack(v)
+	}
+	assert.Equal(t, 2, l.Remove("a", -2))
+	assert.Equal(t, []string{"a", "b", "c"}, l.Values())
+}
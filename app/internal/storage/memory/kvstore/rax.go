@@ -0,0 +1,231 @@
+package kvstore
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Rax 压缩前缀树 (radix tree) 按键的字节序有序遍历
+// 流以 128 位大端 ID 为键保存节点, 消费者组的待确认列表 (PEL) 也以 ID 为键
+type Rax[V any] struct {
+	root raxNode[V]
+	size int
+}
+
+type raxNode[V any] struct {
+	prefix   []byte
+	children []*raxNode[V] // 按 prefix[0] 升序
+	leaf     bool          // 有键在此结束
+	val      V
+}
+
+func NewRax[V any]() *Rax[V] {
+	return &Rax[V]{}
+}
+
+func (r *Rax[V]) Len() int {
+	return r.size
+}
+
+// child 子节点中首字节为 b 的位置 不存在时返回插入位置与 false
+func (n *raxNode[V]) child(b byte) (int, bool) {
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].prefix[0] >= b })
+	return i, i < len(n.children) && n.children[i].prefix[0] == b
+}
+
+func commonPrefix(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// Insert 写入键 返回是否为新键
+func (r *Rax[V]) Insert(key []byte, v V) bool {
+	added := r.root.insert(key, v)
+	if added {
+		r.size++
+	}
+	return added
+}
+
+// insert key 不含 n.prefix 部分
+func (n *raxNode[V]) insert(key []byte, v V) bool {
+	if len(key) == 0 {
+		added := !n.leaf
+		n.leaf, n.val = true, v
+		return added
+	}
+	i, ok := n.child(key[0])
+	if !ok {
+		c := &raxNode[V]{prefix: bytes.Clone(key), leaf: true, val: v}
+		n.children = append(n.children, nil)
+		copy(n.children[i+1:], n.children[i:])
+		n.children[i] = c
+		return true
+	}
+	c := n.children[i]
+	l := commonPrefix(c.prefix, key)
+	if l < len(c.prefix) {
+		// 拆分: 公共前缀成为新的中间节点
+		mid := &raxNode[V]{prefix: bytes.Clone(c.prefix[:l]), children: []*raxNode[V]{c}}
+		c.prefix = c.prefix[l:]
+		n.children[i] = mid
+		c = mid
+	}
+	return c.insert(key[l:], v)
+}
+
+// Get 查找键
+func (r *Rax[V]) Get(key []byte) (V, bool) {
+	n := &r.root
+	for len(key) > 0 {
+		i, ok := n.child(key[0])
+		if !ok {
+			break
+		}
+		c := n.children[i]
+		if !bytes.HasPrefix(key, c.prefix) {
+			break
+		}
+		key, n = key[len(c.prefix):], c
+	}
+	if len(key) == 0 && n.leaf {
+		return n.val, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Remove 删除键 返回键是否存在
+func (r *Rax[V]) Remove(key []byte) bool {
+	removed := r.root.remove(key)
+	if removed {
+		r.size--
+	}
+	return removed
+}
+
+func (n *raxNode[V]) remove(key []byte) bool {
+	if len(key) == 0 {
+		if !n.leaf {
+			return false
+		}
+		var zero V
+		n.leaf, n.val = false, zero
+		return true
+	}
+	i, ok := n.child(key[0])
+	if !ok {
+		return false
+	}
+	c := n.children[i]
+	if !bytes.HasPrefix(key, c.prefix) || !c.remove(key[len(c.prefix):]) {
+		return false
+	}
+	// 删除空节点, 合并只有一个子节点的中间节点
+	switch {
+	case !c.leaf && len(c.children) == 0:
+		n.children = append(n.children[:i], n.children[i+1:]...)
+	case !c.leaf && len(c.children) == 1:
+		gc := c.children[0]
+		gc.prefix = append(bytes.Clone(c.prefix), gc.prefix...)
+		n.children[i] = gc
+	}
+	return true
+}
+
+// Ascend 从第一个 >= from 的键开始按升序遍历 from 为 nil 时从头开始 fn 返回 false 时停止
+func (r *Rax[V]) Ascend(from []byte, fn func(key []byte, v V) bool) {
+	r.root.ascend(nil, from, from != nil, fn)
+}
+
+func (n *raxNode[V]) ascend(acc, from []byte, bounded bool, fn func([]byte, V) bool) bool {
+	acc = append(acc, n.prefix...)
+	if bounded {
+		part := from[min(len(from), len(acc)-len(n.prefix)):min(len(from), len(acc))]
+		switch bytes.Compare(n.prefix, part) {
+		case -1:
+			return true
+		case 1:
+			bounded = false
+		}
+	}
+	// 有界时此处的键是 from 的前缀 只有与 from 相等时才满足 >= from
+	if n.leaf && (!bounded || len(acc) == len(from)) {
+		if !fn(bytes.Clone(acc), n.val) {
+			return false
+		}
+	}
+	for _, c := range n.children {
+		if !c.ascend(acc, from, bounded, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Descend 从最后一个 <= from 的键开始按降序遍历 from 为 nil 时从末尾开始
+func (r *Rax[V]) Descend(from []byte, fn func(key []byte, v V) bool) {
+	r.root.descend(nil, from, from != nil, fn)
+}
+
+func (n *raxNode[V]) descend(acc, from []byte, bounded bool, fn func([]byte, V) bool) bool {
+	acc = append(acc, n.prefix...)
+	if bounded {
+		part := from[min(len(from), len(acc)-len(n.prefix)):min(len(from), len(acc))]
+		switch bytes.Compare(n.prefix, part) {
+		case 1:
+			return true
+		case -1:
+			bounded = false
+		}
+	}
+	for i := len(n.children) - 1; i >= 0; i-- {
+		if !n.children[i].descend(acc, from, bounded, fn) {
+			return false
+		}
+	}
+	// 此处的键是 from 的前缀 (或相等) 必然 <= from
+	if n.leaf {
+		return fn(bytes.Clone(acc), n.val)
+	}
+	return true
+}
+
+// count 子树的节点数
+func (n *raxNode[V]) count() int {
+	c := 1
+	for _, child := range n.children {
+		c += child.count()
+	}
+	return c
+}
+
+// First 最小的键
+func (r *Rax[V]) First() (key []byte, v V, ok bool) {
+	r.Ascend(nil, func(k []byte, val V) bool {
+		key, v, ok = k, val, true
+		return false
+	})
+	return
+}
+
+// Last 最大的键
+func (r *Rax[V]) Last() (key []byte, v V, ok bool) {
+	r.Descend(nil, func(k []byte, val V) bool {
+		key, v, ok = k, val, true
+		return false
+	})
+	return
+}
+
+// Floor 最后一个 <= key 的键
+func (r *Rax[V]) Floor(key []byte) (k []byte, v V, ok bool) {
+	r.Descend(key, func(fk []byte, val V) bool {
+		k, v, ok = fk, val, true
+		return false
+	})
+	return
+}
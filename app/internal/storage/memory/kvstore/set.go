@@ -0,0 +1,233 @@
+package kvstore
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// 编码的上限 (set-max-intset-entries / set-max-listpack-entries / set-max-listpack-value)
+const (
+	setMaxIntsetEntries   = 512
+	setMaxListpackEntries = 128
+	setMaxListpackValue   = 64
+)
+
+// Set 集合值
+// 成员全部为整数且数量不多时以有序 int64 切片存储 (intset), 否则转换为哈希集合 (不再转换回来)
+type Set struct {
+	ints    []int64
+	members map[string]struct{} // 非 nil 时为哈希集合
+}
+
+func NewSet() *Set {
+	return &Set{}
+}
+
+// isIntset 当前是否为 intset 表示
+func (s *Set) isIntset() bool {
+	return s.members == nil
+}
+
+// intMember 成员能否以整数存储: 可表示为 int64 且为规范形式 ("01" 不是)
+func intMember(m string) (int64, bool) {
+	n, err := strconv.ParseInt(m, 10, 64)
+	return n, err == nil && strconv.FormatInt(n, 10) == m
+}
+
+func (s *Set) Len() int {
+	if s.isIntset() {
+		return len(s.ints)
+	}
+	return len(s.members)
+}
+
+func (s *Set) search(n int64) (int, bool) {
+	i := sort.Search(len(s.ints), func(i int) bool { return s.ints[i] >= n })
+	return i, i < len(s.ints) && s.ints[i] == n
+}
+
+// Add 添加成员 返回是否为新成员 需要时由 intset 升级为哈希集合
+func (s *Set) Add(m string) bool {
+	if s.isIntset() {
+		if n, ok := intMember(m); ok {
+			i, found := s.search(n)
+			if found {
+				return false
+			}
+			if len(s.ints) < setMaxIntsetEntries {
+				s.ints = append(s.ints, 0)
+				copy(s.ints[i+1:], s.ints[i:])
+				s.ints[i] = n
+				return true
+			}
+		}
+		s.upgrade()
+	}
+	if _, ok := s.members[m]; ok {
+		return false
+	}
+	s.members[m] = struct{}{}
+	return true
+}
+
+// upgrade intset 转换为哈希集合
+func (s *Set) upgrade() {
+	s.members = make(map[string]struct{}, len(s.ints)+1)
+	for _, n := range s.ints {
+		s.members[strconv.FormatInt(n, 10)] = struct{}{}
+	}
+	s.ints = nil
+}
+
+func (s *Set) Remove(m string) bool {
+	if s.isIntset() {
+		n, ok := intMember(m)
+		if !ok {
+			return false
+		}
+		i, found := s.search(n)
+		if !found {
+			return false
+		}
+		s.ints = append(s.ints[:i], s.ints[i+1:]...)
+		return true
+	}
+	if _, ok := s.members[m]; !ok {
+		return false
+	}
+	delete(s.members, m)
+	return true
+}
+
+func (s *Set) Has(m string) bool {
+	if s.isIntset() {
+		n, ok := intMember(m)
+		if !ok {
+			return false
+		}
+		_, found := s.search(n)
+		return found
+	}
+	_, ok := s.members[m]
+	return ok
+}
+
+// Members 全部成员 intset 按数值升序
+func (s *Set) Members() []string {
+	res := make([]string, 0, s.Len())
+	if s.isIntset() {
+		for _, n := range s.ints {
+			res = append(res, strconv.FormatInt(n, 10))
+		}
+		return res
+	}
+	for m := range s.members {
+		res = append(res, m)
+	}
+	return res
+}
+
+// Ints intset 表示的成员 (RDB intset 编码) 非 intset 时返回 false
+func (s *Set) Ints() ([]int64, bool) {
+	return s.ints, s.isIntset()
+}
+
+// Random 随机返回一个成员
+func (s *Set) Random() (string, bool) {
+	if s.Len() == 0 {
+		return "", false
+	}
+	if s.isIntset() {
+		return strconv.FormatInt(s.ints[rand.Intn(len(s.ints))], 10), true
+	}
+	// map 的遍历顺序是随机的 但不均匀 按随机下标选取
+	i := rand.Intn(len(s.members))
+	for m := range s.members {
+		if i == 0 {
+			return m, true
+		}
+		i--
+	}
+	return "", false
+}
+
+// Pop 随机删除并返回一个成员
+func (s *Set) Pop() (string, bool) {
+	m, ok := s.Random()
+	if ok {
+		s.Remove(m)
+	}
+	return m, ok
+}
+
+func NewSetObject() *Object {
+	return NewObject(TypeSet, EncIntset, NewSet())
+}
+
+// NewSetObjectFrom 由成员构造集合对象 (SINTERSTORE 等与 RDB 加载)
+func NewSetObjectFrom(members []string) *Object {
+	o := NewSetObject()
+	set := o.Value.(*Set)
+	for _, m := range members {
+		set.Add(m)
+	}
+	o.Encoding = setEncoding(set, o.Encoding, members)
+	return o
+}
+
+// Set 读取集合 键不存在时返回 nil, 类型不符时返回 WRONGTYPE
+func (tx *Tx) Set(key string) (*Set, error) {
+	o, ok := tx.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if o.Type != TypeSet {
+		return nil, errors_r.ErrWrongType
+	}
+	return o.Value.(*Set), nil
+}
+
+// SetOrCreate 读取集合 键不存在时创建
+func (tx *Tx) SetOrCreate(key string) (*Set, error) {
+	set, err := tx.Set(key)
+	if err != nil || set != nil {
+		return set, err
+	}
+	o := NewSetObject()
+	tx.Add(key, o)
+	return o.Value.(*Set), nil
+}
+
+// SetChanged 集合修改后调用: 空集合删除键, 否则更新编码
+// added 为本次添加的成员
+func (tx *Tx) SetChanged(key string, added ...string) {
+	o, ok := tx.s.Data[key]
+	if !ok || o.Type != TypeSet {
+		return
+	}
+	set := o.Value.(*Set)
+	if set.Len() == 0 {
+		tx.Delete(key)
+		return
+	}
+	o.Encoding = setEncoding(set, o.Encoding, added)
+}
+
+// setEncoding intset -> listpack -> hashtable 只升级不降级
+func setEncoding(set *Set, enc Encoding, added []string) Encoding {
+	if set.isIntset() {
+		return EncIntset
+	}
+	if enc == EncHashtable || set.Len() > setMaxListpackEntries {
+		return EncHashtable
+	}
+	for _, m := range added {
+		if len(m) > setMaxListpackValue {
+			return EncHashtable
+		}
+	}
+	return EncListpack
+}
@@ -0,0 +1,37 @@
+package kvstore
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestSetEncoding(t *testing.T) {
+	// 全部为整数时为有序的 intset
+	o := NewSetObjectFrom([]string{"3", "-1", "2", "3"})
+	assert.Equal(t, EncIntset, o.Encoding)
+	assert.Equal(t, []string{"-1", "2", "3"}, o.Value.(*Set).Members())
+
+	// 非规范的整数不能存入 intset
+	o = NewSetObjectFrom([]string{"1", "01"})
+	assert.Equal(t, EncListpack, o.Encoding)
+	assert.Equal(t, true, o.Value.(*Set).Has("01"))
+	assert.Equal(t, false, o.Value.(*Set).Has("2"))
+
+	// 超出 intset 上限后升级为哈希集合 不再降级
+	members := make([]string, setMaxIntsetEntries+1)
+	for i := range members {
+		members[i] = strconv.Itoa(i)
+	}
+	o = NewSetObjectFrom(members)
+	assert.Equal(t, EncHashtable, o.Encoding)
+	set := o.Value.(*Set)
+	assert.Equal(t, setMaxIntsetEntries+1, set.Len())
+	for _, m := range members[1:] {
+		set.Remove(m)
+	}
+	_, isIntset := set.Ints()
+	assert.Equal(t, false, isIntset)
+	assert.Equal(t, []string{"0"}, set.Members())
+}
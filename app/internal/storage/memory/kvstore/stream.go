@@ -0,0 +1,358 @@
+package kvstore
+
+import (
+	"encoding/binary"
+	"sort"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// streamNodeMaxEntries 每个节点的条目上限 (stream-node-max-entries)
+const streamNodeMaxEntries = 100
+
+// StreamID 流条目 ID <毫秒时间戳>-<序号>
+type StreamID struct {
+	Ms, Seq uint64
+}
+
+// MaxStreamID 最大的 ID ("+")
+var MaxStreamID = StreamID{Ms: ^uint64(0), Seq: ^uint64(0)}
+
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+func (id StreamID) Compare(o StreamID) int {
+	switch {
+	case id.Ms < o.Ms || (id.Ms == o.Ms && id.Seq < o.Seq):
+		return -1
+	case id == o:
+		return 0
+	}
+	return 1
+}
+
+func (id StreamID) Less(o StreamID) bool {
+	return id.Compare(o) < 0
+}
+
+func (id StreamID) IsZero() bool {
+	return id == StreamID{}
+}
+
+// Next 下一个 ID 已是最大 ID 时返回 false
+func (id StreamID) Next() (StreamID, bool) {
+	switch {
+	case id.Seq < ^uint64(0):
+		return StreamID{id.Ms, id.Seq + 1}, true
+	case id.Ms < ^uint64(0):
+		return StreamID{id.Ms + 1, 0}, true
+	}
+	return id, false
+}
+
+// Prev 上一个 ID 已是 0-0 时返回 false
+func (id StreamID) Prev() (StreamID, bool) {
+	switch {
+	case id.Seq > 0:
+		return StreamID{id.Ms, id.Seq - 1}, true
+	case id.Ms > 0:
+		return StreamID{id.Ms - 1, ^uint64(0)}, true
+	}
+	return id, false
+}
+
+// Key 128 位大端编码 (rax 的键与 RDB 中的原始 ID)
+func (id StreamID) Key() []byte {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], id.Ms)
+	binary.BigEndian.PutUint64(b[8:], id.Seq)
+	return b[:]
+}
+
+func StreamIDFromKey(b []byte) StreamID {
+	return StreamID{Ms: binary.BigEndian.Uint64(b[:8]), Seq: binary.BigEndian.Uint64(b[8:16])}
+}
+
+// StreamEntry 流条目 Fields 为 field, value 交替 (被删除的条目为 nil)
+type StreamEntry struct {
+	ID     StreamID
+	Fields []string
+}
+
+// streamNode 一个节点保存一段连续的条目 (对应 Redis 的一个 listpack)
+// 节点以创建时第一个条目的 ID (master ID) 为键, 条目被删除后键不变
+type streamNode struct {
+	master  StreamID
+	entries []StreamEntry
+}
+
+// search 第一个 ID >= id 的条目位置
+func (n *streamNode) search(id StreamID) int {
+	return sort.Search(len(n.entries), func(i int) bool { return !n.entries[i].ID.Less(id) })
+}
+
+// Stream 流
+type Stream struct {
+	nodes        *Rax[*streamNode]
+	length       uint64
+	LastID       StreamID // 最后添加的 ID (条目删除后不变)
+	MaxDeletedID StreamID // XDEL 删除过的最大 ID
+	EntriesAdded uint64   // 累计添加的条目数
+	groups       map[string]*ConsumerGroup
+}
+
+func NewStream() *Stream {
+	return &Stream{nodes: NewRax[*streamNode](), groups: make(map[string]*ConsumerGroup)}
+}
+
+func (s *Stream) Len() uint64 {
+	return s.length
+}
+
+// Append 追加条目 调用方保证 id 大于 LastID
+func (s *Stream) Append(id StreamID, fields []string) {
+	_, last, ok := s.nodes.Last()
+	if !ok || len(last.entries) >= streamNodeMaxEntries {
+		last = &streamNode{master: id}
+		s.nodes.Insert(id.Key(), last)
+	}
+	last.entries = append(last.entries, StreamEntry{ID: id, Fields: fields})
+	s.length++
+	s.LastID = id
+	s.EntriesAdded++
+}
+
+// AppendNode RDB 加载: 按顺序追加整个节点
+func (s *Stream) AppendNode(master StreamID, entries []StreamEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	s.nodes.Insert(master.Key(), &streamNode{master: master, entries: entries})
+	s.length += uint64(len(entries))
+}
+
+// Range 返回 [start, end] 内的条目 rev 时从 end 开始倒序 count <= 0 不限
+func (s *Stream) Range(start, end StreamID, rev bool, count int) []StreamEntry {
+	res := []StreamEntry{}
+	if end.Less(start) {
+		return res
+	}
+	full := func() bool { return count > 0 && len(res) >= count }
+	if rev {
+		s.nodes.Descend(end.Key(), func(_ []byte, n *streamNode) bool {
+			for i := n.search(end) + 1; i > 0; i-- {
+				if i > len(n.entries) {
+					continue
+				}
+				e := n.entries[i-1]
+				if e.ID.Less(start) {
+					return false
+				}
+				if end.Less(e.ID) {
+					continue
+				}
+				res = append(res, e)
+				if full() {
+					return false
+				}
+			}
+			return true
+		})
+		return res
+	}
+	// 从 master ID <= start 的节点开始
+	from := start.Key()
+	if k, _, ok := s.nodes.Floor(from); ok {
+		from = k
+	}
+	s.nodes.Ascend(from, func(_ []byte, n *streamNode) bool {
+		for _, e := range n.entries[n.search(start):] {
+			if end.Less(e.ID) {
+				return false
+			}
+			res = append(res, e)
+			if full() {
+				return false
+			}
+		}
+		return true
+	})
+	return res
+}
+
+// Get 查找条目
+func (s *Stream) Get(id StreamID) (StreamEntry, bool) {
+	res := s.Range(id, id, false, 1)
+	if len(res) == 0 {
+		return StreamEntry{}, false
+	}
+	return res[0], true
+}
+
+// First 第一个条目
+func (s *Stream) First() (StreamEntry, bool) {
+	res := s.Range(StreamID{}, MaxStreamID, false, 1)
+	if len(res) == 0 {
+		return StreamEntry{}, false
+	}
+	return res[0], true
+}
+
+// Last 最后一个条目
+func (s *Stream) Last() (StreamEntry, bool) {
+	res := s.Range(StreamID{}, MaxStreamID, true, 1)
+	if len(res) == 0 {
+		return StreamEntry{}, false
+	}
+	return res[0], true
+}
+
+// FirstID 第一个条目的 ID 流为空时为 0-0
+func (s *Stream) FirstID() StreamID {
+	e, _ := s.First()
+	return e.ID
+}
+
+// Delete 删除条目 (XDEL)
+func (s *Stream) Delete(id StreamID) bool {
+	k, n, ok := s.nodes.Floor(id.Key())
+	if !ok {
+		return false
+	}
+	i := n.search(id)
+	if i == len(n.entries) || n.entries[i].ID != id {
+		return false
+	}
+	n.entries = append(n.entries[:i], n.entries[i+1:]...)
+	if len(n.entries) == 0 {
+		s.nodes.Remove(k)
+	}
+	s.length--
+	if s.MaxDeletedID.Less(id) {
+		s.MaxDeletedID = id
+	}
+	return true
+}
+
+// TrimMaxLen 删除最早的条目直到剩余 maxLen 个
+// approx 时只删除整个节点 (剩余可能略多于 maxLen); limit > 0 限制删除的条目数
+func (s *Stream) TrimMaxLen(maxLen uint64, approx bool, limit int64) int64 {
+	return s.trim(func(n *streamNode) int {
+		if s.length <= maxLen {
+			return 0
+		}
+		return int(min(s.length-maxLen, uint64(len(n.entries))))
+	}, approx, limit)
+}
+
+// TrimMinID 删除 ID 小于 minID 的条目
+func (s *Stream) TrimMinID(minID StreamID, approx bool, limit int64) int64 {
+	return s.trim(func(n *streamNode) int {
+		return n.search(minID)
+	}, approx, limit)
+}
+
+// trim 从第一个节点开始删除 each 返回节点中应删除的前缀长度
+func (s *Stream) trim(each func(n *streamNode) int, approx bool, limit int64) int64 {
+	var removed int64
+	for {
+		k, n, ok := s.nodes.First()
+		if !ok {
+			break
+		}
+		cnt := each(n)
+		if cnt == 0 {
+			break
+		}
+		if cnt == len(n.entries) {
+			if limit > 0 && removed+int64(cnt) > limit {
+				break
+			}
+			s.nodes.Remove(k)
+		} else {
+			// 近似裁剪不拆分节点
+			if approx || (limit > 0 && removed+int64(cnt) > limit) {
+				break
+			}
+			n.entries = append([]StreamEntry(nil), n.entries[cnt:]...)
+		}
+		s.length -= uint64(cnt)
+		removed += int64(cnt)
+	}
+	return removed
+}
+
+// Nodes 条目节点数与前缀树的节点数 (XINFO STREAM 的 radix-tree-keys / radix-tree-nodes)
+func (s *Stream) Nodes() (keys, nodes int) {
+	return s.nodes.Len(), s.nodes.root.count()
+}
+
+// EachNode 按顺序遍历节点 (RDB)
+func (s *Stream) EachNode(fn func(master StreamID, entries []StreamEntry)) {
+	s.nodes.Ascend(nil, func(_ []byte, n *streamNode) bool {
+		fn(n.master, n.entries)
+		return true
+	})
+}
+
+// EntriesReadAt 估计读到 id 时已读的条目数 (消费者组的 entries-read) 无法确定时返回 -1
+func (s *Stream) EntriesReadAt(id StreamID) int64 {
+	if s.EntriesAdded == 0 {
+		return 0
+	}
+	if s.length == 0 && !s.LastID.Less(id) {
+		return int64(s.EntriesAdded)
+	}
+	switch id.Compare(s.LastID) {
+	case 0:
+		return int64(s.EntriesAdded)
+	case 1:
+		return int64(s.EntriesAdded)
+	}
+	// 中间没有被删除的条目时 id 之前的条目数可以由长度推算
+	first := s.FirstID()
+	if s.MaxDeletedID.Less(first) && id.Less(first) {
+		return int64(s.EntriesAdded - s.length)
+	}
+	return -1
+}
+
+// Lag 消费者组尚未读取的条目数 无法确定时返回 -1
+func (s *Stream) Lag(g *ConsumerGroup) int64 {
+	if s.EntriesAdded == 0 {
+		return 0
+	}
+	if g.EntriesRead < 0 || (g.LastID.Less(s.MaxDeletedID) && s.FirstID().Less(s.MaxDeletedID)) {
+		return -1
+	}
+	return int64(s.EntriesAdded) - g.EntriesRead
+}
+
+func NewStreamObject() *Object {
+	return NewObject(TypeStream, EncStream, NewStream())
+}
+
+// Stream 读取流 键不存在时返回 nil, 类型不符时返回 WRONGTYPE
+func (tx *Tx) Stream(key string) (*Stream, error) {
+	o, ok := tx.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if o.Type != TypeStream {
+		return nil, errors_r.ErrWrongType
+	}
+	return o.Value.(*Stream), nil
+}
+
+// StreamOrCreate 读取流 键不存在时创建 (流为空时不删除键)
+func (tx *Tx) StreamOrCreate(key string) (*Stream, error) {
+	s, err := tx.Stream(key)
+	if err != nil || s != nil {
+		return s, err
+	}
+	o := NewStreamObject()
+	tx.Add(key, o)
+	return o.Value.(*Stream), nil
+}
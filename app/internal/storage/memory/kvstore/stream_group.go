@@ -0,0 +1,202 @@
+package kvstore
+
+import "sort"
+
+// ConsumerGroup 消费者组
+// PEL (pending entries list) 记录已投递但尚未 XACK 的条目 组与每个消费者各保存一份索引
+type ConsumerGroup struct {
+	Name        string
+	LastID      StreamID // 最后投递的 ID
+	EntriesRead int64    // 已读取的条目数 未知时为 -1
+	PEL         *Rax[*PendingEntry]
+	consumers   map[string]*Consumer
+}
+
+// PendingEntry 待确认的条目
+type PendingEntry struct {
+	ID            StreamID
+	Consumer      *Consumer
+	DeliveryTime  int64 // 最后投递时间 Unix 毫秒
+	DeliveryCount uint64
+}
+
+// Consumer 消费者
+type Consumer struct {
+	Name       string
+	SeenTime   int64 // 最后一次交互 (读取/认领) 的时间
+	ActiveTime int64 // 最后一次成功读取/认领条目的时间 从未有过时为 -1
+	PEL        *Rax[*PendingEntry]
+}
+
+// CreateGroup 创建消费者组 已存在时返回 nil
+func (s *Stream) CreateGroup(name string, lastID StreamID, entriesRead int64) *ConsumerGroup {
+	if _, ok := s.groups[name]; ok {
+		return nil
+	}
+	g := &ConsumerGroup{
+		Name:        name,
+		LastID:      lastID,
+		EntriesRead: entriesRead,
+		PEL:         NewRax[*PendingEntry](),
+		consumers:   make(map[string]*Consumer),
+	}
+	s.groups[name] = g
+	return g
+}
+
+func (s *Stream) Group(name string) *ConsumerGroup {
+	return s.groups[name]
+}
+
+func (s *Stream) DestroyGroup(name string) bool {
+	if _, ok := s.groups[name]; !ok {
+		return false
+	}
+	delete(s.groups, name)
+	return true
+}
+
+// Groups 按名称排序的消费者组
+func (s *Stream) Groups() []*ConsumerGroup {
+	res := make([]*ConsumerGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		res = append(res, g)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// Consumer 查找消费者 create 时不存在则创建 返回是否新建
+func (g *ConsumerGroup) Consumer(name string, create bool, now int64) (*Consumer, bool) {
+	if c, ok := g.consumers[name]; ok || !create {
+		return c, false
+	}
+	c := &Consumer{Name: name, SeenTime: now, ActiveTime: -1, PEL: NewRax[*PendingEntry]()}
+	g.consumers[name] = c
+	return c, true
+}
+
+// DeleteConsumer 删除消费者及其待确认条目 返回删除的待确认条目数
+func (g *ConsumerGroup) DeleteConsumer(name string) (int, bool) {
+	c, ok := g.consumers[name]
+	if !ok {
+		return 0, false
+	}
+	n := c.PEL.Len()
+	c.PEL.Ascend(nil, func(k []byte, _ *PendingEntry) bool {
+		g.PEL.Remove(k)
+		return true
+	})
+	delete(g.consumers, name)
+	return n, true
+}
+
+// Consumers 按名称排序的消费者
+func (g *ConsumerGroup) Consumers() []*Consumer {
+	res := make([]*Consumer, 0, len(g.consumers))
+	for _, c := range g.consumers {
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// ReadNew 投递 LastID 之后的新条目 (XREADGROUP >) noAck 时不加入 PEL
+func (g *ConsumerGroup) ReadNew(s *Stream, c *Consumer, count int, noAck bool, now int64) []StreamEntry {
+	from, ok := g.LastID.Next()
+	if !ok || s.LastID.Less(from) {
+		return nil
+	}
+	entries := s.Range(from, MaxStreamID, false, count)
+	if len(entries) == 0 {
+		return nil
+	}
+	// 中间有被删除的条目时 entries-read 不能简单累加
+	if g.EntriesRead >= 0 && !(g.LastID.Less(s.MaxDeletedID) && s.FirstID().Less(s.MaxDeletedID)) {
+		g.EntriesRead += int64(len(entries))
+	} else {
+		g.EntriesRead = s.EntriesReadAt(entries[len(entries)-1].ID)
+	}
+	g.LastID = entries[len(entries)-1].ID
+	c.ActiveTime = now
+	if noAck {
+		return entries
+	}
+	for _, e := range entries {
+		g.Assign(e.ID, c, now, 1)
+	}
+	return entries
+}
+
+// ReadPending 重新投递消费者自己 PEL 中 ID 大于 after 的条目 (XREADGROUP 指定 ID)
+// 已被删除的条目 Fields 为 nil
+func (g *ConsumerGroup) ReadPending(s *Stream, c *Consumer, after StreamID, count int, now int64) []StreamEntry {
+	res := []StreamEntry{}
+	from, ok := after.Next()
+	if !ok {
+		return res
+	}
+	c.PEL.Ascend(from.Key(), func(_ []byte, pe *PendingEntry) bool {
+		e, ok := s.Get(pe.ID)
+		if !ok {
+			e = StreamEntry{ID: pe.ID}
+		}
+		pe.DeliveryTime = now
+		pe.DeliveryCount++
+		res = append(res, e)
+		return count <= 0 || len(res) < count
+	})
+	return res
+}
+
+// Assign 把条目分配给消费者 (新投递/认领/RDB 加载) count 为投递次数
+func (g *ConsumerGroup) Assign(id StreamID, c *Consumer, now int64, count uint64) *PendingEntry {
+	key := id.Key()
+	pe, ok := g.PEL.Get(key)
+	if ok {
+		pe.Consumer.PEL.Remove(key)
+	} else {
+		pe = &PendingEntry{ID: id}
+		g.PEL.Insert(key, pe)
+	}
+	pe.Consumer = c
+	pe.DeliveryTime = now
+	pe.DeliveryCount = count
+	c.PEL.Insert(key, pe)
+	return pe
+}
+
+// Ack 确认条目 从 PEL 中删除
+func (g *ConsumerGroup) Ack(id StreamID) bool {
+	key := id.Key()
+	pe, ok := g.PEL.Get(key)
+	if !ok {
+		return false
+	}
+	g.PEL.Remove(key)
+	pe.Consumer.PEL.Remove(key)
+	return true
+}
+
+// Pending 待确认条目
+func (g *ConsumerGroup) Pending(id StreamID) (*PendingEntry, bool) {
+	return g.PEL.Get(id.Key())
+}
+
+// Claim 把待确认条目转给消费者 (XCLAIM/XAUTOCLAIM)
+// force 时不在 PEL 中的条目也会被创建; justID 时不增加投递次数
+func (g *ConsumerGroup) Claim(id StreamID, c *Consumer, now int64, force, justID bool) *PendingEntry {
+	pe, ok := g.Pending(id)
+	if !ok && !force {
+		return nil
+	}
+	count := uint64(0)
+	if ok {
+		count = pe.DeliveryCount
+	}
+	if !justID {
+		count++
+	}
+	c.ActiveTime = now
+	return g.Assign(id, c, now, count)
+}
@@ -0,0 +1,138 @@
+package kvstore
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// 随机插入删除后 前缀树的有序遍历/Floor 与排序后的键一致
+func TestRax(t *testing.T) {
+	r := NewRax[int]()
+	ref := make(map[string]int)
+	for i := 0; i < 3000; i++ {
+		k := strconv.Itoa(rand.Intn(800))
+		if rand.Intn(3) == 0 {
+			_, exists := ref[k]
+			assert.Equal(t, exists, r.Remove([]byte(k)))
+			delete(ref, k)
+			continue
+		}
+		_, exists := ref[k]
+		assert.Equal(t, !exists, r.Insert([]byte(k), i))
+		ref[k] = i
+	}
+	keys := make([]string, 0, len(ref))
+	for k := range ref {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	assert.Equal(t, len(keys), r.Len())
+
+	var got []string
+	r.Ascend(nil, func(k []byte, v int) bool {
+		assert.Equal(t, ref[string(k)], v)
+		got = append(got, string(k))
+		return true
+	})
+	assert.Equal(t, keys, got)
+
+	// 从不存在的键开始遍历
+	from := []byte("45")
+	i := sort.SearchStrings(keys, string(from))
+	got = nil
+	r.Ascend(from, func(k []byte, _ int) bool {
+		got = append(got, string(k))
+		return true
+	})
+	assert.Equal(t, keys[i:], got)
+	got = nil
+	r.Descend(from, func(k []byte, _ int) bool {
+		got = append(got, string(k))
+		return true
+	})
+	for j := 0; j < len(got); j++ {
+		assert.Equal(t, true, bytes.Compare([]byte(got[j]), from) <= 0)
+	}
+	assert.Equal(t, len(keys[:sort.SearchStrings(keys, "45\x00")]), len(got))
+
+	k, _, ok := r.Floor([]byte("999"))
+	assert.Equal(t, true, ok)
+	assert.Equal(t, keys[len(keys)-1], string(k))
+}
+
+func TestStreamRangeAndTrim(t *testing.T) {
+	s := NewStream()
+	for i := 1; i <= 250; i++ {
+		s.Append(StreamID{Ms: uint64(i)}, []string{"n", strconv.Itoa(i)})
+	}
+	keys, _ := s.Nodes()
+	assert.Equal(t, 3, keys)
+
+	res := s.Range(StreamID{Ms: 99}, StreamID{Ms: 102}, false, 0)
+	assert.Equal(t, 4, len(res))
+	assert.Equal(t, StreamID{Ms: 99}, res[0].ID)
+	res = s.Range(StreamID{Ms: 99}, StreamID{Ms: 102}, true, 2)
+	assert.Equal(t, []StreamID{{Ms: 102}, {Ms: 101}}, []StreamID{res[0].ID, res[1].ID})
+
+	assert.Equal(t, true, s.Delete(StreamID{Ms: 100}))
+	assert.Equal(t, false, s.Delete(StreamID{Ms: 100}))
+	_, ok := s.Get(StreamID{Ms: 100})
+	assert.Equal(t, false, ok)
+	assert.Equal(t, StreamID{Ms: 100}, s.MaxDeletedID)
+	assert.Equal(t, uint64(249), s.Len())
+
+	// 近似裁剪只删除整个节点
+	assert.Equal(t, int64(99), s.TrimMaxLen(120, true, 0))
+	assert.Equal(t, StreamID{Ms: 101}, s.FirstID())
+	assert.Equal(t, int64(30), s.TrimMaxLen(120, false, 0))
+	assert.Equal(t, uint64(120), s.Len())
+	assert.Equal(t, int64(9), s.TrimMinID(StreamID{Ms: 140}, false, 0))
+	assert.Equal(t, StreamID{Ms: 140}, s.FirstID())
+	assert.Equal(t, uint64(250), s.EntriesAdded)
+}
+
+func TestConsumerGroup(t *testing.T) {
+	s := NewStream()
+	for i := 1; i <= 5; i++ {
+		s.Append(StreamID{Ms: uint64(i)}, []string{"n", strconv.Itoa(i)})
+	}
+	g := s.CreateGroup("g", StreamID{}, 0)
+	assert.Equal(t, true, s.CreateGroup("g", StreamID{}, 0) == nil)
+	alice, created := g.Consumer("alice", true, 10)
+	assert.Equal(t, true, created)
+	bob, _ := g.Consumer("bob", true, 10)
+
+	assert.Equal(t, 2, len(g.ReadNew(s, alice, 2, false, 20)))
+	assert.Equal(t, 3, len(g.ReadNew(s, bob, 0, false, 20)))
+	assert.Equal(t, 0, len(g.ReadNew(s, bob, 0, false, 20)))
+	assert.Equal(t, int64(5), g.EntriesRead)
+	assert.Equal(t, int64(0), s.Lag(g))
+	assert.Equal(t, 5, g.PEL.Len())
+
+	// 重新投递自己的待确认条目 被删除的条目字段为 nil
+	s.Delete(StreamID{Ms: 2})
+	res := g.ReadPending(s, alice, StreamID{}, 0, 30)
+	assert.Equal(t, 2, len(res))
+	assert.Equal(t, true, res[1].Fields == nil)
+	pe, _ := g.Pending(StreamID{Ms: 1})
+	assert.Equal(t, uint64(2), pe.DeliveryCount)
+
+	pe = g.Claim(StreamID{Ms: 3}, alice, 40, false, false)
+	assert.Equal(t, alice, pe.Consumer)
+	assert.Equal(t, uint64(2), pe.DeliveryCount)
+	assert.Equal(t, 3, alice.PEL.Len())
+	assert.Equal(t, 2, bob.PEL.Len())
+	assert.Equal(t, true, g.Claim(StreamID{Ms: 9}, alice, 40, false, false) == nil)
+
+	assert.Equal(t, true, g.Ack(StreamID{Ms: 1}))
+	assert.Equal(t, false, g.Ack(StreamID{Ms: 1}))
+	n, ok := g.DeleteConsumer("bob")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 2, g.PEL.Len())
+}
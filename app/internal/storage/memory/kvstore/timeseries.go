@@ -0,0 +1,420 @@
+package kvstore
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// TimeSeries 时间序列 (TS.* 命令, 与 RedisTimeSeries 兼容)
+// 样本按时间戳递增保存在压缩块中, 块的数据达到 ChunkSize 字节后新开一块;
+// 早于最后一个样本的写入解压所在的块, 修改后重新编码
+// Retention 不为 0 时只保留最后一个样本之前 Retention 毫秒内的样本 (整块删除, 查询时过滤)
+type TimeSeries struct {
+	Retention int64
+	ChunkSize uint64
+	Duplicate TSDuplicatePolicy
+	Labels    []TSLabel
+	Rules     []TSRule
+	SrcKey    string // 作为压缩规则目标时的源序列
+	Chunks    []*TSChunk
+	Total     uint64
+}
+
+type TSSample struct {
+	Timestamp int64
+	Value     float64
+}
+
+type TSLabel struct {
+	Name, Value string
+}
+
+// TSRule 压缩规则: 源序列的样本按 [Align+k*Bucket, Align+(k+1)*Bucket) 分桶聚合后写入 DestKey
+// 只聚合时间戳不小于 Since 的样本 (创建规则之后写入的样本)
+type TSRule struct {
+	DestKey string
+	Agg     TSAggregator
+	Bucket  int64
+	Align   int64
+	Since   int64
+}
+
+const TSDefaultChunkSize = 4096
+
+var (
+	ErrTSDuplicate = errors.New("ERR TSDB: Error at upsert, update is not supported when DUPLICATE_POLICY is set to BLOCK mode")
+	ErrTSTooOld    = errors.New("ERR TSDB: Timestamp is older than retention")
+)
+
+// TSDuplicatePolicy 写入已有时间戳时的处理方式
+type TSDuplicatePolicy uint8
+
+const (
+	TSDupBlock TSDuplicatePolicy = iota
+	TSDupFirst
+	TSDupLast
+	TSDupMin
+	TSDupMax
+	TSDupSum
+)
+
+var tsDuplicateNames = []string{"block", "first", "last", "min", "max", "sum"}
+
+func (p TSDuplicatePolicy) String() string {
+	return tsDuplicateNames[p]
+}
+
+func ParseTSDuplicatePolicy(s string) (TSDuplicatePolicy, bool) {
+	i := slices.Index(tsDuplicateNames, strings.ToLower(s))
+	return TSDuplicatePolicy(i), i >= 0
+}
+
+func (p TSDuplicatePolicy) resolve(old, v float64) (float64, error) {
+	switch p {
+	case TSDupFirst:
+		return old, nil
+	case TSDupLast:
+		return v, nil
+	case TSDupMin:
+		return math.Min(old, v), nil
+	case TSDupMax:
+		return math.Max(old, v), nil
+	case TSDupSum:
+		return old + v, nil
+	}
+	return 0, ErrTSDuplicate
+}
+
+// TSAggregator 分桶聚合函数
+type TSAggregator uint8
+
+const (
+	TSAggAvg TSAggregator = iota
+	TSAggSum
+	TSAggMin
+	TSAggMax
+	TSAggRange
+	TSAggCount
+	TSAggFirst
+	TSAggLast
+)
+
+var tsAggregatorNames = []string{"avg", "sum", "min", "max", "range", "count", "first", "last"}
+
+func (a TSAggregator) String() string {
+	return tsAggregatorNames[a]
+}
+
+func ParseTSAggregator(s string) (TSAggregator, bool) {
+	i := slices.Index(tsAggregatorNames, strings.ToLower(s))
+	return TSAggregator(i), i >= 0
+}
+
+func NewTimeSeries() *TimeSeries {
+	return &TimeSeries{ChunkSize: TSDefaultChunkSize}
+}
+
+// Last 最后一个样本
+func (ts *TimeSeries) Last() (TSSample, bool) {
+	if len(ts.Chunks) == 0 {
+		return TSSample{}, false
+	}
+	return ts.Chunks[len(ts.Chunks)-1].last(), true
+}
+
+// First 最早的样本时间戳 没有样本时为 0
+func (ts *TimeSeries) First() int64 {
+	if len(ts.Chunks) == 0 {
+		return 0
+	}
+	return ts.Chunks[0].Start
+}
+
+// Label 标签的值 没有该标签时为 ""
+func (ts *TimeSeries) Label(name string) string {
+	for _, l := range ts.Labels {
+		if l.Name == name {
+			return l.Value
+		}
+	}
+	return ""
+}
+
+// cutoff 保留期的起点
+func (ts *TimeSeries) cutoff() int64 {
+	last, ok := ts.Last()
+	if ts.Retention == 0 || !ok {
+		return math.MinInt64
+	}
+	return last.Timestamp - ts.Retention
+}
+
+// Add 写入样本 时间戳已存在时按 policy 合并
+func (ts *TimeSeries) Add(t int64, v float64, policy TSDuplicatePolicy) error {
+	if t < ts.cutoff() {
+		return ErrTSTooOld
+	}
+	n := len(ts.Chunks)
+	if n == 0 || t > ts.Chunks[n-1].End {
+		if n == 0 || uint64(len(ts.Chunks[n-1].Data)) >= ts.ChunkSize {
+			ts.Chunks = append(ts.Chunks, newTSChunk())
+		}
+		ts.Chunks[len(ts.Chunks)-1].append(TSSample{Timestamp: t, Value: v})
+		ts.Total++
+		ts.trim()
+		return nil
+	}
+	// 第一个结束时间不早于 t 的块 插在块头部时仍晚于上一块
+	i := sort.Search(n, func(i int) bool { return ts.Chunks[i].End >= t })
+	samples := ts.Chunks[i].Samples()
+	j := sort.Search(len(samples), func(j int) bool { return samples[j].Timestamp >= t })
+	if j < len(samples) && samples[j].Timestamp == t {
+		nv, err := policy.resolve(samples[j].Value, v)
+		if err != nil {
+			return err
+		}
+		samples[j].Value = nv
+	} else {
+		samples = slices.Insert(samples, j, TSSample{Timestamp: t, Value: v})
+		ts.Total++
+	}
+	ts.Chunks = slices.Replace(ts.Chunks, i, i+1, ts.encode(samples)...)
+	return nil
+}
+
+// encode 把有序样本编码为若干块
+func (ts *TimeSeries) encode(samples []TSSample) []*TSChunk {
+	var chunks []*TSChunk
+	for _, s := range samples {
+		if len(chunks) == 0 || uint64(len(chunks[len(chunks)-1].Data)) >= ts.ChunkSize {
+			chunks = append(chunks, newTSChunk())
+		}
+		chunks[len(chunks)-1].append(s)
+	}
+	return chunks
+}
+
+// trim 删除整块都在保留期之前的块
+func (ts *TimeSeries) trim() {
+	cutoff := ts.cutoff()
+	i := 0
+	for i < len(ts.Chunks)-1 && ts.Chunks[i].End < cutoff {
+		ts.Total -= ts.Chunks[i].Count
+		i++
+	}
+	ts.Chunks = ts.Chunks[i:]
+}
+
+// Range [from, to] 内的样本 (不含保留期之前的)
+func (ts *TimeSeries) Range(from, to int64) []TSSample {
+	from = max(from, ts.cutoff())
+	var res []TSSample
+	for _, c := range ts.Chunks {
+		if c.End < from || c.Start > to {
+			continue
+		}
+		for _, s := range c.Samples() {
+			if s.Timestamp >= from && s.Timestamp <= to {
+				res = append(res, s)
+			}
+		}
+	}
+	return res
+}
+
+// Delete 删除 [from, to] 内的样本 返回删除的个数
+func (ts *TimeSeries) Delete(from, to int64) uint64 {
+	var deleted uint64
+	chunks := make([]*TSChunk, 0, len(ts.Chunks))
+	for _, c := range ts.Chunks {
+		if c.End < from || c.Start > to {
+			chunks = append(chunks, c)
+			continue
+		}
+		samples := slices.DeleteFunc(c.Samples(), func(s TSSample) bool {
+			return s.Timestamp >= from && s.Timestamp <= to
+		})
+		deleted += c.Count - uint64(len(samples))
+		chunks = append(chunks, ts.encode(samples)...)
+	}
+	ts.Chunks = chunks
+	ts.Total -= deleted
+	return deleted
+}
+
+// MemoryUsage 压缩数据占用的字节数
+func (ts *TimeSeries) MemoryUsage() uint64 {
+	var n uint64
+	for _, c := range ts.Chunks {
+		n += uint64(cap(c.Data))
+	}
+	return n
+}
+
+// RemoveRule 删除到 dest 的压缩规则
+func (ts *TimeSeries) RemoveRule(dest string) bool {
+	n := len(ts.Rules)
+	ts.Rules = slices.DeleteFunc(ts.Rules, func(r TSRule) bool { return r.DestKey == dest })
+	return len(ts.Rules) < n
+}
+
+// TSBucketStart t 所在桶的起点
+func TSBucketStart(t, bucket, align int64) int64 {
+	r := (t - align) % bucket
+	if r < 0 {
+		r += bucket
+	}
+	return t - r
+}
+
+// TSAggregate 有序样本按桶聚合 每个非空桶一个结果, 时间戳为桶的起点
+func TSAggregate(samples []TSSample, agg TSAggregator, bucket, align int64) []TSSample {
+	var res []TSSample
+	var acc tsAccumulator
+	start := int64(0)
+	for i, s := range samples {
+		b := TSBucketStart(s.Timestamp, bucket, align)
+		if i > 0 && b != start {
+			res = append(res, TSSample{Timestamp: start, Value: acc.value(agg)})
+			acc = tsAccumulator{}
+		}
+		start = b
+		acc.add(s.Value)
+	}
+	if len(samples) > 0 {
+		res = append(res, TSSample{Timestamp: start, Value: acc.value(agg)})
+	}
+	return res
+}
+
+type tsAccumulator struct {
+	n                          uint64
+	sum, min, max, first, last float64
+}
+
+func (a *tsAccumulator) add(v float64) {
+	if a.n == 0 {
+		a.min, a.max, a.first = v, v, v
+	}
+	a.n++
+	a.sum += v
+	a.min, a.max, a.last = math.Min(a.min, v), math.Max(a.max, v), v
+}
+
+func (a *tsAccumulator) value(agg TSAggregator) float64 {
+	switch agg {
+	case TSAggAvg:
+		return a.sum / float64(a.n)
+	case TSAggSum:
+		return a.sum
+	case TSAggMin:
+		return a.min
+	case TSAggMax:
+		return a.max
+	case TSAggRange:
+		return a.max - a.min
+	case TSAggCount:
+		return float64(a.n)
+	case TSAggFirst:
+		return a.first
+	}
+	return a.last
+}
+
+// TSMatcher 标签过滤条件 label=value / label!=value / label=(v1,v2) / label!=(v1,v2)
+// 没有该标签按空值处理: label= 匹配没有该标签的序列, label!= 匹配有该标签的序列
+type TSMatcher struct {
+	Label  string
+	Values []string
+	Negate bool
+}
+
+func ParseTSMatcher(s string) (TSMatcher, bool) {
+	i := strings.IndexByte(s, '=')
+	if i <= 0 {
+		return TSMatcher{}, false
+	}
+	m := TSMatcher{Label: s[:i], Values: []string{s[i+1:]}}
+	if strings.HasSuffix(m.Label, "!") {
+		m.Label, m.Negate = m.Label[:len(m.Label)-1], true
+	}
+	// 列表形式 (v1,v2): 括号必须成对, 各值去掉两侧空白与引号
+	if v := m.Values[0]; strings.HasPrefix(v, "(") {
+		if len(v) < 2 || v[len(v)-1] != ')' {
+			return TSMatcher{}, false
+		}
+		m.Values = strings.Split(v[1:len(v)-1], ",")
+		for i, val := range m.Values {
+			m.Values[i] = unquoteTSValue(strings.TrimSpace(val))
+		}
+	}
+	return m, m.Label != ""
+}
+
+func unquoteTSValue(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+// Positive 是否要求标签等于某个非空值 (过滤条件中至少要有一个)
+func (m TSMatcher) Positive() bool {
+	return !m.Negate && slices.ContainsFunc(m.Values, func(v string) bool { return v != "" })
+}
+
+func (m TSMatcher) Match(ts *TimeSeries) bool {
+	return slices.Contains(m.Values, ts.Label(m.Label)) != m.Negate
+}
+
+func NewTimeSeriesObject(ts *TimeSeries) *Object {
+	return NewObject(TypeTimeSeries, EncRaw, ts)
+}
+
+// TimeSeries 读取时间序列 键不存在时返回 nil, 类型不符时返回 WRONGTYPE
+func (tx *Tx) TimeSeries(key string) (*TimeSeries, error) {
+	o, ok := tx.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if o.Type != TypeTimeSeries {
+		return nil, errors_r.ErrWrongType
+	}
+	return o.Value.(*TimeSeries), nil
+}
+
+// TSAdd 写入样本并更新压缩规则的目标序列
+// 样本落入更晚的桶时上一个桶已经完整, 由源序列重新聚合后写入目标;
+// 写入已完成的旧桶时重新聚合该桶并覆盖目标中的值. 目标不存在时跳过
+func (tx *Tx) TSAdd(ts *TimeSeries, t int64, v float64, policy TSDuplicatePolicy) error {
+	last, hasLast := ts.Last()
+	if err := ts.Add(t, v, policy); err != nil {
+		return err
+	}
+	if !hasLast {
+		return nil
+	}
+	for _, r := range ts.Rules {
+		cur := TSBucketStart(t, r.Bucket, r.Align)
+		prev := TSBucketStart(last.Timestamp, r.Bucket, r.Align)
+		if cur != prev {
+			tx.tsCompact(ts, r, min(cur, prev))
+		}
+	}
+	return nil
+}
+
+func (tx *Tx) tsCompact(ts *TimeSeries, r TSRule, start int64) {
+	dst, err := tx.TimeSeries(r.DestKey)
+	if err != nil || dst == nil {
+		return
+	}
+	for _, s := range TSAggregate(ts.Range(max(start, r.Since), start+r.Bucket-1), r.Agg, r.Bucket, r.Align) {
+		tx.TSAdd(dst, s.Timestamp, s.Value, TSDupLast)
+	}
+}
@@ -0,0 +1,106 @@
+package kvstore
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestTimeSeriesAdd(t *testing.T) {
+	ts := NewTimeSeries()
+	ts.ChunkSize = 48
+	for i := int64(1); i <= 100; i++ {
+		assert.Equal(t, nil, ts.Add(i*10, float64(i), TSDupBlock))
+	}
+	assert.Equal(t, uint64(100), ts.Total)
+	assert.Equal(t, true, len(ts.Chunks) > 1)
+
+	// 写入中间的时间戳与重复的时间戳
+	assert.Equal(t, nil, ts.Add(15, 0.5, TSDupBlock))
+	assert.Equal(t, ErrTSDuplicate, ts.Add(20, 9, TSDupBlock))
+	assert.Equal(t, nil, ts.Add(20, 9, TSDupSum))
+	assert.Equal(t, nil, ts.Add(30, 9, TSDupFirst))
+	assert.Equal(t, nil, ts.Add(40, 1, TSDupMin))
+	assert.Equal(t, uint64(101), ts.Total)
+	assert.Equal(t, []TSSample{{10, 1}, {15, 0.5}, {20, 11}, {30, 3}, {40, 1}}, ts.Range(0, 40))
+
+	var n uint64
+	for _, c := range ts.Chunks {
+		n += c.Count
+	}
+	assert.Equal(t, ts.Total, n)
+
+	assert.Equal(t, uint64(3), ts.Delete(15, 30))
+	assert.Equal(t, []TSSample{{10, 1}, {40, 1}, {50, 5}}, ts.Range(0, 50))
+	assert.Equal(t, uint64(98), ts.Total)
+}
+
+func TestTimeSeriesRetention(t *testing.T) {
+	ts := NewTimeSeries()
+	ts.ChunkSize = 48
+	ts.Retention = 100
+	for i := int64(1); i <= 100; i++ {
+		ts.Add(i*10, float64(i), TSDupBlock)
+	}
+	assert.Equal(t, ErrTSTooOld, ts.Add(10, 1, TSDupLast))
+	samples := ts.Range(0, 10000)
+	assert.Equal(t, 11, len(samples))
+	assert.Equal(t, int64(900), samples[0].Timestamp)
+	assert.Equal(t, true, ts.Total < 100)
+}
+
+func TestTSAggregate(t *testing.T) {
+	samples := []TSSample{{1, 1}, {5, 3}, {10, 2}, {19, 8}, {35, 4}}
+	assert.Equal(t, []TSSample{{0, 2}, {10, 5}, {30, 4}}, TSAggregate(samples, TSAggAvg, 10, 0))
+	assert.Equal(t, []TSSample{{0, 2}, {10, 2}, {30, 1}}, TSAggregate(samples, TSAggCount, 10, 0))
+	assert.Equal(t, []TSSample{{-5, 1}, {5, 5}, {15, 8}, {35, 4}}, TSAggregate(samples, TSAggSum, 10, 5))
+	assert.Equal(t, []TSSample{{0, 7}, {20, 0}}, TSAggregate(samples, TSAggRange, 20, 0))
+	assert.Equal(t, int64(-10), TSBucketStart(-1, 10, 0))
+}
+
+func TestTSCompaction(t *testing.T) {
+	s := NewStore()
+	s.Update(func(tx *Tx) error {
+		src, dst := NewTimeSeries(), NewTimeSeries()
+		src.Rules = []TSRule{{DestKey: "dst", Agg: TSAggMax, Bucket: 10}}
+		dst.SrcKey = "src"
+		tx.Add("src", NewTimeSeriesObject(src))
+		tx.Add("dst", NewTimeSeriesObject(dst))
+		for _, v := range []TSSample{{1, 1}, {3, 5}, {12, 2}, {25, 7}} {
+			tx.TSAdd(src, v.Timestamp, v.Value, TSDupBlock)
+		}
+		assert.Equal(t, []TSSample{{0, 5}, {10, 2}}, dst.Range(0, 100))
+		// 写入已完成的桶时重新聚合
+		tx.TSAdd(src, 15, 9, TSDupBlock)
+		assert.Equal(t, []TSSample{{0, 5}, {10, 9}}, dst.Range(0, 100))
+		return nil
+	})
+}
+
+func TestTSMatcher(t *testing.T) {
+	ts := NewTimeSeries()
+	ts.Labels = []TSLabel{{"area", "east"}, {"kind", "temp"}}
+	for s, want := range map[string]bool{
+		"area=east":         true,
+		"area!=east":        false,
+		"area=(west,east)":  true,
+		"area!=(west,n)":    true,
+		"host=":             true,
+		"host!=":            false,
+		"kind!=":            true,
+		"area=(west, east)": true,
+		"area=(\"east\")":   true,
+		"area!=('east',n)":  false,
+		"area=()":           false,
+	} {
+		m, ok := ParseTSMatcher(s)
+		assert.Equal(t, true, ok)
+		assert.Equal(t, want, m.Match(ts))
+	}
+	m, _ := ParseTSMatcher("host=")
+	assert.Equal(t, false, m.Positive())
+	for _, s := range []string{"area", "=east", "!=east", "area=(east,west", "area=("} {
+		_, ok := ParseTSMatcher(s)
+		assert.Equal(t, false, ok)
+	}
+}
@@ -0,0 +1,176 @@
+package kvstore
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// TopK HeavyKeeper 算法跟踪出现次数最多的 K 个元素
+// Depth 行 Width 列的桶记录 (指纹, 计数); 元素落在已被其他指纹占用的桶时, 以 Decay^计数 的概率让计数减一,
+// 减到 0 时接管该桶. 各行中本元素的最大计数不小于堆中的最小计数时进入 (或更新) 大小为 K 的最小堆
+// 衰减使用保存在值中的伪随机数状态 副本执行相同的命令得到相同的结果
+type TopK struct {
+	K, Width, Depth uint64
+	Decay           float64
+	Buckets         []TopKBucket
+	Heap            []TopKItem
+	Seed            uint64
+}
+
+type TopKBucket struct {
+	Fingerprint uint32
+	Count       uint32
+}
+
+type TopKItem struct {
+	Item  string
+	Count uint32
+}
+
+const (
+	TopKDefaultWidth = 8
+	TopKDefaultDepth = 7
+	TopKDefaultDecay = 0.9
+
+	topkSeed = 0x9e3779b97f4a7c15
+)
+
+var ErrTopKSize = errors.New("TopK: invalid k, width or depth")
+
+func NewTopK(k, width, depth uint64, decay float64) (*TopK, error) {
+	if k == 0 || width == 0 || depth == 0 || width > cmsMaxCounters/depth || k > cmsMaxCounters {
+		return nil, ErrTopKSize
+	}
+	return &TopK{K: k, Width: width, Depth: depth, Decay: decay, Buckets: make([]TopKBucket, width*depth), Seed: topkSeed}, nil
+}
+
+func topkFingerprint(item string) uint32 {
+	return uint32(murmurHash64A(item, 1919))
+}
+
+// random xorshift64* 返回 [0, 1) 的浮点数
+func (t *TopK) random() float64 {
+	t.Seed ^= t.Seed >> 12
+	t.Seed ^= t.Seed << 25
+	t.Seed ^= t.Seed >> 27
+	return float64((t.Seed*0x2545f4914f6cdd1d)>>11) / (1 << 53)
+}
+
+// Add 增加元素的计数 返回被挤出堆的元素
+func (t *TopK) Add(item string, incr uint32) (string, bool) {
+	fp := topkFingerprint(item)
+	var maxCount uint32
+	for row := uint64(0); row < t.Depth; row++ {
+		b := &t.Buckets[row*t.Width+murmurHash64A(item, row)%t.Width]
+		switch {
+		case b.Count == 0:
+			b.Fingerprint, b.Count = fp, incr
+		case b.Fingerprint == fp:
+			b.Count += min(incr, math.MaxUint32-b.Count)
+		default:
+			for n := incr; n > 0; n-- {
+				if t.random() < math.Pow(t.Decay, float64(min(b.Count, 255))) {
+					b.Count--
+					if b.Count == 0 {
+						b.Fingerprint, b.Count = fp, n
+						break
+					}
+				}
+			}
+		}
+		if b.Fingerprint == fp {
+			maxCount = max(maxCount, b.Count)
+		}
+	}
+	if maxCount == 0 {
+		return "", false
+	}
+	h := (*topkHeap)(&t.Heap)
+	for i := range t.Heap {
+		if t.Heap[i].Item == item {
+			t.Heap[i].Count = max(t.Heap[i].Count, maxCount)
+			heap.Fix(h, i)
+			return "", false
+		}
+	}
+	if uint64(len(t.Heap)) < t.K {
+		heap.Push(h, TopKItem{Item: item, Count: maxCount})
+		return "", false
+	}
+	if maxCount < t.Heap[0].Count {
+		return "", false
+	}
+	expelled := t.Heap[0].Item
+	t.Heap[0] = TopKItem{Item: item, Count: maxCount}
+	heap.Fix(h, 0)
+	return expelled, true
+}
+
+// Query 元素是否在前 K 个中
+func (t *TopK) Query(item string) bool {
+	for _, it := range t.Heap {
+		if it.Item == item {
+			return true
+		}
+	}
+	return false
+}
+
+// Count 元素的估计计数 (各行中指纹相同的桶的最大计数)
+func (t *TopK) Count(item string) uint32 {
+	fp := topkFingerprint(item)
+	var n uint32
+	for row := uint64(0); row < t.Depth; row++ {
+		b := t.Buckets[row*t.Width+murmurHash64A(item, row)%t.Width]
+		if b.Fingerprint == fp {
+			n = max(n, b.Count)
+		}
+	}
+	return n
+}
+
+// List 前 K 个元素 按计数从大到小
+func (t *TopK) List() []TopKItem {
+	items := append([]TopKItem(nil), t.Heap...)
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Item < items[j].Item
+	})
+	return items
+}
+
+// topkHeap 按计数的最小堆
+type topkHeap []TopKItem
+
+func (h topkHeap) Len() int           { return len(h) }
+func (h topkHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h topkHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *topkHeap) Push(x any)        { *h = append(*h, x.(TopKItem)) }
+func (h *topkHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func NewTopKObject(t *TopK) *Object {
+	return NewObject(TypeTopK, EncRaw, t)
+}
+
+// TopK 读取 top-k 键不存在时返回 nil, 类型不符时返回 WRONGTYPE
+func (tx *Tx) TopK(key string) (*TopK, error) {
+	o, ok := tx.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if o.Type != TypeTopK {
+		return nil, errors_r.ErrWrongType
+	}
+	return o.Value.(*TopK), nil
+}
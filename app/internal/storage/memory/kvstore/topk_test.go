@@ -0,0 +1,44 @@
+package kvstore
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestTopK(t *testing.T) {
+	tk, err := NewTopK(3, 50, 4, 0.9)
+	assert.Equal(t, nil, err)
+
+	// 前 3 个元素出现的次数远多于其他元素
+	for round := 0; round < 100; round++ {
+		tk.Add("a", 3)
+		tk.Add("b", 2)
+		tk.Add("c", 1)
+		tk.Add("x"+strconv.Itoa(round), 1)
+	}
+	items := tk.List()
+	assert.Equal(t, 3, len(items))
+	assert.Equal(t, "a", items[0].Item)
+	assert.Equal(t, "b", items[1].Item)
+	assert.Equal(t, "c", items[2].Item)
+	assert.Equal(t, true, tk.Query("a"))
+	assert.Equal(t, false, tk.Query("x1"))
+	assert.Equal(t, true, tk.Count("a") >= 250)
+
+	// 计数更大的元素把最小的挤出堆
+	expelled, ok := tk.Add("z", 1000)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "c", expelled)
+
+	// 相同的操作序列得到相同的结果
+	t1, _ := NewTopK(2, 4, 2, 0.9)
+	t2, _ := NewTopK(2, 4, 2, 0.9)
+	for i := 0; i < 200; i++ {
+		t1.Add(strconv.Itoa(i%17), uint32(i%5+1))
+		t2.Add(strconv.Itoa(i%17), uint32(i%5+1))
+	}
+	assert.Equal(t, t1.List(), t2.List())
+	assert.Equal(t, t1.Buckets, t2.Buckets)
+}
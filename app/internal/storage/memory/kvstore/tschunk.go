@@ -0,0 +1,202 @@
+package kvstore
+
+import (
+	"math"
+	"math/bits"
+)
+
+// TSChunk 时间序列的一个压缩块 (Gorilla 编码)
+// 第一个样本原样写入 64 位时间戳与 64 位值; 之后的时间戳写二阶差分:
+// 0 -> '0', [-64, 63] -> '10'+7 位, [-256, 255] -> '110'+9 位, [-2048, 2047] -> '1110'+12 位, 其余 '1111'+64 位
+// 值写与上一个值的异或: 0 -> '0'; 有效位落在上一个窗口内 -> '10'+窗口内的位;
+// 否则 '11'+5 位前导零+6 位有效位数 (64 记为 0)+有效位
+type TSChunk struct {
+	Start, End int64
+	Count      uint64
+	Data       []byte
+
+	nbits             uint64 // Data 中已写入的位数
+	prevTS, prevDelta int64
+	prevValue         uint64
+	leading, trailing uint8 // 异或窗口 leading 为 0xff 时还没有窗口
+}
+
+func newTSChunk() *TSChunk {
+	return &TSChunk{leading: 0xff}
+}
+
+func (c *TSChunk) writeBits(v uint64, n uint8) {
+	for i := int(n) - 1; i >= 0; i-- {
+		if c.nbits%8 == 0 {
+			c.Data = append(c.Data, 0)
+		}
+		if v>>uint(i)&1 == 1 {
+			c.Data[len(c.Data)-1] |= 0x80 >> (c.nbits % 8)
+		}
+		c.nbits++
+	}
+}
+
+// tsDoDClasses 二阶差分的前缀与位数
+var tsDoDClasses = []struct {
+	prefix     uint64
+	prefixBits uint8
+	bits       uint8
+}{
+	{0b10, 2, 7},
+	{0b110, 3, 9},
+	{0b1110, 4, 12},
+}
+
+func (c *TSChunk) writeDoD(d int64) {
+	if d == 0 {
+		c.writeBits(0, 1)
+		return
+	}
+	for _, cl := range tsDoDClasses {
+		if d >= -(1<<(cl.bits-1)) && d < 1<<(cl.bits-1) {
+			c.writeBits(cl.prefix, cl.prefixBits)
+			c.writeBits(uint64(d)&(1<<cl.bits-1), cl.bits)
+			return
+		}
+	}
+	c.writeBits(0b1111, 4)
+	c.writeBits(uint64(d), 64)
+}
+
+func (c *TSChunk) writeXOR(v uint64) {
+	x := v ^ c.prevValue
+	if x == 0 {
+		c.writeBits(0, 1)
+		return
+	}
+	lead, trail := uint8(min(bits.LeadingZeros64(x), 31)), uint8(bits.TrailingZeros64(x))
+	if c.leading != 0xff && lead >= c.leading && trail >= c.trailing {
+		c.writeBits(0b10, 2)
+		c.writeBits(x>>c.trailing, 64-c.leading-c.trailing)
+		return
+	}
+	c.leading, c.trailing = lead, trail
+	n := 64 - lead - trail
+	c.writeBits(0b11, 2)
+	c.writeBits(uint64(lead), 5)
+	c.writeBits(uint64(n&63), 6)
+	c.writeBits(x>>trail, n)
+}
+
+// append 追加样本 时间戳必须大于块中最后一个样本
+func (c *TSChunk) append(s TSSample) {
+	v := math.Float64bits(s.Value)
+	if c.Count == 0 {
+		c.Start = s.Timestamp
+		c.writeBits(uint64(s.Timestamp), 64)
+		c.writeBits(v, 64)
+	} else {
+		delta := s.Timestamp - c.prevTS
+		c.writeDoD(delta - c.prevDelta)
+		c.prevDelta = delta
+		c.writeXOR(v)
+	}
+	c.prevTS, c.prevValue = s.Timestamp, v
+	c.End = s.Timestamp
+	c.Count++
+}
+
+// last 块中最后一个样本
+func (c *TSChunk) last() TSSample {
+	return TSSample{Timestamp: c.prevTS, Value: math.Float64frombits(c.prevValue)}
+}
+
+// Samples 解压块中的所有样本
+func (c *TSChunk) Samples() []TSSample {
+	samples, _ := decodeTSChunk(c.Data, c.Count)
+	return samples
+}
+
+// tsBitReader 读到数据末尾之后返回 0 并记录错误
+type tsBitReader struct {
+	data []byte
+	pos  uint64
+	bad  bool
+}
+
+func (r *tsBitReader) read(n uint8) uint64 {
+	var v uint64
+	for i := uint8(0); i < n; i++ {
+		if r.pos/8 >= uint64(len(r.data)) {
+			r.bad = true
+			return 0
+		}
+		v = v<<1 | uint64(r.data[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+	return v
+}
+
+func signExtend(v uint64, n uint8) int64 {
+	return int64(v<<(64-n)) >> (64 - n)
+}
+
+// decodeTSChunk 解码 count 个样本 数据不完整或时间戳不递增时返回 false
+func decodeTSChunk(data []byte, count uint64) ([]TSSample, bool) {
+	r := &tsBitReader{data: data}
+	samples := make([]TSSample, 0, count)
+	var ts, delta int64
+	var v uint64
+	var leading, trailing uint8
+	for i := uint64(0); i < count; i++ {
+		if i == 0 {
+			ts, v = int64(r.read(64)), r.read(64)
+		} else {
+			var dod int64
+			switch {
+			case r.read(1) == 0:
+			case r.read(1) == 0:
+				dod = signExtend(r.read(7), 7)
+			case r.read(1) == 0:
+				dod = signExtend(r.read(9), 9)
+			case r.read(1) == 0:
+				dod = signExtend(r.read(12), 12)
+			default:
+				dod = int64(r.read(64))
+			}
+			delta += dod
+			if delta <= 0 {
+				return samples, false
+			}
+			ts += delta
+			if r.read(1) == 1 {
+				if r.read(1) == 1 {
+					leading = uint8(r.read(5))
+					n := uint8(r.read(6))
+					if n == 0 {
+						n = 64
+					}
+					if leading+n > 64 {
+						return samples, false
+					}
+					trailing = 64 - leading - n
+				}
+				v ^= r.read(64-leading-trailing) << trailing
+			}
+		}
+		if r.bad {
+			return samples, false
+		}
+		samples = append(samples, TSSample{Timestamp: ts, Value: math.Float64frombits(v)})
+	}
+	return samples, true
+}
+
+// LoadTSChunk 由压缩数据重建块 (RDB 加载) 数据损坏时返回 false
+func LoadTSChunk(data []byte, count uint64) (*TSChunk, bool) {
+	samples, ok := decodeTSChunk(data, count)
+	if !ok || count == 0 {
+		return nil, false
+	}
+	c := newTSChunk()
+	for _, s := range samples {
+		c.append(s)
+	}
+	return c, true
+}
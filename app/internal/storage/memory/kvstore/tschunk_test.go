@@ -0,0 +1,44 @@
+package kvstore
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestTSChunk(t *testing.T) {
+	samples := []TSSample{
+		{Timestamp: -5, Value: 1},
+		{Timestamp: 1000, Value: 1},
+		{Timestamp: 2000, Value: 1.5},
+		{Timestamp: 3000, Value: 1.25},
+		{Timestamp: 3001, Value: -7},
+		{Timestamp: 3200, Value: math.Inf(1)},
+		{Timestamp: 5000, Value: 0},
+		{Timestamp: math.MaxInt64 / 2, Value: 3.14159},
+	}
+	c := newTSChunk()
+	for _, s := range samples {
+		c.append(s)
+	}
+	assert.Equal(t, samples, c.Samples())
+	assert.Equal(t, int64(-5), c.Start)
+	assert.Equal(t, samples[len(samples)-1], c.last())
+
+	r, ok := LoadTSChunk(c.Data, c.Count)
+	assert.Equal(t, true, ok)
+	r.append(TSSample{Timestamp: math.MaxInt64/2 + 1, Value: 2})
+	c.append(TSSample{Timestamp: math.MaxInt64/2 + 1, Value: 2})
+	assert.Equal(t, c.Data, r.Data)
+
+	// 固定间隔且值不变时第三个样本起每个只占 2 位
+	c = newTSChunk()
+	for i := int64(0); i < 100; i++ {
+		c.append(TSSample{Timestamp: i * 10, Value: 42})
+	}
+	assert.Equal(t, (128+10+98*2+7)/8, len(c.Data))
+
+	_, ok = LoadTSChunk(c.Data[:10], c.Count)
+	assert.Equal(t, false, ok)
+}
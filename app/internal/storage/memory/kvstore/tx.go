@@ -0,0 +1,88 @@
+package kvstore
+
+import (
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// Tx Update 回调中访问数据集 调用期间持有 Store.Mu 写锁
+// 读-改-写类命令 (LPUSH/LPOP 等) 在一次 Update 内完成, 并发读取不会看到中间状态
+type Tx struct {
+	s *Store
+}
+
+// Update 在写锁内执行 fn
+func (s *Store) Update(fn func(tx *Tx) error) error {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	return fn(&Tx{s: s})
+}
+
+// Lookup 查找键 同时更新访问时钟
+func (tx *Tx) Lookup(key string) (*Object, bool) {
+	o, ok := tx.s.lookup(key)
+	if ok {
+		o.touch()
+	}
+	return o, ok
+}
+
+// Add 写入新键 (键不存在时调用) 不设置过期时间
+func (tx *Tx) Add(key string, o *Object) {
+	tx.s.Data[key] = o
+	delete(tx.s.Expires, key)
+	tx.s.track(key, o)
+}
+
+// Put 覆盖写入任意类型的值 ttl 为 0 时清除过期时间
+func (tx *Tx) Put(key string, o *Object, ttl time.Duration) {
+	tx.s.Data[key] = o
+	if ttl > 0 {
+		tx.s.Expires[key] = time.Now().Add(ttl)
+	} else {
+		delete(tx.s.Expires, key)
+	}
+	tx.s.track(key, o)
+}
+
+// Replace 以新对象替换已有键的值 保留过期时间
+func (tx *Tx) Replace(key string, o *Object) {
+	tx.s.Data[key] = o
+	tx.s.track(key, o)
+}
+
+func (tx *Tx) Delete(key string) {
+	delete(tx.s.Data, key)
+	delete(tx.s.Expires, key)
+}
+
+// ExpireAt 设置已有键的过期时间
+func (tx *Tx) ExpireAt(key string, at time.Time) {
+	tx.s.Expires[key] = at
+}
+
+// Expiry 键的过期时间 没有过期时间时返回 false
+func (tx *Tx) Expiry(key string) (time.Time, bool) {
+	at, ok := tx.s.Expires[key]
+	return at, ok
+}
+
+// Persist 清除键的过期时间 返回是否有过期时间
+func (tx *Tx) Persist(key string) bool {
+	_, ok := tx.s.Expires[key]
+	delete(tx.s.Expires, key)
+	return ok
+}
+
+// String 读取字符串对象 键不存在时返回 nil, 类型不符时返回 WRONGTYPE
+func (tx *Tx) String(key string) (*Object, error) {
+	o, ok := tx.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if o.Type != TypeString {
+		return nil, errors_r.ErrWrongType
+	}
+	return o, nil
+}
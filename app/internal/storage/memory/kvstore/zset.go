@@ -0,0 +1,533 @@
+package kvstore
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/codecrafters-io/redis-starter-go/app/pkg/errors_r"
+)
+
+// listpack 编码的上限 (zset-max-listpack-entries / zset-max-listpack-value)
+const (
+	zsetMaxListpackEntries = 128
+	zsetMaxListpackValue   = 64
+)
+
+// 跳表的层数上限与晋升概率 (1/4) 与 Redis 相同
+const (
+	zslMaxLevel = 32
+	zslP        = 4
+)
+
+// ZEntry 有序集合的成员与分值
+type ZEntry struct {
+	Member string
+	Score  float64
+}
+
+// ScoreRange 分值区间 (ZRANGEBYSCORE 等) Ex 为 true 表示开区间 "(1.5"
+type ScoreRange struct {
+	Min, Max     float64
+	MinEx, MaxEx bool
+}
+
+func (r ScoreRange) gteMin(score float64) bool {
+	if r.MinEx {
+		return score > r.Min
+	}
+	return score >= r.Min
+}
+
+func (r ScoreRange) lteMax(score float64) bool {
+	if r.MaxEx {
+		return score < r.Max
+	}
+	return score <= r.Max
+}
+
+func (r ScoreRange) empty() bool {
+	return r.Min > r.Max || (r.Min == r.Max && (r.MinEx || r.MaxEx))
+}
+
+// LexBound 字典序区间的端点 "[a" "(a" "-" "+"
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	Inf       int // -1 为 "-" (负无穷) 1 为 "+" (正无穷)
+}
+
+// cmp 端点与 s 比较
+func (b LexBound) cmp(s string) int {
+	switch {
+	case b.Inf != 0:
+		return b.Inf
+	case b.Value < s:
+		return -1
+	case b.Value > s:
+		return 1
+	}
+	return 0
+}
+
+// LexRange 字典序区间 (ZRANGEBYLEX 等) 仅在分值全部相同时有意义
+type LexRange struct {
+	Min, Max LexBound
+}
+
+func (r LexRange) gteMin(s string) bool {
+	if r.Min.Exclusive {
+		return r.Min.cmp(s) < 0
+	}
+	return r.Min.cmp(s) <= 0
+}
+
+func (r LexRange) lteMax(s string) bool {
+	if r.Max.Exclusive {
+		return r.Max.cmp(s) > 0
+	}
+	return r.Max.cmp(s) >= 0
+}
+
+func (r LexRange) empty() bool {
+	if r.Min.Inf == 1 || r.Max.Inf == -1 {
+		return true
+	}
+	if r.Min.Inf != 0 || r.Max.Inf != 0 {
+		return false
+	}
+	return r.Min.Value > r.Max.Value || (r.Min.Value == r.Max.Value && (r.Min.Exclusive || r.Max.Exclusive))
+}
+
+// zslNode 跳表节点 span 为到下一个节点跨过的元素数 用于计算排名
+type zslNode struct {
+	member   string
+	score    float64
+	backward *zslNode
+	level    []zslLevel
+}
+
+type zslLevel struct {
+	forward *zslNode
+	span    int
+}
+
+// skiplist 按 (score, member) 升序排列的跳表
+type skiplist struct {
+	header, tail *zslNode
+	length       int
+	level        int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{header: &zslNode{level: make([]zslLevel, zslMaxLevel)}, level: 1}
+}
+
+func zslRandomLevel() int {
+	level := 1
+	for level < zslMaxLevel && rand.Intn(zslP) == 0 {
+		level++
+	}
+	return level
+}
+
+// before 节点是否排在 (score, member) 之前
+func (x *zslNode) before(score float64, member string) bool {
+	return x.score < score || (x.score == score && x.member < member)
+}
+
+// insert 插入新元素 调用方保证 member 不在跳表中
+func (zsl *skiplist) insert(score float64, member string) {
+	var update [zslMaxLevel]*zslNode
+	var rank [zslMaxLevel]int
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		if i < zsl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	level := zslRandomLevel()
+	if level > zsl.level {
+		for i := zsl.level; i < level; i++ {
+			update[i] = zsl.header
+			update[i].level[i].span = zsl.length
+		}
+		zsl.level = level
+	}
+	x = &zslNode{member: member, score: score, level: make([]zslLevel, level)}
+	for i := 0; i < level; i++ {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = rank[0] - rank[i] + 1
+	}
+	for i := level; i < zsl.level; i++ {
+		update[i].level[i].span++
+	}
+	if update[0] != zsl.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		zsl.tail = x
+	}
+	zsl.length++
+}
+
+// delete 删除元素 不存在时返回 false
+func (zsl *skiplist) delete(score float64, member string) bool {
+	var update [zslMaxLevel]*zslNode
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	x = x.level[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+	for i := 0; i < zsl.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		zsl.tail = x.backward
+	}
+	for zsl.level > 1 && zsl.header.level[zsl.level-1].forward == nil {
+		zsl.level--
+	}
+	zsl.length--
+	return true
+}
+
+// rank 元素的排名 从 1 开始 不存在时返回 0
+func (zsl *skiplist) rank(score float64, member string) int {
+	rank := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for f := x.level[i].forward; f != nil && (f.before(score, member) || (f.score == score && f.member == member)); f = x.level[i].forward {
+			rank += x.level[i].span
+			x = f
+		}
+		if x != zsl.header && x.member == member {
+			return rank
+		}
+	}
+	return 0
+}
+
+// byRank 排名 (从 1 开始) 对应的节点
+func (zsl *skiplist) byRank(rank int) *zslNode {
+	traversed := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		if traversed == rank {
+			return x
+		}
+	}
+	return nil
+}
+
+// firstInRange 分值区间内的第一个节点 没有时返回 nil
+func (zsl *skiplist) firstInRange(r ScoreRange) *zslNode {
+	if r.empty() {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.gteMin(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+	}
+	x = x.level[0].forward
+	if x == nil || !r.lteMax(x.score) {
+		return nil
+	}
+	return x
+}
+
+// lastInRange 分值区间内的最后一个节点
+func (zsl *skiplist) lastInRange(r ScoreRange) *zslNode {
+	if r.empty() {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.lteMax(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+	}
+	if x == zsl.header || !r.gteMin(x.score) {
+		return nil
+	}
+	return x
+}
+
+func (zsl *skiplist) firstInLexRange(r LexRange) *zslNode {
+	if r.empty() {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.gteMin(x.level[i].forward.member) {
+			x = x.level[i].forward
+		}
+	}
+	x = x.level[0].forward
+	if x == nil || !r.lteMax(x.member) {
+		return nil
+	}
+	return x
+}
+
+func (zsl *skiplist) lastInLexRange(r LexRange) *zslNode {
+	if r.empty() {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.lteMax(x.level[i].forward.member) {
+			x = x.level[i].forward
+		}
+	}
+	if x == zsl.header || !r.gteMin(x.member) {
+		return nil
+	}
+	return x
+}
+
+// ZSet 有序集合 跳表按分值排序 字典按成员查找分值
+type ZSet struct {
+	dict map[string]float64
+	zsl  *skiplist
+}
+
+func NewZSet() *ZSet {
+	return &ZSet{dict: make(map[string]float64), zsl: newSkiplist()}
+}
+
+func (z *ZSet) Len() int {
+	return len(z.dict)
+}
+
+func (z *ZSet) Score(member string) (float64, bool) {
+	s, ok := z.dict[member]
+	return s, ok
+}
+
+// Set 写入成员的分值 返回是否为新成员
+func (z *ZSet) Set(member string, score float64) bool {
+	cur, exists := z.dict[member]
+	if exists {
+		if cur != score {
+			z.zsl.delete(cur, member)
+			z.zsl.insert(score, member)
+			z.dict[member] = score
+		}
+		return false
+	}
+	z.zsl.insert(score, member)
+	z.dict[member] = score
+	return true
+}
+
+func (z *ZSet) Remove(member string) bool {
+	score, ok := z.dict[member]
+	if !ok {
+		return false
+	}
+	z.zsl.delete(score, member)
+	delete(z.dict, member)
+	return true
+}
+
+// Rank 成员的排名 从 0 开始 rev 为 true 时按分值从大到小
+func (z *ZSet) Rank(member string, rev bool) (int, bool) {
+	score, ok := z.dict[member]
+	if !ok {
+		return 0, false
+	}
+	r := z.zsl.rank(score, member) - 1
+	if rev {
+		r = z.Len() - 1 - r
+	}
+	return r, true
+}
+
+// collect 从 x 开始沿 rev 方向收集元素 跳过 offset 个, 至多 limit 个 (limit < 0 不限), 直到 ok 返回 false
+func collect(x *zslNode, rev bool, offset, limit int, ok func(x *zslNode) bool) []ZEntry {
+	res := []ZEntry{}
+	next := func(x *zslNode) *zslNode {
+		if rev {
+			return x.backward
+		}
+		return x.level[0].forward
+	}
+	for ; x != nil && offset > 0 && ok(x); x = next(x) {
+		offset--
+	}
+	for ; x != nil && limit != 0 && ok(x); x = next(x) {
+		res = append(res, ZEntry{Member: x.member, Score: x.score})
+		limit--
+	}
+	return res
+}
+
+// Range 按排名返回 [start, stop] 的元素 下标可以为负数 (从末尾计)
+func (z *ZSet) Range(start, stop int, rev bool) []ZEntry {
+	n := z.Len()
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	start = max(start, 0)
+	stop = min(stop, n-1)
+	if start > stop {
+		return []ZEntry{}
+	}
+	var x *zslNode
+	if rev {
+		x = z.zsl.byRank(n - start)
+	} else {
+		x = z.zsl.byRank(start + 1)
+	}
+	return collect(x, rev, 0, stop-start+1, func(*zslNode) bool { return true })
+}
+
+// RangeByScore 分值区间内的元素 跳过 offset 个, 至多 limit 个 (limit < 0 不限)
+func (z *ZSet) RangeByScore(r ScoreRange, rev bool, offset, limit int) []ZEntry {
+	if rev {
+		return collect(z.zsl.lastInRange(r), true, offset, limit, func(x *zslNode) bool { return r.gteMin(x.score) })
+	}
+	return collect(z.zsl.firstInRange(r), false, offset, limit, func(x *zslNode) bool { return r.lteMax(x.score) })
+}
+
+// RangeByLex 字典序区间内的元素
+func (z *ZSet) RangeByLex(r LexRange, rev bool, offset, limit int) []ZEntry {
+	if rev {
+		return collect(z.zsl.lastInLexRange(r), true, offset, limit, func(x *zslNode) bool { return r.gteMin(x.member) })
+	}
+	return collect(z.zsl.firstInLexRange(r), false, offset, limit, func(x *zslNode) bool { return r.lteMax(x.member) })
+}
+
+// Count 分值区间内的元素个数 由首尾节点的排名计算
+func (z *ZSet) Count(r ScoreRange) int {
+	first := z.zsl.firstInRange(r)
+	if first == nil {
+		return 0
+	}
+	last := z.zsl.lastInRange(r)
+	return z.zsl.rank(last.score, last.member) - z.zsl.rank(first.score, first.member) + 1
+}
+
+// LexCount 字典序区间内的元素个数
+func (z *ZSet) LexCount(r LexRange) int {
+	first := z.zsl.firstInLexRange(r)
+	if first == nil {
+		return 0
+	}
+	last := z.zsl.lastInLexRange(r)
+	return z.zsl.rank(last.score, last.member) - z.zsl.rank(first.score, first.member) + 1
+}
+
+// Entries 全部元素 按分值升序
+func (z *ZSet) Entries() []ZEntry {
+	return z.Range(0, -1, false)
+}
+
+// Members 全部成员 按成员名排序 (ZSCAN 游标依赖稳定的顺序)
+func (z *ZSet) Members() []string {
+	members := make([]string, 0, len(z.dict))
+	for m := range z.dict {
+		members = append(members, m)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func NewZSetObject() *Object {
+	return NewObject(TypeZSet, EncListpack, NewZSet())
+}
+
+// NewZSetObjectFrom 由元素构造有序集合对象 (ZUNIONSTORE 等与 RDB 加载)
+func NewZSetObjectFrom(entries []ZEntry) *Object {
+	o := NewZSetObject()
+	z := o.Value.(*ZSet)
+	members := make([]string, len(entries))
+	for i, e := range entries {
+		z.Set(e.Member, e.Score)
+		members[i] = e.Member
+	}
+	o.Encoding = zsetEncoding(z, o.Encoding, members)
+	return o
+}
+
+// ZSet 读取有序集合 键不存在时返回 nil, 类型不符时返回 WRONGTYPE
+func (tx *Tx) ZSet(key string) (*ZSet, error) {
+	o, ok := tx.Lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if o.Type != TypeZSet {
+		return nil, errors_r.ErrWrongType
+	}
+	return o.Value.(*ZSet), nil
+}
+
+// ZSetOrCreate 读取有序集合 键不存在时创建
+func (tx *Tx) ZSetOrCreate(key string) (*ZSet, error) {
+	z, err := tx.ZSet(key)
+	if err != nil || z != nil {
+		return z, err
+	}
+	o := NewZSetObject()
+	tx.Add(key, o)
+	return o.Value.(*ZSet), nil
+}
+
+// ZSetChanged 有序集合修改后调用: 空集合删除键, 否则更新编码
+// added 为本次写入的成员
+func (tx *Tx) ZSetChanged(key string, added ...string) {
+	o, ok := tx.s.Data[key]
+	if !ok || o.Type != TypeZSet {
+		return
+	}
+	z := o.Value.(*ZSet)
+	if z.Len() == 0 {
+		tx.Delete(key)
+		return
+	}
+	o.Encoding = zsetEncoding(z, o.Encoding, added)
+}
+
+// zsetEncoding 小集合为 listpack, 超出上限后转换为 skiplist 不再转换回来
+func zsetEncoding(z *ZSet, enc Encoding, added []string) Encoding {
+	if enc == EncSkiplist || z.Len() > zsetMaxListpackEntries {
+		return EncSkiplist
+	}
+	for _, m := range added {
+		if len(m) > zsetMaxListpackValue {
+			return EncSkiplist
+		}
+	}
+	return EncListpack
+}
@@ -0,0 +1,76 @@
+package kvstore
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// 随机增删改后 跳表的顺序/排名/区间查询与排序后的切片一致
+func TestZSetSkiplist(t *testing.T) {
+	z := NewZSet()
+	ref := make(map[string]float64)
+	for i := 0; i < 5000; i++ {
+		m := "m" + strconv.Itoa(rand.Intn(500))
+		if rand.Intn(4) == 0 {
+			assert.Equal(t, z.Remove(m), ref[m] != 0)
+			delete(ref, m)
+			continue
+		}
+		score := float64(rand.Intn(50) + 1)
+		_, exists := ref[m]
+		assert.Equal(t, !exists, z.Set(m, score))
+		ref[m] = score
+	}
+
+	want := make([]ZEntry, 0, len(ref))
+	for m, s := range ref {
+		want = append(want, ZEntry{Member: m, Score: s})
+	}
+	sort.Slice(want, func(i, j int) bool {
+		if want[i].Score != want[j].Score {
+			return want[i].Score < want[j].Score
+		}
+		return want[i].Member < want[j].Member
+	})
+	assert.Equal(t, want, z.Entries())
+	for i, e := range want {
+		rank, ok := z.Rank(e.Member, false)
+		assert.Equal(t, true, ok)
+		assert.Equal(t, i, rank)
+		rank, _ = z.Rank(e.Member, true)
+		assert.Equal(t, len(want)-1-i, rank)
+	}
+	assert.Equal(t, want[10:21], z.Range(10, 20, false))
+	assert.Equal(t, want[len(want)-1], z.Range(0, 0, true)[0])
+
+	r := ScoreRange{Min: 10, Max: 20, MinEx: true}
+	var inRange []ZEntry
+	for _, e := range want {
+		if e.Score > 10 && e.Score <= 20 {
+			inRange = append(inRange, e)
+		}
+	}
+	assert.Equal(t, len(inRange), z.Count(r))
+	assert.Equal(t, inRange[2:5], z.RangeByScore(r, false, 2, 3))
+	rev := z.RangeByScore(r, true, 0, -1)
+	assert.Equal(t, inRange[len(inRange)-1], rev[0])
+	assert.Equal(t, len(inRange), len(rev))
+}
+
+func TestZSetLexRange(t *testing.T) {
+	z := NewZSet()
+	for _, m := range []string{"a", "b", "c", "d", "e"} {
+		z.Set(m, 0)
+	}
+	r := LexRange{Min: LexBound{Value: "b"}, Max: LexBound{Value: "d", Exclusive: true}}
+	assert.Equal(t, []ZEntry{{"b", 0}, {"c", 0}}, z.RangeByLex(r, false, 0, -1))
+	assert.Equal(t, 2, z.LexCount(r))
+	all := LexRange{Min: LexBound{Inf: -1}, Max: LexBound{Inf: 1}}
+	assert.Equal(t, 5, z.LexCount(all))
+	assert.Equal(t, []ZEntry{{"e", 0}, {"d", 0}}, z.RangeByLex(all, true, 0, 2))
+	assert.Equal(t, 0, z.LexCount(LexRange{Min: LexBound{Inf: 1}, Max: LexBound{Inf: 1}}))
+}
@@ -0,0 +1,59 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc64"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// Version 写入 RDB 文件头 ("REDIS0011") 与 DUMP 尾部的版本号
+const Version = 11
+
+var (
+	ErrBadDumpPayload = errors.New("ERR DUMP payload version or checksum are wrong")
+	ErrBadDataFormat  = errors.New("ERR Bad data format")
+)
+
+// DUMP 序列化格式: <类型><RDB 值编码><2字节版本号 LE><8字节 CRC64 LE>
+// CRC64 覆盖前面的全部字节, 与 RDB 文件使用同一张表
+
+// Dump 将值序列化为 DUMP 载荷
+func Dump(o *kvstore.Object) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeObject(&buf, o); err != nil {
+		return nil, err
+	}
+	binary.Write(&buf, binary.LittleEndian, uint16(Version))
+	binary.Write(&buf, binary.LittleEndian, crc64Of(buf.Bytes()))
+	return buf.Bytes(), nil
+}
+
+// Restore 校验版本与 CRC 后解析 DUMP 载荷 值的元素全部过期时返回 nil 对象
+func Restore(payload []byte) (*kvstore.Object, error) {
+	if len(payload) < 10 {
+		return nil, ErrBadDumpPayload
+	}
+	body, footer := payload[:len(payload)-8], payload[len(payload)-8:]
+	version := binary.LittleEndian.Uint16(body[len(body)-2:])
+	if version > Version || binary.LittleEndian.Uint64(footer) != crc64Of(body) {
+		return nil, ErrBadDumpPayload
+	}
+
+	r := bytes.NewReader(body[:len(body)-2])
+	typ, err := r.ReadByte()
+	if err != nil {
+		return nil, ErrBadDumpPayload
+	}
+	o, err := readObject(r, typ)
+	if err != nil || r.Len() != 0 {
+		return nil, ErrBadDataFormat
+	}
+	return o, nil
+}
+
+func crc64Of(p []byte) uint64 {
+	return crc64.Checksum(p, crc64Table)
+}
@@ -0,0 +1,75 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+	"github.com/go-playground/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpRestore(t *testing.T) {
+	for _, o := range []*kvstore.Object{
+		kvstore.NewStringObject("hello"),
+		kvstore.NewStringObject("12345"),
+		kvstore.NewIntObject(-7),
+		kvstore.NewListObjectFrom([]string{"a", "b", "c"}),
+	} {
+		payload, err := Dump(o)
+		require.NoError(t, err)
+		got, err := Restore(payload)
+		require.NoError(t, err)
+		assert.Equal(t, o.Type, got.Type)
+		assert.Equal(t, o.Encoding, got.Encoding)
+		// 再次序列化应得到相同的载荷
+		again, err := Dump(got)
+		require.NoError(t, err)
+		assert.Equal(t, payload, again)
+	}
+
+	// 与 Redis 7 对 SET foo bar 的 DUMP 输出一致 (版本号 11)
+	payload, err := Dump(kvstore.NewStringObject("bar"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("\x00\x03bar\x0b\x00"), payload[:len(payload)-8])
+}
+
+func TestRestoreRejectsBadPayload(t *testing.T) {
+	payload, err := Dump(kvstore.NewStringObject("hello"))
+	require.NoError(t, err)
+
+	// CRC 被篡改
+	bad := append([]byte(nil), payload...)
+	bad[len(bad)-1] ^= 0xff
+	_, err = Restore(bad)
+	assert.Equal(t, ErrBadDumpPayload, err)
+
+	// 内容被篡改 CRC 不再匹配
+	bad = append([]byte(nil), payload...)
+	bad[2] = 'j'
+	_, err = Restore(bad)
+	assert.Equal(t, ErrBadDumpPayload, err)
+
+	// 比当前更新的 RDB 版本 (重新计算 CRC)
+	body := append([]byte(nil), payload[:len(payload)-8]...)
+	binary.LittleEndian.PutUint16(body[len(body)-2:], Version+1)
+	bad = binary.LittleEndian.AppendUint64(body, crc64Of(body))
+	_, err = Restore(bad)
+	assert.Equal(t, ErrBadDumpPayload, err)
+
+	// 较旧的版本可以加载
+	body = append([]byte(nil), payload[:len(payload)-8]...)
+	binary.LittleEndian.PutUint16(body[len(body)-2:], 9)
+	o, err := Restore(binary.LittleEndian.AppendUint64(body, crc64Of(body)))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", o.Str())
+
+	// 载荷过短
+	_, err = Restore([]byte("short"))
+	assert.Equal(t, ErrBadDumpPayload, err)
+
+	// 校验通过但值的编码不完整
+	body = []byte{0x00, 0x05, 'h', 'i', 0x0b, 0x00}
+	_, err = Restore(binary.LittleEndian.AppendUint64(body, crc64Of(body)))
+	assert.Equal(t, ErrBadDataFormat, err)
+}
@@ -0,0 +1,184 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// RDB 值类型
+const (
+	TypeString byte = 0x00
+	TypeList   byte = 0x01
+	TypeSet    byte = 0x02
+	TypeZSet   byte = 0x03
+	TypeHash   byte = 0x04
+	TypeZSet2  byte = 0x05 // 分值以 8 字节二进制 double 存储
+
+	TypeModule2 byte = 0x07 // 模块类型 (带操作码的字段)
+
+	TypeSetIntset    byte = 0x0B // intset 二进制整体作为一个字符串存储
+	TypeHashMetadata byte = 0x18 // 带字段过期时间的哈希 (Redis 7.4)
+
+	TypeStreamListpacks  byte = 0x0F // 流 (Redis 5.0)
+	TypeStreamListpacks2 byte = 0x13 // 流 增加 first-id / max-deleted-id / entries-added (Redis 7.0)
+	TypeStreamListpacks3 byte = 0x15 // 流 消费者增加 active-time (Redis 7.2)
+)
+
+// 操作码
+const (
+	opAux      byte = 0xFA
+	opResizeDB byte = 0xFB
+	opExpireMs byte = 0xFC
+	opExpire   byte = 0xFD
+	opSelectDB byte = 0xFE
+	opEOF      byte = 0xFF
+)
+
+// 字符串的特殊编码 (长度字节以 11 开头)
+const (
+	encInt8  = 0
+	encInt16 = 1
+	encInt32 = 2
+	encLZF   = 3
+)
+
+// reader RDB 解码的输入 (bufio.Reader / bytes.Reader)
+type reader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// writer RDB 编码的输出 (bytes.Buffer)
+type writer interface {
+	io.Writer
+	io.ByteWriter
+}
+
+// writeLength RDB 长度编码
+// 00xxxxxx: 6位; 01xxxxxx xxxxxxxx: 14位; 10000000 + 4字节 BE: 32位; 10000001 + 8字节 BE: 64位
+func writeLength(w writer, n uint64) {
+	switch {
+	case n < 1<<6:
+		w.WriteByte(byte(n))
+	case n < 1<<14:
+		w.WriteByte(byte(n>>8) | 0x40)
+		w.WriteByte(byte(n))
+	case n <= 0xFFFFFFFF:
+		w.WriteByte(0x80)
+		binary.Write(w, binary.BigEndian, uint32(n))
+	default:
+		w.WriteByte(0x81)
+		binary.Write(w, binary.BigEndian, n)
+	}
+}
+
+// readLength 返回长度 encoded 为 true 时 n 为字符串的特殊编码类型
+func readLength(r io.ByteReader) (n uint64, encoded bool, err error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch b >> 6 {
+	case 0:
+		return uint64(b & 0x3F), false, nil
+	case 1:
+		next, err := r.ReadByte()
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(b&0x3F)<<8 | uint64(next), false, nil
+	case 3:
+		return uint64(b & 0x3F), true, nil
+	}
+	size := 0
+	switch b {
+	case 0x80:
+		size = 4
+	case 0x81:
+		size = 8
+	default:
+		return 0, false, fmt.Errorf("unsupported length encoding %#x", b)
+	}
+	for i := 0; i < size; i++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, false, err
+		}
+		n = n<<8 | uint64(c)
+	}
+	return n, false, nil
+}
+
+// readLen 读取不允许特殊编码的长度 (元素个数等)
+func readLen(r io.ByteReader) (uint64, error) {
+	n, encoded, err := readLength(r)
+	if err == nil && encoded {
+		err = fmt.Errorf("unexpected encoded length")
+	}
+	return n, err
+}
+
+// writeString 长度前缀字符串
+func writeString(w writer, s string) {
+	writeLength(w, uint64(len(s)))
+	io.WriteString(w, s)
+}
+
+// writeIntString 整数编码的字符串 11000000 + 1/2/4 字节小端 调用方保证 n 在 int32 范围内
+func writeIntString(w writer, n int64) {
+	switch {
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		w.WriteByte(0xC0 | encInt8)
+		w.WriteByte(byte(n))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		w.WriteByte(0xC0 | encInt16)
+		binary.Write(w, binary.LittleEndian, int16(n))
+	default:
+		w.WriteByte(0xC0 | encInt32)
+		binary.Write(w, binary.LittleEndian, int32(n))
+	}
+}
+
+// readString 长度前缀字符串 支持整数编码 (C0/C1/C2)
+func readString(r reader) (string, error) {
+	n, encoded, err := readLength(r)
+	if err != nil {
+		return "", err
+	}
+	if encoded {
+		var v int64
+		switch n {
+		case encInt8:
+			var x int8
+			err = binary.Read(r, binary.LittleEndian, &x)
+			v = int64(x)
+		case encInt16:
+			var x int16
+			err = binary.Read(r, binary.LittleEndian, &x)
+			v = int64(x)
+		case encInt32:
+			var x int32
+			err = binary.Read(r, binary.LittleEndian, &x)
+			v = int64(x)
+		default:
+			// encLZF 压缩字符串 写入端不会产生
+			return "", fmt.Errorf("unsupported string encoding %d", n)
+		}
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(v, 10), nil
+	}
+	// 防止损坏的长度导致超大分配
+	if n > 512<<20 {
+		return "", fmt.Errorf("string length %d too large", n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
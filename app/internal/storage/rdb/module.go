@@ -0,0 +1,440 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// 模块类型的值 (TypeModule2): <模块 id><字段>...<opModuleEOF>
+// 模块 id 为 9 个字符的类型名 (每个字符 6 位) 加 10 位编码版本, 每个字段以操作码开头
+// 与 Redis 模块 (RedisJSON 等) 的格式兼容
+const (
+	opModuleEOF    = 0
+	opModuleSInt   = 1
+	opModuleUInt   = 2
+	opModuleFloat  = 3
+	opModuleDouble = 4
+	opModuleString = 5
+)
+
+const moduleCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+// moduleType 一种模块类型的序列化
+type moduleType struct {
+	name   string
+	encver uint64
+	save   func(w *moduleWriter, o *kvstore.Object)
+	load   func(r *moduleReader, encver uint64) (*kvstore.Object, error)
+}
+
+var moduleTypes = map[kvstore.ObjType]*moduleType{
+	kvstore.TypeJSON:       jsonModule,
+	kvstore.TypeBloom:      bloomModule,
+	kvstore.TypeCuckoo:     cuckooModule,
+	kvstore.TypeCMS:        cmsModule,
+	kvstore.TypeTopK:       topkModule,
+	kvstore.TypeTimeSeries: tsModule,
+}
+
+func (m *moduleType) id() uint64 {
+	var id uint64
+	for i := 0; i < len(m.name); i++ {
+		id = id<<6 | uint64(strings.IndexByte(moduleCharset, m.name[i]))
+	}
+	return id<<10 | m.encver
+}
+
+// moduleName 从模块 id 还原类型名
+func moduleName(id uint64) string {
+	buf := make([]byte, 9)
+	id >>= 10
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i] = moduleCharset[id&63]
+		id >>= 6
+	}
+	return string(buf)
+}
+
+func writeModule(w writer, m *moduleType, o *kvstore.Object) {
+	writeLength(w, m.id())
+	m.save(&moduleWriter{w: w}, o)
+	writeLength(w, opModuleEOF)
+}
+
+func readModule(r reader) (*kvstore.Object, error) {
+	id, err := readLen(r)
+	if err != nil {
+		return nil, err
+	}
+	name := moduleName(id)
+	for _, m := range moduleTypes {
+		if m.name != name {
+			continue
+		}
+		encver := id & 1023
+		if encver > m.encver {
+			return nil, fmt.Errorf("unsupported %s encoding version %d", name, encver)
+		}
+		mr := &moduleReader{r: r}
+		o, err := m.load(mr, encver)
+		if err != nil {
+			return nil, err
+		}
+		if op, err := readLen(r); err != nil || op != opModuleEOF {
+			return nil, fmt.Errorf("missing %s value EOF", name)
+		}
+		return o, nil
+	}
+	return nil, fmt.Errorf("unsupported module type %s", name)
+}
+
+type moduleWriter struct {
+	w writer
+}
+
+func (mw *moduleWriter) Unsigned(n uint64) {
+	writeLength(mw.w, opModuleUInt)
+	writeLength(mw.w, n)
+}
+
+func (mw *moduleWriter) Signed(n int64) {
+	writeLength(mw.w, opModuleSInt)
+	writeLength(mw.w, uint64(n))
+}
+
+func (mw *moduleWriter) Double(f float64) {
+	writeLength(mw.w, opModuleDouble)
+	binary.Write(mw.w, binary.LittleEndian, math.Float64bits(f))
+}
+
+func (mw *moduleWriter) String(s string) {
+	writeLength(mw.w, opModuleString)
+	writeString(mw.w, s)
+}
+
+// moduleReader 按顺序读取字段 第一个错误之后的读取都返回零值, 由 Err 报告
+type moduleReader struct {
+	r   reader
+	err error
+}
+
+func (mr *moduleReader) expect(op uint64) bool {
+	if mr.err != nil {
+		return false
+	}
+	got, err := readLen(mr.r)
+	if err == nil && got != op {
+		err = fmt.Errorf("unexpected module opcode %d (want %d)", got, op)
+	}
+	mr.err = err
+	return err == nil
+}
+
+func (mr *moduleReader) Unsigned() uint64 {
+	if !mr.expect(opModuleUInt) {
+		return 0
+	}
+	n, err := readLen(mr.r)
+	mr.err = err
+	return n
+}
+
+func (mr *moduleReader) Signed() int64 {
+	if !mr.expect(opModuleSInt) {
+		return 0
+	}
+	n, err := readLen(mr.r)
+	mr.err = err
+	return int64(n)
+}
+
+func (mr *moduleReader) Double() float64 {
+	if !mr.expect(opModuleDouble) {
+		return 0
+	}
+	var bits uint64
+	mr.err = binary.Read(mr.r, binary.LittleEndian, &bits)
+	return math.Float64frombits(bits)
+}
+
+func (mr *moduleReader) String() string {
+	if !mr.expect(opModuleString) {
+		return ""
+	}
+	s, err := readString(mr.r)
+	mr.err = err
+	return s
+}
+
+func (mr *moduleReader) Err() error {
+	return mr.err
+}
+
+// jsonModule RedisJSON 的格式: 文档序列化为一个字符串
+var jsonModule = &moduleType{
+	name:   "ReJSON-RL",
+	encver: 3,
+	save: func(w *moduleWriter, o *kvstore.Object) {
+		w.String(kvstore.MarshalJSON(o.Value.(*kvstore.JSON).Root))
+	},
+	load: func(r *moduleReader, encver uint64) (*kvstore.Object, error) {
+		s := r.String()
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		root, err := kvstore.ParseJSON(s)
+		if err != nil {
+			return nil, err
+		}
+		return kvstore.NewJSONObject(root), nil
+	},
+}
+
+// 概率类型使用与 RedisBloom 相同的类型名, 字段布局为本实现自有的格式
+
+var errModuleCorrupt = errors.New("corrupt module value")
+
+// bloomModule <错误率><扩展倍数><层数>(<容量><个数><错误率><哈希数><位数组>)...
+var bloomModule = &moduleType{
+	name:   "MBbloom--",
+	encver: 1,
+	save: func(w *moduleWriter, o *kvstore.Object) {
+		b := o.Value.(*kvstore.Bloom)
+		w.Double(b.ErrorRate)
+		w.Unsigned(b.Expansion)
+		w.Unsigned(uint64(len(b.Layers)))
+		for _, l := range b.Layers {
+			w.Unsigned(l.Capacity)
+			w.Unsigned(l.Count)
+			w.Double(l.Error)
+			w.Unsigned(l.Hashes)
+			w.String(string(l.Bits))
+		}
+	},
+	load: func(r *moduleReader, encver uint64) (*kvstore.Object, error) {
+		b := &kvstore.Bloom{ErrorRate: r.Double(), Expansion: r.Unsigned()}
+		n := r.Unsigned()
+		for i := uint64(0); i < n && r.Err() == nil; i++ {
+			l := &kvstore.BloomLayer{Capacity: r.Unsigned(), Count: r.Unsigned(), Error: r.Double(), Hashes: r.Unsigned()}
+			l.Bits = []byte(r.String())
+			if r.Err() == nil && (len(l.Bits) == 0 || len(l.Bits)%8 != 0) {
+				return nil, errModuleCorrupt
+			}
+			b.Layers = append(b.Layers, l)
+		}
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		if len(b.Layers) == 0 {
+			return nil, errModuleCorrupt
+		}
+		return kvstore.NewBloomObject(b), nil
+	},
+}
+
+// cuckooModule <桶大小><最大踢出次数><扩展倍数><元素数><删除数><层数>(<桶数><指纹>)...
+var cuckooModule = &moduleType{
+	name:   "MBbloomCF",
+	encver: 1,
+	save: func(w *moduleWriter, o *kvstore.Object) {
+		c := o.Value.(*kvstore.Cuckoo)
+		w.Unsigned(c.BucketSize)
+		w.Unsigned(c.MaxIterations)
+		w.Unsigned(c.Expansion)
+		w.Unsigned(c.Items)
+		w.Unsigned(c.Deletes)
+		w.Unsigned(uint64(len(c.Layers)))
+		for _, l := range c.Layers {
+			w.Unsigned(l.NumBuckets)
+			w.String(string(l.Data))
+		}
+	},
+	load: func(r *moduleReader, encver uint64) (*kvstore.Object, error) {
+		c := &kvstore.Cuckoo{
+			BucketSize:    r.Unsigned(),
+			MaxIterations: r.Unsigned(),
+			Expansion:     r.Unsigned(),
+			Items:         r.Unsigned(),
+			Deletes:       r.Unsigned(),
+		}
+		n := r.Unsigned()
+		for i := uint64(0); i < n && r.Err() == nil; i++ {
+			l := &kvstore.CuckooLayer{NumBuckets: r.Unsigned()}
+			l.Data = []byte(r.String())
+			// 桶数必须是 2 的幂
+			if r.Err() == nil && (l.NumBuckets == 0 || l.NumBuckets&(l.NumBuckets-1) != 0 || uint64(len(l.Data)) != l.NumBuckets*c.BucketSize) {
+				return nil, errModuleCorrupt
+			}
+			c.Layers = append(c.Layers, l)
+		}
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		if len(c.Layers) == 0 {
+			return nil, errModuleCorrupt
+		}
+		return kvstore.NewCuckooObject(c), nil
+	},
+}
+
+// cmsModule <宽度><深度><总数><计数器 (4 字节小端)>
+var cmsModule = &moduleType{
+	name:   "CMSk-TYPE",
+	encver: 1,
+	save: func(w *moduleWriter, o *kvstore.Object) {
+		s := o.Value.(*kvstore.CountMinSketch)
+		w.Unsigned(s.Width)
+		w.Unsigned(s.Depth)
+		w.Unsigned(s.Count)
+		buf := make([]byte, 4*len(s.Counters))
+		for i, c := range s.Counters {
+			binary.LittleEndian.PutUint32(buf[4*i:], c)
+		}
+		w.String(string(buf))
+	},
+	load: func(r *moduleReader, encver uint64) (*kvstore.Object, error) {
+		width, depth, count := r.Unsigned(), r.Unsigned(), r.Unsigned()
+		buf := []byte(r.String())
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		s, err := kvstore.NewCountMinSketch(width, depth)
+		if err != nil || uint64(len(buf)) != 4*width*depth {
+			return nil, errModuleCorrupt
+		}
+		s.Count = count
+		for i := range s.Counters {
+			s.Counters[i] = binary.LittleEndian.Uint32(buf[4*i:])
+		}
+		return kvstore.NewCMSObject(s), nil
+	},
+}
+
+// topkModule <k><宽度><深度><衰减><随机数状态><桶 (指纹, 计数 各 4 字节小端)><堆大小>(<元素><计数>)...
+var topkModule = &moduleType{
+	name:   "TopK-TYPE",
+	encver: 1,
+	save: func(w *moduleWriter, o *kvstore.Object) {
+		t := o.Value.(*kvstore.TopK)
+		w.Unsigned(t.K)
+		w.Unsigned(t.Width)
+		w.Unsigned(t.Depth)
+		w.Double(t.Decay)
+		w.Unsigned(t.Seed)
+		buf := make([]byte, 8*len(t.Buckets))
+		for i, b := range t.Buckets {
+			binary.LittleEndian.PutUint32(buf[8*i:], b.Fingerprint)
+			binary.LittleEndian.PutUint32(buf[8*i+4:], b.Count)
+		}
+		w.String(string(buf))
+		w.Unsigned(uint64(len(t.Heap)))
+		for _, it := range t.Heap {
+			w.String(it.Item)
+			w.Unsigned(uint64(it.Count))
+		}
+	},
+	load: func(r *moduleReader, encver uint64) (*kvstore.Object, error) {
+		k, width, depth, decay := r.Unsigned(), r.Unsigned(), r.Unsigned(), r.Double()
+		seed := r.Unsigned()
+		buf := []byte(r.String())
+		n := r.Unsigned()
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		t, err := kvstore.NewTopK(k, width, depth, decay)
+		if err != nil || uint64(len(buf)) != 8*width*depth || n > k {
+			return nil, errModuleCorrupt
+		}
+		t.Seed = seed
+		for i := range t.Buckets {
+			p := buf[8*i:]
+			t.Buckets[i] = kvstore.TopKBucket{Fingerprint: binary.LittleEndian.Uint32(p), Count: binary.LittleEndian.Uint32(p[4:])}
+		}
+		for i := uint64(0); i < n; i++ {
+			t.Heap = append(t.Heap, kvstore.TopKItem{Item: r.String(), Count: uint32(r.Unsigned())})
+		}
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		return kvstore.NewTopKObject(t), nil
+	},
+}
+
+// tsModule <保留期><块大小><重复策略><标签数>(<名><值>)...<源键><规则数>(<目标键><聚合><桶宽><对齐>)...
+// <块数>(<样本数><压缩数据>)...
+var tsModule = &moduleType{
+	name:   "TSDB-TYPE",
+	encver: 2,
+	save: func(w *moduleWriter, o *kvstore.Object) {
+		ts := o.Value.(*kvstore.TimeSeries)
+		w.Signed(ts.Retention)
+		w.Unsigned(ts.ChunkSize)
+		w.Unsigned(uint64(ts.Duplicate))
+		w.Unsigned(uint64(len(ts.Labels)))
+		for _, l := range ts.Labels {
+			w.String(l.Name)
+			w.String(l.Value)
+		}
+		w.String(ts.SrcKey)
+		w.Unsigned(uint64(len(ts.Rules)))
+		for _, r := range ts.Rules {
+			w.String(r.DestKey)
+			w.Unsigned(uint64(r.Agg))
+			w.Signed(r.Bucket)
+			w.Signed(r.Align)
+			w.Signed(r.Since)
+		}
+		w.Unsigned(uint64(len(ts.Chunks)))
+		for _, c := range ts.Chunks {
+			w.Unsigned(c.Count)
+			w.String(string(c.Data))
+		}
+	},
+	load: func(r *moduleReader, encver uint64) (*kvstore.Object, error) {
+		ts := kvstore.NewTimeSeries()
+		ts.Retention = r.Signed()
+		ts.ChunkSize = r.Unsigned()
+		dup := r.Unsigned()
+		ts.Duplicate = kvstore.TSDuplicatePolicy(dup)
+		for n := r.Unsigned(); n > 0 && r.Err() == nil; n-- {
+			ts.Labels = append(ts.Labels, kvstore.TSLabel{Name: r.String(), Value: r.String()})
+		}
+		ts.SrcKey = r.String()
+		for n := r.Unsigned(); n > 0 && r.Err() == nil; n-- {
+			rule := kvstore.TSRule{DestKey: r.String(), Agg: kvstore.TSAggregator(r.Unsigned()), Bucket: r.Signed(), Align: r.Signed()}
+			// 版本 1 没有 Since: 聚合所有样本
+			if encver >= 2 {
+				rule.Since = r.Signed()
+			}
+			if r.Err() == nil && (rule.Bucket <= 0 || rule.Agg > kvstore.TSAggLast) {
+				return nil, errModuleCorrupt
+			}
+			ts.Rules = append(ts.Rules, rule)
+		}
+		if dup > uint64(kvstore.TSDupSum) {
+			return nil, errModuleCorrupt
+		}
+		for n := r.Unsigned(); n > 0 && r.Err() == nil; n-- {
+			count := r.Unsigned()
+			data := r.String()
+			if r.Err() != nil {
+				break
+			}
+			c, ok := kvstore.LoadTSChunk([]byte(data), count)
+			if !ok || (len(ts.Chunks) > 0 && c.Start <= ts.Chunks[len(ts.Chunks)-1].End) {
+				return nil, errModuleCorrupt
+			}
+			ts.Chunks = append(ts.Chunks, c)
+			ts.Total += count
+		}
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		return kvstore.NewTimeSeriesObject(ts), nil
+	},
+}
@@ -0,0 +1,355 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// rdbType 值在 RDB 中的类型字节
+func rdbType(o *kvstore.Object) (byte, error) {
+	switch o.Type {
+	case kvstore.TypeString:
+		return TypeString, nil
+	case kvstore.TypeList:
+		return TypeList, nil
+	case kvstore.TypeSet:
+		if _, ok := o.Value.(*kvstore.Set).Ints(); ok {
+			return TypeSetIntset, nil
+		}
+		return TypeSet, nil
+	case kvstore.TypeZSet:
+		return TypeZSet2, nil
+	case kvstore.TypeHash:
+		if o.Value.(*kvstore.Hash).HasExpires() {
+			return TypeHashMetadata, nil
+		}
+		return TypeHash, nil
+	case kvstore.TypeStream:
+		return TypeStreamListpacks3, nil
+	}
+	if _, ok := moduleTypes[o.Type]; ok {
+		return TypeModule2, nil
+	}
+	return 0, fmt.Errorf("can't serialize %s value", o.Type)
+}
+
+// writeObject 写入 <RDB 类型><值编码> (DUMP 载荷)
+func writeObject(w writer, o *kvstore.Object) error {
+	typ, err := rdbType(o)
+	if err != nil {
+		return err
+	}
+	w.WriteByte(typ)
+	return writeValue(w, o)
+}
+
+// writeValue 按类型写入值编码
+func writeValue(w writer, o *kvstore.Object) error {
+	switch o.Type {
+	case kvstore.TypeString:
+		// int 编码且在 32 位范围内的整数以整数编码保存
+		if n, ok := o.Value.(int64); ok && n >= math.MinInt32 && n <= math.MaxInt32 {
+			writeIntString(w, n)
+			break
+		}
+		writeString(w, o.Str())
+	case kvstore.TypeList:
+		// <长度><元素>...
+		l := o.Value.(*kvstore.Quicklist)
+		writeLength(w, uint64(l.Len()))
+		l.Each(false, func(_ int, v string) bool {
+			writeString(w, v)
+			return true
+		})
+	case kvstore.TypeSet:
+		set := o.Value.(*kvstore.Set)
+		if ints, ok := set.Ints(); ok {
+			writeString(w, string(encodeIntset(ints)))
+			break
+		}
+		members := set.Members()
+		writeLength(w, uint64(len(members)))
+		for _, m := range members {
+			writeString(w, m)
+		}
+	case kvstore.TypeZSet:
+		// <长度>(<成员><8 字节小端 double 分值>)...
+		entries := o.Value.(*kvstore.ZSet).Entries()
+		writeLength(w, uint64(len(entries)))
+		for _, e := range entries {
+			writeString(w, e.Member)
+			binary.Write(w, binary.LittleEndian, math.Float64bits(e.Score))
+		}
+	case kvstore.TypeHash:
+		writeHash(w, o.Value.(*kvstore.Hash))
+	case kvstore.TypeStream:
+		writeStream(w, o.Value.(*kvstore.Stream))
+	default:
+		if m, ok := moduleTypes[o.Type]; ok {
+			writeModule(w, m, o)
+			break
+		}
+		return fmt.Errorf("can't serialize %s value", o.Type)
+	}
+	return nil
+}
+
+// writeHash 无字段过期时间: <长度>(<字段><值>)...
+// 有字段过期时间: <最小过期时间 8 字节毫秒><长度>(<ttl><字段><值>)...
+// ttl 以长度编码存储, 0 表示不过期, 否则为 过期时间-最小过期时间+1
+func writeHash(w writer, h *kvstore.Hash) {
+	fields := h.Fields()
+	if !h.HasExpires() {
+		writeLength(w, uint64(len(fields)))
+		for _, f := range fields {
+			v, _ := h.Get(f)
+			writeString(w, f)
+			writeString(w, v)
+		}
+		return
+	}
+	minExpire := int64(math.MaxInt64)
+	for _, f := range fields {
+		if ms, ok := h.ExpireAt(f); ok {
+			minExpire = min(minExpire, ms)
+		}
+	}
+	binary.Write(w, binary.LittleEndian, uint64(minExpire))
+	writeLength(w, uint64(len(fields)))
+	for _, f := range fields {
+		ttl := uint64(0)
+		if ms, ok := h.ExpireAt(f); ok {
+			ttl = uint64(ms-minExpire) + 1
+		}
+		v, _ := h.Get(f)
+		writeLength(w, ttl)
+		writeString(w, f)
+		writeString(w, v)
+	}
+}
+
+// readHash 读取 TypeHash / TypeHashMetadata 加载时已过期的字段丢弃
+// 全部字段都已过期时返回 nil 对象
+func readHash(r reader, withTTL bool) (*kvstore.Object, error) {
+	var minExpire uint64
+	if withTTL {
+		if err := binary.Read(r, binary.LittleEndian, &minExpire); err != nil {
+			return nil, err
+		}
+	}
+	n, err := readLen(r)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixMilli()
+	fields := make(map[string]string, min(n, 1024))
+	var expires map[string]int64
+	for i := uint64(0); i < n; i++ {
+		var ttl uint64
+		if withTTL {
+			if ttl, err = readLen(r); err != nil {
+				return nil, err
+			}
+		}
+		f, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		v, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		if ttl == 0 {
+			fields[f] = v
+			continue
+		}
+		ms := int64(minExpire + ttl - 1)
+		if ms <= now {
+			continue
+		}
+		if expires == nil {
+			expires = make(map[string]int64)
+		}
+		fields[f] = v
+		expires[f] = ms
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return kvstore.NewHashObjectFrom(fields, expires), nil
+}
+
+// readZSet 读取有序集合 TypeZSet2 的分值为 8 字节 double,
+// 旧格式 TypeZSet 为 <1 字节长度><字符串> (253/254/255 分别为 nan/+inf/-inf)
+func readZSet(r reader, binaryScore bool) (*kvstore.Object, error) {
+	n, err := readLen(r)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]kvstore.ZEntry, 0, min(n, 1024))
+	for i := uint64(0); i < n; i++ {
+		member, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		var score float64
+		if binaryScore {
+			var bits uint64
+			if err := binary.Read(r, binary.LittleEndian, &bits); err != nil {
+				return nil, err
+			}
+			score = math.Float64frombits(bits)
+		} else if score, err = readDoubleString(r); err != nil {
+			return nil, err
+		}
+		entries = append(entries, kvstore.ZEntry{Member: member, Score: score})
+	}
+	return kvstore.NewZSetObjectFrom(entries), nil
+}
+
+func readDoubleString(r reader) (float64, error) {
+	l, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch l {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(string(buf), 64)
+}
+
+// readObject 按 RDB 类型读取值 值的元素全部过期时返回 nil 对象 (调用方跳过该键)
+func readObject(r reader, typ byte) (*kvstore.Object, error) {
+	switch typ {
+	case TypeString:
+		s, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		return kvstore.NewStringObject(s), nil
+	case TypeList:
+		values, err := readStrings(r)
+		if err != nil {
+			return nil, err
+		}
+		return kvstore.NewListObjectFrom(values), nil
+	case TypeSet:
+		members, err := readStrings(r)
+		if err != nil {
+			return nil, err
+		}
+		return kvstore.NewSetObjectFrom(members), nil
+	case TypeSetIntset:
+		blob, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		members, err := decodeIntset([]byte(blob))
+		if err != nil {
+			return nil, err
+		}
+		return kvstore.NewSetObjectFrom(members), nil
+	case TypeZSet, TypeZSet2:
+		return readZSet(r, typ == TypeZSet2)
+	case TypeHash:
+		return readHash(r, false)
+	case TypeHashMetadata:
+		return readHash(r, true)
+	case TypeStreamListpacks:
+		return readStream(r, 1)
+	case TypeStreamListpacks2:
+		return readStream(r, 2)
+	case TypeStreamListpacks3:
+		return readStream(r, 3)
+	case TypeModule2:
+		return readModule(r)
+	}
+	return nil, fmt.Errorf("unsupported value type %#x", typ)
+}
+
+// readStrings 读取 <长度><字符串>... 序列
+func readStrings(r reader) ([]string, error) {
+	n, err := readLen(r)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, min(n, 1024))
+	for i := uint64(0); i < n; i++ {
+		v, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// encodeIntset intset 二进制: <编码宽度 uint32><个数 uint32><升序整数...> (小端)
+// 宽度取能容纳所有成员的最小值 (2/4/8 字节)
+func encodeIntset(ints []int64) []byte {
+	width := 2
+	for _, n := range ints {
+		switch {
+		case n < math.MinInt32 || n > math.MaxInt32:
+			width = 8
+		case (n < math.MinInt16 || n > math.MaxInt16) && width < 4:
+			width = 4
+		}
+	}
+	buf := make([]byte, 8+width*len(ints))
+	binary.LittleEndian.PutUint32(buf, uint32(width))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(ints)))
+	for i, n := range ints {
+		p := buf[8+i*width:]
+		switch width {
+		case 2:
+			binary.LittleEndian.PutUint16(p, uint16(n))
+		case 4:
+			binary.LittleEndian.PutUint32(p, uint32(n))
+		default:
+			binary.LittleEndian.PutUint64(p, uint64(n))
+		}
+	}
+	return buf
+}
+
+func decodeIntset(buf []byte) ([]string, error) {
+	if len(buf) < 8 {
+		return nil, fmt.Errorf("intset too short")
+	}
+	width := int(binary.LittleEndian.Uint32(buf))
+	n := int(binary.LittleEndian.Uint32(buf[4:]))
+	if (width != 2 && width != 4 && width != 8) || len(buf) != 8+width*n {
+		return nil, fmt.Errorf("invalid intset")
+	}
+	members := make([]string, n)
+	for i := range members {
+		p := buf[8+i*width:]
+		var v int64
+		switch width {
+		case 2:
+			v = int64(int16(binary.LittleEndian.Uint16(p)))
+		case 4:
+			v = int64(int32(binary.LittleEndian.Uint32(p)))
+		default:
+			v = int64(binary.LittleEndian.Uint64(p))
+		}
+		members[i] = strconv.FormatInt(v, 10)
+	}
+	return members, nil
+}
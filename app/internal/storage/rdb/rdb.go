@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"hash/crc64"
 	"io"
 	"log"
@@ -45,9 +46,19 @@ func SaveToRDB(filename string, store *kvstore.Store) error {
 	}
 	defer file.Close()
 
+	return WriteRDB(file, store)
+}
+
+// WriteRDB 将数据集编码为 RDB 写入 w (文件持久化与主从全量同步共用)
+func WriteRDB(w io.Writer, store *kvstore.Store) error {
+	if store != nil {
+		store.Mu.RLock()
+		defer store.Mu.RUnlock()
+	}
+
 	// 创建CRC64 计算器
 	hasher := crc64.New(crc64Table)
-	multiWriter := io.MultiWriter(file, hasher) // 同时写到多个Writer 这里用于同时写入文件和计算校验值
+	multiWriter := io.MultiWriter(w, hasher) // 同时写到多个Writer 这里用于同时写入文件和计算校验值
 
 	// 文件头
 	header := []byte("REDIS0011") // 版本标识
@@ -67,89 +78,47 @@ func SaveToRDB(filename string, store *kvstore.Store) error {
 	}
 
 	// 数据库部分
-	var kvCnt, pxCnt byte = 0, 0
-	if store != nil && store.Data != nil && store.Expires != nil {
-		kvCnt = byte(len(store.Data))
-		pxCnt = byte(len(store.Expires))
-	}
-	// start:
-	if _, err := multiWriter.Write([]byte{
-		0xFE, // 标记数据库区的开始
-		0x00, // db index
-		0xFB, // 标记哈希表大小信息开始
-		kvCnt,
-		pxCnt,
-	}); err != nil {
-		log.Printf("DB Start Error: %s", err)
-		return err
-	}
-	if store != nil && store.Data != nil && store.Expires != nil {
-		// 遍历处理键值对 并写入
-		for key, value := range store.Data {
-			// 检测是否需要编码过期时间戳
+	var body bytes.Buffer
+	body.Write([]byte{
+		opSelectDB, // 标记数据库区的开始
+		0x00,       // db index
+		opResizeDB, // 标记哈希表大小信息开始
+	})
+	if store == nil {
+		writeLength(&body, 0)
+		writeLength(&body, 0)
+	} else {
+		writeLength(&body, uint64(len(store.Data)))
+		writeLength(&body, uint64(len(store.Expires)))
+		// 遍历键值对 [FC <过期时间戳>] <类型> <键> <值>
+		for key, o := range store.Data {
 			if pxTime, exist := store.Expires[key]; exist {
-				// uint64可以安全存储13位数
-				// 过期时间设置
-				pxTimeInt := pxTime.Unix()
-				// 持久化到期时间
-				timeStamp := uint64(pxTimeInt) + uint64(time.Now().UnixMilli())
-				// pxTime int
-				multiWriter.Write([]byte{0xFC}) // 标志过期时间戳
-				if err := binary.Write(multiWriter, binary.LittleEndian, timeStamp); err != nil {
-					log.Printf("Write TimeStamp Error : %s", err)
-					return err
-				}
+				// 持久化到期时间 (Unix 毫秒时间戳)
+				body.WriteByte(opExpireMs)
+				binary.Write(&body, binary.LittleEndian, uint64(pxTime.UnixMilli()))
 			}
-			// bytes.Join方法 可能会产生内存峰值(取决于KV大小)
-			encodedKV := [][]byte{
-				[]byte{0x00}, // Value Type is String
-				[]byte{byte(len(key))},
-				[]byte(key),
-				[]byte{byte(len(value))},
-				[]byte(value),
+			typ, err := rdbType(o)
+			if err != nil {
+				log.Printf("Encode key %s Error: %s", key, err)
+				return err
 			}
-			if _, err := multiWriter.Write(bytes.Join(encodedKV, nil)); err != nil {
-				log.Printf("Write len Error: %s", err)
+			body.WriteByte(typ)
+			writeString(&body, key)
+			if err := writeValue(&body, o); err != nil {
+				log.Printf("Encode key %s Error: %s", key, err)
 				return err
 			}
 		}
-
-		// //// ---  delete  ---
-		// kvMap.Range(func(key, value any) bool {
-		// 	// 处理kv
-		// 	k, _ := key.(string)
-		// 	v, _ := value.(string)
-		// 	// 检测是否需要编码过期时间戳
-		// 	if pxTime, exist := pxMap.Load(key); exist {
-		// 		// uint64可以安全存储13位数
-		// 		timeStamp := pxTime.(uint64) + uint64(time.Now().UnixMilli())
-		// 		// pxTime int
-		// 		multiWriter.Write([]byte{0xFC}) // 标志过期时间戳
-		// 		if err := binary.Write(multiWriter, binary.LittleEndian, timeStamp); err != nil {
-		// 			log.Printf("Write TimeStamp Error : %s", err)
-		// 			return false
-		// 		}
-		// 	}
-		// 	// bytes.Join方法 可能会产生内存峰值(取决于KV大小)
-		// 	encodedKV := [][]byte{
-		// 		[]byte{0x00}, // Value Type is String
-		// 		[]byte{byte(len(key.(string)))},
-		// 		[]byte(k),
-		// 		[]byte{byte(len(value.(string)))},
-		// 		[]byte(v),
-		// 	}
-		// 	if _, err := multiWriter.Write(bytes.Join(encodedKV, nil)); err != nil {
-		// 		log.Printf("Write len Error: %s", err)
-		// 		return false
-		// 	}
-		// 	return true
-		// })
+	}
+	if _, err := multiWriter.Write(body.Bytes()); err != nil {
+		log.Printf("Write DB Error: %s", err)
+		return err
 	}
 	// 写入结束标记
-	multiWriter.Write([]byte{0xFF})
+	multiWriter.Write([]byte{opEOF})
 
 	// 写入8字节(uint64) CRC64校验和
-	return binary.Write(file, binary.LittleEndian, hasher.Sum64())
+	return binary.Write(w, binary.LittleEndian, hasher.Sum64())
 }
 
 func ensureDir(path string) error {
@@ -167,18 +136,16 @@ func UpdateRDB(filename string, store *kvstore.Store) error {
 	return nil
 }
 
-// 过期时间Ticker  传入毫秒
+// 过期时间Ticker  传入毫秒 到期时键仍未被覆盖才删除
 func Expiry(t int, store *kvstore.Store, s string, filename string) {
 	time.Sleep(time.Duration(t) * time.Millisecond)
-	delete(store.Data, s)
-	delete(store.Expires, s)
-	UpdateRDB(filename, store)
+	if store.DeleteIfExpired(s) {
+		UpdateRDB(filename, store)
+	}
 }
 
+// GetRDBkeys 读取 RDB 文件中的字符串键值对 [k1, v1, k2, v2, ...]
 func GetRDBkeys(filename string) ([]string, error) {
-	var res []string
-	var kn int
-
 	f, err := os.Open(filename)
 	if err != nil {
 		log.Printf("Get Keys Error: %s", err)
@@ -186,112 +153,117 @@ func GetRDBkeys(filename string) ([]string, error) {
 	}
 	defer f.Close()
 
-	reader := bufio.NewReader(f)
-	// n, err := io.CopyN(io.Discard, reader, 32) // 废案
-	// FE 数据库部分的标识
-	// 找FB KV信息标识
-	for {
-		b, err := reader.ReadByte()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			panic(err)
-		}
-		if b == 0xFB {
-			KeyNum, err := reader.ReadByte()
-			if err != nil {
-				if err == io.EOF {
-					break
-				}
-				panic(err)
-			}
-			kn = int(KeyNum)
-			_, _ = reader.ReadByte() // 跳过 PXKV数
-			break
-		}
+	tmp := &kvstore.Store{Data: make(map[string]*kvstore.Object), Expires: make(map[string]time.Time)}
+	if err := LoadRDB(f, tmp); err != nil {
+		return nil, err
 	}
-
-	for range kn {
-		// 检测 Flag 值, 分别进入不同情况的解析函数
-		flag, err := reader.ReadByte()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			log.Printf("Reading bytes Error: %s", err)
-			return nil, err
-		}
-		if flag == 0x00 {
-			res = append(res, ParseKV(reader)...)
-		} else if flag == 0xFC {
-			ts, kv := ParsePXKV(reader)
-			// 过期kv会返回nil
-			if kv != nil {
-				log.Printf("key:%s的时间戳为:%v", kv[0], ts)
-				res = append(res, kv...)
-			}
-			// log.Printf("现在UnixMi值为：%v", time.Now().UnixMilli())
-		} else if flag == 0xFF {
-			break
-		} else {
-			log.Printf("unknown flag:%x", flag)
+	var res []string
+	for key, o := range tmp.Data {
+		if o.Type == kvstore.TypeString {
+			res = append(res, key, o.Str())
 		}
 	}
 	return res, nil
 }
 
-// FC 解析时间戳 KV
-func ParsePXKV(reader *bufio.Reader) (ts uint64, res []string) {
-	buf := make([]byte, 8)
-	_, err := io.ReadFull(reader, buf)
+// LoadFile 启动时加载 RDB 文件到 store 文件不存在时视为空数据集
+func LoadFile(filename string, store *kvstore.Store) error {
+	fileLock.RLock()
+	defer fileLock.RUnlock()
+
+	f, err := os.Open(filename)
 	if err != nil {
-		if err == io.EOF || err == io.ErrUnexpectedEOF {
-			return
+		if os.IsNotExist(err) {
+			return nil
 		}
-		panic(err)
-	}
-	ts = binary.LittleEndian.Uint64(buf)
-	_, _ = reader.ReadByte() // 跳过0x00标识 暂时只有00这一种情况
-	res = ParseKV(reader)
-
-	if ts <= uint64(time.Now().UnixMilli()) {
-		return ts, nil
+		return err
 	}
-	return
+	defer f.Close()
+	return LoadRDB(f, store)
 }
 
-// 00 解析普通KV
-func ParseKV(reader *bufio.Reader) (res []string) {
-	keylen, err := reader.ReadByte()
-	if err != nil {
+// LoadRDB 解析 RDB 数据并写入 store (启动加载与从节点全量同步)
+// 文件头 "REDIS" + 4位版本号, 之后为操作码或 <类型><键><值>, 以 FF + CRC64 结束
+func LoadRDB(r io.Reader, store *kvstore.Store) error {
+	reader := bufio.NewReader(r)
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(reader, header); err != nil {
 		if err == io.EOF {
-			return
+			return nil
 		}
-		panic(err)
+		return err
 	}
-	keyBytes := make([]byte, keylen)
-	_, err = io.ReadFull(reader, keyBytes)
-	if err != nil {
-		if err == io.EOF || err == io.ErrUnexpectedEOF {
-			return
-		}
-		panic(err)
+	if string(header[:5]) != "REDIS" {
+		return fmt.Errorf("invalid RDB header %q", header)
 	}
-	res = append(res, string(keyBytes))
 
-	vallen, err := reader.ReadByte()
-	valBytes := make([]byte, vallen)
-	_, err = io.ReadFull(reader, valBytes)
-	if err != nil {
-		if err == io.EOF || err == io.ErrUnexpectedEOF {
-			return
+	now := time.Now()
+	var expireAt time.Time
+	for {
+		op, err := reader.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		switch op {
+		case opEOF:
+			return nil
+		case opAux:
+			// 元数据 redis-ver 等 忽略
+			if _, err := readString(reader); err != nil {
+				return err
+			}
+			if _, err := readString(reader); err != nil {
+				return err
+			}
+		case opSelectDB:
+			if _, err := readLen(reader); err != nil {
+				return err
+			}
+		case opResizeDB:
+			if _, err := readLen(reader); err != nil {
+				return err
+			}
+			if _, err := readLen(reader); err != nil {
+				return err
+			}
+		case opExpireMs:
+			var ms uint64
+			if err := binary.Read(reader, binary.LittleEndian, &ms); err != nil {
+				return err
+			}
+			expireAt = time.UnixMilli(int64(ms))
+		case opExpire:
+			var sec uint32
+			if err := binary.Read(reader, binary.LittleEndian, &sec); err != nil {
+				return err
+			}
+			expireAt = time.Unix(int64(sec), 0)
+		default:
+			key, err := readString(reader)
+			if err != nil {
+				return err
+			}
+			o, err := readObject(reader, op)
+			if err != nil {
+				return fmt.Errorf("load key %s: %w", key, err)
+			}
+			switch {
+			case o == nil:
+				log.Printf("Skip empty key %s", key)
+			case expireAt.IsZero():
+				store.SetObject(key, o, 0)
+			case expireAt.After(now):
+				store.SetObject(key, o, time.Until(expireAt))
+			default:
+				// 过期键不加载
+				log.Printf("Skip expired key %s (expired at %v)", key, expireAt)
+			}
+			expireAt = time.Time{}
 		}
-		panic(err)
 	}
-
-	res = append(res, string(valBytes))
-	return
 }
 
 // func SizeMap(m *sync.Map) byte {
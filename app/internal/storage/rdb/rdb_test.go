@@ -0,0 +1,173 @@
+package rdb
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+	"github.com/go-playground/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func numbered(prefix string, n int) []string {
+	res := make([]string, n)
+	for i := range res {
+		res[i] = prefix + strconv.Itoa(i)
+	}
+	return res
+}
+
+func hashFrom(n, valueLen int, ttl bool) *kvstore.Object {
+	fields := make(map[string]string, n)
+	var expires map[string]int64
+	if ttl {
+		expires = map[string]int64{"f0": time.Now().Add(time.Hour).UnixMilli()}
+	}
+	for i := 0; i < n; i++ {
+		fields["f"+strconv.Itoa(i)] = strings.Repeat("v", valueLen)
+	}
+	return kvstore.NewHashObjectFrom(fields, expires)
+}
+
+func zsetFrom(members []string) *kvstore.Object {
+	entries := make([]kvstore.ZEntry, len(members))
+	for i, m := range members {
+		entries[i] = kvstore.ZEntry{Member: m, Score: float64(i) / 3}
+	}
+	return kvstore.NewZSetObjectFrom(entries)
+}
+
+func streamFrom() *kvstore.Object {
+	o := kvstore.NewStreamObject()
+	s := o.Value.(*kvstore.Stream)
+	// 超过一个节点的条目 并删除一条
+	for i := uint64(1); i <= 150; i++ {
+		s.Append(kvstore.StreamID{Ms: 1000 + i, Seq: i % 3}, []string{"n", strconv.FormatUint(i, 10)})
+	}
+	s.Delete(kvstore.StreamID{Ms: 1002, Seq: 2})
+	g := s.CreateGroup("g1", kvstore.StreamID{}, 0)
+	c, _ := g.Consumer("alice", true, 5000)
+	g.ReadNew(s, c, 3, false, 6000)
+	g.Ack(kvstore.StreamID{Ms: 1001, Seq: 1})
+	g.Consumer("bob", true, 7000)
+	s.CreateGroup("g2", kvstore.StreamID{Ms: 1100, Seq: 1}, -1)
+	return o
+}
+
+// contents 值的可比较表示 集合成员排序后比较
+func contents(o *kvstore.Object) any {
+	switch o.Type {
+	case kvstore.TypeString:
+		return o.Str()
+	case kvstore.TypeList:
+		var res []string
+		o.Value.(*kvstore.Quicklist).Each(false, func(_ int, v string) bool {
+			res = append(res, v)
+			return true
+		})
+		return res
+	case kvstore.TypeSet:
+		members := o.Value.(*kvstore.Set).Members()
+		sort.Strings(members)
+		return members
+	case kvstore.TypeZSet:
+		return o.Value.(*kvstore.ZSet).Entries()
+	case kvstore.TypeHash:
+		h := o.Value.(*kvstore.Hash)
+		var res []string
+		for _, f := range h.Fields() {
+			v, _ := h.Get(f)
+			at, _ := h.ExpireAt(f)
+			res = append(res, f+"="+v+"@"+strconv.FormatInt(at, 10))
+		}
+		sort.Strings(res)
+		return res
+	case kvstore.TypeStream:
+		s := o.Value.(*kvstore.Stream)
+		res := []any{s.Len(), s.LastID, s.MaxDeletedID, s.EntriesAdded,
+			s.Range(kvstore.StreamID{}, kvstore.MaxStreamID, false, 0)}
+		for _, g := range s.Groups() {
+			res = append(res, g.Name, g.LastID, g.EntriesRead)
+			g.PEL.Ascend(nil, func(_ []byte, p *kvstore.PendingEntry) bool {
+				res = append(res, p.ID, p.Consumer.Name, p.DeliveryTime, p.DeliveryCount)
+				return true
+			})
+			for _, c := range g.Consumers() {
+				res = append(res, c.Name, c.SeenTime, c.ActiveTime, c.PEL.Len())
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// 每种类型的每种编码 保存后重新加载 类型、编码、内容与过期时间不变
+func TestRDBRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	objects := map[string]*kvstore.Object{
+		"str:embstr":        kvstore.NewStringObject("hello"),
+		"str:raw":           kvstore.NewStringObject(long),
+		"str:int":           kvstore.NewStringObject("-42"),
+		"str:int64":         kvstore.NewStringObject("12345678901234"),
+		"list:listpack":     kvstore.NewListObjectFrom([]string{"a", "b", "1"}),
+		"list:many":         kvstore.NewListObjectFrom(numbered("e", 300)),
+		"list:long":         kvstore.NewListObjectFrom([]string{"a", long}),
+		"set:intset":        kvstore.NewSetObjectFrom([]string{"3", "-1", "100000"}),
+		"set:listpack":      kvstore.NewSetObjectFrom([]string{"a", "b", "c"}),
+		"set:hashtable":     kvstore.NewSetObjectFrom(numbered("m", 200)),
+		"zset:listpack":     zsetFrom([]string{"a", "b", "c"}),
+		"zset:skiplist":     zsetFrom(numbered("m", 200)),
+		"hash:listpack":     hashFrom(3, 5, false),
+		"hash:listpackex":   hashFrom(3, 5, true),
+		"hash:hashtable":    hashFrom(3, 100, false),
+		"hash:hashtablettl": hashFrom(200, 5, true),
+		"stream":            streamFrom(),
+	}
+	want := map[string]kvstore.Encoding{
+		"str:embstr": kvstore.EncEmbstr, "str:raw": kvstore.EncRaw, "str:int": kvstore.EncInt, "str:int64": kvstore.EncInt,
+		"list:listpack": kvstore.EncListpack, "list:many": kvstore.EncQuicklist, "list:long": kvstore.EncQuicklist,
+		"set:intset": kvstore.EncIntset, "set:listpack": kvstore.EncListpack, "set:hashtable": kvstore.EncHashtable,
+		"zset:listpack": kvstore.EncListpack, "zset:skiplist": kvstore.EncSkiplist,
+		"hash:listpack": kvstore.EncListpack, "hash:listpackex": kvstore.EncListpackEx,
+		"hash:hashtable": kvstore.EncHashtable, "hash:hashtablettl": kvstore.EncHashtable,
+		"stream": kvstore.EncStream,
+	}
+
+	store := kvstore.NewStore()
+	for key, o := range objects {
+		assert.Equal(t, want[key], o.Encoding)
+		store.SetObject(key, o, 0)
+	}
+	store.SetObject("ttl", kvstore.NewStringObject("v"), time.Hour)
+	deadline, _ := store.ExpireAt("ttl")
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteRDB(&buf, store))
+	loaded := kvstore.NewStore()
+	require.NoError(t, LoadRDB(bytes.NewReader(buf.Bytes()), loaded))
+
+	assert.Equal(t, store.Len(), loaded.Len())
+	for key, o := range objects {
+		got, ok := loaded.Peek(key)
+		require.True(t, ok, key)
+		assert.Equal(t, o.Type, got.Type)
+		assert.Equal(t, o.Encoding, got.Encoding)
+		assert.Equal(t, contents(o), contents(got))
+		_, ok = loaded.ExpireAt(key)
+		assert.Equal(t, false, ok)
+	}
+	at, ok := loaded.ExpireAt("ttl")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, deadline.UnixMilli(), at.UnixMilli())
+
+	// 在值的中间截断时报错
+	single := kvstore.NewStore()
+	single.SetObject("k", kvstore.NewStringObject(long), 0)
+	buf.Reset()
+	require.NoError(t, WriteRDB(&buf, single))
+	require.Error(t, LoadRDB(bytes.NewReader(buf.Bytes()[:buf.Len()-20]), kvstore.NewStore()))
+}
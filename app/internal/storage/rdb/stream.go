@@ -0,0 +1,544 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/internal/storage/memory/kvstore"
+)
+
+// 流条目在 listpack 中的标志
+const (
+	streamItemDeleted    = 1
+	streamItemSameFields = 2
+)
+
+// writeStream TypeStreamListpacks3:
+// <节点数>(<master ID 16 字节><listpack>)... <长度><last-id><first-id><max-deleted-id><entries-added>
+// <组数>(<组名><last-id><entries-read><PEL>(<原始 ID><投递时间><投递次数>)... <消费者数>(<名称><seen><active><PEL ID>...)...)...
+func writeStream(w writer, s *kvstore.Stream) {
+	keys, _ := s.Nodes()
+	writeLength(w, uint64(keys))
+	s.EachNode(func(master kvstore.StreamID, entries []kvstore.StreamEntry) {
+		writeString(w, string(master.Key()))
+		writeString(w, string(streamListpack(master, entries)))
+	})
+	writeLength(w, s.Len())
+	writeStreamID(w, s.LastID)
+	writeStreamID(w, s.FirstID())
+	writeStreamID(w, s.MaxDeletedID)
+	writeLength(w, s.EntriesAdded)
+
+	groups := s.Groups()
+	writeLength(w, uint64(len(groups)))
+	for _, g := range groups {
+		writeString(w, g.Name)
+		writeStreamID(w, g.LastID)
+		writeLength(w, uint64(g.EntriesRead))
+		writeLength(w, uint64(g.PEL.Len()))
+		g.PEL.Ascend(nil, func(k []byte, pe *kvstore.PendingEntry) bool {
+			w.Write(k)
+			binary.Write(w, binary.LittleEndian, pe.DeliveryTime)
+			writeLength(w, pe.DeliveryCount)
+			return true
+		})
+		consumers := g.Consumers()
+		writeLength(w, uint64(len(consumers)))
+		for _, c := range consumers {
+			writeString(w, c.Name)
+			binary.Write(w, binary.LittleEndian, c.SeenTime)
+			binary.Write(w, binary.LittleEndian, c.ActiveTime)
+			writeLength(w, uint64(c.PEL.Len()))
+			c.PEL.Ascend(nil, func(k []byte, _ *kvstore.PendingEntry) bool {
+				w.Write(k)
+				return true
+			})
+		}
+	}
+}
+
+func writeStreamID(w writer, id kvstore.StreamID) {
+	writeLength(w, id.Ms)
+	writeLength(w, id.Seq)
+}
+
+func readStreamID(r reader) (kvstore.StreamID, error) {
+	ms, err := readLen(r)
+	if err != nil {
+		return kvstore.StreamID{}, err
+	}
+	seq, err := readLen(r)
+	return kvstore.StreamID{Ms: ms, Seq: seq}, err
+}
+
+// streamListpack 一个节点的 listpack:
+// 主条目 <count><deleted><字段数><字段>...<0>, 之后每个条目
+// <flags><ms 差值><seq 差值>[<字段数><字段><值>...|<值>...]<元素数>
+// 与主条目字段相同的条目只保存值
+func streamListpack(master kvstore.StreamID, entries []kvstore.StreamEntry) []byte {
+	lp := newListpack()
+	fields := entryFields(entries[0])
+	lp.appendInt(int64(len(entries)))
+	lp.appendInt(0)
+	lp.appendInt(int64(len(fields)))
+	for _, f := range fields {
+		lp.appendString(f)
+	}
+	lp.appendInt(0)
+	for _, e := range entries {
+		same := sameFields(e, fields)
+		flags := int64(0)
+		if same {
+			flags = streamItemSameFields
+		}
+		lp.appendInt(flags)
+		lp.appendInt(int64(e.ID.Ms - master.Ms))
+		lp.appendInt(int64(e.ID.Seq - master.Seq))
+		n := len(e.Fields) / 2
+		if same {
+			for i := 1; i < len(e.Fields); i += 2 {
+				lp.appendString(e.Fields[i])
+			}
+			lp.appendInt(int64(n + 3))
+			continue
+		}
+		lp.appendInt(int64(n))
+		for _, v := range e.Fields {
+			lp.appendString(v)
+		}
+		lp.appendInt(int64(2*n + 4))
+	}
+	return lp.bytes()
+}
+
+func entryFields(e kvstore.StreamEntry) []string {
+	fields := make([]string, 0, len(e.Fields)/2)
+	for i := 0; i < len(e.Fields); i += 2 {
+		fields = append(fields, e.Fields[i])
+	}
+	return fields
+}
+
+func sameFields(e kvstore.StreamEntry, fields []string) bool {
+	if len(e.Fields) != 2*len(fields) {
+		return false
+	}
+	for i, f := range fields {
+		if e.Fields[2*i] != f {
+			return false
+		}
+	}
+	return true
+}
+
+// readStream 读取 TypeStreamListpacks / 2 / 3 (version 为 1/2/3)
+func readStream(r reader, version int) (*kvstore.Object, error) {
+	o := kvstore.NewStreamObject()
+	s := o.Value.(*kvstore.Stream)
+	nodes, err := readLen(r)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < nodes; i++ {
+		key, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) != 16 {
+			return nil, fmt.Errorf("invalid stream node key")
+		}
+		blob, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		master := kvstore.StreamIDFromKey([]byte(key))
+		entries, err := readStreamListpack(master, []byte(blob))
+		if err != nil {
+			return nil, err
+		}
+		s.AppendNode(master, entries)
+	}
+	if _, err := readLen(r); err != nil { // 长度 (由节点计算)
+		return nil, err
+	}
+	if s.LastID, err = readStreamID(r); err != nil {
+		return nil, err
+	}
+	s.EntriesAdded = s.Len()
+	if version >= 2 {
+		if _, err := readStreamID(r); err != nil { // first-id (由节点计算)
+			return nil, err
+		}
+		if s.MaxDeletedID, err = readStreamID(r); err != nil {
+			return nil, err
+		}
+		if s.EntriesAdded, err = readLen(r); err != nil {
+			return nil, err
+		}
+	}
+
+	ngroups, err := readLen(r)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < ngroups; i++ {
+		if err := readConsumerGroup(r, s, version); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+func readConsumerGroup(r reader, s *kvstore.Stream, version int) error {
+	name, err := readString(r)
+	if err != nil {
+		return err
+	}
+	lastID, err := readStreamID(r)
+	if err != nil {
+		return err
+	}
+	entriesRead := s.EntriesReadAt(lastID)
+	if version >= 2 {
+		n, err := readLen(r)
+		if err != nil {
+			return err
+		}
+		entriesRead = int64(n)
+	}
+	g := s.CreateGroup(name, lastID, entriesRead)
+	if g == nil {
+		return fmt.Errorf("duplicated consumer group %q", name)
+	}
+
+	// 组的 PEL 保存投递时间与次数 消费者的 PEL 只保存 ID
+	type delivery struct {
+		time  int64
+		count uint64
+	}
+	pending := make(map[kvstore.StreamID]delivery)
+	n, err := readLen(r)
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < n; i++ {
+		var raw [16]byte
+		var d delivery
+		if _, err := io.ReadFull(r, raw[:]); err != nil {
+			return err
+		}
+		if err := binary.Read(r, binary.LittleEndian, &d.time); err != nil {
+			return err
+		}
+		if d.count, err = readLen(r); err != nil {
+			return err
+		}
+		pending[kvstore.StreamIDFromKey(raw[:])] = d
+	}
+
+	nconsumers, err := readLen(r)
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < nconsumers; i++ {
+		cname, err := readString(r)
+		if err != nil {
+			return err
+		}
+		c, _ := g.Consumer(cname, true, 0)
+		if err := binary.Read(r, binary.LittleEndian, &c.SeenTime); err != nil {
+			return err
+		}
+		c.ActiveTime = c.SeenTime
+		if version >= 3 {
+			if err := binary.Read(r, binary.LittleEndian, &c.ActiveTime); err != nil {
+				return err
+			}
+		}
+		n, err := readLen(r)
+		if err != nil {
+			return err
+		}
+		for j := uint64(0); j < n; j++ {
+			var raw [16]byte
+			if _, err := io.ReadFull(r, raw[:]); err != nil {
+				return err
+			}
+			id := kvstore.StreamIDFromKey(raw[:])
+			d, ok := pending[id]
+			if !ok {
+				return fmt.Errorf("consumer PEL entry %s not found in group PEL", id)
+			}
+			g.Assign(id, c, d.time, d.count)
+		}
+	}
+	return nil
+}
+
+// readStreamListpack 解析节点的 listpack 跳过标记为删除的条目
+func readStreamListpack(master kvstore.StreamID, blob []byte) ([]kvstore.StreamEntry, error) {
+	lp, err := parseListpack(blob)
+	if err != nil {
+		return nil, err
+	}
+	next := func() (string, error) {
+		if len(lp) == 0 {
+			return "", fmt.Errorf("truncated stream listpack")
+		}
+		v := lp[0]
+		lp = lp[1:]
+		return v, nil
+	}
+	nextInt := func() (int64, error) {
+		v, err := next()
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseInt(v, 10, 64)
+	}
+
+	count, err := nextInt()
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := nextInt()
+	if err != nil {
+		return nil, err
+	}
+	nfields, err := nextInt()
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]string, nfields)
+	for i := range fields {
+		if fields[i], err = next(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := next(); err != nil { // 主条目结束标记
+		return nil, err
+	}
+
+	entries := make([]kvstore.StreamEntry, 0, count)
+	for i := int64(0); i < count+deleted; i++ {
+		flags, err := nextInt()
+		if err != nil {
+			return nil, err
+		}
+		msDiff, err := nextInt()
+		if err != nil {
+			return nil, err
+		}
+		seqDiff, err := nextInt()
+		if err != nil {
+			return nil, err
+		}
+		e := kvstore.StreamEntry{ID: kvstore.StreamID{Ms: master.Ms + uint64(msDiff), Seq: master.Seq + uint64(seqDiff)}}
+		if flags&streamItemSameFields != 0 {
+			for _, f := range fields {
+				v, err := next()
+				if err != nil {
+					return nil, err
+				}
+				e.Fields = append(e.Fields, f, v)
+			}
+		} else {
+			n, err := nextInt()
+			if err != nil {
+				return nil, err
+			}
+			for j := int64(0); j < 2*n; j++ {
+				v, err := next()
+				if err != nil {
+					return nil, err
+				}
+				e.Fields = append(e.Fields, v)
+			}
+		}
+		if _, err := next(); err != nil { // 元素数
+			return nil, err
+		}
+		if flags&streamItemDeleted == 0 {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// listpack 编码: <总字节数 4><元素数 2><元素>...<0xFF>
+// 每个元素为 <编码+数据><backlen> backlen 为前者的长度 (用于反向遍历)
+type listpack struct {
+	buf bytes.Buffer
+	n   int
+}
+
+func newListpack() *listpack {
+	lp := &listpack{}
+	lp.buf.Write(make([]byte, 6))
+	return lp
+}
+
+func (lp *listpack) appendEntry(enc []byte) {
+	lp.buf.Write(enc)
+	l := len(enc)
+	// backlen: 每字节 7 位 高位在前, 除第一个字节外最高位为 1
+	var back []byte
+	for {
+		back = append([]byte{byte(l & 127)}, back...)
+		l >>= 7
+		if l == 0 {
+			break
+		}
+	}
+	for i := 1; i < len(back); i++ {
+		back[i] |= 128
+	}
+	lp.buf.Write(back)
+	lp.n++
+}
+
+func (lp *listpack) appendInt(v int64) {
+	var enc []byte
+	switch {
+	case v >= 0 && v <= 127:
+		enc = []byte{byte(v)}
+	case v >= -4096 && v <= 4095:
+		u := uint16(v) & 0x1FFF
+		enc = []byte{0xC0 | byte(u>>8), byte(u)}
+	case v >= -32768 && v <= 32767:
+		enc = binary.LittleEndian.AppendUint16([]byte{0xF1}, uint16(v))
+	case v >= -(1<<23) && v < 1<<23:
+		u := uint32(v)
+		enc = []byte{0xF2, byte(u), byte(u >> 8), byte(u >> 16)}
+	case v >= -(1<<31) && v < 1<<31:
+		enc = binary.LittleEndian.AppendUint32([]byte{0xF3}, uint32(v))
+	default:
+		enc = binary.LittleEndian.AppendUint64([]byte{0xF4}, uint64(v))
+	}
+	lp.appendEntry(enc)
+}
+
+func (lp *listpack) appendString(s string) {
+	var enc []byte
+	switch l := len(s); {
+	case l < 64:
+		enc = []byte{0x80 | byte(l)}
+	case l < 4096:
+		enc = []byte{0xE0 | byte(l>>8), byte(l)}
+	default:
+		enc = binary.LittleEndian.AppendUint32([]byte{0xF0}, uint32(l))
+	}
+	lp.appendEntry(append(enc, s...))
+}
+
+func (lp *listpack) bytes() []byte {
+	lp.buf.WriteByte(0xFF)
+	b := lp.buf.Bytes()
+	binary.LittleEndian.PutUint32(b, uint32(len(b)))
+	binary.LittleEndian.PutUint16(b[4:], uint16(min(lp.n, 65535)))
+	return b
+}
+
+// parseListpack 解析全部元素 整数元素转换为十进制字符串
+func parseListpack(b []byte) ([]string, error) {
+	if len(b) < 7 || int(binary.LittleEndian.Uint32(b)) != len(b) {
+		return nil, fmt.Errorf("invalid listpack header")
+	}
+	var res []string
+	p := b[6:]
+	for len(p) > 0 && p[0] != 0xFF {
+		v, size, err := listpackEntry(p)
+		if err != nil {
+			return nil, err
+		}
+		// 跳过 backlen
+		back := 1
+		for l := size >> 7; l > 0; l >>= 7 {
+			back++
+		}
+		if size+back > len(p) {
+			return nil, fmt.Errorf("truncated listpack")
+		}
+		res = append(res, v)
+		p = p[size+back:]
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("listpack without terminator")
+	}
+	return res, nil
+}
+
+// listpackEntry 解析一个元素 返回值与 编码+数据 的字节数
+func listpackEntry(p []byte) (string, int, error) {
+	need := func(n int) error {
+		if len(p) < n {
+			return fmt.Errorf("truncated listpack")
+		}
+		return nil
+	}
+	b := p[0]
+	switch {
+	case b&0x80 == 0: // 7 位无符号整数
+		return strconv.Itoa(int(b)), 1, nil
+	case b&0xC0 == 0x80: // 6 位长度字符串
+		l := int(b & 0x3F)
+		if err := need(1 + l); err != nil {
+			return "", 0, err
+		}
+		return string(p[1 : 1+l]), 1 + l, nil
+	case b&0xE0 == 0xC0: // 13 位有符号整数
+		if err := need(2); err != nil {
+			return "", 0, err
+		}
+		u := int64(b&0x1F)<<8 | int64(p[1])
+		if u >= 1<<12 {
+			u -= 1 << 13
+		}
+		return strconv.FormatInt(u, 10), 2, nil
+	case b&0xF0 == 0xE0: // 12 位长度字符串
+		if err := need(2); err != nil {
+			return "", 0, err
+		}
+		l := int(b&0x0F)<<8 | int(p[1])
+		if err := need(2 + l); err != nil {
+			return "", 0, err
+		}
+		return string(p[2 : 2+l]), 2 + l, nil
+	}
+	switch b {
+	case 0xF0: // 32 位长度字符串
+		if err := need(5); err != nil {
+			return "", 0, err
+		}
+		l := int(binary.LittleEndian.Uint32(p[1:]))
+		if err := need(5 + l); err != nil {
+			return "", 0, err
+		}
+		return string(p[5 : 5+l]), 5 + l, nil
+	case 0xF1:
+		if err := need(3); err != nil {
+			return "", 0, err
+		}
+		return strconv.FormatInt(int64(int16(binary.LittleEndian.Uint16(p[1:]))), 10), 3, nil
+	case 0xF2:
+		if err := need(4); err != nil {
+			return "", 0, err
+		}
+		u := int32(uint32(p[1])<<8|uint32(p[2])<<16|uint32(p[3])<<24) >> 8
+		return strconv.FormatInt(int64(u), 10), 4, nil
+	case 0xF3:
+		if err := need(5); err != nil {
+			return "", 0, err
+		}
+		return strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(p[1:]))), 10), 5, nil
+	case 0xF4:
+		if err := need(9); err != nil {
+			return "", 0, err
+		}
+		return strconv.FormatInt(int64(binary.LittleEndian.Uint64(p[1:])), 10), 9, nil
+	}
+	return "", 0, fmt.Errorf("unsupported listpack encoding %#x", b)
+}
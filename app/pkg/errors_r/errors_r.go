@@ -14,3 +14,8 @@ var (
 	ErrKeyNotFoundInRDB = errors.New("key not found in rdb")
 	ErrInvalidRequest   = errors.New("invalid request")
 )
+
+// 返回给客户端的错误
+var (
+	ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+)
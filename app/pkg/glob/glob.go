@@ -0,0 +1,91 @@
+// Package glob Redis 风格的通配符匹配 (KEYS/SCAN 的 MATCH 选项)
+// 支持 * ? [abc] [^a] [a-z] 与 \ 转义
+package glob
+
+// Match 报告 s 是否匹配 pattern
+func Match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// 合并连续的 *
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if Match(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			rest, ok := matchClass(pattern[1:], s[0])
+			if !ok {
+				return false
+			}
+			pattern = rest
+			s = s[1:]
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass 匹配 [...] 字符类 pattern 从 '[' 之后开始, 返回 ']' 之后的剩余模式
+func matchClass(pattern string, c byte) (string, bool) {
+	not := false
+	if len(pattern) > 0 && pattern[0] == '^' {
+		not = true
+		pattern = pattern[1:]
+	}
+	match := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) >= 2:
+			if pattern[1] == c {
+				match = true
+			}
+			pattern = pattern[2:]
+		case len(pattern) >= 3 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				match = true
+			}
+			pattern = pattern[3:]
+		default:
+			if pattern[0] == c {
+				match = true
+			}
+			pattern = pattern[1:]
+		}
+	}
+	// 未闭合的 [ 视为到模式末尾
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+	return pattern, match != not
+}
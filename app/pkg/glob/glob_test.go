@@ -0,0 +1,31 @@
+package glob
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeeello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"user:*:name", "user:1000:name", true},
+		{`a\*b`, "a*b", true},
+		{`a\*b`, "axb", false},
+		{"**a", "bba", true},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, Match(c.pattern, c.s))
+	}
+}